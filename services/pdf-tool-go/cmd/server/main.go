@@ -20,22 +20,58 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/admin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/audit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/cli"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/connector"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/cron"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/delivery"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/esign"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/handlers"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/health"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/icr"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/janitor"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/linkcheck"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/mailbox"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/middleware"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ner"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/notify"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/provenance"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/retention"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/scripting"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/share"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/watch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/processor"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
-	"github.com/rs/cors"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 )
 
-const (
-	serviceName    = "pdf-tool-go"
-	serviceVersion = "1.0.0"
-)
+const serviceName = "pdf-tool-go"
+
+// multipartOverhead is the room left in request body limits for multipart
+// framing and form fields beyond the uploaded documents
+const multipartOverhead = 1 << 20
 
 func main() {
+	// CLI subcommands (merge, split, ...) run instead of the server
+	if len(os.Args) > 1 && cli.IsCommand(os.Args[1]) {
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+		code := cli.Run(ctx, os.Args[1:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
 	// Initialize configuration
 	cfg, err := config.Load()
 	if err != nil {
@@ -45,10 +81,11 @@ func main() {
 
 	// Initialize logger
 	log := logger.New(cfg.LogLevel, cfg.LogFormat)
-	log.Info("Starting PDF Tool Go service", "version", serviceVersion)
+	build := buildinfo.Get()
+	log.Info("Starting PDF Tool Go service", "version", build.Version, "commit", build.Commit)
 
 	// Initialize OpenTelemetry
-	shutdown, err := telemetry.InitTracer(serviceName, serviceVersion)
+	shutdown, err := telemetry.InitTracer(serviceName, build.Version)
 	if err != nil {
 		log.Error("Failed to initialize telemetry", "error", err)
 		os.Exit(1)
@@ -66,34 +103,299 @@ func main() {
 
 	// Initialize Gin router
 	router := gin.New()
+	router.MaxMultipartMemory = cfg.Server.MaxMultipartMemory
+	if err := router.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Error("Invalid trusted proxies", "error", err)
+		os.Exit(1)
+	}
 
 	// Middleware
 	router.Use(gin.Recovery())
 	router.Use(middleware.Logger(log))
 	router.Use(otelgin.Middleware(serviceName))
+	router.Use(middleware.CORS(cfg.CORS))
 	router.Use(middleware.Metrics())
-	router.Use(middleware.RateLimiter(cfg.RateLimit))
-
-	// CORS configuration
-	corsMiddleware := cors.New(cors.Options{
-		AllowedOrigins:   cfg.CORS.AllowedOrigins,
-		AllowedMethods:   []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposedHeaders:   []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           300,
-	})
-	router.Use(func(c *gin.Context) {
-		corsMiddleware.HandlerFunc(c.Writer, c.Request)
-		c.Next()
-	})
+	router.Use(versioning.Resolve(cfg.Versioning))
+
+	backgroundCtx, stopBackground := context.WithCancel(context.Background())
+	defer stopBackground()
+
+	// Connect to the database holding persistent state
+	var db *database.DB
+	if cfg.Database.Driver != "" {
+		db, err = database.Open(backgroundCtx, cfg.Database)
+		if err != nil {
+			log.Error("Failed to initialize database", "error", err)
+			os.Exit(1)
+		}
+		defer db.Close()
+	}
+
+	// Resolve tenants from configuration and, with onboarding, from the
+	// tenant directory
+	var directory *tenant.Directory
+	if cfg.Onboarding.Enabled {
+		directory, err = tenant.NewDirectory(backgroundCtx, db, cfg.Tenants, log)
+		if err != nil {
+			log.Error("Failed to initialize tenant directory", "error", err)
+			os.Exit(1)
+		}
+		directory.Start(backgroundCtx, time.Duration(cfg.Onboarding.RefreshInterval)*time.Second)
+	}
+	tenants := tenant.NewResolver(cfg.Tenants, directory)
+	limiter := ratelimit.NewEnforcer(cfg.RateLimit, log)
+	router.Use(middleware.RateLimiter(limiter, tenants))
+	router.Use(middleware.Privileged(cfg.Auth))
+	router.Use(middleware.Tenant(tenants))
+	router.Use(middleware.QueueStatus())
+
+	// Audit sampling
+	var auditRecorder *audit.Recorder
+	if cfg.Audit.Enabled {
+		auditRecorder = audit.NewRecorder(cfg.Audit, log)
+		router.Use(middleware.Audit(auditRecorder))
+	}
+
+	// Initialize storage
+	store, err := storage.New(cfg.Storage, cfg.Breakers, log)
+	if err != nil {
+		log.Error("Failed to initialize storage", "error", err)
+		os.Exit(1)
+	}
 
 	// Initialize PDF service
+	retentionPolicies := retention.NewPolicies(cfg.Retention, cfg.Tenants)
+	retentionPolicies.SetOverrides(tenants)
 	pdfService := service.NewPDFService(log, cfg)
 
+	for _, backend := range cfg.ICR.Backends {
+		recognizer, err := icr.New(backend, pdfService.Runner(), cfg.Breakers, log)
+		if err != nil {
+			log.Error("Failed to initialize ICR backend", "backend", backend.Name, "error", err)
+			os.Exit(1)
+		}
+		pdfService.RegisterRecognizer(backend.Name, recognizer)
+	}
+	for _, backend := range cfg.Entities.NER {
+		pdfService.RegisterEntityExtractor(backend.Name, ner.New(backend))
+	}
+	// Start temp directory janitor. The sandbox work directory defaults to
+	// a folder of the temp directory; workspaces of running tools are kept.
+	tempJanitor := janitor.New(cfg.PDF.TempDir, cfg.Janitor, log)
+	tempJanitor.Protect(pdfService.Runner().WorkDir(), pdfService.Runner().InUse)
+	if cfg.Janitor.Enabled {
+		tempJanitor.Start(backgroundCtx)
+	}
+
+	scripts, err := scripting.New(cfg.Scripting, cfg.Tenants, log)
+	if err != nil {
+		log.Error("Failed to load tenant scripts", "error", err)
+		os.Exit(1)
+	}
+	pdfService.SetScripts(scripts)
+	if cfg.Provenance.Enabled {
+		signer, err := provenance.New(cfg.Provenance, cfg.Tenants, build.Version)
+		if err != nil {
+			log.Error("Failed to load provenance signing key", "error", err)
+			os.Exit(1)
+		}
+		pdfService.SetProvenance(signer)
+	}
+	pdfService.SetLinkChecker(linkcheck.New(cfg.LinkCheck))
+	for name, p := range processor.Registered() {
+		pdfService.RegisterProcessor(name, p)
+	}
+	// Out-of-process plugins run until shutdown
+	var stopPlugins []func()
+	for _, plugin := range cfg.Plugins {
+		p, stop, err := processor.Open(plugin.Path)
+		if err != nil {
+			log.Error("Failed to start plugin", "plugin", plugin.Name, "error", err)
+			for _, stop := range stopPlugins {
+				stop()
+			}
+			os.Exit(1)
+		}
+		stopPlugins = append(stopPlugins, stop)
+		pdfService.RegisterProcessor(plugin.Name, p)
+	}
+
+	// Cap request bodies at one document, or as many as a merge accepts,
+	// plus room for multipart framing and form fields
+	router.Use(middleware.BodyLimit(func(c *gin.Context) int64 {
+		files := int64(1)
+		if versioning.RoutePath(c.FullPath()) == "/api/v1/pdf/merge" {
+			if cfg.PDF.MaxMergeFiles == 0 {
+				return 0
+			}
+			files = int64(cfg.PDF.MaxMergeFiles)
+		}
+		return files*pdfService.MaxFileSize(c.Request.Context()) + multipartOverhead
+	}))
+	resultService := service.NewResultService(store, retentionPolicies, log)
+	documentService := service.NewDocumentService(store, retentionPolicies, log)
+
+	// Purge stored documents and results past their retention TTL
+	purger := retention.NewPurger(cfg.Retention, log)
+	purger.Register("result", resultService)
+	purger.Register("document", documentService)
+	if cfg.Retention.Enabled {
+		purger.Start(backgroundCtx)
+	}
+
+	// Sign share links to stored results
+	var shareLinks *share.Links
+	if cfg.Share.Enabled {
+		// Download limits are shared by all replicas through the database
+		var downloads share.Counter = share.NewMemoryCounter()
+		if db != nil {
+			downloads, err = share.NewSQLCounter(backgroundCtx, db)
+			if err != nil {
+				log.Error("Failed to initialize share link downloads", "error", err)
+				os.Exit(1)
+			}
+		}
+		shareLinks = share.New(cfg.Share, cfg.DecryptLockout, downloads)
+	}
+
+	// Run batch jobs on a worker pool
+	jobTTL := time.Duration(cfg.Batch.JobTTL) * time.Second
+	var jobStore batch.Store = batch.NewMemoryStore(jobTTL)
+	if cfg.Batch.Store == "database" {
+		jobStore, err = batch.NewSQLStore(backgroundCtx, db, jobTTL)
+		if err != nil {
+			log.Error("Failed to initialize batch job store", "error", err)
+			os.Exit(1)
+		}
+	}
+	jobManager := batch.NewManager(cfg.Batch, cfg.Tenants, jobStore, pdfService, store, documentService, resultService, log)
+	jobManager.SetResolver(tenants)
+	var mailer *delivery.Mailer
+	if cfg.Email.Enabled {
+		mailer, err = delivery.NewMailer(cfg.Email, resultService, shareLinks)
+		if err != nil {
+			log.Error("Failed to initialize email delivery", "error", err)
+			os.Exit(1)
+		}
+		jobManager.SetDeliverer(mailer)
+	}
+	if len(cfg.Notifications.Sinks) > 0 {
+		jobManager.SetNotifier(notify.New(cfg.Notifications, log))
+	}
+	jobManager.Start(backgroundCtx)
+
+	// Run scheduled maintenance jobs
+	scheduler := cron.NewScheduler(log)
+	scheduler.RegisterTask("purge_expired", cron.PurgeTask(purger))
+	scheduler.RegisterTask("sweep_temp", cron.SweepTask(tempJanitor))
+	scheduler.RegisterTask("recompress", cron.RecompressTask(documentService, jobManager, cfg.Batch.MaxDocuments))
+	if cfg.Cron.Enabled {
+		for _, job := range cfg.Cron.Jobs {
+			if err := scheduler.Add(job, cron.SourceConfig); err != nil {
+				log.Error("Invalid scheduled job", "job", job.Name, "error", err)
+				os.Exit(1)
+			}
+		}
+		scheduler.Start(backgroundCtx)
+	}
+
+	// Poll watch folders
+	watchers := make([]*watch.Watcher, 0, len(cfg.Watch.Folders))
+	for _, folder := range cfg.Watch.Folders {
+		watcher, err := watch.New(folder, cfg.Batch, pdfService.MaxFileSize, documentService, resultService, jobManager, log)
+		if err != nil {
+			log.Error("Invalid watch folder", "error", err)
+			os.Exit(1)
+		}
+		watcher.Start(backgroundCtx)
+		watchers = append(watchers, watcher)
+	}
+
+	// Ingest the attachments of mailbox messages
+	ingesters := make([]*mailbox.Ingester, 0, len(cfg.IMAP.Mailboxes))
+	for _, box := range cfg.IMAP.Mailboxes {
+		ingester, err := mailbox.New(box, cfg.Batch, pdfService.MaxFileSize, documentService, jobManager, mailer, log)
+		if err != nil {
+			log.Error("Invalid imap mailbox", "error", err)
+			os.Exit(1)
+		}
+		ingester.Start(backgroundCtx)
+		ingesters = append(ingesters, ingester)
+	}
+
+	// Register readiness checks
+	checker := health.NewChecker(time.Duration(cfg.Health.CheckTimeout) * time.Second)
+	checker.Register(health.TempDir(cfg.PDF.TempDir), true)
+	checker.Register(health.Storage(store), true)
+	if db != nil {
+		checker.Register(health.Database(db.DB), true)
+	}
+	// Rate limits fall back to per-instance limits while Redis is down
+	if distributed, ok := limiter.(*ratelimit.Distributed); ok {
+		checker.Register(health.Redis(distributed), false)
+	}
+	checker.Register(health.Tool(pdfService.Runner(), "tesseract"), cfg.PDF.OCREnabled)
+	checker.Register(health.Tool(pdfService.Runner(), "gs"), false)
+
+	// Graceful drain: wait for requests, then for background processing
+	drainer := lifecycle.NewDrainer(log)
+	drainer.Register("scheduled jobs", scheduler.Drain)
+	for i, watcher := range watchers {
+		drainer.Register("watch folder "+cfg.Watch.Folders[i].Name, watcher.Drain)
+	}
+	for i, ingester := range ingesters {
+		drainer.Register("imap mailbox "+cfg.IMAP.Mailboxes[i].Name, ingester.Drain)
+	}
+	drainer.Register("batch jobs", jobManager.Drain)
+	drainer.Register("background processing", pdfService.Drain)
+	checker.Register(health.Lifecycle(drainer), true)
+	preStopDelay := time.Duration(cfg.Lifecycle.PreStopDelay) * time.Second
+
+	// Reload safe-to-change settings on SIGHUP
+	reloader := config.NewReloader(cfg, log)
+	reloader.OnReload(func(next *config.Config) {
+		if setter, ok := log.(logger.LevelSetter); ok {
+			if err := setter.SetLevel(next.LogLevel); err != nil {
+				log.Warn("Failed to apply log level", "error", err)
+			}
+		}
+		pdfService.SetMaxFileSize(next.PDF.MaxFileSize)
+		if limiter != nil {
+			limiter.Configure(next.RateLimit)
+		}
+	})
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	go func() {
+		for range hup {
+			if err := reloader.Reload(); err != nil {
+				log.Error("Failed to reload configuration", "error", err)
+			}
+		}
+	}()
+
 	// Initialize handlers
-	pdfHandler := handlers.NewPDFHandler(pdfService, log)
-	healthHandler := handlers.NewHealthHandler(log)
+	pdfHandler := handlers.NewPDFHandler(pdfService, resultService, documentService, jobManager, log)
+	if shareLinks != nil {
+		pdfHandler.SetShareLinks(shareLinks, tenants)
+	}
+	if cfg.Connectors.Enabled {
+		pdfHandler.SetConnectors(connector.New(cfg.Connectors, cfg.Tenants))
+	}
+	if cfg.ESign.Enabled {
+		signing, err := esign.New(cfg.ESign)
+		if err != nil {
+			log.Error("Failed to initialize signing hand-off", "error", err)
+			os.Exit(1)
+		}
+		pdfHandler.SetSigning(signing)
+	}
+	healthHandler := handlers.NewHealthHandler(log, checker, pdfService, cfg.Health.Diagnostics)
+	docsHandler, err := handlers.NewDocsHandler(build.Version)
+	if err != nil {
+		log.Error("Failed to load API documentation", "error", err)
+		os.Exit(1)
+	}
 
 	// Health check endpoints
 	router.GET("/health", healthHandler.Health)
@@ -102,32 +404,142 @@ func main() {
 	// Metrics endpoint
 	router.GET("/metrics", handlers.PrometheusHandler())
 
-	// API v1 routes
-	v1 := router.Group("/api/v1")
-	{
+	// API documentation
+	router.GET("/openapi.json", docsHandler.Spec)
+	router.GET("/docs", docsHandler.UI)
+	router.GET("/docs/assets/:name", docsHandler.Asset)
+
+	// API routes, registered once per version group. Everything but share
+	// link downloads needs an API key unless anonymous access is enabled.
+	registerAPI := func(public *gin.RouterGroup) {
+		api := public.Group("", middleware.Authenticated(cfg.Auth))
+
+		// Routes queueing processing work are shed under overload
+		var shed gin.HandlerFunc = func(c *gin.Context) { c.Next() }
+		if shedder := pdfService.Shedder(); shedder != nil {
+			shed = middleware.LoadShedding(shedder, cfg.LoadShedding.RetryAfter)
+		}
+
 		// PDF operations
-		pdf := v1.Group("/pdf")
+		pdf := api.Group("/pdf", shed)
 		{
 			pdf.POST("/convert/image", pdfHandler.ConvertToImage)
+			pdf.POST("/convert/image/stream", pdfHandler.ConvertToImageStream)
+			pdf.POST("/convert/contact-sheet", pdfHandler.ContactSheet)
+			pdf.POST("/convert/long-image", pdfHandler.LongImage)
+			pdf.POST("/convert/preview", pdfHandler.AnimatedPreview)
 			pdf.POST("/merge", pdfHandler.MergePDFs)
-			pdf.POST("/split", pdfHandler.SplitPDF)
+			pdf.POST("/assemble", pdfHandler.AssemblePDF)
+			pdf.POST("/audit-trail", pdfHandler.AppendAuditTrail)
+			pdf.POST("/secure-section", pdfHandler.SecureSection)
+			pdf.POST("/split", versioning.Handlers{
+				versioning.V1: pdfHandler.SplitPDF,
+				versioning.V2: pdfHandler.SplitPDFArchive,
+			}.Handle)
 			pdf.POST("/extract/text", pdfHandler.ExtractText)
+			pdf.POST("/extract/invoice", pdfHandler.ExtractInvoice)
 			pdf.POST("/extract/metadata", pdfHandler.ExtractMetadata)
+			pdf.POST("/page-labels", pdfHandler.SetPageLabels)
+			pdf.POST("/extract/links", pdfHandler.ExtractLinks)
+			pdf.POST("/navigation/check", pdfHandler.CheckNavigation)
 			pdf.POST("/compress", pdfHandler.CompressPDF)
 			pdf.POST("/watermark", pdfHandler.AddWatermark)
 			pdf.POST("/rotate", pdfHandler.RotatePages)
 			pdf.POST("/encrypt", pdfHandler.EncryptPDF)
 			pdf.POST("/decrypt", pdfHandler.DecryptPDF)
+			pdf.POST("/redact/pii", pdfHandler.RedactPII)
+			pdf.POST("/accessibility/check", pdfHandler.CheckAccessibility)
+			pdf.POST("/accessibility/autotag", pdfHandler.AutoTag)
+			pdf.POST("/accessibility/properties", pdfHandler.SetAccessibilityProperties)
+			pdf.POST("/prepress/color", pdfHandler.ConvertColor)
+			pdf.POST("/prepress/pdfx/check", pdfHandler.CheckPDFX)
+			pdf.POST("/prepress/pdfx/convert", pdfHandler.ConvertPDFX)
+			pdf.POST("/prepress/fonts", pdfHandler.EmbedFonts)
+			pdf.POST("/prepress/outline-text", pdfHandler.OutlineText)
+			pdf.POST("/prepress/flatten", pdfHandler.FlattenTransparency)
+			pdf.POST("/prepress/ink", pdfHandler.AnalyzeInk)
+			pdf.POST("/prepress/spot-colors", pdfHandler.ConvertSpotColors)
+			pdf.POST("/prepress/icc", pdfHandler.ListICCProfiles)
+			pdf.POST("/prepress/icc/extract", pdfHandler.ExtractICCProfile)
+			pdf.POST("/prepress/icc/strip", pdfHandler.StripICCProfiles)
+			pdf.POST("/custom/:name", pdfHandler.RunCustom)
+
+			// Single pages of uploaded documents and stored results
+			pdf.GET("/:docId/pages/:n", pdfHandler.GetPage)
+			pdf.GET("/:docId/cover", pdfHandler.GetCover)
+		}
+
+		// Uploaded documents, referenced by operations via ?document_id
+		api.POST("/documents", pdfHandler.UploadDocument)
+		api.GET("/documents", pdfHandler.ListDocuments)
+		api.GET("/documents/:id", pdfHandler.DownloadDocument)
+		api.HEAD("/documents/:id", pdfHandler.DownloadDocument)
+		api.DELETE("/documents/:id", pdfHandler.DeleteDocument)
+
+		// Stored results
+		api.GET("/results/:id", pdfHandler.DownloadResult)
+		api.HEAD("/results/:id", pdfHandler.DownloadResult)
+		api.DELETE("/results/:id", pdfHandler.DeleteResult)
+
+		// Share links to stored results, downloadable without an API key
+		if cfg.Share.Enabled {
+			api.POST("/results/:id/share", pdfHandler.ShareResult)
+			public.GET("/share/:token", pdfHandler.DownloadShared)
+			public.HEAD("/share/:token", pdfHandler.DownloadShared)
+		}
+
+		// Imports from and exports to the caller's cloud storage
+		if cfg.Connectors.Enabled {
+			api.POST("/documents/import", pdfHandler.ImportDocument)
+			api.POST("/results/:id/export", pdfHandler.ExportResult)
+		}
+
+		// Hand-off of stored results to signing services
+		if cfg.ESign.Enabled {
+			api.POST("/results/:id/signatures", pdfHandler.RequestSignatures)
 		}
 
 		// Batch operations
-		batch := v1.Group("/batch")
+		batch := api.Group("/batch")
 		{
-			batch.POST("/process", pdfHandler.BatchProcess)
+			batch.POST("/process", shed, pdfHandler.BatchProcess)
+			batch.POST("/mailmerge", shed, pdfHandler.MailMerge)
+			batch.GET("/jobs", pdfHandler.ListJobs)
 			batch.GET("/status/:id", pdfHandler.BatchStatus)
+			batch.DELETE("/:id", pdfHandler.CancelJob)
+			batch.POST("/:id/pause", pdfHandler.PauseJob)
+			batch.POST("/:id/resume", shed, pdfHandler.ResumeJob)
 		}
 	}
 
+	// Each version is served under /api/v<N>, and unversioned /api paths
+	// at the version negotiated from the Accept header
+	for _, version := range versioning.Versions {
+		registerAPI(router.Group("/api/"+version.String(), middleware.Drain(drainer)))
+	}
+	registerAPI(router.Group("/api", middleware.Drain(drainer)))
+
+	// Start admin server (pprof, expvar)
+	var adminServer *admin.Server
+	if cfg.Admin.Enabled {
+		adminServer = admin.NewServer(cfg.Admin, log)
+		if auditRecorder != nil {
+			adminServer.Handle("/debug/audit", auditRecorder)
+		}
+		adminServer.Handle("/admin/drain", drainer.PreStopHandler(preStopDelay))
+		adminServer.Handle("/admin/batch/dead-letters/", jobManager.DeadLetterHandler())
+		adminServer.Handle("/admin/batch/workers/", jobManager.WorkersHandler())
+		adminServer.Handle("/admin/limits/", admin.LimitsHandler(pdfService, limiter, log))
+		adminServer.Handle("/admin/temp/", tempJanitor.Handler())
+		if directory != nil {
+			adminServer.Handle("/admin/tenants/", directory.Handler())
+		}
+		if cfg.Cron.Enabled {
+			adminServer.Handle("/admin/cron/", scheduler.Handler())
+		}
+		adminServer.Start()
+	}
+
 	// Create HTTP server
 	srv := &http.Server{
 		Addr:           fmt.Sprintf(":%d", cfg.Port),
@@ -154,11 +566,36 @@ func main() {
 
 	log.Info("Shutting down server...")
 
-	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	// Flip readiness and keep serving until load balancers have noticed;
+	// skipped if a preStop hook already did this via /admin/drain
+	drainer.PreStop(context.Background(), preStopDelay)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Lifecycle.ShutdownTimeout)*time.Second)
 	defer cancel()
 
+	// Reject new work and let in-flight work finish before closing listeners
+	clean := true
+	if err := drainer.Drain(ctx); err != nil {
+		log.Error("Drain did not complete", "error", err)
+		clean = false
+	}
+
 	if err := srv.Shutdown(ctx); err != nil {
 		log.Error("Server forced to shutdown", "error", err)
+		clean = false
+	}
+
+	if adminServer != nil {
+		if err := adminServer.Shutdown(ctx); err != nil {
+			log.Error("Admin server forced to shutdown", "error", err)
+		}
+	}
+
+	for _, stop := range stopPlugins {
+		stop()
+	}
+
+	if !clean {
 		os.Exit(1)
 	}
 
@@ -20,5 +20,19 @@ require (
 	google.golang.org/grpc v1.59.0
 	google.golang.org/protobuf v1.31.0
 	github.com/google/uuid v1.5.0
-	github.com/rs/cors v1.10.1
+	github.com/aws/aws-sdk-go-v2 v1.24.0
+	github.com/aws/aws-sdk-go-v2/config v1.26.1
+	github.com/aws/aws-sdk-go-v2/credentials v1.16.12
+	github.com/aws/aws-sdk-go-v2/service/s3 v1.47.5
+	github.com/aws/aws-sdk-go-v2/service/secretsmanager v1.25.5
+	github.com/redis/go-redis/v9 v9.3.0
+	github.com/sony/gobreaker v0.5.0
+	github.com/hashicorp/go-plugin v1.6.0
+	github.com/yuin/gopher-lua v1.1.1
+	github.com/jackc/pgx/v5 v5.5.1
+	modernc.org/sqlite v1.28.0
+	github.com/pkg/sftp v1.13.6
+	github.com/jlaffaye/ftp v0.2.0
+	golang.org/x/crypto v0.17.0
+	github.com/emersion/go-imap v1.2.1
 )
@@ -0,0 +1,165 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// limitsPath is the admin path of the runtime limits
+const limitsPath = "/admin/limits"
+
+// Concurrency is the heavy-operation limiter; *service.PDFService
+// implements it
+type Concurrency interface {
+	Stats() service.Stats
+	SetConcurrency(maxConcurrent, queueSize int) error
+}
+
+// concurrencyLimits is the body of a concurrency change; unset fields keep
+// their value
+type concurrencyLimits struct {
+	MaxConcurrent *int `json:"max_concurrent"`
+	QueueSize     *int `json:"queue_size"`
+}
+
+// rateLimits are the rate limits as reported to and set by operators;
+// unset fields keep their value and endpoints, when given, replace all
+// endpoint overrides
+type rateLimits struct {
+	RequestsPerMin *int                         `json:"requests_per_minute"`
+	Burst          *int                         `json:"burst"`
+	Endpoints      map[string]endpointRateLimit `json:"endpoints"`
+}
+
+type endpointRateLimit struct {
+	Cost           int `json:"cost,omitempty"`
+	RequestsPerMin int `json:"requests_per_minute,omitempty"`
+	Burst          int `json:"burst,omitempty"`
+}
+
+// limitsReport lists the limits in effect
+type limitsReport struct {
+	Concurrency service.Stats `json:"concurrency"`
+	// RateLimit is omitted when rate limiting is disabled
+	RateLimit *rateLimits `json:"rate_limit,omitempty"`
+}
+
+// LimitsHandler serves the runtime limits at /admin/limits/: GET reports
+// the concurrency and rate limits in effect, and PUT concurrency and PUT
+// rate change them. Changes last until the next configuration reload or
+// restart. limiter is nil when rate limiting is disabled.
+func LimitsHandler(concurrency Concurrency, limiter ratelimit.Enforcer, log logger.Logger) http.Handler {
+	report := func() limitsReport {
+		report := limitsReport{Concurrency: concurrency.Stats()}
+		if limiter != nil {
+			report.RateLimit = toRateLimits(limiter.Limits())
+		}
+		return report
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, limitsPath), "/")
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, report())
+
+		case rest == "concurrency" && r.Method == http.MethodPut:
+			var req concurrencyLimits
+			if !decode(w, r, &req) {
+				return
+			}
+			stats := concurrency.Stats()
+			maxConcurrent, queueSize := stats.Capacity, stats.QueueSize
+			if req.MaxConcurrent != nil {
+				maxConcurrent = *req.MaxConcurrent
+			}
+			if req.QueueSize != nil {
+				queueSize = *req.QueueSize
+			}
+			if err := concurrency.SetConcurrency(maxConcurrent, queueSize); err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			writeJSON(w, http.StatusOK, report())
+
+		case rest == "rate" && r.Method == http.MethodPut:
+			if limiter == nil {
+				http.Error(w, "rate limiting is disabled", http.StatusConflict)
+				return
+			}
+			var req rateLimits
+			if !decode(w, r, &req) {
+				return
+			}
+			next, err := applyRateLimits(limiter.Limits(), req)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			limiter.Configure(next)
+			log.Info("Rate limits changed", "requests_per_minute", next.RequestsPerMin, "burst", next.Burst,
+				"endpoints", len(next.Endpoints))
+			writeJSON(w, http.StatusOK, report())
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+// applyRateLimits returns cfg with the changes of req
+func applyRateLimits(cfg config.RateLimitConfig, req rateLimits) (config.RateLimitConfig, error) {
+	if req.RequestsPerMin != nil {
+		cfg.RequestsPerMin = *req.RequestsPerMin
+	}
+	if req.Burst != nil {
+		cfg.Burst = *req.Burst
+	}
+	if cfg.RequestsPerMin <= 0 || cfg.Burst <= 0 {
+		return cfg, service.NewError(service.ErrCodeInvalidInput, "requests_per_minute and burst must be positive", nil)
+	}
+	if req.Endpoints != nil {
+		cfg.Endpoints = make(map[string]config.EndpointRateLimit, len(req.Endpoints))
+		for route, endpoint := range req.Endpoints {
+			if endpoint.Cost < 0 || endpoint.RequestsPerMin < 0 || endpoint.Burst < 0 {
+				return cfg, service.NewError(service.ErrCodeInvalidInput, "endpoint "+route+": limits must not be negative", nil)
+			}
+			cfg.Endpoints[route] = config.EndpointRateLimit(endpoint)
+		}
+	}
+	return cfg, nil
+}
+
+func toRateLimits(cfg config.RateLimitConfig) *rateLimits {
+	limits := &rateLimits{
+		RequestsPerMin: &cfg.RequestsPerMin,
+		Burst:          &cfg.Burst,
+		Endpoints:      make(map[string]endpointRateLimit, len(cfg.Endpoints)),
+	}
+	for route, endpoint := range cfg.Endpoints {
+		limits.Endpoints[route] = endpointRateLimit(endpoint)
+	}
+	return limits
+}
+
+// decode reads a JSON request body, answering 400 if it is invalid
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
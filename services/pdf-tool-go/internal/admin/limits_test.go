@@ -0,0 +1,96 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeConcurrency records the limits it is set to
+type fakeConcurrency struct {
+	stats service.Stats
+}
+
+func (f *fakeConcurrency) Stats() service.Stats {
+	return f.stats
+}
+
+func (f *fakeConcurrency) SetConcurrency(maxConcurrent, queueSize int) error {
+	if maxConcurrent <= 0 {
+		return service.NewError(service.ErrCodeInvalidInput, "max_concurrent must be positive", nil)
+	}
+	f.stats.Capacity, f.stats.QueueSize = maxConcurrent, queueSize
+	return nil
+}
+
+func TestLimitsHandler(t *testing.T) {
+	log := logger.New("info", "text")
+	concurrency := &fakeConcurrency{stats: service.Stats{Capacity: 4, QueueSize: 8}}
+	limiter := ratelimit.NewEnforcer(config.RateLimitConfig{
+		Enabled:        true,
+		RequestsPerMin: 60,
+		Burst:          10,
+		Endpoints:      map[string]config.EndpointRateLimit{"/api/v1/pdf/extract/text": {Cost: 5}},
+	}, log)
+	handler := LimitsHandler(concurrency, limiter, log)
+
+	do := func(method, path, body string) (*httptest.ResponseRecorder, limitsReport) {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(method, path, strings.NewReader(body)))
+		var report limitsReport
+		if rec.Code == http.StatusOK {
+			require.NoError(t, json.Unmarshal(rec.Body.Bytes(), &report))
+		}
+		return rec, report
+	}
+
+	t.Run("Reports Limits", func(t *testing.T) {
+		rec, report := do(http.MethodGet, "/admin/limits/", "")
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 4, report.Concurrency.Capacity)
+		require.NotNil(t, report.RateLimit)
+		assert.Equal(t, 60, *report.RateLimit.RequestsPerMin)
+		assert.Equal(t, 5, report.RateLimit.Endpoints["/api/v1/pdf/extract/text"].Cost)
+	})
+
+	t.Run("Changes Concurrency", func(t *testing.T) {
+		rec, report := do(http.MethodPut, "/admin/limits/concurrency", `{"max_concurrent": 2}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 2, report.Concurrency.Capacity)
+		assert.Equal(t, 8, report.Concurrency.QueueSize)
+
+		rec, _ = do(http.MethodPut, "/admin/limits/concurrency", `{"max_concurrent": 0}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Changes Rate Limits", func(t *testing.T) {
+		rec, report := do(http.MethodPut, "/admin/limits/rate", `{"burst": 20}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, 60, *report.RateLimit.RequestsPerMin)
+		assert.Equal(t, 20, *report.RateLimit.Burst)
+		assert.Len(t, report.RateLimit.Endpoints, 1)
+
+		rec, report = do(http.MethodPut, "/admin/limits/rate", `{"endpoints": {}}`)
+		require.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, report.RateLimit.Endpoints)
+		assert.Equal(t, 20, limiter.Limits().Burst)
+
+		rec, _ = do(http.MethodPut, "/admin/limits/rate", `{"requests_per_minute": 0}`)
+		assert.Equal(t, http.StatusBadRequest, rec.Code)
+	})
+
+	t.Run("Rate Limits Disabled", func(t *testing.T) {
+		rec := httptest.NewRecorder()
+		LimitsHandler(concurrency, nil, log).ServeHTTP(rec, httptest.NewRequest(http.MethodPut, "/admin/limits/rate", strings.NewReader(`{"burst": 1}`)))
+		assert.Equal(t, http.StatusConflict, rec.Code)
+	})
+}
@@ -0,0 +1,90 @@
+/**
+ * Admin Server
+ *
+ * Separate, authenticated listener for operational endpoints such as
+ * pprof profiling and expvar, kept off the public port.
+ */
+
+package admin
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"expvar"
+	"fmt"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// Server is the admin HTTP listener
+type Server struct {
+	cfg config.AdminConfig
+	log logger.Logger
+	mux *http.ServeMux
+	srv *http.Server
+}
+
+// NewServer creates an admin server with profiling endpoints registered
+func NewServer(cfg config.AdminConfig, log logger.Logger) *Server {
+	s := &Server{
+		cfg: cfg,
+		log: log,
+		mux: http.NewServeMux(),
+	}
+
+	s.mux.HandleFunc("/debug/pprof/", pprof.Index)
+	s.mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	s.mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	s.mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	s.mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	s.mux.Handle("/debug/vars", expvar.Handler())
+
+	s.srv = &http.Server{
+		Addr:    fmt.Sprintf("%s:%d", cfg.BindAddress, cfg.Port),
+		Handler: s.authenticate(s.mux),
+		// CPU profiles and traces stream for up to their requested duration
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 5 * time.Minute,
+	}
+
+	return s
+}
+
+// Handle registers an additional admin endpoint
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	s.mux.Handle(pattern, handler)
+}
+
+// Start runs the listener in the background
+func (s *Server) Start() {
+	go func() {
+		s.log.Info("Admin server starting", "address", s.srv.Addr)
+		if err := s.srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			s.log.Error("Admin server failed", "error", err)
+		}
+	}()
+}
+
+// Shutdown gracefully stops the listener
+func (s *Server) Shutdown(ctx context.Context) error {
+	return s.srv.Shutdown(ctx)
+}
+
+// authenticate requires "Authorization: Bearer <token>" on every request
+func (s *Server) authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if s.cfg.Token == "" || subtle.ConstantTimeCompare([]byte(token), []byte(s.cfg.Token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="admin"`)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
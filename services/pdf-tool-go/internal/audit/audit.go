@@ -0,0 +1,169 @@
+/**
+ * Request Audit Sampling
+ *
+ * Keeps a bounded history of sanitized descriptors for a sample of requests
+ * so customer-reported failures can be investigated. Descriptors record the
+ * shape of a request (operation, parameters, sizes, outcome), never document
+ * content.
+ */
+
+package audit
+
+import (
+	"encoding/json"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// redacted replaces the value of sensitive parameters
+const redacted = "[REDACTED]"
+
+// maxParamLength truncates long parameter values
+const maxParamLength = 64
+
+// sensitiveParams are parameters whose values are secrets or document content
+var sensitiveParams = []string{"password", "token", "secret", "key", "text"}
+
+// File describes an uploaded file without its content
+type File struct {
+	Field     string `json:"field"`
+	Extension string `json:"extension,omitempty"`
+	Size      int64  `json:"size"`
+}
+
+// Descriptor is a sanitized record of one request
+type Descriptor struct {
+	TraceID       string            `json:"trace_id,omitempty"`
+	Time          time.Time         `json:"time"`
+	Method        string            `json:"method"`
+	Operation     string            `json:"operation"`
+	Params        map[string]string `json:"params,omitempty"`
+	Files         []File            `json:"files,omitempty"`
+	RequestBytes  int64             `json:"request_bytes"`
+	ResponseBytes int               `json:"response_bytes"`
+	Status        int               `json:"status"`
+	ErrorCode     string            `json:"error_code,omitempty"`
+	DurationMS    int64             `json:"duration_ms"`
+}
+
+// Recorder samples requests into a ring buffer of recent descriptors
+type Recorder struct {
+	rate float64
+	log  logger.Logger
+
+	mu      sync.Mutex
+	entries []Descriptor
+	next    int
+	full    bool
+}
+
+// NewRecorder creates a recorder sampling cfg.SampleRate percent of requests
+func NewRecorder(cfg config.AuditConfig, log logger.Logger) *Recorder {
+	return &Recorder{
+		rate:    cfg.SampleRate,
+		log:     log,
+		entries: make([]Descriptor, cfg.Capacity),
+	}
+}
+
+// Sample reports whether the current request should be recorded
+func (r *Recorder) Sample() bool {
+	return r.rate > 0 && rand.Float64()*100 < r.rate
+}
+
+// Record stores a descriptor, evicting the oldest once full
+func (r *Recorder) Record(d Descriptor) {
+	r.log.Info("Audit sample",
+		"operation", d.Operation,
+		"status", d.Status,
+		"error_code", d.ErrorCode,
+		"request_bytes", d.RequestBytes,
+		"duration_ms", d.DurationMS,
+	)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if len(r.entries) == 0 {
+		return
+	}
+	r.entries[r.next] = d
+	r.next = (r.next + 1) % len(r.entries)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Recent returns the retained descriptors, newest first
+func (r *Recorder) Recent() []Descriptor {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	count := r.next
+	if r.full {
+		count = len(r.entries)
+	}
+
+	recent := make([]Descriptor, 0, count)
+	for i := 1; i <= count; i++ {
+		recent = append(recent, r.entries[(r.next-i+len(r.entries))%len(r.entries)])
+	}
+	return recent
+}
+
+// ServeHTTP lists the retained descriptors as JSON
+func (r *Recorder) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"sample_rate": r.rate,
+		"samples":     r.Recent(),
+	})
+}
+
+// SanitizeParams flattens request parameters, redacting sensitive values and
+// truncating long ones
+func SanitizeParams(values url.Values) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+
+	params := make(map[string]string, len(values))
+	for name, vals := range values {
+		value := strings.Join(vals, ",")
+		if isSensitive(name) {
+			value = redacted
+		} else if len(value) > maxParamLength {
+			value = value[:maxParamLength] + "..."
+		}
+		params[name] = value
+	}
+	return params
+}
+
+// DescribeFile describes an upload by its field, extension and size. The
+// base name is dropped since file names often identify customers.
+func DescribeFile(field, filename string, size int64) File {
+	return File{
+		Field:     field,
+		Extension: strings.ToLower(filepath.Ext(filename)),
+		Size:      size,
+	}
+}
+
+func isSensitive(name string) bool {
+	name = strings.ToLower(name)
+	for _, sensitive := range sensitiveParams {
+		if strings.Contains(name, sensitive) {
+			return true
+		}
+	}
+	return false
+}
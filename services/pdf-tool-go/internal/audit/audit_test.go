@@ -0,0 +1,63 @@
+package audit
+
+import (
+	"net/url"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSanitizeParams(t *testing.T) {
+	t.Run("redacts sensitive values", func(t *testing.T) {
+		params := SanitizeParams(url.Values{
+			"pages":         {"1-3"},
+			"text":          {"CONFIDENTIAL"},
+			"user_password": {"hunter2"},
+		})
+
+		assert.Equal(t, "1-3", params["pages"])
+		assert.Equal(t, redacted, params["text"])
+		assert.Equal(t, redacted, params["user_password"])
+	})
+
+	t.Run("truncates long values", func(t *testing.T) {
+		long := make([]byte, 100)
+		for i := range long {
+			long[i] = 'a'
+		}
+
+		params := SanitizeParams(url.Values{"pages": {string(long)}})
+
+		assert.Len(t, params["pages"], maxParamLength+3)
+	})
+}
+
+func TestDescribeFile(t *testing.T) {
+	file := DescribeFile("pdf", "Acme Payroll 2024.PDF", 1024)
+
+	assert.Equal(t, File{Field: "pdf", Extension: ".pdf", Size: 1024}, file)
+}
+
+func TestRecorder(t *testing.T) {
+	log := logger.New("error", "json")
+
+	t.Run("keeps the newest entries", func(t *testing.T) {
+		rec := NewRecorder(config.AuditConfig{SampleRate: 100, Capacity: 2}, log)
+		rec.Record(Descriptor{Operation: "a"})
+		rec.Record(Descriptor{Operation: "b"})
+		rec.Record(Descriptor{Operation: "c"})
+
+		recent := rec.Recent()
+		assert.Len(t, recent, 2)
+		assert.Equal(t, "c", recent[0].Operation)
+		assert.Equal(t, "b", recent[1].Operation)
+	})
+
+	t.Run("zero rate never samples", func(t *testing.T) {
+		rec := NewRecorder(config.AuditConfig{SampleRate: 0, Capacity: 2}, log)
+
+		assert.False(t, rec.Sample())
+	})
+}
@@ -0,0 +1,111 @@
+package batch
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// manifestName is the name of the manifest inside a result archive
+const manifestName = "manifest.json"
+
+// File statuses in a manifest
+const (
+	// FileCreated is an output stored by the job
+	FileCreated = "created"
+	// FileExisting is an output identical to a result already stored
+	FileExisting = "existing"
+)
+
+// Manifest lists the contents of a result archive so clients can verify
+// they received every output intact
+type Manifest struct {
+	JobID     string         `json:"job_id"`
+	CreatedAt time.Time      `json:"created_at"`
+	Files     []ManifestFile `json:"files"`
+}
+
+// ManifestFile describes one output in a result archive
+type ManifestFile struct {
+	// Name is the file name inside the archive
+	Name string `json:"name"`
+	// Source traces the output back to a job document, like
+	// Failure.InputName
+	Source   string `json:"source"`
+	ResultID string `json:"result_id"`
+	Size     int64  `json:"size"`
+	SHA256   string `json:"sha256"`
+	Status   string `json:"status"`
+}
+
+// archiveEntry is a committed output awaiting archival
+type archiveEntry struct {
+	file ManifestFile
+	data []byte
+}
+
+// archiveName returns the file name of the n-th output in an archive. The
+// index keeps names unique and ordered like the job's results.
+func archiveName(n int, source string) string {
+	base := strings.NewReplacer("#", "-", "/", "-").Replace(strings.TrimSuffix(source, ".pdf"))
+	return fmt.Sprintf("%03d-%s.pdf", n, base)
+}
+
+// buildArchive writes the outputs and their manifest into a ZIP archive.
+// Entries are stored uncompressed, since PDFs compress poorly, and dated to
+// the job's creation so a retried job produces an identical archive.
+func buildArchive(job *Job, entries []archiveEntry) ([]byte, error) {
+	manifest := Manifest{JobID: job.ID, CreatedAt: job.CreatedAt, Files: make([]ManifestFile, len(entries))}
+	for i, entry := range entries {
+		manifest.Files[i] = entry.file
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	add := func(name string, method uint16, data []byte) error {
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: name, Method: method, Modified: job.CreatedAt})
+		if err != nil {
+			return err
+		}
+		_, err = w.Write(data)
+		return err
+	}
+
+	if err := add(manifestName, zip.Deflate, manifestData); err != nil {
+		return nil, fmt.Errorf("failed to write manifest: %w", err)
+	}
+	for _, entry := range entries {
+		if err := add(entry.file.Name, zip.Store, entry.data); err != nil {
+			return nil, fmt.Errorf("failed to archive %s: %w", entry.file.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to finish archive: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// manifestFile describes a committed output
+func manifestFile(n int, source string, result *service.Result) ManifestFile {
+	status := FileCreated
+	if result.Existed {
+		status = FileExisting
+	}
+	return ManifestFile{
+		Name:     archiveName(n, source),
+		Source:   source,
+		ResultID: result.ID,
+		Size:     result.Size,
+		SHA256:   result.SHA256,
+		Status:   status,
+	}
+}
@@ -0,0 +1,221 @@
+/**
+ * Batch Jobs
+ *
+ * Asynchronous multi-step jobs over stored documents. A job runs as a
+ * transaction: intermediate outputs are staged in storage and promoted to
+ * results only when every step succeeds. On failure everything the job
+ * wrote is removed and the failing step and input are reported.
+ */
+
+package batch
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"go.opentelemetry.io/otel"
+)
+
+var tracer = otel.Tracer("batch")
+
+var jobsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_batch_jobs_total",
+	Help: "Finished batch jobs by status",
+}, []string{"status"})
+
+// Status is the state of a job
+type Status string
+
+const (
+	StatusQueued    Status = "queued"
+	StatusRunning   Status = "running"
+	StatusSucceeded Status = "succeeded"
+	StatusFailed    Status = "failed"
+	// StatusRetrying is a job waiting out the backoff after a transient
+	// failure
+	StatusRetrying Status = "retrying"
+	// StatusPaused is a job taken off the queue until it is resumed
+	StatusPaused Status = "paused"
+	// StatusCanceled is a job stopped on request; nothing it wrote is kept
+	StatusCanceled Status = "canceled"
+)
+
+// Valid reports whether s is a known status
+func (s Status) Valid() bool {
+	switch s {
+	case StatusQueued, StatusRunning, StatusSucceeded, StatusFailed, StatusRetrying, StatusPaused, StatusCanceled:
+		return true
+	}
+	return false
+}
+
+// stepParams lists the operations a step may run and the parameters each
+// accepts. Merge combines all of its inputs into one document; personalize
+// watermarks a copy of each input for every recipient of a CSV list;
+// mailmerge fills a copy of each input form for every row of a CSV or JSON
+// dataset; the other operations transform each input separately.
+var stepParams = map[string][]string{
+	"split":       {"pages"},
+	"merge":       {},
+	"compress":    {"level", "profile"},
+	"watermark":   {"text"},
+	"personalize": {"recipients", "text"},
+	"mailmerge":   {"data", "combine"},
+}
+
+// Step is one operation of a job, applied to the outputs of the previous
+// step (the job's documents for the first step)
+type Step struct {
+	Operation string            `json:"operation"`
+	Params    map[string]string `json:"params,omitempty"`
+}
+
+// commitOperation names the final phase promoting outputs to results in
+// failure reports
+const commitOperation = "commit"
+
+// Failure reports where a job failed
+type Failure struct {
+	// Step is 1-based, or 0 when the job never started; a failure while
+	// storing the final outputs is reported as the step after the last, with
+	// operation "commit"
+	Step      int    `json:"step"`
+	Operation string `json:"operation"`
+	// Input is the 0-based index of the failing input to the step, or -1
+	// when the step failed as a whole
+	Input int `json:"input"`
+	// InputName traces the input back to a job document: the document ID,
+	// with "#n" appended for the n-th output of each split
+	InputName string            `json:"input_name,omitempty"`
+	Code      service.ErrorCode `json:"code"`
+	Error     string            `json:"error"`
+	// cause is the underlying error, logged but not exposed to clients
+	cause error
+}
+
+// Progress counts the work of the running step, in inputs or, for steps
+// making copies per recipient or row, in copies
+type Progress struct {
+	Done  int `json:"done"`
+	Total int `json:"total"`
+}
+
+// Submission is a request to run a job
+type Submission struct {
+	Documents []string `json:"documents"`
+	Steps     []Step   `json:"steps"`
+	Priority  Priority `json:"priority,omitempty"`
+	// Delivery optionally sends the results out when the job succeeds
+	Delivery *Delivery `json:"delivery,omitempty"`
+}
+
+// Job is a batch job. Jobs are scoped to the tenant that submitted them.
+type Job struct {
+	ID        string   `json:"id"`
+	Tenant    string   `json:"-"`
+	Status    Status   `json:"status"`
+	Priority  Priority `json:"priority"`
+	Documents []string `json:"documents"`
+	Steps     []Step   `json:"steps"`
+	// Step is the 1-based step currently running, or the last one run
+	Step int `json:"step"`
+	// Progress is the progress of the step currently running, or of the
+	// last one run
+	Progress *Progress `json:"progress,omitempty"`
+	Results  []string  `json:"results,omitempty"`
+	// Archive is the result ID of a ZIP of the results with a manifest,
+	// set when the job produced more than one result
+	Archive    string     `json:"archive,omitempty"`
+	Failure    *Failure   `json:"failure,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	StartedAt  *time.Time `json:"started_at,omitempty"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	// Attempts counts the runs of the job. Failure holds the failure of the
+	// last attempt while a retry is pending.
+	Attempts      int        `json:"attempts"`
+	NextAttemptAt *time.Time `json:"next_attempt_at,omitempty"`
+	// DeadLetteredAt is set when the job failed permanently; dead-lettered
+	// jobs can be inspected and requeued on the admin listener
+	DeadLetteredAt *time.Time `json:"dead_lettered_at,omitempty"`
+	// PausedAt is set while the job is paused
+	PausedAt *time.Time `json:"paused_at,omitempty"`
+	// Delivery is the delivery of results requested at submission
+	Delivery *Delivery `json:"delivery,omitempty"`
+	// TraceContext links the job's processing to the submitting request
+	TraceContext map[string]string `json:"-"`
+	// Owner is the instance that queued the job
+	Owner string `json:"-"`
+}
+
+// Finished reports whether the job has reached a final status
+func (j *Job) Finished() bool {
+	return j.Status == StatusSucceeded || j.Status == StatusFailed || j.Status == StatusCanceled
+}
+
+// Validate checks a job submission against the batch limits
+func Validate(cfg config.BatchConfig, sub Submission) error {
+	documents, steps := sub.Documents, sub.Steps
+	if sub.Priority != PriorityInteractive && sub.Priority != PriorityBulk {
+		return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("unsupported priority %q", sub.Priority), nil)
+	}
+	if len(documents) == 0 || len(documents) > cfg.MaxDocuments {
+		return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("a job needs between 1 and %d documents", cfg.MaxDocuments), nil)
+	}
+	if len(steps) == 0 || len(steps) > cfg.MaxSteps {
+		return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("a job needs between 1 and %d steps", cfg.MaxSteps), nil)
+	}
+
+	for i, step := range steps {
+		allowed, ok := stepParams[step.Operation]
+		if !ok {
+			return stepError(i, "unsupported operation %q", step.Operation)
+		}
+		for name := range step.Params {
+			if !contains(allowed, name) {
+				return stepError(i, "unsupported parameter %q for %s", name, step.Operation)
+			}
+		}
+		if level, ok := step.Params["level"]; ok {
+			if _, err := strconv.Atoi(level); err != nil {
+				return stepError(i, "level must be an integer")
+			}
+		}
+		if step.Operation == "personalize" {
+			if _, err := parseRecipients(step.Params["recipients"]); err != nil {
+				return stepError(i, "%v", err)
+			}
+		}
+		if step.Operation == "mailmerge" {
+			if _, err := parseDataset(step.Params["data"]); err != nil {
+				return stepError(i, "%v", err)
+			}
+		}
+		if combine, ok := step.Params["combine"]; ok {
+			if _, err := strconv.ParseBool(combine); err != nil {
+				return stepError(i, "combine must be true or false")
+			}
+		}
+	}
+	return nil
+}
+
+// stepError reports an invalid step, numbered from 1 like failures
+func stepError(index int, format string, args ...interface{}) error {
+	err := service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("step %d: ", index+1)+fmt.Sprintf(format, args...), nil)
+	err.Details = map[string]interface{}{"step": index + 1}
+	return err
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,538 @@
+package batch
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessor splits into two parts, merges by joining with "+", fails
+// to compress any input containing "B/2", watermarks by appending the text
+// in brackets, fails transiently to watermark any input containing "B" and
+// fills forms by appending the "name" value in braces
+type fakeProcessor struct{}
+
+func (fakeProcessor) SplitPDF(ctx context.Context, req *service.SplitRequest) ([][]byte, error) {
+	return [][]byte{[]byte(string(req.PDFData) + "/1"), []byte(string(req.PDFData) + "/2")}, nil
+}
+
+func (fakeProcessor) MergePDFs(ctx context.Context, req *service.MergeRequest) ([]byte, error) {
+	return bytes.Join(req.PDFs, []byte("+")), nil
+}
+
+func (fakeProcessor) CompressPDF(ctx context.Context, req *service.CompressRequest) ([]byte, error) {
+	if bytes.Contains(req.PDFData, []byte("B/2")) {
+		return nil, service.NewError(service.ErrCodeCorrupted, "PDF is corrupted or malformed", nil)
+	}
+	return []byte(string(req.PDFData) + "~"), nil
+}
+
+func (fakeProcessor) AddWatermark(ctx context.Context, req *service.WatermarkRequest) ([]byte, error) {
+	if bytes.Contains(req.PDFData, []byte("B")) {
+		return nil, service.NewError(service.ErrCodeInternal, "failed to add watermark", nil)
+	}
+	return []byte(string(req.PDFData) + "[" + req.WatermarkText + "]"), nil
+}
+
+func (fakeProcessor) FillForm(ctx context.Context, req *service.FillFormRequest) ([]byte, error) {
+	name, ok := req.Values["name"]
+	if !ok {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "no form field is named by the values", nil)
+	}
+	return []byte(string(req.PDFData) + "{" + name + "}"), nil
+}
+
+func TestManager(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	log := logger.New("info", "text")
+	documents := service.NewDocumentService(store, nil, log)
+	results := service.NewResultService(store, nil, log)
+	cfg := config.BatchConfig{Workers: 1, QueueSize: 10, TenantQueueSize: 10, InteractiveRatio: 1, MaxSteps: 5, MaxDocuments: 5, JobTimeout: 60, JobTTL: 60, MaxAttempts: 2, RetryDelay: 1, MaxRetryDelay: 1}
+	m := NewManager(cfg, nil, NewMemoryStore(time.Hour), fakeProcessor{}, store, documents, results, log)
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	docA, err := documents.Save(ctx, []byte("A"))
+	require.NoError(t, err)
+	docB, err := documents.Save(ctx, []byte("B"))
+	require.NoError(t, err)
+
+	next := func(id string) *Job {
+		<-m.pending.ready
+		popped, _ := m.pending.pop()
+		m.process(context.Background(), popped)
+		job, err := m.Get(ctx, id)
+		require.NoError(t, err)
+		return job
+	}
+	run := func(documents []string, steps ...Step) *Job {
+		job, err := m.Submit(ctx, Submission{Documents: documents, Steps: steps})
+		require.NoError(t, err)
+		return next(job.ID)
+	}
+	read := func(id string) []byte {
+		obj, _, err := results.Open(ctx, id)
+		require.NoError(t, err)
+		defer obj.Close()
+		data, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		return data
+	}
+	staged := func() int {
+		count := 0
+		require.NoError(t, store.List(ctx, stagingPrefix, func(storage.Info) error {
+			count++
+			return nil
+		}))
+		return count
+	}
+
+	t.Run("Success Commits Final Outputs Only", func(t *testing.T) {
+		job := run([]string{docA.ID}, Step{Operation: "split"}, Step{Operation: "merge"})
+		require.Equal(t, StatusSucceeded, job.Status)
+		require.Len(t, job.Results, 1)
+		assert.Empty(t, job.Archive)
+		assert.Equal(t, "A/1+A/2", string(read(job.Results[0])))
+		assert.Equal(t, 0, staged())
+	})
+
+	t.Run("Several Outputs Are Archived With A Manifest", func(t *testing.T) {
+		job := run([]string{docA.ID}, Step{Operation: "split"})
+		require.Equal(t, StatusSucceeded, job.Status)
+		require.Len(t, job.Results, 2)
+		require.NotEmpty(t, job.Archive)
+
+		archive := read(job.Archive)
+		zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+		require.NoError(t, err)
+		files := make(map[string][]byte)
+		for _, f := range zr.File {
+			rc, err := f.Open()
+			require.NoError(t, err)
+			files[f.Name], err = io.ReadAll(rc)
+			require.NoError(t, err)
+			rc.Close()
+		}
+
+		var manifest Manifest
+		require.NoError(t, json.Unmarshal(files[manifestName], &manifest))
+		assert.Equal(t, job.ID, manifest.JobID)
+		require.Len(t, manifest.Files, 2)
+		for i, file := range manifest.Files {
+			assert.Equal(t, job.Results[i], file.ResultID)
+			assert.Equal(t, fmt.Sprintf("%s#%d", docA.ID, i+1), file.Source)
+			assert.Equal(t, FileCreated, file.Status)
+			assert.Equal(t, service.Checksum(files[file.Name]), file.SHA256)
+			assert.Equal(t, int64(len(files[file.Name])), file.Size)
+		}
+		assert.Equal(t, "A/1", string(files[manifest.Files[0].Name]))
+	})
+
+	t.Run("Personalized Copies Are Made Per Recipient", func(t *testing.T) {
+		recipients := "Name,Email\nJane Doe,jane@example.com\nJohn Roe,john@example.com\n"
+		job := run([]string{docA.ID}, Step{Operation: "personalize",
+			Params: map[string]string{"recipients": recipients, "text": "Confidential: {name} <{email}>"}})
+		require.Equal(t, StatusSucceeded, job.Status)
+		require.Len(t, job.Results, 2)
+		assert.NotEmpty(t, job.Archive)
+		assert.Equal(t, "A[Confidential: Jane Doe <jane@example.com>]", string(read(job.Results[0])))
+		assert.Equal(t, "A[Confidential: John Roe <john@example.com>]", string(read(job.Results[1])))
+
+		job = run([]string{docA.ID}, Step{Operation: "personalize", Params: map[string]string{"recipients": "email\nceo@example.com"}})
+		require.Equal(t, StatusSucceeded, job.Status)
+		assert.Equal(t, "A[Prepared for ceo@example.com]", string(read(job.Results[0])))
+	})
+
+	t.Run("Mail Merge Fills A Copy Per Row", func(t *testing.T) {
+		job := run([]string{docA.ID}, Step{Operation: "mailmerge", Params: map[string]string{"data": "name,city\nJane,Oslo\nJohn,Rome\n"}})
+		require.Equal(t, StatusSucceeded, job.Status)
+		require.Len(t, job.Results, 2)
+		assert.Equal(t, "A{Jane}", string(read(job.Results[0])))
+		assert.Equal(t, "A{John}", string(read(job.Results[1])))
+		assert.Equal(t, &Progress{Done: 2, Total: 2}, job.Progress)
+
+		job = run([]string{docA.ID}, Step{Operation: "mailmerge",
+			Params: map[string]string{"data": `[{"name": "Jane", "age": 41}, {"name": "John", "member": true}]`, "combine": "true"}})
+		require.Equal(t, StatusSucceeded, job.Status)
+		require.Len(t, job.Results, 1)
+		assert.Equal(t, "A{Jane}+A{John}", string(read(job.Results[0])))
+
+		job = run([]string{docA.ID}, Step{Operation: "mailmerge", Params: map[string]string{"data": "title\nCEO"}})
+		require.Equal(t, StatusFailed, job.Status)
+		assert.Equal(t, service.ErrCodeInvalidInput, job.Failure.Code)
+		assert.Equal(t, "row 1: no form field is named by the values", job.Failure.Error)
+	})
+
+	t.Run("Failure Rolls Back And Reports Step And Input", func(t *testing.T) {
+		job := run([]string{docA.ID, docB.ID},
+			Step{Operation: "split"}, Step{Operation: "compress"}, Step{Operation: "merge"})
+		require.Equal(t, StatusFailed, job.Status)
+		assert.Empty(t, job.Results)
+		require.NotNil(t, job.Failure)
+		assert.Equal(t, 2, job.Failure.Step)
+		assert.Equal(t, "compress", job.Failure.Operation)
+		assert.Equal(t, 3, job.Failure.Input)
+		assert.Equal(t, docB.ID+"#2", job.Failure.InputName)
+		assert.Equal(t, service.ErrCodeCorrupted, job.Failure.Code)
+		assert.Equal(t, 0, staged())
+	})
+
+	t.Run("Missing Document Fails First Step", func(t *testing.T) {
+		job := run([]string{docA.ID, "0000000000000000000000000000000000000000000000000000000000000000.pdf"},
+			Step{Operation: "compress"})
+		require.NotNil(t, job.Failure)
+		assert.Equal(t, 1, job.Failure.Step)
+		assert.Equal(t, 1, job.Failure.Input)
+		assert.Equal(t, service.ErrCodeNotFound, job.Failure.Code)
+		assert.Equal(t, 0, staged())
+	})
+
+	t.Run("Invalid Steps Are Rejected", func(t *testing.T) {
+		_, err := m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "rotate"}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, err = m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "compress", Params: map[string]string{"level": "high"}}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, err = m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "personalize", Params: map[string]string{"recipients": "title\nCEO"}}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, err = m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "mailmerge", Params: map[string]string{"data": `[{"name": {"first": "Jane"}}]`}}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, err = m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "mailmerge", Params: map[string]string{"data": "name\nJane", "combine": "yes"}}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("Delivery Needs A Deliverer", func(t *testing.T) {
+		_, err := m.Submit(ctx, Submission{Documents: []string{docA.ID}, Steps: []Step{{Operation: "merge"}},
+			Delivery: &Delivery{Email: []string{"ops@example.com"}}})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("Transient Failures Retry Then Dead-Letter", func(t *testing.T) {
+		watermark := Step{Operation: "watermark", Params: map[string]string{"text": "draft"}}
+		job := run([]string{docB.ID}, watermark)
+		assert.Equal(t, StatusRetrying, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.NotNil(t, job.NextAttemptAt)
+		assert.Nil(t, job.DeadLetteredAt)
+
+		job = next(job.ID)
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.Equal(t, 2, job.Attempts)
+		assert.NotNil(t, job.DeadLetteredAt)
+		assert.Equal(t, service.ErrCodeInternal, job.Failure.Code)
+
+		letters, err := m.DeadLetters(ctx)
+		require.NoError(t, err)
+		require.Len(t, letters, 1)
+		assert.Equal(t, job.ID, letters[0].ID)
+
+		requeued, err := m.Requeue(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, requeued.Status)
+		assert.Equal(t, 1, next(job.ID).Attempts)
+		assert.Len(t, m.takeRetries(), 1)
+	})
+
+	t.Run("Permanent Failures Are Dead-Lettered Without Retry", func(t *testing.T) {
+		job := run([]string{docB.ID}, Step{Operation: "split"}, Step{Operation: "compress"})
+		assert.Equal(t, StatusFailed, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+		assert.NotNil(t, job.DeadLetteredAt)
+	})
+
+	t.Run("Jobs Are Tenant Scoped", func(t *testing.T) {
+		job := run([]string{docA.ID}, Step{Operation: "compress"})
+		_, err := m.Get(context.Background(), job.ID)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+	})
+
+	t.Run("Jobs Are Listed By Tenant And Status", func(t *testing.T) {
+		succeeded, err := m.List(ctx, Filter{Tenant: "acme", Status: StatusSucceeded})
+		require.NoError(t, err)
+		assert.NotEmpty(t, succeeded)
+		for _, job := range succeeded {
+			assert.Equal(t, StatusSucceeded, job.Status)
+		}
+
+		other, err := m.List(ctx, Filter{Tenant: "globex"})
+		require.NoError(t, err)
+		assert.Empty(t, other)
+	})
+}
+
+// controlProcessor runs hook on every compressed input before compressing
+// it like fakeProcessor
+type controlProcessor struct {
+	fakeProcessor
+	compressed int
+	hook       func(ctx context.Context) error
+}
+
+func (p *controlProcessor) CompressPDF(ctx context.Context, req *service.CompressRequest) ([]byte, error) {
+	p.compressed++
+	if err := p.hook(ctx); err != nil {
+		return nil, err
+	}
+	return p.fakeProcessor.CompressPDF(ctx, req)
+}
+
+func TestControl(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	log := logger.New("info", "text")
+	documents := service.NewDocumentService(store, nil, log)
+	results := service.NewResultService(store, nil, log)
+	cfg := config.BatchConfig{Workers: 1, QueueSize: 10, TenantQueueSize: 10, InteractiveRatio: 1, MaxSteps: 5, MaxDocuments: 5, JobTTL: 60, JobTimeout: 60, MaxAttempts: 2, RetryDelay: 1, MaxRetryDelay: 1}
+	processor := &controlProcessor{hook: func(context.Context) error { return nil }}
+	m := NewManager(cfg, nil, NewMemoryStore(time.Hour), processor, store, documents, results, log)
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	docA, err := documents.Save(ctx, []byte("A"))
+	require.NoError(t, err)
+	docC, err := documents.Save(ctx, []byte("C"))
+	require.NoError(t, err)
+
+	submit := func() *Job {
+		processor.compressed = 0
+		job, err := m.Submit(ctx, Submission{Documents: []string{docA.ID, docC.ID}, Steps: []Step{{Operation: "compress"}}})
+		require.NoError(t, err)
+		return job
+	}
+	next := func(id string) *Job {
+		<-m.pending.ready
+		popped, _ := m.pending.pop()
+		m.process(context.Background(), popped)
+		job, err := m.Get(ctx, id)
+		require.NoError(t, err)
+		return job
+	}
+	staged := func() int {
+		count := 0
+		require.NoError(t, store.List(ctx, stagingPrefix, func(storage.Info) error {
+			count++
+			return nil
+		}))
+		return count
+	}
+
+	t.Run("Queued Jobs Are Canceled At Once", func(t *testing.T) {
+		job := submit()
+		canceled, err := m.Cancel(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusCanceled, canceled.Status)
+		assert.NotNil(t, canceled.FinishedAt)
+		_, ok := m.pending.pop()
+		assert.False(t, ok)
+
+		_, err = m.Cancel(ctx, job.ID)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("In-Flight Step Is Interrupted And Rolled Back", func(t *testing.T) {
+		started := make(chan struct{})
+		processor.hook = func(ctx context.Context) error {
+			close(started)
+			<-ctx.Done()
+			return ctx.Err()
+		}
+		defer func() { processor.hook = func(context.Context) error { return nil } }()
+
+		job := submit()
+		done := make(chan *Job)
+		go func() { done <- next(job.ID) }()
+		<-started
+
+		running, err := m.Cancel(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusRunning, running.Status)
+
+		job = <-done
+		assert.Equal(t, StatusCanceled, job.Status)
+		require.NotNil(t, job.Failure)
+		assert.Equal(t, 1, job.Failure.Step)
+		assert.Equal(t, 0, job.Failure.Input)
+		assert.Equal(t, service.ErrCodeCanceled, job.Failure.Code)
+		assert.Nil(t, job.DeadLetteredAt)
+		assert.Empty(t, job.Results)
+		assert.Equal(t, 0, staged())
+	})
+
+	t.Run("Cancellation Stops Before The Next Input", func(t *testing.T) {
+		var id string
+		processor.hook = func(context.Context) error {
+			// Work that cannot be interrupted completes, but the job stops
+			// before its next input
+			_, err := m.Cancel(ctx, id)
+			return err
+		}
+		defer func() { processor.hook = func(context.Context) error { return nil } }()
+
+		id = submit().ID
+		job := next(id)
+		assert.Equal(t, StatusCanceled, job.Status)
+		assert.Equal(t, 1, processor.compressed)
+		require.NotNil(t, job.Failure)
+		assert.Equal(t, 1, job.Failure.Input)
+		assert.Equal(t, 0, staged())
+	})
+
+	t.Run("Paused Jobs Run Once Resumed", func(t *testing.T) {
+		job := submit()
+		paused, err := m.Pause(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusPaused, paused.Status)
+		assert.NotNil(t, paused.PausedAt)
+		_, ok := m.pending.pop()
+		assert.False(t, ok)
+
+		_, err = m.Pause(ctx, job.ID)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+
+		resumed, err := m.Resume(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusQueued, resumed.Status)
+		assert.Nil(t, resumed.PausedAt)
+		assert.Equal(t, StatusSucceeded, next(job.ID).Status)
+
+		_, err = m.Resume(ctx, job.ID)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("Running Jobs Pause Without Using An Attempt", func(t *testing.T) {
+		var id string
+		processor.hook = func(context.Context) error {
+			_, err := m.Pause(ctx, id)
+			return err
+		}
+
+		id = submit().ID
+		job := next(id)
+		processor.hook = func(context.Context) error { return nil }
+		assert.Equal(t, StatusPaused, job.Status)
+		assert.Equal(t, 0, job.Attempts)
+		assert.Nil(t, job.Failure)
+		assert.Equal(t, 0, staged())
+
+		_, err := m.Resume(ctx, id)
+		require.NoError(t, err)
+		job = next(id)
+		assert.Equal(t, StatusSucceeded, job.Status)
+		assert.Equal(t, 1, job.Attempts)
+	})
+
+	t.Run("Jobs Of Other Tenants Cannot Be Controlled", func(t *testing.T) {
+		job := submit()
+		_, err := m.Cancel(tenant.WithID(context.Background(), "globex"), job.ID)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+		_, err = m.Cancel(ctx, job.ID)
+		require.NoError(t, err)
+	})
+}
+
+func TestRestore(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	log := logger.New("info", "text")
+	documents := service.NewDocumentService(store, nil, log)
+	results := service.NewResultService(store, nil, log)
+	cfg := config.BatchConfig{Workers: 1, QueueSize: 10, TenantQueueSize: 10, InteractiveRatio: 1, MaxSteps: 5, MaxDocuments: 5,
+		JobTimeout: 60, JobTTL: 60, MaxAttempts: 2, RetryDelay: 1, MaxRetryDelay: 1, InstanceID: "pdf-tool-0"}
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	doc, err := documents.Save(ctx, []byte("A"))
+	require.NoError(t, err)
+
+	// The first manager stops without draining: one job waiting, one
+	// interrupted after staging an output
+	jobs := NewMemoryStore(time.Hour)
+	first := NewManager(cfg, nil, jobs, fakeProcessor{}, store, documents, results, log)
+	waiting, err := first.Submit(ctx, Submission{Documents: []string{doc.ID}, Steps: []Step{{Operation: "compress"}}})
+	require.NoError(t, err)
+	interrupted, err := first.Submit(ctx, Submission{Documents: []string{doc.ID}, Steps: []Step{{Operation: "split"}, {Operation: "merge"}}})
+	require.NoError(t, err)
+	interrupted.Status = StatusRunning
+	interrupted.Step = 2
+	require.NoError(t, jobs.Update(ctx, interrupted))
+	_, err = store.Put(ctx, stagingPrefix+interrupted.ID+"/1-0.pdf", bytes.NewReader([]byte("A/1")), "application/pdf")
+	require.NoError(t, err)
+
+	// Jobs of other instances are left alone
+	other := *waiting
+	other.ID = "other-instance-job"
+	other.Owner = "pdf-tool-1"
+	require.NoError(t, jobs.Create(ctx, &other))
+
+	second := NewManager(cfg, nil, jobs, fakeProcessor{}, store, documents, results, log)
+	second.restore(context.Background())
+
+	job, err := second.Get(ctx, interrupted.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusFailed, job.Status)
+	assert.NotNil(t, job.DeadLetteredAt)
+	require.NotNil(t, job.Failure)
+	assert.Equal(t, 2, job.Failure.Step)
+	assert.Equal(t, "merge", job.Failure.Operation)
+	assert.Equal(t, service.ErrCodeCanceled, job.Failure.Code)
+	_, err = store.Stat(ctx, stagingPrefix+interrupted.ID+"/1-0.pdf")
+	assert.ErrorIs(t, err, storage.ErrNotFound)
+
+	<-second.pending.ready
+	id, ok := second.pending.pop()
+	require.True(t, ok)
+	assert.Equal(t, waiting.ID, id)
+	_, ok = second.pending.pop()
+	assert.False(t, ok)
+
+	second.process(context.Background(), id)
+	job, err = second.Get(ctx, waiting.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusSucceeded, job.Status)
+}
+
+func TestHold(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	log := logger.New("info", "text")
+	documents := service.NewDocumentService(store, nil, log)
+	results := service.NewResultService(store, nil, log)
+	cfg := config.BatchConfig{Workers: 1, QueueSize: 10, TenantQueueSize: 10, InteractiveRatio: 1, MaxSteps: 5, MaxDocuments: 5, JobTimeout: 60, JobTTL: 60, MaxAttempts: 2, RetryDelay: 1, MaxRetryDelay: 1}
+	m := NewManager(cfg, nil, NewMemoryStore(time.Hour), fakeProcessor{}, store, documents, results, log)
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	doc, err := documents.Save(ctx, []byte("A"))
+	require.NoError(t, err)
+
+	require.NoError(t, m.Hold(context.Background()))
+	assert.False(t, m.Dispatching())
+
+	workerCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	m.Start(workerCtx)
+
+	job, err := m.Submit(ctx, Submission{Documents: []string{doc.ID}, Steps: []Step{{Operation: "compress"}}})
+	require.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	job, err = m.Get(ctx, job.ID)
+	require.NoError(t, err)
+	assert.Equal(t, StatusQueued, job.Status)
+	assert.Equal(t, 1, m.pending.len())
+
+	m.Release()
+	assert.True(t, m.Dispatching())
+	assert.Eventually(t, func() bool {
+		job, err := m.Get(ctx, job.ID)
+		return err == nil && job.Status == StatusSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+}
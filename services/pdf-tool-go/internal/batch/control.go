@@ -0,0 +1,204 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// Causes of a running job being stopped on request
+var (
+	errCanceled = errors.New("job canceled")
+	errPaused   = errors.New("job paused")
+)
+
+// activeJob is a running job
+type activeJob struct {
+	stop context.CancelCauseFunc
+	// requested is the cause the job was stopped with, if any
+	requested error
+}
+
+// activate marks a queued job as running and registers stop to interrupt
+// it. It returns false for jobs canceled or paused after they were taken
+// off the queue.
+func (m *Manager) activate(ctx context.Context, id string, stop context.CancelCauseFunc) (*Job, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.jobs.Get(ctx, id)
+	if err != nil {
+		m.log.Error("Failed to load batch job", "job_id", id, "error", err)
+		return nil, false
+	}
+	if job.Status != StatusQueued {
+		m.log.Info("Skipping batch job no longer queued", "job_id", id, "status", job.Status)
+		return nil, false
+	}
+
+	now := time.Now()
+	job.Status = StatusRunning
+	job.StartedAt = &now
+	job.Attempts++
+	m.update(ctx, job)
+	m.active[id] = &activeJob{stop: stop}
+	return job, true
+}
+
+// deactivate unregisters a running job and returns the cause it was
+// stopped with on request, if any
+func (m *Manager) deactivate(id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	active := m.active[id]
+	delete(m.active, id)
+	if active == nil {
+		return nil
+	}
+	return active.requested
+}
+
+// Cancel stops a job of the tenant of ctx. Waiting and paused jobs are
+// canceled at once. A running job is interrupted between inputs and rolled
+// back, and is reported canceled once it has stopped; the job returned is
+// still running then. A job that completes before noticing keeps its
+// outcome.
+func (m *Manager) Cancel(ctx context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.controllable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if active, ok := m.active[id]; ok {
+		active.requested = errCanceled
+		active.stop(errCanceled)
+		m.log.Info("Canceling running batch job", "job_id", id)
+		return job, nil
+	}
+
+	m.dequeue(job)
+	m.cancel(ctx, job, nil)
+	return job, nil
+}
+
+// Pause takes a job of the tenant of ctx off the queue until Resume is
+// called. A running job is interrupted and rolled back like a canceled one
+// and reported paused once it has stopped; on resume it runs again from
+// its first step.
+func (m *Manager) Pause(ctx context.Context, id string) (*Job, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.controllable(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status == StatusPaused {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "job is already paused", nil)
+	}
+	if active, ok := m.active[id]; ok {
+		active.requested = errPaused
+		active.stop(errPaused)
+		m.log.Info("Pausing running batch job", "job_id", id)
+		return job, nil
+	}
+
+	m.dequeue(job)
+	m.park(ctx, job)
+	return job, nil
+}
+
+// Resume queues a paused job of the tenant of ctx again
+func (m *Manager) Resume(ctx context.Context, id string) (*Job, error) {
+	select {
+	case <-m.stopping:
+		return nil, service.NewError(service.ErrCodeBusy, "server is shutting down", nil)
+	default:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Status != StatusPaused {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "job is not paused", nil)
+	}
+
+	job.Status = StatusQueued
+	job.Step = 0
+	job.Progress = nil
+	job.Failure = nil
+	job.PausedAt = nil
+	job.Owner = m.owner
+	if !m.pending.push(job) {
+		return nil, service.NewError(service.ErrCodeBusy, "batch queue is full", nil)
+	}
+	m.update(ctx, job)
+
+	m.log.Info("Batch job resumed", "job_id", job.ID)
+	return job, nil
+}
+
+// controllable returns a job of the tenant of ctx that can still be
+// stopped. The caller holds m.mu.
+func (m *Manager) controllable(ctx context.Context, id string) (*Job, error) {
+	job, err := m.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Finished() {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "job has already finished", nil)
+	}
+	if _, ok := m.active[id]; !ok && job.Status == StatusRunning {
+		// Between the end of the run and recording its outcome
+		err := service.NewError(service.ErrCodeBusy, "job is finishing, retry shortly", nil)
+		err.RetryAfter = 1
+		return nil, err
+	}
+	return job, nil
+}
+
+// dequeue takes a job off the queue or cancels its pending retry. The
+// caller holds m.mu.
+func (m *Manager) dequeue(job *Job) {
+	if timer, ok := m.retries[job.ID]; ok {
+		timer.Stop()
+		delete(m.retries, job.ID)
+	}
+	m.pending.remove(job)
+}
+
+// cancel records a job as canceled. failure reports where a running job
+// was interrupted.
+func (m *Manager) cancel(ctx context.Context, job *Job, failure *Failure) {
+	now := time.Now()
+	job.Status = StatusCanceled
+	job.FinishedAt = &now
+	job.Failure = failure
+	job.NextAttemptAt = nil
+	job.PausedAt = nil
+	jobsTotal.WithLabelValues(string(job.Status)).Inc()
+	m.update(ctx, job)
+	m.notify(ctx, job)
+
+	m.log.Info("Batch job canceled", "job_id", job.ID, "tenant", job.Tenant, "step", job.Step)
+}
+
+// park records a job as paused
+func (m *Manager) park(ctx context.Context, job *Job) {
+	now := time.Now()
+	job.Status = StatusPaused
+	job.PausedAt = &now
+	job.NextAttemptAt = nil
+	m.update(ctx, job)
+
+	m.log.Info("Batch job paused", "job_id", job.ID, "step", job.Step)
+}
@@ -0,0 +1,127 @@
+package batch
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// deadLetterPath is the admin path of the dead-letter queue
+const deadLetterPath = "/admin/batch/dead-letters"
+
+// deadLetter is a dead-lettered job as listed to operators, who see jobs
+// of every tenant
+type deadLetter struct {
+	Tenant string `json:"tenant"`
+	*Job
+}
+
+// deadLetter marks job as permanently failed
+func (m *Manager) deadLetter(ctx context.Context, job *Job, failure *Failure) {
+	now := time.Now()
+	job.DeadLetteredAt = &now
+	job.NextAttemptAt = nil
+	m.finish(ctx, job, nil, failure)
+}
+
+// DeadLetters returns the dead-lettered jobs of all tenants, oldest first
+func (m *Manager) DeadLetters(ctx context.Context) ([]*Job, error) {
+	return m.jobs.List(ctx, Filter{DeadLettered: true})
+}
+
+// Requeue runs a dead-lettered job again from the start, with a fresh
+// attempt count
+func (m *Manager) Requeue(ctx context.Context, id string) (*Job, error) {
+	select {
+	case <-m.stopping:
+		return nil, service.NewError(service.ErrCodeBusy, "server is shutting down", nil)
+	default:
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	job, err := m.jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.DeadLetteredAt == nil {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "job is not dead-lettered", nil)
+	}
+
+	job.Status = StatusQueued
+	job.Step = 0
+	job.Progress = nil
+	job.Attempts = 0
+	job.Failure = nil
+	job.Results = nil
+	job.Archive = ""
+	job.StartedAt = nil
+	job.FinishedAt = nil
+	job.DeadLetteredAt = nil
+	job.Owner = m.owner
+	if !m.pending.push(job) {
+		return nil, service.NewError(service.ErrCodeBusy, "batch queue is full", nil)
+	}
+	m.update(ctx, job)
+
+	m.log.Info("Dead-lettered batch job requeued", "job_id", job.ID, "tenant", job.Tenant)
+	return job, nil
+}
+
+// DeadLetterHandler serves the dead-letter queue on the admin listener at
+// /admin/batch/dead-letters/: GET lists dead-lettered jobs and
+// POST <id>/requeue runs one again
+func (m *Manager) DeadLetterHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, deadLetterPath), "/")
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			jobs, err := m.DeadLetters(r.Context())
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			letters := make([]deadLetter, len(jobs))
+			for i, job := range jobs {
+				letters[i] = deadLetter{Tenant: job.Tenant, Job: job}
+			}
+			writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": letters})
+
+		case strings.HasSuffix(rest, "/requeue") && r.Method == http.MethodPost:
+			job, err := m.Requeue(r.Context(), strings.TrimSuffix(rest, "/requeue"))
+			if err != nil {
+				writeAdminError(w, err)
+				return
+			}
+			writeJSON(w, http.StatusAccepted, deadLetter{Tenant: job.Tenant, Job: job})
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeAdminError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch service.CodeOf(err) {
+	case service.ErrCodeNotFound:
+		status = http.StatusNotFound
+	case service.ErrCodeInvalidInput:
+		status = http.StatusConflict
+	case service.ErrCodeBusy:
+		status = http.StatusServiceUnavailable
+	}
+	http.Error(w, err.Error(), status)
+}
@@ -0,0 +1,67 @@
+package batch
+
+import (
+	"context"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+var deliveriesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_batch_deliveries_total",
+	Help: "Deliveries of batch job results by outcome",
+}, []string{"outcome"})
+
+// Delivery requests that the results of a job are sent out when it
+// succeeds
+type Delivery struct {
+	// Email lists the addresses the results are mailed to
+	Email []string `json:"email,omitempty"`
+}
+
+// Deliverer sends the results of succeeded jobs that request delivery
+type Deliverer interface {
+	// Validate rejects deliveries the deliverer cannot make
+	Validate(delivery *Delivery) error
+	// Deliver sends the results of job
+	Deliver(ctx context.Context, job *Job) error
+}
+
+// SetDeliverer delivers the results of jobs requesting delivery through
+// deliverer. Without one, such jobs are rejected. It must be called before
+// Start.
+func (m *Manager) SetDeliverer(deliverer Deliverer) {
+	m.deliverer = deliverer
+}
+
+// validateDelivery checks the delivery requested with a submission
+func (m *Manager) validateDelivery(delivery *Delivery) error {
+	if delivery == nil {
+		return nil
+	}
+	if m.deliverer == nil {
+		return service.NewError(service.ErrCodeInvalidInput, "result delivery is not enabled", nil)
+	}
+	return m.deliverer.Validate(delivery)
+}
+
+// deliver sends the results of a succeeded job in the background. Draining
+// waits for deliveries like for running jobs.
+func (m *Manager) deliver(ctx context.Context, job *Job) {
+	if job.Delivery == nil || m.deliverer == nil {
+		return
+	}
+
+	done := m.running.Start()
+	go func() {
+		defer done()
+		if err := m.deliverer.Deliver(context.WithoutCancel(ctx), job); err != nil {
+			deliveriesTotal.WithLabelValues("failed").Inc()
+			m.log.Error("Failed to deliver batch job results", "job_id", job.ID, "error", err)
+			return
+		}
+		deliveriesTotal.WithLabelValues("delivered").Inc()
+		m.log.Info("Batch job results delivered", "job_id", job.ID)
+	}()
+}
@@ -0,0 +1,152 @@
+package batch
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// maxRows bounds the dataset of a mailmerge step, each row of which gets
+// its own copy of every input
+const maxRows = 1000
+
+// parseDataset reads the dataset of a mailmerge step: a JSON array of
+// objects, or CSV whose first row names the columns. Columns name form
+// fields, so their case is kept.
+func parseDataset(data string) ([]map[string]string, error) {
+	trimmed := strings.TrimSpace(data)
+	if strings.HasPrefix(trimmed, "[") {
+		return parseJSONDataset(trimmed)
+	}
+
+	r := csv.NewReader(strings.NewReader(data))
+	r.TrimLeadingSpace = true
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid dataset CSV: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, fmt.Errorf("dataset needs a header row and at least one row")
+	}
+	if len(records)-1 > maxRows {
+		return nil, fmt.Errorf("at most %d dataset rows are supported", maxRows)
+	}
+
+	rows := make([]map[string]string, len(records)-1)
+	for i, record := range records[1:] {
+		row := make(map[string]string, len(records[0]))
+		for j, column := range records[0] {
+			row[strings.TrimSpace(column)] = strings.TrimSpace(record[j])
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// parseJSONDataset reads a dataset given as a JSON array of objects whose
+// values are strings, numbers, booleans or null
+func parseJSONDataset(data string) ([]map[string]string, error) {
+	var objects []map[string]interface{}
+	if err := json.Unmarshal([]byte(data), &objects); err != nil {
+		return nil, fmt.Errorf("invalid dataset JSON: %w", err)
+	}
+	if len(objects) == 0 {
+		return nil, fmt.Errorf("dataset needs at least one row")
+	}
+	if len(objects) > maxRows {
+		return nil, fmt.Errorf("at most %d dataset rows are supported", maxRows)
+	}
+
+	rows := make([]map[string]string, len(objects))
+	for i, object := range objects {
+		row := make(map[string]string, len(object))
+		for column, value := range object {
+			switch v := value.(type) {
+			case string:
+				row[column] = v
+			case float64:
+				row[column] = strconv.FormatFloat(v, 'f', -1, 64)
+			case bool:
+				row[column] = strconv.FormatBool(v)
+			case nil:
+				row[column] = ""
+			default:
+				return nil, fmt.Errorf("dataset row %d: %q must be a string, number, boolean or null", i+1, column)
+			}
+		}
+		rows[i] = row
+	}
+	return rows, nil
+}
+
+// mailMerge fills a copy of each input form for every row of the step's
+// dataset. With combine, the copies of each input are merged into one
+// document; otherwise each copy is an output named by its row.
+func (tx *transaction) mailMerge(ctx context.Context, step Step, inputs []artifact) ([]artifact, *Failure) {
+	rows, err := parseDataset(step.Params["data"])
+	if err != nil {
+		return nil, stepFailure(service.NewError(service.ErrCodeInvalidInput, err.Error(), nil))
+	}
+	combine, _ := strconv.ParseBool(step.Params["combine"])
+
+	var outputs []artifact
+	for i, input := range inputs {
+		data, err := tx.load(ctx, input)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		var copies [][]byte
+		for k, row := range rows {
+			if err := ctx.Err(); err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+			filled, err := tx.m.processor.FillForm(ctx, &service.FillFormRequest{PDFData: data, Values: row})
+			if err != nil {
+				return nil, inputFailure(i, input, rowError(err, k+1))
+			}
+			if combine {
+				copies = append(copies, filled)
+			} else {
+				output, err := tx.stage(ctx, filled, fmt.Sprintf("%s#%d", input.name, k+1))
+				if err != nil {
+					return nil, inputFailure(i, input, err)
+				}
+				outputs = append(outputs, output)
+			}
+			tx.advance(ctx, i*len(rows)+k+1, len(inputs)*len(rows))
+		}
+		if !combine {
+			continue
+		}
+
+		combined := copies[0]
+		if len(copies) > 1 {
+			if combined, err = tx.m.processor.MergePDFs(ctx, &service.MergeRequest{PDFs: copies}); err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+		}
+		output, err := tx.stage(ctx, combined, input.name)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		outputs = append(outputs, output)
+	}
+	return outputs, nil
+}
+
+// rowError attributes a failure to fill a form to a dataset row
+func rowError(err error, row int) error {
+	var pdfErr *service.PDFError
+	if !errors.As(err, &pdfErr) {
+		return fmt.Errorf("row %d: %w", row, err)
+	}
+	attributed := *pdfErr
+	attributed.Message = fmt.Sprintf("row %d: %s", row, pdfErr.Message)
+	return &attributed
+}
@@ -0,0 +1,329 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Processor runs the PDF operations of job steps; *service.PDFService
+// implements it
+type Processor interface {
+	SplitPDF(ctx context.Context, req *service.SplitRequest) ([][]byte, error)
+	MergePDFs(ctx context.Context, req *service.MergeRequest) ([]byte, error)
+	CompressPDF(ctx context.Context, req *service.CompressRequest) ([]byte, error)
+	AddWatermark(ctx context.Context, req *service.WatermarkRequest) ([]byte, error)
+	FillForm(ctx context.Context, req *service.FillFormRequest) ([]byte, error)
+}
+
+// Manager queues jobs and runs them on a pool of workers
+type Manager struct {
+	cfg       config.BatchConfig
+	jobs      Store
+	processor Processor
+	staging   storage.Store
+	documents *service.DocumentService
+	results   *service.ResultService
+	log       logger.Logger
+	// owner identifies this instance on the jobs it queues
+	owner string
+	// tenants resolves the settings jobs run with; nil runs them without
+	tenants *tenant.Resolver
+	// deliverer sends the results of jobs requesting delivery; nil rejects
+	// such jobs
+	deliverer Deliverer
+	// notifier is told about finished jobs; nil tells no one
+	notifier Notifier
+
+	pending  *scheduler
+	stopping chan struct{}
+	// mu guards retries, the timers of jobs waiting to be retried, and
+	// active, the running jobs. It also orders job state changes: jobs are
+	// started, canceled, paused and resumed under it.
+	mu      sync.Mutex
+	retries map[string]*time.Timer
+	active  map[string]*activeJob
+	// dispatch is closed while workers take jobs off the queue; it is
+	// replaced by an open channel while they are held. Guarded by mu.
+	dispatch chan struct{}
+	stopOnce sync.Once
+	running  lifecycle.Tracker
+	// abort cancels running jobs when draining runs out of time
+	abort context.CancelFunc
+}
+
+// NewManager creates a job manager. Tenants are scheduled by their batch
+// weight. Intermediate outputs are staged in staging; final outputs are
+// saved as results.
+func NewManager(cfg config.BatchConfig, tenants []config.TenantConfig, jobs Store, processor Processor, staging storage.Store,
+	documents *service.DocumentService, results *service.ResultService, log logger.Logger) *Manager {
+	owner := cfg.InstanceID
+	if owner == "" {
+		owner, _ = os.Hostname()
+	}
+	return &Manager{
+		cfg:       cfg,
+		jobs:      jobs,
+		processor: processor,
+		staging:   staging,
+		documents: documents,
+		results:   results,
+		log:       log,
+		owner:     owner,
+		pending:   newScheduler(cfg, tenants),
+		retries:   make(map[string]*time.Timer),
+		active:    make(map[string]*activeJob),
+		dispatch:  closedChannel(),
+		stopping:  make(chan struct{}),
+		abort:     func() {},
+	}
+}
+
+// SetResolver resolves the settings of job tenants, such as their watermark
+// preset, through tenants. It must be called before Start.
+func (m *Manager) SetResolver(tenants *tenant.Resolver) {
+	m.tenants = tenants
+}
+
+// Owner returns the name identifying this instance on the jobs it queues
+func (m *Manager) Owner() string {
+	return m.owner
+}
+
+// Start picks up the jobs this instance left behind when it last stopped
+// without draining, then runs the workers until ctx is done or Drain is
+// called
+func (m *Manager) Start(ctx context.Context) {
+	ctx, m.abort = context.WithCancel(ctx)
+	m.restore(ctx)
+	for i := 0; i < m.cfg.Workers; i++ {
+		go m.work(ctx)
+	}
+}
+
+// Submit validates and queues a job over stored documents for the tenant
+// of ctx
+func (m *Manager) Submit(ctx context.Context, sub Submission) (*Job, error) {
+	if sub.Priority == "" {
+		sub.Priority = PriorityBulk
+	}
+	if err := Validate(m.cfg, sub); err != nil {
+		return nil, err
+	}
+	if err := m.validateDelivery(sub.Delivery); err != nil {
+		return nil, err
+	}
+	select {
+	case <-m.stopping:
+		return nil, service.NewError(service.ErrCodeBusy, "server is shutting down", nil)
+	default:
+	}
+
+	job := &Job{
+		ID:           uuid.New().String(),
+		Tenant:       tenant.FromContext(ctx),
+		Status:       StatusQueued,
+		Priority:     sub.Priority,
+		Documents:    sub.Documents,
+		Steps:        sub.Steps,
+		Delivery:     sub.Delivery,
+		CreatedAt:    time.Now(),
+		TraceContext: telemetry.InjectTraceContext(ctx),
+		Owner:        m.owner,
+	}
+	if err := m.jobs.Create(ctx, job); err != nil {
+		return nil, fmt.Errorf("failed to create job: %w", err)
+	}
+
+	if !m.pending.push(job) {
+		busy := service.NewError(service.ErrCodeBusy, "batch queue is full", nil)
+		m.finish(ctx, job, nil, notStarted(busy))
+		return nil, busy
+	}
+
+	m.log.Info("Batch job queued", "job_id", job.ID, "priority", job.Priority,
+		"documents", len(job.Documents), "steps", len(job.Steps))
+	return job, nil
+}
+
+// Get returns a job of the tenant of ctx
+func (m *Manager) Get(ctx context.Context, id string) (*Job, error) {
+	job, err := m.jobs.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if job.Tenant != tenant.FromContext(ctx) {
+		return nil, service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	return job, nil
+}
+
+// List returns the jobs matching filter, oldest first. Callers scope the
+// filter to the tenants the caller may see.
+func (m *Manager) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	return m.jobs.List(ctx, filter)
+}
+
+// Drain stops taking jobs off the queue and waits for running jobs. Jobs
+// still running at the deadline are canceled and rolled back. Queued jobs
+// and jobs waiting for a retry are dead-lettered, since the queue is held
+// in memory.
+func (m *Manager) Drain(ctx context.Context) error {
+	m.stopOnce.Do(func() { close(m.stopping) })
+
+	err := m.running.Wait(ctx)
+	if err != nil {
+		m.log.Warn("Batch jobs still running at drain deadline, rolling back", "running", m.running.Count())
+		m.abort()
+		waitCtx, cancel := context.WithTimeout(context.Background(), rollbackTimeout)
+		m.running.Wait(waitCtx)
+		cancel()
+	}
+
+	for _, id := range append(m.takeRetries(), m.pending.drain()...) {
+		if job, getErr := m.jobs.Get(ctx, id); getErr == nil {
+			m.deadLetter(ctx, job, notStarted(service.NewError(service.ErrCodeBusy, "server shut down before the job ran", nil)))
+		}
+	}
+	return err
+}
+
+// work runs queued jobs until ctx is done or draining starts. While
+// workers are held they wait without taking jobs off the queue.
+func (m *Manager) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopping:
+			return
+		case <-m.dispatching():
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-m.stopping:
+			return
+		case <-m.pending.ready:
+			if !m.Dispatching() {
+				// Held while waiting; leave the job for when workers resume
+				m.pending.ready <- struct{}{}
+				continue
+			}
+			if id, ok := m.pending.pop(); ok {
+				m.process(ctx, id)
+			}
+		}
+	}
+}
+
+// process runs one job in its own trace, linked to the submitting request
+func (m *Manager) process(ctx context.Context, id string) {
+	done := m.running.Start()
+	defer done()
+
+	ctx, stop := context.WithCancelCause(ctx)
+	defer stop(nil)
+	job, ok := m.activate(ctx, id, stop)
+	if !ok {
+		return
+	}
+
+	ctx = tenant.WithSettings(tenant.WithID(ctx, job.Tenant), m.tenants.Settings(job.Tenant))
+	ctx, span := tracer.Start(ctx, "batch.job",
+		telemetry.LinkTraceContext(job.TraceContext),
+		trace.WithAttributes(attribute.String("job.id", job.ID), attribute.Int("job.steps", len(job.Steps))))
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(m.cfg.JobTimeout)*time.Second)
+	defer cancel()
+
+	tx := &transaction{m: m, job: job}
+	results, failure := tx.run(ctx)
+	if failure != nil && ctx.Err() != nil {
+		cause := context.Cause(ctx)
+		failure.Code, failure.Error = interrupted(cause)
+		failure.cause = cause
+	}
+	tx.cleanup(ctx, failure != nil)
+	requested := m.deactivate(job.ID)
+
+	var spanErr error
+	if failure != nil {
+		spanErr = errors.New(failure.Error)
+	}
+	telemetry.EndSpan(span, spanErr)
+
+	switch {
+	case failure == nil:
+		m.finish(ctx, job, results, nil)
+	case errors.Is(requested, errPaused):
+		// A paused attempt does not count against the job's retries
+		job.Attempts--
+		m.park(ctx, job)
+	case errors.Is(requested, errCanceled):
+		m.cancel(ctx, job, failure)
+	case m.retryable(job, failure):
+		m.retry(ctx, job, failure)
+	default:
+		m.deadLetter(ctx, job, failure)
+	}
+}
+
+// finish records the outcome of a job
+func (m *Manager) finish(ctx context.Context, job *Job, results []string, failure *Failure) {
+	now := time.Now()
+	job.FinishedAt = &now
+	job.Results = results
+	job.Failure = failure
+	job.NextAttemptAt = nil
+	job.Status = StatusSucceeded
+	if failure != nil {
+		job.Status = StatusFailed
+		m.log.Warn("Batch job failed", "job_id", job.ID, "step", failure.Step, "operation", failure.Operation,
+			"input", failure.Input, "input_name", failure.InputName, "code", failure.Code, "error", failure.cause)
+	} else {
+		m.log.Info("Batch job succeeded", "job_id", job.ID, "results", len(results))
+	}
+	jobsTotal.WithLabelValues(string(job.Status)).Inc()
+	m.update(ctx, job)
+	m.notify(ctx, job)
+	if failure == nil {
+		m.deliver(ctx, job)
+	}
+}
+
+// update writes job back to the store. It runs detached from ctx so the
+// outcome of a canceled job is still recorded.
+func (m *Manager) update(ctx context.Context, job *Job) {
+	if err := m.jobs.Update(context.WithoutCancel(ctx), job); err != nil {
+		m.log.Error("Failed to update batch job", "job_id", job.ID, "error", err)
+	}
+}
+
+// interrupted describes a job stopped by its deadline, on request or by
+// shutdown
+func interrupted(err error) (service.ErrorCode, string) {
+	switch {
+	case errors.Is(err, context.DeadlineExceeded):
+		return service.ErrCodeTimeout, "job exceeded its time limit"
+	case errors.Is(err, errCanceled):
+		return service.ErrCodeCanceled, "job canceled on request"
+	case errors.Is(err, errPaused):
+		return service.ErrCodeCanceled, "job paused"
+	}
+	return service.ErrCodeCanceled, "job canceled during shutdown"
+}
@@ -0,0 +1,40 @@
+CREATE TABLE batch_jobs (
+    id               TEXT        PRIMARY KEY,
+    tenant           TEXT        NOT NULL,
+    status           TEXT        NOT NULL,
+    priority         TEXT        NOT NULL,
+    step             INTEGER     NOT NULL DEFAULT 0,
+    archive          TEXT        NOT NULL DEFAULT '',
+    failure          TEXT,
+    attempts         INTEGER     NOT NULL DEFAULT 0,
+    trace_context    TEXT,
+    owner            TEXT        NOT NULL DEFAULT '',
+    created_at       TIMESTAMP   NOT NULL,
+    started_at       TIMESTAMP,
+    finished_at      TIMESTAMP,
+    next_attempt_at  TIMESTAMP,
+    dead_lettered_at TIMESTAMP,
+    paused_at        TIMESTAMP
+);
+
+CREATE INDEX batch_jobs_tenant_created ON batch_jobs (tenant, created_at);
+CREATE INDEX batch_jobs_owner_status ON batch_jobs (owner, status);
+CREATE INDEX batch_jobs_finished ON batch_jobs (finished_at) WHERE finished_at IS NOT NULL;
+
+-- Steps are fixed at submission
+CREATE TABLE batch_job_steps (
+    job_id    TEXT    NOT NULL REFERENCES batch_jobs (id) ON DELETE CASCADE,
+    position  INTEGER NOT NULL,
+    operation TEXT    NOT NULL,
+    params    TEXT,
+    PRIMARY KEY (job_id, position)
+);
+
+-- Artifacts are the documents a job reads and the results it produced
+CREATE TABLE batch_job_artifacts (
+    job_id   TEXT    NOT NULL REFERENCES batch_jobs (id) ON DELETE CASCADE,
+    kind     TEXT    NOT NULL CHECK (kind IN ('document', 'result')),
+    position INTEGER NOT NULL,
+    ref      TEXT    NOT NULL,
+    PRIMARY KEY (job_id, kind, position)
+);
@@ -0,0 +1,2 @@
+-- Delivery of results requested at submission
+ALTER TABLE batch_jobs ADD COLUMN delivery TEXT;
@@ -0,0 +1,2 @@
+-- Progress of the step a job is running, or last ran
+ALTER TABLE batch_jobs ADD COLUMN progress TEXT;
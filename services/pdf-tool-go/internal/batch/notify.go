@@ -0,0 +1,28 @@
+package batch
+
+import "context"
+
+// Notifier is told about jobs reaching a final status
+type Notifier interface {
+	Notify(ctx context.Context, job *Job)
+}
+
+// SetNotifier tells notifier about every job that succeeds, fails or is
+// canceled. It must be called before Start.
+func (m *Manager) SetNotifier(notifier Notifier) {
+	m.notifier = notifier
+}
+
+// notify tells the notifier about a finished job in the background.
+// Draining waits for notifications like for running jobs.
+func (m *Manager) notify(ctx context.Context, job *Job) {
+	if m.notifier == nil {
+		return
+	}
+
+	done := m.running.Start()
+	go func() {
+		defer done()
+		m.notifier.Notify(context.WithoutCancel(ctx), job)
+	}()
+}
@@ -0,0 +1,113 @@
+package batch
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// maxRecipients bounds the recipient list of a personalize step, each of
+// which gets its own copy of every input
+const maxRecipients = 1000
+
+// recipient is a row of a personalize step's recipient list by column
+type recipient map[string]string
+
+// parseRecipients reads the recipient list of a personalize step: CSV
+// whose first row names the columns, which must include name or email
+func parseRecipients(data string) ([]recipient, error) {
+	r := csv.NewReader(strings.NewReader(data))
+	r.TrimLeadingSpace = true
+	rows, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("invalid recipients CSV: %w", err)
+	}
+	if len(rows) < 2 {
+		return nil, fmt.Errorf("recipients need a header row and at least one recipient")
+	}
+	if len(rows)-1 > maxRecipients {
+		return nil, fmt.Errorf("at most %d recipients are supported", maxRecipients)
+	}
+
+	columns := make([]string, len(rows[0]))
+	for i, column := range rows[0] {
+		columns[i] = strings.ToLower(strings.TrimSpace(column))
+	}
+	if !contains(columns, "name") && !contains(columns, "email") {
+		return nil, fmt.Errorf("recipients need a name or email column")
+	}
+
+	recipients := make([]recipient, len(rows)-1)
+	for i, row := range rows[1:] {
+		rec := make(recipient, len(columns))
+		for j, column := range columns {
+			rec[column] = strings.TrimSpace(row[j])
+		}
+		recipients[i] = rec
+	}
+	return recipients, nil
+}
+
+// label identifies the n-th recipient in output names and default
+// watermarks: by email, else by name
+func (r recipient) label(n int) string {
+	if r["email"] != "" {
+		return r["email"]
+	}
+	if r["name"] != "" {
+		return r["name"]
+	}
+	return fmt.Sprintf("recipient %d", n)
+}
+
+// stamp fills the {column} placeholders of text with the recipient's
+// values
+func (r recipient) stamp(text string) string {
+	pairs := make([]string, 0, 2*len(r))
+	for column, value := range r {
+		pairs = append(pairs, "{"+column+"}", value)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// personalize watermarks a copy of each input for every recipient, with
+// the step's text filled from the recipient's columns, or "Prepared for"
+// the recipient
+func (tx *transaction) personalize(ctx context.Context, step Step, inputs []artifact) ([]artifact, *Failure) {
+	recipients, err := parseRecipients(step.Params["recipients"])
+	if err != nil {
+		return nil, stepFailure(service.NewError(service.ErrCodeInvalidInput, err.Error(), nil))
+	}
+
+	var outputs []artifact
+	for i, input := range inputs {
+		data, err := tx.load(ctx, input)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		for k, rec := range recipients {
+			if err := ctx.Err(); err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+			req := service.NewWatermarkRequest(ctx, data)
+			req.WatermarkText = "Prepared for " + rec.label(k+1)
+			if text := step.Params["text"]; text != "" {
+				req.WatermarkText = rec.stamp(text)
+			}
+			stamped, err := tx.m.processor.AddWatermark(ctx, req)
+			if err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+			output, err := tx.stage(ctx, stamped, fmt.Sprintf("%s#%s", input.name, rec.label(k+1)))
+			if err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+			outputs = append(outputs, output)
+			tx.advance(ctx, i*len(recipients)+k+1, len(inputs)*len(recipients))
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,77 @@
+package batch
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+)
+
+// restore picks up the jobs this instance left behind when it stopped
+// without draining, which only a persistent store still holds. Queued jobs
+// are queued again and pending retries rescheduled. Jobs that were running
+// are dead-lettered, since their attempt cannot be resumed; their staged
+// outputs are removed, but results already promoted are not known and are
+// left to retention. It runs before any job is submitted.
+func (m *Manager) restore(ctx context.Context) {
+	for _, status := range []Status{StatusRunning, StatusQueued, StatusRetrying} {
+		jobs, err := m.jobs.List(ctx, Filter{Owner: m.owner, Status: status})
+		if err != nil {
+			m.log.Error("Failed to list batch jobs to restore", "status", status, "error", err)
+			continue
+		}
+		for _, job := range jobs {
+			m.restoreJob(ctx, job)
+		}
+	}
+}
+
+// restoreJob resumes or dead-letters one job left behind
+func (m *Manager) restoreJob(ctx context.Context, job *Job) {
+	switch job.Status {
+	case StatusRunning:
+		m.removeStaged(ctx, job.ID)
+		failure := stepFailure(service.NewError(service.ErrCodeCanceled, "server stopped while the job ran", nil))
+		failure.Step = job.Step
+		if job.Step > 0 && job.Step <= len(job.Steps) {
+			failure.Operation = job.Steps[job.Step-1].Operation
+		}
+		m.deadLetter(ctx, job, failure)
+		return
+	case StatusQueued:
+		if !m.pending.push(job) {
+			m.deadLetter(ctx, job, notStarted(service.NewError(service.ErrCodeBusy, "batch queue was full on restart", nil)))
+			return
+		}
+	case StatusRetrying:
+		delay := time.Duration(0)
+		if job.NextAttemptAt != nil {
+			delay = time.Until(*job.NextAttemptAt)
+		}
+		m.scheduleRetry(job.ID, max(delay, 0))
+	}
+	m.log.Info("Batch job restored", "job_id", job.ID, "status", job.Status)
+}
+
+// removeStaged deletes the intermediate outputs of job id
+func (m *Manager) removeStaged(ctx context.Context, id string) {
+	ctx, cancel := context.WithTimeout(ctx, rollbackTimeout)
+	defer cancel()
+
+	var keys []string
+	err := m.staging.List(ctx, stagingPrefix+id+"/", func(info storage.Info) error {
+		keys = append(keys, info.Key)
+		return nil
+	})
+	if err != nil {
+		m.log.Warn("Failed to list staged batch outputs", "job_id", id, "error", err)
+		return
+	}
+	for _, key := range keys {
+		if err := m.staging.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			m.log.Warn("Failed to remove staged batch output", "job_id", id, "key", key, "error", err)
+		}
+	}
+}
@@ -0,0 +1,113 @@
+package batch
+
+import (
+	"context"
+	"math/rand"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+var retriesTotal = promauto.NewCounter(prometheus.CounterOpts{
+	Name: "pdf_tool_batch_retries_total",
+	Help: "Batch job attempts retried after a transient failure",
+})
+
+// Transient reports whether a failure with code may succeed when retried:
+// storage and converter failures and temporary overload. Timeouts are not
+// retried since a job that ran out of time is likely to do so again.
+func Transient(code service.ErrorCode) bool {
+	switch code {
+	case service.ErrCodeInternal, service.ErrCodeBusy, service.ErrCodeLowResources:
+		return true
+	}
+	return false
+}
+
+// retryable reports whether job should run again after failure
+func (m *Manager) retryable(job *Job, failure *Failure) bool {
+	return Transient(failure.Code) && job.Attempts < m.cfg.MaxAttempts
+}
+
+// retryDelay returns the backoff before the attempt following attempt: the
+// retry delay doubled per attempt up to the maximum, plus up to 10% jitter
+// so jobs failed by the same outage do not retry in lockstep
+func (m *Manager) retryDelay(attempt int) time.Duration {
+	delay := time.Duration(m.cfg.RetryDelay) * time.Second
+	maxDelay := time.Duration(m.cfg.MaxRetryDelay) * time.Second
+	for i := 1; i < attempt && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/10+1))
+}
+
+// retry records a transient failure and requeues job after the backoff
+func (m *Manager) retry(ctx context.Context, job *Job, failure *Failure) {
+	delay := m.retryDelay(job.Attempts)
+	next := time.Now().Add(delay)
+	job.Status = StatusRetrying
+	job.Failure = failure
+	job.NextAttemptAt = &next
+	m.update(ctx, job)
+
+	retriesTotal.Inc()
+	m.log.Warn("Batch job failed transiently, retrying", "job_id", job.ID, "attempt", job.Attempts,
+		"retry_in", delay, "step", failure.Step, "code", failure.Code, "error", failure.cause)
+	m.scheduleRetry(job.ID, delay)
+}
+
+// scheduleRetry queues job id once delay has passed
+func (m *Manager) scheduleRetry(id string, delay time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.retries[id] = time.AfterFunc(delay, func() { m.requeueRetry(id) })
+}
+
+// requeueRetry puts a job whose backoff has passed back on the queue. The
+// lock is held throughout so Drain either sees the pending retry or finds
+// the job queued.
+func (m *Manager) requeueRetry(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.retries[id]; !ok {
+		// Taken over by Drain
+		return
+	}
+	delete(m.retries, id)
+
+	ctx := context.Background()
+	job, err := m.jobs.Get(ctx, id)
+	if err != nil {
+		m.log.Error("Failed to load batch job for retry", "job_id", id, "error", err)
+		return
+	}
+	job.Status = StatusQueued
+	job.NextAttemptAt = nil
+	m.update(ctx, job)
+
+	if !m.pending.push(job) {
+		// Queue full; try again after another retry delay
+		delay := time.Duration(m.cfg.RetryDelay) * time.Second
+		m.retries[id] = time.AfterFunc(delay, func() { m.requeueRetry(id) })
+	}
+}
+
+// takeRetries cancels pending retries and returns their job IDs
+func (m *Manager) takeRetries() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	ids := make([]string, 0, len(m.retries))
+	for id, timer := range m.retries {
+		timer.Stop()
+		ids = append(ids, id)
+	}
+	m.retries = make(map[string]*time.Timer)
+	return ids
+}
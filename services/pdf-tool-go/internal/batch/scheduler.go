@@ -0,0 +1,273 @@
+package batch
+
+import (
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+var (
+	queuedJobs = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pdf_tool_batch_queued_jobs",
+		Help: "Batch jobs waiting for a worker",
+	}, []string{"priority"})
+	queueWait = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_batch_queue_wait_seconds",
+		Help:    "Time batch jobs waited for a worker",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 16),
+	}, []string{"priority"})
+)
+
+// Priority selects the scheduling class of a job
+type Priority string
+
+const (
+	// PriorityInteractive is for small jobs a user is waiting on
+	PriorityInteractive Priority = "interactive"
+	// PriorityBulk is for large background runs; it is the default
+	PriorityBulk Priority = "bulk"
+)
+
+// priorities lists the classes in dispatch preference order
+var priorities = []Priority{PriorityInteractive, PriorityBulk}
+
+// stride is the pass increment of a tenant with weight 1
+const stride = 1 << 20
+
+// queued is a job waiting in the scheduler
+type queued struct {
+	id       string
+	queuedAt time.Time
+}
+
+// tenantQueue holds the waiting jobs of one tenant in one class
+type tenantQueue struct {
+	jobs []queued
+	// pass is the tenant's virtual time; the tenant with the lowest pass is
+	// served next and pays stride/weight per job
+	pass uint64
+}
+
+// class is one priority level
+type class struct {
+	tenants map[string]*tenantQueue
+	size    int
+}
+
+// scheduler orders queued jobs. Interactive jobs are preferred over bulk
+// jobs, but while bulk jobs wait every InteractiveRatio-th dispatch goes to
+// bulk so it is never starved. Within a class, tenants share workers by
+// stride scheduling in proportion to their batch weight, so one tenant's
+// large run cannot delay other tenants beyond their share.
+type scheduler struct {
+	mu        sync.Mutex
+	classes   map[Priority]*class
+	capacity  int
+	perTenant int
+	ratio     int
+	weights   map[string]int
+	// perTenantQueued counts waiting jobs per tenant across classes
+	perTenantQueued map[string]int
+	size            int
+	sinceBulk       int
+
+	// ready holds one token per queued job so workers can block on it
+	ready chan struct{}
+}
+
+func newScheduler(cfg config.BatchConfig, tenants []config.TenantConfig) *scheduler {
+	s := &scheduler{
+		classes:         make(map[Priority]*class),
+		capacity:        cfg.QueueSize,
+		perTenant:       cfg.TenantQueueSize,
+		ratio:           cfg.InteractiveRatio,
+		weights:         make(map[string]int),
+		perTenantQueued: make(map[string]int),
+		ready:           make(chan struct{}, cfg.QueueSize),
+	}
+	for _, priority := range priorities {
+		s.classes[priority] = &class{tenants: make(map[string]*tenantQueue)}
+	}
+	for _, tenant := range tenants {
+		if tenant.BatchWeight > 0 {
+			s.weights[tenant.ID] = tenant.BatchWeight
+		}
+	}
+	return s
+}
+
+// push queues a job. It returns false when the queue or the tenant's share
+// of it is full.
+func (s *scheduler) push(job *Job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.size >= s.capacity || s.perTenantQueued[job.Tenant] >= s.perTenant {
+		return false
+	}
+
+	c := s.classes[job.Priority]
+	q, ok := c.tenants[job.Tenant]
+	if !ok {
+		q = &tenantQueue{}
+		c.tenants[job.Tenant] = q
+	}
+	if len(q.jobs) == 0 {
+		// A tenant returning from idle starts level with the waiting
+		// tenants instead of spending credit saved while idle
+		if lowest, ok := c.minPass(); ok && q.pass < lowest {
+			q.pass = lowest
+		}
+	}
+	q.jobs = append(q.jobs, queued{id: job.ID, queuedAt: time.Now()})
+	c.size++
+	s.size++
+	s.perTenantQueued[job.Tenant]++
+	queuedJobs.WithLabelValues(string(job.Priority)).Inc()
+
+	s.ready <- struct{}{}
+	return true
+}
+
+// pop removes the next job to run. It returns false if nothing is queued.
+func (s *scheduler) pop() (string, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	priority, ok := s.nextClass()
+	if !ok {
+		return "", false
+	}
+	if priority == PriorityBulk {
+		s.sinceBulk = 0
+	} else {
+		s.sinceBulk++
+	}
+
+	c := s.classes[priority]
+	tenant, q := c.next()
+	next := q.jobs[0]
+	q.jobs = q.jobs[1:]
+	q.pass += uint64(stride / s.weight(tenant))
+	if len(q.jobs) == 0 {
+		q.jobs = nil
+	}
+	c.size--
+	s.size--
+	s.perTenantQueued[tenant]--
+	if s.perTenantQueued[tenant] == 0 {
+		delete(s.perTenantQueued, tenant)
+	}
+
+	queuedJobs.WithLabelValues(string(priority)).Dec()
+	queueWait.WithLabelValues(string(priority)).Observe(time.Since(next.queuedAt).Seconds())
+	return next.id, true
+}
+
+// remove takes a queued job off the queue. It returns false if the job is
+// not queued.
+func (s *scheduler) remove(job *Job) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	c := s.classes[job.Priority]
+	q, ok := c.tenants[job.Tenant]
+	if !ok {
+		return false
+	}
+	for i, waiting := range q.jobs {
+		if waiting.id != job.ID {
+			continue
+		}
+		q.jobs = append(q.jobs[:i:i], q.jobs[i+1:]...)
+		if len(q.jobs) == 0 {
+			q.jobs = nil
+		}
+		c.size--
+		s.size--
+		s.perTenantQueued[job.Tenant]--
+		if s.perTenantQueued[job.Tenant] == 0 {
+			delete(s.perTenantQueued, job.Tenant)
+		}
+		queuedJobs.WithLabelValues(string(job.Priority)).Dec()
+
+		// Tokens are not tied to jobs, so take back any one. If workers
+		// hold them all, one of them wakes to find nothing to pop.
+		select {
+		case <-s.ready:
+		default:
+		}
+		return true
+	}
+	return false
+}
+
+// len returns the number of queued jobs
+func (s *scheduler) len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size
+}
+
+// drain removes and returns every queued job
+func (s *scheduler) drain() []string {
+	var ids []string
+	for {
+		id, ok := s.pop()
+		if !ok {
+			return ids
+		}
+		ids = append(ids, id)
+	}
+}
+
+// nextClass picks the class to serve
+func (s *scheduler) nextClass() (Priority, bool) {
+	interactive := s.classes[PriorityInteractive].size > 0
+	bulk := s.classes[PriorityBulk].size > 0
+	switch {
+	case bulk && (!interactive || s.sinceBulk >= s.ratio):
+		return PriorityBulk, true
+	case interactive:
+		return PriorityInteractive, true
+	}
+	return "", false
+}
+
+// weight returns the scheduling weight of tenant
+func (s *scheduler) weight(tenant string) int {
+	if weight, ok := s.weights[tenant]; ok {
+		return weight
+	}
+	return 1
+}
+
+// next returns the waiting tenant with the lowest pass. Ties are broken by
+// tenant ID so scheduling is deterministic.
+func (c *class) next() (string, *tenantQueue) {
+	var (
+		bestID string
+		best   *tenantQueue
+	)
+	for id, q := range c.tenants {
+		if len(q.jobs) == 0 {
+			continue
+		}
+		if best == nil || q.pass < best.pass || (q.pass == best.pass && id < bestID) {
+			bestID, best = id, q
+		}
+	}
+	return bestID, best
+}
+
+// minPass returns the lowest pass among tenants with waiting jobs
+func (c *class) minPass() (uint64, bool) {
+	_, q := c.next()
+	if q == nil {
+		return 0, false
+	}
+	return q.pass, true
+}
@@ -0,0 +1,58 @@
+package batch
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScheduler(t *testing.T) {
+	cfg := config.BatchConfig{QueueSize: 20, TenantQueueSize: 10, InteractiveRatio: 2}
+	push := func(s *scheduler, tenant string, priority Priority, n int) {
+		for i := 0; i < n; i++ {
+			require.True(t, s.push(&Job{ID: fmt.Sprintf("%s-%s-%d", tenant, priority, i), Tenant: tenant, Priority: priority}))
+		}
+	}
+	order := func(s *scheduler) []string {
+		var tenants []string
+		for {
+			id, ok := s.pop()
+			if !ok {
+				return tenants
+			}
+			tenants = append(tenants, id[:1])
+		}
+	}
+
+	t.Run("Tenants Take Turns", func(t *testing.T) {
+		s := newScheduler(cfg, nil)
+		push(s, "a", PriorityBulk, 6)
+		push(s, "b", PriorityBulk, 2)
+		assert.Equal(t, []string{"a", "b", "a", "b", "a", "a", "a", "a"}, order(s))
+	})
+
+	t.Run("Weights Scale Shares", func(t *testing.T) {
+		s := newScheduler(cfg, []config.TenantConfig{{ID: "b", BatchWeight: 2}})
+		push(s, "a", PriorityBulk, 4)
+		push(s, "b", PriorityBulk, 4)
+		assert.Equal(t, []string{"a", "b", "b", "a", "b", "b", "a", "a"}, order(s))
+	})
+
+	t.Run("Interactive First Without Starving Bulk", func(t *testing.T) {
+		s := newScheduler(cfg, nil)
+		push(s, "b", PriorityBulk, 3)
+		push(s, "i", PriorityInteractive, 3)
+		assert.Equal(t, []string{"i", "i", "b", "i", "b", "b"}, order(s))
+	})
+
+	t.Run("Queue Limits", func(t *testing.T) {
+		s := newScheduler(cfg, nil)
+		push(s, "a", PriorityBulk, 10)
+		assert.False(t, s.push(&Job{ID: "a-extra", Tenant: "a", Priority: PriorityInteractive}))
+		push(s, "b", PriorityBulk, 10)
+		assert.False(t, s.push(&Job{ID: "c-extra", Tenant: "c", Priority: PriorityBulk}))
+	})
+}
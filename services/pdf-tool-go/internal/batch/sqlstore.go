@@ -0,0 +1,372 @@
+package batch
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// Kinds of job artifacts
+const (
+	artifactDocument = "document"
+	artifactResult   = "result"
+)
+
+// jobColumns are the batch_jobs columns in the order scanJob reads them
+const jobColumns = `id, tenant, status, priority, step, archive, failure, attempts, trace_context, owner,
+	created_at, started_at, finished_at, next_attempt_at, dead_lettered_at, paused_at, delivery, progress`
+
+// SQLStore keeps jobs in a SQL database, so job history and status survive
+// restarts. Finished jobs are deleted once they are older than the TTL.
+type SQLStore struct {
+	db  *database.DB
+	ttl time.Duration
+}
+
+// NewSQLStore migrates the batch tables of db to the current schema and
+// returns a store backed by them
+func NewSQLStore(ctx context.Context, db *database.DB, ttl time.Duration) (*SQLStore, error) {
+	steps, err := database.Load(migrations, "migrations/"+db.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(ctx, db, "batch", steps); err != nil {
+		return nil, err
+	}
+	return &SQLStore{db: db, ttl: ttl}, nil
+}
+
+// Create adds a new job
+func (s *SQLStore) Create(ctx context.Context, job *Job) error {
+	if err := s.prune(ctx, time.Now()); err != nil {
+		return err
+	}
+
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		values, err := jobValues(job)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO batch_jobs (`+jobColumns+`)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18)`), values...); err != nil {
+			return fmt.Errorf("failed to insert job: %w", err)
+		}
+
+		for i, step := range job.Steps {
+			params, err := marshalJSON(step.Params)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO batch_job_steps (job_id, position, operation, params)
+				VALUES ($1, $2, $3, $4)`), job.ID, i, step.Operation, params); err != nil {
+				return fmt.Errorf("failed to insert job step: %w", err)
+			}
+		}
+		if err := s.insertArtifacts(ctx, tx, job.ID, artifactDocument, job.Documents); err != nil {
+			return err
+		}
+		return s.insertArtifacts(ctx, tx, job.ID, artifactResult, job.Results)
+	})
+}
+
+// Get returns the job with id
+func (s *SQLStore) Get(ctx context.Context, id string) (*Job, error) {
+	jobs, err := s.query(ctx, "id = $1", []interface{}{id})
+	if err != nil {
+		return nil, err
+	}
+	if len(jobs) == 0 {
+		return nil, service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	return jobs[0], nil
+}
+
+// Update replaces a stored job. Steps and documents are fixed at
+// submission, so only the job's state and results are written.
+func (s *SQLStore) Update(ctx context.Context, job *Job) error {
+	return s.inTx(ctx, func(tx *sql.Tx) error {
+		values, err := jobValues(job)
+		if err != nil {
+			return err
+		}
+		res, err := tx.ExecContext(ctx, s.db.Rebind(`UPDATE batch_jobs SET
+			tenant = $2, status = $3, priority = $4, step = $5, archive = $6, failure = $7, attempts = $8,
+			trace_context = $9, owner = $10, created_at = $11, started_at = $12, finished_at = $13,
+			next_attempt_at = $14, dead_lettered_at = $15, paused_at = $16, delivery = $17, progress = $18
+			WHERE id = $1`), values...)
+		if err != nil {
+			return fmt.Errorf("failed to update job: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return service.NewError(service.ErrCodeNotFound, "job not found", nil)
+		}
+
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`DELETE FROM batch_job_artifacts WHERE job_id = $1 AND kind = $2`),
+			job.ID, artifactResult); err != nil {
+			return fmt.Errorf("failed to replace job results: %w", err)
+		}
+		return s.insertArtifacts(ctx, tx, job.ID, artifactResult, job.Results)
+	})
+}
+
+// List returns the jobs matching filter, oldest first
+func (s *SQLStore) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	var (
+		conditions []string
+		args       []interface{}
+	)
+	where := func(condition string, arg interface{}) {
+		args = append(args, arg)
+		conditions = append(conditions, strings.ReplaceAll(condition, "?", fmt.Sprintf("$%d", len(args))))
+	}
+	if filter.Tenant != "" {
+		where("tenant = ?", filter.Tenant)
+	}
+	if filter.Owner != "" {
+		where("owner = ?", filter.Owner)
+	}
+	if filter.Status != "" {
+		where("status = ?", string(filter.Status))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where("created_at > ?", filter.CreatedAfter.UTC())
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where("created_at < ?", filter.CreatedBefore.UTC())
+	}
+	if filter.DeadLettered {
+		conditions = append(conditions, "dead_lettered_at IS NOT NULL")
+	}
+	if len(conditions) == 0 {
+		conditions = append(conditions, "TRUE")
+	}
+	return s.query(ctx, strings.Join(conditions, " AND "), args)
+}
+
+// query loads the jobs matching condition with their steps and artifacts,
+// oldest first
+func (s *SQLStore) query(ctx context.Context, condition string, args []interface{}) ([]*Job, error) {
+	rows, err := s.db.QueryContext(ctx, s.db.Rebind(`SELECT `+jobColumns+` FROM batch_jobs
+		WHERE `+condition+` ORDER BY created_at, id`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	defer rows.Close()
+
+	var jobs []*Job
+	byID := make(map[string]*Job)
+	for rows.Next() {
+		job, err := scanJob(rows)
+		if err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+		byID[job.ID] = job
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query jobs: %w", err)
+	}
+	if len(jobs) == 0 {
+		return nil, nil
+	}
+
+	// Steps and artifacts of all selected jobs are read in one query each
+	selected := `job_id IN (SELECT id FROM batch_jobs WHERE ` + condition + `)`
+	steps, err := s.db.QueryContext(ctx, s.db.Rebind(`SELECT job_id, operation, params FROM batch_job_steps
+		WHERE `+selected+` ORDER BY job_id, position`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job steps: %w", err)
+	}
+	defer steps.Close()
+	for steps.Next() {
+		var (
+			id     string
+			step   Step
+			params []byte
+		)
+		if err := steps.Scan(&id, &step.Operation, &params); err != nil {
+			return nil, fmt.Errorf("failed to read job step: %w", err)
+		}
+		if err := unmarshalJSON(params, &step.Params); err != nil {
+			return nil, err
+		}
+		if job, ok := byID[id]; ok {
+			job.Steps = append(job.Steps, step)
+		}
+	}
+	if err := steps.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query job steps: %w", err)
+	}
+
+	artifacts, err := s.db.QueryContext(ctx, s.db.Rebind(`SELECT job_id, kind, ref FROM batch_job_artifacts
+		WHERE `+selected+` ORDER BY job_id, kind, position`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query job artifacts: %w", err)
+	}
+	defer artifacts.Close()
+	for artifacts.Next() {
+		var id, kind, ref string
+		if err := artifacts.Scan(&id, &kind, &ref); err != nil {
+			return nil, fmt.Errorf("failed to read job artifact: %w", err)
+		}
+		job, ok := byID[id]
+		if !ok {
+			continue
+		}
+		switch kind {
+		case artifactDocument:
+			job.Documents = append(job.Documents, ref)
+		case artifactResult:
+			job.Results = append(job.Results, ref)
+		}
+	}
+	if err := artifacts.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query job artifacts: %w", err)
+	}
+	return jobs, nil
+}
+
+// prune deletes finished jobs older than the TTL; their steps and artifacts
+// go with them
+func (s *SQLStore) prune(ctx context.Context, now time.Time) error {
+	if _, err := s.db.ExecContext(ctx, s.db.Rebind(`DELETE FROM batch_jobs WHERE finished_at < $1`), now.Add(-s.ttl).UTC()); err != nil {
+		return fmt.Errorf("failed to prune jobs: %w", err)
+	}
+	return nil
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (s *SQLStore) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// insertArtifacts records refs as artifacts of kind in order
+func (s *SQLStore) insertArtifacts(ctx context.Context, tx *sql.Tx, id, kind string, refs []string) error {
+	for i, ref := range refs {
+		if _, err := tx.ExecContext(ctx, s.db.Rebind(`INSERT INTO batch_job_artifacts (job_id, kind, position, ref)
+			VALUES ($1, $2, $3, $4)`), id, kind, i, ref); err != nil {
+			return fmt.Errorf("failed to insert job %s: %w", kind, err)
+		}
+	}
+	return nil
+}
+
+// jobValues returns the batch_jobs columns of job in jobColumns order.
+// Times are stored in UTC, so SQLite, which keeps them as text, compares
+// them in order.
+func jobValues(job *Job) ([]interface{}, error) {
+	failure, err := marshalJSON(job.Failure)
+	if err != nil {
+		return nil, err
+	}
+	traceContext, err := marshalJSON(job.TraceContext)
+	if err != nil {
+		return nil, err
+	}
+	delivery, err := marshalJSON(job.Delivery)
+	if err != nil {
+		return nil, err
+	}
+	progress, err := marshalJSON(job.Progress)
+	if err != nil {
+		return nil, err
+	}
+	return []interface{}{
+		job.ID, job.Tenant, string(job.Status), string(job.Priority), job.Step, job.Archive, failure, job.Attempts,
+		traceContext, job.Owner, job.CreatedAt.UTC(), nullTime(job.StartedAt), nullTime(job.FinishedAt), nullTime(job.NextAttemptAt),
+		nullTime(job.DeadLetteredAt), nullTime(job.PausedAt), delivery, progress,
+	}, nil
+}
+
+// scanJob reads a batch_jobs row selected with jobColumns
+func scanJob(rows *sql.Rows) (*Job, error) {
+	var (
+		job                   Job
+		status, priority      string
+		failure, traceContext []byte
+		delivery, progress    []byte
+		started, finished     sql.NullTime
+		nextAttempt, dead     sql.NullTime
+		paused                sql.NullTime
+	)
+	if err := rows.Scan(&job.ID, &job.Tenant, &status, &priority, &job.Step, &job.Archive, &failure, &job.Attempts,
+		&traceContext, &job.Owner, &job.CreatedAt, &started, &finished, &nextAttempt, &dead, &paused, &delivery, &progress); err != nil {
+		return nil, fmt.Errorf("failed to read job: %w", err)
+	}
+	job.Status = Status(status)
+	job.Priority = Priority(priority)
+	if err := unmarshalJSON(failure, &job.Failure); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSON(traceContext, &job.TraceContext); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSON(delivery, &job.Delivery); err != nil {
+		return nil, err
+	}
+	if err := unmarshalJSON(progress, &job.Progress); err != nil {
+		return nil, err
+	}
+	job.StartedAt = timePtr(started)
+	job.FinishedAt = timePtr(finished)
+	job.NextAttemptAt = timePtr(nextAttempt)
+	job.DeadLetteredAt = timePtr(dead)
+	job.PausedAt = timePtr(paused)
+	return &job, nil
+}
+
+// marshalJSON encodes v for a JSON column, storing NULL for nil values
+func marshalJSON(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode job: %w", err)
+	}
+	if string(data) == "null" {
+		return nil, nil
+	}
+	return string(data), nil
+}
+
+// unmarshalJSON decodes a JSON column into v, leaving it unset for NULL
+func unmarshalJSON(data []byte, v interface{}) error {
+	if len(data) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("failed to decode job: %w", err)
+	}
+	return nil
+}
+
+func nullTime(t *time.Time) sql.NullTime {
+	if t == nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t.UTC(), Valid: true}
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,144 @@
+package batch
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSQLStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Open(ctx, config.DatabaseConfig{
+		Driver:       "sqlite",
+		DSN:          filepath.Join(t.TempDir(), "jobs.db"),
+		MaxOpenConns: 4,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	s, err := NewSQLStore(ctx, db, time.Hour)
+	require.NoError(t, err)
+	// Migrations already applied are skipped
+	_, err = NewSQLStore(ctx, db, time.Hour)
+	require.NoError(t, err)
+
+	created := time.Now().Add(-time.Minute).Truncate(time.Microsecond)
+	job := &Job{
+		ID:        "job-1",
+		Tenant:    "acme",
+		Status:    StatusQueued,
+		Priority:  PriorityBulk,
+		Documents: []string{"doc-b", "doc-a"},
+		Steps: []Step{
+			{Operation: "split", Params: map[string]string{"pages": "1-2"}},
+			{Operation: "merge"},
+		},
+		CreatedAt:    created,
+		TraceContext: map[string]string{"traceparent": "00-abc-def-01"},
+		Owner:        "pdf-tool-0",
+		Delivery:     &Delivery{Email: []string{"ops@example.com"}},
+		Progress:     &Progress{Done: 3, Total: 10},
+	}
+
+	t.Run("Round Trip", func(t *testing.T) {
+		require.NoError(t, s.Create(ctx, job))
+		got, err := s.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, job.Tenant, got.Tenant)
+		assert.Equal(t, job.Status, got.Status)
+		assert.Equal(t, job.Documents, got.Documents)
+		assert.Equal(t, job.Steps, got.Steps)
+		assert.Equal(t, job.TraceContext, got.TraceContext)
+		assert.Equal(t, job.Owner, got.Owner)
+		assert.Equal(t, job.Delivery, got.Delivery)
+		assert.Equal(t, job.Progress, got.Progress)
+		assert.True(t, job.CreatedAt.Equal(got.CreatedAt))
+		assert.Nil(t, got.StartedAt)
+		assert.Nil(t, got.Failure)
+	})
+
+	t.Run("Update Records State And Results", func(t *testing.T) {
+		finished := time.Now().Truncate(time.Microsecond)
+		job.Status = StatusFailed
+		job.Step = 2
+		job.Attempts = 1
+		job.FinishedAt = &finished
+		job.DeadLetteredAt = &finished
+		job.Results = []string{"res-1", "res-2"}
+		job.Failure = &Failure{Step: 2, Operation: "merge", Input: 1, InputName: "doc-a#2", Code: service.ErrCodeCorrupted, Error: "PDF is corrupted"}
+		require.NoError(t, s.Update(ctx, job))
+
+		got, err := s.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, StatusFailed, got.Status)
+		assert.Equal(t, 2, got.Step)
+		assert.Equal(t, []string{"res-1", "res-2"}, got.Results)
+		assert.Equal(t, job.Failure, got.Failure)
+		require.NotNil(t, got.FinishedAt)
+		assert.True(t, finished.Equal(*got.FinishedAt))
+		assert.Equal(t, job.Documents, got.Documents)
+
+		job.Results = []string{"res-3"}
+		require.NoError(t, s.Update(ctx, job))
+		got, err = s.Get(ctx, job.ID)
+		require.NoError(t, err)
+		assert.Equal(t, []string{"res-3"}, got.Results)
+	})
+
+	t.Run("Missing Jobs Are Not Found", func(t *testing.T) {
+		_, err := s.Get(ctx, "missing")
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+		err = s.Update(ctx, &Job{ID: "missing", CreatedAt: created})
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+	})
+
+	t.Run("List Filters", func(t *testing.T) {
+		other := &Job{ID: "job-2", Tenant: "globex", Status: StatusQueued, Priority: PriorityInteractive,
+			Documents: []string{"doc-c"}, Steps: []Step{{Operation: "compress"}}, CreatedAt: created.Add(time.Second), Owner: "pdf-tool-1"}
+		require.NoError(t, s.Create(ctx, other))
+
+		ids := func(filter Filter) []string {
+			jobs, err := s.List(ctx, filter)
+			require.NoError(t, err)
+			var ids []string
+			for _, job := range jobs {
+				ids = append(ids, job.ID)
+			}
+			return ids
+		}
+		assert.Equal(t, []string{"job-1", "job-2"}, ids(Filter{}))
+		assert.Equal(t, []string{"job-2"}, ids(Filter{Tenant: "globex"}))
+		assert.Equal(t, []string{"job-2"}, ids(Filter{Status: StatusQueued}))
+		assert.Equal(t, []string{"job-1"}, ids(Filter{Owner: "pdf-tool-0"}))
+		assert.Equal(t, []string{"job-1"}, ids(Filter{DeadLettered: true}))
+		assert.Equal(t, []string{"job-2"}, ids(Filter{CreatedAfter: created}))
+		assert.Equal(t, []string{"job-1"}, ids(Filter{CreatedBefore: created.Add(time.Second)}))
+		assert.Empty(t, ids(Filter{Tenant: "acme", Status: StatusQueued}))
+
+		jobs, err := s.List(ctx, Filter{Tenant: "globex"})
+		require.NoError(t, err)
+		require.Len(t, jobs, 1)
+		assert.Equal(t, other.Steps, jobs[0].Steps)
+		assert.Equal(t, other.Documents, jobs[0].Documents)
+	})
+
+	t.Run("Finished Jobs Expire", func(t *testing.T) {
+		expired := time.Now().Add(-2 * time.Hour)
+		job.FinishedAt = &expired
+		require.NoError(t, s.Update(ctx, job))
+
+		require.NoError(t, s.Create(ctx, &Job{ID: "job-3", Tenant: "acme", Status: StatusQueued, Priority: PriorityBulk,
+			Documents: []string{"doc-a"}, Steps: []Step{{Operation: "compress"}}, CreatedAt: time.Now()}))
+		_, err := s.Get(ctx, job.ID)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+		_, err = s.Get(ctx, "job-2")
+		assert.NoError(t, err)
+	})
+}
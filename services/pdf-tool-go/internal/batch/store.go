@@ -0,0 +1,134 @@
+package batch
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// Store persists jobs. Implementations return copies, so callers may modify
+// a job and write it back with Update.
+type Store interface {
+	// Create adds a new job
+	Create(ctx context.Context, job *Job) error
+	// Get returns the job with id, or a NOT_FOUND error
+	Get(ctx context.Context, id string) (*Job, error)
+	// Update replaces a stored job
+	Update(ctx context.Context, job *Job) error
+	// List returns the jobs matching filter, oldest first
+	List(ctx context.Context, filter Filter) ([]*Job, error)
+}
+
+// Filter selects jobs to list
+type Filter struct {
+	// Tenant restricts the listing to one tenant; empty lists all tenants
+	Tenant       string
+	DeadLettered bool
+	// Status restricts the listing to jobs in one status when set
+	Status Status
+	// Owner restricts the listing to jobs queued by one instance when set
+	Owner string
+	// CreatedAfter and CreatedBefore bound the creation time when set
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+}
+
+// matches reports whether job passes the filter
+func (f Filter) matches(job *Job) bool {
+	switch {
+	case f.Tenant != "" && job.Tenant != f.Tenant:
+		return false
+	case f.Status != "" && job.Status != f.Status:
+		return false
+	case f.Owner != "" && job.Owner != f.Owner:
+		return false
+	case !f.CreatedAfter.IsZero() && !job.CreatedAt.After(f.CreatedAfter):
+		return false
+	case !f.CreatedBefore.IsZero() && !job.CreatedAt.Before(f.CreatedBefore):
+		return false
+	}
+	return !f.DeadLettered || job.DeadLetteredAt != nil
+}
+
+// MemoryStore keeps jobs in memory. Finished jobs are dropped once they are
+// older than the TTL; all jobs are lost on restart.
+type MemoryStore struct {
+	mu   sync.Mutex
+	ttl  time.Duration
+	jobs map[string]*Job
+}
+
+// NewMemoryStore creates an in-memory job store
+func NewMemoryStore(ttl time.Duration) *MemoryStore {
+	return &MemoryStore{
+		ttl:  ttl,
+		jobs: make(map[string]*Job),
+	}
+}
+
+// Create adds a new job
+func (s *MemoryStore) Create(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.prune(time.Now())
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// Get returns the job with id
+func (s *MemoryStore) Get(ctx context.Context, id string) (*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, ok := s.jobs[id]
+	if !ok {
+		return nil, service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	copied := *job
+	return &copied, nil
+}
+
+// Update replaces a stored job
+func (s *MemoryStore) Update(ctx context.Context, job *Job) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[job.ID]; !ok {
+		return service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	copied := *job
+	s.jobs[job.ID] = &copied
+	return nil
+}
+
+// List returns the jobs matching filter, oldest first
+func (s *MemoryStore) List(ctx context.Context, filter Filter) ([]*Job, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var jobs []*Job
+	for _, job := range s.jobs {
+		if filter.matches(job) {
+			copied := *job
+			jobs = append(jobs, &copied)
+		}
+	}
+	sort.Slice(jobs, func(i, j int) bool {
+		return jobs[i].CreatedAt.Before(jobs[j].CreatedAt)
+	})
+	return jobs, nil
+}
+
+// prune drops finished jobs older than the TTL
+func (s *MemoryStore) prune(now time.Time) {
+	for id, job := range s.jobs {
+		if job.FinishedAt != nil && now.Sub(*job.FinishedAt) > s.ttl {
+			delete(s.jobs, id)
+		}
+	}
+}
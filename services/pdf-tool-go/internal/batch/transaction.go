@@ -0,0 +1,348 @@
+package batch
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+)
+
+// stagingPrefix is the storage key prefix for intermediate job outputs
+const stagingPrefix = "batch/"
+
+// rollbackTimeout bounds cleanup after a job finished, failed or was canceled
+const rollbackTimeout = 30 * time.Second
+
+// progressInterval throttles the writes of a step's progress to the store
+const progressInterval = time.Second
+
+// artifact is an input or output of a step: a job document or a staged
+// intermediate output
+type artifact struct {
+	name     string
+	document string
+	key      string
+}
+
+// transaction runs the steps of a job and records everything it writes, so
+// a failure leaves nothing behind
+type transaction struct {
+	m   *Manager
+	job *Job
+	// staged holds the keys of intermediate outputs
+	staged []string
+	// promoted holds the results created by the commit; results that
+	// already existed are shared and never rolled back
+	promoted []string
+	// reported is when progress was last written to the store
+	reported time.Time
+}
+
+// run applies the job's steps in order and then commits the final outputs
+// as results
+func (tx *transaction) run(ctx context.Context) ([]string, *Failure) {
+	inputs := make([]artifact, len(tx.job.Documents))
+	for i, id := range tx.job.Documents {
+		inputs[i] = artifact{name: id, document: id}
+	}
+
+	for i, step := range tx.job.Steps {
+		if err := ctx.Err(); err != nil {
+			return nil, &Failure{Step: i + 1, Operation: step.Operation, Input: -1, cause: err}
+		}
+		tx.job.Step = i + 1
+		tx.job.Progress = nil
+		tx.m.update(ctx, tx.job)
+
+		outputs, failure := tx.apply(ctx, step, inputs)
+		if failure != nil {
+			failure.Step = i + 1
+			failure.Operation = step.Operation
+			return nil, failure
+		}
+		inputs = outputs
+	}
+
+	return tx.commit(ctx, inputs)
+}
+
+// apply runs one step over its inputs
+func (tx *transaction) apply(ctx context.Context, step Step, inputs []artifact) ([]artifact, *Failure) {
+	switch step.Operation {
+	case "merge":
+		return tx.merge(ctx, inputs)
+	case "personalize":
+		return tx.personalize(ctx, step, inputs)
+	case "mailmerge":
+		return tx.mailMerge(ctx, step, inputs)
+	}
+
+	var outputs []artifact
+	for i, input := range inputs {
+		// Stop between inputs once the job is canceled, paused or out of
+		// time; the operations themselves also give up on ctx
+		if err := ctx.Err(); err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		data, err := tx.load(ctx, input)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		parts, err := tx.transform(ctx, step, data)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		for k, part := range parts {
+			name := input.name
+			if step.Operation == "split" {
+				name = fmt.Sprintf("%s#%d", input.name, k+1)
+			}
+			output, err := tx.stage(ctx, part, name)
+			if err != nil {
+				return nil, inputFailure(i, input, err)
+			}
+			outputs = append(outputs, output)
+		}
+		tx.advance(ctx, i+1, len(inputs))
+	}
+	return outputs, nil
+}
+
+// advance records the progress of the running step. Writes to the store
+// are throttled, except for the step's completion.
+func (tx *transaction) advance(ctx context.Context, done, total int) {
+	// Progress is replaced rather than changed in place, as stored copies
+	// of the job may share it
+	tx.job.Progress = &Progress{Done: done, Total: total}
+	if done < total && time.Since(tx.reported) < progressInterval {
+		return
+	}
+	tx.reported = time.Now()
+	tx.m.update(ctx, tx.job)
+}
+
+// transform runs a per-document operation
+func (tx *transaction) transform(ctx context.Context, step Step, data []byte) ([][]byte, error) {
+	var (
+		output []byte
+		err    error
+	)
+	switch step.Operation {
+	case "split":
+		return tx.m.processor.SplitPDF(ctx, &service.SplitRequest{PDFData: data, PageRange: step.Params["pages"]})
+	case "compress":
+		level := 1
+		if value, ok := step.Params["level"]; ok {
+			level, _ = strconv.Atoi(value)
+		}
+		output, err = tx.m.processor.CompressPDF(ctx, &service.CompressRequest{
+			PDFData:          data,
+			CompressionLevel: level,
+			Profile:          step.Params["profile"],
+		})
+	case "watermark":
+		req := service.NewWatermarkRequest(ctx, data)
+		if text := step.Params["text"]; text != "" {
+			req.WatermarkText = text
+		}
+		output, err = tx.m.processor.AddWatermark(ctx, req)
+	default:
+		return nil, fmt.Errorf("unsupported operation %q", step.Operation)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return [][]byte{output}, nil
+}
+
+// merge combines all inputs into one document. A single input is passed
+// through unchanged.
+func (tx *transaction) merge(ctx context.Context, inputs []artifact) ([]artifact, *Failure) {
+	if len(inputs) == 1 {
+		return inputs, nil
+	}
+
+	req := &service.MergeRequest{
+		PDFs:       make([][]byte, len(inputs)),
+		InputNames: make([]string, len(inputs)),
+	}
+	for i, input := range inputs {
+		data, err := tx.load(ctx, input)
+		if err != nil {
+			return nil, inputFailure(i, input, err)
+		}
+		req.PDFs[i] = data
+		req.InputNames[i] = input.name
+	}
+
+	merged, err := tx.m.processor.MergePDFs(ctx, req)
+	if err != nil {
+		if i, ok := failedInput(err); ok && i < len(inputs) {
+			return nil, inputFailure(i, inputs[i], err)
+		}
+		return nil, stepFailure(err)
+	}
+
+	output, err := tx.stage(ctx, merged, "merged")
+	if err != nil {
+		return nil, stepFailure(err)
+	}
+	return []artifact{output}, nil
+}
+
+// commit saves the final outputs as results. A job with several outputs
+// also gets a ZIP archive of them with a manifest.
+func (tx *transaction) commit(ctx context.Context, outputs []artifact) ([]string, *Failure) {
+	ids := make([]string, 0, len(outputs))
+	var entries []archiveEntry
+	for i, output := range outputs {
+		data, err := tx.load(ctx, output)
+		var result *service.Result
+		if err == nil {
+			result, err = tx.promote(ctx, data, "pdf")
+		}
+		if err != nil {
+			return nil, tx.commitFailure(inputFailure(i, output, err))
+		}
+		ids = append(ids, result.ID)
+		if len(outputs) > 1 {
+			entries = append(entries, archiveEntry{file: manifestFile(i+1, output.name, result), data: data})
+		}
+	}
+
+	if len(entries) > 0 {
+		archive, err := buildArchive(tx.job, entries)
+		var result *service.Result
+		if err == nil {
+			result, err = tx.promote(ctx, archive, "zip")
+		}
+		if err != nil {
+			return nil, tx.commitFailure(stepFailure(err))
+		}
+		tx.job.Archive = result.ID
+	}
+	return ids, nil
+}
+
+// promote saves data as a result, recording it for rollback unless it was
+// already stored
+func (tx *transaction) promote(ctx context.Context, data []byte, ext string) (*service.Result, error) {
+	result, err := tx.m.results.Save(ctx, data, ext)
+	if err != nil {
+		return nil, err
+	}
+	if !result.Existed {
+		tx.promoted = append(tx.promoted, result.ID)
+	}
+	return result, nil
+}
+
+// commitFailure attributes failure to the commit phase
+func (tx *transaction) commitFailure(failure *Failure) *Failure {
+	failure.Step = len(tx.job.Steps) + 1
+	failure.Operation = commitOperation
+	return failure
+}
+
+// cleanup removes staged outputs and, when the job failed, the results it
+// created. It runs detached from ctx so canceled jobs are still rolled back.
+func (tx *transaction) cleanup(ctx context.Context, failed bool) {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), rollbackTimeout)
+	defer cancel()
+
+	for _, key := range tx.staged {
+		if err := tx.m.staging.Delete(ctx, key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			tx.m.log.Warn("Failed to remove staged batch output", "job_id", tx.job.ID, "key", key, "error", err)
+		}
+	}
+	if !failed {
+		return
+	}
+	for _, id := range tx.promoted {
+		if err := tx.m.results.Delete(ctx, id); err != nil && service.CodeOf(err) != service.ErrCodeNotFound {
+			tx.m.log.Warn("Failed to roll back batch result", "job_id", tx.job.ID, "result_id", id, "error", err)
+		}
+	}
+	tx.m.log.Info("Batch job rolled back", "job_id", tx.job.ID, "staged", len(tx.staged), "results", len(tx.promoted))
+}
+
+// load reads an artifact into memory
+func (tx *transaction) load(ctx context.Context, a artifact) ([]byte, error) {
+	var (
+		obj storage.Object
+		err error
+	)
+	if a.document != "" {
+		obj, _, err = tx.m.documents.Open(ctx, a.document)
+	} else {
+		obj, _, err = tx.m.staging.Open(ctx, a.key)
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", a.name, err)
+	}
+	return data, nil
+}
+
+// stage stores an intermediate output under the job's staging prefix
+func (tx *transaction) stage(ctx context.Context, data []byte, name string) (artifact, error) {
+	key := fmt.Sprintf("%s%s/%d-%d.pdf", stagingPrefix, tx.job.ID, tx.job.Step, len(tx.staged))
+	tx.staged = append(tx.staged, key)
+	if _, err := tx.m.staging.Put(ctx, key, bytes.NewReader(data), "application/pdf"); err != nil {
+		return artifact{}, fmt.Errorf("failed to stage output: %w", err)
+	}
+	return artifact{name: name, key: key}, nil
+}
+
+// stepFailure reports a failure of a step as a whole
+func stepFailure(err error) *Failure {
+	code, message := describe(err)
+	return &Failure{Input: -1, Code: code, Error: message, cause: err}
+}
+
+// inputFailure reports a failure of one input of a step
+func inputFailure(index int, input artifact, err error) *Failure {
+	failure := stepFailure(err)
+	failure.Input = index
+	failure.InputName = input.name
+	return failure
+}
+
+// notStarted reports a job that failed before its first step
+func notStarted(err error) *Failure {
+	failure := stepFailure(err)
+	failure.Step = 0
+	return failure
+}
+
+// describe returns the code and client-safe message of err. Internal errors
+// are not exposed to clients, like in API error responses.
+func describe(err error) (service.ErrorCode, string) {
+	code := service.CodeOf(err)
+	var pdfErr *service.PDFError
+	if code == service.ErrCodeInternal || !errors.As(err, &pdfErr) {
+		return service.ErrCodeInternal, "internal error"
+	}
+	return code, pdfErr.Message
+}
+
+// failedInput returns the input a merge error is attributed to
+func failedInput(err error) (int, bool) {
+	var pdfErr *service.PDFError
+	if !errors.As(err, &pdfErr) {
+		return 0, false
+	}
+	index, ok := pdfErr.Details["input_index"].(int)
+	return index, ok
+}
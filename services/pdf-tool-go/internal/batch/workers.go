@@ -0,0 +1,126 @@
+package batch
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// workersPath is the admin path of the worker controls
+const workersPath = "/admin/batch/workers"
+
+// maxHoldWait bounds how long a drain request waits for running jobs,
+// within the admin listener's write timeout
+const maxHoldWait = 4 * time.Minute
+
+// workersStatus is the state of the worker pool as reported to operators
+type workersStatus struct {
+	Dispatching bool `json:"dispatching"`
+	Workers     int  `json:"workers"`
+	Running     int  `json:"running"`
+	Queued      int  `json:"queued"`
+}
+
+func closedChannel() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}
+
+// dispatching returns a channel closed while workers take jobs
+func (m *Manager) dispatching() <-chan struct{} {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.dispatch
+}
+
+// Dispatching reports whether workers take jobs off the queue
+func (m *Manager) Dispatching() bool {
+	select {
+	case <-m.dispatching():
+		return true
+	default:
+		return false
+	}
+}
+
+// Hold stops workers from taking jobs off the queue and waits until the
+// running jobs have finished or ctx is done. Jobs can still be submitted
+// and wait queued until Release is called.
+func (m *Manager) Hold(ctx context.Context) error {
+	m.mu.Lock()
+	select {
+	case <-m.dispatch:
+		m.dispatch = make(chan struct{})
+		m.log.Info("Batch workers held")
+	default:
+	}
+	m.mu.Unlock()
+
+	return m.running.Wait(ctx)
+}
+
+// Release lets held workers take jobs off the queue again
+func (m *Manager) Release() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	select {
+	case <-m.dispatch:
+	default:
+		close(m.dispatch)
+		m.log.Info("Batch workers released")
+	}
+}
+
+// WorkersHandler serves the worker controls on the admin listener at
+// /admin/batch/workers/: GET reports the pool, POST drain holds the
+// workers and waits up to ?timeout seconds (default 30) for running jobs,
+// answering 202 if some are still running, and POST resume releases them
+func (m *Manager) WorkersHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, workersPath), "/")
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, m.workersStatus())
+
+		case rest == "drain" && r.Method == http.MethodPost:
+			wait := 30 * time.Second
+			if raw := r.URL.Query().Get("timeout"); raw != "" {
+				seconds, err := strconv.Atoi(raw)
+				if err != nil || seconds < 0 {
+					http.Error(w, "timeout must be a non-negative number of seconds", http.StatusBadRequest)
+					return
+				}
+				wait = min(time.Duration(seconds)*time.Second, maxHoldWait)
+			}
+			ctx, cancel := context.WithTimeout(r.Context(), wait)
+			defer cancel()
+
+			status := http.StatusOK
+			if err := m.Hold(ctx); err != nil {
+				status = http.StatusAccepted
+			}
+			writeJSON(w, status, m.workersStatus())
+
+		case rest == "resume" && r.Method == http.MethodPost:
+			m.Release()
+			writeJSON(w, http.StatusOK, m.workersStatus())
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+func (m *Manager) workersStatus() workersStatus {
+	return workersStatus{
+		Dispatching: m.Dispatching(),
+		Workers:     m.cfg.Workers,
+		Running:     m.running.Count(),
+		Queued:      m.pending.len(),
+	}
+}
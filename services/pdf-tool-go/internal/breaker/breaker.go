@@ -0,0 +1,121 @@
+/**
+ * Circuit Breakers
+ *
+ * Circuit breakers around external dependencies (object storage, ICR
+ * backends, external tools), so a dependency that hangs or keeps failing
+ * is failed fast instead of tying up every request that touches it.
+ */
+
+package breaker
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/sony/gobreaker"
+)
+
+// ErrOpen is returned for calls rejected because their dependency's breaker
+// is open
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Set holds a breaker per dependency, created on first use. isFailure
+// decides which errors count against a dependency; errors caused by the
+// caller, such as a corrupt input, should not.
+type Set struct {
+	cfg       config.BreakerConfig
+	log       logger.Logger
+	isFailure func(error) bool
+
+	mu       sync.Mutex
+	breakers map[string]*gobreaker.CircuitBreaker
+}
+
+// New creates a set of breakers. With breakers disabled, calls run directly
+// and are only measured.
+func New(cfg config.BreakerConfig, log logger.Logger, isFailure func(error) bool) *Set {
+	return &Set{
+		cfg:       cfg,
+		log:       log,
+		isFailure: isFailure,
+		breakers:  make(map[string]*gobreaker.CircuitBreaker),
+	}
+}
+
+// Do runs fn through the breaker of dependency. It returns an error
+// wrapping ErrOpen without calling fn while the breaker is open.
+func (s *Set) Do(dependency string, fn func() error) error {
+	if s == nil {
+		return fn()
+	}
+
+	start := time.Now()
+	if !s.cfg.Enabled {
+		err := fn()
+		s.record(dependency, err, start)
+		return err
+	}
+
+	_, err := s.breaker(dependency).Execute(func() (interface{}, error) {
+		return nil, fn()
+	})
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		metrics.DependencyRejected(dependency)
+		return fmt.Errorf("%w: %s", ErrOpen, dependency)
+	}
+	s.record(dependency, err, start)
+	return err
+}
+
+// record measures a call that ran
+func (s *Set) record(dependency string, err error, start time.Time) {
+	result := "success"
+	if err != nil && s.isFailure(err) {
+		result = "failure"
+	}
+	metrics.DependencyCall(dependency, result, time.Since(start))
+}
+
+// breaker returns the breaker of dependency, creating it on first use
+func (s *Set) breaker(dependency string) *gobreaker.CircuitBreaker {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if cb, ok := s.breakers[dependency]; ok {
+		return cb
+	}
+	failures := uint32(s.cfg.Failures)
+	cb := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        dependency,
+		MaxRequests: uint32(s.cfg.HalfOpenRequests),
+		Timeout:     time.Duration(s.cfg.OpenTimeout) * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			return counts.ConsecutiveFailures >= failures
+		},
+		IsSuccessful: func(err error) bool {
+			return err == nil || !s.isFailure(err)
+		},
+		OnStateChange: s.stateChanged,
+	})
+	s.breakers[dependency] = cb
+	metrics.BreakerState(dependency, int(gobreaker.StateClosed))
+	return cb
+}
+
+// stateChanged logs and measures breaker transitions
+func (s *Set) stateChanged(dependency string, from, to gobreaker.State) {
+	metrics.BreakerState(dependency, int(to))
+	switch to {
+	case gobreaker.StateOpen:
+		s.log.Warn("Circuit breaker opened, failing calls fast", "dependency", dependency, "from", from.String())
+	case gobreaker.StateHalfOpen:
+		s.log.Info("Circuit breaker half-open, trying calls again", "dependency", dependency)
+	default:
+		s.log.Info("Circuit breaker closed", "dependency", dependency)
+	}
+}
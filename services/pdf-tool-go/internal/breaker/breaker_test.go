@@ -0,0 +1,63 @@
+package breaker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSet(t *testing.T) {
+	errBadInput := errors.New("bad input")
+	errHung := errors.New("hung")
+	cfg := config.BreakerConfig{Enabled: true, Failures: 2, OpenTimeout: 60, HalfOpenRequests: 1}
+	newSet := func(cfg config.BreakerConfig) *Set {
+		return New(cfg, logger.New("info", "text"), func(err error) bool {
+			return !errors.Is(err, errBadInput)
+		})
+	}
+	fail := func(err error) func() error {
+		return func() error { return err }
+	}
+
+	t.Run("Opens After Consecutive Failures", func(t *testing.T) {
+		s := newSet(cfg)
+		assert.ErrorIs(t, s.Do("gs", fail(errHung)), errHung)
+		assert.ErrorIs(t, s.Do("gs", fail(errHung)), errHung)
+
+		called := false
+		err := s.Do("gs", func() error {
+			called = true
+			return nil
+		})
+		assert.ErrorIs(t, err, ErrOpen)
+		assert.False(t, called)
+		// Other dependencies are unaffected
+		assert.NoError(t, s.Do("tesseract", fail(nil)))
+	})
+
+	t.Run("Caller Errors Do Not Count", func(t *testing.T) {
+		s := newSet(cfg)
+		for i := 0; i < 5; i++ {
+			assert.ErrorIs(t, s.Do("gs", fail(errBadInput)), errBadInput)
+		}
+		assert.NoError(t, s.Do("gs", fail(nil)))
+	})
+
+	t.Run("Successes Reset Failures", func(t *testing.T) {
+		s := newSet(cfg)
+		assert.Error(t, s.Do("gs", fail(errHung)))
+		assert.NoError(t, s.Do("gs", fail(nil)))
+		assert.Error(t, s.Do("gs", fail(errHung)))
+		assert.ErrorIs(t, s.Do("gs", fail(errHung)), errHung)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		s := newSet(config.BreakerConfig{})
+		for i := 0; i < 5; i++ {
+			assert.ErrorIs(t, s.Do("gs", fail(errHung)), errHung)
+		}
+	})
+}
@@ -0,0 +1,84 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// Input is a named input document
+type Input struct {
+	Name string
+	Data []byte
+}
+
+// Backend performs the operations behind the CLI commands
+type Backend interface {
+	Merge(ctx context.Context, inputs []Input, validate bool) ([]byte, error)
+	Split(ctx context.Context, input Input, pageRange string) ([][]byte, error)
+	Compress(ctx context.Context, input Input, level int, profile string) ([]byte, error)
+	ExtractText(ctx context.Context, input Input, ocr bool) (*service.ExtractTextResponse, error)
+	ExtractMetadata(ctx context.Context, input Input) (*service.MetadataResponse, error)
+}
+
+// Local runs operations in-process through the service layer, using the
+// same configuration sources as the server
+type Local struct {
+	service *service.PDFService
+}
+
+// NewLocal loads configuration and creates an in-process backend. Logs go
+// to stderr; only warnings and errors are shown unless verbose is set.
+func NewLocal(verbose bool) (*Local, error) {
+	cfg, err := config.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load configuration: %w", err)
+	}
+
+	level := "warn"
+	if verbose {
+		level = "debug"
+	}
+	return &Local{service: service.NewPDFService(logger.New(level, "text"), cfg)}, nil
+}
+
+// Merge merges inputs in order
+func (l *Local) Merge(ctx context.Context, inputs []Input, validate bool) ([]byte, error) {
+	req := &service.MergeRequest{
+		PDFs:       make([][]byte, len(inputs)),
+		InputNames: make([]string, len(inputs)),
+		Validate:   validate,
+	}
+	for i, input := range inputs {
+		req.PDFs[i] = input.Data
+		req.InputNames[i] = input.Name
+	}
+	return l.service.MergePDFs(ctx, req)
+}
+
+// Split splits input into the pages of pageRange
+func (l *Local) Split(ctx context.Context, input Input, pageRange string) ([][]byte, error) {
+	return l.service.SplitPDF(ctx, &service.SplitRequest{PDFData: input.Data, PageRange: pageRange})
+}
+
+// Compress compresses input
+func (l *Local) Compress(ctx context.Context, input Input, level int, profile string) ([]byte, error) {
+	return l.service.CompressPDF(ctx, &service.CompressRequest{
+		PDFData:          input.Data,
+		CompressionLevel: level,
+		Profile:          profile,
+	})
+}
+
+// ExtractText extracts the text of input
+func (l *Local) ExtractText(ctx context.Context, input Input, ocr bool) (*service.ExtractTextResponse, error) {
+	return l.service.ExtractText(ctx, &service.ExtractTextRequest{PDFData: input.Data, UseOCR: ocr})
+}
+
+// ExtractMetadata extracts the document metadata of input
+func (l *Local) ExtractMetadata(ctx context.Context, input Input) (*service.MetadataResponse, error) {
+	return l.service.ExtractMetadata(ctx, input.Data)
+}
@@ -0,0 +1,301 @@
+/**
+ * Command-Line Client
+ *
+ * Subcommands of the server binary for ops scripting and offline debugging.
+ * Each command either calls a remote server or runs the service layer locally.
+ */
+
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// Exit codes
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// command is a CLI subcommand
+type command struct {
+	usage string
+	run   func(ctx context.Context, e *env, args []string) error
+}
+
+var commands = map[string]command{
+	"merge":    {usage: "merge [-o out.pdf] [-validate] a.pdf b.pdf [...]", run: runMerge},
+	"split":    {usage: "split [-o dir] [-pages 1-3,5] in.pdf", run: runSplit},
+	"compress": {usage: "compress [-o out.pdf] [-level 1-3] [-profile screen|ebook|printer] in.pdf", run: runCompress},
+	"extract":  {usage: "extract text|metadata [-o out.json] [-ocr] in.pdf", run: runExtract},
+}
+
+var (
+	// errUsage marks invalid invocations, reported with exit code 2
+	errUsage = errors.New("invalid usage")
+	// errFlags marks flag parse failures, already reported by the flag package
+	errFlags = errors.New("invalid flags")
+)
+
+// IsCommand reports whether name is a CLI subcommand rather than a server flag
+func IsCommand(name string) bool {
+	_, ok := commands[name]
+	return ok || name == "help"
+}
+
+// env is the state shared by a command invocation
+type env struct {
+	name   string
+	usage  string
+	stdout io.Writer
+	stderr io.Writer
+
+	server  string
+	apiKey  string
+	verbose bool
+}
+
+// flagSet returns a flag set for the command with the common flags registered
+func (e *env) flagSet() *flag.FlagSet {
+	fs := flag.NewFlagSet(e.name, flag.ContinueOnError)
+	fs.SetOutput(e.stderr)
+	fs.StringVar(&e.server, "server", os.Getenv("PDF_TOOL_REMOTE"), "base URL of a remote server; empty runs locally")
+	fs.StringVar(&e.apiKey, "api-key", os.Getenv("PDF_TOOL_API_KEY"), "API key sent to the remote server")
+	fs.BoolVar(&e.verbose, "v", false, "verbose logging in local mode")
+	fs.Usage = func() {
+		fmt.Fprintf(e.stderr, "usage: pdf-tool %s\n", e.usage)
+		fs.PrintDefaults()
+	}
+	return fs
+}
+
+// parse parses args and checks the number of input files left over.
+// maxInputs of 0 means no upper bound.
+func (e *env) parse(fs *flag.FlagSet, args []string, minInputs, maxInputs int) error {
+	if err := fs.Parse(args); err != nil {
+		return errFlags
+	}
+	switch n := fs.NArg(); {
+	case minInputs == maxInputs && n != minInputs:
+		return fmt.Errorf("%w: expected %d input file, got %d", errUsage, minInputs, n)
+	case n < minInputs:
+		return fmt.Errorf("%w: expected at least %d input files, got %d", errUsage, minInputs, n)
+	case maxInputs > 0 && n > maxInputs:
+		return fmt.Errorf("%w: expected at most %d input files, got %d", errUsage, maxInputs, n)
+	}
+	return nil
+}
+
+// backend returns the remote client if -server was given, otherwise the
+// local service layer
+func (e *env) backend() (Backend, error) {
+	if e.server != "" {
+		return NewRemote(e.server, e.apiKey), nil
+	}
+	return NewLocal(e.verbose)
+}
+
+// Run executes the subcommand in args[0] and returns the process exit code
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 || args[0] == "help" {
+		printUsage(stdout)
+		return exitOK
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		printUsage(stderr)
+		return exitUsage
+	}
+
+	e := &env{name: args[0], usage: cmd.usage, stdout: stdout, stderr: stderr}
+	err := cmd.run(ctx, e, args[1:])
+	switch {
+	case err == nil:
+		return exitOK
+	case errors.Is(err, errFlags):
+		return exitUsage
+	case errors.Is(err, errUsage):
+		fmt.Fprintf(stderr, "pdf-tool %s: %v\nusage: pdf-tool %s\n", e.name, err, cmd.usage)
+		return exitUsage
+	default:
+		fmt.Fprintf(stderr, "pdf-tool %s: %s\n", e.name, describeError(err))
+		return exitFailure
+	}
+}
+
+func printUsage(w io.Writer) {
+	fmt.Fprintln(w, "usage: pdf-tool <command> [flags] [args]")
+	fmt.Fprintln(w, "\nWithout a command the HTTP server is started. Commands:")
+	for _, name := range []string{"merge", "split", "compress", "extract"} {
+		fmt.Fprintf(w, "  %s\n", commands[name].usage)
+	}
+	fmt.Fprintln(w, "\nCommon flags: -server URL (or PDF_TOOL_REMOTE), -api-key KEY (or PDF_TOOL_API_KEY), -v")
+}
+
+// describeError formats err with its service error code, if any
+func describeError(err error) string {
+	var pdfErr *service.PDFError
+	if errors.As(err, &pdfErr) {
+		return fmt.Sprintf("[%s] %v", pdfErr.Code, err)
+	}
+	return err.Error()
+}
+
+func runMerge(ctx context.Context, e *env, args []string) error {
+	fs := e.flagSet()
+	out := fs.String("o", "-", "output file, - for stdout")
+	validate := fs.Bool("validate", false, "fully validate inputs before merging")
+	if err := e.parse(fs, args, 2, 0); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	backend, err := e.backend()
+	if err != nil {
+		return err
+	}
+	data, err := backend.Merge(ctx, inputs, *validate)
+	if err != nil {
+		return err
+	}
+	return e.write(*out, data)
+}
+
+func runSplit(ctx context.Context, e *env, args []string) error {
+	fs := e.flagSet()
+	dir := fs.String("o", ".", "output directory")
+	pages := fs.String("pages", "all", "page range to split out")
+	if err := e.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	backend, err := e.backend()
+	if err != nil {
+		return err
+	}
+	parts, err := backend.Split(ctx, inputs[0], *pages)
+	if err != nil {
+		return err
+	}
+
+	// Written paths go to stdout so scripts can pick them up
+	base := strings.TrimSuffix(inputs[0].Name, filepath.Ext(inputs[0].Name))
+	for i, part := range parts {
+		path := filepath.Join(*dir, fmt.Sprintf("%s-%d.pdf", base, i+1))
+		if err := e.write(path, part); err != nil {
+			return err
+		}
+		fmt.Fprintln(e.stdout, path)
+	}
+	return nil
+}
+
+func runCompress(ctx context.Context, e *env, args []string) error {
+	fs := e.flagSet()
+	out := fs.String("o", "-", "output file, - for stdout")
+	level := fs.Int("level", 1, "compression level (1-3)")
+	profile := fs.String("profile", "", "Ghostscript profile: screen, ebook, printer")
+	if err := e.parse(fs, args, 1, 1); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	backend, err := e.backend()
+	if err != nil {
+		return err
+	}
+	data, err := backend.Compress(ctx, inputs[0], *level, *profile)
+	if err != nil {
+		return err
+	}
+	if err := e.write(*out, data); err != nil {
+		return err
+	}
+	fmt.Fprintf(e.stderr, "%s: %d -> %d bytes\n", inputs[0].Name, len(inputs[0].Data), len(data))
+	return nil
+}
+
+func runExtract(ctx context.Context, e *env, args []string) error {
+	if len(args) == 0 || (args[0] != "text" && args[0] != "metadata") {
+		return fmt.Errorf("%w: extract target must be text or metadata", errUsage)
+	}
+	target := args[0]
+
+	fs := e.flagSet()
+	out := fs.String("o", "-", "output file, - for stdout")
+	ocr := fs.Bool("ocr", false, "use OCR for text extraction")
+	if err := e.parse(fs, args[1:], 1, 1); err != nil {
+		return err
+	}
+
+	inputs, err := readInputs(fs.Args())
+	if err != nil {
+		return err
+	}
+	backend, err := e.backend()
+	if err != nil {
+		return err
+	}
+
+	var result interface{}
+	if target == "text" {
+		result, err = backend.ExtractText(ctx, inputs[0], *ocr)
+	} else {
+		result, err = backend.ExtractMetadata(ctx, inputs[0])
+	}
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode result: %w", err)
+	}
+	return e.write(*out, append(data, '\n'))
+}
+
+// readInputs reads the named input files
+func readInputs(paths []string) ([]Input, error) {
+	inputs := make([]Input, len(paths))
+	for i, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read input: %w", err)
+		}
+		inputs[i] = Input{Name: filepath.Base(path), Data: data}
+	}
+	return inputs, nil
+}
+
+// write writes data to path, or to stdout when path is "-"
+func (e *env) write(path string, data []byte) error {
+	if path == "-" {
+		_, err := e.stdout.Write(data)
+		return err
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("failed to write output: %w", err)
+	}
+	return nil
+}
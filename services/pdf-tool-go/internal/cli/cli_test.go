@@ -0,0 +1,77 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	in := filepath.Join(dir, "report.pdf")
+	require.NoError(t, os.WriteFile(in, []byte("%PDF-1.4"), 0644))
+
+	var stdout, stderr bytes.Buffer
+	run := func(args ...string) int {
+		stdout.Reset()
+		stderr.Reset()
+		return Run(context.Background(), args, &stdout, &stderr)
+	}
+
+	t.Run("Help", func(t *testing.T) {
+		assert.Equal(t, exitOK, run("help"))
+		assert.Contains(t, stdout.String(), "merge")
+	})
+
+	t.Run("Usage Errors", func(t *testing.T) {
+		assert.Equal(t, exitUsage, run("merge", in))
+		assert.Contains(t, stderr.String(), "at least 2 input files")
+		assert.Equal(t, exitUsage, run("extract", "images", in))
+		assert.Equal(t, exitUsage, run("split", "-bogus", in))
+	})
+
+	t.Run("Remote Split", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/api/v1/pdf/split", r.URL.Path)
+			assert.Equal(t, "1-2", r.URL.Query().Get("pages"))
+			assert.Equal(t, "secret", r.Header.Get("X-API-Key"))
+			_, header, err := r.FormFile("pdf")
+			require.NoError(t, err)
+			assert.Equal(t, "report.pdf", header.Filename)
+			json.NewEncoder(w).Encode(map[string]interface{}{
+				"files": [][]byte{[]byte("one"), []byte("two")},
+				"count": 2,
+			})
+		}))
+		defer server.Close()
+
+		code := run("split", "-server", server.URL, "-api-key", "secret", "-pages", "1-2", "-o", dir, in)
+		assert.Equal(t, exitOK, code, stderr.String())
+		data, err := os.ReadFile(filepath.Join(dir, "report-2.pdf"))
+		require.NoError(t, err)
+		assert.Equal(t, "two", string(data))
+	})
+
+	t.Run("Remote Error", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			json.NewEncoder(w).Encode(map[string]string{"error": "PDF is encrypted", "code": "PDF_ENCRYPTED"})
+		}))
+		defer server.Close()
+
+		_, err := NewRemote(server.URL, "").Compress(context.Background(), Input{Name: "a.pdf"}, 1, "")
+		assert.Equal(t, service.ErrCodeEncrypted, service.CodeOf(err))
+
+		assert.Equal(t, exitFailure, run("compress", "-server", server.URL, in))
+		assert.Contains(t, stderr.String(), "[PDF_ENCRYPTED]")
+	})
+}
@@ -0,0 +1,153 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo"
+)
+
+// Remote runs operations against a server's HTTP API
+type Remote struct {
+	baseURL string
+	apiKey  string
+	client  *http.Client
+}
+
+// NewRemote creates a backend calling the server at baseURL
+func NewRemote(baseURL, apiKey string) *Remote {
+	return &Remote{
+		baseURL: strings.TrimRight(baseURL, "/"),
+		apiKey:  apiKey,
+		client:  &http.Client{},
+	}
+}
+
+// Merge merges inputs in order
+func (r *Remote) Merge(ctx context.Context, inputs []Input, validate bool) ([]byte, error) {
+	query := url.Values{"validate": {strconv.FormatBool(validate)}}
+	return r.post(ctx, "merge", query, "pdfs", inputs)
+}
+
+// Split splits input into the pages of pageRange
+func (r *Remote) Split(ctx context.Context, input Input, pageRange string) ([][]byte, error) {
+	body, err := r.post(ctx, "split", url.Values{"pages": {pageRange}}, "pdf", []Input{input})
+	if err != nil {
+		return nil, err
+	}
+	var result struct {
+		Files [][]byte `json:"files"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return result.Files, nil
+}
+
+// Compress compresses input
+func (r *Remote) Compress(ctx context.Context, input Input, level int, profile string) ([]byte, error) {
+	query := url.Values{"level": {strconv.Itoa(level)}}
+	if profile != "" {
+		query.Set("profile", profile)
+	}
+	return r.post(ctx, "compress", query, "pdf", []Input{input})
+}
+
+// ExtractText extracts the text of input
+func (r *Remote) ExtractText(ctx context.Context, input Input, ocr bool) (*service.ExtractTextResponse, error) {
+	body, err := r.post(ctx, "extract/text", url.Values{"ocr": {strconv.FormatBool(ocr)}}, "pdf", []Input{input})
+	if err != nil {
+		return nil, err
+	}
+	var result service.ExtractTextResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// ExtractMetadata extracts the document metadata of input
+func (r *Remote) ExtractMetadata(ctx context.Context, input Input) (*service.MetadataResponse, error) {
+	body, err := r.post(ctx, "extract/metadata", nil, "pdf", []Input{input})
+	if err != nil {
+		return nil, err
+	}
+	var result service.MetadataResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+	return &result, nil
+}
+
+// post uploads inputs as the multipart field to /api/v1/pdf/<operation> and
+// returns the response body. Error responses are turned back into coded
+// service errors.
+func (r *Remote) post(ctx context.Context, operation string, query url.Values, field string, inputs []Input) ([]byte, error) {
+	var buf bytes.Buffer
+	form := multipart.NewWriter(&buf)
+	for _, input := range inputs {
+		part, err := form.CreateFormFile(field, input.Name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+		if _, err := part.Write(input.Data); err != nil {
+			return nil, fmt.Errorf("failed to build request: %w", err)
+		}
+	}
+	if err := form.Close(); err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+
+	endpoint := r.baseURL + "/api/v1/pdf/" + operation
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &buf)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %w", err)
+	}
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	req.Header.Set("User-Agent", "pdf-tool-cli/"+buildinfo.Get().Version)
+	if r.apiKey != "" {
+		req.Header.Set("X-API-Key", r.apiKey)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode >= http.StatusBadRequest {
+		return nil, remoteError(resp.StatusCode, body)
+	}
+	return body, nil
+}
+
+// remoteError decodes an API error response
+func remoteError(status int, body []byte) error {
+	var apiErr struct {
+		Error string            `json:"error"`
+		Code  service.ErrorCode `json:"code"`
+	}
+	if err := json.Unmarshal(body, &apiErr); err != nil || apiErr.Error == "" {
+		return fmt.Errorf("server returned %d %s", status, http.StatusText(status))
+	}
+	if apiErr.Code == "" {
+		return fmt.Errorf("server returned %d: %s", status, apiErr.Error)
+	}
+	return service.NewError(apiErr.Code, apiErr.Error, nil)
+}
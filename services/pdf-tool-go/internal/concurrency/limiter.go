@@ -0,0 +1,223 @@
+/**
+ * Concurrency Limiter
+ *
+ * Bounds the number of CPU/memory-heavy operations running at once,
+ * with a bounded queue for callers waiting for a free slot. Queued callers
+ * can be told their position and estimated wait.
+ */
+
+package concurrency
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSaturated is returned when all slots are busy and the queue is full
+// or the queue wait timed out
+var ErrSaturated = errors.New("concurrency limit reached")
+
+// holdWeight is the weight of the latest hold time in the moving average
+// used to estimate waits
+const holdWeight = 0.2
+
+// Wait describes a caller joining the queue
+type Wait struct {
+	// Position counts the caller and the callers queued ahead of it
+	Position int
+	// Estimated is the expected wait for a slot, or 0 while no slot has
+	// been released yet to estimate from
+	Estimated time.Duration
+}
+
+type observerKey struct{}
+
+// WithObserver returns a context under which Acquire calls observe when
+// the caller has to queue for a slot
+func WithObserver(ctx context.Context, observe func(Wait)) context.Context {
+	return context.WithValue(ctx, observerKey{}, observe)
+}
+
+// Limiter is a semaphore with a bounded waiting queue. Waiters are served
+// in arrival order, and both bounds can be changed at runtime.
+type Limiter struct {
+	queueTimeout time.Duration
+
+	mu        sync.Mutex
+	limit     int
+	queueSize int
+	inFlight  int
+	// waiters are the queued callers, oldest first; a waiter's channel is
+	// closed once it has been given a slot
+	waiters []chan struct{}
+	// avgHold is a moving average of how long slots are held
+	avgHold time.Duration
+}
+
+// NewLimiter creates a limiter allowing maxConcurrent holders and up to
+// queueSize waiters, each waiting at most queueTimeout (0 waits until the
+// caller's context is done)
+func NewLimiter(maxConcurrent, queueSize int, queueTimeout time.Duration) *Limiter {
+	l := &Limiter{queueTimeout: queueTimeout}
+	l.Resize(maxConcurrent, queueSize)
+	return l
+}
+
+// Resize changes the number of concurrent holders and queued waiters.
+// Lowering the limit does not interrupt holders; new callers wait until
+// enough slots are released. Waiters beyond a lowered queue size keep their
+// place.
+func (l *Limiter) Resize(maxConcurrent, queueSize int) {
+	if maxConcurrent < 1 {
+		maxConcurrent = 1
+	}
+	if queueSize < 0 {
+		queueSize = 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.limit = maxConcurrent
+	l.queueSize = queueSize
+	l.grant()
+}
+
+// Acquire reserves a slot, queueing if none is free. The returned release
+// function must be called exactly once when the work is done; extra calls
+// are ignored.
+func (l *Limiter) Acquire(ctx context.Context) (func(), error) {
+	l.mu.Lock()
+	// Fast path: free slot and nobody waiting for it
+	if l.inFlight < l.limit && len(l.waiters) == 0 {
+		l.inFlight++
+		l.mu.Unlock()
+		return l.releaseFunc(), nil
+	}
+
+	// Join the queue if there is room
+	if len(l.waiters) >= l.queueSize {
+		l.mu.Unlock()
+		return nil, ErrSaturated
+	}
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	position := len(l.waiters)
+	l.mu.Unlock()
+
+	if observe, ok := ctx.Value(observerKey{}).(func(Wait)); ok {
+		observe(Wait{Position: position, Estimated: l.EstimatedWait(position)})
+	}
+
+	var timeout <-chan time.Time
+	if l.queueTimeout > 0 {
+		timer := time.NewTimer(l.queueTimeout)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+
+	var err error
+	select {
+	case <-ready:
+		return l.releaseFunc(), nil
+	case <-timeout:
+		err = ErrSaturated
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if !l.leave(ready) {
+		// Given a slot while giving up; hand it on
+		l.inFlight--
+		l.grant()
+	}
+	return nil, err
+}
+
+// InFlight returns the number of held slots
+func (l *Limiter) InFlight() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.inFlight
+}
+
+// Queued returns the number of callers waiting for a slot
+func (l *Limiter) Queued() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return len(l.waiters)
+}
+
+// Capacity returns the maximum number of concurrent holders
+func (l *Limiter) Capacity() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.limit
+}
+
+// QueueSize returns the maximum number of queued callers
+func (l *Limiter) QueueSize() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.queueSize
+}
+
+// grant hands free slots to waiters in arrival order. The caller holds l.mu.
+func (l *Limiter) grant() {
+	for l.inFlight < l.limit && len(l.waiters) > 0 {
+		l.inFlight++
+		close(l.waiters[0])
+		l.waiters = l.waiters[1:]
+	}
+}
+
+// leave removes a waiter from the queue. It returns false if the waiter
+// was already given a slot. The caller holds l.mu.
+func (l *Limiter) leave(ready chan struct{}) bool {
+	for i, waiter := range l.waiters {
+		if waiter == ready {
+			l.waiters = append(l.waiters[:i:i], l.waiters[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// EstimatedWait returns how long a caller at position in the queue is
+// expected to wait, assuming every slot frees up once per average hold
+func (l *Limiter) EstimatedWait(position int) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	rounds := (position + l.limit - 1) / l.limit
+	return time.Duration(rounds) * l.avgHold
+}
+
+func (l *Limiter) releaseFunc() func() {
+	var once sync.Once
+	start := time.Now()
+	return func() {
+		once.Do(func() {
+			l.mu.Lock()
+			l.inFlight--
+			l.grant()
+			l.mu.Unlock()
+			l.held(time.Since(start))
+		})
+	}
+}
+
+// held records how long a slot was held
+func (l *Limiter) held(d time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.avgHold == 0 {
+		l.avgHold = d
+		return
+	}
+	l.avgHold += time.Duration(holdWeight * float64(d-l.avgHold))
+}
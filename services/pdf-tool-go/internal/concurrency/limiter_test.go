@@ -0,0 +1,136 @@
+package concurrency
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Acquire(t *testing.T) {
+	t.Run("Within Capacity", func(t *testing.T) {
+		l := NewLimiter(2, 0, 0)
+		release1, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		release2, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		assert.Equal(t, 2, l.InFlight())
+
+		release1()
+		release1()
+		release2()
+		assert.Equal(t, 0, l.InFlight())
+	})
+
+	t.Run("Saturated Without Queue", func(t *testing.T) {
+		l := NewLimiter(1, 0, 0)
+		release, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		defer release()
+
+		_, err = l.Acquire(context.Background())
+		assert.ErrorIs(t, err, ErrSaturated)
+	})
+
+	t.Run("Queue Timeout", func(t *testing.T) {
+		l := NewLimiter(1, 1, 10*time.Millisecond)
+		release, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		defer release()
+
+		_, err = l.Acquire(context.Background())
+		assert.ErrorIs(t, err, ErrSaturated)
+		assert.Equal(t, 0, l.Queued())
+	})
+
+	t.Run("Queued Caller Gets Released Slot", func(t *testing.T) {
+		l := NewLimiter(1, 1, time.Second)
+		release, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+			release()
+		}()
+
+		release2, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		release2()
+	})
+
+	t.Run("Queued Caller Is Told Its Position", func(t *testing.T) {
+		l := NewLimiter(2, 2, time.Second)
+		l.held(4 * time.Second)
+		release1, _ := l.Acquire(context.Background())
+		release2, _ := l.Acquire(context.Background())
+
+		var wait Wait
+		ctx := WithObserver(context.Background(), func(w Wait) {
+			wait = w
+			release1()
+		})
+		release3, err := l.Acquire(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, Wait{Position: 1, Estimated: 4 * time.Second}, wait)
+		release2()
+		release3()
+	})
+}
+
+func TestLimiter_Resize(t *testing.T) {
+	t.Run("Raised Limit Serves Waiters", func(t *testing.T) {
+		l := NewLimiter(1, 1, time.Second)
+		release, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		defer release()
+
+		ctx := WithObserver(context.Background(), func(Wait) {
+			l.Resize(2, 1)
+		})
+		release2, err := l.Acquire(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, 2, l.InFlight())
+		release2()
+	})
+
+	t.Run("Lowered Limit Waits For Releases", func(t *testing.T) {
+		l := NewLimiter(2, 0, 0)
+		release1, _ := l.Acquire(context.Background())
+		release2, _ := l.Acquire(context.Background())
+		l.Resize(1, 0)
+		assert.Equal(t, 1, l.Capacity())
+
+		release1()
+		_, err := l.Acquire(context.Background())
+		assert.ErrorIs(t, err, ErrSaturated)
+
+		release2()
+		release3, err := l.Acquire(context.Background())
+		assert.NoError(t, err)
+		release3()
+	})
+
+	t.Run("Abandoned Wait Leaves The Queue", func(t *testing.T) {
+		l := NewLimiter(1, 1, 0)
+		release, _ := l.Acquire(context.Background())
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err := l.Acquire(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Equal(t, 0, l.Queued())
+
+		release()
+		assert.Equal(t, 0, l.InFlight())
+	})
+}
+
+func TestLimiter_EstimatedWait(t *testing.T) {
+	l := NewLimiter(2, 4, 0)
+	assert.Zero(t, l.EstimatedWait(1))
+
+	l.held(10 * time.Second)
+	l.held(5 * time.Second)
+	assert.Equal(t, 9*time.Second, l.EstimatedWait(2))
+	assert.Equal(t, 18*time.Second, l.EstimatedWait(3))
+}
@@ -8,24 +8,70 @@
 package config
 
 import (
+	"context"
 	"fmt"
+	"net"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
 	"strings"
+	"text/template"
+	"time"
 
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/secrets"
 	"github.com/spf13/viper"
 )
 
 // Config holds all application configuration
 type Config struct {
-	Environment string         `mapstructure:"environment"`
-	Port        int            `mapstructure:"port"`
-	LogLevel    string         `mapstructure:"log_level"`
-	LogFormat   string         `mapstructure:"log_format"`
-	RateLimit   RateLimitConfig `mapstructure:"rate_limit"`
-	Server      ServerConfig    `mapstructure:"server"`
-	PDF         PDFConfig       `mapstructure:"pdf"`
-	Storage     StorageConfig   `mapstructure:"storage"`
-	CORS        CORSConfig      `mapstructure:"cors"`
-	Telemetry   TelemetryConfig `mapstructure:"telemetry"`
+	Environment    string             `mapstructure:"environment"`
+	Port           int                `mapstructure:"port"`
+	LogLevel       string             `mapstructure:"log_level"`
+	LogFormat      string             `mapstructure:"log_format"`
+	RateLimit      RateLimitConfig    `mapstructure:"rate_limit"`
+	Server         ServerConfig       `mapstructure:"server"`
+	PDF            PDFConfig          `mapstructure:"pdf"`
+	Storage        StorageConfig      `mapstructure:"storage"`
+	CORS           CORSConfig         `mapstructure:"cors"`
+	Telemetry      TelemetryConfig    `mapstructure:"telemetry"`
+	Auth           AuthConfig         `mapstructure:"auth"`
+	Timeouts       TimeoutConfig      `mapstructure:"timeouts"`
+	Concurrency    ConcurrencyConfig  `mapstructure:"concurrency"`
+	LoadShedding   LoadSheddingConfig `mapstructure:"load_shedding"`
+	Janitor        JanitorConfig      `mapstructure:"janitor"`
+	Guardrails     GuardrailConfig    `mapstructure:"guardrails"`
+	Sandbox        SandboxConfig      `mapstructure:"sandbox"`
+	Breakers       BreakerConfig      `mapstructure:"breakers"`
+	Health         HealthConfig       `mapstructure:"health"`
+	Admin          AdminConfig        `mapstructure:"admin"`
+	Audit          AuditConfig        `mapstructure:"audit"`
+	AuditTrail     AuditTrailConfig   `mapstructure:"audit_trail"`
+	LinkCheck      LinkCheckConfig    `mapstructure:"link_check"`
+	Lifecycle      LifecycleConfig    `mapstructure:"lifecycle"`
+	Retention      RetentionConfig    `mapstructure:"retention"`
+	Tenants        []TenantConfig     `mapstructure:"tenants"`
+	Cron           CronConfig         `mapstructure:"cron"`
+	ICR            ICRConfig          `mapstructure:"icr"`
+	Entities       EntityConfig       `mapstructure:"entities"`
+	PII            PIIConfig          `mapstructure:"pii"`
+	Prepress       PrepressConfig     `mapstructure:"prepress"`
+	Images         ImageConfig        `mapstructure:"images"`
+	DecryptLockout LockoutConfig      `mapstructure:"decrypt_lockout"`
+	Batch          BatchConfig        `mapstructure:"batch"`
+	Database       DatabaseConfig     `mapstructure:"database"`
+	Onboarding     OnboardingConfig   `mapstructure:"onboarding"`
+	Share          ShareConfig        `mapstructure:"share"`
+	Email          EmailConfig        `mapstructure:"email"`
+	Notifications  NotificationConfig `mapstructure:"notifications"`
+	Watch          WatchConfig        `mapstructure:"watch"`
+	IMAP           IMAPConfig         `mapstructure:"imap"`
+	Connectors     ConnectorsConfig   `mapstructure:"connectors"`
+	ESign          ESignConfig        `mapstructure:"esign"`
+	Provenance     ProvenanceConfig   `mapstructure:"provenance"`
+	Plugins        []PluginConfig     `mapstructure:"plugins"`
+	Scripting      ScriptingConfig    `mapstructure:"scripting"`
+	Versioning     VersioningConfig   `mapstructure:"versioning"`
 }
 
 // RateLimitConfig configures rate limiting
@@ -33,6 +79,35 @@ type RateLimitConfig struct {
 	Enabled        bool  `mapstructure:"enabled"`
 	RequestsPerMin int   `mapstructure:"requests_per_minute"`
 	Burst          int   `mapstructure:"burst"`
+	// Endpoints overrides limits per route, keyed by route path such as
+	// "/api/v1/pdf/extract/text" or "/api/v1/pdf/:docid/pages/:n"
+	Endpoints map[string]EndpointRateLimit `mapstructure:"endpoints"`
+	// Redis shares limits across replicas; without an address each
+	// instance limits its own requests
+	Redis RateLimitRedisConfig `mapstructure:"redis"`
+}
+
+// RateLimitRedisConfig configures the Redis server holding shared rate
+// limit windows
+type RateLimitRedisConfig struct {
+	Address   string `mapstructure:"address"`
+	Password  string `mapstructure:"password"`
+	DB        int    `mapstructure:"db"`
+	KeyPrefix string `mapstructure:"key_prefix"`
+	// Timeout bounds each Redis round trip, in milliseconds; slower calls
+	// fall back to local limiting
+	Timeout int `mapstructure:"timeout"`
+}
+
+// EndpointRateLimit weights or separately limits requests to one endpoint
+type EndpointRateLimit struct {
+	// Cost is the number of tokens a request takes; 0 means 1
+	Cost int `mapstructure:"cost"`
+	// RequestsPerMin gives the endpoint a bucket of its own, refilled at
+	// this rate, instead of drawing from the client's shared bucket. Burst
+	// sizes it, defaulting to the shared burst.
+	RequestsPerMin int `mapstructure:"requests_per_minute"`
+	Burst          int `mapstructure:"burst"`
 }
 
 // ServerConfig holds HTTP server settings
@@ -41,17 +116,41 @@ type ServerConfig struct {
 	WriteTimeout   int `mapstructure:"write_timeout"`
 	IdleTimeout    int `mapstructure:"idle_timeout"`
 	MaxHeaderBytes int `mapstructure:"max_header_bytes"`
+	// MaxMultipartMemory is how much of a multipart upload is held in
+	// memory; the rest is spooled to temp files
+	MaxMultipartMemory int64 `mapstructure:"max_multipart_memory"`
+	// TrustedProxies are the addresses or CIDR ranges of reverse proxies
+	// whose X-Forwarded-For headers name the client. Without them the
+	// client is the peer address, so clients cannot pick the IP address
+	// they are rate limited and locked out by.
+	TrustedProxies []string `mapstructure:"trusted_proxies"`
 }
 
 // PDFConfig holds PDF processing settings
 type PDFConfig struct {
-	MaxFileSize      int64    `mapstructure:"max_file_size"`
-	AllowedFormats   []string `mapstructure:"allowed_formats"`
-	TempDir          string   `mapstructure:"temp_dir"`
-	MaxPages         int      `mapstructure:"max_pages"`
-	OCREnabled       bool     `mapstructure:"ocr_enabled"`
-	OCRLanguages     []string `mapstructure:"ocr_languages"`
-	CompressionLevel int      `mapstructure:"compression_level"`
+	MaxFileSize    int64    `mapstructure:"max_file_size"`
+	AllowedFormats []string `mapstructure:"allowed_formats"`
+	TempDir        string   `mapstructure:"temp_dir"`
+	MaxPages       int      `mapstructure:"max_pages"`
+	OCREnabled     bool     `mapstructure:"ocr_enabled"`
+	OCRLanguages   []string `mapstructure:"ocr_languages"`
+	// OCRDPI is the resolution pages are rendered at for OCR
+	OCRDPI int `mapstructure:"ocr_dpi"`
+	// OCRLowConfidence is the mean word confidence (0-100) below which a
+	// page is flagged as a likely poor scan
+	OCRLowConfidence float64 `mapstructure:"ocr_low_confidence"`
+	// ChunkSize and ChunkOverlap are the default chunk size and overlap, in
+	// characters, of chunked text output
+	ChunkSize        int `mapstructure:"chunk_size"`
+	ChunkOverlap     int `mapstructure:"chunk_overlap"`
+	CompressionLevel int `mapstructure:"compression_level"`
+	StagingWorkers   int `mapstructure:"staging_workers"`
+	// MaxMergeFiles is the most documents one merge accepts, which also
+	// sizes the request body limit of merge uploads; 0 is unlimited
+	MaxMergeFiles int `mapstructure:"max_merge_files"`
+	// StreamBatchPages is the number of pages streamed conversions render
+	// at a time, which bounds their memory use regardless of page count
+	StreamBatchPages int `mapstructure:"stream_batch_pages"`
 }
 
 // StorageConfig holds storage settings
@@ -60,11 +159,37 @@ type StorageConfig struct {
 	LocalPath string `mapstructure:"local_path"`
 	S3Bucket  string `mapstructure:"s3_bucket"`
 	S3Region  string `mapstructure:"s3_region"`
+	// S3Endpoint overrides the S3 endpoint for S3-compatible stores (e.g. MinIO)
+	S3Endpoint string `mapstructure:"s3_endpoint"`
+	// Static S3 credentials. When unset the default AWS credential chain is used.
+	S3AccessKeyID     string `mapstructure:"s3_access_key_id"`
+	S3SecretAccessKey string `mapstructure:"s3_secret_access_key"`
+}
+
+// CORSPolicy is a set of CORS rules
+type CORSPolicy struct {
+	// AllowedOrigins are exact origins, "*", or wildcard subdomains such as
+	// "https://*.example.com"
+	AllowedOrigins   []string `mapstructure:"allowed_origins"`
+	AllowedMethods   []string `mapstructure:"allowed_methods"`
+	AllowedHeaders   []string `mapstructure:"allowed_headers"`
+	ExposedHeaders   []string `mapstructure:"exposed_headers"`
+	AllowCredentials bool     `mapstructure:"allow_credentials"`
+	MaxAge           int      `mapstructure:"max_age"` // seconds
+}
+
+// CORSRoute overrides the CORS policy for requests under PathPrefix.
+// Unset lists and max age are inherited from the default policy.
+type CORSRoute struct {
+	PathPrefix string `mapstructure:"path_prefix"`
+	CORSPolicy `mapstructure:",squash"`
 }
 
 // CORSConfig holds CORS settings
 type CORSConfig struct {
-	AllowedOrigins []string `mapstructure:"allowed_origins"`
+	CORSPolicy `mapstructure:",squash"`
+	// Routes are per-route overrides; the longest matching prefix wins
+	Routes []CORSRoute `mapstructure:"routes"`
 }
 
 // TelemetryConfig holds observability settings
@@ -74,6 +199,619 @@ type TelemetryConfig struct {
 	SamplingRatio float64 `mapstructure:"sampling_ratio"`
 }
 
+// AuthConfig holds caller authentication settings
+type AuthConfig struct {
+	// PrivilegedKeys are API keys allowed to override per-request limits
+	PrivilegedKeys []string `mapstructure:"privileged_keys"`
+	// AllowAnonymous lets requests without an API key use the API as the
+	// default tenant. Unknown keys are rejected either way.
+	AllowAnonymous bool `mapstructure:"allow_anonymous"`
+}
+
+// TimeoutConfig holds processing deadlines in seconds.
+// Operations without an entry fall back to Default; 0 disables the deadline.
+type TimeoutConfig struct {
+	Default    int            `mapstructure:"default"`
+	Operations map[string]int `mapstructure:"operations"`
+}
+
+// ConcurrencyConfig bounds concurrent CPU/memory-heavy operations
+// (rendering, OCR, compression, merging). Requests over the limit wait in
+// a queue of QueueSize for up to QueueTimeout; with QueueSize 0 they are
+// rejected with 503 at once. Timeouts are in seconds.
+type ConcurrencyConfig struct {
+	MaxConcurrent int `mapstructure:"max_concurrent"`
+	QueueSize     int `mapstructure:"queue_size"`
+	QueueTimeout  int `mapstructure:"queue_timeout"`
+	RetryAfter    int `mapstructure:"retry_after"`
+}
+
+// LoadSheddingConfig rejects processing requests while heavy operations
+// wait too long for a slot. Once the 99th percentile wait over the last
+// Window seconds reaches TargetWait milliseconds bulk requests are shed;
+// beyond it interactive requests are shed increasingly, all of them at
+// twice the target. Clients mark requests bulk with "X-Priority: bulk".
+// Processing requests and batch submissions are shed; document and result
+// storage is not, since it does not take processing slots. Waits are only
+// measured with a concurrency limit. RetryAfter is in seconds.
+type LoadSheddingConfig struct {
+	Enabled    bool `mapstructure:"enabled"`
+	TargetWait int  `mapstructure:"target_wait"`
+	Window     int  `mapstructure:"window"`
+	RetryAfter int  `mapstructure:"retry_after"`
+}
+
+// ScriptingConfig configures the sandbox tenant scripts run in. Timeout
+// is in milliseconds and bounds each script run; MemoryBytes bounds what
+// a run may allocate in total; CallStackSize and RegistrySize cap the Lua
+// call and value stacks.
+type ScriptingConfig struct {
+	Enabled       bool `mapstructure:"enabled"`
+	Timeout       int  `mapstructure:"timeout"`
+	MemoryBytes   int  `mapstructure:"memory_bytes"`
+	CallStackSize int  `mapstructure:"call_stack_size"`
+	RegistrySize  int  `mapstructure:"registry_size"`
+}
+
+// VersioningConfig configures API version negotiation. Default is the
+// version unversioned /api/ paths are served at when the Accept header
+// selects none; Deprecations announce the retirement of versions, keyed
+// by version name such as "v1".
+type VersioningConfig struct {
+	Default      int                           `mapstructure:"default"`
+	Deprecations map[string]VersionDeprecation `mapstructure:"deprecations"`
+}
+
+// VersionDeprecation announces a deprecated API version. Date and Sunset
+// are YYYY-MM-DD; Link points to migration notes.
+type VersionDeprecation struct {
+	Date   string `mapstructure:"date"`
+	Sunset string `mapstructure:"sunset"`
+	Link   string `mapstructure:"link"`
+}
+
+// JanitorConfig configures cleanup of orphaned temp files.
+// Interval and TTL are in seconds; TTL must exceed the longest operation timeout.
+type JanitorConfig struct {
+	Enabled        bool  `mapstructure:"enabled"`
+	Interval       int   `mapstructure:"interval"`
+	TTL            int   `mapstructure:"ttl"`
+	CleanOnStartup bool  `mapstructure:"clean_on_startup"`
+	AlertBytes     int64 `mapstructure:"alert_bytes"`
+}
+
+// GuardrailConfig sets resource thresholds checked before accepting work.
+// Sizes are in bytes; WaitTimeout and RetryAfter are in seconds.
+type GuardrailConfig struct {
+	Enabled     bool  `mapstructure:"enabled"`
+	MinFreeDisk int64 `mapstructure:"min_free_disk"`
+	MaxMemory   int64 `mapstructure:"max_memory"`
+	WaitTimeout int   `mapstructure:"wait_timeout"`
+	RetryAfter  int   `mapstructure:"retry_after"`
+}
+
+// SandboxConfig controls execution of external tools.
+// Tools maps allow-listed tool names to binary paths (empty resolves via PATH);
+// anything not listed cannot be executed. Timeout and CPUSeconds are in seconds.
+type SandboxConfig struct {
+	WorkDir        string            `mapstructure:"work_dir"`
+	Timeout        int               `mapstructure:"timeout"`
+	CPUSeconds     int               `mapstructure:"cpu_seconds"`
+	MemoryBytes    int64             `mapstructure:"memory_bytes"`
+	FileSizeBytes  int64             `mapstructure:"file_size_bytes"`
+	OpenFiles      int               `mapstructure:"open_files"`
+	MaxOutputBytes int64             `mapstructure:"max_output_bytes"`
+	Tools          map[string]string `mapstructure:"tools"`
+	NSJail         NSJailConfig      `mapstructure:"nsjail"`
+}
+
+// NSJailConfig enables running external tools inside nsjail
+type NSJailConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Path       string `mapstructure:"path"`
+	ConfigFile string `mapstructure:"config_file"`
+}
+
+// BreakerConfig configures circuit breakers around object storage, ICR
+// backends and external tools, one per dependency. A breaker opens after
+// Failures consecutive failures and fails calls fast for OpenTimeout
+// seconds, then lets HalfOpenRequests trial calls through; if they
+// succeed it closes again.
+type BreakerConfig struct {
+	Enabled          bool `mapstructure:"enabled"`
+	Failures         int  `mapstructure:"failures"`
+	OpenTimeout      int  `mapstructure:"open_timeout"`
+	HalfOpenRequests int  `mapstructure:"half_open_requests"`
+}
+
+// HealthConfig configures health and readiness endpoints
+type HealthConfig struct {
+	CheckTimeout int `mapstructure:"check_timeout"` // seconds, per check
+	// Diagnostics exposes build, runtime and load details on /health.
+	// Only enable when /health is reachable from internal networks only.
+	Diagnostics bool `mapstructure:"diagnostics"`
+}
+
+// AdminConfig configures the separate admin listener (pprof, expvar)
+type AdminConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	BindAddress string `mapstructure:"bind_address"`
+	Port        int    `mapstructure:"port"`
+	Token       string `mapstructure:"token"`
+}
+
+// RetentionPolicy controls how long stored documents and results are kept
+type RetentionPolicy struct {
+	// TTL is in seconds; 0 keeps objects until they are explicitly deleted
+	TTL int `mapstructure:"ttl"`
+	// DeleteAfterDownload removes a document or result once it has been
+	// downloaded in full
+	DeleteAfterDownload bool `mapstructure:"delete_after_download"`
+}
+
+// RetentionConfig configures purging of stored documents and results. The
+// embedded policy applies to tenants without their own. Interval is in seconds.
+type RetentionConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	Interval        int  `mapstructure:"interval"`
+	RetentionPolicy `mapstructure:",squash"`
+}
+
+// LockoutConfig throttles password guessing. Each failure blocks further
+// attempts for a backoff that starts at BaseDelay and doubles up to
+// MaxDelay; after MaxAttempts failures attempts are blocked for Lockout.
+// Failures are forgotten after Window. Durations are in seconds.
+type LockoutConfig struct {
+	MaxAttempts int `mapstructure:"max_attempts"`
+	BaseDelay   int `mapstructure:"base_delay"`
+	MaxDelay    int `mapstructure:"max_delay"`
+	Lockout     int `mapstructure:"lockout"`
+	Window      int `mapstructure:"window"`
+}
+
+// BatchConfig configures asynchronous batch jobs. Durations are in seconds.
+type BatchConfig struct {
+	Workers      int `mapstructure:"workers"`
+	QueueSize    int `mapstructure:"queue_size"`
+	MaxSteps     int `mapstructure:"max_steps"`
+	MaxDocuments int `mapstructure:"max_documents"`
+	JobTimeout   int `mapstructure:"job_timeout"`
+	// TenantQueueSize caps the queued jobs of a single tenant
+	TenantQueueSize int `mapstructure:"tenant_queue_size"`
+	// InteractiveRatio is how many interactive jobs may be dispatched in a
+	// row while bulk jobs are waiting
+	InteractiveRatio int `mapstructure:"interactive_ratio"`
+	// MaxAttempts is how many times a job runs before a transient failure
+	// dead-letters it. RetryDelay doubles per attempt up to MaxRetryDelay.
+	MaxAttempts   int `mapstructure:"max_attempts"`
+	RetryDelay    int `mapstructure:"retry_delay"`
+	MaxRetryDelay int `mapstructure:"max_retry_delay"`
+	// JobTTL is how long finished jobs remain queryable
+	JobTTL int `mapstructure:"job_ttl"`
+	// Store is "memory", losing jobs on restart, or "database" to keep
+	// them in the configured database
+	Store string `mapstructure:"store"`
+	// InstanceID identifies this replica as the owner of the jobs it
+	// queues, defaulting to the host name. With a database store, a
+	// replica restarting under the same ID picks up the jobs it left
+	// behind; give each replica a stable, unique ID.
+	InstanceID string `mapstructure:"instance_id"`
+}
+
+// DatabaseConfig configures the SQL database holding persistent state.
+// Driver is "postgres", or "sqlite" for single-node deployments without an
+// external database; leave it empty to run without a database.
+type DatabaseConfig struct {
+	Driver string `mapstructure:"driver"`
+	// DSN is the connection string, or the database file path for SQLite;
+	// a secret reference is resolved
+	DSN          string `mapstructure:"dsn"`
+	MaxOpenConns int    `mapstructure:"max_open_conns"`
+	MaxIdleConns int    `mapstructure:"max_idle_conns"`
+	// ConnMaxLifetime recycles connections after this many seconds; 0
+	// keeps them open
+	ConnMaxLifetime int `mapstructure:"conn_max_lifetime"`
+}
+
+// OnboardingConfig configures tenants onboarded at runtime through the
+// admin API, which are kept in the configured database. Replicas reread
+// them every RefreshInterval seconds to pick up changes made through
+// another replica.
+type OnboardingConfig struct {
+	Enabled         bool `mapstructure:"enabled"`
+	RefreshInterval int  `mapstructure:"refresh_interval"`
+}
+
+// ShareConfig configures signed, expiring share links to stored results.
+// Links are signed with Secret (a secret reference is resolved), so all
+// replicas must share it. DefaultTTL and MaxTTL are in seconds. Download
+// limits are counted in the configured database, or per instance without
+// one.
+type ShareConfig struct {
+	Enabled    bool   `mapstructure:"enabled"`
+	Secret     string `mapstructure:"secret"`
+	DefaultTTL int    `mapstructure:"default_ttl"`
+	MaxTTL     int    `mapstructure:"max_ttl"`
+	// PublicURL is the external base URL of the API, such as
+	// https://pdf.example.com/api/v1, for links sent outside a request
+	PublicURL string `mapstructure:"public_url"`
+}
+
+// EmailConfig configures email delivery of batch job results through an
+// SMTP relay. TLS connects with implicit TLS (usually port 465); otherwise
+// STARTTLS is used when the server offers it. Password may be a secret
+// reference. Results larger than MaxAttachmentSize bytes are sent as share
+// links. Subject and Body are text/template templates. Timeout is in
+// seconds.
+type EmailConfig struct {
+	Enabled           bool   `mapstructure:"enabled"`
+	Host              string `mapstructure:"host"`
+	Port              int    `mapstructure:"port"`
+	TLS               bool   `mapstructure:"tls"`
+	Username          string `mapstructure:"username"`
+	Password          string `mapstructure:"password"`
+	From              string `mapstructure:"from"`
+	MaxRecipients     int    `mapstructure:"max_recipients"`
+	MaxAttachmentSize int64  `mapstructure:"max_attachment_size"`
+	Subject           string `mapstructure:"subject"`
+	Body              string `mapstructure:"body"`
+	Timeout           int    `mapstructure:"timeout"`
+}
+
+// NotificationConfig configures chat webhooks told about finished batch
+// jobs
+type NotificationConfig struct {
+	Sinks []NotificationSink `mapstructure:"sinks"`
+}
+
+// NotificationSink posts job summaries to a Slack ("slack") or Microsoft
+// Teams ("teams") incoming webhook at URL (a secret reference is
+// resolved). Events lists the job statuses posted (succeeded, failed,
+// canceled) and Tenants the tenants whose jobs are posted; empty lists post
+// all. Timeout is in seconds.
+type NotificationSink struct {
+	Name    string   `mapstructure:"name"`
+	Type    string   `mapstructure:"type"`
+	URL     string   `mapstructure:"url"`
+	Events  []string `mapstructure:"events"`
+	Tenants []string `mapstructure:"tenants"`
+	Timeout int      `mapstructure:"timeout"`
+}
+
+// WatchConfig configures watch folders: directories polled for new
+// files, which are run through a batch pipeline
+type WatchConfig struct {
+	Folders []WatchFolder `mapstructure:"folders"`
+}
+
+// WatchFolder is a local ("local"), SFTP ("sftp") or FTP ("ftp") directory
+// polled every Interval seconds for files matching Pattern (default
+// "*.pdf") that have not changed for MinAge seconds. Each file is run
+// through Steps as a batch job of Tenant (default tenant if unset); its
+// results are written to Output and the file is moved to Done, or to
+// Failed with an error report. Remote folders are reached at Address
+// (host:port) as Username with Password or, for SFTP, PrivateKey (PEM);
+// secret references are resolved. SFTP servers must present HostKey, in
+// authorized_keys format.
+type WatchFolder struct {
+	Name       string      `mapstructure:"name"`
+	Type       string      `mapstructure:"type"`
+	Tenant     string      `mapstructure:"tenant"`
+	Address    string      `mapstructure:"address"`
+	Username   string      `mapstructure:"username"`
+	Password   string      `mapstructure:"password"`
+	PrivateKey string      `mapstructure:"private_key"`
+	HostKey    string      `mapstructure:"host_key"`
+	Inbox      string      `mapstructure:"inbox"`
+	Output     string      `mapstructure:"output"`
+	Done       string      `mapstructure:"done"`
+	Failed     string      `mapstructure:"failed"`
+	Pattern    string      `mapstructure:"pattern"`
+	Steps      []WatchStep `mapstructure:"steps"`
+	Interval   int         `mapstructure:"interval"`
+	MinAge     int         `mapstructure:"min_age"`
+	Timeout    int         `mapstructure:"timeout"`
+}
+
+// WatchStep is a batch job step run on watched files and mailbox
+// attachments
+type WatchStep struct {
+	Operation string            `mapstructure:"operation"`
+	Params    map[string]string `mapstructure:"params"`
+}
+
+// IMAPConfig configures mailbox ingestion: IMAP mailboxes polled for
+// messages whose PDF attachments are run through a batch pipeline
+type IMAPConfig struct {
+	Mailboxes []IMAPMailbox `mapstructure:"mailboxes"`
+}
+
+// IMAPMailbox is polled every Interval seconds for unseen messages in
+// Folder (default "INBOX") on the server at Address (host:port), logged in
+// as Username with Password; secret references are resolved. The
+// connection uses implicit TLS when TLS is set and STARTTLS otherwise.
+// The PDF attachments of a message from Senders (addresses, or "@domain"
+// for a whole domain; anyone if empty) are run through Steps as one batch
+// job of Tenant (default tenant if unset). Results are emailed back to the
+// sender when Reply is set and to the Forward addresses; failures are
+// reported the same way. Handled messages are moved to Processed, or
+// flagged seen when it is unset. IMAP has no way to claim a message, so
+// only the instance whose batch.instance_id is Instance polls the mailbox;
+// every instance does when it is unset.
+type IMAPMailbox struct {
+	Name      string      `mapstructure:"name"`
+	Tenant    string      `mapstructure:"tenant"`
+	Instance  string      `mapstructure:"instance"`
+	Address   string      `mapstructure:"address"`
+	TLS       bool        `mapstructure:"tls"`
+	Username  string      `mapstructure:"username"`
+	Password  string      `mapstructure:"password"`
+	Folder    string      `mapstructure:"folder"`
+	Processed string      `mapstructure:"processed"`
+	Senders   []string    `mapstructure:"senders"`
+	Steps     []WatchStep `mapstructure:"steps"`
+	Reply     bool        `mapstructure:"reply"`
+	Forward   []string    `mapstructure:"forward"`
+	Interval  int         `mapstructure:"interval"`
+	Timeout   int         `mapstructure:"timeout"`
+}
+
+// ConnectorsConfig configures cloud storage connectors, which import
+// documents from and export results to user-authorized Dropbox
+// ("dropbox"), Google Drive ("google_drive") and OneDrive ("onedrive")
+// storage. Requests pass an OAuth access token, or use the refresh token
+// stored for their tenant under tenants[].connectors. Timeout is in
+// seconds.
+type ConnectorsConfig struct {
+	Enabled   bool                `mapstructure:"enabled"`
+	Providers []ConnectorProvider `mapstructure:"providers"`
+	Timeout   int                 `mapstructure:"timeout"`
+}
+
+// ConnectorProvider enables a provider. ClientID and ClientSecret identify
+// the OAuth application tenants authorized; they are only needed to
+// refresh stored tokens. Secret references are resolved.
+type ConnectorProvider struct {
+	Name         string `mapstructure:"name"`
+	ClientID     string `mapstructure:"client_id"`
+	ClientSecret string `mapstructure:"client_secret"`
+}
+
+// ESignConfig configures the hand-off of stored results to DocuSign or
+// Adobe Sign for signing. A provider is enabled when its base URL is set.
+// ReturnURL is where signers land after signing unless a request names
+// another. Timeout is in seconds.
+type ESignConfig struct {
+	Enabled   bool            `mapstructure:"enabled"`
+	ReturnURL string          `mapstructure:"return_url"`
+	Timeout   int             `mapstructure:"timeout"`
+	DocuSign  DocuSignConfig  `mapstructure:"docusign"`
+	AdobeSign AdobeSignConfig `mapstructure:"adobe_sign"`
+}
+
+// DocuSignConfig authorizes with a JWT grant: IntegrationKey impersonates
+// UserID, signing with the PEM encoded RSA PrivateKey (a secret reference
+// is resolved). BaseURL is the account's REST base URI, such as
+// https://na3.docusign.net/restapi, and AuthHost the OAuth host,
+// account-d.docusign.com for the developer sandbox.
+type DocuSignConfig struct {
+	BaseURL        string `mapstructure:"base_url"`
+	AuthHost       string `mapstructure:"auth_host"`
+	AccountID      string `mapstructure:"account_id"`
+	IntegrationKey string `mapstructure:"integration_key"`
+	UserID         string `mapstructure:"user_id"`
+	PrivateKey     string `mapstructure:"private_key"`
+}
+
+// AdobeSignConfig authorizes with an integration key (a secret reference
+// is resolved). BaseURL is the account's API access point, such as
+// https://api.na1.adobesign.com.
+type AdobeSignConfig struct {
+	BaseURL        string `mapstructure:"base_url"`
+	IntegrationKey string `mapstructure:"integration_key"`
+}
+
+// ProvenanceConfig configures C2PA manifests embedded in the PDF outputs of
+// tenants with provenance set, recording the operation applied, by which
+// service and when. Manifests are signed with PrivateKey, an ECDSA P-256
+// or P-384, RSA or Ed25519 key, and carry Certificate, its certificate
+// chain leaf first. Both are PEM encoded; secret references are resolved.
+type ProvenanceConfig struct {
+	Enabled     bool   `mapstructure:"enabled"`
+	Certificate string `mapstructure:"certificate"`
+	PrivateKey  string `mapstructure:"private_key"`
+}
+
+// ICRConfig configures handwriting recognition backends, selectable by name
+// per text extraction request
+type ICRConfig struct {
+	Backends []ICRBackend `mapstructure:"backends"`
+}
+
+// ICRBackend is a handwriting recognition backend. Type "http" posts page
+// images to URL with Token as bearer credentials (a secret reference is
+// resolved); type "command" runs Tool, which must be allow-listed in the
+// sandbox. Timeout is in seconds and bounds each page.
+type ICRBackend struct {
+	Name    string `mapstructure:"name"`
+	Type    string `mapstructure:"type"`
+	URL     string `mapstructure:"url"`
+	Token   string `mapstructure:"token"`
+	Tool    string `mapstructure:"tool"`
+	Timeout int    `mapstructure:"timeout"`
+}
+
+// PluginConfig is an out-of-process custom processor: a go-plugin binary
+// at Path, started with the server and exposed as /api/v1/pdf/custom/<name>
+type PluginConfig struct {
+	Name string `mapstructure:"name"`
+	Path string `mapstructure:"path"`
+}
+
+// EntityConfig configures the entity extractors selectable by name when
+// extracting text, in addition to the builtin email, ssn, invoice_number,
+// date, phone and credit_card patterns
+type EntityConfig struct {
+	// Patterns are regular expressions; one with a capture group extracts
+	// the group. A pattern named like a builtin replaces it.
+	Patterns []EntityPattern `mapstructure:"patterns"`
+	// NER are named entity recognition backends
+	NER []NERBackend `mapstructure:"ner"`
+}
+
+// EntityPattern is a regular expression entity extractor
+type EntityPattern struct {
+	Name    string `mapstructure:"name"`
+	Pattern string `mapstructure:"pattern"`
+}
+
+// PrepressConfig configures print production operations
+type PrepressConfig struct {
+	// Profiles maps ICC profile names selectable per request to profile
+	// files, e.g. "fogra39": "/etc/icc/ISOcoated_v2_eci.icc"
+	Profiles map[string]string `mapstructure:"profiles"`
+	// DefaultProfile names the profile used when a request names none
+	DefaultProfile string `mapstructure:"default_profile"`
+	// FontDirectory holds the font files embedded in place of missing
+	// fonts, searched recursively and matched by file name, e.g.
+	// Calibri-Bold.ttf for Calibri-Bold
+	FontDirectory string `mapstructure:"font_directory"`
+	// FontSubstitutes maps font families to metrics-compatible families
+	// from FontDirectory used when a font itself is not available, e.g.
+	// "Calibri": "Carlito"
+	FontSubstitutes map[string]string `mapstructure:"font_substitutes"`
+	// SpotColors maps spot colour names to the CMYK percentages they are
+	// converted to, e.g. "PANTONE 185 C": [0, 91, 76, 0]. Names match
+	// ignoring case.
+	SpotColors map[string][]float64 `mapstructure:"spot_colors"`
+	// FlattenDPI is the resolution transparent regions are rasterized at
+	// when transparency is flattened, unless a request sets one
+	FlattenDPI int `mapstructure:"flatten_dpi"`
+}
+
+// ImageConfig configures image conversion output
+type ImageConfig struct {
+	// Encoders holds the default settings of each output format's encoder,
+	// keyed by format (jpeg, webp, avif)
+	Encoders map[string]ImageEncoderConfig `mapstructure:"encoders"`
+}
+
+// ImageEncoderConfig holds the default settings of an image encoder
+type ImageEncoderConfig struct {
+	// Quality is the lossy encoding quality from 1 to 100
+	Quality int `mapstructure:"quality"`
+	// Lossless selects lossless encoding where the format offers both
+	Lossless bool `mapstructure:"lossless"`
+	// Progressive selects progressive encoding; JPEG only
+	Progressive bool `mapstructure:"progressive"`
+	// Speed trades encoding time for size, from 0 (slowest, smallest) to
+	// 10; AVIF only
+	Speed int `mapstructure:"speed"`
+}
+
+// PIIConfig configures PII detection and redaction
+type PIIConfig struct {
+	// Entities names the entity extractors detecting PII when a request
+	// selects none
+	Entities []string `mapstructure:"entities"`
+}
+
+// NERBackend is an HTTP named entity recognition backend. Token is sent as
+// bearer credentials (a secret reference is resolved). Timeout is in
+// seconds and bounds each page.
+type NERBackend struct {
+	Name    string `mapstructure:"name"`
+	URL     string `mapstructure:"url"`
+	Token   string `mapstructure:"token"`
+	Timeout int    `mapstructure:"timeout"`
+}
+
+// CronConfig configures scheduled maintenance jobs. Jobs can also be added
+// on the admin listener at /admin/cron/; those are lost on restart.
+type CronConfig struct {
+	Enabled bool      `mapstructure:"enabled"`
+	Jobs    []CronJob `mapstructure:"jobs"`
+}
+
+// CronJob runs a maintenance task on a schedule: a five-field cron
+// expression evaluated in UTC, a descriptor such as @daily, or
+// "@every <duration>". Params are passed to the task.
+type CronJob struct {
+	Name     string            `mapstructure:"name"`
+	Schedule string            `mapstructure:"schedule"`
+	Task     string            `mapstructure:"task"`
+	Params   map[string]string `mapstructure:"params"`
+}
+
+// TenantConfig identifies a tenant by its API keys and holds per-tenant
+// settings. Requests without a tenant API key belong to the default tenant.
+type TenantConfig struct {
+	ID      string   `mapstructure:"id"`
+	APIKeys []string `mapstructure:"api_keys"`
+	// Retention replaces the default retention policy when set
+	Retention *RetentionPolicy `mapstructure:"retention"`
+	// BatchWeight is the tenant's share of batch workers relative to other
+	// tenants; 0 means 1
+	BatchWeight int `mapstructure:"batch_weight"`
+	// Script is the path of a Lua script run at pipeline hooks of the
+	// tenant's operations when scripting is enabled
+	Script string `mapstructure:"script"`
+	// Connectors are the OAuth refresh tokens of the tenant's cloud storage
+	// by provider name, used by requests that pass no token. Secret
+	// references are resolved.
+	Connectors map[string]string `mapstructure:"connectors"`
+	// Provenance embeds signed C2PA manifests in the tenant's PDF outputs
+	// when provenance is enabled
+	Provenance bool `mapstructure:"provenance"`
+}
+
+// AuditConfig configures request audit sampling. Sampled descriptors are
+// listed on the admin listener at /debug/audit.
+type AuditConfig struct {
+	Enabled    bool    `mapstructure:"enabled"`
+	SampleRate float64 `mapstructure:"sample_rate"` // percent of requests
+	Capacity   int     `mapstructure:"capacity"`    // descriptors retained
+}
+
+// AuditTrailConfig styles the audit trail pages appended to documents.
+// Template is a text/template rendering service.AuditTrail into the page
+// text; lines starting with "# " are set as headings. Title heads the
+// trail and its page footers. PageSize is a4 or letter and FontSize is
+// the body text size in points.
+type AuditTrailConfig struct {
+	Title    string  `mapstructure:"title"`
+	Template string  `mapstructure:"template"`
+	PageSize string  `mapstructure:"page_size"`
+	FontSize float64 `mapstructure:"font_size"`
+}
+
+// LinkCheckConfig configures the validation of the links extracted from
+// documents. Each link gets a HEAD request, or a GET where HEAD is refused,
+// bounded by Timeout seconds with Concurrency in flight. MaxLinks bounds
+// the distinct links checked per document. Private, loopback and link-local
+// addresses are refused unless AllowPrivate is set, so documents cannot
+// probe the internal network.
+type LinkCheckConfig struct {
+	Timeout      int    `mapstructure:"timeout"`
+	Concurrency  int    `mapstructure:"concurrency"`
+	MaxLinks     int    `mapstructure:"max_links"`
+	AllowPrivate bool   `mapstructure:"allow_private"`
+	UserAgent    string `mapstructure:"user_agent"`
+}
+
+// LifecycleConfig configures shutdown behaviour. Both values are seconds.
+type LifecycleConfig struct {
+	// PreStopDelay is how long the instance reports not ready, while still
+	// serving, before it starts draining. Set it to cover load balancer
+	// deregistration during rolling deploys.
+	PreStopDelay int `mapstructure:"pre_stop_delay"`
+	// ShutdownTimeout bounds draining and server shutdown
+	ShutdownTimeout int `mapstructure:"shutdown_timeout"`
+}
+
+// secretsTimeout bounds how long Load waits for secret managers
+const secretsTimeout = 30 * time.Second
+
 // Load reads configuration from environment and files
 func Load() (*Config, error) {
 	v := viper.New()
@@ -103,6 +841,13 @@ func Load() (*Config, error) {
 		return nil, fmt.Errorf("failed to unmarshal config: %w", err)
 	}
 
+	// Resolve secret manager references (vault://, awssm://, gcpsm://, file://)
+	ctx, cancel := context.WithTimeout(context.Background(), secretsTimeout)
+	defer cancel()
+	if err := secrets.NewResolver().ResolveStruct(ctx, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to resolve secrets: %w", err)
+	}
+
 	// Validate configuration
 	if err := validate(&cfg); err != nil {
 		return nil, fmt.Errorf("invalid configuration: %w", err)
@@ -122,12 +867,36 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("rate_limit.enabled", true)
 	v.SetDefault("rate_limit.requests_per_minute", 60)
 	v.SetDefault("rate_limit.burst", 10)
+	v.SetDefault("rate_limit.redis.key_prefix", "pdf-tool:ratelimit:")
+	v.SetDefault("rate_limit.redis.timeout", 50)
+	// Rendering, OCR and whole-document rewrites cost more than reads
+	v.SetDefault("rate_limit.endpoints", map[string]interface{}{
+		"/api/v1/pdf/convert/image":         map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/convert/image/stream":  map[string]interface{}{"cost": 10},
+		"/api/v1/pdf/convert/contact-sheet": map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/convert/long-image":    map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/convert/preview":       map[string]interface{}{"cost": 3},
+		"/api/v1/pdf/extract/text":          map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/extract/invoice":       map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/redact/pii":            map[string]interface{}{"cost": 10},
+		"/api/v1/pdf/accessibility/autotag": map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/color":        map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/pdfx/convert": map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/fonts":        map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/outline-text": map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/flatten":      map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/ink":          map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/prepress/spot-colors":  map[string]interface{}{"cost": 5},
+		"/api/v1/pdf/:docid/pages/:n":       map[string]interface{}{"cost": 2},
+	})
 
 	// Server
 	v.SetDefault("server.read_timeout", 30)
 	v.SetDefault("server.write_timeout", 30)
 	v.SetDefault("server.idle_timeout", 60)
-	v.SetDefault("server.max_header_bytes", 1048576) // 1MB
+	v.SetDefault("server.max_header_bytes", 1048576)     // 1MB
+	v.SetDefault("server.max_multipart_memory", 8388608) // 8MB
+	v.SetDefault("server.trusted_proxies", []string{})
 
 	// PDF
 	v.SetDefault("pdf.max_file_size", 52428800) // 50MB
@@ -136,7 +905,14 @@ func setDefaults(v *viper.Viper) {
 	v.SetDefault("pdf.max_pages", 1000)
 	v.SetDefault("pdf.ocr_enabled", true)
 	v.SetDefault("pdf.ocr_languages", []string{"eng"})
+	v.SetDefault("pdf.ocr_dpi", 300)
+	v.SetDefault("pdf.ocr_low_confidence", 60)
+	v.SetDefault("pdf.chunk_size", 1000)
+	v.SetDefault("pdf.chunk_overlap", 200)
 	v.SetDefault("pdf.compression_level", 1)
+	v.SetDefault("pdf.staging_workers", 4)
+	v.SetDefault("pdf.max_merge_files", 20)
+	v.SetDefault("pdf.stream_batch_pages", 10)
 
 	// Storage
 	v.SetDefault("storage.type", "local")
@@ -144,11 +920,219 @@ func setDefaults(v *viper.Viper) {
 
 	// CORS
 	v.SetDefault("cors.allowed_origins", []string{"*"})
+	v.SetDefault("cors.allowed_methods", []string{"GET", "HEAD", "POST", "PUT", "DELETE"})
+	v.SetDefault("cors.allowed_headers", []string{"Origin", "Content-Type", "Accept", "Authorization", "X-API-Key", "X-Priority"})
+	v.SetDefault("cors.exposed_headers", []string{"Content-Length", "Content-Disposition", "ETag", "Retry-After", "X-Content-SHA256", "X-Queue-Position", "X-Queue-Wait", "X-Part-Count", "API-Version", "Deprecation", "Sunset", "Link"})
+	v.SetDefault("cors.allow_credentials", false)
+	v.SetDefault("cors.max_age", 300)
 
 	// Telemetry
 	v.SetDefault("telemetry.enabled", true)
 	v.SetDefault("telemetry.otlp_endpoint", "localhost:4318")
 	v.SetDefault("telemetry.sampling_ratio", 1.0)
+
+	// Auth
+	v.SetDefault("auth.privileged_keys", []string{})
+	v.SetDefault("auth.allow_anonymous", true)
+
+	// Timeouts
+	v.SetDefault("timeouts.default", 60)
+	v.SetDefault("timeouts.operations", map[string]int{
+		"convert_image":   120,
+		"extract_text":    300, // OCR is slow on scanned documents
+		"extract_invoice": 300,
+		"compress":        120,
+		"merge":           120,
+		"redact_pii":      600, // two OCR passes
+	})
+
+	// Concurrency
+	v.SetDefault("concurrency.max_concurrent", 4)
+	v.SetDefault("concurrency.queue_size", 16)
+	v.SetDefault("concurrency.queue_timeout", 10)
+	v.SetDefault("concurrency.retry_after", 5)
+
+	// Load shedding
+	v.SetDefault("load_shedding.enabled", false)
+	v.SetDefault("load_shedding.target_wait", 2000)
+	v.SetDefault("load_shedding.window", 30)
+	v.SetDefault("load_shedding.retry_after", 5)
+	v.SetDefault("scripting.enabled", false)
+	v.SetDefault("scripting.timeout", 100)
+	v.SetDefault("scripting.memory_bytes", 16777216) // 16MB
+	v.SetDefault("scripting.call_stack_size", 64)
+	v.SetDefault("scripting.registry_size", 4096)
+	v.SetDefault("versioning.default", 1)
+
+	// Janitor
+	v.SetDefault("janitor.enabled", true)
+	v.SetDefault("janitor.interval", 300)
+	v.SetDefault("janitor.ttl", 3600)
+	v.SetDefault("janitor.clean_on_startup", true)
+	v.SetDefault("janitor.alert_bytes", 1073741824) // 1GB
+
+	// Retention (objects are kept until a TTL is configured)
+	v.SetDefault("retention.enabled", true)
+	v.SetDefault("retention.interval", 3600)
+	v.SetDefault("retention.ttl", 0)
+	v.SetDefault("retention.delete_after_download", false)
+
+	// Decrypt lockout (per document and per client)
+	v.SetDefault("decrypt_lockout.max_attempts", 10)
+	v.SetDefault("decrypt_lockout.base_delay", 1)
+	v.SetDefault("decrypt_lockout.max_delay", 60)
+	v.SetDefault("decrypt_lockout.lockout", 3600)
+	v.SetDefault("decrypt_lockout.window", 3600)
+
+	// Batch jobs
+	v.SetDefault("batch.workers", 2)
+	v.SetDefault("batch.queue_size", 100)
+	v.SetDefault("batch.max_steps", 10)
+	v.SetDefault("batch.max_documents", 100)
+	v.SetDefault("batch.job_timeout", 3600)
+	v.SetDefault("batch.job_ttl", 86400)
+	v.SetDefault("batch.tenant_queue_size", 50)
+	v.SetDefault("batch.interactive_ratio", 4)
+	v.SetDefault("batch.max_attempts", 3)
+	v.SetDefault("batch.retry_delay", 5)
+	v.SetDefault("batch.max_retry_delay", 300)
+	v.SetDefault("batch.store", "memory")
+
+	// Database (none unless configured)
+	v.SetDefault("database.max_open_conns", 10)
+	v.SetDefault("database.max_idle_conns", 5)
+	v.SetDefault("database.conn_max_lifetime", 1800)
+
+	// Tenant onboarding (disabled by default)
+	v.SetDefault("onboarding.enabled", false)
+	v.SetDefault("onboarding.refresh_interval", 30)
+
+	// Share links (disabled by default)
+	v.SetDefault("share.enabled", false)
+	v.SetDefault("share.default_ttl", 86400)
+	v.SetDefault("share.max_ttl", 604800)
+
+	// Cloud storage connectors (disabled by default)
+	v.SetDefault("connectors.enabled", false)
+	v.SetDefault("connectors.timeout", 60)
+
+	// Signing hand-off (disabled by default)
+	v.SetDefault("esign.enabled", false)
+	v.SetDefault("esign.timeout", 30)
+	v.SetDefault("esign.docusign.auth_host", "account.docusign.com")
+
+	// Content credentials (disabled by default)
+	v.SetDefault("provenance.enabled", false)
+
+	// Email delivery of job results (disabled by default)
+	v.SetDefault("email.enabled", false)
+	v.SetDefault("email.port", 587)
+	v.SetDefault("email.max_recipients", 10)
+	v.SetDefault("email.max_attachment_size", 10485760) // 10MB
+	v.SetDefault("email.subject", "Your documents are ready (job {{.JobID}})")
+	v.SetDefault("email.body", "Batch job {{.JobID}} has finished.\n"+
+		"{{range .Attachments}}\nAttached: {{.Name}} ({{.Size}} bytes){{end}}"+
+		"{{range .Links}}\nDownload: {{.URL}} (until {{.ExpiresAt.Format \"2006-01-02 15:04 MST\"}}){{end}}\n")
+	v.SetDefault("email.timeout", 30)
+
+	// Scheduled jobs (none unless configured)
+	v.SetDefault("cron.enabled", true)
+
+	// PII
+	v.SetDefault("pii.entities", []string{"email", "ssn", "phone", "credit_card"})
+
+	// Image encoders
+	v.SetDefault("images.encoders.jpeg.quality", 85)
+	v.SetDefault("images.encoders.webp.quality", 80)
+	v.SetDefault("images.encoders.avif.quality", 60)
+	v.SetDefault("images.encoders.avif.speed", 6)
+
+	// Guardrails
+	v.SetDefault("guardrails.enabled", true)
+	v.SetDefault("guardrails.min_free_disk", 536870912) // 512MB
+	v.SetDefault("guardrails.max_memory", 0)            // disabled; set below the container limit
+	v.SetDefault("guardrails.wait_timeout", 5)
+	v.SetDefault("guardrails.retry_after", 30)
+
+	// Sandbox
+	v.SetDefault("sandbox.work_dir", "/tmp/pdf-tool/exec")
+	v.SetDefault("sandbox.timeout", 120)
+	v.SetDefault("sandbox.cpu_seconds", 120)
+	v.SetDefault("sandbox.memory_bytes", 2147483648)   // 2GB
+	v.SetDefault("sandbox.file_size_bytes", 536870912) // 512MB
+	v.SetDefault("sandbox.open_files", 256)
+	v.SetDefault("sandbox.max_output_bytes", 1048576) // 1MB
+	v.SetDefault("sandbox.tools", map[string]string{
+		"gs":        "",
+		"tesseract": "",
+		"cwebp":     "",
+		"avifenc":   "",
+		"img2webp":  "",
+		"jpegtran":  "",
+	})
+	v.SetDefault("sandbox.nsjail.enabled", false)
+	v.SetDefault("sandbox.nsjail.path", "/usr/bin/nsjail")
+
+	// Circuit breakers
+	v.SetDefault("breakers.enabled", true)
+	v.SetDefault("breakers.failures", 5)
+	v.SetDefault("breakers.open_timeout", 30)
+	v.SetDefault("breakers.half_open_requests", 1)
+
+	// Health
+	v.SetDefault("health.check_timeout", 3)
+	v.SetDefault("health.diagnostics", false)
+
+	// Admin
+	v.SetDefault("admin.enabled", false)
+	v.SetDefault("admin.bind_address", "127.0.0.1")
+	v.SetDefault("admin.port", 6060)
+
+	// Lifecycle
+	v.SetDefault("lifecycle.pre_stop_delay", 0)
+	v.SetDefault("lifecycle.shutdown_timeout", 30)
+
+	// Audit
+	v.SetDefault("audit.enabled", false)
+	v.SetDefault("audit.sample_rate", 1.0)
+	v.SetDefault("audit.capacity", 1000)
+
+	// Prepress
+	v.SetDefault("prepress.flatten_dpi", 300)
+	// Font substitution; the substitutes share the metrics of the fonts
+	// they replace, so text keeps its layout
+	v.SetDefault("prepress.font_substitutes", map[string]interface{}{
+		"Arial":         "LiberationSans",
+		"TimesNewRoman": "LiberationSerif",
+		"CourierNew":    "LiberationMono",
+		"Calibri":       "Carlito",
+		"Cambria":       "Caladea",
+		"ArialNarrow":   "LiberationSansNarrow",
+		"Georgia":       "Gelasio",
+		"SegoeUI":       "Selawik",
+	})
+
+	// Audit trail pages
+	v.SetDefault("audit_trail.title", "Certificate of Completion")
+	v.SetDefault("audit_trail.template", "# {{.Title}}\n"+
+		"Document: {{.Document}}\n"+
+		"Pages: {{.Pages}}\n"+
+		"SHA-256: {{.SHA256}}\n"+
+		"Generated: {{.GeneratedAt.Format \"2006-01-02 15:04:05 MST\"}}\n"+
+		"{{if .Signers}}\n# Signers\n{{range .Signers}}{{.Name}}{{if .Email}} <{{.Email}}>{{end}}"+
+		"{{if not .SignedAt.IsZero}}, signed {{.SignedAt.UTC.Format \"2006-01-02 15:04:05 MST\"}}{{end}}"+
+		"{{if .IP}} from {{.IP}}{{end}}\n{{end}}{{end}}"+
+		"{{if .Events}}\n# History\n{{range .Events}}{{.At.UTC.Format \"2006-01-02 15:04:05 MST\"}}  {{.Action}}"+
+		"{{if .Actor}} by {{.Actor}}{{end}}\n{{if .SHA256}}    SHA-256: {{.SHA256}}\n{{end}}{{end}}{{end}}"+
+		"\nThe SHA-256 hash covers the document without this certificate.\n")
+	v.SetDefault("audit_trail.page_size", "a4")
+	v.SetDefault("audit_trail.font_size", 10)
+
+	v.SetDefault("link_check.timeout", 10)
+	v.SetDefault("link_check.concurrency", 8)
+	v.SetDefault("link_check.max_links", 500)
+	v.SetDefault("link_check.allow_private", false)
+	v.SetDefault("link_check.user_agent", "pdf-tool-link-check/1.0")
 }
 
 // validate checks if configuration is valid
@@ -165,6 +1149,242 @@ func validate(cfg *Config) error {
 		return fmt.Errorf("max_pages must be positive")
 	}
 
+	if cfg.RateLimit.Enabled {
+		if cfg.RateLimit.RequestsPerMin <= 0 || cfg.RateLimit.Burst <= 0 {
+			return fmt.Errorf("rate_limit.requests_per_minute and rate_limit.burst must be positive")
+		}
+		if cfg.RateLimit.Redis.Address != "" && cfg.RateLimit.Redis.Timeout <= 0 {
+			return fmt.Errorf("rate_limit.redis.timeout must be positive")
+		}
+		for route, endpoint := range cfg.RateLimit.Endpoints {
+			if endpoint.Cost < 0 || endpoint.RequestsPerMin < 0 || endpoint.Burst < 0 {
+				return fmt.Errorf("rate_limit endpoint %s: cost, requests_per_minute and burst must not be negative", route)
+			}
+		}
+	}
+
+	if cfg.PDF.OCREnabled {
+		if cfg.PDF.OCRDPI < 72 || cfg.PDF.OCRDPI > 1200 {
+			return fmt.Errorf("pdf.ocr_dpi must be between 72 and 1200")
+		}
+		if cfg.PDF.OCRLowConfidence < 0 || cfg.PDF.OCRLowConfidence > 100 {
+			return fmt.Errorf("pdf.ocr_low_confidence must be between 0 and 100")
+		}
+	}
+
+	if cfg.PDF.ChunkSize <= 0 || cfg.PDF.ChunkOverlap < 0 || cfg.PDF.ChunkOverlap >= cfg.PDF.ChunkSize {
+		return fmt.Errorf("pdf.chunk_size must be positive and larger than pdf.chunk_overlap")
+	}
+	if cfg.PDF.MaxMergeFiles < 0 || cfg.PDF.MaxMergeFiles == 1 {
+		return fmt.Errorf("pdf.max_merge_files must be at least 2, or 0 for no limit")
+	}
+	if cfg.Server.MaxMultipartMemory < 0 {
+		return fmt.Errorf("server.max_multipart_memory must not be negative")
+	}
+	for _, proxy := range cfg.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return fmt.Errorf("server.trusted_proxies: %q is not an IP address or CIDR range", proxy)
+			}
+		}
+	}
+	if cfg.PDF.StreamBatchPages < 0 {
+		return fmt.Errorf("pdf.stream_batch_pages must not be negative")
+	}
+
+	if cfg.Timeouts.Default < 0 {
+		return fmt.Errorf("timeouts.default must not be negative")
+	}
+
+	for operation, seconds := range cfg.Timeouts.Operations {
+		if seconds < 0 {
+			return fmt.Errorf("timeout for %s must not be negative", operation)
+		}
+	}
+
+	if cfg.Concurrency.MaxConcurrent < 0 || cfg.Concurrency.QueueSize < 0 {
+		return fmt.Errorf("concurrency limits must not be negative")
+	}
+
+	if cfg.LoadShedding.Enabled && (cfg.LoadShedding.TargetWait <= 0 || cfg.LoadShedding.Window <= 0) {
+		return fmt.Errorf("load_shedding requires positive target_wait and window")
+	}
+	if cfg.LoadShedding.Enabled && cfg.Concurrency.MaxConcurrent == 0 {
+		// Shedding is driven by waits for concurrency slots, which never
+		// happen without a limit
+		return fmt.Errorf("load_shedding requires concurrency.max_concurrent")
+	}
+	if cfg.Scripting.Enabled && (cfg.Scripting.Timeout <= 0 || cfg.Scripting.MemoryBytes <= 0 || cfg.Scripting.CallStackSize <= 0 || cfg.Scripting.RegistrySize <= 0) {
+		return fmt.Errorf("scripting requires positive timeout, memory_bytes, call_stack_size and registry_size")
+	}
+
+	if cfg.Janitor.Enabled && cfg.Janitor.TTL <= 0 {
+		return fmt.Errorf("janitor.ttl must be positive")
+	}
+
+	if cfg.Sandbox.NSJail.Enabled && cfg.Sandbox.NSJail.Path == "" {
+		return fmt.Errorf("sandbox.nsjail.path is required when nsjail is enabled")
+	}
+
+	if cfg.Breakers.Enabled && (cfg.Breakers.Failures <= 0 || cfg.Breakers.OpenTimeout <= 0 || cfg.Breakers.HalfOpenRequests <= 0) {
+		return fmt.Errorf("breakers requires positive failures, open_timeout and half_open_requests")
+	}
+
+	if err := validateCORSPolicy("cors", cfg.CORS.CORSPolicy); err != nil {
+		return err
+	}
+	for _, route := range cfg.CORS.Routes {
+		if !strings.HasPrefix(route.PathPrefix, "/") {
+			return fmt.Errorf("cors route path_prefix must start with /: %q", route.PathPrefix)
+		}
+		if err := validateCORSPolicy("cors route "+route.PathPrefix, route.CORSPolicy); err != nil {
+			return err
+		}
+	}
+
+	if cfg.Lifecycle.PreStopDelay < 0 || cfg.Lifecycle.ShutdownTimeout <= 0 {
+		return fmt.Errorf("lifecycle.pre_stop_delay must not be negative and lifecycle.shutdown_timeout must be positive")
+	}
+
+	if cfg.Admin.Enabled {
+		if cfg.Admin.Token == "" {
+			return fmt.Errorf("admin.token is required when the admin server is enabled")
+		}
+		if cfg.Admin.Port < 1 || cfg.Admin.Port > 65535 || cfg.Admin.Port == cfg.Port {
+			return fmt.Errorf("invalid admin port: %d", cfg.Admin.Port)
+		}
+	}
+
+	if cfg.Audit.Enabled {
+		if cfg.Audit.SampleRate < 0 || cfg.Audit.SampleRate > 100 {
+			return fmt.Errorf("audit.sample_rate must be between 0 and 100")
+		}
+		if cfg.Audit.Capacity <= 0 {
+			return fmt.Errorf("audit.capacity must be positive")
+		}
+	}
+
+	if cfg.Retention.Enabled && cfg.Retention.Interval <= 0 {
+		return fmt.Errorf("retention.interval must be positive")
+	}
+	if cfg.Retention.TTL < 0 {
+		return fmt.Errorf("retention.ttl must not be negative")
+	}
+
+	lockout := cfg.DecryptLockout
+	if lockout.MaxAttempts <= 0 || lockout.BaseDelay <= 0 || lockout.MaxDelay < lockout.BaseDelay ||
+		lockout.Lockout < lockout.MaxDelay || lockout.Window <= 0 {
+		return fmt.Errorf("decrypt_lockout requires positive values with base_delay <= max_delay <= lockout")
+	}
+
+	if cfg.Batch.Workers <= 0 || cfg.Batch.QueueSize <= 0 || cfg.Batch.MaxSteps <= 0 || cfg.Batch.MaxDocuments <= 0 {
+		return fmt.Errorf("batch workers, queue_size, max_steps and max_documents must be positive")
+	}
+	if cfg.Batch.JobTimeout <= 0 || cfg.Batch.JobTTL <= 0 {
+		return fmt.Errorf("batch.job_timeout and batch.job_ttl must be positive")
+	}
+	if cfg.Batch.TenantQueueSize <= 0 || cfg.Batch.TenantQueueSize > cfg.Batch.QueueSize {
+		return fmt.Errorf("batch.tenant_queue_size must be positive and at most batch.queue_size")
+	}
+	if cfg.Batch.InteractiveRatio <= 0 {
+		return fmt.Errorf("batch.interactive_ratio must be positive")
+	}
+	if cfg.Batch.MaxAttempts <= 0 || cfg.Batch.RetryDelay <= 0 || cfg.Batch.MaxRetryDelay < cfg.Batch.RetryDelay {
+		return fmt.Errorf("batch.max_attempts and batch.retry_delay must be positive and batch.max_retry_delay at least batch.retry_delay")
+	}
+	switch cfg.Batch.Store {
+	case "memory":
+	case "database":
+		if cfg.Database.Driver == "" {
+			return fmt.Errorf("batch.store database requires database.driver")
+		}
+	default:
+		return fmt.Errorf("unsupported batch.store: %s", cfg.Batch.Store)
+	}
+	if err := validateDatabase(cfg.Database); err != nil {
+		return err
+	}
+	if cfg.Onboarding.Enabled {
+		if cfg.Database.Driver == "" {
+			return fmt.Errorf("onboarding requires database.driver")
+		}
+		if cfg.Onboarding.RefreshInterval <= 0 {
+			return fmt.Errorf("onboarding.refresh_interval must be positive")
+		}
+	}
+	if cfg.Share.Enabled {
+		if len(cfg.Share.Secret) < 32 {
+			return fmt.Errorf("share.secret must be at least 32 bytes")
+		}
+		if cfg.Share.DefaultTTL <= 0 || cfg.Share.MaxTTL < cfg.Share.DefaultTTL {
+			return fmt.Errorf("share requires 0 < default_ttl <= max_ttl")
+		}
+	}
+	if err := validateAuditTrail(cfg.AuditTrail); err != nil {
+		return err
+	}
+	if cfg.LinkCheck.Timeout <= 0 || cfg.LinkCheck.Concurrency <= 0 || cfg.LinkCheck.MaxLinks <= 0 {
+		return fmt.Errorf("link_check.timeout, link_check.concurrency and link_check.max_links must be positive")
+	}
+
+	if err := validateEmail(cfg.Email, cfg.Share); err != nil {
+		return err
+	}
+	if err := validateNotifications(cfg.Notifications); err != nil {
+		return err
+	}
+	if err := validateConnectors(cfg.Connectors, cfg.Tenants); err != nil {
+		return err
+	}
+	if err := validateESign(cfg.ESign); err != nil {
+		return err
+	}
+	if err := validateProvenance(cfg.Provenance, cfg.Tenants); err != nil {
+		return err
+	}
+	if err := validateWatch(cfg.Watch); err != nil {
+		return err
+	}
+	if err := validateIMAP(cfg.IMAP, cfg.Email); err != nil {
+		return err
+	}
+
+	if err := validateTenants(cfg.Tenants); err != nil {
+		return err
+	}
+
+	if err := validateICR(cfg.ICR); err != nil {
+		return err
+	}
+	if err := validateEntities(cfg.Entities); err != nil {
+		return err
+	}
+	if err := validatePlugins(cfg.Plugins); err != nil {
+		return err
+	}
+	if err := validateVersioning(cfg.Versioning); err != nil {
+		return err
+	}
+	if err := validatePrepress(cfg.Prepress); err != nil {
+		return err
+	}
+	for format, encoder := range cfg.Images.Encoders {
+		if encoder.Quality < 1 || encoder.Quality > 100 || encoder.Speed < 0 || encoder.Speed > 10 {
+			return fmt.Errorf("images encoder %s: quality must be 1-100 and speed 0-10", format)
+		}
+	}
+
+	names := make(map[string]bool, len(cfg.Cron.Jobs))
+	for _, job := range cfg.Cron.Jobs {
+		if job.Name == "" || job.Schedule == "" || job.Task == "" {
+			return fmt.Errorf("cron jobs require a name, schedule and task")
+		}
+		if names[job.Name] {
+			return fmt.Errorf("duplicate cron job %q", job.Name)
+		}
+		names[job.Name] = true
+	}
+
 	validLogLevels := map[string]bool{
 		"debug": true,
 		"info":  true,
@@ -178,3 +1398,407 @@ func validate(cfg *Config) error {
 
 	return nil
 }
+
+// DefaultTenant is the tenant of requests without a tenant API key
+const DefaultTenant = "default"
+
+// tenantIDPattern restricts tenant IDs to values safe in storage keys
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+// ValidTenantID reports whether id may name a tenant
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id) && id != DefaultTenant
+}
+
+// validatePrepress checks the ICC profiles, font substitutes, spot colour
+// table and flattening resolution
+func validatePrepress(cfg PrepressConfig) error {
+	for name, path := range cfg.Profiles {
+		if path == "" {
+			return fmt.Errorf("prepress profile %s: path is required", name)
+		}
+	}
+	if name := cfg.DefaultProfile; name != "" && cfg.Profiles[name] == "" {
+		return fmt.Errorf("prepress default_profile %s is not a configured profile", name)
+	}
+	for family, substitute := range cfg.FontSubstitutes {
+		if substitute == "" {
+			return fmt.Errorf("prepress font_substitutes %s: substitute is required", family)
+		}
+	}
+	for name, cmyk := range cfg.SpotColors {
+		if len(cmyk) != 4 {
+			return fmt.Errorf("prepress spot_colors %s: four CMYK percentages are required", name)
+		}
+		for _, value := range cmyk {
+			if value < 0 || value > 100 {
+				return fmt.Errorf("prepress spot_colors %s: percentages must be between 0 and 100", name)
+			}
+		}
+	}
+	if cfg.FlattenDPI < 72 || cfg.FlattenDPI > 1200 {
+		return fmt.Errorf("prepress flatten_dpi must be between 72 and 1200")
+	}
+	return nil
+}
+
+// validateAuditTrail checks the audit trail template and page style
+func validateAuditTrail(cfg AuditTrailConfig) error {
+	if _, err := template.New("audit_trail").Parse(cfg.Template); err != nil {
+		return fmt.Errorf("invalid audit_trail.template: %w", err)
+	}
+	if cfg.PageSize != "a4" && cfg.PageSize != "letter" {
+		return fmt.Errorf("audit_trail.page_size must be a4 or letter")
+	}
+	if cfg.FontSize < 6 || cfg.FontSize > 24 {
+		return fmt.Errorf("audit_trail.font_size must be between 6 and 24")
+	}
+	return nil
+}
+
+// validateEmail checks email delivery. Large results are sent as share
+// links, so delivery needs share links with a public URL.
+func validateEmail(email EmailConfig, share ShareConfig) error {
+	if !email.Enabled {
+		return nil
+	}
+	if email.Host == "" || email.Port <= 0 || email.From == "" {
+		return fmt.Errorf("email requires host, port and from")
+	}
+	if email.MaxRecipients <= 0 || email.MaxAttachmentSize < 0 || email.Timeout <= 0 {
+		return fmt.Errorf("email requires positive max_recipients and timeout and a non-negative max_attachment_size")
+	}
+	if !share.Enabled || share.PublicURL == "" {
+		return fmt.Errorf("email requires share links with share.public_url")
+	}
+	return nil
+}
+
+// validateNotifications rejects unnamed or duplicate sinks and unknown
+// types and events
+func validateNotifications(cfg NotificationConfig) error {
+	names := make(map[string]bool, len(cfg.Sinks))
+	for _, sink := range cfg.Sinks {
+		if sink.Name == "" || names[sink.Name] {
+			return fmt.Errorf("notification sinks need unique names, got %q", sink.Name)
+		}
+		names[sink.Name] = true
+
+		switch {
+		case sink.Type != "slack" && sink.Type != "teams":
+			return fmt.Errorf("notification sink %s: unsupported type %q", sink.Name, sink.Type)
+		case sink.URL == "":
+			return fmt.Errorf("notification sink %s: url is required", sink.Name)
+		case sink.Timeout <= 0:
+			return fmt.Errorf("notification sink %s: timeout must be positive", sink.Name)
+		}
+		for _, event := range sink.Events {
+			if event != "succeeded" && event != "failed" && event != "canceled" {
+				return fmt.Errorf("notification sink %s: unsupported event %q", sink.Name, event)
+			}
+		}
+	}
+	return nil
+}
+
+// validateConnectors rejects unknown or duplicate providers and stored
+// tokens for providers that cannot refresh them
+func validateConnectors(cfg ConnectorsConfig, tenants []TenantConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("connectors.timeout must be positive")
+	}
+	refreshable := make(map[string]bool, len(cfg.Providers))
+	seen := make(map[string]bool, len(cfg.Providers))
+	for _, provider := range cfg.Providers {
+		switch provider.Name {
+		case "dropbox", "google_drive", "onedrive":
+		default:
+			return fmt.Errorf("unsupported connector provider %q", provider.Name)
+		}
+		if seen[provider.Name] {
+			return fmt.Errorf("connector provider %s is configured twice", provider.Name)
+		}
+		seen[provider.Name] = true
+		refreshable[provider.Name] = provider.ClientID != "" && provider.ClientSecret != ""
+	}
+	for _, t := range tenants {
+		for name := range t.Connectors {
+			if !refreshable[name] {
+				return fmt.Errorf("tenant %s: connector %s needs an enabled provider with client_id and client_secret", t.ID, name)
+			}
+		}
+	}
+	return nil
+}
+
+// validateESign rejects a signing hand-off without providers or with
+// incomplete provider credentials
+func validateESign(cfg ESignConfig) error {
+	if !cfg.Enabled {
+		return nil
+	}
+	if cfg.Timeout <= 0 {
+		return fmt.Errorf("esign.timeout must be positive")
+	}
+	if cfg.DocuSign.BaseURL == "" && cfg.AdobeSign.BaseURL == "" {
+		return fmt.Errorf("esign needs esign.docusign or esign.adobe_sign")
+	}
+	if d := cfg.DocuSign; d.BaseURL != "" &&
+		(d.AuthHost == "" || d.AccountID == "" || d.IntegrationKey == "" || d.UserID == "" || d.PrivateKey == "") {
+		return fmt.Errorf("esign.docusign needs auth_host, account_id, integration_key, user_id and private_key")
+	}
+	if a := cfg.AdobeSign; a.BaseURL != "" && a.IntegrationKey == "" {
+		return fmt.Errorf("esign.adobe_sign needs integration_key")
+	}
+	return nil
+}
+
+// validateProvenance rejects provenance without signing credentials, and
+// tenants opting in while it is disabled. Keys are parsed when the signer
+// is created.
+func validateProvenance(cfg ProvenanceConfig, tenants []TenantConfig) error {
+	if cfg.Enabled && (cfg.Certificate == "" || cfg.PrivateKey == "") {
+		return fmt.Errorf("provenance needs certificate and private_key")
+	}
+	for _, t := range tenants {
+		if t.Provenance && !cfg.Enabled {
+			return fmt.Errorf("tenant %s: provenance requires provenance.enabled", t.ID)
+		}
+	}
+	return nil
+}
+
+// validateWatch rejects unnamed or duplicate watch folders and folders
+// missing their paths or connection settings. Steps are checked when the
+// watcher starts.
+func validateWatch(cfg WatchConfig) error {
+	names := make(map[string]bool, len(cfg.Folders))
+	for _, folder := range cfg.Folders {
+		if folder.Name == "" || names[folder.Name] {
+			return fmt.Errorf("watch folders need unique names, got %q", folder.Name)
+		}
+		names[folder.Name] = true
+
+		switch {
+		case folder.Type != "local" && folder.Type != "sftp" && folder.Type != "ftp":
+			return fmt.Errorf("watch folder %s: unsupported type %q", folder.Name, folder.Type)
+		case folder.Type != "local" && folder.Address == "":
+			return fmt.Errorf("watch folder %s: address is required", folder.Name)
+		case folder.Type == "sftp" && folder.HostKey == "":
+			return fmt.Errorf("watch folder %s: host_key is required", folder.Name)
+		case folder.Inbox == "" || folder.Output == "" || folder.Done == "" || folder.Failed == "":
+			return fmt.Errorf("watch folder %s: inbox, output, done and failed are required", folder.Name)
+		case folder.Tenant != "" && !ValidTenantID(folder.Tenant):
+			return fmt.Errorf("watch folder %s: invalid tenant %q", folder.Name, folder.Tenant)
+		case len(folder.Steps) == 0:
+			return fmt.Errorf("watch folder %s: steps are required", folder.Name)
+		case folder.Interval <= 0 || folder.Timeout <= 0 || folder.MinAge < 0:
+			return fmt.Errorf("watch folder %s: interval and timeout must be positive and min_age not negative", folder.Name)
+		}
+		if _, err := path.Match(folder.Pattern, ""); err != nil {
+			return fmt.Errorf("watch folder %s: invalid pattern: %w", folder.Name, err)
+		}
+	}
+	return nil
+}
+
+// validateIMAP rejects unnamed or duplicate mailboxes and mailboxes
+// missing connection settings or recipients. Results and failures are
+// emailed, so mailboxes need email delivery.
+func validateIMAP(cfg IMAPConfig, email EmailConfig) error {
+	names := make(map[string]bool, len(cfg.Mailboxes))
+	for _, mailbox := range cfg.Mailboxes {
+		if mailbox.Name == "" || names[mailbox.Name] {
+			return fmt.Errorf("imap mailboxes need unique names, got %q", mailbox.Name)
+		}
+		names[mailbox.Name] = true
+
+		switch {
+		case !email.Enabled:
+			return fmt.Errorf("imap mailbox %s: email must be enabled to send results", mailbox.Name)
+		case mailbox.Address == "" || mailbox.Username == "":
+			return fmt.Errorf("imap mailbox %s: address and username are required", mailbox.Name)
+		case !mailbox.Reply && len(mailbox.Forward) == 0:
+			return fmt.Errorf("imap mailbox %s: reply or forward is required", mailbox.Name)
+		case mailbox.Tenant != "" && !ValidTenantID(mailbox.Tenant):
+			return fmt.Errorf("imap mailbox %s: invalid tenant %q", mailbox.Name, mailbox.Tenant)
+		case len(mailbox.Steps) == 0:
+			return fmt.Errorf("imap mailbox %s: steps are required", mailbox.Name)
+		case mailbox.Interval <= 0 || mailbox.Timeout <= 0:
+			return fmt.Errorf("imap mailbox %s: interval and timeout must be positive", mailbox.Name)
+		}
+	}
+	return nil
+}
+
+// validateTenants rejects malformed or duplicate tenant IDs and API keys
+// shared between tenants
+func validateTenants(tenants []TenantConfig) error {
+	ids := make(map[string]bool, len(tenants))
+	keys := make(map[string]string)
+	for _, tenant := range tenants {
+		if !ValidTenantID(tenant.ID) {
+			return fmt.Errorf("invalid tenant id %q", tenant.ID)
+		}
+		if ids[tenant.ID] {
+			return fmt.Errorf("duplicate tenant id %q", tenant.ID)
+		}
+		ids[tenant.ID] = true
+
+		for _, key := range tenant.APIKeys {
+			if key == "" {
+				return fmt.Errorf("tenant %s: empty api key", tenant.ID)
+			}
+			if owner, ok := keys[key]; ok {
+				return fmt.Errorf("tenant %s: api key already assigned to tenant %s", tenant.ID, owner)
+			}
+			keys[key] = tenant.ID
+		}
+
+		if tenant.Retention != nil && tenant.Retention.TTL < 0 {
+			return fmt.Errorf("tenant %s: retention.ttl must not be negative", tenant.ID)
+		}
+		if tenant.BatchWeight < 0 {
+			return fmt.Errorf("tenant %s: batch_weight must not be negative", tenant.ID)
+		}
+	}
+	return nil
+}
+
+// validateICR rejects incomplete or duplicate ICR backends
+func validateICR(cfg ICRConfig) error {
+	names := make(map[string]bool, len(cfg.Backends))
+	for _, backend := range cfg.Backends {
+		if backend.Name == "" || names[backend.Name] {
+			return fmt.Errorf("icr backends need unique names, got %q", backend.Name)
+		}
+		names[backend.Name] = true
+
+		switch {
+		case backend.Type == "http" && backend.URL == "":
+			return fmt.Errorf("icr backend %s: url is required", backend.Name)
+		case backend.Type == "command" && backend.Tool == "":
+			return fmt.Errorf("icr backend %s: tool is required", backend.Name)
+		case backend.Type != "http" && backend.Type != "command":
+			return fmt.Errorf("icr backend %s: unsupported type %q", backend.Name, backend.Type)
+		case backend.Timeout <= 0:
+			return fmt.Errorf("icr backend %s: timeout must be positive", backend.Name)
+		}
+	}
+	return nil
+}
+
+// pluginNamePattern matches custom processor names, which appear in URLs
+var pluginNamePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+// validatePlugins rejects unnamed, duplicate or pathless plugins
+func validatePlugins(plugins []PluginConfig) error {
+	names := make(map[string]bool, len(plugins))
+	for _, plugin := range plugins {
+		if !pluginNamePattern.MatchString(plugin.Name) || names[plugin.Name] {
+			return fmt.Errorf("plugins need unique names of lowercase letters, digits, - and _, got %q", plugin.Name)
+		}
+		names[plugin.Name] = true
+		if plugin.Path == "" {
+			return fmt.Errorf("plugin %s: path is required", plugin.Name)
+		}
+	}
+	return nil
+}
+
+// The range of API versions the server implements; see versioning.Versions
+const (
+	minAPIVersion = 1
+	maxAPIVersion = 2
+)
+
+// validateDatabase rejects unknown drivers and missing connection strings
+func validateDatabase(cfg DatabaseConfig) error {
+	switch cfg.Driver {
+	case "":
+		return nil
+	case "postgres", "sqlite":
+	default:
+		return fmt.Errorf("unsupported database.driver: %s", cfg.Driver)
+	}
+	if cfg.DSN == "" {
+		return fmt.Errorf("database.dsn is required")
+	}
+	if cfg.MaxOpenConns <= 0 || cfg.MaxIdleConns < 0 || cfg.ConnMaxLifetime < 0 {
+		return fmt.Errorf("database.max_open_conns must be positive and idle conns and lifetime not negative")
+	}
+	return nil
+}
+
+// validateVersioning rejects unknown versions and malformed dates
+func validateVersioning(cfg VersioningConfig) error {
+	if cfg.Default < minAPIVersion || cfg.Default > maxAPIVersion {
+		return fmt.Errorf("versioning.default must be between %d and %d", minAPIVersion, maxAPIVersion)
+	}
+	for name, deprecation := range cfg.Deprecations {
+		n, err := strconv.Atoi(strings.TrimPrefix(name, "v"))
+		if err != nil || !strings.HasPrefix(name, "v") || n < minAPIVersion || n > maxAPIVersion {
+			return fmt.Errorf("versioning.deprecations: unknown version %q", name)
+		}
+		for _, date := range []string{deprecation.Date, deprecation.Sunset} {
+			if _, err := time.Parse(time.DateOnly, date); date != "" && err != nil {
+				return fmt.Errorf("versioning.deprecations.%s: dates must be YYYY-MM-DD, got %q", name, date)
+			}
+		}
+	}
+	return nil
+}
+
+// validateEntities rejects invalid patterns and incomplete NER backends.
+// Names are shared by patterns and backends, so they must be unique across
+// both.
+func validateEntities(cfg EntityConfig) error {
+	names := make(map[string]bool, len(cfg.Patterns)+len(cfg.NER))
+	for _, pattern := range cfg.Patterns {
+		if pattern.Name == "" || names[pattern.Name] {
+			return fmt.Errorf("entity extractors need unique names, got %q", pattern.Name)
+		}
+		names[pattern.Name] = true
+		if _, err := regexp.Compile(pattern.Pattern); err != nil {
+			return fmt.Errorf("entity pattern %s: %w", pattern.Name, err)
+		}
+	}
+	for _, backend := range cfg.NER {
+		if backend.Name == "" || names[backend.Name] {
+			return fmt.Errorf("entity extractors need unique names, got %q", backend.Name)
+		}
+		names[backend.Name] = true
+		if backend.URL == "" {
+			return fmt.Errorf("ner backend %s: url is required", backend.Name)
+		}
+		if backend.Timeout <= 0 {
+			return fmt.Errorf("ner backend %s: timeout must be positive", backend.Name)
+		}
+	}
+	return nil
+}
+
+// validateCORSPolicy rejects malformed origins and credentialed wildcards
+func validateCORSPolicy(name string, policy CORSPolicy) error {
+	for _, origin := range policy.AllowedOrigins {
+		if origin == "*" {
+			if policy.AllowCredentials {
+				return fmt.Errorf("%s: allow_credentials cannot be combined with the * origin", name)
+			}
+			continue
+		}
+
+		u, err := url.Parse(strings.Replace(origin, "://*.", "://", 1))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || (u.Path != "" && u.Path != "/") {
+			return fmt.Errorf("%s: invalid origin %q, expected scheme://host[:port]", name, origin)
+		}
+	}
+
+	if policy.MaxAge < 0 {
+		return fmt.Errorf("%s: max_age must not be negative", name)
+	}
+	return nil
+}
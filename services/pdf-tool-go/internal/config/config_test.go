@@ -0,0 +1,186 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateCORSPolicy(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  CORSPolicy
+		wantErr bool
+	}{
+		{"wildcard without credentials", CORSPolicy{AllowedOrigins: []string{"*"}}, false},
+		{"wildcard with credentials", CORSPolicy{AllowedOrigins: []string{"*"}, AllowCredentials: true}, true},
+		{"exact origins with credentials", CORSPolicy{AllowedOrigins: []string{"https://app.example.com", "http://localhost:3000"}, AllowCredentials: true}, false},
+		{"wildcard subdomain", CORSPolicy{AllowedOrigins: []string{"https://*.example.com"}}, false},
+		{"missing scheme", CORSPolicy{AllowedOrigins: []string{"app.example.com"}}, true},
+		{"origin with path", CORSPolicy{AllowedOrigins: []string{"https://app.example.com/ui"}}, true},
+		{"negative max age", CORSPolicy{MaxAge: -1}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateCORSPolicy("cors", tt.policy)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateTenants(t *testing.T) {
+	tests := []struct {
+		name    string
+		tenants []TenantConfig
+		wantErr bool
+	}{
+		{"valid", []TenantConfig{{ID: "acme", APIKeys: []string{"k1"}}, {ID: "globex", APIKeys: []string{"k2"}}}, false},
+		{"reserved id", []TenantConfig{{ID: DefaultTenant}}, true},
+		{"unsafe id", []TenantConfig{{ID: "../acme"}}, true},
+		{"duplicate id", []TenantConfig{{ID: "acme"}, {ID: "acme"}}, true},
+		{"shared key", []TenantConfig{{ID: "acme", APIKeys: []string{"k"}}, {ID: "globex", APIKeys: []string{"k"}}}, true},
+		{"negative ttl", []TenantConfig{{ID: "acme", Retention: &RetentionPolicy{TTL: -1}}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateTenants(tt.tenants)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidatePlugins(t *testing.T) {
+	tests := []struct {
+		name    string
+		plugins []PluginConfig
+		wantErr bool
+	}{
+		{"valid", []PluginConfig{{Name: "stamp", Path: "/opt/plugins/stamp"}, {Name: "bates_number", Path: "/opt/plugins/bates"}}, false},
+		{"unsafe name", []PluginConfig{{Name: "../stamp", Path: "/opt/plugins/stamp"}}, true},
+		{"duplicate name", []PluginConfig{{Name: "stamp", Path: "/a"}, {Name: "stamp", Path: "/b"}}, true},
+		{"missing path", []PluginConfig{{Name: "stamp"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePlugins(tt.plugins)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateDatabase(t *testing.T) {
+	pool := DatabaseConfig{MaxOpenConns: 10, MaxIdleConns: 5, ConnMaxLifetime: 1800}
+	withDriver := func(driver, dsn string) DatabaseConfig {
+		cfg := pool
+		cfg.Driver, cfg.DSN = driver, dsn
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		cfg     DatabaseConfig
+		wantErr bool
+	}{
+		{"disabled", DatabaseConfig{}, false},
+		{"postgres", withDriver("postgres", "postgres://pdf@db/pdf"), false},
+		{"sqlite", withDriver("sqlite", "/var/lib/pdf-tool/pdf-tool.db"), false},
+		{"missing dsn", withDriver("postgres", ""), true},
+		{"unknown driver", withDriver("mysql", "pdf@tcp(db)/pdf"), true},
+		{"no connections", DatabaseConfig{Driver: "postgres", DSN: "postgres://pdf@db/pdf"}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateDatabase(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidateAuditTrail(t *testing.T) {
+	valid := AuditTrailConfig{Template: "# {{.Title}}\nSHA-256: {{.SHA256}}", PageSize: "a4", FontSize: 10}
+	with := func(change func(*AuditTrailConfig)) AuditTrailConfig {
+		cfg := valid
+		change(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		cfg     AuditTrailConfig
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"letter", with(func(c *AuditTrailConfig) { c.PageSize = "letter" }), false},
+		{"broken template", with(func(c *AuditTrailConfig) { c.Template = "{{range .Events}}" }), true},
+		{"unknown page size", with(func(c *AuditTrailConfig) { c.PageSize = "a3" }), true},
+		{"tiny font", with(func(c *AuditTrailConfig) { c.FontSize = 2 }), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validateAuditTrail(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestValidatePrepress(t *testing.T) {
+	valid := PrepressConfig{
+		Profiles:        map[string]string{"fogra39": "/etc/icc/fogra39.icc"},
+		DefaultProfile:  "fogra39",
+		FontSubstitutes: map[string]string{"calibri": "Carlito"},
+		SpotColors:      map[string][]float64{"pantone 185 c": {0, 91, 76, 0}},
+		FlattenDPI:      300,
+	}
+	with := func(change func(*PrepressConfig)) PrepressConfig {
+		cfg := valid
+		change(&cfg)
+		return cfg
+	}
+	tests := []struct {
+		name    string
+		cfg     PrepressConfig
+		wantErr bool
+	}{
+		{"valid", valid, false},
+		{"unknown default profile", with(func(c *PrepressConfig) { c.DefaultProfile = "swop" }), true},
+		{"empty substitute", with(func(c *PrepressConfig) { c.FontSubstitutes = map[string]string{"calibri": ""} }), true},
+		{"spot colour without black", with(func(c *PrepressConfig) { c.SpotColors = map[string][]float64{"brand": {0, 91, 76}} }), true},
+		{"spot colour over 100%", with(func(c *PrepressConfig) { c.SpotColors = map[string][]float64{"brand": {0, 191, 76, 0}} }), true},
+		{"coarse flattening", with(func(c *PrepressConfig) { c.FlattenDPI = 36 }), true},
+		{"fine flattening", with(func(c *PrepressConfig) { c.FlattenDPI = 2400 }), true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validatePrepress(tt.cfg)
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
@@ -0,0 +1,146 @@
+package config
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// reloadablePrefixes lists the settings that may change without a restart.
+// Everything else is only read at startup.
+var reloadablePrefixes = []string{
+	"log_level",
+	"pdf.max_file_size",
+	"rate_limit.requests_per_minute",
+	"rate_limit.burst",
+	"rate_limit.endpoints",
+}
+
+// Reloadable reports whether the setting key may change at runtime
+func Reloadable(key string) bool {
+	for _, prefix := range reloadablePrefixes {
+		if key == prefix || strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Reloader re-reads configuration on demand and hands the reloadable
+// settings to registered callbacks
+type Reloader struct {
+	log logger.Logger
+
+	mu        sync.Mutex
+	current   *Config
+	callbacks []func(*Config)
+}
+
+// NewReloader creates a reloader starting from the loaded configuration
+func NewReloader(cfg *Config, log logger.Logger) *Reloader {
+	return &Reloader{log: log, current: cfg}
+}
+
+// OnReload registers fn to be called with the new configuration after each
+// reload that changed a reloadable setting
+func (r *Reloader) OnReload(fn func(*Config)) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.callbacks = append(r.callbacks, fn)
+}
+
+// Reload reads and validates the configuration again. Changed reloadable
+// settings are applied; other changes are logged and ignored until restart.
+// Only key names are logged since values may be secrets.
+func (r *Reloader) Reload() error {
+	next, err := Load()
+	if err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var applied, ignored []string
+	for _, key := range Diff(r.current, next) {
+		if Reloadable(key) {
+			applied = append(applied, key)
+		} else {
+			ignored = append(ignored, key)
+		}
+	}
+
+	if len(ignored) > 0 {
+		r.log.Warn("Configuration changes require a restart", "keys", ignored)
+	}
+	if len(applied) == 0 {
+		r.log.Info("Configuration reloaded, no reloadable changes")
+		return nil
+	}
+
+	// Keep startup-only settings as they were so the applied config
+	// matches what is actually running
+	updated := *r.current
+	updated.LogLevel = next.LogLevel
+	updated.PDF.MaxFileSize = next.PDF.MaxFileSize
+	updated.RateLimit.RequestsPerMin = next.RateLimit.RequestsPerMin
+	updated.RateLimit.Burst = next.RateLimit.Burst
+	updated.RateLimit.Endpoints = next.RateLimit.Endpoints
+	r.current = &updated
+
+	for _, fn := range r.callbacks {
+		fn(r.current)
+	}
+
+	r.log.Info("Configuration reloaded", "changed", applied)
+	return nil
+}
+
+// Diff returns the sorted setting keys whose values differ between a and b
+func Diff(a, b *Config) []string {
+	left := map[string]interface{}{}
+	right := map[string]interface{}{}
+	flatten(reflect.ValueOf(*a), "", left)
+	flatten(reflect.ValueOf(*b), "", right)
+
+	var changed []string
+	for key, value := range left {
+		if !reflect.DeepEqual(value, right[key]) {
+			changed = append(changed, key)
+		}
+	}
+	for key := range right {
+		if _, ok := left[key]; !ok {
+			changed = append(changed, key)
+		}
+	}
+	sort.Strings(changed)
+	return changed
+}
+
+// flatten collects leaf settings keyed by their dotted mapstructure path
+func flatten(v reflect.Value, prefix string, out map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" || name == "-" {
+			continue
+		}
+
+		if name == ",squash" {
+			flatten(v.Field(i), prefix, out)
+			continue
+		}
+
+		key := prefix + name
+		if field.Type.Kind() == reflect.Struct {
+			flatten(v.Field(i), key+".", out)
+			continue
+		}
+		out[key] = v.Field(i).Interface()
+	}
+}
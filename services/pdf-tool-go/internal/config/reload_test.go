@@ -0,0 +1,37 @@
+package config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	t.Run("reports changed leaf keys", func(t *testing.T) {
+		a := &Config{LogLevel: "info", PDF: PDFConfig{MaxFileSize: 10}}
+		b := &Config{LogLevel: "debug", PDF: PDFConfig{MaxFileSize: 20}}
+
+		assert.Equal(t, []string{"log_level", "pdf.max_file_size"}, Diff(a, b))
+	})
+
+	t.Run("compares maps by value", func(t *testing.T) {
+		a := &Config{Timeouts: TimeoutConfig{Operations: map[string]int{"merge": 60}}}
+		b := &Config{Timeouts: TimeoutConfig{Operations: map[string]int{"merge": 60}}}
+
+		assert.Empty(t, Diff(a, b))
+
+		b.Timeouts.Operations["merge"] = 90
+		assert.Equal(t, []string{"timeouts.operations"}, Diff(a, b))
+	})
+}
+
+func TestReloadable(t *testing.T) {
+	assert.True(t, Reloadable("log_level"))
+	assert.True(t, Reloadable("pdf.max_file_size"))
+	assert.True(t, Reloadable("rate_limit.burst"))
+	assert.True(t, Reloadable("rate_limit.endpoints"))
+	assert.False(t, Reloadable("rate_limit.enabled"))
+	assert.False(t, Reloadable("rate_limit.redis.address"))
+	assert.False(t, Reloadable("pdf.max_pages"))
+	assert.False(t, Reloadable("port"))
+}
@@ -0,0 +1,249 @@
+/**
+ * Cloud Storage Connectors
+ *
+ * Reads documents from and writes results to user-authorized Dropbox,
+ * Google Drive and OneDrive storage. Requests authorize with an OAuth
+ * access token of their own, or use a refresh token stored for their
+ * tenant, which is exchanged for access tokens as they expire.
+ */
+
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+)
+
+// refreshMargin is how long before their expiry refreshed access tokens
+// are replaced
+const refreshMargin = time.Minute
+
+// storage is a provider's file API
+type storage interface {
+	// read returns the file at path, failing once it exceeds limit bytes
+	read(ctx context.Context, token, path string, limit int64) ([]byte, error)
+	// write creates or replaces the file at path and returns where it was
+	// written
+	write(ctx context.Context, token, path string, data []byte) (string, error)
+}
+
+// provider is an enabled provider
+type provider struct {
+	cfg      config.ConnectorProvider
+	files    storage
+	tokenURL string
+	// scope is requested when refreshing tokens, for providers needing one
+	scope string
+}
+
+// accessToken is a refreshed access token
+type accessToken struct {
+	value     string
+	expiresAt time.Time
+}
+
+// Connectors reads and writes files through the enabled providers
+type Connectors struct {
+	providers map[string]*provider
+	// refreshTokens are the stored refresh tokens by tenant and provider
+	refreshTokens map[string]map[string]string
+	client        *http.Client
+	now           func() time.Time
+
+	mu     sync.Mutex
+	tokens map[string]accessToken
+}
+
+// New creates connectors for the configured providers, with the refresh
+// tokens stored for tenants
+func New(cfg config.ConnectorsConfig, tenants []config.TenantConfig) *Connectors {
+	client := &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	c := &Connectors{
+		providers:     make(map[string]*provider, len(cfg.Providers)),
+		refreshTokens: make(map[string]map[string]string, len(tenants)),
+		client:        client,
+		now:           time.Now,
+		tokens:        make(map[string]accessToken),
+	}
+	for _, p := range cfg.Providers {
+		t := &transport{client: client, name: p.Name}
+		switch p.Name {
+		case "dropbox":
+			c.providers[p.Name] = &provider{cfg: p, files: newDropbox(t), tokenURL: "https://api.dropboxapi.com/oauth2/token"}
+		case "google_drive":
+			c.providers[p.Name] = &provider{cfg: p, files: newGoogleDrive(t), tokenURL: "https://oauth2.googleapis.com/token"}
+		case "onedrive":
+			c.providers[p.Name] = &provider{cfg: p, files: newOneDrive(t),
+				tokenURL: "https://login.microsoftonline.com/common/oauth2/v2.0/token", scope: "offline_access Files.ReadWrite"}
+		}
+	}
+	for _, t := range tenants {
+		if len(t.Connectors) > 0 {
+			c.refreshTokens[t.ID] = t.Connectors
+		}
+	}
+	return c
+}
+
+// Read returns the file at path in the storage of provider, authorized by
+// token or, when it is empty, by the token stored for the tenant of ctx.
+// Files larger than limit bytes are rejected.
+func (c *Connectors) Read(ctx context.Context, name, token, path string, limit int64) ([]byte, error) {
+	p, token, err := c.authorize(ctx, name, token)
+	if err != nil {
+		return nil, err
+	}
+	return p.files.read(ctx, token, path, limit)
+}
+
+// Write stores data at path in the storage of provider, authorized like
+// Read, and returns where it was written: the path, or the new file's ID
+// on Google Drive
+func (c *Connectors) Write(ctx context.Context, name, token, path string, data []byte) (string, error) {
+	p, token, err := c.authorize(ctx, name, token)
+	if err != nil {
+		return "", err
+	}
+	return p.files.write(ctx, token, path, data)
+}
+
+// authorize returns the provider and the access token a call uses
+func (c *Connectors) authorize(ctx context.Context, name, token string) (*provider, string, error) {
+	p, ok := c.providers[name]
+	if !ok {
+		return nil, "", service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("connector %q is not enabled", name), nil)
+	}
+	if token != "" {
+		return p, token, nil
+	}
+
+	owner := tenant.FromContext(ctx)
+	refreshToken := c.refreshTokens[owner][name]
+	if refreshToken == "" {
+		return nil, "", service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("no %s token is stored for the tenant; pass an access token", name), nil)
+	}
+	token, err := c.refresh(ctx, p, owner, refreshToken)
+	return p, token, err
+}
+
+// refresh returns an access token for a stored refresh token, exchanging
+// it when the cached one is about to expire
+func (c *Connectors) refresh(ctx context.Context, p *provider, owner, refreshToken string) (string, error) {
+	key := owner + "/" + p.cfg.Name
+	c.mu.Lock()
+	cached, ok := c.tokens[key]
+	c.mu.Unlock()
+	if ok && c.now().Add(refreshMargin).Before(cached.expiresAt) {
+		return cached.value, nil
+	}
+
+	form := url.Values{
+		"grant_type":    {"refresh_token"},
+		"refresh_token": {refreshToken},
+		"client_id":     {p.cfg.ClientID},
+		"client_secret": {p.cfg.ClientSecret},
+	}
+	if p.scope != "" {
+		form.Set("scope", p.scope)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.tokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return "", unavailable(p.cfg.Name, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		if resp.StatusCode >= 500 {
+			return "", unavailable(p.cfg.Name, fmt.Errorf("token endpoint returned status %d", resp.StatusCode))
+		}
+		return "", service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("%s rejected the stored token: %s", p.cfg.Name, bytes.TrimSpace(text)), nil)
+	}
+
+	var body struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int    `json:"expires_in"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&body); err != nil || body.AccessToken == "" {
+		return "", fmt.Errorf("invalid %s token response: %v", p.cfg.Name, err)
+	}
+
+	c.mu.Lock()
+	c.tokens[key] = accessToken{value: body.AccessToken, expiresAt: c.now().Add(time.Duration(body.ExpiresIn) * time.Second)}
+	c.mu.Unlock()
+	return body.AccessToken, nil
+}
+
+// transport sends authorized calls to a provider's API
+type transport struct {
+	client *http.Client
+	name   string
+}
+
+// do sends req authorized by token and returns the response of a
+// successful call. Failures are mapped onto service errors.
+func (t *transport) do(req *http.Request, token string) (*http.Response, error) {
+	req.Header.Set("Authorization", "Bearer "+token)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, unavailable(t.name, err)
+	}
+	if resp.StatusCode/100 == 2 {
+		return resp, nil
+	}
+	defer resp.Body.Close()
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+
+	switch {
+	case resp.StatusCode == http.StatusNotFound ||
+		(resp.StatusCode == http.StatusConflict && bytes.Contains(text, []byte("not_found"))):
+		return nil, service.NewError(service.ErrCodeNotFound, fmt.Sprintf("file not found in %s", t.name), nil)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		return nil, service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("%s rejected the access token", t.name), nil)
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return nil, unavailable(t.name, fmt.Errorf("status %d", resp.StatusCode))
+	}
+	return nil, service.NewError(service.ErrCodeInvalidInput,
+		fmt.Sprintf("%s rejected the request: %s", t.name, bytes.TrimSpace(text)), nil)
+}
+
+// readLimited reads a downloaded file, failing once it exceeds limit bytes
+func readLimited(resp *http.Response, limit int64) ([]byte, error) {
+	defer resp.Body.Close()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > limit {
+		return nil, service.NewError(service.ErrCodeFileTooLarge, fmt.Sprintf("file exceeds the maximum size of %d bytes", limit), nil)
+	}
+	return data, nil
+}
+
+// unavailable reports a provider that could not be reached or is failing
+func unavailable(name string, err error) error {
+	// Request URLs may carry file paths; keep the cause only
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	return service.NewError(service.ErrCodeBusy, fmt.Sprintf("%s is unavailable, retry later", name), err)
+}
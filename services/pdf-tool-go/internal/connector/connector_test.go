@@ -0,0 +1,159 @@
+package connector
+
+import (
+	"context"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestConnectors(t *testing.T) {
+	cfg := config.ConnectorsConfig{Enabled: true, Timeout: 5, Providers: []config.ConnectorProvider{
+		{Name: "dropbox", ClientID: "id", ClientSecret: "secret"},
+		{Name: "google_drive", ClientID: "id", ClientSecret: "secret"},
+		{Name: "onedrive", ClientID: "id", ClientSecret: "secret"},
+	}}
+	tenants := []config.TenantConfig{{ID: "acme", Connectors: map[string]string{"dropbox": "refresh-acme"}}}
+	ctx := tenant.WithID(context.Background(), "acme")
+
+	// newConnectors points every provider at server
+	newConnectors := func(server *httptest.Server) *Connectors {
+		c := New(cfg, tenants)
+		for _, p := range c.providers {
+			p.tokenURL = server.URL + "/token"
+			switch files := p.files.(type) {
+			case *dropbox:
+				files.content = server.URL
+			case *googleDrive:
+				files.api = server.URL
+				files.upload = server.URL + "/upload"
+			case *oneDrive:
+				files.graph = server.URL
+			}
+		}
+		return c
+	}
+
+	t.Run("Dropbox Files Are Read And Written By Path", func(t *testing.T) {
+		var args []string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer access", r.Header.Get("Authorization"))
+			args = append(args, r.Header.Get("Dropbox-API-Arg"))
+			switch r.URL.Path {
+			case "/2/files/download":
+				io.WriteString(w, "%PDF-1.7 march")
+			case "/2/files/upload":
+				body, _ := io.ReadAll(r.Body)
+				assert.Equal(t, "%PDF-1.7 out", string(body))
+				io.WriteString(w, `{"path_display": "/Out/März.pdf"}`)
+			}
+		}))
+		defer server.Close()
+		c := newConnectors(server)
+
+		data, err := c.Read(ctx, "dropbox", "access", "/Invoices/march.pdf", 1024)
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF-1.7 march", string(data))
+
+		written, err := c.Write(ctx, "dropbox", "access", "/out/märz.pdf", []byte("%PDF-1.7 out"))
+		require.NoError(t, err)
+		assert.Equal(t, "/Out/März.pdf", written)
+		assert.Equal(t, []string{
+			`{"path":"/Invoices/march.pdf"}`,
+			`{"mode":"overwrite","mute":true,"path":"/out/m\u00e4rz.pdf"}`,
+		}, args)
+	})
+
+	t.Run("Google Drive Files Are Created In Folders", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "/upload/drive/v3/files", r.URL.Path)
+			_, params, err := mime.ParseMediaType(r.Header.Get("Content-Type"))
+			require.NoError(t, err)
+			parts := multipart.NewReader(r.Body, params["boundary"])
+			metadata, err := parts.NextPart()
+			require.NoError(t, err)
+			body, _ := io.ReadAll(metadata)
+			assert.JSONEq(t, `{"name": "out.pdf", "parents": ["folder1"]}`, string(body))
+			io.WriteString(w, `{"id": "file1"}`)
+		}))
+		defer server.Close()
+
+		written, err := newConnectors(server).Write(ctx, "google_drive", "access", "folder1/out.pdf", []byte("%PDF-1.7"))
+		require.NoError(t, err)
+		assert.Equal(t, "file1", written)
+	})
+
+	t.Run("Stored Refresh Tokens Are Exchanged Once", func(t *testing.T) {
+		refreshes := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path == "/token" {
+				refreshes++
+				assert.Equal(t, "refresh-acme", r.PostFormValue("refresh_token"))
+				assert.Equal(t, "secret", r.PostFormValue("client_secret"))
+				io.WriteString(w, `{"access_token": "refreshed", "expires_in": 3600}`)
+				return
+			}
+			assert.Equal(t, "Bearer refreshed", r.Header.Get("Authorization"))
+			io.WriteString(w, "%PDF-1.7")
+		}))
+		defer server.Close()
+		c := newConnectors(server)
+		now := time.Now()
+		c.now = func() time.Time { return now }
+
+		for i := 0; i < 2; i++ {
+			_, err := c.Read(ctx, "dropbox", "", "/a.pdf", 1024)
+			require.NoError(t, err)
+		}
+		assert.Equal(t, 1, refreshes)
+
+		now = now.Add(time.Hour)
+		_, err := c.Read(ctx, "dropbox", "", "/a.pdf", 1024)
+		require.NoError(t, err)
+		assert.Equal(t, 2, refreshes)
+
+		_, err = c.Read(tenant.WithID(context.Background(), "globex"), "dropbox", "", "/a.pdf", 1024)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("Provider Failures Are Mapped", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch {
+			case strings.Contains(r.URL.Path, "missing"):
+				http.Error(w, `{"error": {"code": "itemNotFound"}}`, http.StatusNotFound)
+			case strings.Contains(r.URL.Path, "throttled"):
+				w.WriteHeader(http.StatusTooManyRequests)
+			case strings.Contains(r.URL.Path, "denied"):
+				w.WriteHeader(http.StatusUnauthorized)
+			default:
+				io.WriteString(w, strings.Repeat("x", 2048))
+			}
+		}))
+		defer server.Close()
+		c := newConnectors(server)
+
+		for path, code := range map[string]service.ErrorCode{
+			"/missing.pdf":   service.ErrCodeNotFound,
+			"/throttled.pdf": service.ErrCodeBusy,
+			"/denied.pdf":    service.ErrCodeInvalidInput,
+			"/large.pdf":     service.ErrCodeFileTooLarge,
+		} {
+			_, err := c.Read(ctx, "onedrive", "access", path, 1024)
+			assert.Equal(t, code, service.CodeOf(err), path)
+		}
+
+		_, err := c.Read(ctx, "box", "access", "/a.pdf", 1024)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+}
@@ -0,0 +1,212 @@
+package connector
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// dropbox addresses files by path, such as /Invoices/march.pdf
+type dropbox struct {
+	*transport
+	content string
+}
+
+func newDropbox(t *transport) *dropbox {
+	return &dropbox{transport: t, content: "https://content.dropboxapi.com"}
+}
+
+func (d *dropbox) read(ctx context.Context, token, path string, limit int64) ([]byte, error) {
+	req, err := d.request(ctx, "/2/files/download", map[string]interface{}{"path": path}, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := d.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	return readLimited(resp, limit)
+}
+
+func (d *dropbox) write(ctx context.Context, token, path string, data []byte) (string, error) {
+	req, err := d.request(ctx, "/2/files/upload", map[string]interface{}{"path": path, "mode": "overwrite", "mute": true}, data)
+	if err != nil {
+		return "", err
+	}
+	resp, err := d.do(req, token)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var written struct {
+		PathDisplay string `json:"path_display"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&written); err != nil || written.PathDisplay == "" {
+		return path, nil
+	}
+	return written.PathDisplay, nil
+}
+
+// request builds a content call, whose arguments travel in the
+// Dropbox-API-Arg header
+func (d *dropbox) request(ctx context.Context, endpoint string, args map[string]interface{}, body []byte) (*http.Request, error) {
+	arg, err := json.Marshal(args)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, d.content+endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Dropbox-API-Arg", asciiJSON(arg))
+	req.Header.Set("Content-Type", "application/octet-stream")
+	return req, nil
+}
+
+// asciiJSON escapes the non-ASCII characters of JSON data, which HTTP
+// headers cannot carry
+func asciiJSON(data []byte) string {
+	var b strings.Builder
+	for _, r := range string(data) {
+		switch {
+		case r < utf8.RuneSelf:
+			b.WriteRune(r)
+		case r > 0xFFFF:
+			r -= 0x10000
+			fmt.Fprintf(&b, `\u%04x\u%04x`, 0xD800+(r>>10), 0xDC00+(r&0x3FF))
+		default:
+			fmt.Fprintf(&b, `\u%04x`, r)
+		}
+	}
+	return b.String()
+}
+
+// googleDrive addresses files by ID. Files are written as
+// <folder ID>/<name>, or <name> for the root folder, and a new file is
+// created on every write.
+type googleDrive struct {
+	*transport
+	api    string
+	upload string
+}
+
+func newGoogleDrive(t *transport) *googleDrive {
+	return &googleDrive{transport: t, api: "https://www.googleapis.com", upload: "https://www.googleapis.com/upload"}
+}
+
+func (g *googleDrive) read(ctx context.Context, token, id string, limit int64) ([]byte, error) {
+	if id == "" || strings.Contains(id, "/") {
+		return nil, service.NewError(service.ErrCodeInvalidInput, "google_drive files are read by file ID", nil)
+	}
+	endpoint := g.api + "/drive/v3/files/" + url.PathEscape(id) + "?alt=media&supportsAllDrives=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := g.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	return readLimited(resp, limit)
+}
+
+func (g *googleDrive) write(ctx context.Context, token, target string, data []byte) (string, error) {
+	metadata := map[string]interface{}{"name": path.Base(target)}
+	if folder := path.Dir(target); folder != "." {
+		metadata["parents"] = []string{folder}
+	}
+
+	var body bytes.Buffer
+	w := multipart.NewWriter(&body)
+	part, err := w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/json; charset=UTF-8"}})
+	if err != nil {
+		return "", err
+	}
+	if err := json.NewEncoder(part).Encode(metadata); err != nil {
+		return "", err
+	}
+	if part, err = w.CreatePart(textproto.MIMEHeader{"Content-Type": {"application/pdf"}}); err != nil {
+		return "", err
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return "", err
+	}
+
+	endpoint := g.upload + "/drive/v3/files?uploadType=multipart&supportsAllDrives=true"
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, &body)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "multipart/related; boundary="+w.Boundary())
+	resp, err := g.do(req, token)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || created.ID == "" {
+		return "", fmt.Errorf("invalid google_drive upload response: %v", err)
+	}
+	return created.ID, nil
+}
+
+// oneDrive addresses files by path from the drive root, such as
+// /Invoices/march.pdf
+type oneDrive struct {
+	*transport
+	graph string
+}
+
+func newOneDrive(t *transport) *oneDrive {
+	return &oneDrive{transport: t, graph: "https://graph.microsoft.com"}
+}
+
+func (o *oneDrive) read(ctx context.Context, token, path string, limit int64) ([]byte, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.contentURL(path), nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := o.do(req, token)
+	if err != nil {
+		return nil, err
+	}
+	return readLimited(resp, limit)
+}
+
+func (o *oneDrive) write(ctx context.Context, token, path string, data []byte) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPut, o.contentURL(path), bytes.NewReader(data))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/pdf")
+	resp, err := o.do(req, token)
+	if err != nil {
+		return "", err
+	}
+	resp.Body.Close()
+	return path, nil
+}
+
+// contentURL addresses the content of the file at path
+func (o *oneDrive) contentURL(path string) string {
+	segments := strings.Split(strings.Trim(path, "/"), "/")
+	for i, segment := range segments {
+		segments[i] = url.PathEscape(segment)
+	}
+	return o.graph + "/v1.0/me/drive/root:/" + strings.Join(segments, "/") + ":/content"
+}
@@ -0,0 +1,312 @@
+/**
+ * Scheduled Jobs
+ *
+ * Runs recurring maintenance tasks, such as purging expired documents or
+ * re-compressing archives, on cron schedules defined in configuration or
+ * added on the admin listener.
+ */
+
+package cron
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var (
+	runsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_cron_runs_total",
+		Help: "Scheduled job runs by task and outcome",
+	}, []string{"task", "status"})
+	runDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_cron_run_duration_seconds",
+		Help:    "Duration of scheduled job runs",
+		Buckets: prometheus.ExponentialBuckets(0.1, 2, 16),
+	}, []string{"task"})
+)
+
+// idleWait bounds how long the scheduler sleeps when no job is due
+const idleWait = time.Hour
+
+// Task is a maintenance operation scheduled jobs can run
+type Task struct {
+	// Params lists the parameters the task accepts
+	Params []string
+	Run    func(ctx context.Context, params map[string]string) error
+}
+
+// Source records where a job was defined
+type Source string
+
+const (
+	// SourceConfig jobs come from configuration
+	SourceConfig Source = "config"
+	// SourceAPI jobs were added on the admin listener and are lost on restart
+	SourceAPI Source = "api"
+)
+
+// Entry describes a scheduled job and its last run
+type Entry struct {
+	Name      string            `json:"name"`
+	Schedule  string            `json:"schedule"`
+	Task      string            `json:"task"`
+	Params    map[string]string `json:"params,omitempty"`
+	Source    Source            `json:"source"`
+	NextRun   time.Time         `json:"next_run"`
+	LastRun   *time.Time        `json:"last_run,omitempty"`
+	LastError string            `json:"last_error,omitempty"`
+	Running   bool              `json:"running"`
+}
+
+// entry is a scheduled job
+type entry struct {
+	Entry
+	schedule *Schedule
+}
+
+// Scheduler runs jobs when their schedule is due. A job still running when
+// it is due again skips that run.
+type Scheduler struct {
+	log logger.Logger
+
+	mu    sync.Mutex
+	tasks map[string]Task
+	jobs  map[string]*entry
+	// wake interrupts the scheduler's sleep when jobs change
+	wake chan struct{}
+
+	stopping chan struct{}
+	stopOnce sync.Once
+	running  lifecycle.Tracker
+	// ctx is the context runs start under, set by Start
+	ctx context.Context
+}
+
+// NewScheduler creates a scheduler without tasks or jobs
+func NewScheduler(log logger.Logger) *Scheduler {
+	return &Scheduler{
+		log:      log,
+		tasks:    make(map[string]Task),
+		jobs:     make(map[string]*entry),
+		wake:     make(chan struct{}, 1),
+		stopping: make(chan struct{}),
+		ctx:      context.Background(),
+	}
+}
+
+// RegisterTask makes a task available to jobs under name
+func (s *Scheduler) RegisterTask(name string, task Task) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.tasks[name] = task
+}
+
+// Add schedules a job. It fails if the name is taken, the task is unknown
+// or the schedule or parameters are invalid.
+func (s *Scheduler) Add(job config.CronJob, source Source) error {
+	schedule, err := Parse(job.Schedule)
+	if err != nil {
+		return service.NewError(service.ErrCodeInvalidInput, err.Error(), nil)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if job.Name == "" {
+		return service.NewError(service.ErrCodeInvalidInput, "job name is required", nil)
+	}
+	if _, ok := s.jobs[job.Name]; ok {
+		return service.NewError(service.ErrCodeInvalidInput, "job "+job.Name+" already exists", nil)
+	}
+	task, ok := s.tasks[job.Task]
+	if !ok {
+		return service.NewError(service.ErrCodeInvalidInput, "unknown task "+job.Task, nil)
+	}
+	for name := range job.Params {
+		if !accepts(task, name) {
+			return service.NewError(service.ErrCodeInvalidInput, "task "+job.Task+" does not accept parameter "+name, nil)
+		}
+	}
+
+	s.jobs[job.Name] = &entry{
+		Entry: Entry{
+			Name:     job.Name,
+			Schedule: job.Schedule,
+			Task:     job.Task,
+			Params:   job.Params,
+			Source:   source,
+			NextRun:  schedule.Next(time.Now()),
+		},
+		schedule: schedule,
+	}
+	s.notify()
+
+	s.log.Info("Scheduled job added", "job", job.Name, "task", job.Task, "schedule", job.Schedule, "source", source)
+	return nil
+}
+
+// Remove unschedules a job. A run in progress is not interrupted.
+func (s *Scheduler) Remove(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.jobs[name]; !ok {
+		return service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	delete(s.jobs, name)
+	s.notify()
+
+	s.log.Info("Scheduled job removed", "job", name)
+	return nil
+}
+
+// List returns the scheduled jobs ordered by name
+func (s *Scheduler) List() []Entry {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]Entry, 0, len(s.jobs))
+	for _, e := range s.jobs {
+		entries = append(entries, e.Entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Name < entries[j].Name
+	})
+	return entries
+}
+
+// Trigger runs a job now, outside its schedule
+func (s *Scheduler) Trigger(name string) error {
+	select {
+	case <-s.stopping:
+		return service.NewError(service.ErrCodeBusy, "server is shutting down", nil)
+	default:
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	e, ok := s.jobs[name]
+	if !ok {
+		return service.NewError(service.ErrCodeNotFound, "job not found", nil)
+	}
+	if e.Running {
+		return service.NewError(service.ErrCodeBusy, "job is already running", nil)
+	}
+	s.launch(e)
+	return nil
+}
+
+// Start runs due jobs until ctx is done or Drain is called
+func (s *Scheduler) Start(ctx context.Context) {
+	s.mu.Lock()
+	s.ctx = ctx
+	s.mu.Unlock()
+	go func() {
+		for {
+			timer := time.NewTimer(s.dispatch(time.Now()))
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return
+			case <-s.stopping:
+				timer.Stop()
+				return
+			case <-s.wake:
+				timer.Stop()
+			case <-timer.C:
+			}
+		}
+	}()
+}
+
+// Drain stops starting runs and waits for running ones
+func (s *Scheduler) Drain(ctx context.Context) error {
+	s.stopOnce.Do(func() { close(s.stopping) })
+	return s.running.Wait(ctx)
+}
+
+// dispatch launches the jobs due at now and returns how long to wait for
+// the next one
+func (s *Scheduler) dispatch(now time.Time) time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	wait := idleWait
+	for _, e := range s.jobs {
+		if !e.NextRun.After(now) {
+			if e.Running {
+				s.log.Warn("Scheduled job still running, skipping run", "job", e.Name)
+			} else {
+				s.launch(e)
+			}
+			e.NextRun = e.schedule.Next(now)
+		}
+		if until := e.NextRun.Sub(now); until < wait {
+			wait = until
+		}
+	}
+	return wait
+}
+
+// launch runs e in the background. s.mu must be held.
+func (s *Scheduler) launch(e *entry) {
+	task, ctx, job := s.tasks[e.Task], s.ctx, e.Entry
+	e.Running = true
+	done := s.running.Start()
+
+	go func() {
+		defer done()
+		s.log.Info("Scheduled job started", "job", job.Name, "task", job.Task)
+
+		start := time.Now()
+		err := task.Run(ctx, job.Params)
+		elapsed := time.Since(start)
+
+		status := "success"
+		if err != nil {
+			status = "error"
+			s.log.Error("Scheduled job failed", "job", job.Name, "task", job.Task, "duration", elapsed, "error", err)
+		} else {
+			s.log.Info("Scheduled job completed", "job", job.Name, "task", job.Task, "duration", elapsed)
+		}
+		runsTotal.WithLabelValues(job.Task, status).Inc()
+		runDuration.WithLabelValues(job.Task).Observe(elapsed.Seconds())
+
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		e.Running = false
+		e.LastRun = &start
+		e.LastError = ""
+		if err != nil {
+			e.LastError = err.Error()
+		}
+	}()
+}
+
+// notify wakes the scheduler to recompute its sleep
+func (s *Scheduler) notify() {
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// accepts reports whether task takes the parameter name
+func accepts(task Task, name string) bool {
+	for _, param := range task.Params {
+		if param == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+package cron
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSchedule(t *testing.T) {
+	// A Wednesday
+	from := time.Date(2024, 1, 10, 10, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		expr string
+		next time.Time
+	}{
+		{"*/15 * * * *", time.Date(2024, 1, 10, 10, 45, 0, 0, time.UTC)},
+		{"0 2 * * *", time.Date(2024, 1, 11, 2, 0, 0, 0, time.UTC)},
+		{"@hourly", time.Date(2024, 1, 10, 11, 0, 0, 0, time.UTC)},
+		{"0 0 * * 0", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 1, 14, 0, 0, 0, 0, time.UTC)},
+		{"30 4 1,15 * *", time.Date(2024, 1, 15, 4, 30, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches
+		{"0 0 1 * 5", time.Date(2024, 1, 12, 0, 0, 0, 0, time.UTC)},
+		{"0 0 29 2 *", time.Date(2024, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2024, 1, 10, 13, 0, 0, 0, time.UTC)},
+		{"@every 90m", from.Add(90 * time.Minute)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			schedule, err := Parse(tt.expr)
+			require.NoError(t, err)
+			assert.Equal(t, tt.next, schedule.Next(from))
+		})
+	}
+
+	t.Run("Invalid Expressions", func(t *testing.T) {
+		for _, expr := range []string{"", "* * * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 30 2 *", "@every 10s", "@often"} {
+			_, err := Parse(expr)
+			assert.Error(t, err, expr)
+		}
+	})
+}
+
+func TestScheduler(t *testing.T) {
+	s := NewScheduler(logger.New("info", "text"))
+	ran := make(chan map[string]string, 1)
+	s.RegisterTask("echo", Task{
+		Params: []string{"word"},
+		Run: func(ctx context.Context, params map[string]string) error {
+			ran <- params
+			return errors.New("echo failed")
+		},
+	})
+
+	t.Run("Add Validates Jobs", func(t *testing.T) {
+		require.NoError(t, s.Add(config.CronJob{Name: "nightly", Schedule: "@daily", Task: "echo", Params: map[string]string{"word": "hi"}}, SourceConfig))
+
+		for _, job := range []config.CronJob{
+			{Name: "nightly", Schedule: "@daily", Task: "echo"},
+			{Name: "other", Schedule: "@daily", Task: "missing"},
+			{Name: "other", Schedule: "@daily", Task: "echo", Params: map[string]string{"color": "red"}},
+			{Name: "other", Schedule: "daily", Task: "echo"},
+		} {
+			assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(s.Add(job, SourceAPI)))
+		}
+	})
+
+	t.Run("Trigger Runs And Records Outcome", func(t *testing.T) {
+		require.NoError(t, s.Trigger("nightly"))
+		assert.Equal(t, map[string]string{"word": "hi"}, <-ran)
+		require.NoError(t, s.Drain(context.Background()))
+
+		entries := s.List()
+		require.Len(t, entries, 1)
+		assert.False(t, entries[0].Running)
+		assert.NotNil(t, entries[0].LastRun)
+		assert.Equal(t, "echo failed", entries[0].LastError)
+	})
+
+	t.Run("Remove", func(t *testing.T) {
+		require.NoError(t, s.Remove("nightly"))
+		assert.Empty(t, s.List())
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(s.Remove("nightly")))
+	})
+}
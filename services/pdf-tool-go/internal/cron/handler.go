@@ -0,0 +1,84 @@
+package cron
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// adminPath is the admin path of the scheduled jobs API
+const adminPath = "/admin/cron"
+
+// jobRequest is the body of a request adding a job
+type jobRequest struct {
+	Name     string            `json:"name"`
+	Schedule string            `json:"schedule"`
+	Task     string            `json:"task"`
+	Params   map[string]string `json:"params"`
+}
+
+// Handler serves scheduled jobs on the admin listener at /admin/cron/:
+// GET lists jobs, POST adds one, DELETE <name> removes one and
+// POST <name>/run runs one now
+func (s *Scheduler) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, adminPath), "/")
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			writeJSON(w, http.StatusOK, map[string]interface{}{"jobs": s.List()})
+
+		case rest == "" && r.Method == http.MethodPost:
+			var req jobRequest
+			if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(&req); err != nil {
+				http.Error(w, "invalid job: "+err.Error(), http.StatusBadRequest)
+				return
+			}
+			job := config.CronJob{Name: req.Name, Schedule: req.Schedule, Task: req.Task, Params: req.Params}
+			if err := s.Add(job, SourceAPI); err != nil {
+				writeError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusCreated)
+
+		case strings.HasSuffix(rest, "/run") && r.Method == http.MethodPost:
+			if err := s.Trigger(strings.TrimSuffix(rest, "/run")); err != nil {
+				writeError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusAccepted)
+
+		case rest != "" && r.Method == http.MethodDelete:
+			if err := s.Remove(rest); err != nil {
+				writeError(w, err)
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	status := http.StatusInternalServerError
+	switch service.CodeOf(err) {
+	case service.ErrCodeNotFound:
+		status = http.StatusNotFound
+	case service.ErrCodeInvalidInput:
+		status = http.StatusBadRequest
+	case service.ErrCodeBusy:
+		status = http.StatusConflict
+	}
+	http.Error(w, err.Error(), status)
+}
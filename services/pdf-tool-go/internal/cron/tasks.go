@@ -0,0 +1,78 @@
+package cron
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/janitor"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/retention"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+)
+
+// PurgeTask purges documents and results past their retention TTL
+func PurgeTask(purger *retention.Purger) Task {
+	return Task{
+		Run: func(ctx context.Context, params map[string]string) error {
+			purger.Purge(ctx)
+			return nil
+		},
+	}
+}
+
+// SweepTask removes orphaned temp files
+func SweepTask(j *janitor.Janitor) Task {
+	return Task{
+		Run: func(ctx context.Context, params map[string]string) error {
+			_, err := j.Sweep()
+			return err
+		},
+	}
+}
+
+// RecompressTask re-compresses the stored documents of a tenant ("tenant",
+// default tenant if unset) as bulk batch jobs of at most maxDocuments
+// documents each. "level" and "profile" are passed to the compress step;
+// outputs are saved as results.
+func RecompressTask(documents *service.DocumentService, jobs *batch.Manager, maxDocuments int) Task {
+	return Task{
+		Params: []string{"tenant", "level", "profile"},
+		Run: func(ctx context.Context, params map[string]string) error {
+			owner := params["tenant"]
+			if owner == "" {
+				owner = config.DefaultTenant
+			}
+			ctx = tenant.WithID(ctx, owner)
+
+			step := batch.Step{Operation: "compress", Params: make(map[string]string)}
+			for _, name := range []string{"level", "profile"} {
+				if value := params[name]; value != "" {
+					step.Params[name] = value
+				}
+			}
+
+			var ids []string
+			err := documents.List(ctx, func(doc *service.Result) error {
+				ids = append(ids, doc.ID)
+				return nil
+			})
+			if err != nil {
+				return fmt.Errorf("failed to list documents: %w", err)
+			}
+
+			for start := 0; start < len(ids); start += maxDocuments {
+				sub := batch.Submission{
+					Documents: ids[start:min(start+maxDocuments, len(ids))],
+					Steps:     []batch.Step{step},
+					Priority:  batch.PriorityBulk,
+				}
+				if _, err := jobs.Submit(ctx, sub); err != nil {
+					return fmt.Errorf("failed to submit recompress job: %w", err)
+				}
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,182 @@
+/**
+ * Database Module
+ *
+ * Connection pool for the SQL database holding persistent state, and
+ * schema migrations embedded by the packages owning the tables.
+ */
+
+package database
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	// Register the "pgx" and "sqlite" database/sql drivers
+	_ "github.com/jackc/pgx/v5/stdlib"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	_ "modernc.org/sqlite"
+)
+
+// migrationLock is the advisory lock serialising migrations of replicas
+// starting at the same time
+const migrationLock = 7316504
+
+// DB is a connection pool with the dialect of its driver. Queries are
+// written with PostgreSQL's $n placeholders and passed through Rebind.
+type DB struct {
+	*sql.DB
+	// Driver is the configured driver, which names the dialect
+	Driver string
+}
+
+// Open connects to the configured database and checks it is reachable.
+// SQLite databases are opened with foreign keys enforced, write-ahead
+// logging, and a busy timeout so concurrent writers wait for each other.
+func Open(ctx context.Context, cfg config.DatabaseConfig) (*DB, error) {
+	var driver, dsn string
+	switch cfg.Driver {
+	case "postgres":
+		driver, dsn = "pgx", cfg.DSN
+	case "sqlite":
+		driver, dsn = "sqlite", sqliteDSN(cfg.DSN)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %s", cfg.Driver)
+	}
+
+	pool, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open database: %w", err)
+	}
+	pool.SetMaxOpenConns(cfg.MaxOpenConns)
+	pool.SetMaxIdleConns(cfg.MaxIdleConns)
+	pool.SetConnMaxLifetime(time.Duration(cfg.ConnMaxLifetime) * time.Second)
+
+	if err := pool.PingContext(ctx); err != nil {
+		pool.Close()
+		return nil, fmt.Errorf("failed to connect to database: %w", err)
+	}
+	return &DB{DB: pool, Driver: cfg.Driver}, nil
+}
+
+// sqliteDSN adds the connection pragmas to the path of a SQLite database
+func sqliteDSN(path string) string {
+	sep := "?"
+	if strings.Contains(path, "?") {
+		sep = "&"
+	}
+	return "file:" + strings.TrimPrefix(path, "file:") + sep +
+		"_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)&_time_format=sqlite"
+}
+
+// placeholder matches the $n placeholders of a query
+var placeholder = regexp.MustCompile(`\$(\d+)`)
+
+// Rebind converts the placeholders of query to the dialect of db. SQLite
+// takes ?n for numbered parameters.
+func (db *DB) Rebind(query string) string {
+	if db.Driver != "sqlite" {
+		return query
+	}
+	return placeholder.ReplaceAllString(query, "?$1")
+}
+
+// Migration is one schema change
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Load reads the migrations in dir of fsys. Files are named
+// "<version>_<name>.sql" and applied in version order.
+func Load(fsys fs.FS, dir string) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read migrations: %w", err)
+	}
+
+	var migrations []Migration
+	seen := make(map[int]bool)
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".sql") {
+			continue
+		}
+		prefix, rest, ok := strings.Cut(strings.TrimSuffix(name, ".sql"), "_")
+		version, err := strconv.Atoi(prefix)
+		if !ok || err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s: name must be <version>_<name>.sql", name)
+		}
+		if seen[version] {
+			return nil, fmt.Errorf("migration %s: duplicate version %d", name, version)
+		}
+		seen[version] = true
+
+		content, err := fs.ReadFile(fsys, path.Join(dir, name))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read migration %s: %w", name, err)
+		}
+		migrations = append(migrations, Migration{Version: version, Name: rest, SQL: string(content)})
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// Migrate applies the migrations of component not yet recorded in
+// schema_migrations. All of them run in one transaction, so a failed
+// migration leaves the schema as it was.
+func Migrate(ctx context.Context, db *DB, component string, migrations []Migration) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start migration: %w", err)
+	}
+	defer tx.Rollback()
+
+	// A SQLite database has a single writer, which the transaction holds
+	// once it creates the table
+	if db.Driver == "postgres" {
+		if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, migrationLock); err != nil {
+			return fmt.Errorf("failed to lock migrations: %w", err)
+		}
+	}
+	if _, err := tx.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		component  TEXT      NOT NULL,
+		version    INTEGER   NOT NULL,
+		name       TEXT      NOT NULL,
+		applied_at TIMESTAMP NOT NULL,
+		PRIMARY KEY (component, version)
+	)`); err != nil {
+		return fmt.Errorf("failed to create schema_migrations: %w", err)
+	}
+
+	var current int
+	if err := tx.QueryRowContext(ctx, db.Rebind(
+		`SELECT COALESCE(MAX(version), 0) FROM schema_migrations WHERE component = $1`), component).Scan(&current); err != nil {
+		return fmt.Errorf("failed to read schema version: %w", err)
+	}
+
+	for _, migration := range migrations {
+		if migration.Version <= current {
+			continue
+		}
+		if _, err := tx.ExecContext(ctx, migration.SQL); err != nil {
+			return fmt.Errorf("migration %s %d_%s failed: %w", component, migration.Version, migration.Name, err)
+		}
+		if _, err := tx.ExecContext(ctx, db.Rebind(
+			`INSERT INTO schema_migrations (component, version, name, applied_at) VALUES ($1, $2, $3, $4)`),
+			component, migration.Version, migration.Name, time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to record migration %s %d: %w", component, migration.Version, err)
+		}
+	}
+	return tx.Commit()
+}
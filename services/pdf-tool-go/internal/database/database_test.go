@@ -0,0 +1,47 @@
+package database
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoad(t *testing.T) {
+	t.Run("Version Order", func(t *testing.T) {
+		fsys := fstest.MapFS{
+			"m/0002_add_index.sql":    {Data: []byte("CREATE INDEX a ON t (x);")},
+			"m/0001_create_table.sql": {Data: []byte("CREATE TABLE t (x INTEGER);")},
+			"m/README.md":             {Data: []byte("not a migration")},
+		}
+		migrations, err := Load(fsys, "m")
+		require.NoError(t, err)
+		require.Len(t, migrations, 2)
+		assert.Equal(t, 1, migrations[0].Version)
+		assert.Equal(t, "create_table", migrations[0].Name)
+		assert.Equal(t, 2, migrations[1].Version)
+		assert.Equal(t, "CREATE INDEX a ON t (x);", migrations[1].SQL)
+	})
+
+	t.Run("Rejects Bad Names", func(t *testing.T) {
+		for _, name := range []string{"m/create_table.sql", "m/0_create_table.sql"} {
+			_, err := Load(fstest.MapFS{name: {Data: []byte("")}}, "m")
+			assert.Error(t, err, name)
+		}
+	})
+
+	t.Run("Rejects Duplicate Versions", func(t *testing.T) {
+		_, err := Load(fstest.MapFS{
+			"m/0001_a.sql": {Data: []byte("")},
+			"m/1_b.sql":    {Data: []byte("")},
+		}, "m")
+		assert.Error(t, err)
+	})
+}
+
+func TestRebind(t *testing.T) {
+	query := `SELECT id FROM t WHERE a = $1 AND (b = $2 OR c = $1) AND d = $10`
+	assert.Equal(t, query, (&DB{Driver: "postgres"}).Rebind(query))
+	assert.Equal(t, `SELECT id FROM t WHERE a = ?1 AND (b = ?2 OR c = ?1) AND d = ?10`, (&DB{Driver: "sqlite"}).Rebind(query))
+}
@@ -0,0 +1,337 @@
+/**
+ * Result Delivery
+ *
+ * Sends the results of batch jobs to recipients named at submission. Email
+ * goes through a configured SMTP relay: results up to the attachment limit
+ * are attached, larger ones are sent as share links.
+ */
+
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"path"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/share"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+)
+
+// Attachment is a result attached to an email
+type Attachment struct {
+	Name string
+	Size int64
+}
+
+// Link is a result sent as a share link
+type Link struct {
+	Name      string
+	Size      int64
+	URL       string
+	ExpiresAt time.Time
+}
+
+// Message is the data the subject and body templates are executed with
+type Message struct {
+	JobID       string
+	Tenant      string
+	Attachments []Attachment
+	Links       []Link
+}
+
+// Mailer emails the results of batch jobs
+type Mailer struct {
+	cfg     config.EmailConfig
+	from    *mail.Address
+	subject *template.Template
+	body    *template.Template
+	results *service.ResultService
+	links   *share.Links
+	// send hands a message to the SMTP relay; replaced in tests
+	send func(to []string, msg []byte) error
+}
+
+// NewMailer creates a mailer sending results through the configured relay,
+// with results too large to attach sent as links minted by links
+func NewMailer(cfg config.EmailConfig, results *service.ResultService, links *share.Links) (*Mailer, error) {
+	from, err := mail.ParseAddress(cfg.From)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email.from: %w", err)
+	}
+	subject, err := template.New("subject").Parse(cfg.Subject)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email.subject template: %w", err)
+	}
+	body, err := template.New("body").Parse(cfg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("invalid email.body template: %w", err)
+	}
+
+	m := &Mailer{
+		cfg:     cfg,
+		from:    from,
+		subject: subject,
+		body:    body,
+		results: results,
+		links:   links,
+	}
+	m.send = m.sendSMTP
+	return m, nil
+}
+
+// Validate checks the recipients of a delivery
+func (m *Mailer) Validate(delivery *batch.Delivery) error {
+	if len(delivery.Email) == 0 {
+		return service.NewError(service.ErrCodeInvalidInput, "delivery needs at least one email recipient", nil)
+	}
+	if len(delivery.Email) > m.cfg.MaxRecipients {
+		return service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("delivery allows at most %d email recipients", m.cfg.MaxRecipients), nil)
+	}
+	for _, recipient := range delivery.Email {
+		if _, err := mail.ParseAddress(recipient); err != nil || strings.ContainsAny(recipient, "\r\n") {
+			return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("invalid email recipient %q", recipient), nil)
+		}
+	}
+	return nil
+}
+
+// Deliver emails the results of job to its recipients. A job with several
+// results sends its archive.
+func (m *Mailer) Deliver(ctx context.Context, job *batch.Job) error {
+	if len(job.Delivery.Email) == 0 {
+		return nil
+	}
+	ids := job.Results
+	if job.Archive != "" {
+		ids = []string{job.Archive}
+	}
+
+	ctx = tenant.WithID(ctx, job.Tenant)
+	msg := Message{JobID: job.ID, Tenant: job.Tenant}
+	var files []file
+	for i, id := range ids {
+		name := fmt.Sprintf("job-%s%s", job.ID, path.Ext(id))
+		if len(ids) > 1 {
+			name = fmt.Sprintf("job-%s-%d%s", job.ID, i+1, path.Ext(id))
+		}
+		f, link, err := m.prepare(ctx, job.Tenant, id, name)
+		if err != nil {
+			return err
+		}
+		if link != nil {
+			msg.Links = append(msg.Links, *link)
+			continue
+		}
+		msg.Attachments = append(msg.Attachments, Attachment{Name: name, Size: int64(len(f.data))})
+		files = append(files, f)
+	}
+
+	data, err := m.compose(job.Delivery.Email, msg, files)
+	if err != nil {
+		return err
+	}
+	return m.send(job.Delivery.Email, data)
+}
+
+// Reply sends a plain text message to to, threaded under the message with
+// ID inReplyTo when it is set. It is marked auto-replied so that
+// autoresponders do not answer it.
+func (m *Mailer) Reply(to []string, inReplyTo, subject, body string) error {
+	var buf bytes.Buffer
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", m.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", subject))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), m.cfg.Host))
+	if inReplyTo != "" && !strings.ContainsAny(inReplyTo, "\r\n") {
+		header("In-Reply-To", inReplyTo)
+		header("References", inReplyTo)
+	}
+	header("Auto-Submitted", "auto-replied")
+	header("MIME-Version", "1.0")
+	header("Content-Type", "text/plain; charset=utf-8")
+	header("Content-Transfer-Encoding", "base64")
+	buf.WriteString("\r\n")
+	writeBase64(&buf, []byte(body))
+	return m.send(to, buf.Bytes())
+}
+
+// file is a result read for attaching
+type file struct {
+	name        string
+	contentType string
+	data        []byte
+}
+
+// prepare reads result id to attach it, or mints a share link to it when it
+// is larger than the attachment limit
+func (m *Mailer) prepare(ctx context.Context, tenantID, id, name string) (file, *Link, error) {
+	obj, result, err := m.results.Open(ctx, id)
+	if err != nil {
+		return file{}, nil, err
+	}
+	defer obj.Close()
+
+	if result.Size > m.cfg.MaxAttachmentSize {
+		token, link, err := m.links.Mint(tenantID, id, share.Options{})
+		if err != nil {
+			return file{}, nil, err
+		}
+		return file{}, &Link{Name: name, Size: result.Size, URL: m.links.URL(token), ExpiresAt: link.ExpiresAt().UTC()}, nil
+	}
+
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		return file{}, nil, fmt.Errorf("failed to read result %s: %w", id, err)
+	}
+	return file{name: name, contentType: result.ContentType, data: data}, nil, nil
+}
+
+// compose renders msg and builds a MIME message with the files attached
+func (m *Mailer) compose(to []string, msg Message, files []file) ([]byte, error) {
+	var subject, body bytes.Buffer
+	if err := m.subject.Execute(&subject, msg); err != nil {
+		return nil, fmt.Errorf("failed to render email subject: %w", err)
+	}
+	if err := m.body.Execute(&body, msg); err != nil {
+		return nil, fmt.Errorf("failed to render email body: %w", err)
+	}
+
+	var buf bytes.Buffer
+	w := multipart.NewWriter(&buf)
+	header := func(name, value string) {
+		fmt.Fprintf(&buf, "%s: %s\r\n", name, value)
+	}
+	header("From", m.from.String())
+	header("To", strings.Join(to, ", "))
+	header("Subject", mime.QEncoding.Encode("utf-8", strings.TrimSpace(subject.String())))
+	header("Date", time.Now().Format(time.RFC1123Z))
+	header("Message-ID", fmt.Sprintf("<%s@%s>", uuid.NewString(), m.cfg.Host))
+	header("MIME-Version", "1.0")
+	header("Content-Type", `multipart/mixed; boundary="`+w.Boundary()+`"`)
+	buf.WriteString("\r\n")
+
+	part, err := w.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {"text/plain; charset=utf-8"},
+		"Content-Transfer-Encoding": {"base64"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	writeBase64(part, body.Bytes())
+
+	for _, f := range files {
+		part, err := w.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {f.contentType},
+			"Content-Transfer-Encoding": {"base64"},
+			"Content-Disposition":       {mime.FormatMediaType("attachment", map[string]string{"filename": f.name})},
+		})
+		if err != nil {
+			return nil, err
+		}
+		writeBase64(part, f.data)
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeBase64 writes data base64 encoded in lines of 76 characters
+func writeBase64(w io.Writer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		io.WriteString(w, encoded[:76]+"\r\n")
+		encoded = encoded[76:]
+	}
+	io.WriteString(w, encoded+"\r\n")
+}
+
+// sendSMTP hands msg for to to the configured relay. PLAIN authentication
+// is only used over TLS.
+func (m *Mailer) sendSMTP(to []string, msg []byte) error {
+	timeout := time.Duration(m.cfg.Timeout) * time.Second
+	addr := net.JoinHostPort(m.cfg.Host, strconv.Itoa(m.cfg.Port))
+	dialer := &net.Dialer{Timeout: timeout}
+	tlsConfig := &tls.Config{ServerName: m.cfg.Host, MinVersion: tls.VersionTLS12}
+
+	var (
+		conn net.Conn
+		err  error
+	)
+	if m.cfg.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP relay: %w", err)
+	}
+	conn.SetDeadline(time.Now().Add(timeout))
+
+	client, err := smtp.NewClient(conn, m.cfg.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to start SMTP session: %w", err)
+	}
+	defer client.Close()
+
+	if !m.cfg.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(tlsConfig); err != nil {
+				return fmt.Errorf("failed to start TLS with SMTP relay: %w", err)
+			}
+		}
+	}
+	if m.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, m.cfg.Host)); err != nil {
+			return fmt.Errorf("failed to authenticate with SMTP relay: %w", err)
+		}
+	}
+
+	if err := client.Mail(m.from.Address); err != nil {
+		return fmt.Errorf("SMTP relay rejected sender: %w", err)
+	}
+	for _, recipient := range to {
+		address, err := mail.ParseAddress(recipient)
+		if err != nil {
+			return fmt.Errorf("invalid email recipient %q: %w", recipient, err)
+		}
+		if err := client.Rcpt(address.Address); err != nil {
+			return fmt.Errorf("SMTP relay rejected recipient %s: %w", address.Address, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("SMTP relay rejected message: %w", err)
+	}
+	if _, err := w.Write(msg); err != nil {
+		return fmt.Errorf("failed to send message: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("SMTP relay rejected message: %w", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,147 @@
+package delivery
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/multipart"
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/share"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMailer(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	results := service.NewResultService(store, nil, logger.New("error", "text"))
+	links := share.New(
+		config.ShareConfig{Secret: strings.Repeat("s", 32), DefaultTTL: 3600, MaxTTL: 3600, PublicURL: "https://pdf.example.com/api/v1/"},
+		config.LockoutConfig{MaxAttempts: 4, BaseDelay: 1, MaxDelay: 3, Lockout: 60, Window: 120},
+		share.NewMemoryCounter())
+
+	cfg := config.EmailConfig{
+		Host:              "smtp.example.com",
+		Port:              587,
+		From:              "PDF Tool <noreply@example.com>",
+		MaxRecipients:     2,
+		MaxAttachmentSize: 8,
+		Subject:           "Job {{.JobID}} for {{.Tenant}}",
+		Body:              "{{range .Attachments}}attached {{.Name}}\n{{end}}{{range .Links}}link {{.URL}}\n{{end}}",
+		Timeout:           5,
+	}
+	m, err := NewMailer(cfg, results, links)
+	require.NoError(t, err)
+
+	var sent []string
+	var message []byte
+	m.send = func(to []string, msg []byte) error {
+		sent, message = to, msg
+		return nil
+	}
+
+	ctx := tenant.WithID(context.Background(), "acme")
+	small, err := results.Save(ctx, []byte("%PDF-1"), "pdf")
+	require.NoError(t, err)
+	large, err := results.Save(ctx, []byte("%PDF-1.7 large"), "pdf")
+	require.NoError(t, err)
+
+	// parts returns the subject of the last message and its MIME parts
+	parts := func() (string, []*multipart.Part, [][]byte) {
+		msg, err := mail.ReadMessage(bytes.NewReader(message))
+		require.NoError(t, err)
+		subject, err := new(mime.WordDecoder).DecodeHeader(msg.Header.Get("Subject"))
+		require.NoError(t, err)
+		_, params, err := mime.ParseMediaType(msg.Header.Get("Content-Type"))
+		require.NoError(t, err)
+
+		var (
+			found    []*multipart.Part
+			contents [][]byte
+		)
+		r := multipart.NewReader(msg.Body, params["boundary"])
+		for {
+			part, err := r.NextRawPart()
+			if err == io.EOF {
+				break
+			}
+			require.NoError(t, err)
+			data, err := io.ReadAll(part)
+			require.NoError(t, err)
+			found = append(found, part)
+			contents = append(contents, data)
+		}
+		return subject, found, contents
+	}
+
+	t.Run("Recipients Are Validated", func(t *testing.T) {
+		assert.NoError(t, m.Validate(&batch.Delivery{Email: []string{"a@example.com", "B <b@example.com>"}}))
+		for _, email := range [][]string{nil, {"a@example.com", "b@example.com", "c@example.com"}, {"not an address"}} {
+			err := m.Validate(&batch.Delivery{Email: email})
+			assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err), email)
+		}
+	})
+
+	t.Run("Small Results Are Attached", func(t *testing.T) {
+		job := &batch.Job{ID: "job-1", Tenant: "acme", Results: []string{small.ID},
+			Delivery: &batch.Delivery{Email: []string{"a@example.com"}}}
+		require.NoError(t, m.Deliver(context.Background(), job))
+		assert.Equal(t, []string{"a@example.com"}, sent)
+
+		subject, found, contents := parts()
+		assert.Equal(t, "Job job-1 for acme", subject)
+		require.Len(t, found, 2)
+		assert.Contains(t, decode(t, contents[0]), "attached job-job-1.pdf")
+		assert.Contains(t, found[1].Header.Get("Content-Disposition"), `filename=job-job-1.pdf`)
+		assert.Equal(t, "%PDF-1", decode(t, contents[1]))
+	})
+
+	t.Run("Large Results Are Linked", func(t *testing.T) {
+		job := &batch.Job{ID: "job-2", Tenant: "acme", Results: []string{large.ID},
+			Delivery: &batch.Delivery{Email: []string{"a@example.com"}}}
+		require.NoError(t, m.Deliver(context.Background(), job))
+
+		_, found, contents := parts()
+		require.Len(t, found, 1)
+		body := decode(t, contents[0])
+		require.Contains(t, body, "link https://pdf.example.com/api/v1/share/")
+
+		token := strings.TrimSpace(strings.TrimPrefix(body, "link https://pdf.example.com/api/v1/share/"))
+		link, err := links.Resolve(context.Background(), token, "", false)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", link.Tenant)
+		assert.Equal(t, large.ID, link.Result)
+	})
+
+	t.Run("Replies Are Threaded", func(t *testing.T) {
+		require.NoError(t, m.Reply([]string{"a@example.com"}, "<orig@example.com>", "Re: Invoices", "No PDF attachments found"))
+		assert.Equal(t, []string{"a@example.com"}, sent)
+
+		msg, err := mail.ReadMessage(bytes.NewReader(message))
+		require.NoError(t, err)
+		assert.Equal(t, "<orig@example.com>", msg.Header.Get("In-Reply-To"))
+		assert.Equal(t, "auto-replied", msg.Header.Get("Auto-Submitted"))
+		body, err := io.ReadAll(msg.Body)
+		require.NoError(t, err)
+		assert.Equal(t, "No PDF attachments found", decode(t, body))
+	})
+}
+
+// decode returns the content of a base64 encoded part
+func decode(t *testing.T, data []byte) string {
+	t.Helper()
+	decoded, err := io.ReadAll(base64.NewDecoder(base64.StdEncoding, bytes.NewReader(data)))
+	require.NoError(t, err)
+	return string(decoded)
+}
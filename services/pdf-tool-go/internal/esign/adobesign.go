@@ -0,0 +1,144 @@
+package esign
+
+import (
+	"bytes"
+	"context"
+	"mime"
+	"mime/multipart"
+	"net/http"
+	"net/textproto"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// signingURLAttempts bounds how often signing URLs are asked for while
+// Adobe Sign prepares a new agreement
+const signingURLAttempts = 10
+
+// form is a multipart request body
+type form struct {
+	buf         bytes.Buffer
+	contentType string
+}
+
+// adobeSign creates agreements through the REST API v6. Adobe Sign also
+// emails the signers, who may sign through either.
+type adobeSign struct {
+	*api
+	cfg  config.AdobeSignConfig
+	base string
+	// poll is the wait between requests for signing URLs
+	poll time.Duration
+}
+
+func newAdobeSign(cfg config.AdobeSignConfig, a *api) *adobeSign {
+	return &adobeSign{api: a, cfg: cfg, base: strings.TrimSuffix(cfg.BaseURL, "/") + "/api/rest/v6", poll: time.Second}
+}
+
+func (a *adobeSign) send(ctx context.Context, name string, data []byte, req Request) (*Envelope, error) {
+	upload, err := documentForm(name, data)
+	if err != nil {
+		return nil, err
+	}
+	var transient struct {
+		ID string `json:"transientDocumentId"`
+	}
+	if err := a.call(ctx, http.MethodPost, a.base+"/transientDocuments", a.cfg.IntegrationKey, upload, &transient); err != nil {
+		return nil, err
+	}
+
+	participants := make([]map[string]interface{}, len(req.Signers))
+	for i, signer := range req.Signers {
+		participants[i] = map[string]interface{}{
+			"name":        signer.Name,
+			"memberInfos": []map[string]string{{"email": signer.Email}},
+			"order":       1,
+			"role":        "SIGNER",
+		}
+	}
+	agreement := map[string]interface{}{
+		"name":                req.Subject,
+		"message":             req.Message,
+		"fileInfos":           []map[string]string{{"transientDocumentId": transient.ID}},
+		"participantSetsInfo": participants,
+		"signatureType":       "ESIGN",
+		"state":               "IN_PROCESS",
+	}
+	if req.ReturnURL != "" {
+		agreement["postSignOption"] = map[string]interface{}{"redirectUrl": req.ReturnURL}
+	}
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := a.call(ctx, http.MethodPost, a.base+"/agreements", a.cfg.IntegrationKey, agreement, &created); err != nil {
+		return nil, err
+	}
+
+	urls, err := a.signingURLs(ctx, created.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &Envelope{ID: created.ID, SigningURLs: urls}, nil
+}
+
+// signingURLs returns the signing URLs of an agreement, waiting for Adobe
+// Sign to expose them
+func (a *adobeSign) signingURLs(ctx context.Context, id string) ([]SigningURL, error) {
+	var sets struct {
+		SigningURLSetInfos []struct {
+			SigningURLs []struct {
+				Email    string `json:"email"`
+				ESignURL string `json:"esignUrl"`
+			} `json:"signingUrls"`
+		} `json:"signingUrlSetInfos"`
+	}
+	endpoint := a.base + "/agreements/" + url.PathEscape(id) + "/signingUrls"
+	for attempt := 1; ; attempt++ {
+		err := a.call(ctx, http.MethodGet, endpoint, a.cfg.IntegrationKey, nil, &sets)
+		if err == nil {
+			break
+		}
+		if service.CodeOf(err) != service.ErrCodeNotFound || attempt == signingURLAttempts {
+			return nil, err
+		}
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(a.poll):
+		}
+	}
+
+	var urls []SigningURL
+	for _, set := range sets.SigningURLSetInfos {
+		for _, u := range set.SigningURLs {
+			urls = append(urls, SigningURL{Email: u.Email, URL: u.ESignURL})
+		}
+	}
+	return urls, nil
+}
+
+// documentForm builds the upload of a transient document
+func documentForm(name string, data []byte) (*form, error) {
+	f := &form{}
+	w := multipart.NewWriter(&f.buf)
+	if err := w.WriteField("File-Name", name); err != nil {
+		return nil, err
+	}
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Disposition", mime.FormatMediaType("form-data", map[string]string{"name": "File", "filename": name}))
+	header.Set("Content-Type", "application/pdf")
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return nil, err
+	}
+	part.Write(data)
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+	f.contentType = w.FormDataContentType()
+	return f, nil
+}
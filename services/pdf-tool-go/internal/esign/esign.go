@@ -0,0 +1,202 @@
+/**
+ * Signing Hand-off
+ *
+ * Hands prepared documents to DocuSign or Adobe Sign, which collect the
+ * signatures. Each hand-off creates an envelope (an agreement on Adobe
+ * Sign) for its signers, who sign in parallel through the signing URLs
+ * returned for them.
+ */
+
+package esign
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// maxSigners bounds the signers of an envelope
+const maxSigners = 20
+
+// Signer is a recipient asked to sign
+type Signer struct {
+	Name  string `json:"name"`
+	Email string `json:"email"`
+}
+
+// Request describes an envelope
+type Request struct {
+	Signers []Signer
+	// Subject and Message are shown to signers; Subject defaults to one
+	// naming the document
+	Subject string
+	Message string
+	// ReturnURL is where signers land after signing; it defaults to the
+	// configured one
+	ReturnURL string
+}
+
+// SigningURL is where a signer signs
+type SigningURL struct {
+	Email string `json:"email"`
+	URL   string `json:"url"`
+}
+
+// Envelope is a created envelope
+type Envelope struct {
+	Provider    string       `json:"provider"`
+	ID          string       `json:"envelope_id"`
+	SigningURLs []SigningURL `json:"signing_urls"`
+}
+
+// provider creates envelopes in a signing service
+type provider interface {
+	send(ctx context.Context, name string, data []byte, req Request) (*Envelope, error)
+}
+
+// Client hands documents to the enabled providers
+type Client struct {
+	providers map[string]provider
+	returnURL string
+}
+
+// New creates a client for the configured providers
+func New(cfg config.ESignConfig) (*Client, error) {
+	client := &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	c := &Client{providers: make(map[string]provider), returnURL: cfg.ReturnURL}
+	if cfg.DocuSign.BaseURL != "" {
+		d, err := newDocuSign(cfg.DocuSign, &api{client: client, name: "docusign"})
+		if err != nil {
+			return nil, err
+		}
+		c.providers["docusign"] = d
+	}
+	if cfg.AdobeSign.BaseURL != "" {
+		c.providers["adobe_sign"] = newAdobeSign(cfg.AdobeSign, &api{client: client, name: "adobe_sign"})
+	}
+	return c, nil
+}
+
+// Send creates an envelope in the named provider for the PDF data, which
+// is shown to signers as name
+func (c *Client) Send(ctx context.Context, providerName, name string, data []byte, req Request) (*Envelope, error) {
+	p, ok := c.providers[providerName]
+	if !ok {
+		return nil, service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("signing provider %q is not enabled", providerName), nil)
+	}
+	if len(req.Signers) == 0 || len(req.Signers) > maxSigners {
+		return nil, service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("between 1 and %d signers are required", maxSigners), nil)
+	}
+	for _, signer := range req.Signers {
+		if signer.Name == "" {
+			return nil, service.NewError(service.ErrCodeInvalidInput, "signers need a name", nil)
+		}
+		if _, err := mail.ParseAddress(signer.Email); err != nil {
+			return nil, service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("invalid signer email %q", signer.Email), err)
+		}
+	}
+	if req.Subject == "" {
+		req.Subject = "Please sign " + name
+	}
+	if req.ReturnURL == "" {
+		req.ReturnURL = c.returnURL
+	}
+	if req.ReturnURL != "" {
+		if u, err := url.Parse(req.ReturnURL); err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return nil, service.NewError(service.ErrCodeInvalidInput, "return_url must be an http(s) URL", nil)
+		}
+	}
+
+	envelope, err := p.send(ctx, name, data, req)
+	if err != nil {
+		return nil, err
+	}
+	envelope.Provider = providerName
+	return envelope, nil
+}
+
+// api sends authorized calls to a provider
+type api struct {
+	client *http.Client
+	name   string
+}
+
+// call sends a JSON body, or a prepared one, to a provider and decodes
+// its JSON response into out. Failures are mapped onto service errors.
+func (a *api) call(ctx context.Context, method, endpoint, token string, body interface{}, out interface{}) error {
+	var (
+		reader      io.Reader
+		contentType = "application/json"
+	)
+	switch b := body.(type) {
+	case nil:
+	case *form:
+		reader, contentType = &b.buf, b.contentType
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, endpoint, reader)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Accept", "application/json")
+	if reader != nil {
+		req.Header.Set("Content-Type", contentType)
+	}
+
+	resp, err := a.client.Do(req)
+	if err != nil {
+		return a.unavailable(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return a.failure(resp)
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("invalid %s response: %w", a.name, err)
+	}
+	return nil
+}
+
+// failure maps a failed call onto a service error
+func (a *api) failure(resp *http.Response) error {
+	text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	text = bytes.TrimSpace(text)
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		return service.NewError(service.ErrCodeNotFound, fmt.Sprintf("%s: not found: %s", a.name, text), nil)
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		// The credentials are the service's own, so this is misconfiguration
+		return service.NewError(service.ErrCodeInternal, fmt.Sprintf("%s rejected the configured credentials", a.name),
+			fmt.Errorf("status %d: %s", resp.StatusCode, text))
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500:
+		return a.unavailable(fmt.Errorf("status %d", resp.StatusCode))
+	}
+	return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("%s rejected the request: %s", a.name, text), nil)
+}
+
+// unavailable reports a provider that could not be reached or is failing
+func (a *api) unavailable(err error) error {
+	if urlErr, ok := err.(*url.Error); ok {
+		err = urlErr.Err
+	}
+	return service.NewError(service.ErrCodeBusy, fmt.Sprintf("%s is unavailable, retry later", a.name), err)
+}
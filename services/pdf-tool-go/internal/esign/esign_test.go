@@ -0,0 +1,162 @@
+package esign
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	privateKey := string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+	signers := []Signer{{Name: "Alice", Email: "alice@example.com"}, {Name: "Bob", Email: "bob@example.com"}}
+
+	// newClient points every provider at server
+	newClient := func(server *httptest.Server) *Client {
+		c, err := New(config.ESignConfig{
+			Enabled:   true,
+			Timeout:   5,
+			ReturnURL: "https://app.example.com/signed",
+			DocuSign: config.DocuSignConfig{BaseURL: server.URL + "/restapi", AuthHost: "account-d.docusign.com",
+				AccountID: "acct", IntegrationKey: "ik", UserID: "user", PrivateKey: privateKey},
+			AdobeSign: config.AdobeSignConfig{BaseURL: server.URL, IntegrationKey: "adobe-key"},
+		})
+		require.NoError(t, err)
+		c.providers["docusign"].(*docuSign).authURL = server.URL + "/oauth/token"
+		c.providers["adobe_sign"].(*adobeSign).poll = time.Millisecond
+		return c
+	}
+
+	t.Run("DocuSign Envelopes Return Recipient Views", func(t *testing.T) {
+		grants := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/oauth/token":
+				grants++
+				parts := strings.Split(r.PostFormValue("assertion"), ".")
+				require.Len(t, parts, 3)
+				signature, _ := base64.RawURLEncoding.DecodeString(parts[2])
+				digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+				assert.NoError(t, rsa.VerifyPKCS1v15(&key.PublicKey, crypto.SHA256, digest[:], signature))
+				io.WriteString(w, `{"access_token": "ds-token", "expires_in": 3600}`)
+			case "/restapi/v2.1/accounts/acct/envelopes":
+				assert.Equal(t, "Bearer ds-token", r.Header.Get("Authorization"))
+				var body struct {
+					EmailSubject string `json:"emailSubject"`
+					Documents    []struct {
+						DocumentBase64 string `json:"documentBase64"`
+					} `json:"documents"`
+					Recipients struct {
+						Signers []map[string]string `json:"signers"`
+					} `json:"recipients"`
+				}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+				assert.Equal(t, "Please sign contract.pdf", body.EmailSubject)
+				assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("%PDF-1.7")), body.Documents[0].DocumentBase64)
+				require.Len(t, body.Recipients.Signers, 2)
+				assert.Equal(t, "2", body.Recipients.Signers[1]["clientUserId"])
+				io.WriteString(w, `{"envelopeId": "env-1"}`)
+			case "/restapi/v2.1/accounts/acct/envelopes/env-1/views/recipient":
+				var view map[string]string
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&view))
+				assert.Equal(t, "https://app.example.com/signed", view["returnUrl"])
+				io.WriteString(w, `{"url": "https://sign.example/`+view["clientUserId"]+`"}`)
+			default:
+				t.Errorf("unexpected call to %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+		c := newClient(server)
+
+		for i := 0; i < 2; i++ {
+			envelope, err := c.Send(ctx, "docusign", "contract.pdf", []byte("%PDF-1.7"), Request{Signers: signers})
+			require.NoError(t, err)
+			assert.Equal(t, &Envelope{Provider: "docusign", ID: "env-1", SigningURLs: []SigningURL{
+				{Email: "alice@example.com", URL: "https://sign.example/1"},
+				{Email: "bob@example.com", URL: "https://sign.example/2"},
+			}}, envelope)
+		}
+		assert.Equal(t, 1, grants)
+	})
+
+	t.Run("Adobe Sign Agreements Wait For Signing URLs", func(t *testing.T) {
+		polls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			assert.Equal(t, "Bearer adobe-key", r.Header.Get("Authorization"))
+			switch r.URL.Path {
+			case "/api/rest/v6/transientDocuments":
+				file, header, err := r.FormFile("File")
+				require.NoError(t, err)
+				data, _ := io.ReadAll(file)
+				assert.Equal(t, "contract.pdf", header.Filename)
+				assert.Equal(t, "%PDF-1.7", string(data))
+				io.WriteString(w, `{"transientDocumentId": "td-1"}`)
+			case "/api/rest/v6/agreements":
+				var agreement map[string]interface{}
+				require.NoError(t, json.NewDecoder(r.Body).Decode(&agreement))
+				assert.Equal(t, "Contract", agreement["name"])
+				assert.Len(t, agreement["participantSetsInfo"], 2)
+				io.WriteString(w, `{"id": "ag-1"}`)
+			case "/api/rest/v6/agreements/ag-1/signingUrls":
+				if polls++; polls < 3 {
+					http.Error(w, `{"code": "AGREEMENT_NOT_EXPOSED"}`, http.StatusNotFound)
+					return
+				}
+				io.WriteString(w, `{"signingUrlSetInfos": [
+					{"signingUrls": [{"email": "alice@example.com", "esignUrl": "https://adobe.example/a"}]},
+					{"signingUrls": [{"email": "bob@example.com", "esignUrl": "https://adobe.example/b"}]}]}`)
+			default:
+				t.Errorf("unexpected call to %s", r.URL.Path)
+			}
+		}))
+		defer server.Close()
+
+		envelope, err := newClient(server).Send(ctx, "adobe_sign", "contract.pdf", []byte("%PDF-1.7"), Request{Signers: signers, Subject: "Contract"})
+		require.NoError(t, err)
+		assert.Equal(t, "ag-1", envelope.ID)
+		assert.Equal(t, []SigningURL{
+			{Email: "alice@example.com", URL: "https://adobe.example/a"},
+			{Email: "bob@example.com", URL: "https://adobe.example/b"},
+		}, envelope.SigningURLs)
+		assert.Equal(t, 3, polls)
+	})
+
+	t.Run("Invalid Requests Are Rejected", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			t.Errorf("unexpected call to %s", r.URL.Path)
+		}))
+		defer server.Close()
+		c := newClient(server)
+
+		for _, req := range []Request{
+			{},
+			{Signers: []Signer{{Name: "Alice", Email: "not an address"}}},
+			{Signers: []Signer{{Email: "alice@example.com"}}},
+			{Signers: signers, ReturnURL: "javascript:alert(1)"},
+		} {
+			_, err := c.Send(ctx, "docusign", "contract.pdf", []byte("%PDF-1.7"), req)
+			assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		}
+		_, err := c.Send(ctx, "hellosign", "contract.pdf", []byte("%PDF-1.7"), Request{Signers: signers})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+}
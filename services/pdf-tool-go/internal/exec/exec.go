@@ -0,0 +1,373 @@
+/**
+ * External Tool Execution
+ *
+ * Runs allow-listed external binaries (ghostscript, tesseract, libreoffice)
+ * in isolated working directories with resource limits, timeouts, an
+ * optional nsjail sandbox and a circuit breaker per tool.
+ */
+
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/breaker"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("exec")
+
+var (
+	// ErrToolNotAllowed is returned for binaries missing from the allow-list
+	ErrToolNotAllowed = errors.New("tool is not allow-listed")
+	// ErrToolNotFound is returned when an allow-listed binary is not installed
+	ErrToolNotFound = errors.New("tool binary not found")
+)
+
+// waitDelay bounds how long Run waits for output pipes after killing a process
+const waitDelay = 2 * time.Second
+
+// Command describes a single tool invocation
+type Command struct {
+	// Tool is the allow-list name of the binary, e.g. "gs"
+	Tool string
+	Args []string
+	// Stdin is optional process input
+	Stdin io.Reader
+	// Timeout overrides the configured default timeout
+	Timeout time.Duration
+}
+
+// Result holds the outcome of a completed invocation
+type Result struct {
+	Stdout   []byte
+	Stderr   []byte
+	Duration time.Duration
+}
+
+// ExitError is returned when a tool exits with a non-zero status
+type ExitError struct {
+	Tool     string
+	ExitCode int
+	Stderr   string
+}
+
+func (e *ExitError) Error() string {
+	return fmt.Sprintf("%s exited with status %d: %s", e.Tool, e.ExitCode, e.Stderr)
+}
+
+// Runner executes allow-listed tools
+type Runner struct {
+	cfg      config.SandboxConfig
+	log      logger.Logger
+	prlimit  string
+	breakers *breaker.Set
+
+	mu sync.Mutex
+	// workspaces are the directories of open workspaces
+	workspaces map[string]bool
+}
+
+// NewRunner creates a runner. Resource limits are applied through nsjail
+// when enabled, otherwise through prlimit(1) when it is installed. Each
+// tool has its own circuit breaker, so a hung converter only fails calls
+// to itself.
+func NewRunner(cfg config.SandboxConfig, breakers config.BreakerConfig, log logger.Logger) *Runner {
+	r := &Runner{cfg: cfg, log: log, breakers: breaker.New(breakers, log, isToolFailure), workspaces: make(map[string]bool)}
+
+	if !cfg.NSJail.Enabled {
+		if path, err := osexec.LookPath("prlimit"); err == nil {
+			r.prlimit = path
+		} else {
+			log.Warn("prlimit not found, external tools will run without resource limits")
+		}
+	}
+
+	return r
+}
+
+// Available reports whether the tool is allow-listed and installed
+func (r *Runner) Available(tool string) error {
+	_, err := r.resolve(tool)
+	return err
+}
+
+// Run executes cmd inside ws and waits for it to finish. The process group is
+// killed when ctx is done or the timeout expires. While the tool's breaker
+// is open Run fails with an error wrapping breaker.ErrOpen.
+func (r *Runner) Run(ctx context.Context, ws *Workspace, cmd Command) (result *Result, err error) {
+	ctx, span := tracer.Start(ctx, "exec."+cmd.Tool, trace.WithAttributes(
+		attribute.String("exec.tool", cmd.Tool),
+		attribute.Bool("exec.nsjail", r.cfg.NSJail.Enabled),
+	))
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	binary, err := r.resolve(cmd.Tool)
+	if err != nil {
+		return nil, err
+	}
+
+	err = r.breakers.Do(cmd.Tool, func() error {
+		result, err = r.run(ctx, ws, cmd, binary, span)
+		return err
+	})
+	return result, err
+}
+
+// isToolFailure reports whether err means the tool itself is failing, as
+// opposed to rejecting its input or the caller giving up
+func isToolFailure(err error) bool {
+	var exitErr *ExitError
+	return !errors.As(err, &exitErr) && !errors.Is(err, context.Canceled)
+}
+
+// run executes a resolved tool binary
+func (r *Runner) run(ctx context.Context, ws *Workspace, cmd Command, binary string, span trace.Span) (*Result, error) {
+	timeout := cmd.Timeout
+	if timeout <= 0 {
+		timeout = time.Duration(r.cfg.Timeout) * time.Second
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+
+	argv := r.wrap(binary, cmd.Args, ws.Dir)
+	proc := osexec.CommandContext(ctx, argv[0], argv[1:]...)
+	proc.Dir = ws.Dir
+	proc.Env = []string{
+		"PATH=/usr/local/bin:/usr/bin:/bin",
+		"HOME=" + ws.Dir,
+		"TMPDIR=" + ws.Dir,
+		"LANG=C.UTF-8",
+	}
+	// Tools that understand W3C trace context can join the request's trace
+	if traceparent := telemetry.InjectTraceContext(ctx)["traceparent"]; traceparent != "" {
+		proc.Env = append(proc.Env, "TRACEPARENT="+traceparent)
+	}
+	proc.Stdin = cmd.Stdin
+	stdout := &cappedBuffer{limit: r.cfg.MaxOutputBytes}
+	stderr := &cappedBuffer{limit: r.cfg.MaxOutputBytes}
+	proc.Stdout = stdout
+	proc.Stderr = stderr
+	proc.WaitDelay = waitDelay
+	isolate(proc)
+
+	start := time.Now()
+	err := proc.Run()
+	result := &Result{
+		Stdout:   stdout.Bytes(),
+		Stderr:   stderr.Bytes(),
+		Duration: time.Since(start),
+	}
+	span.SetAttributes(attribute.Int64("exec.duration_ms", result.Duration.Milliseconds()))
+
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return result, ctxErr
+	}
+
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) {
+		span.SetAttributes(attribute.Int("exec.exit_code", exitErr.ExitCode()))
+		return result, &ExitError{
+			Tool:     cmd.Tool,
+			ExitCode: exitErr.ExitCode(),
+			Stderr:   string(bytes.TrimSpace(result.Stderr)),
+		}
+	}
+	if err != nil {
+		return result, fmt.Errorf("failed to run %s: %w", cmd.Tool, err)
+	}
+
+	r.log.Debug("External tool completed", "tool", cmd.Tool, "duration", result.Duration.String())
+
+	return result, nil
+}
+
+// resolve maps an allow-list name to an executable path
+func (r *Runner) resolve(tool string) (string, error) {
+	path, ok := r.cfg.Tools[tool]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrToolNotAllowed, tool)
+	}
+	if path == "" {
+		path = tool
+	}
+
+	resolved, err := osexec.LookPath(path)
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrToolNotFound, tool)
+	}
+	return resolved, nil
+}
+
+// wrap builds the final argv, prefixing the sandbox or prlimit wrapper
+func (r *Runner) wrap(binary string, args []string, dir string) []string {
+	if r.cfg.NSJail.Enabled {
+		argv := []string{r.cfg.NSJail.Path, "--mode", "o", "--quiet", "--cwd", dir, "--bindmount", dir}
+		if r.cfg.NSJail.ConfigFile != "" {
+			argv = append(argv, "--config", r.cfg.NSJail.ConfigFile)
+		}
+		if r.cfg.CPUSeconds > 0 {
+			argv = append(argv, "--rlimit_cpu", strconv.Itoa(r.cfg.CPUSeconds))
+		}
+		if r.cfg.MemoryBytes > 0 {
+			argv = append(argv, "--rlimit_as", strconv.FormatInt(r.cfg.MemoryBytes>>20, 10))
+		}
+		if r.cfg.FileSizeBytes > 0 {
+			argv = append(argv, "--rlimit_fsize", strconv.FormatInt(r.cfg.FileSizeBytes>>20, 10))
+		}
+		if r.cfg.OpenFiles > 0 {
+			argv = append(argv, "--rlimit_nofile", strconv.Itoa(r.cfg.OpenFiles))
+		}
+		argv = append(argv, "--", binary)
+		return append(argv, args...)
+	}
+
+	if r.prlimit != "" {
+		argv := []string{r.prlimit}
+		if r.cfg.CPUSeconds > 0 {
+			argv = append(argv, "--cpu="+strconv.Itoa(r.cfg.CPUSeconds))
+		}
+		if r.cfg.MemoryBytes > 0 {
+			argv = append(argv, "--as="+strconv.FormatInt(r.cfg.MemoryBytes, 10))
+		}
+		if r.cfg.FileSizeBytes > 0 {
+			argv = append(argv, "--fsize="+strconv.FormatInt(r.cfg.FileSizeBytes, 10))
+		}
+		if r.cfg.OpenFiles > 0 {
+			argv = append(argv, "--nofile="+strconv.Itoa(r.cfg.OpenFiles))
+		}
+		if len(argv) > 1 {
+			argv = append(argv, "--", binary)
+			return append(argv, args...)
+		}
+	}
+
+	return append([]string{binary}, args...)
+}
+
+// Workspace is an isolated working directory for one tool invocation
+type Workspace struct {
+	Dir string
+	// release unregisters the workspace from its runner
+	release func()
+}
+
+// NewWorkspace creates a fresh working directory under the configured root
+func (r *Runner) NewWorkspace() (*Workspace, error) {
+	if err := os.MkdirAll(r.cfg.WorkDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create sandbox root: %w", err)
+	}
+
+	dir, err := os.MkdirTemp(r.cfg.WorkDir, "exec-*")
+	if err != nil {
+		return nil, fmt.Errorf("failed to create workspace: %w", err)
+	}
+
+	key := filepath.Clean(dir)
+	r.mu.Lock()
+	r.workspaces[key] = true
+	r.mu.Unlock()
+	release := func() {
+		r.mu.Lock()
+		delete(r.workspaces, key)
+		r.mu.Unlock()
+	}
+	return &Workspace{Dir: dir, release: release}, nil
+}
+
+// WorkDir returns the root directory workspaces are created in
+func (r *Runner) WorkDir() string {
+	return r.cfg.WorkDir
+}
+
+// InUse reports whether path is the directory of an open workspace, so
+// temp directory sweeps leave the files of running tools alone
+func (r *Runner) InUse(path string) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.workspaces[filepath.Clean(path)]
+}
+
+// Path returns the absolute path of a file inside the workspace
+func (w *Workspace) Path(name string) string {
+	return filepath.Join(w.Dir, filepath.Base(name))
+}
+
+// WriteFile stages an input file in the workspace and returns its path
+func (w *Workspace) WriteFile(name string, data []byte) (string, error) {
+	path := w.Path(name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// CopyFile stages an input file read from r in the workspace, without
+// holding it in memory, and returns its path
+func (w *Workspace) CopyFile(name string, r io.Reader) (string, error) {
+	path := w.Path(name)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", name, err)
+	}
+	if _, err := io.Copy(f, r); err != nil {
+		f.Close()
+		return "", fmt.Errorf("failed to stage %s: %w", name, err)
+	}
+	if err := f.Close(); err != nil {
+		return "", fmt.Errorf("failed to stage %s: %w", name, err)
+	}
+	return path, nil
+}
+
+// ReadFile reads an output file from the workspace
+func (w *Workspace) ReadFile(name string) ([]byte, error) {
+	return os.ReadFile(w.Path(name))
+}
+
+// Close removes the workspace and everything in it
+func (w *Workspace) Close() error {
+	err := os.RemoveAll(w.Dir)
+	if w.release != nil {
+		w.release()
+	}
+	return err
+}
+
+// cappedBuffer keeps at most limit bytes of output (0 means unlimited)
+type cappedBuffer struct {
+	bytes.Buffer
+	limit int64
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.limit > 0 {
+		room := b.limit - int64(b.Len())
+		if room <= 0 {
+			return len(p), nil
+		}
+		if int64(len(p)) > room {
+			b.Buffer.Write(p[:room])
+			return len(p), nil
+		}
+	}
+	return b.Buffer.Write(p)
+}
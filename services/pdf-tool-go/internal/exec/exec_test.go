@@ -0,0 +1,72 @@
+package exec
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func newTestRunner(t *testing.T) *Runner {
+	cfg := config.SandboxConfig{
+		WorkDir: t.TempDir(),
+		Timeout: 5,
+		Tools: map[string]string{
+			"sh": "",
+		},
+	}
+	return NewRunner(cfg, config.BreakerConfig{}, logger.New("info", "text"))
+}
+
+func TestRunner_Run(t *testing.T) {
+	r := newTestRunner(t)
+	ws, err := r.NewWorkspace()
+	assert.NoError(t, err)
+	defer ws.Close()
+
+	t.Run("Tool Not Allowed", func(t *testing.T) {
+		_, err := r.Run(context.Background(), ws, Command{Tool: "rm", Args: []string{"-rf", "/"}})
+		assert.ErrorIs(t, err, ErrToolNotAllowed)
+	})
+
+	t.Run("Runs In Workspace", func(t *testing.T) {
+		_, err := ws.WriteFile("input.txt", []byte("hello"))
+		assert.NoError(t, err)
+
+		result, err := r.Run(context.Background(), ws, Command{Tool: "sh", Args: []string{"-c", "cat input.txt"}})
+		assert.NoError(t, err)
+		assert.Equal(t, "hello", string(result.Stdout))
+	})
+
+	t.Run("Non-Zero Exit", func(t *testing.T) {
+		_, err := r.Run(context.Background(), ws, Command{Tool: "sh", Args: []string{"-c", "echo boom >&2; exit 3"}})
+		exitErr, ok := err.(*ExitError)
+		assert.True(t, ok)
+		if ok {
+			assert.Equal(t, 3, exitErr.ExitCode)
+			assert.Equal(t, "boom", exitErr.Stderr)
+		}
+	})
+
+	t.Run("Timeout Kills Process", func(t *testing.T) {
+		start := time.Now()
+		_, err := r.Run(context.Background(), ws, Command{Tool: "sh", Args: []string{"-c", "sleep 10"}, Timeout: 50 * time.Millisecond})
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+		assert.Less(t, time.Since(start), 5*time.Second)
+	})
+}
+
+func TestRunner_InUse(t *testing.T) {
+	r := newTestRunner(t)
+	ws, err := r.NewWorkspace()
+	assert.NoError(t, err)
+
+	assert.True(t, r.InUse(ws.Dir))
+	assert.False(t, r.InUse(r.WorkDir()))
+
+	assert.NoError(t, ws.Close())
+	assert.False(t, r.InUse(ws.Dir))
+}
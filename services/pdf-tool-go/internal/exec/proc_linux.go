@@ -0,0 +1,23 @@
+//go:build linux
+
+package exec
+
+import (
+	osexec "os/exec"
+	"syscall"
+)
+
+// isolate runs the tool in its own process group, kills the whole group on
+// cancellation and ensures it dies with the service
+func isolate(cmd *osexec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{
+		Setpgid:   true,
+		Pdeathsig: syscall.SIGKILL,
+	}
+	cmd.Cancel = func() error {
+		if cmd.Process == nil {
+			return nil
+		}
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+}
@@ -0,0 +1,8 @@
+//go:build !linux
+
+package exec
+
+import osexec "os/exec"
+
+// isolate is a no-op outside Linux; CommandContext still kills the direct child
+func isolate(cmd *osexec.Cmd) {}
@@ -0,0 +1,114 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// assemblyComponent is a component of an assembly request: a stored
+// document or an uploaded file, named by its form field
+type assemblyComponent struct {
+	DocumentID string `json:"document_id"`
+	File       string `json:"file"`
+	Template   bool   `json:"template"`
+	Pages      string `json:"pages"`
+}
+
+// assemblyRequest lists the components of an assembled document in order
+type assemblyRequest struct {
+	Components []assemblyComponent `json:"components"`
+	Variables  map[string]string   `json:"variables"`
+}
+
+// AssemblePDF builds a document from stored and uploaded components, like
+// a cover template, an uploaded body and standard terms. The assembly is
+// sent as JSON, either as the body or, with uploaded components, in the
+// "assembly" form field. Variables fill the text fields of template
+// components.
+func (h *PDFHandler) AssemblePDF(c *gin.Context) {
+	var req assemblyRequest
+	var err error
+	if c.ContentType() == "application/json" {
+		err = c.ShouldBindJSON(&req)
+	} else {
+		err = json.Unmarshal([]byte(c.PostForm("assembly")), &req)
+	}
+	if err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "assembly must be a JSON object", err), "Invalid assembly")
+		return
+	}
+	if len(req.Components) < 2 {
+		c.JSON(http.StatusBadRequest, versioning.ErrorBody(c, string(service.ErrCodeInvalidInput), "At least 2 components required", nil))
+		return
+	}
+
+	uploads := make([]*upload, 0, len(req.Components))
+	defer func() {
+		for _, u := range uploads {
+			u.release()
+		}
+	}()
+	components := make([]service.AssemblyComponent, len(req.Components))
+	for i, component := range req.Components {
+		u, name, err := h.loadComponent(c, component)
+		if err != nil {
+			h.respondError(c, err, fmt.Sprintf("Failed to load component %d", i))
+			return
+		}
+		uploads = append(uploads, u)
+		components[i] = service.AssemblyComponent{
+			Name:     name,
+			PDF:      u.Bytes(),
+			Template: component.Template,
+			Pages:    component.Pages,
+		}
+	}
+
+	name := outputFilename(c, "assembled.pdf")
+	result, err := h.service.Assemble(h.requestContext(c), &service.AssembleRequest{
+		Components: components,
+		Variables:  req.Variables,
+		OutputName: name,
+	})
+	for _, u := range uploads {
+		u.settle(err)
+	}
+	if err != nil {
+		h.respondError(c, err, "Assembly failed")
+		return
+	}
+
+	h.respondPDF(c, result, name)
+}
+
+// loadComponent reads a component of an assembly, returning the name it
+// is reported by
+func (h *PDFHandler) loadComponent(c *gin.Context, component assemblyComponent) (*upload, string, error) {
+	switch {
+	case component.DocumentID != "" && component.File != "":
+		return nil, "", service.NewError(service.ErrCodeInvalidInput, "a component names either a document_id or a file", nil)
+	case component.DocumentID != "":
+		u, err := h.loadDocument(c, component.DocumentID)
+		return u, component.DocumentID, err
+	case component.File == "":
+		return nil, "", service.NewError(service.ErrCodeInvalidInput, "a component needs a document_id or a file", nil)
+	}
+
+	file, err := c.FormFile(component.File)
+	if err != nil {
+		return nil, "", uploadError(err, fmt.Sprintf("no file uploaded as %q", component.File))
+	}
+	if err := h.checkUploadSize(c.Request.Context(), file); err != nil {
+		return nil, "", err
+	}
+	u, err := readUploadedFile(file)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read file: %w", err)
+	}
+	return u, component.File, nil
+}
@@ -0,0 +1,58 @@
+package handlers
+
+import (
+	"encoding/json"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// auditTrailRequest is the history of a document an audit trail reports
+type auditTrailRequest struct {
+	Document string                `json:"document"`
+	Events   []service.AuditEvent  `json:"events"`
+	Signers  []service.AuditSigner `json:"signers"`
+}
+
+// AppendAuditTrail handles certificates of completion: pages listing the
+// document's hash, history and signers are appended to it. The history is
+// a JSON object in the "audit" form field, like {"events": [{"action":
+// "signed", "actor": "ann@example.com", "at": "2024-05-01T10:00:00Z"}],
+// "signers": [{"name": "Ann", "email": "ann@example.com"}]}.
+func (h *PDFHandler) AppendAuditTrail(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	var audit auditTrailRequest
+	if raw := c.PostForm("audit"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &audit); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "audit must be a JSON object", err), "Invalid audit trail")
+			return
+		}
+	}
+	if audit.Document == "" {
+		audit.Document = c.Query("document_id")
+	}
+	if audit.Document == "" {
+		if file, err := c.FormFile("pdf"); err == nil {
+			audit.Document = file.Filename
+		}
+	}
+
+	result, err := h.service.AppendAuditTrail(h.requestContext(c), &service.AuditTrailRequest{
+		PDFData:      upload.Bytes(),
+		DocumentName: audit.Document,
+		Events:       audit.Events,
+		Signers:      audit.Signers,
+	})
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Appending audit trail failed")
+		return
+	}
+
+	h.respondPDF(c, result, outputFilename(c, "certified.pdf"))
+}
@@ -0,0 +1,220 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/listing"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/middleware"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// BatchProcess queues a multi-step job over stored documents. The job runs
+// as a transaction: either every step succeeds and the final outputs are
+// stored as results, or nothing the job wrote is kept and the job status
+// reports the failing step and input. Interactive jobs are scheduled ahead
+// of bulk jobs, and tenants share workers fairly.
+func (h *PDFHandler) BatchProcess(c *gin.Context) {
+	var req batch.Submission
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid job request", err), "Invalid job request")
+		return
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), req)
+	if err != nil {
+		h.respondError(c, err, "Failed to submit job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"status_url": versioning.URL(c, "/batch/status/"+job.ID),
+	})
+}
+
+// mailMergeRequest is the body of a mail merge request
+type mailMergeRequest struct {
+	// Template is the ID of the stored form filled for each row
+	Template string `json:"template"`
+	// Dataset is CSV text, JSON text or a JSON array of objects
+	Dataset json.RawMessage `json:"dataset"`
+	// Combine merges the filled copies into one document
+	Combine  bool            `json:"combine"`
+	Priority batch.Priority  `json:"priority,omitempty"`
+	Delivery *batch.Delivery `json:"delivery,omitempty"`
+}
+
+// MailMerge queues a batch job filling a copy of a stored form for every
+// row of a dataset, as separate results in a ZIP archive or combined into
+// one document. The job's progress counts the filled copies.
+func (h *PDFHandler) MailMerge(c *gin.Context) {
+	var req mailMergeRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid mail merge request", err), "Invalid mail merge request")
+		return
+	}
+	if req.Template == "" {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "template is required", nil), "Invalid mail merge request")
+		return
+	}
+	// A dataset given as JSON text or CSV arrives as a string; an array is
+	// passed on as is
+	dataset := string(req.Dataset)
+	var text string
+	if err := json.Unmarshal(req.Dataset, &text); err == nil {
+		dataset = text
+	}
+
+	job, err := h.jobs.Submit(c.Request.Context(), batch.Submission{
+		Documents: []string{req.Template},
+		Steps: []batch.Step{{Operation: "mailmerge", Params: map[string]string{
+			"data":    dataset,
+			"combine": strconv.FormatBool(req.Combine),
+		}}},
+		Priority: req.Priority,
+		Delivery: req.Delivery,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to submit job")
+		return
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{
+		"job_id":     job.ID,
+		"status":     job.Status,
+		"status_url": versioning.URL(c, "/batch/status/"+job.ID),
+	})
+}
+
+// BatchStatus reports the progress or outcome of a job
+func (h *PDFHandler) BatchStatus(c *gin.Context) {
+	job, err := h.jobs.Get(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to get job")
+		return
+	}
+
+	c.JSON(http.StatusOK, job)
+}
+
+// jobSorter pages batch jobs
+var jobSorter = listing.Sorter[*batch.Job]{
+	ID: func(job *batch.Job) string { return job.ID },
+	Fields: map[string]func(*batch.Job) string{
+		"created_at": func(job *batch.Job) string { return listing.Time(job.CreatedAt) },
+		"status":     func(job *batch.Job) string { return string(job.Status) },
+	},
+}
+
+// ListJobs lists the caller's batch jobs a page at a time, optionally
+// filtered by status and submission date
+func (h *PDFHandler) ListJobs(c *gin.Context) {
+	q, err := listing.ParseQuery(c.Request.URL.Query(), []string{"created_at", "status"})
+	if err != nil {
+		h.respondError(c, err, "Invalid listing request")
+		return
+	}
+	owner, err := listTenant(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid listing request")
+		return
+	}
+	status := batch.Status(c.Query("status"))
+	if status != "" && !status.Valid() {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf("unknown job status %q", status), nil), "Invalid listing request")
+		return
+	}
+
+	jobs, err := h.jobs.List(c.Request.Context(), batch.Filter{
+		Tenant:        owner,
+		Status:        status,
+		CreatedAfter:  q.CreatedAfter,
+		CreatedBefore: q.CreatedBefore,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to list jobs")
+		return
+	}
+
+	page, next := jobSorter.Page(jobs, q)
+	c.JSON(http.StatusOK, gin.H{
+		"items":       page,
+		"count":       len(page),
+		"next_cursor": next,
+	})
+}
+
+// listTenant returns the tenant a listing covers: the caller's own, or for
+// privileged callers the one named with ?tenant. The default tenant is
+// shared by every anonymous caller and by ingestion without a tenant, so
+// only privileged callers may list it.
+func listTenant(c *gin.Context) (string, error) {
+	owner := tenant.FromContext(c.Request.Context())
+	privileged := c.GetBool(middleware.PrivilegedKey)
+	requested := c.Query("tenant")
+	if requested != "" && requested != owner {
+		if !privileged {
+			return "", service.NewError(service.ErrCodeInvalidInput, "listing other tenants requires a privileged API key", nil)
+		}
+		return requested, nil
+	}
+	if owner == config.DefaultTenant && !privileged {
+		return "", service.NewError(service.ErrCodeUnauthorized, "listing requires a tenant API key", nil)
+	}
+	return owner, nil
+}
+
+// CancelJob stops a job. Waiting and paused jobs are canceled at once
+// (200); a running job is interrupted at the next input and rolled back,
+// so the response is 202 and the job status turns canceled once it has
+// stopped.
+func (h *PDFHandler) CancelJob(c *gin.Context) {
+	job, err := h.jobs.Cancel(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to cancel job")
+		return
+	}
+	respondJobControl(c, job)
+}
+
+// PauseJob takes a job off the queue until it is resumed. A running job is
+// interrupted and rolled back like a canceled one (202) and runs again from
+// its first step when resumed.
+func (h *PDFHandler) PauseJob(c *gin.Context) {
+	job, err := h.jobs.Pause(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to pause job")
+		return
+	}
+	respondJobControl(c, job)
+}
+
+// ResumeJob queues a paused job again
+func (h *PDFHandler) ResumeJob(c *gin.Context) {
+	job, err := h.jobs.Resume(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to resume job")
+		return
+	}
+	c.JSON(http.StatusAccepted, job)
+}
+
+// respondJobControl reports a canceled or paused job, with 202 while a
+// running job has yet to stop
+func respondJobControl(c *gin.Context, job *batch.Job) {
+	status := http.StatusOK
+	if job.Status == batch.StatusRunning {
+		status = http.StatusAccepted
+		c.Header("Location", versioning.URL(c, "/batch/status/"+job.ID))
+	}
+	c.JSON(status, job)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/connector"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// connectorTokenHeader carries the OAuth access token of a connector call.
+// Without it, the refresh token stored for the caller's tenant is used.
+const connectorTokenHeader = "X-Connector-Token"
+
+// connectorRequest is the body of a connector import or export
+type connectorRequest struct {
+	Provider string `json:"provider" binding:"required"`
+	// Path is the file path, or for Google Drive the file ID to import and
+	// the <folder ID>/<name> to export to
+	Path string `json:"path" binding:"required"`
+}
+
+// SetConnectors enables importing documents from and exporting results to
+// cloud storage through connectors. It must be called before the handler
+// serves requests.
+func (h *PDFHandler) SetConnectors(connectors *connector.Connectors) {
+	h.connectors = connectors
+}
+
+// ImportDocument stores a PDF read from the caller's cloud storage and
+// returns its document ID, like an upload
+func (h *PDFHandler) ImportDocument(c *gin.Context) {
+	var req connectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid import request", err), "Invalid import request")
+		return
+	}
+
+	ctx := c.Request.Context()
+	data, err := h.connectors.Read(ctx, req.Provider, c.GetHeader(connectorTokenHeader), req.Path, h.service.MaxFileSize(ctx))
+	if err != nil {
+		h.respondError(c, err, "Failed to import document")
+		return
+	}
+	if err := h.service.ValidateRequest(ctx, data); err != nil {
+		h.respondError(c, err, "Invalid PDF")
+		return
+	}
+
+	doc, err := h.documents.Save(ctx, data)
+	if err != nil {
+		h.respondError(c, err, "Failed to store document")
+		return
+	}
+
+	c.Header(checksumHeader, doc.SHA256)
+	c.JSON(http.StatusCreated, documentBody(c, doc))
+}
+
+// ExportResult writes a stored result of the caller's tenant to the
+// caller's cloud storage, replacing any file at the path
+func (h *PDFHandler) ExportResult(c *gin.Context) {
+	var req connectorRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid export request", err), "Invalid export request")
+		return
+	}
+
+	ctx := c.Request.Context()
+	obj, result, err := h.results.Open(ctx, c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to open result")
+		return
+	}
+	data, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		h.respondError(c, err, "Failed to read result")
+		return
+	}
+
+	written, err := h.connectors.Write(ctx, req.Provider, c.GetHeader(connectorTokenHeader), req.Path, data)
+	if err != nil {
+		h.respondError(c, err, "Failed to export result")
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"result_id": result.ID,
+		"provider":  req.Provider,
+		"path":      written,
+	})
+}
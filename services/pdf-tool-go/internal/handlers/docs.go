@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"mime"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/openapi"
+)
+
+// DocsHandler serves the OpenAPI specification and Swagger UI
+type DocsHandler struct {
+	spec []byte
+}
+
+// NewDocsHandler prepares the OpenAPI document for the running version
+func NewDocsHandler(version string) (*DocsHandler, error) {
+	spec, err := openapi.Spec(version)
+	if err != nil {
+		return nil, err
+	}
+	return &DocsHandler{spec: spec}, nil
+}
+
+// Spec serves the OpenAPI document
+func (h *DocsHandler) Spec(c *gin.Context) {
+	c.Data(http.StatusOK, "application/json", h.spec)
+}
+
+// UI serves the Swagger UI page
+func (h *DocsHandler) UI(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", openapi.SwaggerUI())
+}
+
+// Asset serves the embedded Swagger UI scripts and styles
+func (h *DocsHandler) Asset(c *gin.Context) {
+	name := c.Param("name")
+	data, ok := openapi.SwaggerAsset(name)
+	if !ok {
+		c.Status(http.StatusNotFound)
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, mime.TypeByExtension(path.Ext(name)), data)
+}
@@ -0,0 +1,207 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/listing"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// UploadDocument stores an uploaded PDF and returns its document ID. PDF
+// operations accept ?document_id=<id> in place of an upload, so clients
+// upload a document once and run any number of operations on it.
+func (h *PDFHandler) UploadDocument(c *gin.Context) {
+	file, ok := h.uploadedPDF(c)
+	if !ok {
+		return
+	}
+
+	upload, err := readUploadedFile(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, versioning.ErrorBody(c, string(service.ErrCodeInternal), "Failed to read file", nil))
+		return
+	}
+	defer upload.release()
+
+	if err := h.service.ValidateRequest(c.Request.Context(), upload.Bytes()); err != nil {
+		h.respondError(c, err, "Invalid PDF")
+		return
+	}
+
+	doc, err := h.documents.Save(c.Request.Context(), upload.Bytes())
+	if err != nil {
+		h.respondError(c, err, "Failed to store document")
+		return
+	}
+
+	c.Header(checksumHeader, doc.SHA256)
+	c.JSON(http.StatusCreated, documentBody(c, doc))
+}
+
+// documentSorter pages stored documents
+var documentSorter = listing.Sorter[*service.Result]{
+	ID: func(doc *service.Result) string { return doc.ID },
+	Fields: map[string]func(*service.Result) string{
+		"created_at": func(doc *service.Result) string { return listing.Time(doc.CreatedAt) },
+		"size":       func(doc *service.Result) string { return listing.Int(doc.Size) },
+	},
+}
+
+// ListDocuments lists the caller's stored documents a page at a time,
+// optionally filtered by upload date
+func (h *PDFHandler) ListDocuments(c *gin.Context) {
+	q, err := listing.ParseQuery(c.Request.URL.Query(), []string{"created_at", "size"})
+	if err != nil {
+		h.respondError(c, err, "Invalid listing request")
+		return
+	}
+	owner, err := listTenant(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid listing request")
+		return
+	}
+
+	var docs []*service.Result
+	err = h.documents.List(tenant.WithID(c.Request.Context(), owner), func(doc *service.Result) error {
+		if q.Created(doc.CreatedAt) {
+			docs = append(docs, doc)
+		}
+		return nil
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to list documents")
+		return
+	}
+
+	page, next := documentSorter.Page(docs, q)
+	items := make([]gin.H, len(page))
+	for i, doc := range page {
+		items[i] = documentBody(c, doc)
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"items":       items,
+		"count":       len(items),
+		"next_cursor": next,
+	})
+}
+
+// documentBody describes a stored document in responses
+func documentBody(c *gin.Context, doc *service.Result) gin.H {
+	return gin.H{
+		"document_id":  doc.ID,
+		"size":         doc.Size,
+		"content_type": doc.ContentType,
+		"sha256":       doc.SHA256,
+		"created_at":   doc.CreatedAt,
+		"url":          versioning.URL(c, "/documents/"+doc.ID),
+	}
+}
+
+// DownloadDocument streams a stored document
+func (h *PDFHandler) DownloadDocument(c *gin.Context) {
+	obj, doc, err := h.documents.Open(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to open document")
+		return
+	}
+	defer obj.Close()
+
+	if policy := downloadWatermark(c, doc); policy != nil {
+		if h.serveWatermarked(c, obj, policy, downloadUser(c, "")) {
+			h.documents.Downloaded(c.Request.Context(), doc.ID)
+		}
+		return
+	}
+	if serveStored(c, obj, doc) {
+		h.documents.Downloaded(c.Request.Context(), doc.ID)
+	}
+}
+
+// DeleteDocument removes a stored document
+func (h *PDFHandler) DeleteDocument(c *gin.Context) {
+	if err := h.documents.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to delete document")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// serveStored streams a stored object with http.ServeContent and reports
+// whether the whole object was sent, which is when delete-after-download
+// retention applies
+func serveStored(c *gin.Context, obj storage.Object, stored *service.Result) bool {
+	c.Header("Content-Type", stored.ContentType)
+	c.Header("Accept-Ranges", "bytes")
+	c.Header("ETag", stored.ETag)
+	if stored.SHA256 != "" {
+		c.Header(checksumHeader, stored.SHA256)
+	}
+	http.ServeContent(c.Writer, c.Request, stored.ID, stored.CreatedAt, obj)
+
+	return c.Request.Method == http.MethodGet &&
+		c.Writer.Status() == http.StatusOK &&
+		int64(c.Writer.Size()) == stored.Size
+}
+
+// endUserHeader names the end user a caller downloads a stored file for,
+// stamped into the tenant's download watermark
+const endUserHeader = "X-End-User"
+
+// downloadWatermark returns the download watermark policy of the tenant of
+// the request that applies to a stored file: none unless it is a PDF
+func downloadWatermark(c *gin.Context, stored *service.Result) *tenant.Watermark {
+	if stored.ContentType != "application/pdf" {
+		return nil
+	}
+	return tenant.SettingsFromContext(c.Request.Context()).Policies.DownloadWatermark
+}
+
+// downloadUser names who a download is for: recipient when known, else the
+// end user named in X-End-User, else the client's address
+func downloadUser(c *gin.Context, recipient string) string {
+	if recipient != "" {
+		return recipient
+	}
+	if user := strings.TrimSpace(c.GetHeader(endUserHeader)); user != "" {
+		return user
+	}
+	return c.ClientIP()
+}
+
+// serveWatermarked sends a stored PDF stamped with a download watermark
+// naming user, and reports whether it was sent. Stamped copies differ on
+// every download, so ranges and validators do not apply.
+func (h *PDFHandler) serveWatermarked(c *gin.Context, obj storage.Object, policy *tenant.Watermark, user string) bool {
+	data, err := io.ReadAll(obj)
+	if err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInternal, "failed to read stored file", err), "Failed to read stored file")
+		return false
+	}
+
+	ctx := c.Request.Context()
+	now := time.Now().UTC()
+	req := service.PresetWatermarkRequest(data, policy)
+	req.WatermarkText = strings.NewReplacer(
+		"{user}", user,
+		"{tenant}", tenant.FromContext(ctx),
+		"{date}", now.Format("2006-01-02"),
+		"{time}", now.Format("15:04 MST"),
+	).Replace(req.WatermarkText)
+
+	stamped, err := h.service.AddWatermark(ctx, req)
+	if err != nil {
+		h.respondError(c, err, "Failed to watermark download")
+		return false
+	}
+	c.Header("Cache-Control", "private, no-store")
+	c.Header(checksumHeader, service.Checksum(stamped))
+	c.Data(http.StatusOK, "application/pdf", stamped)
+	return c.Request.Method == http.MethodGet
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/middleware"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/stretchr/testify/assert"
+)
+
+// storedObject is an in-memory storage.Object
+type storedObject struct {
+	*strings.Reader
+}
+
+func (storedObject) Close() error { return nil }
+
+func TestServeStored(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	content := "%PDF-1.7 0123456789 abcdefghij %%EOF"
+	created := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	stored := &service.Result{
+		ID:          "doc.pdf",
+		Size:        int64(len(content)),
+		ContentType: "application/pdf",
+		CreatedAt:   created,
+		ETag:        `"doc"`,
+		SHA256:      "abc123",
+	}
+
+	serve := func(method string, header http.Header) (*httptest.ResponseRecorder, bool) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(method, "/results/doc.pdf", nil)
+		for name, values := range header {
+			c.Request.Header[name] = values
+		}
+		whole := serveStored(c, storedObject{strings.NewReader(content)}, stored)
+		c.Writer.WriteHeaderNow()
+		return rec, whole
+	}
+
+	t.Run("Whole Object", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Equal(t, content, rec.Body.String())
+		assert.Equal(t, "bytes", rec.Header().Get("Accept-Ranges"))
+		assert.Equal(t, `"doc"`, rec.Header().Get("ETag"))
+		assert.Equal(t, "abc123", rec.Header().Get(checksumHeader))
+		assert.Equal(t, created.Format(http.TimeFormat), rec.Header().Get("Last-Modified"))
+		assert.True(t, whole)
+	})
+
+	t.Run("Range", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, http.Header{"Range": {"bytes=9-18"}})
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, content[9:19], rec.Body.String())
+		assert.Equal(t, "bytes 9-18/36", rec.Header().Get("Content-Range"))
+		assert.False(t, whole)
+	})
+
+	t.Run("Unsatisfiable Range", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, http.Header{"Range": {"bytes=100-"}})
+		assert.Equal(t, http.StatusRequestedRangeNotSatisfiable, rec.Code)
+		assert.False(t, whole)
+	})
+
+	t.Run("Not Modified Since", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, http.Header{"If-Modified-Since": {created.Add(time.Hour).Format(http.TimeFormat)}})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.False(t, whole)
+	})
+
+	t.Run("Modified Since", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, http.Header{"If-Modified-Since": {created.Add(-time.Hour).Format(http.TimeFormat)}})
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.True(t, whole)
+	})
+
+	t.Run("Matching ETag", func(t *testing.T) {
+		rec, whole := serve(http.MethodGet, http.Header{"If-None-Match": {`"doc"`}})
+		assert.Equal(t, http.StatusNotModified, rec.Code)
+		assert.False(t, whole)
+	})
+
+	t.Run("Head", func(t *testing.T) {
+		rec, whole := serve(http.MethodHead, nil)
+		assert.Equal(t, http.StatusOK, rec.Code)
+		assert.Empty(t, rec.Body.String())
+		assert.False(t, whole)
+	})
+}
+
+func TestListTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	list := func(owner, query string, privileged bool) (string, error) {
+		c, _ := gin.CreateTestContext(httptest.NewRecorder())
+		c.Request = httptest.NewRequest(http.MethodGet, "/documents"+query, nil)
+		if owner != "" {
+			c.Request = c.Request.WithContext(tenant.WithID(c.Request.Context(), owner))
+		}
+		c.Set(middleware.PrivilegedKey, privileged)
+		return listTenant(c)
+	}
+
+	t.Run("Own Tenant", func(t *testing.T) {
+		owner, err := list("acme", "", false)
+		assert.NoError(t, err)
+		assert.Equal(t, "acme", owner)
+	})
+
+	t.Run("Other Tenant", func(t *testing.T) {
+		_, err := list("acme", "?tenant=globex", false)
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		owner, err := list("acme", "?tenant=globex", true)
+		assert.NoError(t, err)
+		assert.Equal(t, "globex", owner)
+	})
+
+	t.Run("Default Tenant", func(t *testing.T) {
+		_, err := list("", "", false)
+		assert.Equal(t, service.ErrCodeUnauthorized, service.CodeOf(err))
+		_, err = list("acme", "?tenant="+config.DefaultTenant, false)
+		assert.Error(t, err)
+		owner, err := list("", "", true)
+		assert.NoError(t, err)
+		assert.Equal(t, config.DefaultTenant, owner)
+	})
+}
+
+func TestArchivePassword(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	password := func(query, header string) (*httptest.ResponseRecorder, string, error) {
+		rec := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(rec)
+		c.Request = httptest.NewRequest(http.MethodGet, "/results/parts.zip"+query, nil)
+		if header != "" {
+			c.Request.Header.Set(archivePasswordHeader, header)
+		}
+		password, err := archivePassword(c)
+		return rec, password, err
+	}
+
+	t.Run("Caller Password", func(t *testing.T) {
+		rec, got, err := password("?encrypt=true", "correct horse")
+		assert.NoError(t, err)
+		assert.Equal(t, "correct horse", got)
+		assert.Empty(t, rec.Header().Get(archivePasswordHeader))
+	})
+
+	t.Run("Encryption Without Password", func(t *testing.T) {
+		rec, _, err := password("?encrypt=true", "")
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		assert.Empty(t, rec.Header().Get(archivePasswordHeader))
+	})
+
+	t.Run("Short Password", func(t *testing.T) {
+		_, _, err := password("", "short")
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("No Encryption", func(t *testing.T) {
+		_, got, err := password("", "")
+		assert.NoError(t, err)
+		assert.Empty(t, got)
+	})
+}
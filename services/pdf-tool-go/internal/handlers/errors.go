@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/middleware"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// statusClientClosedRequest is the de-facto status for requests abandoned by the client
+const statusClientClosedRequest = 499
+
+// errorStatus maps service error codes to HTTP status codes
+var errorStatus = map[service.ErrorCode]int{
+	service.ErrCodeInvalidInput:       http.StatusBadRequest,
+	service.ErrCodeFileTooLarge:       http.StatusRequestEntityTooLarge,
+	service.ErrCodeEncrypted:          http.StatusUnprocessableEntity,
+	service.ErrCodeBadPassword:        http.StatusUnprocessableEntity,
+	service.ErrCodeCorrupted:          http.StatusUnprocessableEntity,
+	service.ErrCodeTooManyPages:       http.StatusUnprocessableEntity,
+	service.ErrCodeUnsupportedVersion: http.StatusUnprocessableEntity,
+	service.ErrCodeTimeout:            http.StatusGatewayTimeout,
+	service.ErrCodeCanceled:           statusClientClosedRequest,
+	service.ErrCodeBusy:               http.StatusServiceUnavailable,
+	service.ErrCodeLowResources:       http.StatusServiceUnavailable,
+	service.ErrCodeNotFound:           http.StatusNotFound,
+	service.ErrCodeTooManyAttempts:    http.StatusTooManyRequests,
+	service.ErrCodeRateLimited:        http.StatusTooManyRequests,
+	service.ErrCodeUnauthorized:       http.StatusUnauthorized,
+	service.ErrCodeToolUnavailable:    http.StatusNotImplemented,
+	service.ErrCodeInternal:           http.StatusInternalServerError,
+}
+
+// respondError writes a coded error response. Client-caused failures expose
+// the service message; internal failures only expose the fallback message.
+func (h *PDFHandler) respondError(c *gin.Context, err error, fallback string) {
+	code := service.CodeOf(err)
+	c.Set(middleware.ErrorCodeKey, string(code))
+	status, ok := errorStatus[code]
+	if !ok {
+		status = http.StatusInternalServerError
+	}
+
+	message := fallback
+	var pdfErr *service.PDFError
+	if code != service.ErrCodeInternal && errors.As(err, &pdfErr) {
+		message = pdfErr.Message
+		if pdfErr.RetryAfter > 0 {
+			c.Header("Retry-After", strconv.Itoa(pdfErr.RetryAfter))
+		}
+	}
+
+	if status >= http.StatusInternalServerError {
+		h.log.Error(fallback, "error", err, "code", code)
+	} else {
+		h.log.Warn(fallback, "error", err, "code", code)
+	}
+
+	var details map[string]interface{}
+	if pdfErr != nil && code != service.ErrCodeInternal {
+		details = pdfErr.Details
+	}
+
+	c.JSON(status, versioning.ErrorBody(c, string(code), message, details))
+}
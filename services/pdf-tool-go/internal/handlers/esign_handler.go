@@ -0,0 +1,63 @@
+package handlers
+
+import (
+	"io"
+	"net/http"
+	"path"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/esign"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// signingRequest is the body of a signing hand-off
+type signingRequest struct {
+	Provider  string         `json:"provider" binding:"required"`
+	Signers   []esign.Signer `json:"signers" binding:"required"`
+	Subject   string         `json:"subject"`
+	Message   string         `json:"message"`
+	ReturnURL string         `json:"return_url"`
+}
+
+// SetSigning enables handing stored results to signing services through
+// client. It must be called before the handler serves requests.
+func (h *PDFHandler) SetSigning(client *esign.Client) {
+	h.signing = client
+}
+
+// RequestSignatures hands a stored result of the caller's tenant, such as
+// a filled form, to DocuSign or Adobe Sign and returns the URLs its
+// signers sign at
+func (h *PDFHandler) RequestSignatures(c *gin.Context) {
+	var req signingRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid signing request", err), "Invalid signing request")
+		return
+	}
+
+	ctx := c.Request.Context()
+	obj, result, err := h.results.Open(ctx, c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to open result")
+		return
+	}
+	data, err := io.ReadAll(obj)
+	obj.Close()
+	if err != nil {
+		h.respondError(c, err, "Failed to read result")
+		return
+	}
+
+	envelope, err := h.signing.Send(ctx, req.Provider, path.Base(result.ID), data, esign.Request{
+		Signers:   req.Signers,
+		Subject:   req.Subject,
+		Message:   req.Message,
+		ReturnURL: req.ReturnURL,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to request signatures")
+		return
+	}
+
+	c.JSON(http.StatusCreated, envelope)
+}
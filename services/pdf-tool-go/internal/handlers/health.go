@@ -2,30 +2,88 @@ package handlers
 
 import (
 	"net/http"
+	"runtime"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/health"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
 )
 
 type HealthHandler struct {
-	log logger.Logger
+	log         logger.Logger
+	checker     *health.Checker
+	service     *service.PDFService
+	diagnostics bool
+	startedAt   time.Time
 }
 
-func NewHealthHandler(log logger.Logger) *HealthHandler {
-	return &HealthHandler{log: log}
+// NewHealthHandler creates a health handler. When diagnostics is set, the
+// liveness response includes build, runtime and load details; only enable
+// it where /health is not publicly reachable.
+func NewHealthHandler(log logger.Logger, checker *health.Checker, svc *service.PDFService, diagnostics bool) *HealthHandler {
+	return &HealthHandler{
+		log:         log,
+		checker:     checker,
+		service:     svc,
+		diagnostics: diagnostics,
+		startedAt:   time.Now(),
+	}
 }
 
 func (h *HealthHandler) Health(c *gin.Context) {
+	if !h.diagnostics {
+		c.JSON(http.StatusOK, gin.H{
+			"status":  "healthy",
+			"service": "pdf-tool-go",
+		})
+		return
+	}
+
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	uptime := time.Since(h.startedAt)
+
 	c.JSON(http.StatusOK, gin.H{
-		"status": "healthy",
-		"service": "pdf-tool-go",
+		"status":         "healthy",
+		"service":        "pdf-tool-go",
+		"build":          buildinfo.Get(),
+		"uptime":         uptime.Round(time.Second).String(),
+		"uptime_seconds": int64(uptime.Seconds()),
+		"runtime": gin.H{
+			"goroutines":     runtime.NumGoroutine(),
+			"num_cpu":        runtime.NumCPU(),
+			"gomaxprocs":     runtime.GOMAXPROCS(0),
+			"heap_alloc":     mem.HeapAlloc,
+			"heap_sys":       mem.HeapSys,
+			"sys":            mem.Sys,
+			"num_gc":         mem.NumGC,
+			"gc_pause_total": time.Duration(mem.PauseTotalNs).String(),
+		},
+		"jobs": h.service.Stats(),
 	})
 }
 
+// Ready runs the dependency checks and returns 503 if any critical
+// dependency is down
 func (h *HealthHandler) Ready(c *gin.Context) {
+	report := h.checker.Run(c.Request.Context())
+
+	if !report.Ready {
+		h.log.Warn("Readiness check failed", "checks", report.Checks)
+		c.JSON(http.StatusServiceUnavailable, gin.H{
+			"status": "not_ready",
+			"checks": report.Checks,
+		})
+		return
+	}
+
 	c.JSON(http.StatusOK, gin.H{
 		"status": "ready",
+		"checks": report.Checks,
 	})
 }
 
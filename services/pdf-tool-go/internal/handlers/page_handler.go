@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// pageCacheControl lets viewers cache pages; stored documents never change
+const pageCacheControl = "private, max-age=86400"
+
+// GetPage returns a single page of an uploaded document or stored result, as a PDF or rendered
+// with ?format=png|jpeg&dpi=N, so viewers can lazy-load large documents
+func (h *PDFHandler) GetPage(c *gin.Context) {
+	page, err := strconv.Atoi(c.Param("n"))
+	if err != nil || page < 1 {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "page must be a positive integer", err), "Invalid page")
+		return
+	}
+	format := c.DefaultQuery("format", "pdf")
+	dpi := parseIntParam(c, "dpi", 150)
+
+	docID := c.Param("docId")
+	doc, err := h.loadStored(c, docID)
+	if err != nil {
+		h.respondError(c, err, "Failed to open document")
+		return
+	}
+	defer doc.release()
+	req := &service.PageRequest{
+		PDFData: doc.Bytes(),
+		Page:    page,
+		Format:  format,
+		DPI:     dpi,
+	}
+
+	// Stored documents are immutable, so the ID plus the rendering
+	// parameters identify the response. Only pages that still exist are
+	// reported unchanged; deleted documents and missing pages are 404.
+	etag := fmt.Sprintf(`W/"%s-%d-%s-%d"`, docID, page, format, dpi)
+	if strings.Contains(c.GetHeader("If-None-Match"), etag) {
+		err := h.service.CheckPage(h.requestContext(c), req)
+		doc.settle(err)
+		if err != nil {
+			h.respondError(c, err, "Failed to get page")
+			return
+		}
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	out, err := h.service.GetPage(h.requestContext(c), req)
+	doc.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Failed to get page")
+		return
+	}
+
+	c.Header("Cache-Control", pageCacheControl)
+	c.Header("ETag", etag)
+	c.Header(checksumHeader, service.Checksum(out))
+	c.Data(http.StatusOK, service.PageFormats[format], out)
+}
+
+// GetCover returns the first page of an uploaded document or stored result
+// as a small png or jpeg (?format=, ?width=), for listing views
+func (h *PDFHandler) GetCover(c *gin.Context) {
+	format := c.DefaultQuery("format", "jpeg")
+	width := parseIntParam(c, "width", 0)
+
+	docID := c.Param("docId")
+	doc, err := h.loadStored(c, docID)
+	if err != nil {
+		h.respondError(c, err, "Failed to open document")
+		return
+	}
+	defer doc.release()
+
+	// Covers are reported unchanged only while the document exists
+	etag := fmt.Sprintf(`W/"%s-cover-%s-%d"`, docID, format, width)
+	if strings.Contains(c.GetHeader("If-None-Match"), etag) {
+		c.Header("ETag", etag)
+		c.Status(http.StatusNotModified)
+		return
+	}
+
+	out, err := h.service.Cover(h.requestContext(c), &service.CoverRequest{
+		PDFData: doc.Bytes(),
+		Width:   width,
+		Format:  format,
+	})
+	doc.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Failed to render cover")
+		return
+	}
+
+	c.Header("Cache-Control", pageCacheControl)
+	c.Header("ETag", etag)
+	c.Header(checksumHeader, service.Checksum(out))
+	c.Data(http.StatusOK, service.PageFormats[format], out)
+}
+
+// loadStored loads an uploaded document or, failing that, a stored result
+func (h *PDFHandler) loadStored(c *gin.Context, id string) (*upload, error) {
+	doc, err := h.loadDocument(c, id)
+	if service.CodeOf(err) != service.ErrCodeNotFound {
+		return doc, err
+	}
+
+	obj, result, err := h.results.Open(c.Request.Context(), id)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return readInput(obj, result.Size)
+}
@@ -7,159 +7,531 @@
 package handlers
 
 import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/connector"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/esign"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/middleware"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/pipeline"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/securezip"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/share"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
 )
 
 // PDFHandler handles PDF-related HTTP requests
 type PDFHandler struct {
-	service *service.PDFService
+	service   *service.PDFService
+	results   *service.ResultService
+	documents *service.DocumentService
+	jobs      *batch.Manager
+	// shares mints and resolves share links; nil unless enabled
+	shares *share.Links
+	// tenants resolves the settings of share link tenants
+	tenants *tenant.Resolver
+	// connectors reach cloud storage; nil unless enabled
+	connectors *connector.Connectors
+	// signing hands results to signing services; nil unless enabled
+	signing *esign.Client
 	log     logger.Logger
 }
 
 // NewPDFHandler creates a new PDF handler
-func NewPDFHandler(svc *service.PDFService, log logger.Logger) *PDFHandler {
+func NewPDFHandler(svc *service.PDFService, results *service.ResultService, documents *service.DocumentService, jobs *batch.Manager, log logger.Logger) *PDFHandler {
 	return &PDFHandler{
-		service: svc,
-		log:     log,
+		service:   svc,
+		results:   results,
+		documents: documents,
+		jobs:      jobs,
+		log:       log,
 	}
 }
 
 // ConvertToImage handles PDF to image conversion
 func (h *PDFHandler) ConvertToImage(c *gin.Context) {
-	file, err := c.FormFile("pdf")
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	if err := h.service.ValidateRequest(c.Request.Context(), pdfData); err != nil {
+		h.respondError(c, err, "Invalid PDF")
+		return
+	}
+
+	req := &service.ConvertToImageRequest{
+		PDFData:   pdfData,
+		Format:    formatParam(c, overrides(c).ImageFormat, "png"),
+		DPI:       parseIntParam(c, "dpi", 150),
+		PageRange: c.Query("pages"),
+		Quality:   parseIntParam(c, "quality", 0),
+		MaxBytes:  parseIntParam(c, "max_bytes", 0),
+	}
+	if lossless, ok := c.GetQuery("lossless"); ok {
+		value := lossless == "true"
+		req.Lossless = &value
+	}
+	if progressive, ok := c.GetQuery("progressive"); ok {
+		value := progressive == "true"
+		req.Progressive = &value
+	}
+
+	result, err := h.service.ConvertToImage(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+		h.respondError(c, err, "Conversion failed")
 		return
 	}
 
-	pdfData, err := readUploadedFile(file)
+	c.JSON(http.StatusOK, gin.H{
+		"images":       result.Images,
+		"sha256":       checksums(result.Images),
+		"page_count":   result.PageCount,
+		"format":       result.Format,
+		"content_type": result.ContentType,
+	})
+}
+
+// ConvertToImageStream handles image conversion of documents too large to
+// hold in memory. Pages are rendered a batch at a time and streamed back
+// in a ZIP archive as they are ready.
+func (h *PDFHandler) ConvertToImageStream(c *gin.Context) {
+	input, ok := h.openInput(c)
+	if !ok {
+		return
+	}
+	defer input.Close()
+
+	req := &service.StreamImagesRequest{
+		Input:     input,
+		Format:    formatParam(c, overrides(c).ImageFormat, "png"),
+		DPI:       parseIntParam(c, "dpi", 150),
+		PageRange: c.Query("pages"),
+		Quality:   parseIntParam(c, "quality", 0),
+	}
+	if lossless, ok := c.GetQuery("lossless"); ok {
+		value := lossless == "true"
+		req.Lossless = &value
+	}
+	password, err := archivePassword(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Invalid conversion request")
 		return
 	}
 
-	if err := h.service.ValidateRequest(pdfData); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	var archive *securezip.Writer
+	err = h.service.StreamImages(h.requestContext(c), req, func(page service.PageImage) error {
+		if archive == nil {
+			c.Header("Content-Type", "application/zip")
+			attachment(c, outputFilename(c, "pages.zip"))
+			c.Status(http.StatusOK)
+			archive = securezip.NewWriter(c.Writer, password)
+		}
+		// Images are already compressed, so entries are stored as is
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("page-%04d.%s", page.Page, req.Format),
+			Method:   zip.Store,
+			Modified: time.Now(),
+		})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(page.Data); err != nil {
+			return err
+		}
+		c.Writer.Flush()
+		return nil
+	})
+	if err != nil {
+		if archive == nil {
+			h.respondError(c, err, "Conversion failed")
+			return
+		}
+		// The response has started; leaving the archive without its central
+		// directory tells the client it is incomplete
+		h.log.Error("Streamed conversion failed", "error", err)
+		c.Abort()
 		return
 	}
+	if err := archive.Close(); err != nil {
+		h.log.Error("Failed to finish streamed archive", "error", err)
+	}
+}
 
-	req := &service.ConvertToImageRequest{
-		PDFData: pdfData,
-		Format:  c.DefaultQuery("format", "png"),
-		DPI:     parseIntParam(c, "dpi", 150),
+// ContactSheet handles contact sheet rendering: page thumbnails in a grid
+// on one image
+func (h *PDFHandler) ContactSheet(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
 	}
+	defer upload.release()
 
-	result, err := h.service.ConvertToImage(c.Request.Context(), req)
+	req := &service.ContactSheetRequest{
+		PDFData:    upload.Bytes(),
+		PageRange:  c.Query("pages"),
+		Columns:    parseIntParam(c, "columns", 0),
+		ThumbWidth: parseIntParam(c, "thumb_width", 0),
+		Format:     c.DefaultQuery("format", "png"),
+	}
+
+	result, err := h.service.ContactSheet(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to convert PDF to images", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Conversion failed"})
+		h.respondError(c, err, "Contact sheet failed")
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"images":     result.Images,
-		"page_count": result.PageCount,
-		"format":     result.Format,
-	})
+	respondFile(c, result, service.PageFormats[req.Format], "contact-sheet."+req.Format)
+}
+
+// LongImage handles stitching PDF pages into one tall image
+func (h *PDFHandler) LongImage(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.LongImageRequest{
+		PDFData:   upload.Bytes(),
+		PageRange: c.Query("pages"),
+		Width:     parseIntParam(c, "width", 0),
+		Gap:       parseIntParam(c, "gap", 0),
+		Format:    c.DefaultQuery("format", "jpeg"),
+	}
+
+	result, err := h.service.LongImage(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Long image failed")
+		return
+	}
+
+	respondFile(c, result, service.PageFormats[req.Format], "long-image."+req.Format)
 }
 
-// MergePDFs handles PDF merging
+// AnimatedPreview handles rendering the first pages of a PDF as a looping
+// GIF or WebP animation
+func (h *PDFHandler) AnimatedPreview(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.PreviewRequest{
+		PDFData:    upload.Bytes(),
+		Pages:      parseIntParam(c, "pages", 0),
+		Width:      parseIntParam(c, "width", 0),
+		FrameDelay: parseIntParam(c, "delay", 0),
+		Format:     c.DefaultQuery("format", "gif"),
+	}
+
+	result, err := h.service.AnimatedPreview(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Preview failed")
+		return
+	}
+
+	respondFile(c, result, service.PreviewFormats[req.Format], "preview."+req.Format)
+}
+
+// MergePDFs handles PDF merging. Inputs are either uploaded as "pdfs" or
+// named in order by repeated ?document_id parameters.
 func (h *PDFHandler) MergePDFs(c *gin.Context) {
+	if ids := c.QueryArray("document_id"); len(ids) > 0 {
+		h.mergeDocuments(c, ids)
+		return
+	}
+
 	form, err := c.MultipartForm()
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid multipart form"})
+		h.respondError(c, uploadError(err, "Invalid multipart form"), "Invalid upload")
 		return
 	}
 
 	files := form.File["pdfs"]
 	if len(files) < 2 {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "At least 2 PDFs required"})
+		c.JSON(http.StatusBadRequest, versioning.ErrorBody(c, string(service.ErrCodeInvalidInput), "At least 2 PDFs required", nil))
 		return
 	}
+	for _, file := range files {
+		if err := h.checkUploadSize(c.Request.Context(), file); err != nil {
+			h.respondError(c, err, "Invalid upload")
+			return
+		}
+	}
 
-	pdfs := make([][]byte, len(files))
+	names := make([]string, len(files))
+	uploads := make([]*upload, 0, len(files))
+	defer func() {
+		for _, u := range uploads {
+			u.release()
+		}
+	}()
 	for i, file := range files {
-		data, err := readUploadedFile(file)
+		u, err := readUploadedFile(file)
 		if err != nil {
-			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+			c.JSON(http.StatusInternalServerError, versioning.ErrorBody(c, string(service.ErrCodeInternal), "Failed to read file", nil))
+			return
+		}
+		uploads = append(uploads, u)
+		names[i] = file.Filename
+	}
+
+	h.merge(c, uploads, names)
+}
+
+// mergeDocuments merges stored documents
+func (h *PDFHandler) mergeDocuments(c *gin.Context, ids []string) {
+	if len(ids) < 2 {
+		c.JSON(http.StatusBadRequest, versioning.ErrorBody(c, string(service.ErrCodeInvalidInput), "At least 2 PDFs required", nil))
+		return
+	}
+
+	uploads := make([]*upload, 0, len(ids))
+	defer func() {
+		for _, u := range uploads {
+			u.release()
+		}
+	}()
+	for _, id := range ids {
+		u, err := h.loadDocument(c, id)
+		if err != nil {
+			h.respondError(c, err, "Failed to load document")
+			return
+		}
+		uploads = append(uploads, u)
+	}
+
+	h.merge(c, uploads, ids)
+}
+
+// merge merges loaded inputs and writes the result. An optional manifest
+// in the "manifest" form field selects pages of the inputs, named by file
+// name or document ID, like
+// [{"file": "a.pdf", "pages": "1-3"}, {"file": "b.pdf", "pages": "5", "rotate": 90}].
+// Optional page labels in the "page_labels" form field label the result,
+// like [{"page": 1, "style": "lower-roman"}, {"page": 4, "style": "decimal"}].
+func (h *PDFHandler) merge(c *gin.Context, uploads []*upload, names []string) {
+	pdfs := make([][]byte, len(uploads))
+	for i, u := range uploads {
+		pdfs[i] = u.Bytes()
+	}
+
+	var manifest []service.MergeItem
+	if raw := c.PostForm("manifest"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &manifest); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "manifest must be a JSON array", err), "Invalid manifest")
+			return
+		}
+	}
+	var labels []service.PageLabelRange
+	if raw := c.PostForm("page_labels"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &labels); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "page_labels must be a JSON array", err), "Invalid page labels")
 			return
 		}
-		pdfs[i] = data
 	}
 
 	req := &service.MergeRequest{
-		PDFs: pdfs,
+		PDFs:       pdfs,
+		OutputName: outputFilename(c, "merged.pdf"),
+		InputNames: names,
+		Validate:   c.DefaultQuery("validate", "false") == "true",
+		Mode:       service.MergeMode(c.Query("mode")),
+		// Back sides scanned by turning the stack over come in reverse
+		ReverseSecond: c.Query("reverse") == "true",
+		Manifest:      manifest,
+		PageLabels:    labels,
 	}
 
-	result, err := h.service.MergePDFs(c.Request.Context(), req)
+	result, err := h.service.MergePDFs(h.requestContext(c), req)
+	for _, u := range uploads {
+		u.settle(err)
+	}
 	if err != nil {
-		h.log.Error("Failed to merge PDFs", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Merge failed"})
+		h.respondError(c, err, "Merge failed")
 		return
 	}
 
-	c.Data(http.StatusOK, "application/pdf", result)
+	h.respondPDF(c, result, req.OutputName)
 }
 
-// SplitPDF handles PDF splitting
+// SplitPDF handles PDF splitting, returning the parts base64-encoded in JSON
 func (h *PDFHandler) SplitPDF(c *gin.Context) {
-	file, err := c.FormFile("pdf")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+	result, ok := h.split(c)
+	if !ok {
 		return
 	}
 
-	pdfData, err := readUploadedFile(file)
+	c.JSON(http.StatusOK, gin.H{
+		"files":  result,
+		"sha256": checksums(result),
+		"count":  len(result),
+	})
+}
+
+// SplitPDFArchive handles PDF splitting from API v2 on, returning the parts
+// in a ZIP archive
+func (h *PDFHandler) SplitPDFArchive(c *gin.Context) {
+	password, err := archivePassword(c)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Invalid split request")
+		return
+	}
+	result, ok := h.split(c)
+	if !ok {
 		return
 	}
 
+	var buf bytes.Buffer
+	archive := securezip.NewWriter(&buf, password)
+	for i, part := range result {
+		// PDFs are already compressed, so entries are stored as is
+		w, err := archive.CreateHeader(&zip.FileHeader{
+			Name:     fmt.Sprintf("part-%04d.pdf", i+1),
+			Method:   zip.Store,
+			Modified: time.Now(),
+		})
+		if err == nil {
+			_, err = w.Write(part)
+		}
+		if err != nil {
+			h.respondError(c, err, "Failed to write archive")
+			return
+		}
+	}
+	if err := archive.Close(); err != nil {
+		h.respondError(c, err, "Failed to write archive")
+		return
+	}
+
+	c.Header("X-Part-Count", strconv.Itoa(len(result)))
+	respondFile(c, buf.Bytes(), "application/zip", "split.zip")
+}
+
+// split runs a split request, responding with the error if it fails
+func (h *PDFHandler) split(c *gin.Context) ([][]byte, bool) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return nil, false
+	}
+	defer upload.release()
+
 	req := &service.SplitRequest{
-		PDFData:   pdfData,
+		PDFData:   upload.Bytes(),
 		PageRange: c.DefaultQuery("pages", "all"),
 	}
 
-	result, err := h.service.SplitPDF(c.Request.Context(), req)
+	result, err := h.service.SplitPDF(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to split PDF", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Split failed"})
-		return
+		h.respondError(c, err, "Split failed")
+		return nil, false
 	}
-
-	c.JSON(http.StatusOK, gin.H{
-		"files": result,
-		"count": len(result),
-	})
+	return result, true
 }
 
 // ExtractText handles text extraction
 func (h *PDFHandler) ExtractText(c *gin.Context) {
-	file, err := c.FormFile("pdf")
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	req := &service.ExtractTextRequest{
+		PDFData:    pdfData,
+		UseOCR:     c.DefaultQuery("ocr", "false") == "true",
+		Searchable: c.DefaultQuery("searchable", "false") == "true",
+		Words:      c.DefaultQuery("words", "false") == "true",
+		Format:     service.OCRFormat(formatParam(c, overrides(c).OCRFormat, "")),
+		ICR:        c.Query("icr"),
+		Entities:   queryList(c, "entities"),
+	}
+	if c.DefaultQuery("chunk", "false") == "true" {
+		req.Chunking = &service.ChunkOptions{
+			Size:    parseIntParam(c, "chunk_size", 0),
+			Overlap: parseIntParam(c, "chunk_overlap", 0),
+			Unit:    service.ChunkUnit(c.Query("chunk_unit")),
+		}
+	}
+
+	result, err := h.service.ExtractText(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+		h.respondError(c, err, "Extraction failed")
 		return
 	}
 
-	pdfData, err := readUploadedFile(file)
+	if result.Document != nil {
+		attachment(c, outputFilename(c, "ocr"+req.Format.Extension()))
+		c.Data(http.StatusOK, req.Format.ContentType(), result.Document)
+		return
+	}
+	if result.SearchablePDF == nil {
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
+	// The searchable PDF is stored so it can be returned with the text
+	stored, err := h.results.Save(c.Request.Context(), result.SearchablePDF, "pdf")
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Failed to store result")
 		return
 	}
+	c.JSON(http.StatusOK, struct {
+		*service.ExtractTextResponse
+		SearchablePDF gin.H `json:"searchable_pdf"`
+	}{
+		ExtractTextResponse: result,
+		SearchablePDF: gin.H{
+			"result_id":    stored.ID,
+			"size":         stored.Size,
+			"content_type": stored.ContentType,
+			"sha256":       stored.SHA256,
+			"download_url": versioning.URL(c, "/results/"+stored.ID),
+		},
+	})
+}
 
-	req := &service.ExtractTextRequest{
-		PDFData: pdfData,
-		UseOCR:  c.DefaultQuery("ocr", "false") == "true",
+// ExtractInvoice handles invoice and receipt field extraction
+func (h *PDFHandler) ExtractInvoice(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
 	}
+	defer upload.release()
+	pdfData := upload.Bytes()
 
-	result, err := h.service.ExtractText(c.Request.Context(), req)
+	req := &service.ExtractInvoiceRequest{
+		PDFData:  pdfData,
+		Entities: queryList(c, "entities"),
+	}
+
+	result, err := h.service.ExtractInvoice(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to extract text", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Extraction failed"})
+		h.respondError(c, err, "Extraction failed")
 		return
 	}
 
@@ -168,119 +540,692 @@ func (h *PDFHandler) ExtractText(c *gin.Context) {
 
 // ExtractMetadata handles metadata extraction
 func (h *PDFHandler) ExtractMetadata(c *gin.Context) {
-	file, err := c.FormFile("pdf")
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.ExtractMetadata(h.requestContext(c), pdfData)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+		h.respondError(c, err, "Extraction failed")
 		return
 	}
 
-	pdfData, err := readUploadedFile(file)
+	c.JSON(http.StatusOK, result)
+}
+
+// SetPageLabels handles page labelling. The label ranges are a JSON array
+// in the "labels" form field, like
+// [{"page": 1, "style": "lower-roman"}, {"page": 4, "style": "decimal"}];
+// without it the document's labels are removed.
+func (h *PDFHandler) SetPageLabels(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.SetPageLabelsRequest{}
+	if raw := c.PostForm("labels"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req.Labels); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "labels must be a JSON array", err), "Invalid page labels")
+			return
+		}
+	}
+	req.PDFData = upload.Bytes()
+
+	result, err := h.service.SetPageLabels(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Setting page labels failed")
 		return
 	}
 
-	result, err := h.service.ExtractMetadata(c.Request.Context(), pdfData)
+	h.respondPDF(c, result, "labeled.pdf")
+}
+
+// ExtractLinks handles link extraction: the URI links of a document with
+// their page and anchor text. With ?validate=true every web link is
+// requested and dead links are flagged.
+func (h *PDFHandler) ExtractLinks(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.ExtractLinksRequest{
+		PDFData:  upload.Bytes(),
+		Validate: c.Query("validate") == "true",
+	}
+
+	result, err := h.service.ExtractLinks(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to extract metadata", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Extraction failed"})
+		h.respondError(c, err, "Link extraction failed")
 		return
 	}
 
 	c.JSON(http.StatusOK, result)
 }
 
-// CompressPDF handles PDF compression
-func (h *PDFHandler) CompressPDF(c *gin.Context) {
-	file, err := c.FormFile("pdf")
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+// CheckNavigation handles internal link and destination integrity checks
+func (h *PDFHandler) CheckNavigation(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
 		return
 	}
+	defer upload.release()
+	pdfData := upload.Bytes()
 
-	pdfData, err := readUploadedFile(file)
+	result, err := h.service.CheckNavigation(h.requestContext(c), pdfData)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Navigation check failed")
 		return
 	}
 
+	c.JSON(http.StatusOK, result)
+}
+
+// CompressPDF handles PDF compression
+func (h *PDFHandler) CompressPDF(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
 	req := &service.CompressRequest{
 		PDFData:          pdfData,
 		CompressionLevel: parseIntParam(c, "level", 1),
+		Profile:          c.Query("profile"),
 	}
 
-	result, err := h.service.CompressPDF(c.Request.Context(), req)
+	result, err := h.service.CompressPDF(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to compress PDF", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Compression failed"})
+		h.respondError(c, err, "Compression failed")
 		return
 	}
 
-	c.Data(http.StatusOK, "application/pdf", result)
+	// Documents compression cannot shrink come back unchanged
+	h.respondPDF(c, upload.detach(result), "compressed.pdf")
 }
 
-// AddWatermark handles watermark addition
+// AddWatermark handles watermark addition. Without text, the tenant's
+// watermark preset applies.
 func (h *PDFHandler) AddWatermark(c *gin.Context) {
-	file, err := c.FormFile("pdf")
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	req := service.NewWatermarkRequest(c.Request.Context(), pdfData)
+	if text, ok := c.GetQuery("text"); ok {
+		req.WatermarkText = text
+	}
+
+	result, err := h.service.AddWatermark(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "PDF file required"})
+		h.respondError(c, err, "Watermark failed")
 		return
 	}
 
-	pdfData, err := readUploadedFile(file)
+	h.respondPDF(c, result, "watermarked.pdf")
+}
+
+// RedactPII handles PII detection and redaction. With ?report=true the PII
+// found is returned as JSON instead of a redacted copy.
+func (h *PDFHandler) RedactPII(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	req := &service.RedactPIIRequest{
+		PDFData:    pdfData,
+		Entities:   queryList(c, "entities"),
+		ReportOnly: c.DefaultQuery("report", "false") == "true",
+	}
+
+	result, err := h.service.RedactPII(h.requestContext(c), req)
+	upload.settle(err)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read file"})
+		h.respondError(c, err, "Redaction failed")
 		return
 	}
 
-	req := &service.WatermarkRequest{
-		PDFData:       pdfData,
-		WatermarkText: c.DefaultQuery("text", "CONFIDENTIAL"),
-		Opacity:       0.3,
-		Rotation:      45,
-		FontSize:      48,
+	if req.ReportOnly {
+		c.JSON(http.StatusOK, result)
+		return
 	}
+	c.Header("X-Redacted-Entities", strconv.Itoa(len(result.Entities)))
+	h.respondPDF(c, result.PDF, "redacted.pdf")
+}
 
-	result, err := h.service.AddWatermark(c.Request.Context(), req)
+// CheckAccessibility handles PDF/UA accessibility checks
+func (h *PDFHandler) CheckAccessibility(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.CheckAccessibility(h.requestContext(c), pdfData)
+	upload.settle(err)
 	if err != nil {
-		h.log.Error("Failed to add watermark", "error", err)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Watermark failed"})
+		h.respondError(c, err, "Accessibility check failed")
 		return
 	}
 
-	c.Data(http.StatusOK, "application/pdf", result)
+	c.JSON(http.StatusOK, result)
 }
 
-// RotatePages, EncryptPDF, DecryptPDF, BatchProcess, BatchStatus
-// These are placeholder implementations
-func (h *PDFHandler) RotatePages(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Coming soon"})
+// AutoTag handles best-effort accessibility fixes. The number of PDF/UA
+// issues remaining afterwards is returned in a header.
+func (h *PDFHandler) AutoTag(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	req := &service.AutoTagRequest{
+		PDFData: pdfData,
+		Lang:    c.Query("lang"),
+	}
+
+	result, err := h.service.AutoTag(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Auto-tagging failed")
+		return
+	}
+
+	c.Header("X-Accessibility-Issues", strconv.Itoa(len(result.Report.Issues)))
+	h.respondPDF(c, result.PDF, "tagged.pdf")
 }
 
-func (h *PDFHandler) EncryptPDF(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Coming soon"})
+// SetAccessibilityProperties handles accessibility remediation. The
+// properties are a JSON object in the "properties" form field, like
+// {"lang": "en-US", "display_doc_title": true, "alt_text": {"1": "Logo"}}.
+func (h *PDFHandler) SetAccessibilityProperties(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.AccessibilityPropertiesRequest{}
+	if err := json.Unmarshal([]byte(c.PostForm("properties")), req); err != nil {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "properties must be a JSON object", err), "Invalid properties")
+		return
+	}
+	req.PDFData = upload.Bytes()
+
+	result, err := h.service.SetAccessibilityProperties(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Setting accessibility properties failed")
+		return
+	}
+
+	c.Header("X-Accessibility-Issues", strconv.Itoa(len(result.Report.Issues)))
+	h.respondPDF(c, result.PDF, "accessible.pdf")
+}
+
+// maxICCProfileSize bounds uploaded ICC profiles
+const maxICCProfileSize = 16 << 20
+
+// ConvertColor handles colour space conversion. An ICC profile is uploaded
+// in the "icc" form field or named with ?profile.
+func (h *PDFHandler) ConvertColor(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	icc, err := optionalFormFile(c, "icc", maxICCProfileSize)
+	if err != nil {
+		h.respondError(c, err, "Failed to read ICC profile")
+		return
+	}
+
+	req := &service.ConvertColorRequest{
+		PDFData:         upload.Bytes(),
+		ColorSpace:      service.ColorSpace(c.DefaultQuery("colorspace", "cmyk")),
+		Profile:         c.Query("profile"),
+		ICCProfile:      icc,
+		OutputCondition: c.Query("output_condition"),
+	}
+
+	result, err := h.service.ConvertColor(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Colour conversion failed")
+		return
+	}
+
+	h.respondPDF(c, result, "converted.pdf")
+}
+
+// CheckPDFX handles PDF/X conformance checks against ?standard, x1a or x4
+func (h *PDFHandler) CheckPDFX(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	standard := service.PDFXStandard(c.DefaultQuery("standard", "x1a"))
+	result, err := h.service.CheckPDFX(h.requestContext(c), pdfData, standard)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "PDF/X check failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ConvertPDFX handles PDF/X conversion. The output ICC profile is uploaded
+// in the "icc" form field or named with ?profile, and the number of PDF/X
+// issues remaining afterwards is returned in a header.
+func (h *PDFHandler) ConvertPDFX(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	icc, err := optionalFormFile(c, "icc", maxICCProfileSize)
+	if err != nil {
+		h.respondError(c, err, "Failed to read ICC profile")
+		return
+	}
+
+	req := &service.ConvertPDFXRequest{
+		PDFData:         upload.Bytes(),
+		Standard:        service.PDFXStandard(c.DefaultQuery("standard", "x1a")),
+		Profile:         c.Query("profile"),
+		ICCProfile:      icc,
+		OutputCondition: c.Query("output_condition"),
+	}
+
+	result, err := h.service.ConvertPDFX(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "PDF/X conversion failed")
+		return
+	}
+
+	c.Header("X-PDFX-Issues", strconv.Itoa(len(result.Report.Issues)))
+	h.respondPDF(c, result.PDF, "pdfx.pdf")
+}
+
+// EmbedFonts handles embedding of missing fonts. How many fonts were
+// embedded, substituted or left unresolved is returned in headers.
+func (h *PDFHandler) EmbedFonts(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.EmbedFonts(h.requestContext(c), pdfData)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Font embedding failed")
+		return
+	}
+
+	counts := make(map[string]int)
+	for _, font := range result.Fonts {
+		counts[font.Action]++
+	}
+	c.Header("X-Fonts-Embedded", strconv.Itoa(counts[service.FontEmbedded]))
+	c.Header("X-Fonts-Substituted", strconv.Itoa(counts[service.FontSubstituted]))
+	c.Header("X-Fonts-Unresolved", strconv.Itoa(counts[service.FontUnresolved]))
+	h.respondPDF(c, result.PDF, "fonts.pdf")
+}
+
+// OutlineText handles conversion of text to vector outlines
+func (h *PDFHandler) OutlineText(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.OutlineText(h.requestContext(c), pdfData)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Text outlining failed")
+		return
+	}
+
+	h.respondPDF(c, result, "outlined.pdf")
+}
+
+// FlattenTransparency handles transparency flattening. The number of
+// transparent objects found is returned in a header.
+func (h *PDFHandler) FlattenTransparency(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.FlattenTransparencyRequest{
+		PDFData: upload.Bytes(),
+		DPI:     parseIntParam(c, "dpi", 0),
+	}
+
+	result, err := h.service.FlattenTransparency(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Transparency flattening failed")
+		return
+	}
+
+	c.Header("X-Transparent-Objects", strconv.Itoa(result.Transparent))
+	// Documents without transparency come back unchanged
+	h.respondPDF(c, upload.detach(result.PDF), "flattened.pdf")
+}
+
+// AnalyzeInk handles ink coverage, overprint and spot colour analysis
+func (h *PDFHandler) AnalyzeInk(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.AnalyzeInk(h.requestContext(c), pdfData)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Ink analysis failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ConvertSpotColors handles spot to process colour conversion. A mapping
+// table may be given as a JSON object in the "mapping" form field, like
+// {"PANTONE 185 C": [0, 91, 76, 0]}. The number of spot colours converted,
+// and of those mapped, is returned in headers.
+func (h *PDFHandler) ConvertSpotColors(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.ConvertSpotColorsRequest{}
+	if raw := c.PostForm("mapping"); raw != "" {
+		if err := json.Unmarshal([]byte(raw), &req.Mapping); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "mapping must be a JSON object of CMYK percentages", err), "Invalid mapping")
+			return
+		}
+	}
+	req.PDFData = upload.Bytes()
+
+	result, err := h.service.ConvertSpotColors(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Spot colour conversion failed")
+		return
+	}
+
+	mapped := 0
+	for _, spot := range result.SpotColors {
+		if spot.Mapped {
+			mapped++
+		}
+	}
+	c.Header("X-Spot-Colors", strconv.Itoa(len(result.SpotColors)))
+	c.Header("X-Spot-Colors-Mapped", strconv.Itoa(mapped))
+	h.respondPDF(c, result.PDF, "process.pdf")
+}
+
+// ListICCProfiles handles listing the ICC profiles of colour spaces and
+// output intents
+func (h *PDFHandler) ListICCProfiles(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	result, err := h.service.ListICCProfiles(h.requestContext(c), pdfData)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "ICC profile listing failed")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// ExtractICCProfile handles extracting the ICC profile selected by the id
+// query parameter, as listed by ListICCProfiles
+func (h *PDFHandler) ExtractICCProfile(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+	id := parseIntParam(c, "id", 0)
+
+	result, err := h.service.ExtractICCProfile(h.requestContext(c), pdfData, id)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "ICC profile extraction failed")
+		return
+	}
+
+	respondFile(c, result, "application/vnd.iccprofile", fmt.Sprintf("profile-%d.icc", id))
 }
 
+// StripICCProfiles handles ICC profile and output intent stripping. Output
+// intents are kept with keep_output_intents=true; the number of colour
+// spaces and output intents stripped is returned in a header.
+func (h *PDFHandler) StripICCProfiles(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	req := &service.StripICCRequest{
+		PDFData:           upload.Bytes(),
+		KeepOutputIntents: c.Query("keep_output_intents") == "true",
+	}
+
+	result, err := h.service.StripICCProfiles(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "ICC profile stripping failed")
+		return
+	}
+
+	c.Header("X-ICC-Stripped", strconv.Itoa(result.Stripped))
+	// Documents without profiles to strip come back unchanged
+	h.respondPDF(c, upload.detach(result.PDF), "stripped.pdf")
+}
+
+// customReservedParams are query parameters the service handles itself,
+// not passed to custom processors
+var customReservedParams = map[string]bool{"document_id": true, "filename": true, "store": true}
+
+// RunCustom runs a custom processor named in the path. Query parameters are
+// passed to the processor; PDF output can be stored like other operations.
+func (h *PDFHandler) RunCustom(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	params := make(map[string]string)
+	for key, values := range c.Request.URL.Query() {
+		if !customReservedParams[key] && len(values) > 0 {
+			params[key] = values[0]
+		}
+	}
+
+	name := c.Param("name")
+	result, err := h.service.RunCustom(h.requestContext(c), name, params, upload.Bytes())
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Custom operation failed")
+		return
+	}
+
+	if result.ContentType == "application/pdf" {
+		h.respondPDF(c, result.Data, name+".pdf")
+		return
+	}
+	respondFile(c, result.Data, result.ContentType, name)
+}
+
+// DecryptPDF handles PDF decryption. The password is read from the
+// "password" form field, never the query string, so it stays out of access
+// logs. Wrong passwords are throttled per document and per client.
 func (h *PDFHandler) DecryptPDF(c *gin.Context) {
-	c.JSON(http.StatusNotImplemented, gin.H{"message": "Coming soon"})
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+	pdfData := upload.Bytes()
+
+	req := &service.DecryptRequest{
+		PDFData:  pdfData,
+		Password: c.PostForm("password"),
+		Client:   tenant.FromContext(c.Request.Context()) + "/" + c.ClientIP(),
+	}
+
+	result, err := h.service.DecryptPDF(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Decryption failed")
+		return
+	}
+
+	h.respondPDF(c, result, "decrypted.pdf")
 }
 
-func (h *PDFHandler) BatchProcess(c *gin.Context) {
+// RotatePages, EncryptPDF
+// These are placeholder implementations
+func (h *PDFHandler) RotatePages(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{"message": "Coming soon"})
 }
 
-func (h *PDFHandler) BatchStatus(c *gin.Context) {
+func (h *PDFHandler) EncryptPDF(c *gin.Context) {
 	c.JSON(http.StatusNotImplemented, gin.H{"message": "Coming soon"})
 }
 
 // Helper functions
-func readUploadedFile(file *multipart.FileHeader) ([]byte, error) {
+
+// inputPDF returns the input document of a single-document operation: the
+// stored document named by ?document_id, or else the uploaded "pdf" file.
+// On failure the error response has already been written.
+func (h *PDFHandler) inputPDF(c *gin.Context) (*upload, bool) {
+	if id := c.Query("document_id"); id != "" {
+		u, err := h.loadDocument(c, id)
+		if err != nil {
+			h.respondError(c, err, "Failed to load document")
+			return nil, false
+		}
+		return u, true
+	}
+
+	file, ok := h.uploadedPDF(c)
+	if !ok {
+		return nil, false
+	}
+
+	u, err := readUploadedFile(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, versioning.ErrorBody(c, string(service.ErrCodeInternal), "Failed to read file", nil))
+		return nil, false
+	}
+	return u, true
+}
+
+// openInput opens the request's input PDF for reading without buffering
+// it, like inputPDF. It responds and returns false if there is none.
+func (h *PDFHandler) openInput(c *gin.Context) (io.ReadCloser, bool) {
+	if id := c.Query("document_id"); id != "" {
+		obj, _, err := h.documents.Open(c.Request.Context(), id)
+		if err != nil {
+			h.respondError(c, err, "Failed to load document")
+			return nil, false
+		}
+		return obj, true
+	}
+
+	file, ok := h.uploadedPDF(c)
+	if !ok {
+		return nil, false
+	}
 	f, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, versioning.ErrorBody(c, string(service.ErrCodeInternal), "Failed to read file", nil))
+		return nil, false
+	}
+	return f, true
+}
+
+// loadDocument reads a stored document into a pooled buffer
+func (h *PDFHandler) loadDocument(c *gin.Context, id string) (*upload, error) {
+	obj, doc, err := h.documents.Open(c.Request.Context(), id)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
-	return io.ReadAll(f)
+	defer obj.Close()
+
+	u, err := readInput(obj, doc.Size)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read document: %w", err)
+	}
+	return u, nil
+}
+
+// requestContext returns the service context for a request, applying
+// limit overrides requested by privileged callers
+func (h *PDFHandler) requestContext(c *gin.Context) context.Context {
+	ctx := pipeline.WithNameObserver(c.Request.Context(), func(name string) {
+		c.Set(scriptNameKey, name)
+	})
+	if !c.GetBool(middleware.PrivilegedKey) {
+		return ctx
+	}
+	if maxPages := parseIntParam(c, "max_pages", 0); maxPages > 0 {
+		ctx = service.WithMaxPages(ctx, maxPages)
+	}
+	return ctx
 }
 
 func parseIntParam(c *gin.Context, key string, defaultValue int) int {
@@ -291,8 +1236,31 @@ func parseIntParam(c *gin.Context, key string, defaultValue int) int {
 	return value
 }
 
-import (
-	"io"
-	"mime/multipart"
-	"strconv"
-)
+// overrides returns the configuration overrides of the request's tenant
+func overrides(c *gin.Context) tenant.Overrides {
+	return tenant.SettingsFromContext(c.Request.Context()).Overrides
+}
+
+// formatParam returns the format query parameter, else the tenant's
+// default format, else fallback
+func formatParam(c *gin.Context, tenantDefault, fallback string) string {
+	if format, ok := c.GetQuery("format"); ok {
+		return format
+	}
+	if tenantDefault != "" {
+		return tenantDefault
+	}
+	return fallback
+}
+
+// queryList returns a comma-separated query parameter as a list, skipping
+// empty items
+func queryList(c *gin.Context, key string) []string {
+	var items []string
+	for _, item := range strings.Split(c.Query(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
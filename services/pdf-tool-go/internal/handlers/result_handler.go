@@ -0,0 +1,231 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/securezip"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// checksumHeader carries the hex SHA-256 of a response's output
+const checksumHeader = "X-Content-SHA256"
+
+// checksums returns the hex SHA-256 of each output
+func checksums(outputs [][]byte) []string {
+	sums := make([]string, len(outputs))
+	for i, output := range outputs {
+		sums[i] = service.Checksum(output)
+	}
+	return sums
+}
+
+// respondPDF writes a PDF operation output as a download called name, or
+// the file name requested with ?filename. With ?store=true the output is
+// persisted and a result descriptor is returned instead of the bytes; its
+// download URL carries the file name.
+func (h *PDFHandler) respondPDF(c *gin.Context, data []byte, name string) {
+	name = outputFilename(c, name)
+	if c.DefaultQuery("store", "false") != "true" {
+		c.Header(checksumHeader, service.Checksum(data))
+		attachment(c, name)
+		c.Data(http.StatusOK, "application/pdf", data)
+		return
+	}
+
+	result, err := h.results.Save(c.Request.Context(), data, "pdf")
+	if err != nil {
+		h.respondError(c, err, "Failed to store result")
+		return
+	}
+
+	c.Header(checksumHeader, result.SHA256)
+	c.JSON(http.StatusCreated, gin.H{
+		"result_id":    result.ID,
+		"size":         result.Size,
+		"content_type": result.ContentType,
+		"sha256":       result.SHA256,
+		"download_url": versioning.URL(c, "/results/"+result.ID) + "?filename=" + url.QueryEscape(name),
+	})
+}
+
+// respondFile writes an operation output of contentType as a download
+// called name, or the file name requested with ?filename
+func respondFile(c *gin.Context, data []byte, contentType, name string) {
+	c.Header(checksumHeader, service.Checksum(data))
+	attachment(c, outputFilename(c, name))
+	c.Data(http.StatusOK, contentType, data)
+}
+
+// archivePasswordHeader carries the password of an encrypted ZIP output,
+// chosen by the caller on the request
+const archivePasswordHeader = "X-Archive-Password"
+
+// minArchivePassword is the length of the shortest password callers may
+// choose
+const minArchivePassword = 8
+
+// encryptionRequested reports whether the caller asked for an encrypted
+// ZIP output
+func encryptionRequested(c *gin.Context) bool {
+	return c.GetHeader(archivePasswordHeader) != "" || c.Query("encrypt") == "true"
+}
+
+// archivePassword returns the caller's password to encrypt a ZIP output
+// with, or none. Passwords are never generated: one returned next to the
+// archive would reach whoever reads the response, so ?encrypt=true only
+// insists on encryption and fails without a password.
+func archivePassword(c *gin.Context) (string, error) {
+	password := c.GetHeader(archivePasswordHeader)
+	switch {
+	case password == "" && c.Query("encrypt") == "true":
+		return "", service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("encrypt=true requires a password in %s", archivePasswordHeader), nil)
+	case password == "":
+		return "", nil
+	case len(password) < minArchivePassword:
+		return "", service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("%s must be at least %d characters", archivePasswordHeader, minArchivePassword), nil)
+	}
+	c.Header("Cache-Control", "private, no-store")
+	return password, nil
+}
+
+// scriptNameKey holds the output name a tenant script chose
+const scriptNameKey = "script_output_name"
+
+// outputFilename returns the file name requested with ?filename, or else
+// chosen by a tenant script, reduced to its base name, or fallback. Names
+// without an extension get fallback's.
+func outputFilename(c *gin.Context, fallback string) string {
+	requested := c.Query("filename")
+	if requested == "" {
+		requested = c.GetString(scriptNameKey)
+	}
+	name := strings.Map(func(r rune) rune {
+		if r < 0x20 || r == 0x7f {
+			return -1
+		}
+		return r
+	}, requested)
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+	name = strings.TrimSpace(name)
+	if name == "" || name == "." || name == ".." {
+		return fallback
+	}
+	if path.Ext(name) == "" {
+		name += path.Ext(fallback)
+	}
+	return name
+}
+
+// attachment sets a Content-Disposition header offering the response as a
+// download called name. Names that are not plain ASCII are also sent RFC
+// 5987 encoded in filename*, with an ASCII fallback in filename for
+// clients that ignore it.
+func attachment(c *gin.Context, name string) {
+	fallback := strings.Map(func(r rune) rune {
+		if r < 0x20 || r > 0x7e || r == '"' || r == '\\' || r == '%' {
+			return '_'
+		}
+		return r
+	}, name)
+	if fallback == name {
+		c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, name))
+		return
+	}
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"; filename*=UTF-8''%s`, fallback, encodeRFC5987(name)))
+}
+
+// encodeRFC5987 percent-encodes s as an RFC 5987 ext-value, leaving only
+// attr-chars unescaped
+func encodeRFC5987(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		ch := s[i]
+		if ('a' <= ch && ch <= 'z') || ('A' <= ch && ch <= 'Z') || ('0' <= ch && ch <= '9') || strings.IndexByte("!#$&+-.^_`|~", ch) >= 0 {
+			b.WriteByte(ch)
+			continue
+		}
+		fmt.Fprintf(&b, "%%%02X", ch)
+	}
+	return b.String()
+}
+
+// DownloadResult streams a stored result from storage with
+// http.ServeContent, without buffering it in memory. Byte-range requests
+// (including If-Range revalidation against the ETag) are honoured so PDF
+// viewers can fetch linearized documents progressively. Result IDs are
+// content hashes, so a client holding a SHA-256 can fetch or HEAD
+// /results/<sha256>.<ext> directly. With ?filename the result is offered
+// as a download by that name.
+func (h *PDFHandler) DownloadResult(c *gin.Context) {
+	obj, result, err := h.results.Open(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to open result")
+		return
+	}
+	defer obj.Close()
+
+	if c.Query("filename") != "" {
+		attachment(c, outputFilename(c, result.ID))
+	}
+	if encryptionRequested(c) {
+		h.serveEncrypted(c, obj, result)
+		return
+	}
+	if policy := downloadWatermark(c, result); policy != nil {
+		if h.serveWatermarked(c, obj, policy, downloadUser(c, "")) {
+			h.results.Downloaded(c.Request.Context(), result.ID)
+		}
+		return
+	}
+	if serveStored(c, obj, result) {
+		h.results.Downloaded(c.Request.Context(), result.ID)
+	}
+}
+
+// serveEncrypted sends a stored ZIP result repacked with its entries
+// encrypted. Encryption salts differ on every download, so ranges and
+// validators do not apply.
+func (h *PDFHandler) serveEncrypted(c *gin.Context, obj storage.Object, result *service.Result) {
+	if result.ContentType != "application/zip" {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "only ZIP results can be encrypted", nil), "Invalid download request")
+		return
+	}
+	password, err := archivePassword(c)
+	if err != nil {
+		h.respondError(c, err, "Invalid download request")
+		return
+	}
+	data, err := io.ReadAll(obj)
+	if err == nil {
+		data, err = securezip.Encrypt(data, password)
+	}
+	if err != nil {
+		h.respondError(c, err, "Failed to encrypt result")
+		return
+	}
+
+	c.Header(checksumHeader, service.Checksum(data))
+	c.Data(http.StatusOK, "application/zip", data)
+	h.results.Downloaded(c.Request.Context(), result.ID)
+}
+
+// DeleteResult removes a stored result
+func (h *PDFHandler) DeleteResult(c *gin.Context) {
+	if err := h.results.Delete(c.Request.Context(), c.Param("id")); err != nil {
+		h.respondError(c, err, "Failed to delete result")
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,60 @@
+package handlers
+
+import (
+	"fmt"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/securezip"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// sectionPasswordHeader returns the password generated for a secured
+// section when the caller chose none
+const sectionPasswordHeader = "X-Section-Password"
+
+// SecureSection handles secure sections: the pages selected with ?pages
+// move into an encrypted PDF attached to the rest of the document. The
+// password is the "password" form field, or else generated and returned
+// in the X-Section-Password header. ?attachment names the attachment.
+func (h *PDFHandler) SecureSection(c *gin.Context) {
+	upload, ok := h.inputPDF(c)
+	if !ok {
+		return
+	}
+	defer upload.release()
+
+	password := c.PostForm("password")
+	generated := password == ""
+	if generated {
+		var err error
+		if password, err = securezip.GeneratePassword(); err != nil {
+			h.respondError(c, fmt.Errorf("failed to generate section password: %w", err), "Securing section failed")
+			return
+		}
+	} else if len(password) < minArchivePassword {
+		h.respondError(c, service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("password must be at least %d characters", minArchivePassword), nil), "Invalid password")
+		return
+	}
+
+	req := &service.SecureSectionRequest{
+		PDFData:        upload.Bytes(),
+		Pages:          c.Query("pages"),
+		Password:       password,
+		AttachmentName: c.Query("attachment"),
+	}
+
+	result, err := h.service.SecureSection(h.requestContext(c), req)
+	upload.settle(err)
+	if err != nil {
+		h.respondError(c, err, "Securing section failed")
+		return
+	}
+
+	if generated {
+		c.Header(sectionPasswordHeader, password)
+	}
+	c.Header("Cache-Control", "private, no-store")
+
+	h.respondPDF(c, result, "secured.pdf")
+}
@@ -0,0 +1,122 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/share"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
+)
+
+// sharePasswordHeader carries the password of a protected share link
+const sharePasswordHeader = "X-Share-Password"
+
+// shareRequest is the body of a share link request; all fields are optional
+type shareRequest struct {
+	// ExpiresIn is the lifetime of the link in seconds
+	ExpiresIn    int    `json:"expires_in"`
+	MaxDownloads int    `json:"max_downloads"`
+	Password     string `json:"password"`
+	// Recipient names who the link is for, stamped into the tenant's
+	// download watermark
+	Recipient string `json:"recipient"`
+}
+
+// SetShareLinks enables share links to stored results, minted and resolved
+// by links, applying the policies of tenants as resolved. It must be
+// called before the handler serves requests.
+func (h *PDFHandler) SetShareLinks(links *share.Links, tenants *tenant.Resolver) {
+	h.shares = links
+	h.tenants = tenants
+}
+
+// ShareResult mints a signed, expiring link to a stored result of the
+// caller's tenant. The link downloads the result without an API key, so it
+// can be handed to end users directly.
+func (h *PDFHandler) ShareResult(c *gin.Context) {
+	var req shareRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.respondError(c, service.NewError(service.ErrCodeInvalidInput, "invalid share request", err), "Invalid share request")
+			return
+		}
+	}
+
+	ctx := c.Request.Context()
+	obj, result, err := h.results.Open(ctx, c.Param("id"))
+	if err != nil {
+		h.respondError(c, err, "Failed to open result")
+		return
+	}
+	obj.Close()
+
+	token, link, err := h.shares.Mint(tenant.FromContext(ctx), result.ID, share.Options{
+		TTL:          time.Duration(req.ExpiresIn) * time.Second,
+		MaxDownloads: req.MaxDownloads,
+		Password:     req.Password,
+		Recipient:    req.Recipient,
+	})
+	if err != nil {
+		h.respondError(c, err, "Failed to create share link")
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"token":         token,
+		"url":           versioning.URL(c, "/share/"+token),
+		"expires_at":    link.ExpiresAt().UTC(),
+		"max_downloads": link.MaxDownloads,
+		"protected":     link.Password != "",
+		"recipient":     link.Recipient,
+	})
+}
+
+// DownloadShared streams the result a share link points to. The password
+// of a protected link is sent in the X-Share-Password header or with
+// ?password. Requests for the whole result, or a range from its first
+// byte, count against the link's download limit; later ranges of a
+// progressive download do not.
+func (h *PDFHandler) DownloadShared(c *gin.Context) {
+	password := c.GetHeader(sharePasswordHeader)
+	if password == "" {
+		password = c.Query("password")
+	}
+	rangeHeader := c.GetHeader("Range")
+	download := c.Request.Method == http.MethodGet &&
+		(rangeHeader == "" || strings.HasPrefix(rangeHeader, "bytes=0-"))
+
+	link, err := h.shares.Resolve(c.Request.Context(), c.Param("token"), password, download)
+	if err != nil {
+		h.respondError(c, err, "Failed to resolve share link")
+		return
+	}
+
+	// Share links are downloaded without an API key, so the request
+	// carries the link's tenant rather than the default one
+	ctx := tenant.WithSettings(tenant.WithID(c.Request.Context(), link.Tenant), h.tenants.Settings(link.Tenant))
+	obj, result, err := h.results.Open(ctx, link.Result)
+	if err != nil {
+		h.respondError(c, err, "Failed to open result")
+		return
+	}
+	defer obj.Close()
+
+	c.Request = c.Request.WithContext(ctx)
+	if c.Query("filename") != "" {
+		attachment(c, outputFilename(c, result.ID))
+	}
+	c.Header("Cache-Control", "private, no-store")
+	if policy := downloadWatermark(c, result); policy != nil {
+		if h.serveWatermarked(c, obj, policy, downloadUser(c, link.Recipient)) {
+			h.results.Downloaded(ctx, result.ID)
+		}
+		return
+	}
+	if serveStored(c, obj, result) {
+		h.results.Downloaded(ctx, result.ID)
+	}
+}
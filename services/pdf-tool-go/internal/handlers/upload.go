@@ -0,0 +1,139 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/bufferpool"
+)
+
+// upload is an uploaded file read into a pooled buffer
+type upload struct {
+	buf       *bytes.Buffer
+	abandoned bool
+}
+
+// readUploadedFile reads a multipart file into a pooled buffer sized up front
+// from the part header, avoiding the repeated grow-and-copy of io.ReadAll
+func readUploadedFile(file *multipart.FileHeader) (*upload, error) {
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return readInput(f, file.Size)
+}
+
+// readInput reads r, whose size is known up front, into a pooled buffer
+func readInput(r io.Reader, size int64) (*upload, error) {
+	buf := bufferpool.Get()
+	if size > 0 {
+		buf.Grow(int(size) + bytes.MinRead)
+	}
+	if _, err := buf.ReadFrom(r); err != nil {
+		bufferpool.Put(buf)
+		return nil, err
+	}
+	return &upload{buf: buf}, nil
+}
+
+// Bytes returns the uploaded data. It is only valid until release.
+func (u *upload) Bytes() []byte {
+	return u.buf.Bytes()
+}
+
+// detach returns output that outlives the upload: a copy when output is
+// the uploaded data itself, as operations return documents they leave
+// unchanged, and output otherwise
+func (u *upload) detach(output []byte) []byte {
+	if u.buf == nil || len(output) == 0 {
+		return output
+	}
+	if data := u.buf.Bytes(); len(data) > 0 && &data[0] == &output[0] {
+		return bytes.Clone(output)
+	}
+	return output
+}
+
+// settle records the outcome of the service call that used the upload.
+// Timed out or cancelled operations may still be reading the data in the
+// background, so their buffers are left to the GC instead of being reused.
+func (u *upload) settle(err error) {
+	switch service.CodeOf(err) {
+	case service.ErrCodeTimeout, service.ErrCodeCanceled:
+		u.abandoned = true
+	}
+}
+
+// release returns the buffer to the pool
+func (u *upload) release() {
+	if u.abandoned {
+		return
+	}
+	bufferpool.Put(u.buf)
+	u.buf = nil
+}
+
+// uploadError maps a failure to parse a multipart upload to a service
+// error. Bodies cut off by the request size limit are FILE_TOO_LARGE.
+func uploadError(err error, message string) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return service.NewError(service.ErrCodeFileTooLarge, fmt.Sprintf("request body too large (max %d bytes)", tooLarge.Limit), err)
+	}
+	return service.NewError(service.ErrCodeInvalidInput, message, err)
+}
+
+// uploadedPDF returns the "pdf" upload of a request, rejecting files over
+// the size limit before they are read. On failure the error response has
+// already been written.
+func (h *PDFHandler) uploadedPDF(c *gin.Context) (*multipart.FileHeader, bool) {
+	file, err := c.FormFile("pdf")
+	if err != nil {
+		h.respondError(c, uploadError(err, "PDF file required"), "Invalid upload")
+		return nil, false
+	}
+	if err := h.checkUploadSize(c.Request.Context(), file); err != nil {
+		h.respondError(c, err, "Invalid upload")
+		return nil, false
+	}
+	return file, true
+}
+
+// checkUploadSize rejects uploaded files over the size limit of the
+// tenant of ctx
+func (h *PDFHandler) checkUploadSize(ctx context.Context, file *multipart.FileHeader) error {
+	if limit := h.service.MaxFileSize(ctx); file.Size > limit {
+		return service.NewError(service.ErrCodeFileTooLarge, fmt.Sprintf("PDF file too large: %d bytes (max %d)", file.Size, limit), nil)
+	}
+	return nil
+}
+
+// optionalFormFile reads a small auxiliary upload, such as an ICC profile,
+// returning nil if the field is absent. Files over limit bytes are
+// rejected.
+func optionalFormFile(c *gin.Context, field string, limit int64) ([]byte, error) {
+	file, err := c.FormFile(field)
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, uploadError(err, fmt.Sprintf("invalid %s upload", field))
+	}
+	if file.Size > limit {
+		return nil, service.NewError(service.ErrCodeFileTooLarge, fmt.Sprintf("%s upload is too large (max %d bytes)", field, limit), nil)
+	}
+	f, err := file.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return io.ReadAll(io.LimitReader(f, limit))
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/bufferpool"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpload(t *testing.T) {
+	data := "%PDF-1.7 uploaded document"
+
+	t.Run("Detached Input Survives Release", func(t *testing.T) {
+		u, err := readInput(strings.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+
+		output := u.detach(u.Bytes())
+		assert.NotSame(t, &u.Bytes()[0], &output[0])
+		u.release()
+
+		// Reuse pooled buffers until the released one comes back
+		for i := 0; i < 10; i++ {
+			buf := bufferpool.Get()
+			buf.WriteString(strings.Repeat("x", len(data)))
+			defer bufferpool.Put(buf)
+		}
+		assert.Equal(t, data, string(output))
+	})
+
+	t.Run("Other Output Is Not Copied", func(t *testing.T) {
+		u, err := readInput(strings.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+		defer u.release()
+
+		output := []byte("%PDF-1.7 rewritten document")
+		assert.Same(t, &output[0], &u.detach(output)[0])
+		assert.Empty(t, u.detach(nil))
+	})
+
+	t.Run("Abandoned Buffers Are Not Pooled", func(t *testing.T) {
+		u, err := readInput(strings.NewReader(data), int64(len(data)))
+		require.NoError(t, err)
+
+		u.settle(service.NewError(service.ErrCodeTimeout, "processing deadline exceeded", nil))
+		u.release()
+		assert.NotNil(t, u.buf)
+		assert.True(t, bytes.Equal([]byte(data), u.Bytes()))
+	})
+}
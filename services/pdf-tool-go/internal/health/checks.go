@@ -0,0 +1,75 @@
+package health
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+)
+
+// Lifecycle fails once the instance is shutting down, so readiness flips
+// before draining starts
+func Lifecycle(drainer *lifecycle.Drainer) Check {
+	return NewCheck("lifecycle", func(ctx context.Context) error {
+		if !drainer.Ready() {
+			return errors.New("shutting down")
+		}
+		return nil
+	})
+}
+
+// probeKey is looked up in storage to verify connectivity; it never exists
+const probeKey = "health/probe"
+
+// TempDir verifies that the temp directory is writable
+func TempDir(dir string) Check {
+	return NewCheck("temp_dir", func(ctx context.Context) error {
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			return err
+		}
+		f, err := os.CreateTemp(dir, "health-*")
+		if err != nil {
+			return fmt.Errorf("temp dir not writable: %w", err)
+		}
+		name := f.Name()
+		f.Close()
+		return os.Remove(name)
+	})
+}
+
+// Storage verifies that the object store is reachable
+func Storage(store storage.Store) Check {
+	return NewCheck("storage", func(ctx context.Context) error {
+		_, err := store.Stat(ctx, probeKey)
+		if err == nil || errors.Is(err, storage.ErrNotFound) {
+			return nil
+		}
+		return err
+	})
+}
+
+// Database verifies that the database is reachable
+func Database(db *sql.DB) Check {
+	return NewCheck("database", func(ctx context.Context) error {
+		return db.PingContext(ctx)
+	})
+}
+
+// Redis verifies that the Redis server shared rate limits are kept in is
+// reachable
+func Redis(limiter *ratelimit.Distributed) Check {
+	return NewCheck("redis", limiter.Ping)
+}
+
+// Tool verifies that an external tool is allow-listed and installed
+func Tool(runner *exec.Runner, tool string) Check {
+	return NewCheck("tool_"+tool, func(ctx context.Context) error {
+		return runner.Available(tool)
+	})
+}
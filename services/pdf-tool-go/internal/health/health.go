@@ -0,0 +1,132 @@
+/**
+ * Health Checks
+ *
+ * Dependency checks backing the readiness probe.
+ */
+
+package health
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Check verifies a single dependency
+type Check interface {
+	Name() string
+	Check(ctx context.Context) error
+}
+
+// checkFunc adapts a function to the Check interface
+type checkFunc struct {
+	name string
+	fn   func(ctx context.Context) error
+}
+
+func (c checkFunc) Name() string                    { return c.name }
+func (c checkFunc) Check(ctx context.Context) error { return c.fn(ctx) }
+
+// NewCheck creates a named check from a function
+func NewCheck(name string, fn func(ctx context.Context) error) Check {
+	return checkFunc{name: name, fn: fn}
+}
+
+// Status values reported per dependency
+const (
+	StatusUp       = "up"
+	StatusDown     = "down"
+	StatusDegraded = "degraded"
+)
+
+// Result is the outcome of one check
+type Result struct {
+	Name     string `json:"name"`
+	Status   string `json:"status"`
+	Critical bool   `json:"critical"`
+	Error    string `json:"error,omitempty"`
+	Latency  string `json:"latency"`
+}
+
+// Report is the outcome of all checks
+type Report struct {
+	Ready  bool     `json:"ready"`
+	Checks []Result `json:"checks"`
+}
+
+type registration struct {
+	check    Check
+	critical bool
+}
+
+// Checker runs registered dependency checks
+type Checker struct {
+	mu      sync.RWMutex
+	checks  []registration
+	timeout time.Duration
+}
+
+// NewChecker creates a checker applying timeout to each check
+func NewChecker(timeout time.Duration) *Checker {
+	return &Checker{timeout: timeout}
+}
+
+// Register adds a check. Failing critical checks make the service not ready;
+// failing non-critical checks are reported as degraded.
+func (c *Checker) Register(check Check, critical bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.checks = append(c.checks, registration{check: check, critical: critical})
+}
+
+// Run executes all checks concurrently
+func (c *Checker) Run(ctx context.Context) Report {
+	c.mu.RLock()
+	checks := append([]registration(nil), c.checks...)
+	c.mu.RUnlock()
+
+	results := make([]Result, len(checks))
+	var wg sync.WaitGroup
+	for i, reg := range checks {
+		wg.Add(1)
+		go func(i int, reg registration) {
+			defer wg.Done()
+			results[i] = c.run(ctx, reg)
+		}(i, reg)
+	}
+	wg.Wait()
+
+	report := Report{Ready: true, Checks: results}
+	for _, result := range results {
+		if result.Status == StatusDown {
+			report.Ready = false
+		}
+	}
+	return report
+}
+
+func (c *Checker) run(ctx context.Context, reg registration) Result {
+	if c.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, c.timeout)
+		defer cancel()
+	}
+
+	start := time.Now()
+	err := reg.check.Check(ctx)
+	result := Result{
+		Name:     reg.check.Name(),
+		Status:   StatusUp,
+		Critical: reg.critical,
+		Latency:  time.Since(start).String(),
+	}
+
+	if err != nil {
+		result.Error = err.Error()
+		result.Status = StatusDegraded
+		if reg.critical {
+			result.Status = StatusDown
+		}
+	}
+	return result
+}
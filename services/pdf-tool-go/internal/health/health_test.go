@@ -0,0 +1,44 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker_Run(t *testing.T) {
+	ok := NewCheck("ok", func(ctx context.Context) error { return nil })
+	failing := NewCheck("failing", func(ctx context.Context) error { return errors.New("unreachable") })
+
+	t.Run("All Up", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.Register(ok, true)
+
+		report := c.Run(context.Background())
+		assert.True(t, report.Ready)
+		assert.Equal(t, StatusUp, report.Checks[0].Status)
+	})
+
+	t.Run("Critical Down", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.Register(ok, true)
+		c.Register(failing, true)
+
+		report := c.Run(context.Background())
+		assert.False(t, report.Ready)
+		assert.Equal(t, StatusDown, report.Checks[1].Status)
+		assert.Equal(t, "unreachable", report.Checks[1].Error)
+	})
+
+	t.Run("Non-Critical Degraded", func(t *testing.T) {
+		c := NewChecker(time.Second)
+		c.Register(failing, false)
+
+		report := c.Run(context.Background())
+		assert.True(t, report.Ready)
+		assert.Equal(t, StatusDegraded, report.Checks[0].Status)
+	})
+}
@@ -0,0 +1,176 @@
+/**
+ * Handwriting Recognition
+ *
+ * ICR backends for documents containing handwriting: an HTTP backend for
+ * cloud vision APIs (directly or through an adapter) and a command backend
+ * running a local model as a sandboxed external tool. Both exchange the
+ * same JSON word list.
+ */
+
+package icr
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/breaker"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// maxResponseBytes bounds backend responses
+const maxResponseBytes = 16 << 20
+
+// response is the word list backends return for a page
+type response struct {
+	Words []struct {
+		Text string `json:"text"`
+		// Confidence is from 0 to 100
+		Confidence float64 `json:"confidence"`
+		Left       int     `json:"left"`
+		Top        int     `json:"top"`
+		Width      int     `json:"width"`
+		Height     int     `json:"height"`
+	} `json:"words"`
+}
+
+// New creates the backend described by cfg. Command backends run through
+// runner, so their tool must be allow-listed in the sandbox, and share its
+// breaker for the tool; HTTP backends get a breaker of their own.
+func New(cfg config.ICRBackend, runner *exec.Runner, breakers config.BreakerConfig, log logger.Logger) (service.Recognizer, error) {
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	switch cfg.Type {
+	case "http":
+		return &HTTPRecognizer{
+			url:        cfg.URL,
+			token:      cfg.Token,
+			client:     &http.Client{Timeout: timeout},
+			dependency: "icr:" + cfg.Name,
+			breakers:   breaker.New(breakers, log, isBackendFailure),
+		}, nil
+	case "command":
+		return &CommandRecognizer{runner: runner, tool: cfg.Tool, timeout: timeout}, nil
+	default:
+		return nil, fmt.Errorf("unsupported ICR backend type: %s", cfg.Type)
+	}
+}
+
+// HTTPRecognizer posts page images to an HTTP endpoint as image/png, with
+// the page resolution in the X-Image-DPI header, and reads the word list
+// from the JSON response
+type HTTPRecognizer struct {
+	url        string
+	token      string
+	client     *http.Client
+	dependency string
+	breakers   *breaker.Set
+}
+
+// isBackendFailure reports whether err means the backend is failing rather
+// than the caller giving up
+func isBackendFailure(err error) bool {
+	return !errors.Is(err, context.Canceled)
+}
+
+// Recognize implements service.Recognizer. While the backend's breaker is
+// open it fails with an error wrapping breaker.ErrOpen.
+func (r *HTTPRecognizer) Recognize(ctx context.Context, image []byte, dpi int) (words []service.OCRWord, err error) {
+	err = r.breakers.Do(r.dependency, func() error {
+		words, err = r.recognize(ctx, image, dpi)
+		return err
+	})
+	return words, err
+}
+
+// recognize posts one page image
+func (r *HTTPRecognizer) recognize(ctx context.Context, image []byte, dpi int) ([]service.OCRWord, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, r.url, bytes.NewReader(image))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "image/png")
+	req.Header.Set("X-Image-DPI", strconv.Itoa(dpi))
+	if r.token != "" {
+		req.Header.Set("Authorization", "Bearer "+r.token)
+	}
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ICR response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, service.NewError(service.ErrCodeBusy, "handwriting recognition backend is busy", nil)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("ICR backend returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return decode(body)
+}
+
+// CommandRecognizer runs a local model as "<tool> page.png <dpi>" and reads
+// the word list from its standard output
+type CommandRecognizer struct {
+	runner  *exec.Runner
+	tool    string
+	timeout time.Duration
+}
+
+// Recognize implements service.Recognizer
+func (r *CommandRecognizer) Recognize(ctx context.Context, image []byte, dpi int) ([]service.OCRWord, error) {
+	ws, err := r.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	if _, err := ws.WriteFile("page.png", image); err != nil {
+		return nil, err
+	}
+	result, err := r.runner.Run(ctx, ws, exec.Command{
+		Tool:    r.tool,
+		Args:    []string{"page.png", strconv.Itoa(dpi)},
+		Timeout: r.timeout,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return decode(result.Stdout)
+}
+
+// decode parses a backend word list
+func decode(data []byte) ([]service.OCRWord, error) {
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid ICR response: %w", err)
+	}
+	words := make([]service.OCRWord, 0, len(resp.Words))
+	for _, w := range resp.Words {
+		if w.Text == "" {
+			continue
+		}
+		words = append(words, service.OCRWord{
+			Text:       w.Text,
+			Confidence: w.Confidence,
+			Left:       w.Left,
+			Top:        w.Top,
+			Width:      w.Width,
+			Height:     w.Height,
+		})
+	}
+	return words, nil
+}
@@ -0,0 +1,64 @@
+package janitor
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// adminPath is the admin path of the temp directory API
+const adminPath = "/admin/temp"
+
+// usageReport is the temp directory usage as reported to operators
+type usageReport struct {
+	Dir             string `json:"dir"`
+	UsageBytes      int64  `json:"usage_bytes"`
+	Files           int    `json:"files"`
+	AlertBytes      int64  `json:"alert_bytes"`
+	SweepTTLSeconds int    `json:"sweep_ttl_seconds"`
+}
+
+// Handler serves the temp directory on the admin listener at /admin/temp/:
+// GET reports its usage and POST sweep removes orphaned entries now, as a
+// periodic sweep would
+func (j *Janitor) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, adminPath), "/")
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			size, files := j.usage()
+			writeJSON(w, http.StatusOK, usageReport{
+				Dir:             j.dir,
+				UsageBytes:      size,
+				Files:           files,
+				AlertBytes:      j.cfg.AlertBytes,
+				SweepTTLSeconds: j.cfg.TTL,
+			})
+
+		case rest == "sweep" && r.Method == http.MethodPost:
+			if j.cfg.TTL <= 0 {
+				// Without a TTL every entry, including those of running
+				// operations, would be swept
+				http.Error(w, "janitor.ttl is not configured", http.StatusConflict)
+				return
+			}
+			result, err := j.Sweep()
+			if err != nil {
+				j.log.Error("Temp directory sweep failed", "error", err)
+				http.Error(w, "sweep failed", http.StatusInternalServerError)
+				return
+			}
+			writeJSON(w, http.StatusOK, result)
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
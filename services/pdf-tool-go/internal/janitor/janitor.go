@@ -0,0 +1,235 @@
+/**
+ * Temp Directory Janitor
+ *
+ * Sweeps orphaned temp files left behind by crashed or killed operations
+ * and reports temp directory disk usage.
+ */
+
+package janitor
+
+import (
+	"context"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var (
+	tempDirBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pdf_tool_temp_dir_usage_bytes",
+		Help: "Disk space used by the PDF temp directory",
+	})
+	tempDirFiles = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pdf_tool_temp_dir_files",
+		Help: "Number of files in the PDF temp directory",
+	})
+	tempDirAlertBytes = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pdf_tool_temp_dir_alert_threshold_bytes",
+		Help: "Configured temp directory usage alert threshold",
+	})
+	removedTotal = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pdf_tool_temp_files_removed_total",
+		Help: "Orphaned temp entries removed by the janitor",
+	})
+)
+
+// SweepResult summarises a single sweep
+type SweepResult struct {
+	Removed    int   `json:"removed"`
+	UsageBytes int64 `json:"usage_bytes"`
+	Files      int   `json:"files"`
+}
+
+// Janitor periodically removes orphaned temp files
+type Janitor struct {
+	dir string
+	cfg config.JanitorConfig
+	log logger.Logger
+	// protected are directories inside dir holding the files of running
+	// operations
+	protected []protection
+}
+
+// protection is a directory swept entry by entry, keeping entries in use
+type protection struct {
+	dir   string
+	inUse func(path string) bool
+}
+
+// New creates a janitor for the given temp directory
+func New(dir string, cfg config.JanitorConfig, log logger.Logger) *Janitor {
+	tempDirAlertBytes.Set(float64(cfg.AlertBytes))
+	return &Janitor{
+		dir: dir,
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// Protect keeps a directory inside the temp directory whose entries belong
+// to running operations, such as the sandbox work directory: sweeps remove
+// its old entries one by one, never the directory itself, and keep entries
+// inUse reports as belonging to a running operation however old they are.
+// Protect must be called before Start.
+func (j *Janitor) Protect(dir string, inUse func(path string) bool) {
+	j.protected = append(j.protected, protection{dir: filepath.Clean(dir), inUse: inUse})
+}
+
+// Start performs the startup cleanup and runs periodic sweeps until ctx is done
+func (j *Janitor) Start(ctx context.Context) {
+	if j.cfg.CleanOnStartup {
+		// Nothing can be in flight before the server starts, so every entry is an orphan
+		if result, err := j.sweep(0); err != nil {
+			j.log.Error("Startup temp cleanup failed", "error", err)
+		} else {
+			j.log.Info("Startup temp cleanup completed", "removed", result.Removed)
+		}
+	}
+
+	interval := time.Duration(j.cfg.Interval) * time.Second
+	if interval <= 0 {
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := j.Sweep(); err != nil {
+					j.log.Error("Temp directory sweep failed", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Sweep removes temp entries older than the configured TTL
+func (j *Janitor) Sweep() (SweepResult, error) {
+	return j.sweep(time.Duration(j.cfg.TTL) * time.Second)
+}
+
+// sweep removes top-level temp entries older than ttl and refreshes usage metrics
+func (j *Janitor) sweep(ttl time.Duration) (SweepResult, error) {
+	var result SweepResult
+
+	entries, err := os.ReadDir(j.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			j.record(result)
+			return result, nil
+		}
+		return result, err
+	}
+
+	result.Removed = j.sweepEntries(j.dir, entries, time.Now().Add(-ttl))
+	removedTotal.Add(float64(result.Removed))
+
+	result.UsageBytes, result.Files = j.usage()
+	j.record(result)
+
+	if result.Removed > 0 {
+		j.log.Info("Removed orphaned temp entries", "removed", result.Removed)
+	}
+
+	return result, nil
+}
+
+// sweepEntries removes the entries of dir modified before cutoff and
+// returns how many it removed. Protected directories, and directories
+// containing them, are swept in turn instead of removed.
+func (j *Janitor) sweepEntries(dir string, entries []os.DirEntry, cutoff time.Time) int {
+	removed := 0
+	for _, entry := range entries {
+		path := filepath.Join(dir, entry.Name())
+		if j.inUse(path) {
+			continue
+		}
+		if j.holdsProtected(path) {
+			inner, err := os.ReadDir(path)
+			if err != nil {
+				j.log.Warn("Failed to read protected temp directory", "path", path, "error", err)
+				continue
+			}
+			removed += j.sweepEntries(path, inner, cutoff)
+			continue
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		if info.ModTime().After(cutoff) {
+			continue
+		}
+
+		if err := os.RemoveAll(path); err != nil {
+			j.log.Warn("Failed to remove orphaned temp entry", "path", path, "error", err)
+			continue
+		}
+		removed++
+	}
+	return removed
+}
+
+// inUse reports whether a running operation uses path
+func (j *Janitor) inUse(path string) bool {
+	for _, p := range j.protected {
+		if p.inUse != nil && filepath.Dir(path) == p.dir && p.inUse(path) {
+			return true
+		}
+	}
+	return false
+}
+
+// holdsProtected reports whether path is a protected directory or contains
+// one
+func (j *Janitor) holdsProtected(path string) bool {
+	for _, p := range j.protected {
+		if p.dir == path || strings.HasPrefix(p.dir, path+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return false
+}
+
+// usage returns the total size and number of files under the temp directory
+func (j *Janitor) usage() (int64, int) {
+	var size int64
+	var files int
+	filepath.WalkDir(j.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return nil
+		}
+		if info, err := d.Info(); err == nil {
+			size += info.Size()
+			files++
+		}
+		return nil
+	})
+	return size, files
+}
+
+// record publishes usage metrics and warns when usage crosses the alert threshold
+func (j *Janitor) record(result SweepResult) {
+	tempDirBytes.Set(float64(result.UsageBytes))
+	tempDirFiles.Set(float64(result.Files))
+
+	if j.cfg.AlertBytes > 0 && result.UsageBytes > j.cfg.AlertBytes {
+		j.log.Warn("Temp directory usage above alert threshold",
+			"usage_bytes", result.UsageBytes,
+			"threshold_bytes", j.cfg.AlertBytes,
+		)
+	}
+}
@@ -0,0 +1,95 @@
+package janitor
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// makeEntry creates a file or directory modified age ago
+func makeEntry(t *testing.T, path string, dir bool, age time.Duration) {
+	t.Helper()
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	if dir {
+		require.NoError(t, os.Mkdir(path, 0o755))
+	} else {
+		require.NoError(t, os.WriteFile(path, []byte("data"), 0o644))
+	}
+	modified := time.Now().Add(-age)
+	require.NoError(t, os.Chtimes(path, modified, modified))
+}
+
+func TestJanitor_Sweep(t *testing.T) {
+	cfg := config.JanitorConfig{TTL: 3600}
+	log := logger.New("error", "text")
+
+	t.Run("Removes Entries Older Than TTL", func(t *testing.T) {
+		dir := t.TempDir()
+		makeEntry(t, filepath.Join(dir, "old.pdf"), false, 2*time.Hour)
+		makeEntry(t, filepath.Join(dir, "old-dir"), true, 2*time.Hour)
+		makeEntry(t, filepath.Join(dir, "new.pdf"), false, time.Minute)
+
+		result, err := New(dir, cfg, log).Sweep()
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Removed)
+		assert.NoFileExists(t, filepath.Join(dir, "old.pdf"))
+		assert.NoDirExists(t, filepath.Join(dir, "old-dir"))
+		assert.FileExists(t, filepath.Join(dir, "new.pdf"))
+		assert.Equal(t, 1, result.Files)
+	})
+
+	t.Run("Missing Directory", func(t *testing.T) {
+		result, err := New(filepath.Join(t.TempDir(), "missing"), cfg, log).Sweep()
+		require.NoError(t, err)
+		assert.Zero(t, result.Removed)
+	})
+
+	t.Run("Keeps Workspaces In Use", func(t *testing.T) {
+		dir := t.TempDir()
+		work := filepath.Join(dir, "sandbox", "work")
+		active := filepath.Join(work, "ws-active")
+		makeEntry(t, active, true, 2*time.Hour)
+		makeEntry(t, filepath.Join(work, "ws-orphan"), true, 2*time.Hour)
+		makeEntry(t, filepath.Join(work, "ws-new"), true, time.Minute)
+		makeEntry(t, filepath.Join(dir, "sandbox", "stale.tmp"), false, 2*time.Hour)
+		for _, d := range []string{work, filepath.Join(dir, "sandbox")} {
+			old := time.Now().Add(-2 * time.Hour)
+			require.NoError(t, os.Chtimes(d, old, old))
+		}
+
+		j := New(dir, cfg, log)
+		j.Protect(work, func(path string) bool { return path == active })
+		result, err := j.Sweep()
+		require.NoError(t, err)
+
+		assert.Equal(t, 2, result.Removed)
+		assert.DirExists(t, work)
+		assert.DirExists(t, active)
+		assert.DirExists(t, filepath.Join(work, "ws-new"))
+		assert.NoDirExists(t, filepath.Join(work, "ws-orphan"))
+		assert.NoFileExists(t, filepath.Join(dir, "sandbox", "stale.tmp"))
+	})
+
+	t.Run("Startup Cleanup Keeps Workspaces In Use", func(t *testing.T) {
+		dir := t.TempDir()
+		work := filepath.Join(dir, "work")
+		active := filepath.Join(work, "ws-active")
+		makeEntry(t, active, true, 0)
+		makeEntry(t, filepath.Join(dir, "new.pdf"), false, 0)
+
+		j := New(dir, cfg, log)
+		j.Protect(work, func(path string) bool { return path == active })
+		result, err := j.sweep(0)
+		require.NoError(t, err)
+
+		assert.Equal(t, 1, result.Removed)
+		assert.DirExists(t, active)
+	})
+}
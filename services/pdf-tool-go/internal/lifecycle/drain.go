@@ -0,0 +1,195 @@
+/**
+ * Lifecycle Management
+ *
+ * Coordinates graceful shutdown: once draining starts, new work is rejected
+ * while in-flight requests, background processing and registered hooks
+ * (such as async job checkpointing) are given until the shutdown deadline
+ * to finish.
+ */
+
+package lifecycle
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// statusInterval is how often drain progress is logged
+const statusInterval = 5 * time.Second
+
+// Hook is a named shutdown step run after in-flight requests finish
+type Hook struct {
+	Name string
+	Fn   func(ctx context.Context) error
+}
+
+// Drainer tracks in-flight requests and runs shutdown hooks. Shutdown has
+// two phases: first the instance reports not ready while still serving, so
+// load balancers can stop routing to it, then it drains.
+type Drainer struct {
+	log      logger.Logger
+	requests Tracker
+	draining atomic.Bool
+
+	mu            sync.Mutex
+	hooks         []Hook
+	notReadySince time.Time
+}
+
+// NewDrainer creates a drainer
+func NewDrainer(log logger.Logger) *Drainer {
+	return &Drainer{log: log}
+}
+
+// Register adds a hook run during Drain, in registration order
+func (d *Drainer) Register(name string, fn func(ctx context.Context) error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.hooks = append(d.hooks, Hook{Name: name, Fn: fn})
+}
+
+// Draining reports whether shutdown has started and new work must be rejected
+func (d *Drainer) Draining() bool {
+	return d.draining.Load()
+}
+
+// MarkNotReady makes readiness checks fail while requests are still served
+func (d *Drainer) MarkNotReady() {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.notReadySince.IsZero() {
+		d.notReadySince = time.Now()
+		d.log.Info("Marked not ready for shutdown")
+	}
+}
+
+// Ready reports whether the instance should receive new traffic
+func (d *Drainer) Ready() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return d.notReadySince.IsZero()
+}
+
+// PreStop marks the instance not ready and waits out delay, so load
+// balancers stop routing to it before draining starts. Time already spent
+// not ready counts towards the delay, so calling it again (from a preStop
+// hook and then on SIGTERM) does not wait twice.
+func (d *Drainer) PreStop(ctx context.Context, delay time.Duration) {
+	d.MarkNotReady()
+
+	d.mu.Lock()
+	remaining := delay - time.Since(d.notReadySince)
+	d.mu.Unlock()
+	if remaining <= 0 {
+		return
+	}
+
+	timer := time.NewTimer(remaining)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-ctx.Done():
+	}
+}
+
+// PreStopHandler serves the admin drain endpoint. It runs PreStop and then
+// reports drain status, so it can be used directly as a Kubernetes preStop
+// httpGet hook, which is why GET is accepted as well as POST.
+func (d *Drainer) PreStopHandler(delay time.Duration) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodGet && r.Method != http.MethodPost {
+			w.Header().Set("Allow", "GET, POST")
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		d.PreStop(r.Context(), delay)
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"ready":              d.Ready(),
+			"draining":           d.Draining(),
+			"in_flight_requests": d.InFlight(),
+		})
+	})
+}
+
+// StartDraining marks the server as draining without waiting for anything
+func (d *Drainer) StartDraining() {
+	d.MarkNotReady()
+	if d.draining.CompareAndSwap(false, true) {
+		d.log.Info("Draining started", "in_flight_requests", d.requests.Count())
+	}
+}
+
+// TrackRequest records an in-flight request. The returned function must be
+// called when the request completes.
+func (d *Drainer) TrackRequest() func() {
+	return d.requests.Start()
+}
+
+// InFlight returns the number of tracked in-flight requests
+func (d *Drainer) InFlight() int {
+	return d.requests.Count()
+}
+
+// Drain stops accepting work, waits for in-flight requests and then runs the
+// registered hooks. It returns ctx's error if the deadline passes first;
+// hooks still run so they can checkpoint whatever is left.
+func (d *Drainer) Drain(ctx context.Context) error {
+	d.StartDraining()
+	start := time.Now()
+
+	stopStatus := d.logStatus(start)
+	err := d.requests.Wait(ctx)
+	stopStatus()
+	if err != nil {
+		d.log.Warn("Drain deadline reached with requests in flight", "in_flight_requests", d.requests.Count())
+	}
+
+	d.mu.Lock()
+	hooks := append([]Hook(nil), d.hooks...)
+	d.mu.Unlock()
+
+	for _, hook := range hooks {
+		hookStart := time.Now()
+		if hookErr := hook.Fn(ctx); hookErr != nil {
+			d.log.Error("Drain step failed", "step", hook.Name, "error", hookErr)
+			if err == nil {
+				err = hookErr
+			}
+			continue
+		}
+		d.log.Info("Drain step completed", "step", hook.Name, "duration", time.Since(hookStart).String())
+	}
+
+	d.log.Info("Drain finished", "duration", time.Since(start).String(), "clean", err == nil)
+	return err
+}
+
+// logStatus periodically logs drain progress until the returned function is called
+func (d *Drainer) logStatus(start time.Time) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(statusInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				d.log.Info("Drain status",
+					"in_flight_requests", d.requests.Count(),
+					"elapsed", time.Since(start).String(),
+				)
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() { close(done) }
+}
@@ -0,0 +1,94 @@
+package lifecycle
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracker(t *testing.T) {
+	t.Run("wait returns immediately when idle", func(t *testing.T) {
+		var tracker Tracker
+		assert.NoError(t, tracker.Wait(context.Background()))
+	})
+
+	t.Run("wait blocks until work finishes", func(t *testing.T) {
+		var tracker Tracker
+		done := tracker.Start()
+		assert.Equal(t, 1, tracker.Count())
+
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			done()
+			done() // extra calls are ignored
+		}()
+
+		assert.NoError(t, tracker.Wait(context.Background()))
+		assert.Equal(t, 0, tracker.Count())
+	})
+
+	t.Run("wait honours the deadline", func(t *testing.T) {
+		var tracker Tracker
+		defer tracker.Start()()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		assert.ErrorIs(t, tracker.Wait(ctx), context.DeadlineExceeded)
+	})
+}
+
+func TestDrainer(t *testing.T) {
+	log := logger.New("error", "json")
+
+	t.Run("waits for requests then runs hooks in order", func(t *testing.T) {
+		drainer := NewDrainer(log)
+		var order []string
+		drainer.Register("first", func(ctx context.Context) error {
+			order = append(order, "first")
+			return nil
+		})
+		drainer.Register("second", func(ctx context.Context) error {
+			order = append(order, "second")
+			return nil
+		})
+
+		done := drainer.TrackRequest()
+		go func() {
+			time.Sleep(20 * time.Millisecond)
+			order = append(order, "request")
+			done()
+		}()
+
+		assert.NoError(t, drainer.Drain(context.Background()))
+		assert.True(t, drainer.Draining())
+		assert.Equal(t, []string{"request", "first", "second"}, order)
+	})
+
+	t.Run("pre-stop flips readiness and waits once", func(t *testing.T) {
+		drainer := NewDrainer(log)
+		assert.True(t, drainer.Ready())
+
+		start := time.Now()
+		drainer.PreStop(context.Background(), 30*time.Millisecond)
+		assert.False(t, drainer.Ready())
+		assert.False(t, drainer.Draining())
+		assert.GreaterOrEqual(t, time.Since(start), 30*time.Millisecond)
+
+		start = time.Now()
+		drainer.PreStop(context.Background(), 30*time.Millisecond)
+		assert.Less(t, time.Since(start), 30*time.Millisecond)
+	})
+
+	t.Run("reports hook failures", func(t *testing.T) {
+		drainer := NewDrainer(log)
+		failure := errors.New("checkpoint failed")
+		drainer.Register("checkpoint", func(ctx context.Context) error { return failure })
+
+		assert.ErrorIs(t, drainer.Drain(context.Background()), failure)
+	})
+}
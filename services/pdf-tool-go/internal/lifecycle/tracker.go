@@ -0,0 +1,63 @@
+package lifecycle
+
+import (
+	"context"
+	"sync"
+)
+
+// Tracker counts in-flight units of work so shutdown can wait for them.
+// Unlike sync.WaitGroup, Start may be called while Wait is in progress.
+type Tracker struct {
+	mu   sync.Mutex
+	n    int
+	idle chan struct{}
+}
+
+// Start records the start of a unit of work. The returned function must be
+// called exactly once when the work is finished.
+func (t *Tracker) Start() func() {
+	t.mu.Lock()
+	if t.n == 0 {
+		t.idle = make(chan struct{})
+	}
+	t.n++
+	t.mu.Unlock()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			t.mu.Lock()
+			t.n--
+			if t.n == 0 {
+				close(t.idle)
+			}
+			t.mu.Unlock()
+		})
+	}
+}
+
+// Count returns the number of in-flight units of work
+func (t *Tracker) Count() int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.n
+}
+
+// Wait blocks until no work is in flight or ctx is done
+func (t *Tracker) Wait(ctx context.Context) error {
+	t.mu.Lock()
+	if t.n == 0 {
+		t.mu.Unlock()
+		return nil
+	}
+	idle := t.idle
+	t.mu.Unlock()
+
+	select {
+	case <-idle:
+		// New work may have started since; keep waiting for it too
+		return t.Wait(ctx)
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,149 @@
+/**
+ * Link Validation
+ *
+ * Checks the web links extracted from documents, flagging the dead ones.
+ * Links come from uploaded documents, so connections to private, loopback
+ * and link-local addresses are refused at dial time, redirects included,
+ * unless the deployment allows them.
+ */
+
+package linkcheck
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// maxRedirects bounds the redirects followed per link
+const maxRedirects = 5
+
+// errPrivateAddress refuses connections into the internal network
+var errPrivateAddress = errors.New("private address not allowed")
+
+// Checker validates links with HEAD requests, falling back to GET for
+// servers refusing HEAD
+type Checker struct {
+	client      *http.Client
+	userAgent   string
+	concurrency int
+}
+
+// New creates a checker configured by cfg
+func New(cfg config.LinkCheckConfig) *Checker {
+	dialer := &net.Dialer{Timeout: time.Duration(cfg.Timeout) * time.Second}
+	transport := &http.Transport{
+		DialContext:         dialer.DialContext,
+		TLSHandshakeTimeout: time.Duration(cfg.Timeout) * time.Second,
+		MaxIdleConnsPerHost: 2,
+	}
+	// A proxy would dial on the checker's behalf, past the guard, so only
+	// checkers allowing private addresses use one
+	if cfg.AllowPrivate {
+		transport.Proxy = http.ProxyFromEnvironment
+	} else {
+		dialer.Control = refusePrivate
+	}
+	return &Checker{
+		client: &http.Client{
+			Timeout:   time.Duration(cfg.Timeout) * time.Second,
+			Transport: transport,
+			CheckRedirect: func(req *http.Request, via []*http.Request) error {
+				if len(via) >= maxRedirects {
+					return fmt.Errorf("stopped after %d redirects", maxRedirects)
+				}
+				return nil
+			},
+		},
+		userAgent:   cfg.UserAgent,
+		concurrency: cfg.Concurrency,
+	}
+}
+
+// refusePrivate is a dialer control refusing addresses outside the public
+// internet. It runs on the resolved address, so DNS cannot point around it.
+func refusePrivate(network, address string, _ syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	ip := net.ParseIP(host)
+	if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsUnspecified() || ip.IsMulticast() {
+		return errPrivateAddress
+	}
+	return nil
+}
+
+// Check implements service.LinkChecker
+func (c *Checker) Check(ctx context.Context, urls []string) map[string]service.LinkStatus {
+	statuses := make(map[string]service.LinkStatus, len(urls))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, c.concurrency)
+	for _, url := range urls {
+		url := url
+		wg.Add(1)
+		slots <- struct{}{}
+		go func() {
+			defer func() { <-slots; wg.Done() }()
+			status := c.check(ctx, url)
+			mu.Lock()
+			statuses[url] = status
+			mu.Unlock()
+		}()
+	}
+	wg.Wait()
+	return statuses
+}
+
+// check requests one link. Servers answering HEAD with 405 or 501 get a
+// GET whose body is left unread.
+func (c *Checker) check(ctx context.Context, url string) service.LinkStatus {
+	code, err := c.request(ctx, http.MethodHead, url)
+	if err == nil && (code == http.StatusMethodNotAllowed || code == http.StatusNotImplemented) {
+		code, err = c.request(ctx, http.MethodGet, url)
+	}
+	if errors.Is(err, errPrivateAddress) {
+		return service.LinkStatus{Error: errPrivateAddress.Error(), Dead: true}
+	}
+	if err != nil {
+		return service.LinkStatus{Error: err.Error(), Dead: true}
+	}
+	return service.LinkStatus{StatusCode: code, Dead: dead(code)}
+}
+
+// request sends a request for url, returning the final status code
+func (c *Checker) request(ctx context.Context, method, url string) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, url, nil)
+	if err != nil {
+		return 0, err
+	}
+	if c.userAgent != "" {
+		req.Header.Set("User-Agent", c.userAgent)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// dead reports whether a status code means the link is broken. Servers
+// refusing unauthenticated or automated requests are not assumed dead.
+func dead(code int) bool {
+	switch code {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests:
+		return false
+	}
+	return code >= 400
+}
@@ -0,0 +1,46 @@
+package linkcheck
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestChecker(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.UserAgent() != "link-check-test":
+			w.WriteHeader(http.StatusBadRequest)
+		case r.URL.Path == "/gone":
+			w.WriteHeader(http.StatusNotFound)
+		case r.URL.Path == "/login":
+			w.WriteHeader(http.StatusForbidden)
+		case r.URL.Path == "/moved":
+			http.Redirect(w, r, "/gone", http.StatusMovedPermanently)
+		case r.URL.Path == "/no-head" && r.Method == http.MethodHead:
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		}
+	}))
+	defer server.Close()
+
+	cfg := config.LinkCheckConfig{Timeout: 5, Concurrency: 2, AllowPrivate: true, UserAgent: "link-check-test"}
+	statuses := New(cfg).Check(context.Background(), []string{
+		server.URL + "/ok", server.URL + "/gone", server.URL + "/login", server.URL + "/moved", server.URL + "/no-head",
+	})
+	assert.Equal(t, map[string]service.LinkStatus{
+		server.URL + "/ok":      {StatusCode: http.StatusOK},
+		server.URL + "/gone":    {StatusCode: http.StatusNotFound, Dead: true},
+		server.URL + "/login":   {StatusCode: http.StatusForbidden},
+		server.URL + "/moved":   {StatusCode: http.StatusNotFound, Dead: true},
+		server.URL + "/no-head": {StatusCode: http.StatusOK},
+	}, statuses)
+
+	cfg.AllowPrivate = false
+	statuses = New(cfg).Check(context.Background(), []string{server.URL + "/ok"})
+	assert.Equal(t, service.LinkStatus{Error: "private address not allowed", Dead: true}, statuses[server.URL+"/ok"])
+}
@@ -0,0 +1,183 @@
+/**
+ * Listing
+ *
+ * Cursor pagination, sorting and date filtering shared by the listing
+ * endpoints. Cursors are opaque to clients: they encode the sort and the
+ * position of the last item returned, so pages stay consistent while items
+ * are added or removed between requests.
+ */
+
+package listing
+
+import (
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+const (
+	// DefaultLimit is the page size when the request sets none
+	DefaultLimit = 50
+	// MaxLimit caps the page size
+	MaxLimit = 500
+)
+
+// Query is a parsed listing request
+type Query struct {
+	Limit int
+	// Sort names the field to sort by; Descending reverses the order
+	Sort       string
+	Descending bool
+	// CreatedAfter and CreatedBefore bound the creation time when set
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// after is the position the cursor points past
+	after *position
+}
+
+type position struct {
+	key string
+	id  string
+}
+
+// ParseQuery parses the limit, sort, cursor, created_after and
+// created_before parameters. Sort is a field of sortable, prefixed with
+// "-" for descending order; it defaults to newest first.
+func ParseQuery(values url.Values, sortable []string) (Query, error) {
+	q := Query{Limit: DefaultLimit, Sort: "created_at", Descending: true}
+
+	if limit := values.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 || n > MaxLimit {
+			return Query{}, invalid("limit must be between 1 and %d", MaxLimit)
+		}
+		q.Limit = n
+	}
+
+	if sortBy := values.Get("sort"); sortBy != "" {
+		q.Sort, q.Descending = strings.TrimPrefix(sortBy, "-"), strings.HasPrefix(sortBy, "-")
+		if !contains(sortable, q.Sort) {
+			return Query{}, invalid("cannot sort by %q; sortable: %s", q.Sort, strings.Join(sortable, ", "))
+		}
+	}
+
+	for name, bound := range map[string]*time.Time{"created_after": &q.CreatedAfter, "created_before": &q.CreatedBefore} {
+		if value := values.Get(name); value != "" {
+			t, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				return Query{}, invalid("%s must be an RFC 3339 time", name)
+			}
+			*bound = t
+		}
+	}
+
+	if cursor := values.Get("cursor"); cursor != "" {
+		after, err := q.decode(cursor)
+		if err != nil {
+			return Query{}, err
+		}
+		q.after = after
+	}
+	return q, nil
+}
+
+// Created reports whether an item created at t is within the date filter
+func (q Query) Created(t time.Time) bool {
+	if !q.CreatedAfter.IsZero() && !t.After(q.CreatedAfter) {
+		return false
+	}
+	return q.CreatedBefore.IsZero() || t.Before(q.CreatedBefore)
+}
+
+// cursor encodes the position after p under the query's sort
+func (q Query) cursor(p position) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(q.sortSpec() + "\x00" + p.key + "\x00" + p.id))
+}
+
+// decode parses a cursor, which must have been issued for the same sort
+func (q Query) decode(cursor string) (*position, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(cursor)
+	parts := strings.SplitN(string(raw), "\x00", 3)
+	if err != nil || len(parts) != 3 {
+		return nil, invalid("invalid cursor")
+	}
+	if parts[0] != q.sortSpec() {
+		return nil, invalid("cursor was issued for a different sort")
+	}
+	return &position{key: parts[1], id: parts[2]}, nil
+}
+
+func (q Query) sortSpec() string {
+	if q.Descending {
+		return "-" + q.Sort
+	}
+	return q.Sort
+}
+
+// Sorter pages items of type T. Fields map sortable field names to keys
+// that order lexicographically, such as those from Time and Int; items
+// with equal keys are ordered by ID.
+type Sorter[T any] struct {
+	ID     func(item T) string
+	Fields map[string]func(item T) string
+}
+
+// Page sorts items by the query and returns the page after its cursor,
+// with the cursor of the next page, or "" on the last page
+func (s Sorter[T]) Page(items []T, q Query) ([]T, string) {
+	key := s.Fields[q.Sort]
+	less := func(a, b position) bool {
+		if q.Descending {
+			a, b = b, a
+		}
+		if a.key != b.key {
+			return a.key < b.key
+		}
+		return a.id < b.id
+	}
+	at := func(i int) position {
+		return position{key: key(items[i]), id: s.ID(items[i])}
+	}
+
+	sort.Slice(items, func(i, j int) bool { return less(at(i), at(j)) })
+
+	start := 0
+	if q.after != nil {
+		start = sort.Search(len(items), func(i int) bool { return less(*q.after, at(i)) })
+	}
+	end := start + q.Limit
+	if end >= len(items) {
+		return items[start:], ""
+	}
+	return items[start:end], q.cursor(at(end - 1))
+}
+
+// Time returns a sort key for t
+func Time(t time.Time) string {
+	return fmt.Sprintf("%020d", t.UnixNano())
+}
+
+// Int returns a sort key for a non-negative n
+func Int(n int64) string {
+	return fmt.Sprintf("%020d", n)
+}
+
+func invalid(format string, args ...interface{}) error {
+	return service.NewError(service.ErrCodeInvalidInput, fmt.Sprintf(format, args...), nil)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,82 @@
+package listing
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type item struct {
+	id      string
+	created time.Time
+}
+
+var itemSorter = Sorter[item]{
+	ID: func(it item) string { return it.id },
+	Fields: map[string]func(item) string{
+		"created_at": func(it item) string { return Time(it.created) },
+	},
+}
+
+func TestSorter_Page(t *testing.T) {
+	base := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	var items []item
+	for i := 0; i < 5; i++ {
+		items = append(items, item{id: strconv.Itoa(i), created: base.Add(time.Duration(i) * time.Hour)})
+	}
+	ids := func(page []item) []string {
+		var out []string
+		for _, it := range page {
+			out = append(out, it.id)
+		}
+		return out
+	}
+
+	t.Run("Cursors Walk Every Item Once", func(t *testing.T) {
+		q, err := ParseQuery(url.Values{"limit": {"2"}}, []string{"created_at"})
+		require.NoError(t, err)
+
+		var seen []string
+		for {
+			page, next := itemSorter.Page(items, q)
+			seen = append(seen, ids(page)...)
+			if next == "" {
+				break
+			}
+			q, err = ParseQuery(url.Values{"limit": {"2"}, "cursor": {next}}, []string{"created_at"})
+			require.NoError(t, err)
+		}
+		assert.Equal(t, []string{"4", "3", "2", "1", "0"}, seen)
+	})
+
+	t.Run("Ascending Sort", func(t *testing.T) {
+		q, err := ParseQuery(url.Values{"sort": {"created_at"}, "limit": {"3"}}, []string{"created_at"})
+		require.NoError(t, err)
+		page, next := itemSorter.Page(items, q)
+		assert.Equal(t, []string{"0", "1", "2"}, ids(page))
+		assert.NotEmpty(t, next)
+	})
+
+	t.Run("Invalid Requests Are Rejected", func(t *testing.T) {
+		for _, values := range []url.Values{
+			{"limit": {"0"}},
+			{"sort": {"size"}},
+			{"created_after": {"yesterday"}},
+			{"cursor": {"not-a-cursor"}},
+		} {
+			_, err := ParseQuery(values, []string{"created_at"})
+			assert.Error(t, err, "%v", values)
+		}
+	})
+
+	t.Run("Cursors Are Tied To Their Sort", func(t *testing.T) {
+		q, _ := ParseQuery(url.Values{"limit": {"1"}}, []string{"created_at"})
+		_, next := itemSorter.Page(items, q)
+		_, err := ParseQuery(url.Values{"sort": {"created_at"}, "cursor": {next}}, []string{"created_at"})
+		assert.Error(t, err)
+	})
+}
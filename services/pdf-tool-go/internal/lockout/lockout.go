@@ -0,0 +1,210 @@
+/**
+ * Attempt Lockout
+ *
+ * Tracks failed attempts per key with exponential backoff and lockout, so
+ * password-checking endpoints cannot be used as a guessing oracle.
+ */
+
+package lockout
+
+import (
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+// pruneInterval bounds how often forgotten entries are swept
+const pruneInterval = time.Minute
+
+type entry struct {
+	failures     int
+	lastFailure  time.Time
+	blockedUntil time.Time
+	// inFlight counts reserved attempts not yet settled
+	inFlight int
+}
+
+// Guard counts failures per key. After each failure further attempts are
+// blocked for a backoff that doubles per failure; after MaxAttempts
+// failures the key is locked out. Failures are forgotten once Window has
+// passed without another.
+type Guard struct {
+	mu        sync.Mutex
+	cfg       config.LockoutConfig
+	entries   map[string]*entry
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// NewGuard creates a guard
+func NewGuard(cfg config.LockoutConfig) *Guard {
+	return &Guard{
+		cfg:     cfg,
+		entries: make(map[string]*entry),
+		now:     time.Now,
+	}
+}
+
+// Check returns how long the caller must wait before the next attempt is
+// allowed for all of keys, or 0 if it is allowed now
+func (g *Guard) Check(keys ...string) time.Duration {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	var wait time.Duration
+	for _, key := range keys {
+		if e, ok := g.entries[key]; ok {
+			if remaining := e.blockedUntil.Sub(now); remaining > wait {
+				wait = remaining
+			}
+		}
+	}
+	return wait
+}
+
+// Failure records a failed attempt against every key
+func (g *Guard) Failure(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.prune(now)
+
+	for _, key := range keys {
+		g.fail(g.entry(key, now), now)
+	}
+}
+
+// Reservation is an attempt reserved against keys by Reserve
+type Reservation struct {
+	g       *Guard
+	entries []*entry
+	keys    []string
+}
+
+// Reserve checks keys like Check and, when the attempt is allowed, reserves
+// it against every key in the same step. Reserved attempts count towards
+// MaxAttempts until settled, so concurrent attempts cannot all pass the
+// check before the first of them fails. It returns how long to wait when
+// the attempt is not allowed, including while the remaining attempts are
+// all in flight. Reservations must be settled with Done.
+func (g *Guard) Reserve(keys ...string) (*Reservation, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	now := g.now()
+	g.prune(now)
+
+	var wait time.Duration
+	for _, key := range keys {
+		e, ok := g.entries[key]
+		if !ok || g.forgotten(e, now) {
+			continue
+		}
+		if remaining := e.blockedUntil.Sub(now); remaining > wait {
+			wait = remaining
+		}
+		if g.cfg.MaxAttempts > 0 && e.failures+e.inFlight >= g.cfg.MaxAttempts {
+			if pending := time.Duration(g.cfg.BaseDelay) * time.Second; pending > wait {
+				wait = pending
+			}
+		}
+	}
+	if wait > 0 {
+		return nil, wait
+	}
+
+	r := &Reservation{g: g, keys: keys}
+	for _, key := range keys {
+		e := g.entry(key, now)
+		e.inFlight++
+		r.entries = append(r.entries, e)
+	}
+	return r, 0
+}
+
+// Done settles the reservation, recording a failure against its keys if
+// the attempt failed
+func (r *Reservation) Done(failed bool) {
+	g := r.g
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, e := range r.entries {
+		e.inFlight--
+	}
+	if !failed {
+		return
+	}
+
+	now := g.now()
+	for _, key := range r.keys {
+		g.fail(g.entry(key, now), now)
+	}
+}
+
+// Reset forgets the failures of keys, e.g. a document once its password
+// has been supplied
+func (g *Guard) Reset(keys ...string) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	for _, key := range keys {
+		delete(g.entries, key)
+	}
+}
+
+// entry returns the entry of key, replacing a forgotten one
+func (g *Guard) entry(key string, now time.Time) *entry {
+	e, ok := g.entries[key]
+	if !ok || g.forgotten(e, now) {
+		e = &entry{}
+		g.entries[key] = e
+	}
+	return e
+}
+
+// fail records a failure in e
+func (g *Guard) fail(e *entry, now time.Time) {
+	e.failures++
+	e.lastFailure = now
+	e.blockedUntil = now.Add(g.backoff(e.failures))
+}
+
+// backoff returns how long to block after the given number of failures
+func (g *Guard) backoff(failures int) time.Duration {
+	if g.cfg.MaxAttempts > 0 && failures >= g.cfg.MaxAttempts {
+		return time.Duration(g.cfg.Lockout) * time.Second
+	}
+
+	delay := time.Duration(g.cfg.BaseDelay) * time.Second
+	maxDelay := time.Duration(g.cfg.MaxDelay) * time.Second
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+	return delay
+}
+
+// forgotten reports whether e has no attempts in flight, is no longer
+// blocked and its last failure is older than the window
+func (g *Guard) forgotten(e *entry, now time.Time) bool {
+	return e.inFlight == 0 && now.After(e.blockedUntil) && now.Sub(e.lastFailure) > time.Duration(g.cfg.Window)*time.Second
+}
+
+// prune drops forgotten entries so memory is bounded by recent failures
+func (g *Guard) prune(now time.Time) {
+	if now.Sub(g.lastPrune) < pruneInterval {
+		return
+	}
+	g.lastPrune = now
+	for key, e := range g.entries {
+		if g.forgotten(e, now) {
+			delete(g.entries, key)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package lockout
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuard(t *testing.T) {
+	cfg := config.LockoutConfig{MaxAttempts: 4, BaseDelay: 1, MaxDelay: 3, Lockout: 60, Window: 120}
+	now := time.Unix(1700000000, 0)
+	newGuard := func() *Guard {
+		g := NewGuard(cfg)
+		g.now = func() time.Time { return now }
+		return g
+	}
+
+	t.Run("Backoff Doubles Up To Max", func(t *testing.T) {
+		g := newGuard()
+		assert.Zero(t, g.Check("doc"))
+		g.Failure("doc")
+		assert.Equal(t, 1*time.Second, g.Check("doc"))
+		g.Failure("doc")
+		assert.Equal(t, 2*time.Second, g.Check("doc"))
+		g.Failure("doc")
+		assert.Equal(t, 3*time.Second, g.Check("doc"))
+	})
+
+	t.Run("Lockout After Max Attempts", func(t *testing.T) {
+		g := newGuard()
+		for i := 0; i < 4; i++ {
+			g.Failure("doc", "client")
+		}
+		assert.Equal(t, 60*time.Second, g.Check("other", "client"))
+	})
+
+	t.Run("Failures Are Forgotten After Window", func(t *testing.T) {
+		g := newGuard()
+		g.Failure("doc")
+		g.Failure("doc")
+		now = now.Add(time.Duration(cfg.Window+1) * time.Second)
+		assert.Zero(t, g.Check("doc"))
+		g.Failure("doc")
+		assert.Equal(t, 1*time.Second, g.Check("doc"))
+	})
+
+	t.Run("Reset", func(t *testing.T) {
+		g := newGuard()
+		g.Failure("doc")
+		g.Reset("doc")
+		assert.Zero(t, g.Check("doc"))
+	})
+}
+
+func TestGuard_Reserve(t *testing.T) {
+	cfg := config.LockoutConfig{MaxAttempts: 3, BaseDelay: 1, MaxDelay: 3, Lockout: 60, Window: 120}
+	now := time.Unix(1700000000, 0)
+	newGuard := func() *Guard {
+		g := NewGuard(cfg)
+		g.now = func() time.Time { return now }
+		return g
+	}
+
+	t.Run("Concurrent Attempts Are Capped", func(t *testing.T) {
+		g := newGuard()
+		var reserved []*Reservation
+		for i := 0; i < cfg.MaxAttempts; i++ {
+			r, wait := g.Reserve("doc", "client")
+			assert.Zero(t, wait)
+			reserved = append(reserved, r)
+		}
+		r, wait := g.Reserve("doc", "other")
+		assert.Nil(t, r)
+		assert.Equal(t, time.Second, wait)
+
+		for _, r := range reserved {
+			r.Done(true)
+		}
+		assert.Equal(t, 60*time.Second, g.Check("doc"))
+		assert.Equal(t, 60*time.Second, g.Check("client"))
+	})
+
+	t.Run("Settled Attempts Free Their Slot", func(t *testing.T) {
+		g := newGuard()
+		for i := 0; i < 10; i++ {
+			r, wait := g.Reserve("doc", "client")
+			assert.Zero(t, wait)
+			r.Done(false)
+		}
+		assert.Zero(t, g.Check("doc", "client"))
+	})
+
+	t.Run("Failures Block Like Failure", func(t *testing.T) {
+		g := newGuard()
+		r, _ := g.Reserve("doc")
+		r.Done(true)
+		r, wait := g.Reserve("doc")
+		assert.Nil(t, r)
+		assert.Equal(t, time.Second, wait)
+	})
+
+	t.Run("Attempts In Flight Are Not Pruned", func(t *testing.T) {
+		g := newGuard()
+		r, _ := g.Reserve("doc")
+		now = now.Add(time.Duration(cfg.Window+1) * time.Second)
+		g.Failure("other")
+		r.Done(true)
+		assert.Equal(t, time.Second, g.Check("doc"))
+	})
+
+	t.Run("Reset While In Flight", func(t *testing.T) {
+		g := newGuard()
+		r, _ := g.Reserve("doc")
+		g.Reset("doc")
+		r.Done(false)
+		assert.Zero(t, g.Check("doc"))
+		r, wait := g.Reserve("doc")
+		assert.Zero(t, wait)
+		r.Done(false)
+	})
+}
@@ -0,0 +1,442 @@
+/**
+ * Mailbox Ingestion
+ *
+ * Polls IMAP mailboxes for messages carrying PDF attachments and runs each
+ * message's attachments through a configured batch pipeline, supporting
+ * "email your documents to process@..." workflows. Results are emailed
+ * back to the sender or forwarded through result delivery; messages that
+ * cannot be processed are answered with the reason.
+ */
+
+package mailbox
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"fmt"
+	"io"
+	"net"
+	"net/mail"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/emersion/go-imap"
+	"github.com/emersion/go-imap/client"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/delivery"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var messagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_mailbox_messages_total",
+	Help: "Mailbox messages handled by mailbox and outcome",
+}, []string{"mailbox", "outcome"})
+
+// defaultFolder is the folder polled when a mailbox sets none
+const defaultFolder = "INBOX"
+
+// messageOverhead is allowed on top of the attachments in a message, for
+// its headers and text
+const messageOverhead = 1 << 20
+
+// request is a message submitted as a job, awaiting the job's outcome
+type request struct {
+	to        []string
+	messageID string
+	subject   string
+	documents []*service.Result
+}
+
+// Ingester polls one mailbox
+type Ingester struct {
+	cfg          config.IMAPMailbox
+	maxDocuments int
+	maxFileSize  func(ctx context.Context) int64
+	steps        []batch.Step
+	documents    *service.DocumentService
+	jobs         *batch.Manager
+	mailer       *delivery.Mailer
+	log          logger.Logger
+
+	// requests are the submitted messages by job ID. They are only touched
+	// by the polling goroutine.
+	requests map[string]*request
+	stopping chan struct{}
+	stopOnce sync.Once
+	running  lifecycle.Tracker
+}
+
+// New creates an ingester of mailbox. Its steps are validated against the
+// batch limits; messages with more attachments than a job takes or with
+// attachments larger than maxFileSize returns for the mailbox's tenant are
+// rejected. The limit is read for every message, so configuration reloads
+// apply. Results and failures are sent through mailer.
+func New(mailbox config.IMAPMailbox, batchCfg config.BatchConfig, maxFileSize func(ctx context.Context) int64, documents *service.DocumentService,
+	jobs *batch.Manager, mailer *delivery.Mailer, log logger.Logger) (*Ingester, error) {
+	if mailbox.Folder == "" {
+		mailbox.Folder = defaultFolder
+	}
+	if mailbox.Tenant == "" {
+		mailbox.Tenant = config.DefaultTenant
+	}
+
+	steps := make([]batch.Step, len(mailbox.Steps))
+	for i, step := range mailbox.Steps {
+		steps[i] = batch.Step{Operation: step.Operation, Params: step.Params}
+	}
+	sub := batch.Submission{Documents: []string{mailbox.Name}, Steps: steps, Priority: batch.PriorityBulk}
+	if err := batch.Validate(batchCfg, sub); err != nil {
+		return nil, fmt.Errorf("imap mailbox %s: %w", mailbox.Name, err)
+	}
+
+	return &Ingester{
+		cfg:          mailbox,
+		maxDocuments: batchCfg.MaxDocuments,
+		maxFileSize:  maxFileSize,
+		steps:        steps,
+		documents:    documents,
+		jobs:         jobs,
+		mailer:       mailer,
+		log:          log,
+		requests:     make(map[string]*request),
+		stopping:     make(chan struct{}),
+	}, nil
+}
+
+// Start polls the mailbox until ctx is done or Drain is called. Instances
+// other than the one the mailbox is assigned to do not poll it.
+func (in *Ingester) Start(ctx context.Context) {
+	if in.cfg.Instance != "" && in.cfg.Instance != in.jobs.Owner() {
+		in.log.Info("Mailbox is polled by another instance", "mailbox", in.cfg.Name, "instance", in.cfg.Instance)
+		return
+	}
+
+	ctx = tenant.WithID(ctx, in.cfg.Tenant)
+	done := in.running.Start()
+	go func() {
+		defer done()
+		ticker := time.NewTicker(time.Duration(in.cfg.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			in.poll(ctx)
+			select {
+			case <-ctx.Done():
+				return
+			case <-in.stopping:
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Drain stops polling and waits for the current poll. Messages whose jobs
+// are still running have already been handled; their failures are not
+// reported.
+func (in *Ingester) Drain(ctx context.Context) error {
+	in.stopOnce.Do(func() { close(in.stopping) })
+	return in.running.Wait(ctx)
+}
+
+// poll reports finished jobs, then submits the unseen messages of the
+// mailbox
+func (in *Ingester) poll(ctx context.Context) {
+	in.settle(ctx)
+
+	c, err := in.connect()
+	if err != nil {
+		in.log.Warn("Failed to connect to mailbox", "mailbox", in.cfg.Name, "error", err)
+		return
+	}
+	defer c.Logout()
+
+	if _, err := c.Select(in.cfg.Folder, false); err != nil {
+		in.log.Warn("Failed to open mailbox folder", "mailbox", in.cfg.Name, "folder", in.cfg.Folder, "error", err)
+		return
+	}
+	criteria := imap.NewSearchCriteria()
+	criteria.WithoutFlags = []string{imap.SeenFlag}
+	uids, err := c.UidSearch(criteria)
+	if err != nil {
+		in.log.Warn("Failed to search mailbox", "mailbox", in.cfg.Name, "error", err)
+		return
+	}
+
+	for _, uid := range uids {
+		if ctx.Err() != nil || !in.handle(ctx, c, uid) {
+			return
+		}
+	}
+}
+
+// connect logs in to the mailbox server. Credentials are only sent over
+// TLS: servers reached without implicit TLS must support STARTTLS.
+func (in *Ingester) connect() (*client.Client, error) {
+	host, _, err := net.SplitHostPort(in.cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	timeout := time.Duration(in.cfg.Timeout) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+	tlsConfig := &tls.Config{ServerName: host, MinVersion: tls.VersionTLS12}
+
+	var c *client.Client
+	if in.cfg.TLS {
+		c, err = client.DialWithDialerTLS(dialer, in.cfg.Address, tlsConfig)
+	} else {
+		c, err = client.DialWithDialer(dialer, in.cfg.Address)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c.Timeout = timeout
+
+	if !in.cfg.TLS {
+		if ok, _ := c.SupportStartTLS(); !ok {
+			c.Logout()
+			return nil, fmt.Errorf("server does not support STARTTLS")
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			c.Logout()
+			return nil, fmt.Errorf("failed to start TLS: %w", err)
+		}
+	}
+	if err := c.Login(in.cfg.Username, in.cfg.Password); err != nil {
+		c.Logout()
+		return nil, fmt.Errorf("failed to log in: %w", err)
+	}
+	return c, nil
+}
+
+// handle submits the attachments of message uid, or answers it with why
+// they cannot be processed, and marks it handled. It reports false when
+// the message was left for the next poll, because it could not be fetched
+// or the batch queue is busy, and polling should stop.
+func (in *Ingester) handle(ctx context.Context, c *client.Client, uid uint32) bool {
+	outcome, err := in.process(ctx, c, uid)
+	if outcome == "" {
+		if err != nil {
+			in.log.Warn("Failed to fetch mailbox message", "mailbox", in.cfg.Name, "uid", uid, "error", err)
+		} else {
+			in.log.Info("Batch queue busy, deferring mailbox messages", "mailbox", in.cfg.Name)
+		}
+		return false
+	}
+	if err != nil {
+		in.log.Warn("Ignoring unreadable mailbox message", "mailbox", in.cfg.Name, "uid", uid, "error", err)
+	}
+
+	set := new(imap.SeqSet)
+	set.AddNum(uid)
+	if in.cfg.Processed != "" {
+		err = c.UidMove(set, in.cfg.Processed)
+	} else {
+		err = c.UidStore(set, imap.FormatFlagsOp(imap.AddFlags, true), []interface{}{imap.SeenFlag}, nil)
+	}
+	if err != nil {
+		in.log.Error("Failed to mark mailbox message handled", "mailbox", in.cfg.Name, "uid", uid, "error", err)
+	}
+	messagesTotal.WithLabelValues(in.cfg.Name, outcome).Inc()
+	return true
+}
+
+// process reads message uid and submits its attachments. It returns the
+// outcome: "submitted", "rejected" when the sender was told why the
+// message cannot be processed, "ignored" for messages never answered, or
+// "invalid" for unreadable messages. The outcome is empty when the message
+// is left for the next poll.
+func (in *Ingester) process(ctx context.Context, c *client.Client, uid uint32) (string, error) {
+	maxFileSize := in.maxFileSize(ctx)
+	limit := maxFileSize*int64(in.maxDocuments)*4/3 + messageOverhead
+	fetched, err := fetch(c, uid, imap.FetchRFC822Size)
+	if err != nil {
+		return "", err
+	}
+	large := int64(fetched.Size) > limit
+
+	// Only the header is needed to answer a message too large to fetch
+	section := &imap.BodySectionName{Peek: true}
+	if large {
+		section.Specifier = imap.HeaderSpecifier
+	}
+	if fetched, err = fetch(c, uid, section.FetchItem()); err != nil {
+		return "", err
+	}
+	body := fetched.GetBody(section)
+	if body == nil {
+		return "", fmt.Errorf("server returned no message body")
+	}
+	raw, err := io.ReadAll(body)
+	if err != nil {
+		return "", err
+	}
+
+	var msg *message
+	if large {
+		header, err := mail.ReadMessage(bytes.NewReader(append(raw, "\r\n"...)))
+		if err != nil {
+			return "invalid", err
+		}
+		msg, err = newMessage(header.Header)
+	} else {
+		msg, err = parse(raw)
+	}
+	if err != nil {
+		return "invalid", err
+	}
+	if msg.automatic {
+		return "ignored", nil
+	}
+	if !allowed(in.cfg.Senders, msg.from.Address) {
+		in.log.Warn("Ignoring mailbox message from unknown sender", "mailbox", in.cfg.Name, "from", msg.from.Address)
+		return "ignored", nil
+	}
+
+	req := &request{to: in.recipients(msg), messageID: msg.id, subject: msg.subject}
+	switch {
+	case large:
+		return in.reject(req, fmt.Sprintf("Your message is too large; attachments may be at most %d bytes each.", maxFileSize))
+	case len(msg.attachments) == 0:
+		return in.reject(req, "No PDF attachments were found in your message.")
+	case len(msg.attachments) > in.maxDocuments:
+		return in.reject(req, fmt.Sprintf("Your message has %d PDF attachments; at most %d can be processed together.",
+			len(msg.attachments), in.maxDocuments))
+	}
+	for _, a := range msg.attachments {
+		if int64(len(a.data)) > maxFileSize {
+			return in.reject(req, fmt.Sprintf("%s exceeds the maximum size of %d bytes.", a.name, maxFileSize))
+		}
+	}
+
+	job, err := in.submit(ctx, req, msg.attachments)
+	if service.CodeOf(err) == service.ErrCodeBusy {
+		in.release(ctx, req)
+		return "", nil
+	}
+	if err != nil {
+		in.release(ctx, req)
+		return in.reject(req, "Your documents could not be processed: "+err.Error())
+	}
+	in.requests[job.ID] = req
+	in.log.Info("Mailbox message submitted", "mailbox", in.cfg.Name, "job_id", job.ID,
+		"from", msg.from.Address, "attachments", len(msg.attachments))
+	return "submitted", nil
+}
+
+// recipients returns the addresses results of msg are sent to
+func (in *Ingester) recipients(msg *message) []string {
+	var to []string
+	if in.cfg.Reply {
+		sender := msg.from
+		if msg.replyTo != nil {
+			sender = msg.replyTo
+		}
+		to = append(to, sender.Address)
+	}
+	return append(to, in.cfg.Forward...)
+}
+
+// submit uploads the attachments of a message and queues its job, with
+// the results delivered to the request's recipients
+func (in *Ingester) submit(ctx context.Context, req *request, attachments []attachment) (*batch.Job, error) {
+	ids := make([]string, 0, len(attachments))
+	for _, a := range attachments {
+		doc, err := in.documents.Save(ctx, a.data)
+		if err != nil {
+			return nil, err
+		}
+		req.documents = append(req.documents, doc)
+		ids = append(ids, doc.ID)
+	}
+	return in.jobs.Submit(ctx, batch.Submission{
+		Documents: ids,
+		Steps:     in.steps,
+		Priority:  batch.PriorityBulk,
+		Delivery:  &batch.Delivery{Email: req.to},
+	})
+}
+
+// settle reports the failed jobs of submitted messages and removes the
+// documents of finished ones. Results of succeeded jobs are sent by result
+// delivery.
+func (in *Ingester) settle(ctx context.Context) {
+	for id, req := range in.requests {
+		job, err := in.jobs.Get(ctx, id)
+		if err != nil {
+			if service.CodeOf(err) != service.ErrCodeNotFound {
+				in.log.Warn("Failed to check mailbox job", "mailbox", in.cfg.Name, "job_id", id, "error", err)
+				continue
+			}
+			in.reply(req, fmt.Sprintf("Your documents could not be processed: job %s expired before it finished.", id))
+		} else {
+			switch job.Status {
+			case batch.StatusSucceeded:
+			case batch.StatusFailed, batch.StatusCanceled:
+				reason := "job " + string(job.Status)
+				if f := job.Failure; f != nil {
+					reason = fmt.Sprintf("step %d (%s) failed: %s", f.Step, f.Operation, f.Error)
+				}
+				in.reply(req, fmt.Sprintf("Your documents could not be processed (job %s): %s.", id, reason))
+			default:
+				continue
+			}
+		}
+		in.release(ctx, req)
+		delete(in.requests, id)
+	}
+}
+
+// reject answers a message with why it cannot be processed
+func (in *Ingester) reject(req *request, text string) (string, error) {
+	in.reply(req, text)
+	return "rejected", nil
+}
+
+// reply sends text to the recipients of a request, threaded under its
+// message
+func (in *Ingester) reply(req *request, text string) {
+	subject := req.subject
+	if !strings.HasPrefix(strings.ToLower(subject), "re:") {
+		subject = "Re: " + subject
+	}
+	if err := in.mailer.Reply(req.to, req.messageID, subject, text); err != nil {
+		in.log.Warn("Failed to reply to mailbox message", "mailbox", in.cfg.Name, "error", err)
+	}
+}
+
+// release removes the documents uploaded for a request. Documents stored
+// before the message arrived are not the ingester's to remove.
+func (in *Ingester) release(ctx context.Context, req *request) {
+	for _, doc := range req.documents {
+		if doc.Existed {
+			continue
+		}
+		if err := in.documents.Delete(ctx, doc.ID); err != nil {
+			in.log.Warn("Failed to delete mailbox document", "mailbox", in.cfg.Name, "document", doc.ID, "error", err)
+		}
+	}
+	req.documents = nil
+}
+
+// fetch returns item of message uid
+func fetch(c *client.Client, uid uint32, item imap.FetchItem) (*imap.Message, error) {
+	set := new(imap.SeqSet)
+	set.AddNum(uid)
+	messages := make(chan *imap.Message, 1)
+	if err := c.UidFetch(set, []imap.FetchItem{imap.FetchUid, item}, messages); err != nil {
+		return nil, err
+	}
+	msg, ok := <-messages
+	if !ok {
+		return nil, fmt.Errorf("message %d no longer exists", uid)
+	}
+	return msg, nil
+}
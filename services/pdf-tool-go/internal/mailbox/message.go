@@ -0,0 +1,165 @@
+package mailbox
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"net/textproto"
+	"path"
+	"strings"
+)
+
+// maxDepth bounds the nesting of multiparts and attached messages walked
+// for attachments
+const maxDepth = 5
+
+// attachment is a PDF attached to a message
+type attachment struct {
+	name string
+	data []byte
+}
+
+// message is a parsed incoming message
+type message struct {
+	id      string
+	from    *mail.Address
+	replyTo *mail.Address
+	subject string
+	// automatic is set for messages sent by autoresponders, mailing lists
+	// and bounces, which are never answered
+	automatic   bool
+	attachments []attachment
+}
+
+// parse reads a raw RFC 5322 message and collects its PDF attachments,
+// including those of attached messages
+func parse(raw []byte) (*message, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(raw))
+	if err != nil {
+		return nil, fmt.Errorf("invalid message: %w", err)
+	}
+	m, err := newMessage(msg.Header)
+	if err != nil {
+		return nil, err
+	}
+	return m, m.walk(textproto.MIMEHeader(msg.Header), msg.Body, 0)
+}
+
+// newMessage reads the sender and threading details of a message from its
+// header
+func newMessage(header mail.Header) (*message, error) {
+	m := &message{id: strings.TrimSpace(header.Get("Message-ID"))}
+	from, err := header.AddressList("From")
+	if err != nil || len(from) == 0 {
+		return nil, fmt.Errorf("invalid From address: %v", err)
+	}
+	m.from = from[0]
+	if list, err := header.AddressList("Reply-To"); err == nil && len(list) > 0 {
+		m.replyTo = list[0]
+	}
+	if subject, err := new(mime.WordDecoder).DecodeHeader(header.Get("Subject")); err == nil {
+		m.subject = subject
+	}
+	auto := strings.ToLower(header.Get("Auto-Submitted"))
+	precedence := strings.ToLower(header.Get("Precedence"))
+	m.automatic = (auto != "" && auto != "no") || precedence == "bulk" || precedence == "list" ||
+		precedence == "junk" || header.Get("List-Id") != "" || header.Get("Return-Path") == "<>"
+	return m, nil
+}
+
+// walk collects the PDF attachments of a MIME entity
+func (m *message) walk(header textproto.MIMEHeader, body io.Reader, depth int) error {
+	if depth > maxDepth {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType = "text/plain"
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		r := multipart.NewReader(body, params["boundary"])
+		for {
+			part, err := r.NextRawPart()
+			if err == io.EOF {
+				return nil
+			}
+			if err != nil {
+				return fmt.Errorf("invalid multipart body: %w", err)
+			}
+			if err := m.walk(part.Header, part, depth+1); err != nil {
+				return err
+			}
+		}
+	case mediaType == "message/rfc822":
+		inner, err := mail.ReadMessage(decode(header, body))
+		if err != nil {
+			return nil
+		}
+		return m.walk(textproto.MIMEHeader(inner.Header), inner.Body, depth+1)
+	}
+
+	name := filename(header, params)
+	if mediaType != "application/pdf" && !strings.EqualFold(path.Ext(name), ".pdf") {
+		return nil
+	}
+	data, err := io.ReadAll(decode(header, body))
+	if err != nil {
+		return fmt.Errorf("invalid attachment %q: %w", name, err)
+	}
+	if name == "" {
+		name = fmt.Sprintf("attachment-%d.pdf", len(m.attachments)+1)
+	}
+	m.attachments = append(m.attachments, attachment{name: name, data: data})
+	return nil
+}
+
+// filename returns the decoded file name of a part, from its disposition
+// or its content type
+func filename(header textproto.MIMEHeader, params map[string]string) string {
+	name := params["name"]
+	if _, disposition, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && disposition["filename"] != "" {
+		name = disposition["filename"]
+	}
+	if decoded, err := new(mime.WordDecoder).DecodeHeader(name); err == nil {
+		name = decoded
+	}
+	if name == "" {
+		return ""
+	}
+	return path.Base(strings.ReplaceAll(name, "\\", "/"))
+}
+
+// decode undoes the transfer encoding of a part
+func decode(header textproto.MIMEHeader, body io.Reader) io.Reader {
+	switch strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding"))) {
+	case "base64":
+		return base64.NewDecoder(base64.StdEncoding, body)
+	case "quoted-printable":
+		return quotedprintable.NewReader(body)
+	}
+	return body
+}
+
+// allowed reports whether address matches one of senders: an address, or
+// "@domain" for every address of a domain. Any sender is allowed when
+// senders is empty.
+func allowed(senders []string, address string) bool {
+	if len(senders) == 0 {
+		return true
+	}
+	address = strings.ToLower(address)
+	for _, sender := range senders {
+		sender = strings.ToLower(sender)
+		if sender == address || (strings.HasPrefix(sender, "@") && strings.HasSuffix(address, sender)) {
+			return true
+		}
+	}
+	return false
+}
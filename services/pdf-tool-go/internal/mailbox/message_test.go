@@ -0,0 +1,99 @@
+package mailbox
+
+import (
+	"encoding/base64"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	pdf := base64.StdEncoding.EncodeToString([]byte("%PDF-1.7 invoice"))
+	raw := strings.ReplaceAll(`From: Alice <alice@example.com>
+Reply-To: accounts@example.com
+Subject: =?utf-8?q?Rechnungen_f=C3=BCr_M=C3=A4rz?=
+Message-ID: <abc@example.com>
+MIME-Version: 1.0
+Content-Type: multipart/mixed; boundary="outer"
+
+--outer
+Content-Type: multipart/alternative; boundary="inner"
+
+--inner
+Content-Type: text/plain
+
+Please compress these.
+--inner--
+--outer
+Content-Type: application/pdf; name="march.pdf"
+Content-Disposition: attachment; filename="march.pdf"
+Content-Transfer-Encoding: base64
+
+`+pdf[:8]+`
+`+pdf[8:]+`
+--outer
+Content-Type: application/octet-stream
+Content-Disposition: attachment; filename="scans/April.PDF"
+
+%PDF-1.7 april
+--outer
+Content-Type: image/png; name="logo.png"
+Content-Transfer-Encoding: base64
+
+iVBORw0KGgo=
+--outer
+Content-Type: message/rfc822
+
+From: Bob <bob@example.com>
+Subject: Fwd
+Content-Type: application/pdf
+Content-Transfer-Encoding: quoted-printable
+
+%PDF-1.7 forwarded=3D
+--outer--
+`, "\n", "\r\n")
+
+	msg, err := parse([]byte(raw))
+	require.NoError(t, err)
+
+	t.Run("Headers Are Decoded", func(t *testing.T) {
+		assert.Equal(t, "alice@example.com", msg.from.Address)
+		assert.Equal(t, "accounts@example.com", msg.replyTo.Address)
+		assert.Equal(t, "Rechnungen für März", msg.subject)
+		assert.Equal(t, "<abc@example.com>", msg.id)
+		assert.False(t, msg.automatic)
+	})
+
+	t.Run("PDF Attachments Are Collected", func(t *testing.T) {
+		require.Len(t, msg.attachments, 3)
+		assert.Equal(t, attachment{name: "march.pdf", data: []byte("%PDF-1.7 invoice")}, msg.attachments[0])
+		assert.Equal(t, "April.PDF", msg.attachments[1].name)
+		assert.Equal(t, "%PDF-1.7 april", string(msg.attachments[1].data))
+		assert.Equal(t, "attachment-3.pdf", msg.attachments[2].name)
+		assert.Equal(t, "%PDF-1.7 forwarded=", string(msg.attachments[2].data))
+	})
+
+	t.Run("Automatic Messages Are Flagged", func(t *testing.T) {
+		for _, header := range []string{"Auto-Submitted: auto-replied", "Precedence: bulk", "List-Id: <list.example.com>", "Return-Path: <>"} {
+			msg, err := parse([]byte("From: a@example.com\r\n" + header + "\r\n\r\nOut of office\r\n"))
+			require.NoError(t, err)
+			assert.True(t, msg.automatic, header)
+		}
+	})
+
+	t.Run("Messages Need A Sender", func(t *testing.T) {
+		_, err := parse([]byte("Subject: anonymous\r\n\r\nhello\r\n"))
+		assert.Error(t, err)
+	})
+}
+
+func TestAllowed(t *testing.T) {
+	senders := []string{"Alice@Example.com", "@partner.example"}
+	assert.True(t, allowed(nil, "anyone@example.org"))
+	assert.True(t, allowed(senders, "alice@example.com"))
+	assert.True(t, allowed(senders, "bob@partner.example"))
+	assert.False(t, allowed(senders, "bob@example.com"))
+	assert.False(t, allowed(senders, "mallory@evilpartner.example"))
+}
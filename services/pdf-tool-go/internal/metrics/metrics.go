@@ -0,0 +1,189 @@
+/**
+ * Processing Metrics
+ *
+ * Per-operation Prometheus metrics for PDF processing: outcomes, durations,
+ * pages, payload sizes, compression ratios and OCR output, plus calls to
+ * external dependencies and the state of their circuit breakers, load
+ * shedding and tenant script runs.
+ */
+
+package metrics
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+)
+
+// byteBuckets spans 1KB to 1GB
+var byteBuckets = prometheus.ExponentialBuckets(1024, 4, 11)
+
+var (
+	operationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_operations_total",
+		Help: "PDF operations by outcome",
+	}, []string{"operation", "status"})
+	failuresTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_operation_failures_total",
+		Help: "Failed PDF operations by failure reason",
+	}, []string{"operation", "reason"})
+	operationDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_operation_duration_seconds",
+		Help:    "PDF operation duration",
+		Buckets: prometheus.ExponentialBuckets(0.01, 2, 14),
+	}, []string{"operation"})
+	pagesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_pages_processed_total",
+		Help: "Pages processed by successful PDF operations",
+	}, []string{"operation"})
+	inputBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_input_bytes",
+		Help:    "Input payload size per PDF operation",
+		Buckets: byteBuckets,
+	}, []string{"operation"})
+	outputBytes = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_output_bytes",
+		Help:    "Output payload size per successful PDF operation",
+		Buckets: byteBuckets,
+	}, []string{"operation"})
+	compressionRatio = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_compression_ratio",
+		Help:    "Compressed size divided by original size",
+		Buckets: prometheus.LinearBuckets(0.1, 0.1, 10),
+	}, []string{"profile"})
+	ocrCharacters = promauto.NewCounter(prometheus.CounterOpts{
+		Name: "pdf_tool_ocr_characters_total",
+		Help: "Characters recognised by OCR",
+	})
+	ocrPageConfidence = promauto.NewHistogram(prometheus.HistogramOpts{
+		Name:    "pdf_tool_ocr_page_confidence",
+		Help:    "Mean OCR word confidence of pages, from 0 to 100",
+		Buckets: prometheus.LinearBuckets(10, 10, 9),
+	})
+	dependencyCalls = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_dependency_calls_total",
+		Help: "Calls to external dependencies by result: success, failure or rejected by an open breaker",
+	}, []string{"dependency", "result"})
+	dependencyDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "pdf_tool_dependency_duration_seconds",
+		Help:    "Duration of calls to external dependencies",
+		Buckets: prometheus.ExponentialBuckets(0.005, 2, 16),
+	}, []string{"dependency"})
+	breakerState = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "pdf_tool_breaker_state",
+		Help: "Circuit breaker state per dependency: 0 closed, 1 half-open, 2 open",
+	}, []string{"dependency"})
+	shedRequests = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_shed_requests_total",
+		Help: "Requests rejected by load shedding by priority",
+	}, []string{"priority"})
+	queueWaitP99 = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "pdf_tool_queue_wait_p99_seconds",
+		Help: "99th percentile wait for a processing slot over the load shedding window",
+	})
+	scriptRuns = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "pdf_tool_script_runs_total",
+		Help: "Tenant script runs by result: ok, error, timeout or memory",
+	}, []string{"result"})
+)
+
+// Operation accumulates measurements for a single PDF operation
+type Operation struct {
+	name        string
+	start       time.Time
+	pages       int
+	inputBytes  int64
+	outputBytes int64
+}
+
+// Start begins measuring an operation
+func Start(name string) *Operation {
+	return &Operation{name: name, start: time.Now()}
+}
+
+// Pages records the number of pages the operation processes
+func (o *Operation) Pages(n int) {
+	o.pages = n
+}
+
+// Input records the input payload size
+func (o *Operation) Input(n int64) {
+	o.inputBytes = n
+}
+
+// Output records the output payload size
+func (o *Operation) Output(n int64) {
+	o.outputBytes = n
+}
+
+// Done records the operation outcome. An empty reason marks success;
+// otherwise it is the failure reason label, typically an error code.
+func (o *Operation) Done(reason string) {
+	operationDuration.WithLabelValues(o.name).Observe(time.Since(o.start).Seconds())
+	if o.inputBytes > 0 {
+		inputBytes.WithLabelValues(o.name).Observe(float64(o.inputBytes))
+	}
+
+	if reason != "" {
+		operationsTotal.WithLabelValues(o.name, "failure").Inc()
+		failuresTotal.WithLabelValues(o.name, reason).Inc()
+		return
+	}
+
+	operationsTotal.WithLabelValues(o.name, "success").Inc()
+	pagesTotal.WithLabelValues(o.name).Add(float64(o.pages))
+	outputBytes.WithLabelValues(o.name).Observe(float64(o.outputBytes))
+}
+
+// CompressionRatio records the achieved compressed/original size ratio
+func CompressionRatio(profile string, original, compressed int64) {
+	if original <= 0 {
+		return
+	}
+	if profile == "" {
+		profile = "default"
+	}
+	compressionRatio.WithLabelValues(profile).Observe(float64(compressed) / float64(original))
+}
+
+// OCRCharacters records characters produced by OCR
+func OCRCharacters(n int) {
+	ocrCharacters.Add(float64(n))
+}
+
+// OCRPageConfidence records the mean word confidence of an OCR'd page
+func OCRPageConfidence(confidence float64) {
+	ocrPageConfidence.Observe(confidence)
+}
+
+// DependencyCall records a call to an external dependency that ran
+func DependencyCall(dependency, result string, d time.Duration) {
+	dependencyCalls.WithLabelValues(dependency, result).Inc()
+	dependencyDuration.WithLabelValues(dependency).Observe(d.Seconds())
+}
+
+// DependencyRejected records a call failed fast by an open breaker
+func DependencyRejected(dependency string) {
+	dependencyCalls.WithLabelValues(dependency, "rejected").Inc()
+}
+
+// BreakerState records the state of a dependency's circuit breaker
+func BreakerState(dependency string, state int) {
+	breakerState.WithLabelValues(dependency).Set(float64(state))
+}
+
+// Shed records a request rejected by load shedding
+func Shed(priority string) {
+	shedRequests.WithLabelValues(priority).Inc()
+}
+
+// QueueWaitP99 records the percentile slot wait load shedding acts on
+func QueueWaitP99(wait time.Duration) {
+	queueWaitP99.Set(wait.Seconds())
+}
+
+// ScriptRun records a tenant script run
+func ScriptRun(result string) {
+	scriptRuns.WithLabelValues(result).Inc()
+}
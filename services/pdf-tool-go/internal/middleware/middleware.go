@@ -1,12 +1,40 @@
 package middleware
 
 import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/audit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/concurrency"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/shedding"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/versioning"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"go.opentelemetry.io/otel/trace"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
+// PrivilegedKey is the gin context key set for callers presenting a privileged API key
+const PrivilegedKey = "privileged"
+
+// TenantKey is the gin context key holding the resolved tenant ID
+const TenantKey = "tenant"
+
+// ErrorCodeKey is the gin context key handlers set to the error code of a failed request
+const ErrorCodeKey = "error_code"
+
 func Logger(log logger.Logger) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		start := time.Now()
@@ -30,8 +58,419 @@ func Metrics() gin.HandlerFunc {
 	}
 }
 
-func RateLimiter(cfg config.RateLimitConfig) gin.HandlerFunc {
+// RateLimiter limits requests per client: the tenant owning its X-API-Key,
+// or its IP address when the key is missing or unknown, so rotating made-up
+// keys does not earn fresh allowances. Requests take tokens by the cost of
+// their endpoint; clients out of tokens get 429 with a Retry-After. A nil
+// limiter disables rate limiting.
+func RateLimiter(limiter ratelimit.Enforcer, resolver *tenant.Resolver) gin.HandlerFunc {
+	if limiter == nil {
+		return func(c *gin.Context) {
+			c.Next()
+		}
+	}
+
+	return func(c *gin.Context) {
+		client := "ip:" + c.ClientIP()
+		ctx := c.Request.Context()
+		if id, ok := resolver.Lookup(c.GetHeader("X-API-Key")); ok {
+			client = "tenant:" + id
+			quotas := resolver.Settings(id).Quotas
+			ctx = ratelimit.WithQuota(ctx, ratelimit.Quota{RequestsPerMin: quotas.RequestsPerMin, Burst: quotas.Burst})
+		}
+
+		if wait := limiter.Allow(ctx, client, versioning.RoutePath(c.FullPath())); wait > 0 {
+			c.Set(ErrorCodeKey, string(service.ErrCodeRateLimited))
+			c.Header("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, versioning.ErrorBody(c, string(service.ErrCodeRateLimited), "rate limit exceeded, retry later", nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// Privileged marks requests carrying a configured privileged API key in the
+// X-API-Key header. It never rejects requests; handlers decide what
+// privileged callers may do.
+func Privileged(cfg config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader("X-API-Key")
+		if key != "" {
+			for _, privileged := range cfg.PrivilegedKeys {
+				if subtle.ConstantTimeCompare([]byte(key), []byte(privileged)) == 1 {
+					c.Set(PrivilegedKey, true)
+					break
+				}
+			}
+		}
+		c.Next()
+	}
+}
+
+// Tenant resolves the tenant of each request from its X-API-Key header and
+// stores it, with its settings, in the request context. Missing keys and
+// privileged keys no tenant owns map to the default tenant; other unknown
+// keys are rejected with 401.
+func Tenant(resolver *tenant.Resolver) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id, err := resolver.Resolve(c.GetHeader("X-API-Key"))
+		if errors.Is(err, tenant.ErrUnknownKey) && c.GetBool(PrivilegedKey) {
+			id, err = config.DefaultTenant, nil
+		}
+		if err != nil {
+			unauthorized(c, "invalid API key")
+			return
+		}
+		c.Set(TenantKey, id)
+		ctx := tenant.WithSettings(tenant.WithID(c.Request.Context(), id), resolver.Settings(id))
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
+
+// Authenticated rejects requests without an API key with 401 unless
+// anonymous access is enabled. Tenant has already rejected unknown keys.
+func Authenticated(cfg config.AuthConfig) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !cfg.AllowAnonymous && c.GetHeader("X-API-Key") == "" {
+			unauthorized(c, "API key required")
+			return
+		}
+		c.Next()
+	}
+}
+
+func unauthorized(c *gin.Context, message string) {
+	c.Set(ErrorCodeKey, string(service.ErrCodeUnauthorized))
+	c.AbortWithStatusJSON(http.StatusUnauthorized, versioning.ErrorBody(c, string(service.ErrCodeUnauthorized), message, nil))
+}
+
+// Audit records sanitized descriptors for a sample of requests. Only
+// parameters, upload sizes and outcomes are captured, never content.
+func Audit(recorder *audit.Recorder) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if !recorder.Sample() {
+			c.Next()
+			return
+		}
+
+		start := time.Now()
+		c.Next()
+
+		descriptor := audit.Descriptor{
+			Time:          start,
+			Method:        c.Request.Method,
+			Operation:     c.FullPath(),
+			Params:        audit.SanitizeParams(c.Request.URL.Query()),
+			RequestBytes:  c.Request.ContentLength,
+			ResponseBytes: c.Writer.Size(),
+			Status:        c.Writer.Status(),
+			ErrorCode:     c.GetString(ErrorCodeKey),
+			DurationMS:    time.Since(start).Milliseconds(),
+		}
+		if sc := trace.SpanContextFromContext(c.Request.Context()); sc.IsValid() {
+			descriptor.TraceID = sc.TraceID().String()
+		}
+		// Only inspect uploads the handler already parsed
+		if form := c.Request.MultipartForm; form != nil {
+			for field, files := range form.File {
+				for _, file := range files {
+					descriptor.Files = append(descriptor.Files, audit.DescribeFile(field, file.Filename, file.Size))
+				}
+			}
+		}
+
+		recorder.Record(descriptor)
+	}
+}
+
+// corsPolicy is a CORS policy prepared for matching
+type corsPolicy struct {
+	anyOrigin        bool
+	origins          map[string]bool
+	suffixes         []string // "https://*.example.com" becomes scheme "https://" + suffix ".example.com"
+	schemes          []string
+	methods          map[string]bool
+	allowMethods     string
+	headers          map[string]bool
+	allowHeaders     string
+	exposeHeaders    string
+	allowCredentials bool
+	maxAge           string
+}
+
+func newCORSPolicy(p config.CORSPolicy) *corsPolicy {
+	policy := &corsPolicy{
+		origins:          map[string]bool{},
+		methods:          map[string]bool{},
+		headers:          map[string]bool{},
+		allowMethods:     strings.Join(p.AllowedMethods, ", "),
+		allowHeaders:     strings.Join(p.AllowedHeaders, ", "),
+		exposeHeaders:    strings.Join(p.ExposedHeaders, ", "),
+		allowCredentials: p.AllowCredentials,
+		maxAge:           strconv.Itoa(p.MaxAge),
+	}
+	for _, origin := range p.AllowedOrigins {
+		if origin == "*" {
+			policy.anyOrigin = true
+		} else if scheme, host, ok := strings.Cut(origin, "://*."); ok {
+			policy.schemes = append(policy.schemes, scheme+"://")
+			policy.suffixes = append(policy.suffixes, "."+strings.ToLower(host))
+		} else {
+			policy.origins[strings.ToLower(origin)] = true
+		}
+	}
+	for _, method := range p.AllowedMethods {
+		policy.methods[strings.ToUpper(method)] = true
+	}
+	for _, header := range p.AllowedHeaders {
+		policy.headers[http.CanonicalHeaderKey(header)] = true
+	}
+	return policy
+}
+
+func (p *corsPolicy) allowsOrigin(origin string) bool {
+	if p.anyOrigin {
+		return true
+	}
+	origin = strings.ToLower(origin)
+	if p.origins[origin] {
+		return true
+	}
+	for i, suffix := range p.suffixes {
+		if strings.HasPrefix(origin, p.schemes[i]) && strings.HasSuffix(origin, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *corsPolicy) allowsHeaders(requested string) bool {
+	for _, header := range strings.Split(requested, ",") {
+		header = http.CanonicalHeaderKey(strings.TrimSpace(header))
+		if header != "" && !p.headers[header] {
+			return false
+		}
+	}
+	return true
+}
+
+// inheritCORS fills unset fields of a route override from the default policy
+func inheritCORS(route, base config.CORSPolicy) config.CORSPolicy {
+	if len(route.AllowedOrigins) == 0 {
+		route.AllowedOrigins = base.AllowedOrigins
+	}
+	if len(route.AllowedMethods) == 0 {
+		route.AllowedMethods = base.AllowedMethods
+	}
+	if len(route.AllowedHeaders) == 0 {
+		route.AllowedHeaders = base.AllowedHeaders
+	}
+	if len(route.ExposedHeaders) == 0 {
+		route.ExposedHeaders = base.ExposedHeaders
+	}
+	if route.MaxAge == 0 {
+		route.MaxAge = base.MaxAge
+	}
+	return route
+}
+
+// CORS applies the configured CORS policy, using the longest matching
+// per-route override. Preflight requests are answered here and never reach
+// the route handlers.
+func CORS(cfg config.CORSConfig) gin.HandlerFunc {
+	base := newCORSPolicy(cfg.CORSPolicy)
+
+	type route struct {
+		prefix string
+		policy *corsPolicy
+	}
+	routes := make([]route, 0, len(cfg.Routes))
+	for _, r := range cfg.Routes {
+		routes = append(routes, route{r.PathPrefix, newCORSPolicy(inheritCORS(r.CORSPolicy, cfg.CORSPolicy))})
+	}
+	sort.Slice(routes, func(i, j int) bool { return len(routes[i].prefix) > len(routes[j].prefix) })
+
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+
+		policy := base
+		for _, r := range routes {
+			if strings.HasPrefix(c.Request.URL.Path, r.prefix) {
+				policy = r.policy
+				break
+			}
+		}
+
+		c.Writer.Header().Add("Vary", "Origin")
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		if !policy.allowsOrigin(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		if policy.anyOrigin && !policy.allowCredentials {
+			c.Header("Access-Control-Allow-Origin", "*")
+		} else {
+			c.Header("Access-Control-Allow-Origin", origin)
+		}
+		if policy.allowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+
+		if preflight {
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Method")
+			c.Writer.Header().Add("Vary", "Access-Control-Request-Headers")
+			if !policy.methods[strings.ToUpper(c.GetHeader("Access-Control-Request-Method"))] ||
+				!policy.allowsHeaders(c.GetHeader("Access-Control-Request-Headers")) {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Header("Access-Control-Allow-Methods", policy.allowMethods)
+			c.Header("Access-Control-Allow-Headers", policy.allowHeaders)
+			c.Header("Access-Control-Max-Age", policy.maxAge)
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+
+		if policy.exposeHeaders != "" {
+			c.Header("Access-Control-Expose-Headers", policy.exposeHeaders)
+		}
+		c.Next()
+	}
+}
+
+// BodyLimit caps request bodies at the size limit returns for a request,
+// 0 meaning unlimited. Bodies declaring a larger Content-Length are
+// rejected with 413 before any of them is read, so nothing is spooled to
+// disk; other bodies fail with *http.MaxBytesError once reading passes the
+// limit.
+func BodyLimit(limit func(c *gin.Context) int64) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		size := limit(c)
+		if size <= 0 {
+			c.Next()
+			return
+		}
+		if c.Request.ContentLength > size {
+			c.Header("Connection", "close")
+			c.AbortWithStatusJSON(http.StatusRequestEntityTooLarge, versioning.ErrorBody(c, string(service.ErrCodeFileTooLarge), fmt.Sprintf("request body too large: %d bytes (max %d)", c.Request.ContentLength, size), nil))
+			return
+		}
+		c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, size)
+		c.Next()
+	}
+}
+
+// Drain tracks in-flight requests and rejects new ones once the server has
+// started draining for shutdown
+func Drain(drainer *lifecycle.Drainer) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if drainer.Draining() {
+			c.Header("Connection", "close")
+			c.Header("Retry-After", "1")
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, versioning.ErrorBody(c, string(service.ErrCodeBusy), "server is shutting down, retry later", nil))
+			return
+		}
+
+		done := drainer.TrackRequest()
+		defer done()
+		c.Next()
+	}
+}
+
+// LoadShedding rejects requests the shedder drops for their priority,
+// given in X-Priority as "bulk" or "interactive" (the default)
+func LoadShedding(shedder *shedding.Shedder, retryAfter int) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		priority := shedding.PriorityInteractive
+		if name := c.GetHeader("X-Priority"); name != "" {
+			var ok bool
+			if priority, ok = shedding.ParsePriority(name); !ok {
+				c.AbortWithStatusJSON(http.StatusBadRequest, versioning.ErrorBody(c, string(service.ErrCodeInvalidInput), fmt.Sprintf("unsupported X-Priority: %q", name), nil))
+				return
+			}
+		}
+
+		if shedder.Shed(priority) {
+			metrics.Shed(priority.String())
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusServiceUnavailable, versioning.ErrorBody(c, string(service.ErrCodeBusy), "service is overloaded, retry later", nil))
+			return
+		}
+		c.Next()
+	}
+}
+
+// QueueStatus tells clients whose requests had to queue for a processing
+// slot how far back they joined, in X-Queue-Position, and the wait
+// estimated then in seconds, in X-Queue-Wait. The headers come with the
+// response, since a synchronous request cannot send headers before its
+// status; they explain a slow response rather than report progress.
+func QueueStatus() gin.HandlerFunc {
 	return func(c *gin.Context) {
+		w := &queueWriter{ResponseWriter: c.Writer}
+		c.Writer = w
+		c.Request = c.Request.WithContext(concurrency.WithObserver(c.Request.Context(), w.queued))
 		c.Next()
 	}
 }
+
+// queueWriter adds queue headers when the response is written. The first
+// time a request queues is reported; work a request fans out may queue
+// from other goroutines, so the wait is guarded.
+type queueWriter struct {
+	gin.ResponseWriter
+	mu   sync.Mutex
+	wait *concurrency.Wait
+	sent bool
+}
+
+func (w *queueWriter) queued(wait concurrency.Wait) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.wait == nil {
+		w.wait = &wait
+	}
+}
+
+// addHeaders sets the queue headers once, before the response is written
+func (w *queueWriter) addHeaders() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sent || w.wait == nil || w.ResponseWriter.Written() {
+		return
+	}
+	w.sent = true
+	w.Header().Set("X-Queue-Position", strconv.Itoa(w.wait.Position))
+	w.Header().Set("X-Queue-Wait", strconv.Itoa(int(math.Ceil(w.wait.Estimated.Seconds()))))
+}
+
+func (w *queueWriter) WriteHeader(code int) {
+	w.addHeaders()
+	w.ResponseWriter.WriteHeader(code)
+}
+
+func (w *queueWriter) WriteHeaderNow() {
+	w.addHeaders()
+	w.ResponseWriter.WriteHeaderNow()
+}
+
+func (w *queueWriter) Write(data []byte) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *queueWriter) WriteString(s string) (int, error) {
+	w.addHeaders()
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,239 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/ratelimit"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimiter(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.RateLimitConfig{Enabled: true, RequestsPerMin: 1, Burst: 2}
+	resolver := tenant.NewResolver([]config.TenantConfig{{ID: "acme", APIKeys: []string{"acme-key"}}}, nil)
+	router := gin.New()
+	router.Use(RateLimiter(ratelimit.NewEnforcer(cfg, logger.New("info", "text")), resolver))
+	router.GET("/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	get := func(key string) int {
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		req.RemoteAddr = "192.0.2.1:1234"
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	t.Run("Rotating Unknown Keys Shares The IP Bucket", func(t *testing.T) {
+		for i := 0; i < cfg.Burst; i++ {
+			assert.Equal(t, http.StatusOK, get(fmt.Sprintf("made-up-%d", i)))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get("made-up-again"))
+		assert.Equal(t, http.StatusTooManyRequests, get(""))
+	})
+
+	t.Run("Tenant Keys Have Their Own Bucket", func(t *testing.T) {
+		for i := 0; i < cfg.Burst; i++ {
+			assert.Equal(t, http.StatusOK, get("acme-key"))
+		}
+		assert.Equal(t, http.StatusTooManyRequests, get("acme-key"))
+	})
+}
+
+func TestCORS(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := config.CORSConfig{
+		CORSPolicy: config.CORSPolicy{
+			AllowedOrigins: []string{"https://app.example.com", "https://*.partner.com"},
+			AllowedMethods: []string{"GET", "POST"},
+			AllowedHeaders: []string{"Content-Type", "X-API-Key"},
+			ExposedHeaders: []string{"X-Checksum-SHA256"},
+			MaxAge:         600,
+		},
+		Routes: []config.CORSRoute{
+			{PathPrefix: "/api", CORSPolicy: config.CORSPolicy{AllowedOrigins: []string{"https://api.example.com"}}},
+			{PathPrefix: "/api/public", CORSPolicy: config.CORSPolicy{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}},
+		},
+	}
+	router := gin.New()
+	router.Use(CORS(cfg))
+	reached := false
+	handler := func(c *gin.Context) {
+		reached = true
+		c.Status(http.StatusOK)
+	}
+	for _, path := range []string{"/pdf/compress", "/api/pdf", "/api/public/docs"} {
+		router.Handle(http.MethodGet, path, handler)
+		router.Handle(http.MethodPost, path, handler)
+		router.Handle(http.MethodOptions, path, handler)
+	}
+
+	send := func(method, path, origin string, header http.Header) *httptest.ResponseRecorder {
+		reached = false
+		req := httptest.NewRequest(method, path, nil)
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		for name, values := range header {
+			req.Header[name] = values
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+	preflight := func(path, origin, method, headers string) *httptest.ResponseRecorder {
+		header := http.Header{"Access-Control-Request-Method": {method}}
+		if headers != "" {
+			header.Set("Access-Control-Request-Headers", headers)
+		}
+		return send(http.MethodOptions, path, origin, header)
+	}
+
+	t.Run("Allowed Preflight Is Answered", func(t *testing.T) {
+		w := preflight("/pdf/compress", "https://app.example.com", "POST", "content-type, x-api-key")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.False(t, reached)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "GET, POST", w.Header().Get("Access-Control-Allow-Methods"))
+		assert.Equal(t, "Content-Type, X-API-Key", w.Header().Get("Access-Control-Allow-Headers"))
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Contains(t, w.Header().Values("Vary"), "Origin")
+	})
+
+	t.Run("Preflight From Unknown Origin Is Forbidden", func(t *testing.T) {
+		w := preflight("/pdf/compress", "https://evil.example.net", "POST", "")
+		assert.Equal(t, http.StatusForbidden, w.Code)
+		assert.False(t, reached)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+	})
+
+	t.Run("Preflight For Disallowed Method Or Header Is Forbidden", func(t *testing.T) {
+		assert.Equal(t, http.StatusForbidden, preflight("/pdf/compress", "https://app.example.com", "DELETE", "").Code)
+		assert.Equal(t, http.StatusForbidden, preflight("/pdf/compress", "https://app.example.com", "POST", "X-Secret").Code)
+		assert.False(t, reached)
+	})
+
+	t.Run("Plain OPTIONS Reaches The Handler", func(t *testing.T) {
+		w := send(http.MethodOptions, "/pdf/compress", "https://app.example.com", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, reached)
+	})
+
+	t.Run("Simple Request", func(t *testing.T) {
+		w := send(http.MethodGet, "/pdf/compress", "https://app.example.com", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.True(t, reached)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "X-Checksum-SHA256", w.Header().Get("Access-Control-Expose-Headers"))
+
+		w = send(http.MethodGet, "/pdf/compress", "https://evil.example.net", nil)
+		assert.Equal(t, http.StatusOK, w.Code)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+		w = send(http.MethodGet, "/pdf/compress", "", nil)
+		assert.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Empty(t, w.Header().Values("Vary"))
+	})
+
+	t.Run("Wildcard Subdomains", func(t *testing.T) {
+		for _, origin := range []string{"https://eu.partner.com", "https://a.b.partner.com", "HTTPS://EU.PARTNER.COM"} {
+			w := preflight("/pdf/compress", origin, "GET", "")
+			assert.Equal(t, http.StatusNoContent, w.Code, origin)
+		}
+		for _, origin := range []string{"https://partner.com", "http://eu.partner.com", "https://evilpartner.com", "https://partner.com.evil.net"} {
+			w := preflight("/pdf/compress", origin, "GET", "")
+			assert.Equal(t, http.StatusForbidden, w.Code, origin)
+		}
+	})
+
+	t.Run("Longest Route Prefix Wins", func(t *testing.T) {
+		// /api replaces the origins and inherits methods and headers
+		w := preflight("/api/pdf", "https://api.example.com", "POST", "X-API-Key")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "600", w.Header().Get("Access-Control-Max-Age"))
+		assert.Equal(t, http.StatusForbidden, preflight("/api/pdf", "https://app.example.com", "POST", "").Code)
+
+		// /api/public is longer than /api, so it applies to its paths
+		w = preflight("/api/public/docs", "https://anyone.example.org", "GET", "")
+		assert.Equal(t, http.StatusNoContent, w.Code)
+		assert.Equal(t, "*", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, http.StatusForbidden, preflight("/api/public/docs", "https://anyone.example.org", "POST", "").Code)
+	})
+
+	t.Run("Credentials Echo The Origin", func(t *testing.T) {
+		credentialed := cfg
+		credentialed.Routes = nil
+		credentialed.AllowedOrigins = []string{"*"}
+		credentialed.AllowCredentials = true
+		router := gin.New()
+		router.Use(CORS(credentialed))
+		router.GET("/pdf", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		req := httptest.NewRequest(http.MethodGet, "/pdf", nil)
+		req.Header.Set("Origin", "https://app.example.com")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		assert.Equal(t, "https://app.example.com", w.Header().Get("Access-Control-Allow-Origin"))
+		assert.Equal(t, "true", w.Header().Get("Access-Control-Allow-Credentials"))
+	})
+}
+
+func TestTenant(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	resolver := tenant.NewResolver([]config.TenantConfig{{ID: "acme", APIKeys: []string{"acme-key"}}}, nil)
+	auth := config.AuthConfig{PrivilegedKeys: []string{"admin-key"}}
+
+	get := func(auth config.AuthConfig, key string) (int, string) {
+		router := gin.New()
+		router.Use(Privileged(auth), Tenant(resolver), Authenticated(auth))
+		router.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, tenant.FromContext(c.Request.Context())) })
+
+		req := httptest.NewRequest(http.MethodGet, "/ping", nil)
+		if key != "" {
+			req.Header.Set("X-API-Key", key)
+		}
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	t.Run("Tenant Keys Resolve", func(t *testing.T) {
+		code, id := get(auth, "acme-key")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, "acme", id)
+	})
+
+	t.Run("Unknown Keys Are Rejected", func(t *testing.T) {
+		code, body := get(auth, "acme-kye")
+		assert.Equal(t, http.StatusUnauthorized, code)
+		assert.Contains(t, body, "UNAUTHORIZED")
+	})
+
+	t.Run("Privileged Keys Use The Default Tenant", func(t *testing.T) {
+		code, id := get(auth, "admin-key")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, config.DefaultTenant, id)
+	})
+
+	t.Run("Missing Keys Need Anonymous Access", func(t *testing.T) {
+		code, _ := get(auth, "")
+		assert.Equal(t, http.StatusUnauthorized, code)
+
+		anonymous := auth
+		anonymous.AllowAnonymous = true
+		code, id := get(anonymous, "")
+		assert.Equal(t, http.StatusOK, code)
+		assert.Equal(t, config.DefaultTenant, id)
+		code, _ = get(anonymous, "made-up")
+		assert.Equal(t, http.StatusUnauthorized, code)
+	})
+}
@@ -0,0 +1,108 @@
+/**
+ * Named Entity Recognition
+ *
+ * HTTP backends for entity extraction beyond regular expressions, such as
+ * a hosted NER model finding names and addresses. Backends receive the text
+ * of one page and return labelled spans.
+ */
+
+package ner
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// maxResponseBytes bounds backend responses
+const maxResponseBytes = 16 << 20
+
+// request is the body posted to backends
+type request struct {
+	Text string `json:"text"`
+}
+
+// response is the entity list backends return. Offsets are bytes into the
+// posted text.
+type response struct {
+	Entities []struct {
+		Type  string `json:"type"`
+		Start int    `json:"start"`
+		End   int    `json:"end"`
+	} `json:"entities"`
+}
+
+// Extractor posts page text as JSON to an HTTP endpoint and reads labelled
+// spans from the JSON response
+type Extractor struct {
+	url    string
+	token  string
+	client *http.Client
+}
+
+// New creates the backend described by cfg
+func New(cfg config.NERBackend) *Extractor {
+	return &Extractor{
+		url:    cfg.URL,
+		token:  cfg.Token,
+		client: &http.Client{Timeout: time.Duration(cfg.Timeout) * time.Second},
+	}
+}
+
+// Extract implements service.EntityExtractor
+func (e *Extractor) Extract(ctx context.Context, text string) ([]service.Entity, error) {
+	payload, err := json.Marshal(request{Text: text})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.url, bytes.NewReader(payload))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if e.token != "" {
+		req.Header.Set("Authorization", "Bearer "+e.token)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read NER response: %w", err)
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		return nil, service.NewError(service.ErrCodeBusy, "entity recognition backend is busy", nil)
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("NER backend returned status %d: %s", resp.StatusCode, bytes.TrimSpace(body))
+	}
+	return decode(body, text)
+}
+
+// decode parses a backend entity list, taking values from text so they
+// cannot disagree with the offsets
+func decode(data []byte, text string) ([]service.Entity, error) {
+	var resp response
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("invalid NER response: %w", err)
+	}
+	entities := make([]service.Entity, 0, len(resp.Entities))
+	for _, e := range resp.Entities {
+		if e.Start < 0 || e.End <= e.Start || e.End > len(text) {
+			return nil, fmt.Errorf("invalid NER response: span %d-%d outside the text", e.Start, e.End)
+		}
+		entities = append(entities, service.Entity{Type: e.Type, Value: text[e.Start:e.End], Start: e.Start, End: e.End})
+	}
+	return entities, nil
+}
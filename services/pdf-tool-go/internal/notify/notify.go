@@ -0,0 +1,168 @@
+/**
+ * Job Notifications
+ *
+ * Posts summaries of finished batch jobs to Slack and Microsoft Teams
+ * incoming webhooks, so back-office teams running bulk jobs hear about
+ * completions and failures without polling.
+ */
+
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var notificationsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_notifications_total",
+	Help: "Job notifications posted to webhooks by sink and outcome",
+}, []string{"sink", "outcome"})
+
+// themeColors are the Teams card colors of job statuses
+var themeColors = map[batch.Status]string{
+	batch.StatusSucceeded: "2EB886",
+	batch.StatusFailed:    "E01E5A",
+	batch.StatusCanceled:  "A0A0A0",
+}
+
+// sink is a configured webhook
+type sink struct {
+	cfg     config.NotificationSink
+	client  *http.Client
+	events  map[string]bool
+	tenants map[string]bool
+}
+
+// wants reports whether the sink posts job
+func (s *sink) wants(job *batch.Job) bool {
+	return (len(s.events) == 0 || s.events[string(job.Status)]) &&
+		(len(s.tenants) == 0 || s.tenants[job.Tenant])
+}
+
+// Notifier posts finished jobs to the configured sinks. It implements
+// batch.Notifier.
+type Notifier struct {
+	sinks []*sink
+	log   logger.Logger
+}
+
+// New creates a notifier for the configured sinks
+func New(cfg config.NotificationConfig, log logger.Logger) *Notifier {
+	n := &Notifier{log: log}
+	for _, c := range cfg.Sinks {
+		s := &sink{
+			cfg:     c,
+			client:  &http.Client{Timeout: time.Duration(c.Timeout) * time.Second},
+			events:  make(map[string]bool, len(c.Events)),
+			tenants: make(map[string]bool, len(c.Tenants)),
+		}
+		for _, event := range c.Events {
+			s.events[event] = true
+		}
+		for _, tenant := range c.Tenants {
+			s.tenants[tenant] = true
+		}
+		n.sinks = append(n.sinks, s)
+	}
+	return n
+}
+
+// Notify posts a summary of job to each sink that wants it. Failures are
+// logged; a job's outcome does not depend on its notifications.
+func (n *Notifier) Notify(ctx context.Context, job *batch.Job) {
+	title, details := summarize(job)
+	for _, s := range n.sinks {
+		if !s.wants(job) {
+			continue
+		}
+		if err := s.post(ctx, payload(s.cfg.Type, job.Status, title, details)); err != nil {
+			// Webhook URLs are credentials; keep them out of the log
+			var urlErr *url.Error
+			if errors.As(err, &urlErr) {
+				err = urlErr.Err
+			}
+			notificationsTotal.WithLabelValues(s.cfg.Name, "failed").Inc()
+			n.log.Warn("Failed to post job notification", "sink", s.cfg.Name, "job_id", job.ID, "error", err)
+			continue
+		}
+		notificationsTotal.WithLabelValues(s.cfg.Name, "posted").Inc()
+	}
+}
+
+// summarize describes a finished job in a title and detail lines
+func summarize(job *batch.Job) (string, []string) {
+	title := fmt.Sprintf("Batch job %s %s", job.ID, job.Status)
+	details := []string{"Tenant: " + job.Tenant, fmt.Sprintf("Documents: %d, steps: %d", len(job.Documents), len(job.Steps))}
+	if job.StartedAt != nil && job.FinishedAt != nil {
+		details = append(details, "Duration: "+job.FinishedAt.Sub(*job.StartedAt).Round(time.Second).String())
+	}
+	if job.Status == batch.StatusSucceeded {
+		details = append(details, fmt.Sprintf("Results: %d", len(job.Results)))
+	}
+	if f := job.Failure; f != nil {
+		at := fmt.Sprintf("Step %d (%s)", f.Step, f.Operation)
+		if f.InputName != "" {
+			at += ", input " + f.InputName
+		}
+		details = append(details, fmt.Sprintf("%s: %s: %s", at, f.Code, f.Error))
+	}
+	if job.DeadLetteredAt != nil {
+		details = append(details, fmt.Sprintf("Dead-lettered after %d attempts", job.Attempts))
+	}
+	return title, details
+}
+
+// payload builds the webhook body of a summary: a plain message for Slack
+// and a message card for Teams
+func payload(kind string, status batch.Status, title string, details []string) interface{} {
+	if kind == "teams" {
+		return map[string]string{
+			"@type":      "MessageCard",
+			"@context":   "https://schema.org/extensions",
+			"summary":    title,
+			"title":      title,
+			"themeColor": themeColors[status],
+			// Teams renders markdown, which needs blank lines between lines
+			"text": strings.Join(details, "\n\n"),
+		}
+	}
+	return map[string]string{"text": "*" + title + "*\n" + strings.Join(details, "\n")}
+}
+
+// post sends body to the sink's webhook
+func (s *sink) post(ctx context.Context, body interface{}) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.cfg.URL, bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("webhook returned status %d: %s", resp.StatusCode, bytes.TrimSpace(text))
+	}
+	return nil
+}
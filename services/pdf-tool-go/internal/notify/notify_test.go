@@ -0,0 +1,67 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNotifier(t *testing.T) {
+	posted := make(map[string][]map[string]string)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&body))
+		posted[r.URL.Path] = append(posted[r.URL.Path], body)
+		if r.URL.Path == "/broken" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	n := New(config.NotificationConfig{Sinks: []config.NotificationSink{
+		{Name: "ops", Type: "slack", URL: server.URL + "/slack", Timeout: 5},
+		{Name: "back-office", Type: "teams", URL: server.URL + "/teams", Events: []string{"failed"}, Tenants: []string{"acme"}, Timeout: 5},
+		{Name: "broken", Type: "slack", URL: server.URL + "/broken", Timeout: 5},
+	}}, logger.New("error", "text"))
+
+	started := time.Now()
+	finished := started.Add(90 * time.Second)
+	succeeded := &batch.Job{ID: "job-1", Tenant: "acme", Status: batch.StatusSucceeded, Documents: []string{"a", "b"},
+		Steps: []batch.Step{{Operation: "merge"}}, Results: []string{"r"}, StartedAt: &started, FinishedAt: &finished}
+	failed := &batch.Job{ID: "job-2", Tenant: "acme", Status: batch.StatusFailed, Attempts: 3, DeadLetteredAt: &finished,
+		Failure: &batch.Failure{Step: 2, Operation: "compress", InputName: "doc-a#1", Code: service.ErrCodeCorrupted, Error: "PDF is corrupted"}}
+	other := &batch.Job{ID: "job-3", Tenant: "globex", Status: batch.StatusFailed}
+
+	for _, job := range []*batch.Job{succeeded, failed, other} {
+		n.Notify(context.Background(), job)
+	}
+
+	t.Run("Slack Gets Every Job", func(t *testing.T) {
+		require.Len(t, posted["/slack"], 3)
+		assert.Equal(t, "*Batch job job-1 succeeded*\nTenant: acme\nDocuments: 2, steps: 1\nDuration: 1m30s\nResults: 1", posted["/slack"][0]["text"])
+		assert.Contains(t, posted["/slack"][1]["text"], "Step 2 (compress), input doc-a#1: PDF_CORRUPTED: PDF is corrupted")
+		assert.Contains(t, posted["/slack"][1]["text"], "Dead-lettered after 3 attempts")
+	})
+
+	t.Run("Teams Gets Filtered Jobs As Cards", func(t *testing.T) {
+		require.Len(t, posted["/teams"], 1)
+		card := posted["/teams"][0]
+		assert.Equal(t, "MessageCard", card["@type"])
+		assert.Equal(t, "Batch job job-2 failed", card["title"])
+		assert.Equal(t, "E01E5A", card["themeColor"])
+	})
+
+	t.Run("Failing Sinks Do Not Stop Others", func(t *testing.T) {
+		assert.Len(t, posted["/broken"], 3)
+	})
+}
@@ -0,0 +1,75 @@
+package openapi
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSpec(t *testing.T) {
+	data, err := Spec("2.3.4")
+	require.NoError(t, err)
+
+	var doc struct {
+		OpenAPI string `json:"openapi"`
+		Info    struct {
+			Version string `json:"version"`
+		} `json:"info"`
+		Paths map[string]map[string]interface{} `json:"paths"`
+	}
+	require.NoError(t, json.Unmarshal(data, &doc))
+
+	assert.Equal(t, "3.0.3", doc.OpenAPI)
+	assert.Equal(t, "2.3.4", doc.Info.Version)
+
+	// Routes registered in cmd/server; keep in sync
+	routes := map[string]string{
+		"/api/v1/pdf/convert/image":       "post",
+		"/api/v1/pdf/merge":               "post",
+		"/api/v1/pdf/assemble":            "post",
+		"/api/v1/pdf/audit-trail":         "post",
+		"/api/v1/pdf/secure-section":      "post",
+		"/api/v1/pdf/split":               "post",
+		"/api/v1/pdf/extract/text":        "post",
+		"/api/v1/pdf/extract/metadata":    "post",
+		"/api/v1/pdf/page-labels":         "post",
+		"/api/v1/pdf/extract/links":       "post",
+		"/api/v1/pdf/navigation/check":    "post",
+		"/api/v1/pdf/compress":            "post",
+		"/api/v1/pdf/watermark":           "post",
+		"/api/v1/pdf/decrypt":             "post",
+		"/api/v1/pdf/{docId}/pages/{n}":   "get",
+		"/api/v1/documents":               "post",
+		"/api/v1/documents/{id}":          "get",
+		"/api/v1/documents/import":        "post",
+		"/api/v1/results/{id}":            "get",
+		"/api/v1/results/{id}/share":      "post",
+		"/api/v1/share/{token}":           "get",
+		"/api/v1/results/{id}/export":     "post",
+		"/api/v1/results/{id}/signatures": "post",
+		"/api/v1/batch/process":           "post",
+		"/api/v1/batch/mailmerge":         "post",
+		"/api/v1/batch/status/{id}":       "get",
+		"/api/v1/batch/{id}":              "delete",
+		"/api/v1/batch/{id}/pause":        "post",
+		"/api/v1/batch/{id}/resume":       "post",
+		"/health":                         "get",
+		"/ready":                          "get",
+	}
+	for path, method := range routes {
+		assert.Contains(t, doc.Paths[path], method, "%s %s missing from spec", method, path)
+	}
+}
+
+func TestSwaggerUI(t *testing.T) {
+	page := string(SwaggerUI())
+	assert.NotContains(t, page, "https://", "the docs page loads no third-party assets")
+	assert.Contains(t, page, `src="/docs/assets/swagger-ui-bundle.js"`)
+
+	for _, name := range []string{"README.md", "../openapi.json", "swagger-ui.map", ""} {
+		_, ok := SwaggerAsset(name)
+		assert.False(t, ok, name)
+	}
+}
@@ -0,0 +1,202 @@
+/**
+ * Processing Pipelines
+ *
+ * Runs an operation as a sequence of steps (validate, stage, transform,
+ * persist) with shared tracing, metrics, deadlines and temp-file handling,
+ * so operations only implement the steps specific to them. Handlers respond
+ * with the result.
+ */
+
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("pipeline")
+
+// Mode selects how a step is executed
+type Mode int
+
+const (
+	// Inline steps run on the caller's goroutine, for validation and cheap
+	// checks
+	Inline Mode = iota
+	// Cancellable steps return as soon as the context is done; abandoned
+	// work stops at its next checkpoint in the background
+	Cancellable
+	// Heavy steps are cancellable and hold a concurrency slot while they run
+	Heavy
+)
+
+// Step is one stage of an operation
+type Step struct {
+	Name string
+	Mode Mode
+	Fn   func(st *State) error
+}
+
+// State is the data an operation's steps share. Temp files a step stages
+// are removed when the step returns, on whichever goroutine ran it, so
+// work abandoned by a cancelled caller still cleans up after itself.
+type State struct {
+	Ctx   context.Context
+	Input []byte
+	// Pages is the page count of the input, once a step has read it
+	Pages int
+	// Output is the single output document; Parts holds outputs of
+	// operations producing several
+	Output []byte
+	Parts  [][]byte
+	// Operation is the name of the operation being run
+	Operation string
+	// OutputName is a base name for the output file chosen by a step,
+	// reported to the context's name observer
+	OutputName string
+
+	tempDir string
+	files   []string
+}
+
+// Stage writes data to a temp file and returns its path
+func (st *State) Stage(data []byte, pattern string) (string, error) {
+	_, span := tracer.Start(st.Ctx, "tempfile.write", trace.WithAttributes(attribute.Int("bytes", len(data))))
+	defer span.End()
+
+	if err := os.MkdirAll(st.tempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	file, err := os.CreateTemp(st.tempDir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp file: %w", err)
+	}
+	defer file.Close()
+	st.files = append(st.files, file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		return "", fmt.Errorf("failed to write temp file: %w", err)
+	}
+	return file.Name(), nil
+}
+
+// OutputDir creates a temp directory for a tool to write output to,
+// removed like staged files
+func (st *State) OutputDir(pattern string) (string, error) {
+	if err := os.MkdirAll(st.tempDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	dir, err := os.MkdirTemp(st.tempDir, pattern)
+	if err != nil {
+		return "", fmt.Errorf("failed to create temp directory: %w", err)
+	}
+	st.files = append(st.files, dir)
+	return dir, nil
+}
+
+// ReadFile reads a tool's output file
+func (st *State) ReadFile(path string) ([]byte, error) {
+	_, span := tracer.Start(st.Ctx, "tempfile.read")
+	data, err := os.ReadFile(path)
+	telemetry.EndSpan(span, err)
+	return data, err
+}
+
+// cleanup removes the temp files staged so far
+func (st *State) cleanup() {
+	for _, file := range st.files {
+		os.RemoveAll(file)
+	}
+	st.files = nil
+}
+
+// outputSize is the total size of the outputs
+func (st *State) outputSize() int64 {
+	size := int64(len(st.Output))
+	for _, part := range st.Parts {
+		size += int64(len(part))
+	}
+	return size
+}
+
+type nameObserverKey struct{}
+
+// WithNameObserver returns a context under which pipelines report the
+// output names their steps choose to observe
+func WithNameObserver(ctx context.Context, observe func(name string)) context.Context {
+	return context.WithValue(ctx, nameObserverKey{}, observe)
+}
+
+// Engine runs pipelines. The hooks tie it into the service: deadlines per
+// operation, background execution with concurrency limits, and the
+// failure reason metrics label errors with. After, when set, returns steps
+// run after every operation's own steps.
+type Engine struct {
+	TempDir        string
+	Timeout        func(ctx context.Context, operation string) (context.Context, context.CancelFunc)
+	RunCancellable func(ctx context.Context, fn func() error) error
+	RunHeavy       func(ctx context.Context, fn func() error) error
+	Reason         func(err error) string
+	After          func(operation string) []Step
+}
+
+// Run runs steps in order over input, stopping at the first error. The
+// operation name labels its span and metrics and selects its deadline.
+func (e *Engine) Run(ctx context.Context, operation string, input []byte, steps ...Step) (_ *State, err error) {
+	ctx, span := tracer.Start(ctx, "pipeline."+operation)
+	defer func() { telemetry.EndSpan(span, err) }()
+
+	op := metrics.Start(operation)
+	op.Input(int64(len(input)))
+	defer func() { op.Done(e.Reason(err)) }()
+
+	ctx, cancel := e.Timeout(ctx, operation)
+	defer cancel()
+
+	if e.After != nil {
+		steps = append(steps[:len(steps):len(steps)], e.After(operation)...)
+	}
+
+	st := &State{Ctx: ctx, Input: input, Operation: operation, tempDir: e.TempDir}
+	for _, step := range steps {
+		if err := e.runStep(st, step); err != nil {
+			return nil, err
+		}
+	}
+
+	if observe, ok := ctx.Value(nameObserverKey{}).(func(string)); ok && st.OutputName != "" {
+		observe(st.OutputName)
+	}
+
+	span.SetAttributes(attribute.Int("pages", st.Pages))
+	op.Pages(st.Pages)
+	op.Output(st.outputSize())
+	return st, nil
+}
+
+// runStep runs one step in its own span and mode
+func (e *Engine) runStep(st *State, step Step) error {
+	fn := func() error {
+		_, span := tracer.Start(st.Ctx, "pipeline.step."+step.Name)
+		defer st.cleanup()
+		err := step.Fn(st)
+		telemetry.EndSpan(span, err)
+		return err
+	}
+
+	switch step.Mode {
+	case Cancellable:
+		return e.RunCancellable(st.Ctx, fn)
+	case Heavy:
+		return e.RunHeavy(st.Ctx, fn)
+	default:
+		return fn()
+	}
+}
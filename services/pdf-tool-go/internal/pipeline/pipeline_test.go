@@ -0,0 +1,86 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Run(t *testing.T) {
+	run := func(ctx context.Context, fn func() error) error { return fn() }
+	engine := &Engine{
+		TempDir: t.TempDir(),
+		Timeout: func(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+			return context.WithCancel(ctx)
+		},
+		RunCancellable: run,
+		RunHeavy:       run,
+		Reason: func(err error) string {
+			if err != nil {
+				return "failed"
+			}
+			return ""
+		},
+	}
+
+	t.Run("Steps Share State", func(t *testing.T) {
+		var staged string
+		st, err := engine.Run(context.Background(), "test", []byte("input"),
+			Step{Name: "check", Fn: func(st *State) error {
+				st.Pages = 3
+				return nil
+			}},
+			Step{Name: "transform", Mode: Heavy, Fn: func(st *State) (err error) {
+				if staged, err = st.Stage(st.Input, "test-*.pdf"); err != nil {
+					return err
+				}
+				st.Output, err = st.ReadFile(staged)
+				return err
+			}},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, 3, st.Pages)
+		assert.Equal(t, []byte("input"), st.Output)
+
+		_, err = os.Stat(staged)
+		assert.True(t, os.IsNotExist(err), "staged files are removed after their step")
+	})
+
+	t.Run("Stops At First Error", func(t *testing.T) {
+		errInvalid := errors.New("invalid")
+		ran := false
+		_, err := engine.Run(context.Background(), "test", nil,
+			Step{Name: "validate", Fn: func(*State) error { return errInvalid }},
+			Step{Name: "transform", Fn: func(*State) error {
+				ran = true
+				return nil
+			}},
+		)
+		assert.ErrorIs(t, err, errInvalid)
+		assert.False(t, ran)
+	})
+
+	t.Run("After Steps Run Last And Name The Output", func(t *testing.T) {
+		hooked := *engine
+		hooked.After = func(operation string) []Step {
+			return []Step{{Name: "rename", Fn: func(st *State) error {
+				st.OutputName = operation + "-" + string(st.Output)
+				return nil
+			}}}
+		}
+
+		var name string
+		ctx := WithNameObserver(context.Background(), func(n string) { name = n })
+		_, err := hooked.Run(ctx, "test", nil,
+			Step{Name: "transform", Fn: func(st *State) error {
+				st.Output = []byte("output")
+				return nil
+			}},
+		)
+		assert.NoError(t, err)
+		assert.Equal(t, "test-output", name)
+	})
+}
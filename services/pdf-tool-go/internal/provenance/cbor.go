@@ -0,0 +1,101 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+)
+
+// cborMap is a CBOR map written in the order of its entries, so encodings
+// are deterministic
+type cborMap []cborEntry
+
+// cborEntry is an entry of a cborMap
+type cborEntry struct {
+	key   interface{}
+	value interface{}
+}
+
+// cborTag is a tagged CBOR item
+type cborTag struct {
+	number  uint64
+	content interface{}
+}
+
+// encodeCBOR encodes nil, booleans, integers, strings, byte strings,
+// []interface{} arrays, maps and tags as CBOR (RFC 8949)
+func encodeCBOR(v interface{}) []byte {
+	var b bytes.Buffer
+	writeCBOR(&b, v)
+	return b.Bytes()
+}
+
+func writeCBOR(b *bytes.Buffer, v interface{}) {
+	switch v := v.(type) {
+	case nil:
+		b.WriteByte(0xf6)
+	case bool:
+		if v {
+			b.WriteByte(0xf5)
+		} else {
+			b.WriteByte(0xf4)
+		}
+	case int:
+		writeInt(b, int64(v))
+	case int64:
+		writeInt(b, v)
+	case uint64:
+		writeHead(b, 0, v)
+	case string:
+		writeHead(b, 3, uint64(len(v)))
+		b.WriteString(v)
+	case []byte:
+		writeHead(b, 2, uint64(len(v)))
+		b.Write(v)
+	case []interface{}:
+		writeHead(b, 4, uint64(len(v)))
+		for _, item := range v {
+			writeCBOR(b, item)
+		}
+	case cborMap:
+		writeHead(b, 5, uint64(len(v)))
+		for _, entry := range v {
+			writeCBOR(b, entry.key)
+			writeCBOR(b, entry.value)
+		}
+	case cborTag:
+		writeHead(b, 6, v.number)
+		writeCBOR(b, v.content)
+	default:
+		panic(fmt.Sprintf("cbor: unsupported type %T", v))
+	}
+}
+
+func writeInt(b *bytes.Buffer, v int64) {
+	if v < 0 {
+		writeHead(b, 1, uint64(-1-v))
+		return
+	}
+	writeHead(b, 0, uint64(v))
+}
+
+// writeHead writes the initial bytes of an item of a major type, in the
+// shortest form for its argument
+func writeHead(b *bytes.Buffer, major byte, arg uint64) {
+	major <<= 5
+	switch {
+	case arg < 24:
+		b.WriteByte(major | byte(arg))
+	case arg <= 0xff:
+		b.Write([]byte{major | 24, byte(arg)})
+	case arg <= 0xffff:
+		b.WriteByte(major | 25)
+		binary.Write(b, binary.BigEndian, uint16(arg))
+	case arg <= 0xffffffff:
+		b.WriteByte(major | 26)
+		binary.Write(b, binary.BigEndian, uint32(arg))
+	default:
+		b.WriteByte(major | 27)
+		binary.Write(b, binary.BigEndian, arg)
+	}
+}
@@ -0,0 +1,54 @@
+package provenance
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/hex"
+)
+
+// JUMBF content types of C2PA boxes (ISO/IEC 19566-5), each a four
+// character code followed by the ISO suffix
+var (
+	typeStore      = jumbfType("c2pa")
+	typeManifest   = jumbfType("c2ma")
+	typeAssertions = jumbfType("c2as")
+	typeClaim      = jumbfType("c2cl")
+	typeSignature  = jumbfType("c2cs")
+	typeCBOR       = jumbfType("cbor")
+)
+
+// isoSuffix completes the content type UUIDs
+var isoSuffix, _ = hex.DecodeString("00110010800000AA00389B71")
+
+func jumbfType(code string) []byte {
+	return append([]byte(code), isoSuffix...)
+}
+
+// box encodes an ISO BMFF box of a type
+func box(boxType string, content ...[]byte) []byte {
+	size := 8
+	for _, c := range content {
+		size += len(c)
+	}
+	var b bytes.Buffer
+	binary.Write(&b, binary.BigEndian, uint32(size))
+	b.WriteString(boxType)
+	for _, c := range content {
+		b.Write(c)
+	}
+	return b.Bytes()
+}
+
+// superbox encodes a labelled JUMBF superbox of a content type holding
+// boxes
+func superbox(contentType []byte, label string, boxes ...[]byte) []byte {
+	// Toggles: requestable, with a label
+	description := append(append(append([]byte{}, contentType...), 0x03), label...)
+	description = append(description, 0)
+	return box("jumb", append([][]byte{box("jumd", description)}, boxes...)...)
+}
+
+// cborAssertion encodes an assertion superbox holding CBOR
+func cborAssertion(label string, v interface{}) []byte {
+	return superbox(typeCBOR, label, box("cbor", encodeCBOR(v)))
+}
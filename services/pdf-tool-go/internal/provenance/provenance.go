@@ -0,0 +1,296 @@
+/**
+ * Content Credentials
+ *
+ * Builds C2PA manifests recording the operations applied to a document,
+ * by which service and when, signed with a configured key. The service
+ * embeds a manifest in each PDF output of the tenants opted in; its hash
+ * binding covers the whole file except the manifest, so any later change
+ * to the document invalidates it.
+ */
+
+package provenance
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+// COSE algorithm identifiers (RFC 9053)
+const (
+	algES256 = -7
+	algES384 = -35
+	algPS256 = -37
+	algEdDSA = -8
+)
+
+// COSE header labels
+const (
+	headerAlg     = 1
+	headerX5Chain = 33
+)
+
+// basePad is the padding of the hash assertion of a placeholder. Sealed
+// manifests are never longer than their placeholder, and the difference is
+// added to the padding so they replace it byte for byte.
+const basePad = 32
+
+// unknownOffset stands in for the manifest's position in placeholders; it
+// encodes to the largest size positions of supported files encode to
+const unknownOffset = uint64(0xffffffff)
+
+// Signer signs the manifests of the tenants opted in
+type Signer struct {
+	key   crypto.Signer
+	alg   int
+	hash  crypto.Hash
+	chain [][]byte
+	// agent names the service and its version as claim generator
+	agent   string
+	version string
+	tenants map[string]bool
+}
+
+// New creates a signer from the configured key and certificate chain for
+// the tenants with provenance enabled
+func New(cfg config.ProvenanceConfig, tenants []config.TenantConfig, version string) (*Signer, error) {
+	key, err := parseKey(cfg.PrivateKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance.private_key: %w", err)
+	}
+	s := &Signer{key: key, agent: "pdf-tool-go/" + version, version: version, tenants: make(map[string]bool)}
+
+	switch k := key.(type) {
+	case *ecdsa.PrivateKey:
+		switch k.Curve {
+		case elliptic.P256():
+			s.alg, s.hash = algES256, crypto.SHA256
+		case elliptic.P384():
+			s.alg, s.hash = algES384, crypto.SHA384
+		default:
+			return nil, fmt.Errorf("invalid provenance.private_key: unsupported curve %s", k.Curve.Params().Name)
+		}
+	case *rsa.PrivateKey:
+		if k.N.BitLen() < 2048 {
+			return nil, fmt.Errorf("invalid provenance.private_key: RSA keys need at least 2048 bits")
+		}
+		s.alg, s.hash = algPS256, crypto.SHA256
+	case ed25519.PrivateKey:
+		s.alg = algEdDSA
+	default:
+		return nil, fmt.Errorf("invalid provenance.private_key: unsupported key type %T", key)
+	}
+
+	for rest := []byte(cfg.Certificate); ; {
+		var block *pem.Block
+		if block, rest = pem.Decode(rest); block == nil {
+			break
+		}
+		if block.Type == "CERTIFICATE" {
+			s.chain = append(s.chain, block.Bytes)
+		}
+	}
+	if len(s.chain) == 0 {
+		return nil, fmt.Errorf("invalid provenance.certificate: no certificate found")
+	}
+	leaf, err := x509.ParseCertificate(s.chain[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid provenance.certificate: %w", err)
+	}
+	if public, ok := key.Public().(interface{ Equal(crypto.PublicKey) bool }); !ok || !public.Equal(leaf.PublicKey) {
+		return nil, fmt.Errorf("provenance.certificate does not belong to provenance.private_key")
+	}
+
+	for _, t := range tenants {
+		if t.Provenance {
+			s.tenants[t.ID] = true
+		}
+	}
+	return s, nil
+}
+
+// parseKey reads a PEM encoded PKCS #8, PKCS #1 or SEC 1 private key
+func parseKey(data string) (crypto.Signer, error) {
+	block, _ := pem.Decode([]byte(data))
+	if block == nil {
+		return nil, fmt.Errorf("no PEM block found")
+	}
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		if signer, ok := key.(crypto.Signer); ok {
+			return signer, nil
+		}
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+	return x509.ParseECPrivateKey(block.Bytes)
+}
+
+// Has reports whether the outputs of a tenant carry manifests
+func (s *Signer) Has(tenant string) bool {
+	return s.tenants[tenant]
+}
+
+// Manifest is the manifest of one output. Embedding takes two passes: the
+// placeholder is written into the document, then sealed with the hash of
+// the written document and replaced in place.
+type Manifest struct {
+	signer     *Signer
+	label      string
+	instanceID string
+	actions    []byte
+	size       int
+}
+
+// Manifest starts the manifest of an output produced by operation at when
+func (s *Signer) Manifest(operation string, when time.Time) *Manifest {
+	return &Manifest{
+		signer:     s,
+		label:      "urn:uuid:" + uuid.NewString(),
+		instanceID: "xmp:iid:" + uuid.NewString(),
+		actions: cborAssertion("c2pa.actions", cborMap{{"actions", []interface{}{cborMap{
+			{"action", "c2pa.edited"},
+			{"when", when.UTC().Format(time.RFC3339)},
+			{"softwareAgent", s.agent},
+			{"parameters", cborMap{{"operation", operation}}},
+		}}}}),
+	}
+}
+
+// Placeholder returns the manifest store to write into the document. It
+// is unique, so it can be found again in the written document.
+func (m *Manifest) Placeholder() ([]byte, error) {
+	store, err := m.store(unknownOffset, unknownOffset, make([]byte, sha256.Size), basePad)
+	if err != nil {
+		return nil, err
+	}
+	m.size = len(store)
+	return store, nil
+}
+
+// Seal returns the manifest store replacing the placeholder at offset in
+// file, binding the rest of the file by its hash
+func (m *Manifest) Seal(file []byte, offset int) ([]byte, error) {
+	if m.size == 0 || offset < 0 || offset+m.size > len(file) {
+		return nil, fmt.Errorf("manifest placeholder not found in the document")
+	}
+	h := sha256.New()
+	h.Write(file[:offset])
+	h.Write(file[offset+m.size:])
+	digest := h.Sum(nil)
+
+	store, err := m.store(uint64(offset), uint64(m.size), digest, basePad)
+	if err != nil {
+		return nil, err
+	}
+	if len(store) > m.size {
+		return nil, fmt.Errorf("sealed manifest outgrew its placeholder")
+	}
+	if len(store) < m.size {
+		if store, err = m.store(uint64(offset), uint64(m.size), digest, basePad+m.size-len(store)); err != nil {
+			return nil, err
+		}
+	}
+	if len(store) != m.size {
+		return nil, fmt.Errorf("sealed manifest does not fit its placeholder")
+	}
+	return store, nil
+}
+
+// store builds the signed manifest store, whose hash binding excludes
+// length bytes at start
+func (m *Manifest) store(start, length uint64, digest []byte, pad int) ([]byte, error) {
+	hashData := cborAssertion("c2pa.hash.data", cborMap{
+		{"exclusions", []interface{}{cborMap{{"start", start}, {"length", length}}}},
+		{"name", "jumbf manifest"},
+		{"alg", "sha256"},
+		{"hash", digest},
+		{"pad", make([]byte, pad)},
+	})
+	claim := encodeCBOR(cborMap{
+		{"claim_generator", m.signer.agent},
+		{"claim_generator_info", []interface{}{cborMap{{"name", "pdf-tool-go"}, {"version", m.signer.version}}}},
+		{"signature", "self#jumbf=c2pa.signature"},
+		{"assertions", []interface{}{hashedURI("c2pa.actions", m.actions), hashedURI("c2pa.hash.data", hashData)}},
+		{"dc:format", "application/pdf"},
+		{"instanceID", m.instanceID},
+		{"alg", "sha256"},
+	})
+	signature, err := m.signer.coseSign(claim)
+	if err != nil {
+		return nil, err
+	}
+
+	return superbox(typeStore, "c2pa",
+		superbox(typeManifest, m.label,
+			superbox(typeAssertions, "c2pa.assertions", m.actions, hashData),
+			superbox(typeClaim, "c2pa.claim", box("cbor", claim)),
+			superbox(typeSignature, "c2pa.signature", box("cbor", signature)),
+		),
+	), nil
+}
+
+// hashedURI references an assertion from the claim by its label and the
+// hash of its superbox contents
+func hashedURI(label string, assertion []byte) cborMap {
+	sum := sha256.Sum256(assertion[8:])
+	return cborMap{{"url", "self#jumbf=c2pa.assertions/" + label}, {"hash", sum[:]}}
+}
+
+// coseSign returns a COSE_Sign1 signature of the claim, which is detached
+// from it, carrying the certificate chain
+func (s *Signer) coseSign(claim []byte) ([]byte, error) {
+	var chain interface{} = s.chain[0]
+	if len(s.chain) > 1 {
+		certs := make([]interface{}, len(s.chain))
+		for i, cert := range s.chain {
+			certs[i] = cert
+		}
+		chain = certs
+	}
+	protected := encodeCBOR(cborMap{{headerAlg, s.alg}, {headerX5Chain, chain}})
+	signature, err := s.sign(encodeCBOR([]interface{}{"Signature1", protected, []byte{}, claim}))
+	if err != nil {
+		return nil, fmt.Errorf("failed to sign manifest: %w", err)
+	}
+	return encodeCBOR(cborTag{18, []interface{}{protected, cborMap{}, nil, signature}}), nil
+}
+
+// sign signs data with the key's algorithm. ECDSA signatures are encoded
+// as fixed-size r and s, as COSE requires.
+func (s *Signer) sign(data []byte) ([]byte, error) {
+	if key, ok := s.key.(ed25519.PrivateKey); ok {
+		return ed25519.Sign(key, data), nil
+	}
+	h := s.hash.New()
+	h.Write(data)
+	digest := h.Sum(nil)
+
+	switch key := s.key.(type) {
+	case *ecdsa.PrivateKey:
+		r, sv, err := ecdsa.Sign(rand.Reader, key, digest)
+		if err != nil {
+			return nil, err
+		}
+		size := (key.Curve.Params().BitSize + 7) / 8
+		signature := make([]byte, 2*size)
+		r.FillBytes(signature[:size])
+		sv.FillBytes(signature[size:])
+		return signature, nil
+	case *rsa.PrivateKey:
+		return rsa.SignPSS(rand.Reader, key, s.hash, digest, &rsa.PSSOptions{SaltLength: rsa.PSSSaltLengthEqualsHash})
+	}
+	return nil, fmt.Errorf("unsupported key type %T", s.key)
+}
@@ -0,0 +1,108 @@
+package provenance
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEncodeCBOR(t *testing.T) {
+	// Examples from RFC 8949 appendix A
+	for hexValue, v := range map[string]interface{}{
+		"00":           0,
+		"17":           23,
+		"1818":         24,
+		"1903e8":       1000,
+		"1a000f4240":   1000000,
+		"20":           -1,
+		"3903e7":       -1000,
+		"f4":           false,
+		"f6":           nil,
+		"6449455446":   "IETF",
+		"4401020304":   []byte{1, 2, 3, 4},
+		"8201820203":   []interface{}{1, []interface{}{2, 3}},
+		"a201020304":   cborMap{{1, 2}, {3, 4}},
+		"d74401020304": cborTag{23, []byte{1, 2, 3, 4}},
+	} {
+		assert.Equal(t, hexValue, hex.EncodeToString(encodeCBOR(v)))
+	}
+}
+
+func TestManifest(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	template := &x509.Certificate{SerialNumber: big.NewInt(1), Subject: pkix.Name{CommonName: "pdf-tool-go"},
+		NotBefore: time.Now(), NotAfter: time.Now().Add(time.Hour)}
+	cert, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	cfg := config.ProvenanceConfig{
+		Enabled:     true,
+		Certificate: string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert})),
+		PrivateKey:  string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})),
+	}
+
+	signer, err := New(cfg, []config.TenantConfig{{ID: "acme", Provenance: true}, {ID: "globex"}}, "1.2.3")
+	require.NoError(t, err)
+
+	t.Run("Tenants Opt In", func(t *testing.T) {
+		assert.True(t, signer.Has("acme"))
+		assert.False(t, signer.Has("globex"))
+	})
+
+	t.Run("Sealed Manifests Replace Placeholders", func(t *testing.T) {
+		m := signer.Manifest("compress", time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC))
+		placeholder, err := m.Placeholder()
+		require.NoError(t, err)
+		assert.Equal(t, "jumb", string(placeholder[4:8]))
+
+		file := append(append([]byte("%PDF-1.7 head "), placeholder...), " tail %%EOF"...)
+		offset := bytes.Index(file, placeholder)
+		store, err := m.Seal(file, offset)
+		require.NoError(t, err)
+		require.Len(t, store, len(placeholder))
+
+		digest := sha256.Sum256([]byte("%PDF-1.7 head  tail %%EOF"))
+		assert.True(t, bytes.Contains(store, digest[:]), "hash binding excludes the manifest")
+		assert.True(t, bytes.Contains(store, []byte("2026-03-01T12:00:00Z")))
+		assert.True(t, bytes.Contains(store, []byte("pdf-tool-go/1.2.3")))
+		assert.True(t, bytes.Contains(store, cert), "certificate chain is embedded")
+	})
+
+	t.Run("Claims Are Signed", func(t *testing.T) {
+		claim := []byte("claim")
+		signature, err := signer.coseSign(claim)
+		require.NoError(t, err)
+
+		protected := encodeCBOR(cborMap{{headerAlg, algES256}, {headerX5Chain, cert}})
+		digest := sha256.Sum256(encodeCBOR([]interface{}{"Signature1", protected, []byte{}, claim}))
+		raw := signature[len(signature)-64:]
+		r, s := new(big.Int).SetBytes(raw[:32]), new(big.Int).SetBytes(raw[32:])
+		assert.True(t, ecdsa.Verify(&key.PublicKey, digest[:], r, s))
+	})
+
+	t.Run("Certificates Must Match The Key", func(t *testing.T) {
+		other, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		otherDER, err := x509.MarshalPKCS8PrivateKey(other)
+		require.NoError(t, err)
+		mismatched := cfg
+		mismatched.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: otherDER}))
+		_, err = New(mismatched, nil, "1.2.3")
+		assert.Error(t, err)
+	})
+}
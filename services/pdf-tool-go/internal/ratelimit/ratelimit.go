@@ -0,0 +1,259 @@
+/**
+ * Rate Limiting
+ *
+ * Token buckets per client and endpoint. Requests take tokens by the cost
+ * of their endpoint, so expensive operations such as OCR and rendering use
+ * up a client's allowance faster than cheap ones such as metadata reads.
+ */
+
+package ratelimit
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// pruneInterval bounds how often full buckets are swept
+const pruneInterval = time.Minute
+
+// bucket is a token bucket
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	// quota is the client's quota when the bucket was last drawn from
+	quota Quota
+}
+
+// rule is the bucket a request draws from and the tokens it takes
+type rule struct {
+	// scope names the bucket: "" for the client's shared bucket, or the
+	// route of an endpoint limited on its own
+	scope    string
+	cost     float64
+	capacity float64
+	perSec   float64
+}
+
+// Quota replaces the shared rate limit of one client; zero fields keep the
+// configured limit. Endpoints limited on their own keep their limits.
+type Quota struct {
+	RequestsPerMin int
+	Burst          int
+}
+
+type quotaKey struct{}
+
+// WithQuota returns a context carrying the quota of the requesting client
+func WithQuota(ctx context.Context, quota Quota) context.Context {
+	return context.WithValue(ctx, quotaKey{}, quota)
+}
+
+// quotaFrom returns the quota carried by ctx, or none
+func quotaFrom(ctx context.Context) Quota {
+	quota, _ := ctx.Value(quotaKey{}).(Quota)
+	return quota
+}
+
+// within returns r with the shared limit replaced by quota
+func (r rule) within(quota Quota) rule {
+	if r.scope != "" {
+		return r
+	}
+	if quota.RequestsPerMin > 0 {
+		r.perSec = float64(quota.RequestsPerMin) / 60
+	}
+	if quota.Burst > 0 {
+		r.capacity = float64(quota.Burst)
+	}
+	return r
+}
+
+// Enforcer limits requests with limits that can be replaced at runtime
+type Enforcer interface {
+	// Allow returns 0 if a request to route by client is allowed, or how
+	// long the client must wait until it would be. A quota carried by ctx
+	// replaces the client's shared limit.
+	Allow(ctx context.Context, client, route string) time.Duration
+	// Configure replaces the limits
+	Configure(cfg config.RateLimitConfig)
+	// Limits returns the limits in effect
+	Limits() config.RateLimitConfig
+}
+
+// NewEnforcer returns the limiter cfg selects: limits shared through Redis
+// when an address is configured, else limits per instance. It returns nil
+// when rate limiting is disabled.
+func NewEnforcer(cfg config.RateLimitConfig, log logger.Logger) Enforcer {
+	switch {
+	case !cfg.Enabled:
+		return nil
+	case cfg.Redis.Address != "":
+		return NewDistributed(cfg, log)
+	}
+	return instance{New(cfg)}
+}
+
+// instance adapts a Limiter to Enforcer
+type instance struct {
+	limiter *Limiter
+}
+
+func (i instance) Allow(ctx context.Context, client, route string) time.Duration {
+	return i.limiter.AllowWithin(client, route, quotaFrom(ctx))
+}
+
+func (i instance) Configure(cfg config.RateLimitConfig) {
+	i.limiter.Configure(cfg)
+}
+
+func (i instance) Limits() config.RateLimitConfig {
+	return i.limiter.Limits()
+}
+
+// Limiter enforces a request rate per client. Each client has a shared
+// bucket holding Burst tokens, refilled at RequestsPerMin a minute;
+// endpoints configured with a rate of their own get a separate bucket.
+type Limiter struct {
+	mu        sync.Mutex
+	cfg       config.RateLimitConfig
+	shared    rule
+	endpoints map[string]rule
+	buckets   map[string]*bucket
+	lastPrune time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter
+func New(cfg config.RateLimitConfig) *Limiter {
+	l := &Limiter{
+		buckets: make(map[string]*bucket),
+		now:     time.Now,
+	}
+	l.Configure(cfg)
+	return l
+}
+
+// Configure replaces the limits, for configuration reloads. Buckets are
+// kept, so clients neither regain a spent allowance nor keep more tokens
+// than a lowered burst allows.
+func (l *Limiter) Configure(cfg config.RateLimitConfig) {
+	shared := rule{cost: 1, capacity: float64(cfg.Burst), perSec: float64(cfg.RequestsPerMin) / 60}
+	endpoints := make(map[string]rule, len(cfg.Endpoints))
+	for route, endpoint := range cfg.Endpoints {
+		// Config keys are lowercased when loaded, so routes are matched
+		// case-insensitively
+		route = strings.ToLower(route)
+		r := shared
+		if endpoint.RequestsPerMin > 0 {
+			burst := endpoint.Burst
+			if burst == 0 {
+				burst = cfg.Burst
+			}
+			r = rule{scope: route, capacity: float64(burst), perSec: float64(endpoint.RequestsPerMin) / 60}
+		}
+		r.cost = 1
+		if endpoint.Cost > 0 {
+			r.cost = float64(endpoint.Cost)
+		}
+		endpoints[route] = r
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.cfg = cfg
+	l.shared = shared
+	l.endpoints = endpoints
+}
+
+// Limits returns the limits in effect
+func (l *Limiter) Limits() config.RateLimitConfig {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.cfg
+}
+
+// Allow takes the tokens a request to route costs from the bucket of
+// client. It returns 0 if the request is allowed, or how long the client
+// must wait until it would be.
+func (l *Limiter) Allow(client, route string) time.Duration {
+	return l.AllowWithin(client, route, Quota{})
+}
+
+// AllowWithin is Allow for a client whose shared limit is replaced by
+// quota
+func (l *Limiter) AllowWithin(client, route string, quota Quota) time.Duration {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	r := l.lookup(route).within(quota)
+	// A request costing more than the bucket holds could never pass, so it
+	// empties a full bucket instead
+	cost := math.Min(r.cost, r.capacity)
+
+	now := l.now()
+	l.prune(now)
+
+	key := r.scope + "|" + client
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: r.capacity, updated: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(r.capacity, b.tokens+now.Sub(b.updated).Seconds()*r.perSec)
+	b.updated = now
+	b.quota = quota
+
+	if b.tokens >= cost {
+		b.tokens -= cost
+		return 0
+	}
+	if r.perSec <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration((cost - b.tokens) / r.perSec * float64(time.Second))
+}
+
+// rule returns the rule requests to route are limited by
+func (l *Limiter) rule(route string) rule {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.lookup(route)
+}
+
+// lookup is rule for callers holding l.mu
+func (l *Limiter) lookup(route string) rule {
+	if r, ok := l.endpoints[strings.ToLower(route)]; ok {
+		return r
+	}
+	return l.shared
+}
+
+// prune drops buckets that have refilled, which are no different from new
+// ones, so memory is bounded by recently active clients
+func (l *Limiter) prune(now time.Time) {
+	if now.Sub(l.lastPrune) < pruneInterval {
+		return
+	}
+	l.lastPrune = now
+	for key, b := range l.buckets {
+		scope, _, _ := strings.Cut(key, "|")
+		r := l.shared.within(b.quota)
+		if scope != "" {
+			// Endpoints dropped by a reload no longer have a rule
+			var ok bool
+			if r, ok = l.endpoints[scope]; !ok {
+				delete(l.buckets, key)
+				continue
+			}
+		}
+		if r.perSec > 0 && b.tokens+now.Sub(b.updated).Seconds()*r.perSec >= r.capacity {
+			delete(l.buckets, key)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter(t *testing.T) {
+	cfg := config.RateLimitConfig{
+		Enabled:        true,
+		RequestsPerMin: 60,
+		Burst:          10,
+		Endpoints: map[string]config.EndpointRateLimit{
+			"/api/v1/pdf/extract/text":    {Cost: 5},
+			"/api/v1/pdf/redact/pii":      {Cost: 50},
+			"/api/v1/pdf/:docId/pages/:n": {RequestsPerMin: 120, Burst: 2},
+		},
+	}
+	now := time.Unix(1700000000, 0)
+	newLimiter := func() *Limiter {
+		l := New(cfg)
+		l.now = func() time.Time { return now }
+		return l
+	}
+
+	t.Run("Expensive Endpoints Cost More", func(t *testing.T) {
+		l := newLimiter()
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/text"))
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/metadata"))
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/metadata"))
+		// 3 tokens left, 5 needed at 1 token a second
+		assert.Equal(t, 2*time.Second, l.Allow("a", "/api/v1/pdf/extract/text"))
+		assert.Zero(t, l.Allow("b", "/api/v1/pdf/extract/text"))
+	})
+
+	t.Run("Costs Above Burst Empty The Bucket", func(t *testing.T) {
+		l := newLimiter()
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/redact/pii"))
+		assert.Equal(t, time.Second, l.Allow("a", "/api/v1/pdf/extract/metadata"))
+	})
+
+	t.Run("Endpoints With Their Own Rate", func(t *testing.T) {
+		l := newLimiter()
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/:docId/pages/:n"))
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/:docId/pages/:n"))
+		assert.Equal(t, 500*time.Millisecond, l.Allow("a", "/api/v1/pdf/:docId/pages/:n"))
+		// The shared bucket is untouched
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/text"))
+	})
+
+	t.Run("Buckets Refill", func(t *testing.T) {
+		l := newLimiter()
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/redact/pii"))
+		now = now.Add(5 * time.Second)
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/text"))
+		assert.Equal(t, time.Second, l.Allow("a", "/api/v1/pdf/extract/metadata"))
+	})
+
+	t.Run("Reloaded Limits Keep Spent Tokens", func(t *testing.T) {
+		l := newLimiter()
+		assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/text"))
+
+		reloaded := cfg
+		reloaded.Burst = 4
+		reloaded.Endpoints = nil
+		l.Configure(reloaded)
+		// 5 tokens left, capped at the new burst of 4, and text extraction
+		// now costs 1
+		for i := 0; i < 4; i++ {
+			assert.Zero(t, l.Allow("a", "/api/v1/pdf/extract/text"))
+		}
+		assert.Equal(t, time.Second, l.Allow("a", "/api/v1/pdf/extract/text"))
+	})
+
+	t.Run("Quotas Replace The Shared Limit", func(t *testing.T) {
+		l := newLimiter()
+		quota := Quota{RequestsPerMin: 120, Burst: 2}
+		assert.Zero(t, l.AllowWithin("a", "/api/v1/pdf/extract/metadata", quota))
+		assert.Zero(t, l.AllowWithin("a", "/api/v1/pdf/extract/metadata", quota))
+		assert.Equal(t, 500*time.Millisecond, l.AllowWithin("a", "/api/v1/pdf/extract/metadata", quota))
+		// Costs still apply, capped at the quota's burst
+		now = now.Add(time.Second)
+		assert.Zero(t, l.AllowWithin("a", "/api/v1/pdf/extract/text", quota))
+	})
+}
@@ -0,0 +1,135 @@
+package ratelimit
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/redis/go-redis/v9"
+)
+
+// window is the span the sliding window counts requests over. Limits are
+// configured per minute.
+const window = time.Minute
+
+// slidingWindowScript records a request of cost ARGV[3] in the sorted set
+// KEYS[1] if the costs recorded over the last ARGV[1] ms leave room for it
+// under ARGV[2]. It returns 0 if the request was recorded, or the ms until
+// enough earlier requests leave the window. Members are "<id>:<cost>",
+// scored by time. Time comes from the Redis server so replicas with
+// skewed clocks share one window.
+var slidingWindowScript = redis.NewScript(`
+local key = KEYS[1]
+local window = tonumber(ARGV[1])
+local limit = tonumber(ARGV[2])
+local cost = tonumber(ARGV[3])
+local time = redis.call('TIME')
+local now = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+redis.call('ZREMRANGEBYSCORE', key, '-inf', now - window)
+local entries = redis.call('ZRANGE', key, 0, -1, 'WITHSCORES')
+local used = 0
+for i = 1, #entries, 2 do
+	used = used + tonumber(string.match(entries[i], ':(%d+)$'))
+end
+
+if used + cost <= limit then
+	redis.call('ZADD', key, now, ARGV[4] .. ':' .. cost)
+	redis.call('PEXPIRE', key, window)
+	return 0
+end
+
+local excess = used + cost - limit
+for i = 1, #entries, 2 do
+	excess = excess - tonumber(string.match(entries[i], ':(%d+)$'))
+	if excess <= 0 then
+		return math.max(1, tonumber(entries[i + 1]) + window - now)
+	end
+end
+return window
+`)
+
+// Distributed enforces limits shared by every replica with a sliding
+// window per client in Redis. Each client may spend RequestsPerMin tokens
+// in any rolling minute; Burst only applies to the local fallback. While
+// Redis is unreachable requests are limited per instance instead.
+type Distributed struct {
+	client   *redis.Client
+	local    *Limiter
+	prefix   string
+	timeout  time.Duration
+	log      logger.Logger
+	degraded atomic.Bool
+}
+
+// NewDistributed creates a Redis-backed limiter
+func NewDistributed(cfg config.RateLimitConfig, log logger.Logger) *Distributed {
+	timeout := time.Duration(cfg.Redis.Timeout) * time.Millisecond
+	return &Distributed{
+		client: redis.NewClient(&redis.Options{
+			Addr:         cfg.Redis.Address,
+			Password:     cfg.Redis.Password,
+			DB:           cfg.Redis.DB,
+			DialTimeout:  timeout,
+			ReadTimeout:  timeout,
+			WriteTimeout: timeout,
+			PoolTimeout:  timeout,
+		}),
+		local:   New(cfg),
+		prefix:  cfg.Redis.KeyPrefix,
+		timeout: timeout,
+		log:     log,
+	}
+}
+
+// Configure replaces the limits, for configuration reloads. The Redis
+// connection is kept.
+func (d *Distributed) Configure(cfg config.RateLimitConfig) {
+	d.local.Configure(cfg)
+}
+
+// Limits returns the limits in effect
+func (d *Distributed) Limits() config.RateLimitConfig {
+	return d.local.Limits()
+}
+
+// Ping verifies that Redis is reachable
+func (d *Distributed) Ping(ctx context.Context) error {
+	return d.client.Ping(ctx).Err()
+}
+
+// Allow records a request to route by client against the shared window.
+// It returns 0 if the request is allowed, or how long the client must wait
+// until it would be.
+func (d *Distributed) Allow(ctx context.Context, client, route string) time.Duration {
+	quota := quotaFrom(ctx)
+	r := d.local.rule(route).within(quota)
+	limit := r.perSec * window.Seconds()
+	cost := math.Min(r.cost, limit)
+
+	ctx, cancel := context.WithTimeout(ctx, d.timeout)
+	defer cancel()
+
+	// Clients are identified by API keys, which are not stored in Redis
+	// as is
+	sum := sha256.Sum256([]byte(client))
+	key := d.prefix + r.scope + "|" + hex.EncodeToString(sum[:])
+	wait, err := slidingWindowScript.Run(ctx, d.client, []string{key},
+		window.Milliseconds(), int64(limit), int64(cost), uuid.NewString()).Int64()
+	if err != nil {
+		if !d.degraded.Swap(true) {
+			d.log.Warn("Rate limiting locally, Redis is unavailable", "error", err)
+		}
+		return d.local.AllowWithin(client, route, quota)
+	}
+	if d.degraded.Swap(false) {
+		d.log.Info("Rate limiting through Redis again")
+	}
+	return time.Duration(wait) * time.Millisecond
+}
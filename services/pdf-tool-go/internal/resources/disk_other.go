@@ -0,0 +1,10 @@
+//go:build !unix
+
+package resources
+
+import "errors"
+
+// FreeDiskBytes is not supported on this platform
+func FreeDiskBytes(dir string) (uint64, error) {
+	return 0, errors.New("free disk space not supported on this platform")
+}
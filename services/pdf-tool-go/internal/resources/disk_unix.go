@@ -0,0 +1,22 @@
+//go:build unix
+
+package resources
+
+import (
+	"os"
+	"syscall"
+)
+
+// FreeDiskBytes returns the bytes available to unprivileged users on the
+// filesystem holding dir
+func FreeDiskBytes(dir string) (uint64, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return 0, err
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dir, &stat); err != nil {
+		return 0, err
+	}
+	return stat.Bavail * uint64(stat.Bsize), nil
+}
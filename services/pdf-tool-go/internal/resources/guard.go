@@ -0,0 +1,103 @@
+/**
+ * Resource Guardrails
+ *
+ * Checks free disk space in the temp directory and process memory
+ * against configured thresholds before work is accepted.
+ */
+
+package resources
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+var (
+	// ErrLowDisk is returned when the temp directory lacks free space
+	ErrLowDisk = errors.New("insufficient free disk space")
+	// ErrHighMemory is returned when process memory is above the limit
+	ErrHighMemory = errors.New("process memory above limit")
+)
+
+// pollInterval is how often Wait re-checks resources
+const pollInterval = 500 * time.Millisecond
+
+// Guard checks resource thresholds
+type Guard struct {
+	dir string
+	cfg config.GuardrailConfig
+	// freeDisk and processRSS read current resources
+	freeDisk   func(dir string) (uint64, error)
+	processRSS func() (uint64, error)
+}
+
+// NewGuard creates a guard for the given temp directory
+func NewGuard(dir string, cfg config.GuardrailConfig) *Guard {
+	return &Guard{
+		dir:        dir,
+		cfg:        cfg,
+		freeDisk:   FreeDiskBytes,
+		processRSS: ProcessRSS,
+	}
+}
+
+// Check verifies that there is room for a job needing the given number of
+// temp bytes. It returns ErrLowDisk or ErrHighMemory (wrapped) when not.
+func (g *Guard) Check(needBytes int64) error {
+	if !g.cfg.Enabled {
+		return nil
+	}
+
+	if g.cfg.MinFreeDisk > 0 {
+		free, err := g.freeDisk(g.dir)
+		if err != nil {
+			return fmt.Errorf("failed to read free disk space: %w", err)
+		}
+		if int64(free)-needBytes < g.cfg.MinFreeDisk {
+			return fmt.Errorf("%w: %d bytes free, %d needed (min %d)", ErrLowDisk, free, needBytes, g.cfg.MinFreeDisk)
+		}
+	}
+
+	if g.cfg.MaxMemory > 0 {
+		rss, err := g.processRSS()
+		if err != nil {
+			return fmt.Errorf("failed to read process memory: %w", err)
+		}
+		if int64(rss) > g.cfg.MaxMemory {
+			return fmt.Errorf("%w: %d bytes in use (max %d)", ErrHighMemory, rss, g.cfg.MaxMemory)
+		}
+	}
+
+	return nil
+}
+
+// Wait is Check that keeps re-checking for up to the configured wait timeout
+// before giving up, so short resource spikes queue work instead of failing it
+func (g *Guard) Wait(ctx context.Context, needBytes int64) error {
+	err := g.Check(needBytes)
+	if err == nil || g.cfg.WaitTimeout <= 0 {
+		return err
+	}
+
+	deadline := time.NewTimer(time.Duration(g.cfg.WaitTimeout) * time.Second)
+	defer deadline.Stop()
+	ticker := time.NewTicker(pollInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-deadline.C:
+			return err
+		case <-ticker.C:
+			if err = g.Check(needBytes); err == nil {
+				return nil
+			}
+		}
+	}
+}
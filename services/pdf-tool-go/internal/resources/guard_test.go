@@ -0,0 +1,97 @@
+package resources
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestGuard(t *testing.T) {
+	cfg := config.GuardrailConfig{Enabled: true, MinFreeDisk: 1000, MaxMemory: 1000}
+	newGuard := func(cfg config.GuardrailConfig, free, rss *atomic.Uint64) *Guard {
+		g := NewGuard(t.TempDir(), cfg)
+		g.freeDisk = func(string) (uint64, error) { return free.Load(), nil }
+		g.processRSS = func() (uint64, error) { return rss.Load(), nil }
+		return g
+	}
+	resources := func(free, rss uint64) (*atomic.Uint64, *atomic.Uint64) {
+		var f, r atomic.Uint64
+		f.Store(free)
+		r.Store(rss)
+		return &f, &r
+	}
+
+	t.Run("Admits Within Limits", func(t *testing.T) {
+		free, rss := resources(5000, 500)
+		g := newGuard(cfg, free, rss)
+		assert.NoError(t, g.Check(4000))
+	})
+
+	t.Run("Rejects Low Disk", func(t *testing.T) {
+		free, rss := resources(5000, 500)
+		g := newGuard(cfg, free, rss)
+		assert.ErrorIs(t, g.Check(4500), ErrLowDisk)
+	})
+
+	t.Run("Rejects High Memory", func(t *testing.T) {
+		free, rss := resources(5000, 1500)
+		g := newGuard(cfg, free, rss)
+		assert.ErrorIs(t, g.Check(0), ErrHighMemory)
+	})
+
+	t.Run("Disabled", func(t *testing.T) {
+		free, rss := resources(0, 1<<40)
+		g := newGuard(config.GuardrailConfig{MinFreeDisk: 1000, MaxMemory: 1000}, free, rss)
+		assert.NoError(t, g.Check(1<<30))
+	})
+
+	t.Run("Read Failure", func(t *testing.T) {
+		free, rss := resources(5000, 500)
+		g := newGuard(cfg, free, rss)
+		g.processRSS = func() (uint64, error) { return 0, errors.New("no statm") }
+		err := g.Check(0)
+		assert.Error(t, err)
+		assert.False(t, errors.Is(err, ErrHighMemory))
+	})
+
+	t.Run("Wait Admits Once Resources Are Released", func(t *testing.T) {
+		free, rss := resources(5000, 1500)
+		waitCfg := cfg
+		waitCfg.WaitTimeout = 5
+		g := newGuard(waitCfg, free, rss)
+		time.AfterFunc(100*time.Millisecond, func() { rss.Store(500) })
+
+		start := time.Now()
+		assert.NoError(t, g.Wait(context.Background(), 0))
+		assert.Less(t, time.Since(start), 3*time.Second)
+	})
+
+	t.Run("Wait Rejects After Timeout", func(t *testing.T) {
+		free, rss := resources(5000, 1500)
+		waitCfg := cfg
+		waitCfg.WaitTimeout = 1
+		g := newGuard(waitCfg, free, rss)
+		assert.ErrorIs(t, g.Wait(context.Background(), 0), ErrHighMemory)
+	})
+
+	t.Run("Wait Without Timeout Rejects At Once", func(t *testing.T) {
+		free, rss := resources(100, 500)
+		g := newGuard(cfg, free, rss)
+		assert.ErrorIs(t, g.Wait(context.Background(), 0), ErrLowDisk)
+	})
+
+	t.Run("Wait Stops On Cancel", func(t *testing.T) {
+		free, rss := resources(100, 500)
+		waitCfg := cfg
+		waitCfg.WaitTimeout = 60
+		g := newGuard(waitCfg, free, rss)
+		ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+		defer cancel()
+		assert.ErrorIs(t, g.Wait(ctx, 0), context.DeadlineExceeded)
+	})
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package resources
+
+import "runtime"
+
+// ProcessRSS approximates resident memory with the Go runtime's view of
+// memory obtained from the OS
+func ProcessRSS() (uint64, error) {
+	var m runtime.MemStats
+	runtime.ReadMemStats(&m)
+	return m.Sys, nil
+}
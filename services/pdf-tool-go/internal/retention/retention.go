@@ -0,0 +1,141 @@
+/**
+ * Retention
+ *
+ * Per-tenant retention policies for stored documents and results, and the
+ * background purger enforcing their TTLs.
+ */
+
+package retention
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var purgedTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_retention_purged_total",
+	Help: "Stored objects removed after their retention TTL expired",
+}, []string{"kind"})
+
+// Overrides looks up retention policies of tenants set at runtime;
+// *tenant.Resolver implements it
+type Overrides interface {
+	Retention(tenant string) (config.RetentionPolicy, bool)
+}
+
+// Policies resolves the retention policy of a tenant
+type Policies struct {
+	defaults  config.RetentionPolicy
+	tenants   map[string]config.RetentionPolicy
+	overrides Overrides
+}
+
+// NewPolicies creates policies from the default policy and tenant overrides
+func NewPolicies(cfg config.RetentionConfig, tenants []config.TenantConfig) *Policies {
+	p := &Policies{
+		defaults: cfg.RetentionPolicy,
+		tenants:  make(map[string]config.RetentionPolicy),
+	}
+	for _, tenant := range tenants {
+		if tenant.Retention != nil {
+			p.tenants[tenant.ID] = *tenant.Retention
+		}
+	}
+	return p
+}
+
+// SetOverrides consults overrides for tenants without a configured policy.
+// It must be called before policies are looked up.
+func (p *Policies) SetOverrides(overrides Overrides) {
+	p.overrides = overrides
+}
+
+// For returns the retention policy of tenant. A nil Policies keeps
+// everything.
+func (p *Policies) For(tenant string) config.RetentionPolicy {
+	if p == nil {
+		return config.RetentionPolicy{}
+	}
+	if policy, ok := p.tenants[tenant]; ok {
+		return policy
+	}
+	if p.overrides != nil {
+		if policy, ok := p.overrides.Retention(tenant); ok {
+			return policy
+		}
+	}
+	return p.defaults
+}
+
+// Expired reports whether an object last written at modTime has outlived
+// policy at now
+func Expired(policy config.RetentionPolicy, modTime, now time.Time) bool {
+	return policy.TTL > 0 && now.Sub(modTime) > time.Duration(policy.TTL)*time.Second
+}
+
+// Target is a collection of stored objects subject to retention
+type Target interface {
+	// Purge removes objects expired at now and returns how many were removed
+	Purge(ctx context.Context, now time.Time) (int, error)
+}
+
+// Purger periodically purges expired objects from its targets
+type Purger struct {
+	cfg     config.RetentionConfig
+	log     logger.Logger
+	kinds   []string
+	targets []Target
+}
+
+// NewPurger creates a purger
+func NewPurger(cfg config.RetentionConfig, log logger.Logger) *Purger {
+	return &Purger{
+		cfg: cfg,
+		log: log,
+	}
+}
+
+// Register adds a target; kind labels it in logs and metrics
+func (p *Purger) Register(kind string, target Target) {
+	p.kinds = append(p.kinds, kind)
+	p.targets = append(p.targets, target)
+}
+
+// Start runs periodic purges until ctx is done
+func (p *Purger) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(time.Duration(p.cfg.Interval) * time.Second)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				p.Purge(ctx)
+			}
+		}
+	}()
+}
+
+// Purge purges every target once. Failures are logged so one broken
+// target does not stop the others.
+func (p *Purger) Purge(ctx context.Context) {
+	now := time.Now()
+	for i, target := range p.targets {
+		removed, err := target.Purge(ctx, now)
+		purgedTotal.WithLabelValues(p.kinds[i]).Add(float64(removed))
+		if err != nil {
+			p.log.Error("Retention purge failed", "kind", p.kinds[i], "removed", removed, "error", err)
+			continue
+		}
+		if removed > 0 {
+			p.log.Info("Purged expired objects", "kind", p.kinds[i], "removed", removed)
+		}
+	}
+}
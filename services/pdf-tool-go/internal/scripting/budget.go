@@ -0,0 +1,136 @@
+package scripting
+
+import (
+	"strings"
+
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Approximate sizes charged for values whose length is not their size
+const (
+	// tableSize is charged for each table constructed
+	tableSize = 64
+	// slotSize is charged for each table field added
+	slotSize = 48
+	// numberSize bounds the length of a number converted to a string
+	numberSize = 24
+)
+
+// rawConcat concatenates its two arguments with Lua's own operator, for
+// operands the concat guard leaves to Lua. It is compiled uninstrumented.
+var rawConcat = func() *lua.FunctionProto {
+	chunk, err := parse.Parse(strings.NewReader("local lhs, rhs = ... return lhs .. rhs"), "concat")
+	if err != nil {
+		panic(err)
+	}
+	proto, err := lua.Compile(chunk, "concat")
+	if err != nil {
+		panic(err)
+	}
+	return proto
+}()
+
+// budget counts the bytes a script run allocates. Allocations are charged
+// as they happen and never refunded, so the budget bounds what a run may
+// allocate in total rather than what it holds at once.
+type budget struct {
+	limit    int
+	used     int
+	exceeded bool
+}
+
+// check raises an error if allocating n more bytes would exceed the budget
+func (b *budget) check(L *lua.LState, n int) {
+	if n > b.limit-b.used {
+		b.exceeded = true
+		L.RaiseError("memory budget of %d bytes exceeded", b.limit)
+	}
+}
+
+// charge accounts n allocated bytes to the run
+func (b *budget) charge(L *lua.LState, n int) {
+	b.check(L, n)
+	b.used += n
+}
+
+// results charges the strings among the n values on top of the stack and
+// returns n, so that it can wrap the return of a library function
+func (b *budget) results(L *lua.LState, n int) int {
+	b.charge(L, b.sizes(L, L.GetTop()-n+1, L.GetTop()))
+	return n
+}
+
+// sizes returns the length the stack values from first to last take as
+// strings
+func (b *budget) sizes(L *lua.LState, first, last int) int {
+	size := 0
+	for i := first; i <= last; i++ {
+		size += valueSize(L.Get(i))
+	}
+	return size
+}
+
+// valueSize returns the length of v as a string
+func valueSize(v lua.LValue) int {
+	switch v := v.(type) {
+	case lua.LString:
+		return len(v)
+	case lua.LNumber:
+		return numberSize
+	}
+	return 0
+}
+
+// grow charges a field added to table by assigning value to key
+func (b *budget) grow(L *lua.LState, table *lua.LTable, key, value lua.LValue) {
+	if value != lua.LNil && key != lua.LNil && table.RawGet(key) == lua.LNil {
+		b.charge(L, slotSize)
+	}
+}
+
+// elements returns the length of the string table.concat would build from
+// the arguments on the stack
+func (b *budget) elements(L *lua.LState) int {
+	table := L.CheckTable(1)
+	sep := len(L.OptString(2, ""))
+	size := 0
+	for i := L.OptInt(3, 1); i <= L.OptInt(4, table.Len()); i++ {
+		v := table.RawGetInt(i)
+		if v == lua.LNil {
+			// table.concat itself reports the missing element
+			break
+		}
+		size += valueSize(v) + sep
+	}
+	return size
+}
+
+// gsub checks string.gsub against the budget before it runs. Replacement
+// strings are bounded by their length and the captures they expand, which
+// lie within the subject; replacement functions and tables are wrapped to
+// charge each value they produce.
+func (b *budget) gsub(L *lua.LState) int {
+	subject := L.CheckString(1)
+	switch repl := L.Get(3).(type) {
+	case lua.LString:
+		matches := len(subject) + 1
+		if limit := L.OptInt(4, -1); limit >= 0 && limit < matches {
+			matches = limit
+		}
+		captures := strings.Count(string(repl), "%")
+		b.check(L, len(subject)+matches*(len(repl)+captures*numberSize)+captures*len(subject))
+	case *lua.LTable:
+		L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+			L.Push(L.GetTable(repl, L.Get(1)))
+			return b.results(L, 1)
+		}))
+	case *lua.LFunction:
+		L.Replace(3, L.NewFunction(func(L *lua.LState) int {
+			L.Insert(repl, 1)
+			L.Call(L.GetTop()-1, 1)
+			return b.results(L, 1)
+		}))
+	}
+	return 0
+}
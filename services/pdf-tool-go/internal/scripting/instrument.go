@@ -0,0 +1,191 @@
+package scripting
+
+import (
+	"strconv"
+
+	"github.com/yuin/gopher-lua/ast"
+)
+
+// Guards are locals the instrumented chunk receives as its arguments.
+// Their names are not valid Lua identifiers, so scripts can neither read
+// nor reassign them.
+const (
+	// guardConcat(lhs, rhs) concatenates two values
+	guardConcat = "#concat"
+	// guardTable(table) returns a table built by a constructor
+	guardTable = "#table"
+	// guardSet(table, key, value) assigns to a table field
+	guardSet = "#set"
+)
+
+// instrument rewrites a parsed chunk so that everything allocating memory
+// in proportion to how long it runs goes through a guard charging the run's
+// budget: concatenations, table constructors and table field assignments.
+// Library functions are guarded by the sandbox itself.
+func instrument(chunk []ast.Stmt) []ast.Stmt {
+	guards := &ast.LocalAssignStmt{
+		Names: []string{guardConcat, guardTable, guardSet},
+		Exprs: []ast.Expr{&ast.Comma3Expr{}},
+	}
+	return append([]ast.Stmt{guards}, block(chunk)...)
+}
+
+// block instruments stmts in place
+func block(stmts []ast.Stmt) []ast.Stmt {
+	for i, s := range stmts {
+		stmts[i] = stmt(s)
+	}
+	return stmts
+}
+
+// stmt instruments s, returning its replacement
+func stmt(s ast.Stmt) ast.Stmt {
+	switch s := s.(type) {
+	case *ast.AssignStmt:
+		return assign(s)
+	case *ast.LocalAssignStmt:
+		exprs(s.Exprs)
+	case *ast.FuncCallStmt:
+		s.Expr = expr(s.Expr)
+	case *ast.DoBlockStmt:
+		block(s.Stmts)
+	case *ast.WhileStmt:
+		s.Condition = expr(s.Condition)
+		block(s.Stmts)
+	case *ast.RepeatStmt:
+		s.Condition = expr(s.Condition)
+		block(s.Stmts)
+	case *ast.IfStmt:
+		s.Condition = expr(s.Condition)
+		block(s.Then)
+		block(s.Else)
+	case *ast.NumberForStmt:
+		s.Init = expr(s.Init)
+		s.Limit = expr(s.Limit)
+		if s.Step != nil {
+			s.Step = expr(s.Step)
+		}
+		block(s.Stmts)
+	case *ast.GenericForStmt:
+		exprs(s.Exprs)
+		block(s.Stmts)
+	case *ast.FuncDefStmt:
+		s.Name.Func = expr(s.Name.Func)
+		if s.Name.Receiver != nil {
+			s.Name.Receiver = expr(s.Name.Receiver)
+		}
+		block(s.Func.Stmts)
+	case *ast.ReturnStmt:
+		exprs(s.Exprs)
+	}
+	return s
+}
+
+// assign turns an assignment to table fields into a block evaluating the
+// values into locals first, as Lua does, then storing them through the
+// set guard. Fields are stored before variables are, so that keys such as
+// t[i] in "i, t[i] = ..." use the value i had before the assignment.
+func assign(s *ast.AssignStmt) ast.Stmt {
+	exprs(s.Rhs)
+	fields := false
+	for _, lhs := range s.Lhs {
+		if attr, ok := lhs.(*ast.AttrGetExpr); ok {
+			attr.Object = expr(attr.Object)
+			attr.Key = expr(attr.Key)
+			fields = true
+		}
+	}
+	if !fields {
+		return s
+	}
+
+	names := make([]string, len(s.Lhs))
+	for i := range names {
+		names[i] = "#" + strconv.Itoa(i)
+	}
+	values := &ast.LocalAssignStmt{Names: names, Exprs: s.Rhs}
+	values.SetLine(s.Line())
+	stores := []ast.Stmt{values}
+	var variables []ast.Stmt
+	for i, lhs := range s.Lhs {
+		value := &ast.IdentExpr{Value: names[i]}
+		if attr, ok := lhs.(*ast.AttrGetExpr); ok {
+			set := &ast.FuncCallStmt{Expr: call(guardSet, attr, attr.Object, attr.Key, value)}
+			set.SetLine(attr.Line())
+			stores = append(stores, set)
+			continue
+		}
+		variable := &ast.AssignStmt{Lhs: []ast.Expr{lhs}, Rhs: []ast.Expr{value}}
+		variable.SetLine(s.Line())
+		variables = append(variables, variable)
+	}
+
+	do := &ast.DoBlockStmt{Stmts: append(stores, variables...)}
+	do.SetLine(s.Line())
+	do.SetLastLine(s.LastLine())
+	return do
+}
+
+// exprs instruments exprs in place
+func exprs(exprs []ast.Expr) {
+	for i, e := range exprs {
+		exprs[i] = expr(e)
+	}
+}
+
+// expr instruments e, returning its replacement
+func expr(e ast.Expr) ast.Expr {
+	switch e := e.(type) {
+	case *ast.StringConcatOpExpr:
+		return call(guardConcat, e, expr(e.Lhs), expr(e.Rhs))
+	case *ast.TableExpr:
+		for _, field := range e.Fields {
+			if field.Key != nil {
+				field.Key = expr(field.Key)
+			}
+			field.Value = expr(field.Value)
+		}
+		return call(guardTable, e, e)
+	case *ast.AttrGetExpr:
+		e.Object = expr(e.Object)
+		e.Key = expr(e.Key)
+	case *ast.FuncCallExpr:
+		if e.Func != nil {
+			e.Func = expr(e.Func)
+		}
+		if e.Receiver != nil {
+			e.Receiver = expr(e.Receiver)
+		}
+		exprs(e.Args)
+	case *ast.LogicalOpExpr:
+		e.Lhs = expr(e.Lhs)
+		e.Rhs = expr(e.Rhs)
+	case *ast.RelationalOpExpr:
+		e.Lhs = expr(e.Lhs)
+		e.Rhs = expr(e.Rhs)
+	case *ast.ArithmeticOpExpr:
+		e.Lhs = expr(e.Lhs)
+		e.Rhs = expr(e.Rhs)
+	case *ast.UnaryMinusOpExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.UnaryNotOpExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.UnaryLenOpExpr:
+		e.Expr = expr(e.Expr)
+	case *ast.FunctionExpr:
+		block(e.Stmts)
+	}
+	return e
+}
+
+// call builds a call of guard with args, positioned at the expression it
+// replaces so that errors report the script's line
+func call(guard string, at ast.Expr, args ...ast.Expr) *ast.FuncCallExpr {
+	fn := &ast.IdentExpr{Value: guard}
+	fn.SetLine(at.Line())
+	fn.SetLastLine(at.LastLine())
+	c := &ast.FuncCallExpr{Func: fn, Args: args}
+	c.SetLine(at.Line())
+	c.SetLastLine(at.LastLine())
+	return c
+}
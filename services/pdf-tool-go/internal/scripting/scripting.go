@@ -0,0 +1,360 @@
+/**
+ * Tenant Scripting
+ *
+ * Small Lua scripts tenants register to customise operations at pipeline
+ * hooks, such as renaming outputs or watermarking documents depending on
+ * their metadata. Scripts run sandboxed: only the base, string, table and
+ * math libraries are available, without file or code loading, each run is
+ * bounded by a timeout and a memory budget, and the Lua call and value
+ * stacks are capped.
+ */
+
+package scripting
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	lua "github.com/yuin/gopher-lua"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Hooks are global functions a script may define. Each is called with a
+// document table of operation, tenant, pages, size and metadata (title,
+// author, subject, keywords, creator, producer).
+const (
+	// HookTransform runs after an operation and returns a table of actions:
+	// watermark = "text" stamps the output
+	HookTransform = "transform"
+	// HookOutputName runs after an operation and returns the base name of
+	// the output file, without extension
+	HookOutputName = "output_name"
+)
+
+// ErrTimeout is returned for scripts that ran out of time
+var ErrTimeout = errors.New("script timed out")
+
+// ErrMemory is returned for scripts that allocated more than their budget
+var ErrMemory = errors.New("script exceeded its memory budget")
+
+// maxStringSize caps strings built by string.rep, so a script cannot
+// allocate arbitrary memory in a single call
+const maxStringSize = 1 << 20
+
+// wideFormat matches string.format directives with widths or precisions
+// of three or more digits
+var wideFormat = regexp.MustCompile(`%[-+ #0]*(\d{3,}|\d*\.\d{3,})`)
+
+// unsafeGlobals are base library functions removed from the sandbox
+var unsafeGlobals = []string{"dofile", "loadfile", "load", "loadstring", "collectgarbage", "print", "_printregs"}
+
+// Document is what scripts see of the document an operation processed
+type Document struct {
+	Operation string
+	Tenant    string
+	Pages     int
+	Size      int
+	Metadata  map[string]string
+}
+
+// Actions are what a script asked for
+type Actions struct {
+	// Watermark is text to stamp the output with
+	Watermark string
+	// Name is the base name of the output file
+	Name string
+}
+
+// Engine runs tenant scripts
+type Engine struct {
+	cfg     config.ScriptingConfig
+	log     logger.Logger
+	scripts map[string]*lua.FunctionProto
+}
+
+// New compiles the scripts of tenants, failing on the first that cannot be
+// read or parsed. It returns nil when scripting is disabled.
+func New(cfg config.ScriptingConfig, tenants []config.TenantConfig, log logger.Logger) (*Engine, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+
+	e := &Engine{cfg: cfg, log: log, scripts: make(map[string]*lua.FunctionProto)}
+	for _, tenant := range tenants {
+		if tenant.Script == "" {
+			continue
+		}
+		proto, err := compile(tenant.Script)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %s: %w", tenant.ID, err)
+		}
+		e.scripts[tenant.ID] = proto
+	}
+	return e, nil
+}
+
+// compile parses the script at path
+func compile(path string) (*lua.FunctionProto, error) {
+	source, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read script: %w", err)
+	}
+	chunk, err := parse.Parse(bytes.NewReader(source), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse script: %w", err)
+	}
+	return lua.Compile(instrument(chunk), path)
+}
+
+// Has reports whether tenant has a script
+func (e *Engine) Has(tenant string) bool {
+	if e == nil {
+		return false
+	}
+	_, ok := e.scripts[tenant]
+	return ok
+}
+
+// Run runs the hooks the tenant's script defines over doc. It returns no
+// actions when the tenant has no script.
+func (e *Engine) Run(ctx context.Context, doc Document) (_ *Actions, err error) {
+	actions := &Actions{}
+	if !e.Has(doc.Tenant) {
+		return actions, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(e.cfg.Timeout)*time.Millisecond)
+	defer cancel()
+	defer func() {
+		switch {
+		case err == nil:
+			metrics.ScriptRun("ok")
+		case errors.Is(err, ErrTimeout):
+			metrics.ScriptRun("timeout")
+		case errors.Is(err, ErrMemory):
+			metrics.ScriptRun("memory")
+		default:
+			metrics.ScriptRun("error")
+		}
+	}()
+
+	budget := &budget{limit: e.cfg.MemoryBytes}
+	L, guards := e.sandbox(budget)
+	defer L.Close()
+	L.SetContext(ctx)
+
+	// Scripts catching the budget error with pcall still fail
+	call := func(fn lua.LValue, args ...lua.LValue) (lua.LValue, error) {
+		err := L.CallByParam(lua.P{Fn: fn, NRet: 1, Protect: true}, args...)
+		switch {
+		case budget.exceeded:
+			return nil, fmt.Errorf("%w of %d bytes", ErrMemory, e.cfg.MemoryBytes)
+		case err == nil:
+		case ctx.Err() != nil:
+			return nil, fmt.Errorf("%w after %dms", ErrTimeout, e.cfg.Timeout)
+		default:
+			return nil, fmt.Errorf("script failed: %w", err)
+		}
+		ret := L.Get(-1)
+		L.Pop(1)
+		return ret, nil
+	}
+
+	if _, err := call(L.NewFunctionFromProto(e.scripts[doc.Tenant]), guards...); err != nil {
+		return nil, err
+	}
+	table := e.document(L, doc)
+
+	if fn, ok := L.GetGlobal(HookTransform).(*lua.LFunction); ok {
+		ret, err := call(fn, table)
+		if err != nil {
+			return nil, err
+		}
+		switch ret := ret.(type) {
+		case *lua.LTable:
+			if watermark, ok := ret.RawGetString("watermark").(lua.LString); ok {
+				actions.Watermark = string(watermark)
+			}
+		case *lua.LNilType:
+		default:
+			return nil, fmt.Errorf("script failed: %s must return a table or nil, got %s", HookTransform, ret.Type())
+		}
+	}
+
+	if fn, ok := L.GetGlobal(HookOutputName).(*lua.LFunction); ok {
+		ret, err := call(fn, table)
+		if err != nil {
+			return nil, err
+		}
+		switch ret := ret.(type) {
+		case lua.LString:
+			actions.Name = string(ret)
+		case *lua.LNilType:
+		default:
+			return nil, fmt.Errorf("script failed: %s must return a string or nil, got %s", HookOutputName, ret.Type())
+		}
+	}
+
+	e.log.Debug("Script ran", "tenant", doc.Tenant, "operation", doc.Operation, "watermark", actions.Watermark != "", "name", actions.Name)
+	return actions, nil
+}
+
+// sandbox creates a Lua state with only the safe libraries, charging what
+// they allocate to b. It returns the guards instrumented chunks expect.
+func (e *Engine) sandbox(b *budget) (*lua.LState, []lua.LValue) {
+	L := lua.NewState(lua.Options{
+		SkipOpenLibs:  true,
+		CallStackSize: e.cfg.CallStackSize,
+		RegistrySize:  e.cfg.RegistrySize,
+	})
+	for _, lib := range []struct {
+		name string
+		open lua.LGFunction
+	}{
+		{lua.BaseLibName, lua.OpenBase},
+		{lua.TabLibName, lua.OpenTable},
+		{lua.StringLibName, lua.OpenString},
+		{lua.MathLibName, lua.OpenMath},
+	} {
+		L.Push(L.NewFunction(lib.open))
+		L.Push(lua.LString(lib.name))
+		L.Call(1, 0)
+	}
+	for _, name := range unsafeGlobals {
+		L.SetGlobal(name, lua.LNil)
+	}
+
+	// The string table is also the metatable index of strings, so the
+	// guards apply to method calls too. Every string function is charged
+	// for the strings it returns; those that can build strings much larger
+	// than their arguments are checked against the budget beforehand.
+	strlib := L.GetGlobal(lua.StringLibName).(*lua.LTable)
+	guard := func(name string, check lua.LGFunction) {
+		fn := strlib.RawGetString(name).(*lua.LFunction).GFunction
+		strlib.RawSetString(name, L.NewFunction(func(L *lua.LState) int {
+			if check != nil {
+				check(L)
+			}
+			return b.results(L, fn(L))
+		}))
+	}
+	var names []string
+	strlib.ForEach(func(name, value lua.LValue) {
+		if _, ok := value.(*lua.LFunction); ok {
+			names = append(names, name.String())
+		}
+	})
+	for _, name := range names {
+		switch name {
+		case "rep":
+			guard("rep", func(L *lua.LState) int {
+				size := len(L.CheckString(1)) * L.CheckInt(2)
+				if size > maxStringSize {
+					L.RaiseError("string.rep result exceeds %d bytes", maxStringSize)
+				}
+				b.check(L, size)
+				return 0
+			})
+		case "format":
+			guard("format", func(L *lua.LState) int {
+				if wideFormat.MatchString(L.CheckString(1)) {
+					L.RaiseError("string.format widths and precisions are limited to two digits")
+				}
+				b.check(L, b.sizes(L, 1, L.GetTop()))
+				return 0
+			})
+		case "gsub":
+			guard("gsub", b.gsub)
+		case "gmatch":
+			// gmatch keeps its iterator in an upvalue, so it is called
+			// rather than wrapped
+			gmatch := strlib.RawGetString("gmatch")
+			strlib.RawSetString("gmatch", L.NewFunction(func(L *lua.LState) int {
+				L.Insert(gmatch, 1)
+				L.Call(L.GetTop()-1, 2)
+				iter := L.Get(1).(*lua.LFunction).GFunction
+				L.Replace(1, L.NewFunction(func(L *lua.LState) int {
+					return b.results(L, iter(L))
+				}))
+				return 2
+			}))
+		default:
+			guard(name, nil)
+		}
+	}
+
+	tablib := L.GetGlobal(lua.TabLibName).(*lua.LTable)
+	concat := tablib.RawGetString("concat").(*lua.LFunction).GFunction
+	tablib.RawSetString("concat", L.NewFunction(func(L *lua.LState) int {
+		b.check(L, b.elements(L))
+		return b.results(L, concat(L))
+	}))
+	insert := tablib.RawGetString("insert").(*lua.LFunction).GFunction
+	tablib.RawSetString("insert", L.NewFunction(func(L *lua.LState) int {
+		b.charge(L, slotSize)
+		return insert(L)
+	}))
+	rawset := L.GetGlobal("rawset").(*lua.LFunction).GFunction
+	L.SetGlobal("rawset", L.NewFunction(func(L *lua.LState) int {
+		b.grow(L, L.CheckTable(1), L.CheckAny(2), L.CheckAny(3))
+		return rawset(L)
+	}))
+
+	raw := L.NewFunctionFromProto(rawConcat)
+	guards := []lua.LValue{
+		L.NewFunction(func(L *lua.LState) int {
+			lhs, lok := L.Get(1).(lua.LString)
+			rhs, rok := L.Get(2).(lua.LString)
+			if lok && rok {
+				b.charge(L, len(lhs)+len(rhs))
+				L.Push(lhs + rhs)
+				return 1
+			}
+			// Numbers and __concat metamethods are left to Lua
+			L.Insert(raw, 1)
+			L.Call(2, 1)
+			return b.results(L, 1)
+		}),
+		L.NewFunction(func(L *lua.LState) int {
+			table := L.CheckTable(1)
+			size := tableSize
+			table.ForEach(func(_, _ lua.LValue) { size += slotSize })
+			b.charge(L, size)
+			return 1
+		}),
+		L.NewFunction(func(L *lua.LState) int {
+			table, key, value := L.Get(1), L.Get(2), L.Get(3)
+			if table, ok := table.(*lua.LTable); ok {
+				b.grow(L, table, key, value)
+			}
+			L.SetTable(table, key, value)
+			return 0
+		}),
+	}
+	return L, guards
+}
+
+// document converts doc to a Lua table
+func (e *Engine) document(L *lua.LState, doc Document) *lua.LTable {
+	metadata := L.NewTable()
+	for key, value := range doc.Metadata {
+		metadata.RawSetString(strings.ToLower(key), lua.LString(value))
+	}
+
+	table := L.NewTable()
+	table.RawSetString("operation", lua.LString(doc.Operation))
+	table.RawSetString("tenant", lua.LString(doc.Tenant))
+	table.RawSetString("pages", lua.LNumber(doc.Pages))
+	table.RawSetString("size", lua.LNumber(doc.Size))
+	table.RawSetString("metadata", metadata)
+	return table
+}
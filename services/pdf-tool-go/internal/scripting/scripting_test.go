@@ -0,0 +1,122 @@
+package scripting
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEngine_Run(t *testing.T) {
+	dir := t.TempDir()
+	script := func(tenant, source string) config.TenantConfig {
+		path := filepath.Join(dir, tenant+".lua")
+		assert.NoError(t, os.WriteFile(path, []byte(source), 0o600))
+		return config.TenantConfig{ID: tenant, Script: path}
+	}
+
+	tenants := []config.TenantConfig{
+		script("acme", `
+			function transform(doc)
+				if doc.metadata.subject == "contract" then
+					return {watermark = "CONFIDENTIAL"}
+				end
+			end
+			function output_name(doc)
+				return doc.operation .. "-" .. doc.pages
+			end`),
+		script("spin", `function transform(doc) while true do end end`),
+		script("escape", `function transform(doc) return {watermark = dofile("/etc/passwd")} end`),
+		script("huge", `function output_name(doc) return ("x"):rep(1e9) end`),
+		script("hoard", `
+			function transform(doc)
+				local pages = {}
+				for i = 1, 1e7 do
+					pages[i] = ("x"):rep(1000) .. i
+				end
+			end`),
+		script("double", `
+			function output_name(doc)
+				local s = "x"
+				while true do s = s .. s end
+			end`),
+		script("join", `
+			function output_name(doc)
+				local parts, part = {}, ("x"):rep(1e4)
+				for i = 1, 1e4 do parts[i] = part end
+				return table.concat(parts)
+			end`),
+		script("expand", `
+			function output_name(doc)
+				return (("x"):rep(1e4):gsub("", ("y"):rep(1e4)))
+			end`),
+		script("swallow", `
+			function output_name(doc)
+				local pages = {}
+				pcall(function() for i = 1, 1e7 do pages[i] = {("x"):rep(1000)} end end)
+				return "kept"
+			end`),
+		script("fields", `
+			function output_name(doc)
+				local t, i = {}, 1
+				i, t[i] = 2, "a"
+				return t[1] .. i .. #t
+			end`),
+		{ID: "plain"},
+	}
+	cfg := config.ScriptingConfig{Enabled: true, Timeout: 100, MemoryBytes: 1 << 20, CallStackSize: 64, RegistrySize: 4096}
+	engine, err := New(cfg, tenants, logger.New("error", "text"))
+	assert.NoError(t, err)
+
+	t.Run("Hooks Return Actions", func(t *testing.T) {
+		actions, err := engine.Run(context.Background(), Document{
+			Operation: "compress",
+			Tenant:    "acme",
+			Pages:     3,
+			Metadata:  map[string]string{"Subject": "contract"},
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, "CONFIDENTIAL", actions.Watermark)
+		assert.Equal(t, "compress-3", actions.Name)
+	})
+
+	t.Run("Tenants Without Scripts Get No Actions", func(t *testing.T) {
+		actions, err := engine.Run(context.Background(), Document{Tenant: "plain"})
+		assert.NoError(t, err)
+		assert.Equal(t, &Actions{}, actions)
+	})
+
+	t.Run("Runaway Scripts Time Out", func(t *testing.T) {
+		_, err := engine.Run(context.Background(), Document{Tenant: "spin"})
+		assert.ErrorIs(t, err, ErrTimeout)
+	})
+
+	t.Run("Sandbox Blocks Files And Huge Strings", func(t *testing.T) {
+		_, err := engine.Run(context.Background(), Document{Tenant: "escape"})
+		assert.Error(t, err)
+		_, err = engine.Run(context.Background(), Document{Tenant: "huge"})
+		assert.Error(t, err)
+	})
+
+	t.Run("Memory Hungry Scripts Exceed The Budget", func(t *testing.T) {
+		for _, tenant := range []string{"hoard", "double", "join", "expand", "swallow"} {
+			_, err := engine.Run(context.Background(), Document{Tenant: tenant})
+			assert.ErrorIs(t, err, ErrMemory, tenant)
+		}
+	})
+
+	t.Run("Assignments Keep Lua Semantics", func(t *testing.T) {
+		actions, err := engine.Run(context.Background(), Document{Tenant: "fields"})
+		assert.NoError(t, err)
+		assert.Equal(t, "a21", actions.Name)
+	})
+
+	t.Run("Invalid Scripts Fail At Startup", func(t *testing.T) {
+		_, err := New(cfg, []config.TenantConfig{script("broken", "function (")}, logger.New("error", "text"))
+		assert.Error(t, err)
+	})
+}
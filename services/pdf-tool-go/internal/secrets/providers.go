@@ -0,0 +1,168 @@
+package secrets
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/secretsmanager"
+)
+
+// httpClient is shared by the HTTP-based providers
+var httpClient = &http.Client{Timeout: 10 * time.Second}
+
+// VaultProvider reads secrets from HashiCorp Vault's HTTP API using
+// VAULT_ADDR and VAULT_TOKEN
+type VaultProvider struct{}
+
+// Resolve implements Provider for "<path>#<field>" references
+func (p *VaultProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	path, field := splitField(ref)
+	if field == "" {
+		return "", fmt.Errorf("vault reference %q must name a field", ref)
+	}
+
+	addr := strings.TrimRight(os.Getenv("VAULT_ADDR"), "/")
+	token := os.Getenv("VAULT_TOKEN")
+	if addr == "" || token == "" {
+		return "", fmt.Errorf("VAULT_ADDR and VAULT_TOKEN must be set")
+	}
+
+	var body struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	err := getJSON(ctx, addr+"/v1/"+strings.TrimLeft(path, "/"), map[string]string{"X-Vault-Token": token}, &body)
+	if err != nil {
+		return "", err
+	}
+
+	// KV v2 nests the secret under data.data
+	data := body.Data
+	if nested, ok := data["data"].(map[string]interface{}); ok {
+		data = nested
+	}
+	value, ok := data[field]
+	if !ok {
+		return "", fmt.Errorf("vault secret %s has no field %q", path, field)
+	}
+	return fmt.Sprint(value), nil
+}
+
+// AWSProvider reads secrets from AWS Secrets Manager using the default
+// credential chain
+type AWSProvider struct {
+	once   sync.Once
+	client *secretsmanager.Client
+	err    error
+}
+
+// Resolve implements Provider for "<secret-id>[#<json-field>]" references
+func (p *AWSProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	p.once.Do(func() {
+		awsCfg, err := awsconfig.LoadDefaultConfig(ctx)
+		if err != nil {
+			p.err = fmt.Errorf("failed to load AWS configuration: %w", err)
+			return
+		}
+		p.client = secretsmanager.NewFromConfig(awsCfg)
+	})
+	if p.err != nil {
+		return "", p.err
+	}
+
+	id, field := splitField(ref)
+	out, err := p.client.GetSecretValue(ctx, &secretsmanager.GetSecretValueInput{
+		SecretId: aws.String(id),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	secret := aws.ToString(out.SecretString)
+	if out.SecretString == nil {
+		secret = string(out.SecretBinary)
+	}
+	return jsonField(secret, field)
+}
+
+// GCPProvider reads secrets from GCP Secret Manager's REST API, authenticating
+// with the instance service account through the metadata server
+type GCPProvider struct{}
+
+// metadataTokenURL issues access tokens for the default service account
+const metadataTokenURL = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+
+// Resolve implements Provider for "projects/<p>/secrets/<s>[/versions/<v>]" references
+func (p *GCPProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	name, field := splitField(ref)
+	if !strings.Contains(name, "/versions/") {
+		name += "/versions/latest"
+	}
+
+	var token struct {
+		AccessToken string `json:"access_token"`
+	}
+	if err := getJSON(ctx, metadataTokenURL, map[string]string{"Metadata-Flavor": "Google"}, &token); err != nil {
+		return "", fmt.Errorf("failed to get GCP access token: %w", err)
+	}
+
+	var body struct {
+		Payload struct {
+			Data string `json:"data"`
+		} `json:"payload"`
+	}
+	url := "https://secretmanager.googleapis.com/v1/" + name + ":access"
+	if err := getJSON(ctx, url, map[string]string{"Authorization": "Bearer " + token.AccessToken}, &body); err != nil {
+		return "", err
+	}
+
+	secret, err := base64.StdEncoding.DecodeString(body.Payload.Data)
+	if err != nil {
+		return "", fmt.Errorf("failed to decode secret payload: %w", err)
+	}
+	return jsonField(string(secret), field)
+}
+
+// fileProvider reads secrets from files, such as mounted Kubernetes secrets
+type fileProvider struct{}
+
+// Resolve implements Provider for "<path>" references
+func (fileProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	data, err := os.ReadFile(ref)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// getJSON performs a GET request and decodes a JSON response
+func getJSON(ctx context.Context, url string, headers map[string]string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("unexpected status %d from %s", resp.StatusCode, req.URL.Host)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
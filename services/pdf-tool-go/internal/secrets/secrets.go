@@ -0,0 +1,169 @@
+/**
+ * Secrets Resolution
+ *
+ * Resolves secret references in configuration values so credentials can be
+ * kept in a secrets manager instead of plain environment variables:
+ *
+ *   vault://<path>#<field>             HashiCorp Vault (KV v1 or v2)
+ *   awssm://<secret-id>[#<json-field>] AWS Secrets Manager
+ *   gcpsm://projects/<p>/secrets/<s>   GCP Secret Manager (latest version
+ *                                      unless /versions/<v> is given)
+ *   file://<path>                      mounted secret file
+ */
+
+package secrets
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Provider fetches the secret a reference points at
+type Provider interface {
+	Resolve(ctx context.Context, ref string) (string, error)
+}
+
+// Resolver dispatches references to providers by scheme
+type Resolver struct {
+	providers map[string]Provider
+}
+
+// NewResolver creates a resolver with the built-in providers. Cloud
+// providers are configured from their standard environment (VAULT_ADDR and
+// VAULT_TOKEN, the AWS credential chain, the GCP metadata server).
+func NewResolver() *Resolver {
+	return &Resolver{
+		providers: map[string]Provider{
+			"vault": &VaultProvider{},
+			"awssm": &AWSProvider{},
+			"gcpsm": &GCPProvider{},
+			"file":  fileProvider{},
+		},
+	}
+}
+
+// Register adds or replaces the provider for a scheme
+func (r *Resolver) Register(scheme string, provider Provider) {
+	r.providers[scheme] = provider
+}
+
+// IsReference reports whether value is a secret reference
+func (r *Resolver) IsReference(value string) bool {
+	scheme, _, ok := strings.Cut(value, "://")
+	if !ok {
+		return false
+	}
+	_, known := r.providers[scheme]
+	return known
+}
+
+// Resolve returns the secret value for ref, or value unchanged if it is not
+// a secret reference
+func (r *Resolver) Resolve(ctx context.Context, value string) (string, error) {
+	scheme, ref, ok := strings.Cut(value, "://")
+	if !ok {
+		return value, nil
+	}
+	provider, known := r.providers[scheme]
+	if !known {
+		return value, nil
+	}
+
+	secret, err := provider.Resolve(ctx, ref)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve %s secret: %w", scheme, err)
+	}
+	return secret, nil
+}
+
+// ResolveStruct replaces every secret reference in the string, []string and
+// map[string]string fields of the struct pointed to by target, recursively
+func (r *Resolver) ResolveStruct(ctx context.Context, target interface{}) error {
+	return r.resolveValue(ctx, reflect.ValueOf(target).Elem(), "")
+}
+
+func (r *Resolver) resolveValue(ctx context.Context, v reflect.Value, path string) error {
+	switch v.Kind() {
+	case reflect.Struct:
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			if !t.Field(i).IsExported() {
+				continue
+			}
+			fieldPath := path
+			switch name := t.Field(i).Tag.Get("mapstructure"); {
+			case name == "":
+				fieldPath = joinPath(path, t.Field(i).Name)
+			case name != ",squash":
+				fieldPath = joinPath(path, name)
+			}
+			if err := r.resolveValue(ctx, v.Field(i), fieldPath); err != nil {
+				return err
+			}
+		}
+	case reflect.String:
+		resolved, err := r.Resolve(ctx, v.String())
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		v.SetString(resolved)
+	case reflect.Slice:
+		if kind := v.Type().Elem().Kind(); kind != reflect.String && kind != reflect.Struct {
+			return nil
+		}
+		for i := 0; i < v.Len(); i++ {
+			if err := r.resolveValue(ctx, v.Index(i), fmt.Sprintf("%s[%d]", path, i)); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		if v.Type().Key().Kind() != reflect.String || v.Type().Elem().Kind() != reflect.String {
+			return nil
+		}
+		for _, key := range v.MapKeys() {
+			resolved, err := r.Resolve(ctx, v.MapIndex(key).String())
+			if err != nil {
+				return fmt.Errorf("%s: %w", joinPath(path, key.String()), err)
+			}
+			v.SetMapIndex(key, reflect.ValueOf(resolved).Convert(v.Type().Elem()))
+		}
+	}
+	return nil
+}
+
+func joinPath(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+// splitField separates "<location>#<field>" references
+func splitField(ref string) (string, string) {
+	location, field, _ := strings.Cut(ref, "#")
+	return location, field
+}
+
+// jsonField extracts a field from a JSON object secret. An empty field
+// returns the whole secret.
+func jsonField(secret, field string) (string, error) {
+	if field == "" {
+		return secret, nil
+	}
+
+	var values map[string]interface{}
+	if err := json.Unmarshal([]byte(secret), &values); err != nil {
+		return "", fmt.Errorf("secret is not a JSON object: %w", err)
+	}
+	value, ok := values[field]
+	if !ok {
+		return "", fmt.Errorf("secret has no field %q", field)
+	}
+	if s, ok := value.(string); ok {
+		return s, nil
+	}
+	return fmt.Sprint(value), nil
+}
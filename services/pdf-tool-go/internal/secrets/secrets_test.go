@@ -0,0 +1,75 @@
+package secrets
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type staticProvider map[string]string
+
+func (p staticProvider) Resolve(ctx context.Context, ref string) (string, error) {
+	return p[ref], nil
+}
+
+func TestResolveStruct(t *testing.T) {
+	resolver := NewResolver()
+	resolver.Register("test", staticProvider{"s3": "s3-secret", "key": "api-key"})
+
+	type nested struct {
+		Secret string `mapstructure:"secret"`
+	}
+	target := struct {
+		Plain  string            `mapstructure:"plain"`
+		Nested nested            `mapstructure:"nested"`
+		Keys   []string          `mapstructure:"keys"`
+		Tools  map[string]string `mapstructure:"tools"`
+		Count  int               `mapstructure:"count"`
+	}{
+		Plain:  "https://example.com",
+		Nested: nested{Secret: "test://s3"},
+		Keys:   []string{"test://key", "literal"},
+		Tools:  map[string]string{"gs": "test://key"},
+		Count:  3,
+	}
+
+	require.NoError(t, resolver.ResolveStruct(context.Background(), &target))
+
+	assert.Equal(t, "https://example.com", target.Plain)
+	assert.Equal(t, "s3-secret", target.Nested.Secret)
+	assert.Equal(t, []string{"api-key", "literal"}, target.Keys)
+	assert.Equal(t, "api-key", target.Tools["gs"])
+}
+
+func TestFileProvider(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "password")
+	require.NoError(t, os.WriteFile(path, []byte("hunter2\n"), 0600))
+
+	value, err := NewResolver().Resolve(context.Background(), "file://"+path)
+
+	require.NoError(t, err)
+	assert.Equal(t, "hunter2", value)
+}
+
+func TestJSONField(t *testing.T) {
+	t.Run("extracts field", func(t *testing.T) {
+		value, err := jsonField(`{"username":"svc","password":"hunter2"}`, "password")
+		require.NoError(t, err)
+		assert.Equal(t, "hunter2", value)
+	})
+
+	t.Run("missing field", func(t *testing.T) {
+		_, err := jsonField(`{"username":"svc"}`, "password")
+		assert.Error(t, err)
+	})
+
+	t.Run("whole secret without field", func(t *testing.T) {
+		value, err := jsonField("plain", "")
+		require.NoError(t, err)
+		assert.Equal(t, "plain", value)
+	})
+}
@@ -0,0 +1,244 @@
+/**
+ * Encrypted ZIP Archives
+ *
+ * Writes ZIP archives whose entries are AES-256 encrypted as specified by
+ * WinZip (AE-1), which 7-Zip, WinZip and most archive tools open. Keys are
+ * derived from a password with PBKDF2, and each entry is authenticated
+ * with an HMAC.
+ */
+
+package securezip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"fmt"
+	"hash"
+	"io"
+	"math/big"
+
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// methodAES is the compression method of encrypted entries; the actual
+// method is recorded in their AES extra field
+const methodAES = 99
+
+const (
+	saltSize    = 16
+	keySize     = 32
+	macSize     = 10
+	iterations  = 1000
+	flagEncrypt = 0x1
+)
+
+// passwordAlphabet excludes characters easily confused when read out
+const passwordAlphabet = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnpqrstuvwxyz23456789"
+
+// GeneratePassword returns a random password of 20 characters
+func GeneratePassword() (string, error) {
+	password := make([]byte, 20)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range password {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		password[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(password), nil
+}
+
+// Writer is a zip.Writer encrypting its entries with a password. Without
+// a password it writes plain archives.
+type Writer struct {
+	*zip.Writer
+	password string
+	// method is the compression method of the entry being created
+	method uint16
+}
+
+// NewWriter returns a writer of an archive to w, encrypted with password
+// unless it is empty
+func NewWriter(w io.Writer, password string) *Writer {
+	zw := &Writer{Writer: zip.NewWriter(w), password: password}
+	if password != "" {
+		zw.RegisterCompressor(methodAES, zw.compressor)
+	}
+	return zw
+}
+
+// Create adds a deflated entry named name
+func (w *Writer) Create(name string) (io.Writer, error) {
+	return w.CreateHeader(&zip.FileHeader{Name: name, Method: zip.Deflate})
+}
+
+// CreateHeader adds an entry described by fh, which must be stored or
+// deflated
+func (w *Writer) CreateHeader(fh *zip.FileHeader) (io.Writer, error) {
+	if w.password == "" {
+		return w.Writer.CreateHeader(fh)
+	}
+	if fh.Method != zip.Store && fh.Method != zip.Deflate {
+		return nil, fmt.Errorf("unsupported compression method %d", fh.Method)
+	}
+	w.method = fh.Method
+	fh.Method = methodAES
+	fh.Flags |= flagEncrypt
+	// AES extra field: vendor version AE-1, vendor "AE", AES-256 and the
+	// actual compression method
+	fh.Extra = append(fh.Extra, 0x01, 0x99, 7, 0, 1, 0, 'A', 'E', 3, byte(w.method), byte(w.method>>8))
+	return w.Writer.CreateHeader(fh)
+}
+
+// Encrypt rewrites a plain archive with its entries encrypted with
+// password
+func Encrypt(archive []byte, password string) ([]byte, error) {
+	zr, err := zip.NewReader(bytes.NewReader(archive), int64(len(archive)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid archive: %w", err)
+	}
+	var buf bytes.Buffer
+	zw := NewWriter(&buf, password)
+	for _, f := range zr.File {
+		w, err := zw.CreateHeader(&zip.FileHeader{
+			Name:          f.Name,
+			Comment:       f.Comment,
+			Method:        f.Method,
+			Modified:      f.Modified,
+			ExternalAttrs: f.ExternalAttrs,
+		})
+		if err != nil {
+			return nil, err
+		}
+		r, err := f.Open()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		_, err = io.Copy(w, r)
+		r.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// compressor writes an encrypted entry: the salt and password verifier,
+// the encrypted data and its authentication code
+func (w *Writer) compressor(out io.Writer) (io.WriteCloser, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	keys := pbkdf2.Key([]byte(w.password), salt, iterations, 2*keySize+2, sha1.New)
+	block, err := aes.NewCipher(keys[:keySize])
+	if err != nil {
+		return nil, err
+	}
+
+	e := &entry{
+		out:    out,
+		head:   append(salt, keys[2*keySize:]...),
+		stream: newCounter(block),
+		mac:    hmac.New(sha1.New, keys[keySize:2*keySize]),
+	}
+	if w.method == zip.Deflate {
+		fw, err := flate.NewWriter(e, flate.DefaultCompression)
+		if err != nil {
+			return nil, err
+		}
+		return &deflated{Writer: fw, entry: e}, nil
+	}
+	return e, nil
+}
+
+// entry encrypts and authenticates the data of an entry
+type entry struct {
+	out io.Writer
+	// head is the salt and password verifier, written with the first data
+	// as compressors are created before the entry's local header is written
+	head   []byte
+	stream cipher.Stream
+	mac    hash.Hash
+}
+
+func (e *entry) Write(p []byte) (int, error) {
+	if err := e.writeHead(); err != nil {
+		return 0, err
+	}
+	encrypted := make([]byte, len(p))
+	e.stream.XORKeyStream(encrypted, p)
+	e.mac.Write(encrypted)
+	return e.out.Write(encrypted)
+}
+
+// Close writes the authentication code
+func (e *entry) Close() error {
+	if err := e.writeHead(); err != nil {
+		return err
+	}
+	_, err := e.out.Write(e.mac.Sum(nil)[:macSize])
+	return err
+}
+
+func (e *entry) writeHead() error {
+	if e.head == nil {
+		return nil
+	}
+	_, err := e.out.Write(e.head)
+	e.head = nil
+	return err
+}
+
+// deflated compresses an entry before encryption
+type deflated struct {
+	*flate.Writer
+	entry *entry
+}
+
+func (d *deflated) Close() error {
+	if err := d.Writer.Close(); err != nil {
+		return err
+	}
+	return d.entry.Close()
+}
+
+// counter is AES in counter mode with the little-endian counter, starting
+// at 1, that WinZip uses
+type counter struct {
+	block   cipher.Block
+	counter [aes.BlockSize]byte
+	stream  [aes.BlockSize]byte
+	used    int
+}
+
+func newCounter(block cipher.Block) *counter {
+	return &counter{block: block, used: aes.BlockSize}
+}
+
+func (c *counter) XORKeyStream(dst, src []byte) {
+	for i := range src {
+		if c.used == aes.BlockSize {
+			for j := range c.counter {
+				c.counter[j]++
+				if c.counter[j] != 0 {
+					break
+				}
+			}
+			c.block.Encrypt(c.stream[:], c.counter[:])
+			c.used = 0
+		}
+		dst[i] = src[i] ^ c.stream[c.used]
+		c.used++
+	}
+}
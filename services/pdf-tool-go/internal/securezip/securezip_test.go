@@ -0,0 +1,115 @@
+package securezip
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"crypto/aes"
+	"crypto/hmac"
+	"crypto/sha1"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+// decrypt reads an encrypted entry as an archive tool would
+func decrypt(t *testing.T, f *zip.File, password string) []byte {
+	require.Equal(t, uint16(methodAES), f.Method)
+	at := bytes.Index(f.Extra, []byte{0x01, 0x99, 7, 0, 1, 0, 'A', 'E', 3})
+	require.GreaterOrEqual(t, at, 0, "AES extra field")
+	method := uint16(f.Extra[at+9]) | uint16(f.Extra[at+10])<<8
+
+	r, err := f.OpenRaw()
+	require.NoError(t, err)
+	raw, err := io.ReadAll(r)
+	require.NoError(t, err)
+	salt, verifier := raw[:saltSize], raw[saltSize:saltSize+2]
+	data, mac := raw[saltSize+2:len(raw)-macSize], raw[len(raw)-macSize:]
+
+	keys := pbkdf2.Key([]byte(password), salt, iterations, 2*keySize+2, sha1.New)
+	require.Equal(t, keys[2*keySize:], verifier, "password verifier")
+	h := hmac.New(sha1.New, keys[keySize:2*keySize])
+	h.Write(data)
+	require.Equal(t, h.Sum(nil)[:macSize], mac, "authentication code")
+
+	block, err := aes.NewCipher(keys[:keySize])
+	require.NoError(t, err)
+	plain := make([]byte, len(data))
+	newCounter(block).XORKeyStream(plain, data)
+	if method == zip.Deflate {
+		plain, err = io.ReadAll(flate.NewReader(bytes.NewReader(plain)))
+		require.NoError(t, err)
+	}
+	return plain
+}
+
+func TestWriter(t *testing.T) {
+	content := bytes.Repeat([]byte("statement of account "), 100)
+
+	t.Run("Entries Are Encrypted", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := NewWriter(&buf, "correct horse")
+		for _, method := range []uint16{zip.Store, zip.Deflate} {
+			w, err := zw.CreateHeader(&zip.FileHeader{Name: "statement.pdf", Method: method})
+			require.NoError(t, err)
+			_, err = w.Write(content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, zw.Close())
+		assert.False(t, bytes.Contains(buf.Bytes(), []byte("statement of account")))
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 2)
+		for _, f := range zr.File {
+			assert.Equal(t, uint16(flagEncrypt), f.Flags&flagEncrypt)
+			assert.Equal(t, content, decrypt(t, f, "correct horse"))
+		}
+	})
+
+	t.Run("Without A Password Archives Are Plain", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := NewWriter(&buf, "")
+		w, err := zw.Create("statement.pdf")
+		require.NoError(t, err)
+		w.Write(content)
+		require.NoError(t, zw.Close())
+
+		zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+		require.NoError(t, err)
+		r, err := zr.File[0].Open()
+		require.NoError(t, err)
+		data, err := io.ReadAll(r)
+		require.NoError(t, err)
+		assert.Equal(t, content, data)
+	})
+
+	t.Run("Plain Archives Can Be Encrypted", func(t *testing.T) {
+		var buf bytes.Buffer
+		zw := zip.NewWriter(&buf)
+		w, err := zw.Create("manifest.json")
+		require.NoError(t, err)
+		w.Write([]byte(`{"files":1}`))
+		require.NoError(t, zw.Close())
+
+		encrypted, err := Encrypt(buf.Bytes(), "correct horse")
+		require.NoError(t, err)
+		zr, err := zip.NewReader(bytes.NewReader(encrypted), int64(len(encrypted)))
+		require.NoError(t, err)
+		require.Len(t, zr.File, 1)
+		assert.Equal(t, "manifest.json", zr.File[0].Name)
+		assert.Equal(t, []byte(`{"files":1}`), decrypt(t, zr.File[0], "correct horse"))
+	})
+}
+
+func TestGeneratePassword(t *testing.T) {
+	a, err := GeneratePassword()
+	require.NoError(t, err)
+	b, err := GeneratePassword()
+	require.NoError(t, err)
+	assert.Len(t, a, 20)
+	assert.NotEqual(t, a, b)
+}
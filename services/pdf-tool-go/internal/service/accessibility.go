@@ -0,0 +1,387 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxStructureNodes bounds the structure tree walk, so a cyclic or hostile
+// tree cannot stall a check
+const maxStructureNodes = 100000
+
+// PDF/UA rules checked by CheckAccessibility
+const (
+	RuleTagged          = "tagged"
+	RuleStructureTree   = "structure_tree"
+	RuleLanguage        = "language"
+	RuleTitle           = "title"
+	RuleDisplayTitle    = "display_doc_title"
+	RuleFigureAltText   = "figure_alt_text"
+	RulePDFUAIdentifier = "pdfua_identifier"
+)
+
+// AccessibilityIssue is a failed PDF/UA rule
+type AccessibilityIssue struct {
+	Rule    string
+	Message string
+	// Fixable issues are resolved by AutoTag
+	Fixable bool
+}
+
+// AccessibilityReport is the result of a PDF/UA check
+type AccessibilityReport struct {
+	Compliant bool
+	PageCount int
+	Issues    []AccessibilityIssue
+}
+
+// AutoTagRequest represents a best-effort accessibility fix request
+type AutoTagRequest struct {
+	PDFData []byte
+	// Lang is the natural language to declare, e.g. "en-US", when the
+	// document has none
+	Lang string
+}
+
+// AccessibilityPropertiesRequest sets the accessibility properties of a
+// document. Unset fields are left unchanged.
+type AccessibilityPropertiesRequest struct {
+	PDFData []byte `json:"-"`
+	// Lang is the natural language of the document, e.g. "en-US"
+	Lang string `json:"lang"`
+	// DisplayDocTitle has viewers show the title instead of the file name
+	DisplayDocTitle *bool `json:"display_doc_title"`
+	// AltText maps figure numbers, counted from 1 in structure tree order,
+	// to their alternate text
+	AltText map[string]string `json:"alt_text"`
+}
+
+// AccessibilityResponse contains a fixed document and the issues that
+// remain
+type AccessibilityResponse struct {
+	PDF    []byte `json:"-"`
+	Report *AccessibilityReport
+}
+
+// CheckAccessibility audits a document against the machine-checkable
+// PDF/UA rules: tagging, a structure tree for reading order, a declared
+// language and title, and alternate text on figures. Rules needing human
+// judgement, like whether alt text is meaningful, are out of scope.
+func (s *PDFService) CheckAccessibility(ctx context.Context, pdfData []byte) (_ *AccessibilityReport, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.CheckAccessibility")
+	defer span.End()
+
+	op := metrics.Start("check_accessibility")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "check_accessibility")
+	defer cancel()
+
+	s.log.Info("Checking PDF accessibility")
+
+	var report *AccessibilityReport
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		report = checkAccessibility(ctx2, pdfData)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(report.PageCount)
+	span.SetAttributes(attribute.Int("issues", len(report.Issues)))
+
+	s.log.Info("Accessibility check completed", "compliant", report.Compliant, "issues", len(report.Issues))
+
+	return report, nil
+}
+
+// AutoTag applies the document-level fixes PDF/UA needs: it marks the
+// document as tagged, declares its language and has viewers show its
+// title. Content without a structure tree cannot be tagged reliably, so
+// such documents still fail the structure rule afterwards.
+func (s *PDFService) AutoTag(ctx context.Context, req *AutoTagRequest) (_ *AccessibilityResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.AutoTag")
+	defer span.End()
+
+	op := metrics.Start("auto_tag")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "auto_tag")
+	defer cancel()
+
+	s.log.Info("Auto-tagging PDF", "lang", req.Lang)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response, err := s.editAccessibility(ctx, req.PDFData, func(ctx2 *pdfcpu.Context) error {
+		root := ctx2.RootDict
+		root.Update("MarkInfo", pdfcpu.Dict{"Marked": pdfcpu.Boolean(true)})
+		if _, ok := root.Find("Lang"); !ok && req.Lang != "" {
+			root.Update("Lang", pdfcpu.StringLiteral(req.Lang))
+		}
+		setDisplayDocTitle(ctx2, true)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(response.Report.PageCount)
+	op.Output(int64(len(response.PDF)))
+
+	s.log.Info("Auto-tagging completed", "remaining_issues", len(response.Report.Issues))
+
+	return response, nil
+}
+
+// SetAccessibilityProperties sets the language, title display and figure
+// alternate text of a document. Alternate text can only be attached to
+// figures in the structure tree, so untagged documents have none to set.
+func (s *PDFService) SetAccessibilityProperties(ctx context.Context, req *AccessibilityPropertiesRequest) (_ *AccessibilityResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.SetAccessibilityProperties")
+	defer span.End()
+
+	op := metrics.Start("accessibility_properties")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "accessibility_properties")
+	defer cancel()
+
+	span.SetAttributes(attribute.Int("alt_texts", len(req.AltText)))
+	s.log.Info("Setting PDF accessibility properties", "lang", req.Lang, "alt_texts", len(req.AltText))
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response, err := s.editAccessibility(ctx, req.PDFData, func(ctx2 *pdfcpu.Context) error {
+		if err := setAltText(ctx2, req.AltText); err != nil {
+			return err
+		}
+		if req.Lang != "" {
+			ctx2.RootDict.Update("Lang", pdfcpu.StringLiteral(req.Lang))
+		}
+		if req.DisplayDocTitle != nil {
+			setDisplayDocTitle(ctx2, *req.DisplayDocTitle)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(response.Report.PageCount)
+	op.Output(int64(len(response.PDF)))
+
+	s.log.Info("Accessibility properties set", "remaining_issues", len(response.Report.Issues))
+
+	return response, nil
+}
+
+// editAccessibility applies edit to a parsed document, writes it and checks
+// the result
+func (s *PDFService) editAccessibility(ctx context.Context, pdfData []byte, edit func(*pdfcpu.Context) error) (*AccessibilityResponse, error) {
+	response := &AccessibilityResponse{}
+	err := s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		if err := edit(ctx2); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		response.PDF = buf.Bytes()
+		response.Report = checkAccessibility(ctx2, response.PDF)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// setDisplayDocTitle sets the DisplayDocTitle viewer preference
+func setDisplayDocTitle(ctx2 *pdfcpu.Context, display bool) {
+	prefs, _ := dictEntry(ctx2, ctx2.RootDict, "ViewerPreferences")
+	if prefs == nil {
+		prefs = pdfcpu.Dict{}
+	}
+	prefs.Update("DisplayDocTitle", pdfcpu.Boolean(display))
+	ctx2.RootDict.Update("ViewerPreferences", prefs)
+}
+
+// setAltText sets the alternate text of figures by number. All numbers are
+// checked before any figure is changed.
+func setAltText(ctx2 *pdfcpu.Context, altText map[string]string) error {
+	if len(altText) == 0 {
+		return nil
+	}
+	var found []pdfcpu.Dict
+	if tree, ok := dictEntry(ctx2, ctx2.RootDict, "StructTreeRoot"); ok {
+		found = figures(ctx2, tree)
+	}
+
+	targets := make(map[int]string, len(altText))
+	for key, text := range altText {
+		n, err := strconv.Atoi(key)
+		if err != nil || n < 1 || n > len(found) {
+			return NewError(ErrCodeInvalidInput, fmt.Sprintf("alt_text key %q is not a figure number; the document has %d figures", key, len(found)), nil)
+		}
+		targets[n] = text
+	}
+	for n, text := range targets {
+		found[n-1].Update("Alt", pdfcpu.StringLiteral(text))
+	}
+	return nil
+}
+
+// readContext parses a document for inspection, enforcing the page limit
+func (s *PDFService) readContext(ctx context.Context, pdfData []byte) (*pdfcpu.Context, error) {
+	ctx2 := pdfcpu.NewContext(bytes.NewReader(pdfData), pdfcpu.NewDefaultConfiguration())
+	if err := inSpan(ctx, "pdfcpu.read", ctx2.Read.ReadContext); err != nil {
+		return nil, classifyPDFError(err, "failed to read PDF")
+	}
+	if err := s.enforcePageLimit(ctx, ctx2.PageCount); err != nil {
+		return nil, err
+	}
+	return ctx2, nil
+}
+
+// checkAccessibility runs the PDF/UA rules over a parsed document
+func checkAccessibility(ctx2 *pdfcpu.Context, pdfData []byte) *AccessibilityReport {
+	report := &AccessibilityReport{PageCount: ctx2.PageCount, Issues: []AccessibilityIssue{}}
+	fail := func(rule, message string, fixable bool) {
+		report.Issues = append(report.Issues, AccessibilityIssue{Rule: rule, Message: message, Fixable: fixable})
+	}
+	root := ctx2.RootDict
+
+	markInfo, _ := dictEntry(ctx2, root, "MarkInfo")
+	if marked, _ := markInfo.Find("Marked"); marked != pdfcpu.Boolean(true) {
+		fail(RuleTagged, "document is not marked as tagged (MarkInfo /Marked)", true)
+	}
+
+	if tree, ok := dictEntry(ctx2, root, "StructTreeRoot"); !ok {
+		fail(RuleStructureTree, "document has no structure tree, so assistive technology cannot determine reading order", false)
+	} else if missing := figuresWithoutAlt(ctx2, tree); missing > 0 {
+		fail(RuleFigureAltText, fmt.Sprintf("%d figures have no alternate text", missing), false)
+	}
+
+	if lang, ok := root.Find("Lang"); !ok || lang == pdfcpu.StringLiteral("") {
+		fail(RuleLanguage, "document declares no natural language (Lang)", true)
+	}
+
+	if !hasTitle(ctx2) {
+		fail(RuleTitle, "document has no title in its document information", false)
+	}
+	prefs, _ := dictEntry(ctx2, root, "ViewerPreferences")
+	if display, _ := prefs.Find("DisplayDocTitle"); display != pdfcpu.Boolean(true) {
+		fail(RuleDisplayTitle, "viewers are not told to display the title instead of the file name", true)
+	}
+
+	// XMP metadata is normally stored uncompressed, so the identifier can
+	// be found without decoding streams
+	if !bytes.Contains(pdfData, []byte("pdfuaid:part")) {
+		fail(RulePDFUAIdentifier, "XMP metadata does not claim PDF/UA conformance (pdfuaid:part)", false)
+	}
+
+	report.Compliant = len(report.Issues) == 0
+	return report
+}
+
+// dictEntry returns the dictionary stored under key, following an indirect
+// reference
+func dictEntry(ctx2 *pdfcpu.Context, dict pdfcpu.Dict, key string) (pdfcpu.Dict, bool) {
+	obj, ok := dict.Find(key)
+	if !ok {
+		return nil, false
+	}
+	entry, err := ctx2.DereferenceDict(obj)
+	if err != nil || entry == nil {
+		return nil, false
+	}
+	return entry, true
+}
+
+// hasTitle reports whether the document information has a non-empty title
+func hasTitle(ctx2 *pdfcpu.Context) bool {
+	if ctx2.Info == nil {
+		return false
+	}
+	info, err := ctx2.DereferenceDict(*ctx2.Info)
+	if err != nil || info == nil {
+		return false
+	}
+	title, ok := info.Find("Title")
+	return ok && title != pdfcpu.StringLiteral("") && title != pdfcpu.HexLiteral("")
+}
+
+// figures returns the Figure structure elements in structure tree order
+func figures(ctx2 *pdfcpu.Context, tree pdfcpu.Dict) []pdfcpu.Dict {
+	var found []pdfcpu.Dict
+	visited := 0
+	var walk func(obj pdfcpu.Object)
+	walk = func(obj pdfcpu.Object) {
+		if visited++; visited > maxStructureNodes {
+			return
+		}
+		if array, ok := obj.(pdfcpu.Array); ok {
+			for _, kid := range array {
+				walk(kid)
+			}
+			return
+		}
+		// Kids may also be marked-content identifiers, which end the walk
+		elem, err := ctx2.DereferenceDict(obj)
+		if err != nil || elem == nil {
+			return
+		}
+		if kind, _ := elem.Find("S"); kind == pdfcpu.Name("Figure") {
+			found = append(found, elem)
+		}
+		if kids, ok := elem.Find("K"); ok {
+			if array, err := ctx2.Dereference(kids); err == nil {
+				walk(array)
+			}
+		}
+	}
+	if kids, ok := tree.Find("K"); ok {
+		walk(kids)
+	}
+	return found
+}
+
+// figuresWithoutAlt counts Figure structure elements with neither Alt nor
+// ActualText
+func figuresWithoutAlt(ctx2 *pdfcpu.Context, tree pdfcpu.Dict) int {
+	missing := 0
+	for _, figure := range figures(ctx2, tree) {
+		_, alt := figure.Find("Alt")
+		_, actual := figure.Find("ActualText")
+		if !alt && !actual {
+			missing++
+		}
+	}
+	return missing
+}
@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// fieldReadOnly is the ReadOnly bit of form field flags
+const fieldReadOnly = 1 << 0
+
+// AssemblyComponent is one part of an assembled document, such as a cover
+// template, an uploaded body or a standard terms document
+type AssemblyComponent struct {
+	// Name identifies the component in errors, like its document ID or
+	// upload field
+	Name string
+	PDF  []byte
+	// Template components are forms whose text fields are filled from the
+	// assembly's variables
+	Template bool
+	// Pages selects pages of the component in order, like "1-3,5"; empty
+	// selects every page
+	Pages string
+}
+
+// AssembleRequest builds one document from components in order
+type AssembleRequest struct {
+	Components []AssemblyComponent
+	// Variables fill the text fields of template components, by full
+	// field name. Every variable must fill a field of some template.
+	Variables map[string]string
+	// OutputName is the file name the document is offered under
+	OutputName string
+}
+
+// Assemble fills the template components of a document from its variables
+// and joins the components. Filled fields are made read-only and viewers
+// are asked to render their values, so templates need no fonts of their
+// own for the values.
+func (s *PDFService) Assemble(ctx context.Context, req *AssembleRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.Assemble")
+	defer span.End()
+
+	op := metrics.Start("assemble")
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "assemble")
+	defer cancel()
+
+	span.SetAttributes(attribute.Int("components", len(req.Components)), attribute.Int("variables", len(req.Variables)))
+	s.log.Info("Assembling PDF", "components", len(req.Components), "variables", len(req.Variables), "output_name", req.OutputName)
+
+	if len(req.Components) < 2 {
+		return nil, NewError(ErrCodeInvalidInput, "at least 2 components required for assembly", nil)
+	}
+
+	var totalSize int64
+	names := make([]string, len(req.Components))
+	pdfs := make([][]byte, len(req.Components))
+	for i, component := range req.Components {
+		totalSize += int64(len(component.PDF))
+		names[i] = component.Name
+		pdfs[i] = component.PDF
+	}
+	op.Input(totalSize)
+	if err := s.checkResources(ctx, totalSize); err != nil {
+		return nil, err
+	}
+
+	filled := make(map[string]bool, len(req.Variables))
+	for i, component := range req.Components {
+		if !component.Template || len(req.Variables) == 0 {
+			continue
+		}
+		if pdfs[i], err = s.fillTemplate(ctx, component.PDF, req.Variables, filled); err != nil {
+			return nil, inputError(err, i, component.Name)
+		}
+	}
+	var unused []string
+	for name := range req.Variables {
+		if !filled[name] {
+			unused = append(unused, name)
+		}
+	}
+	if len(unused) > 0 {
+		sort.Strings(unused)
+		err := NewError(ErrCodeInvalidInput, fmt.Sprintf("no template has a text field for variables: %s", strings.Join(unused, ", ")), nil)
+		err.Details = map[string]interface{}{"variables": unused}
+		return nil, err
+	}
+
+	manifest, err := assemblyManifest(req.Components)
+	if err != nil {
+		return nil, err
+	}
+	assembled, err := s.MergePDFs(ctx, &MergeRequest{
+		PDFs:       pdfs,
+		OutputName: req.OutputName,
+		InputNames: names,
+		Manifest:   manifest,
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Output(int64(len(assembled)))
+
+	s.log.Info("Assembly completed", "output_size", len(assembled))
+
+	return assembled, nil
+}
+
+// FillFormRequest fills the text fields of a form, like a template row of
+// a mail merge
+type FillFormRequest struct {
+	PDFData []byte
+	// Values fill text fields by full field name. Values naming no field
+	// are ignored, so datasets may carry extra columns, but at least one
+	// must fill a field.
+	Values map[string]string
+}
+
+// FillForm fills the text fields of a form and makes them read-only
+func (s *PDFService) FillForm(ctx context.Context, req *FillFormRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.FillForm")
+	defer span.End()
+
+	op := metrics.Start("fill_form")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "fill_form")
+	defer cancel()
+
+	span.SetAttributes(attribute.Int("values", len(req.Values)))
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	filled := make(map[string]bool, len(req.Values))
+	output, err := s.fillTemplate(ctx, req.PDFData, req.Values, filled)
+	if err != nil {
+		return nil, err
+	}
+	if len(filled) == 0 {
+		return nil, NewError(ErrCodeInvalidInput, "no form field is named by the values", nil)
+	}
+	op.Output(int64(len(output)))
+	return output, nil
+}
+
+// assemblyManifest selects the pages of components for the merge, or
+// returns nil when every page of each is used
+func assemblyManifest(components []AssemblyComponent) ([]MergeItem, error) {
+	selected := false
+	seen := make(map[string]bool, len(components))
+	manifest := make([]MergeItem, len(components))
+	for i, component := range components {
+		selected = selected || component.Pages != ""
+		if seen[component.Name] {
+			manifest = nil
+		}
+		seen[component.Name] = true
+		if manifest != nil {
+			manifest[i] = MergeItem{File: component.Name, Pages: component.Pages}
+		}
+	}
+	switch {
+	case !selected:
+		return nil, nil
+	case manifest == nil:
+		// The manifest names inputs, so it cannot tell repeats apart
+		return nil, NewError(ErrCodeInvalidInput, "pages can only be selected when every component is used once", nil)
+	}
+	return manifest, nil
+}
+
+// fillTemplate fills the text fields of a form from variables, recording
+// the variables used in filled
+func (s *PDFService) fillTemplate(ctx context.Context, pdfData []byte, variables map[string]string, filled map[string]bool) ([]byte, error) {
+	var output []byte
+	err := s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		if err := fillFields(ctx2, variables, filled); err != nil {
+			return err
+		}
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		output = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return output, nil
+}
+
+// fillFields sets the values of the text fields of a form that variables
+// name, recording the variables used in filled. Stale appearances of the
+// filled fields are dropped and NeedAppearances is set, so viewers render
+// the new values.
+func fillFields(ctx2 *pdfcpu.Context, variables map[string]string, filled map[string]bool) error {
+	form, ok := dictEntry(ctx2, ctx2.RootDict, "AcroForm")
+	if !ok {
+		return NewError(ErrCodeInvalidInput, "template has no form fields", nil)
+	}
+	fields, _ := ctx2.Dereference(form["Fields"])
+	roots, _ := fields.(pdfcpu.Array)
+
+	changed := false
+	visited := 0
+	var walk func(obj pdfcpu.Object, parent, fieldType string) error
+	walk = func(obj pdfcpu.Object, parent, fieldType string) error {
+		visited++
+		if visited > maxStructureNodes {
+			return nil
+		}
+		field, err := ctx2.DereferenceDict(obj)
+		if err != nil || field == nil {
+			return nil
+		}
+		name := parent
+		if t, ok := field.Find("T"); ok {
+			if partial, ok := textValue(ctx2, t); ok {
+				name = partial
+				if parent != "" {
+					name = parent + "." + partial
+				}
+			}
+		}
+		if ft, ok := field.Find("FT"); ok {
+			if n, ok := ft.(pdfcpu.Name); ok {
+				fieldType = string(n)
+			}
+		}
+
+		if value, ok := variables[name]; ok && name != parent {
+			if fieldType != "Tx" {
+				return NewError(ErrCodeInvalidInput, fmt.Sprintf("form field %q is not a text field", name), nil)
+			}
+			setFieldValue(ctx2, field, value)
+			filled[name] = true
+			changed = true
+			return nil
+		}
+
+		kids, _ := ctx2.Dereference(field["Kids"])
+		children, _ := kids.(pdfcpu.Array)
+		for _, kid := range children {
+			if err := walk(kid, name, fieldType); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, root := range roots {
+		if err := walk(root, "", ""); err != nil {
+			return err
+		}
+	}
+
+	if changed {
+		form.Update("NeedAppearances", pdfcpu.Boolean(true))
+	}
+	return nil
+}
+
+// setFieldValue sets the value of a text field and its widgets, and makes
+// the field read-only
+func setFieldValue(ctx2 *pdfcpu.Context, field pdfcpu.Dict, value string) {
+	field.Update("V", textString(value))
+	field.Delete("AP")
+	kids, _ := ctx2.Dereference(field["Kids"])
+	widgets, _ := kids.(pdfcpu.Array)
+	for _, kid := range widgets {
+		if widget, err := ctx2.DereferenceDict(kid); err == nil && widget != nil {
+			widget.Delete("AP")
+		}
+	}
+
+	flags := 0
+	if ff, ok := field.Find("Ff"); ok {
+		if n, ok := ff.(pdfcpu.Integer); ok {
+			flags = int(n)
+		}
+	}
+	field.Update("Ff", pdfcpu.Integer(flags|fieldReadOnly))
+}
+
+// textValue returns the value of a PDF text string
+func textValue(ctx2 *pdfcpu.Context, obj pdfcpu.Object) (string, bool) {
+	obj, err := ctx2.Dereference(obj)
+	if err != nil {
+		return "", false
+	}
+	switch s := obj.(type) {
+	case pdfcpu.StringLiteral:
+		return string(s), true
+	case pdfcpu.HexLiteral:
+		data, err := hex.DecodeString(string(s))
+		if err != nil {
+			return "", false
+		}
+		return decodeText(data), true
+	}
+	return "", false
+}
+
+// decodeText decodes the bytes of a text string, which are UTF-16BE after
+// a byte order mark, or else treated as Latin-1
+func decodeText(data []byte) string {
+	if len(data) >= 2 && data[0] == 0xFE && data[1] == 0xFF {
+		units := make([]uint16, 0, (len(data)-2)/2)
+		for i := 2; i+1 < len(data); i += 2 {
+			units = append(units, uint16(data[i])<<8|uint16(data[i+1]))
+		}
+		return string(utf16.Decode(units))
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// textString encodes s as a PDF text string: a literal for ASCII, else
+// UTF-16BE with a byte order mark
+func textString(s string) pdfcpu.Object {
+	ascii := true
+	for i := 0; i < len(s); i++ {
+		if s[i] >= 0x80 {
+			ascii = false
+			break
+		}
+	}
+	if ascii {
+		return pdfcpu.StringLiteral(strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\r", `\r`, "\n", `\n`).Replace(s))
+	}
+
+	data := []byte{0xFE, 0xFF}
+	for _, unit := range utf16.Encode([]rune(s)) {
+		data = append(data, byte(unit>>8), byte(unit))
+	}
+	return pdfcpu.HexLiteral(hex.EncodeToString(data))
+}
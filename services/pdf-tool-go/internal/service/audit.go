@@ -0,0 +1,130 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxAuditEntries bounds the events and the signers of an audit trail
+const maxAuditEntries = 500
+
+// AuditEvent is a step in the history of a document, like its upload, a
+// processing operation or a signature
+type AuditEvent struct {
+	Action string    `json:"action"`
+	Actor  string    `json:"actor"`
+	At     time.Time `json:"at"`
+	// SHA256 is the hash of the document after the step, when known
+	SHA256 string `json:"sha256"`
+}
+
+// AuditSigner is a signer of a document
+type AuditSigner struct {
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	IP       string    `json:"ip"`
+	SignedAt time.Time `json:"signed_at"`
+}
+
+// AuditTrailRequest appends an audit trail to a document
+type AuditTrailRequest struct {
+	PDFData []byte
+	// DocumentName is the name the trail reports the document under
+	DocumentName string
+	Events       []AuditEvent
+	Signers      []AuditSigner
+}
+
+// AuditTrail is the data an audit trail template renders
+type AuditTrail struct {
+	Title    string
+	Document string
+	Tenant   string
+	// SHA256 is the hash of the document without its audit trail
+	SHA256      string
+	Pages       int
+	GeneratedAt time.Time
+	Events      []AuditEvent
+	Signers     []AuditSigner
+}
+
+// AppendAuditTrail appends audit trail pages, a certificate of completion
+// listing the document's hash, history and signers, rendered from the
+// configured template
+func (s *PDFService) AppendAuditTrail(ctx context.Context, req *AuditTrailRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.AppendAuditTrail")
+	defer span.End()
+
+	op := metrics.Start("audit_trail")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	span.SetAttributes(attribute.Int("events", len(req.Events)), attribute.Int("signers", len(req.Signers)))
+	s.log.Info("Appending audit trail", "events", len(req.Events), "signers", len(req.Signers))
+
+	if len(req.Events) > maxAuditEntries || len(req.Signers) > maxAuditEntries {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("at most %d events and %d signers are supported", maxAuditEntries, maxAuditEntries), nil)
+	}
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(req.PDFData)
+	trail := AuditTrail{
+		Title:       s.config.AuditTrail.Title,
+		Document:    req.DocumentName,
+		Tenant:      tenant.FromContext(ctx),
+		SHA256:      hex.EncodeToString(sum[:]),
+		Pages:       pageCount,
+		GeneratedAt: time.Now().UTC(),
+		Events:      req.Events,
+		Signers:     req.Signers,
+	}
+	text, err := renderAuditTrail(s.config.AuditTrail.Template, trail)
+	if err != nil {
+		return nil, err
+	}
+	pages := textDocument(text, textStyle{
+		PageSize: s.config.AuditTrail.PageSize,
+		FontSize: s.config.AuditTrail.FontSize,
+		Footer:   trail.Title,
+	})
+
+	output, err := s.MergePDFs(ctx, &MergeRequest{
+		PDFs:       [][]byte{req.PDFData, pages},
+		InputNames: []string{req.DocumentName, "audit trail"},
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+	op.Output(int64(len(output)))
+
+	s.log.Info("Audit trail appended", "sha256", trail.SHA256)
+
+	return output, nil
+}
+
+// renderAuditTrail renders the text of an audit trail from a template
+func renderAuditTrail(text string, trail AuditTrail) (string, error) {
+	tmpl, err := template.New("audit_trail").Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("invalid audit trail template: %w", err)
+	}
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, trail); err != nil {
+		return "", fmt.Errorf("failed to render audit trail: %w", err)
+	}
+	return strings.TrimSpace(buf.String()), nil
+}
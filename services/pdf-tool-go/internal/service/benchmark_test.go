@@ -0,0 +1,135 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// benchFixtures are representative inputs: a one-page letter, a report and
+// a book-length document
+var benchFixtures = []struct {
+	name  string
+	pages int
+}{
+	{"letter", 1},
+	{"report", 40},
+	{"book", 400},
+}
+
+// benchLines is the number of text lines on each fixture page
+const benchLines = 45
+
+// benchPDF builds a PDF of text pages, so benchmarks need no binary
+// fixtures and produce identical inputs on every run
+func benchPDF(pages int) []byte {
+	var buf bytes.Buffer
+	var offsets []int
+	object := func(body string) {
+		offsets = append(offsets, buf.Len())
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", len(offsets), body)
+	}
+
+	buf.WriteString("%PDF-1.7\n")
+	object("<< /Type /Catalog /Pages 2 0 R >>")
+	kids := make([]byte, 0, pages*10)
+	for i := 0; i < pages; i++ {
+		kids = fmt.Appendf(kids, "%d 0 R ", 4+2*i)
+	}
+	object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", kids, pages))
+	object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>")
+	for i := 0; i < pages; i++ {
+		object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] "+
+			"/Resources << /Font << /F1 3 0 R >> >> /Contents %d 0 R >>", 5+2*i))
+
+		var content bytes.Buffer
+		content.WriteString("BT /F1 11 Tf 14 TL 56 740 Td\n")
+		for line := 0; line < benchLines; line++ {
+			fmt.Fprintf(&content, "(Page %d line %d: the quick brown fox jumps over the lazy dog) '\n", i+1, line+1)
+		}
+		content.WriteString("ET")
+		object(fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", content.Len(), content.Bytes()))
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(offsets)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(offsets)+1, xref)
+	return buf.Bytes()
+}
+
+// newBenchService creates a service without limits that would reject the
+// larger fixtures, logging errors only
+func newBenchService(b *testing.B) *PDFService {
+	return NewPDFService(logger.New("error", "text"), &config.Config{
+		PDF: config.PDFConfig{
+			MaxFileSize: 1 << 30,
+			TempDir:     b.TempDir(),
+		},
+	})
+}
+
+// runFixtures runs bench once per fixture, reporting throughput in input
+// bytes
+func runFixtures(b *testing.B, bench func(b *testing.B, svc *PDFService, pdf []byte)) {
+	for _, fixture := range benchFixtures {
+		pdf := benchPDF(fixture.pages)
+		b.Run(fixture.name, func(b *testing.B) {
+			svc := newBenchService(b)
+			b.SetBytes(int64(len(pdf)))
+			b.ReportAllocs()
+			b.ResetTimer()
+			bench(b, svc, pdf)
+		})
+	}
+}
+
+func BenchmarkMergePDFs(b *testing.B) {
+	runFixtures(b, func(b *testing.B, svc *PDFService, pdf []byte) {
+		req := &MergeRequest{PDFs: [][]byte{pdf, pdf}}
+		for i := 0; i < b.N; i++ {
+			if _, err := svc.MergePDFs(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkSplitPDF(b *testing.B) {
+	runFixtures(b, func(b *testing.B, svc *PDFService, pdf []byte) {
+		req := &SplitRequest{PDFData: pdf}
+		for i := 0; i < b.N; i++ {
+			if _, err := svc.SplitPDF(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkCompressPDF(b *testing.B) {
+	runFixtures(b, func(b *testing.B, svc *PDFService, pdf []byte) {
+		req := &CompressRequest{PDFData: pdf, CompressionLevel: 2}
+		for i := 0; i < b.N; i++ {
+			if _, err := svc.CompressPDF(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
+
+func BenchmarkExtractText(b *testing.B) {
+	runFixtures(b, func(b *testing.B, svc *PDFService, pdf []byte) {
+		req := &ExtractTextRequest{PDFData: pdf}
+		for i := 0; i < b.N; i++ {
+			if _, err := svc.ExtractText(context.Background(), req); err != nil {
+				b.Fatal(err)
+			}
+		}
+	})
+}
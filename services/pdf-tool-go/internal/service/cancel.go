@@ -0,0 +1,125 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/concurrency"
+)
+
+// withTimeout derives the processing context for an operation, applying the
+// configured per-operation deadline (or the default deadline)
+func (s *PDFService) withTimeout(ctx context.Context, operation string) (context.Context, context.CancelFunc) {
+	seconds := s.config.Timeouts.Default
+	if override, ok := s.config.Timeouts.Operations[operation]; ok {
+		seconds = override
+	}
+	if seconds <= 0 {
+		return context.WithCancel(ctx)
+	}
+	return context.WithTimeout(ctx, time.Duration(seconds)*time.Second)
+}
+
+// runCancellable runs fn and returns as soon as either fn completes or ctx is
+// done. pdfcpu offers no cancellation hooks, so fn stops at checkpoints
+// instead: every pdfcpu phase and temp file access goes through inSpan,
+// which refuses to start once ctx is done. An abandoned fn finishes the
+// phase it is in and then returns; it must own all of its temp file cleanup
+// so nothing is leaked once the caller has gone away. Background work is
+// tracked so shutdown can wait for it instead of killing it mid-write.
+func (s *PDFService) runCancellable(ctx context.Context, fn func() error) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+
+	finished := s.background.Start()
+	done := make(chan error, 1)
+	go func() {
+		defer finished()
+		done <- fn()
+	}()
+
+	return waitFor(ctx, done)
+}
+
+// runHeavy is runCancellable for CPU/memory-heavy work. The concurrency slot
+// is held until fn actually returns, so work abandoned by a cancelled caller
+// still counts against the limit until it reaches its next checkpoint.
+func (s *PDFService) runHeavy(ctx context.Context, fn func() error) error {
+	release, err := s.acquireSlot(ctx)
+	if err != nil {
+		return err
+	}
+
+	finished := s.background.Start()
+	done := make(chan error, 1)
+	go func() {
+		defer finished()
+		defer release()
+		if err := ctx.Err(); err != nil {
+			done <- contextError(err)
+			return
+		}
+		done <- fn()
+	}()
+
+	return waitFor(ctx, done)
+}
+
+// waitFor waits for a background result or for ctx to be done
+func waitFor(ctx context.Context, done <-chan error) error {
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return contextError(ctx.Err())
+	}
+}
+
+// contextError converts a context error into a coded error
+func contextError(err error) error {
+	if errors.Is(err, context.DeadlineExceeded) {
+		return NewError(ErrCodeTimeout, "processing deadline exceeded", err)
+	}
+	return NewError(ErrCodeCanceled, "request canceled", err)
+}
+
+// acquireSlot reserves a slot for a CPU/memory-heavy operation.
+// The returned release function must be called when the work is done.
+func (s *PDFService) acquireSlot(ctx context.Context) (func(), error) {
+	if s.limiter == nil {
+		return func() {}, nil
+	}
+
+	start := time.Now()
+	release, err := s.limiter.Acquire(ctx)
+	// Rejections count as waits too: they are the strongest overload signal
+	if ctx.Err() == nil {
+		s.shedder.Observe(time.Since(start))
+	}
+	if errors.Is(err, concurrency.ErrSaturated) {
+		busy := NewError(ErrCodeBusy, "service is at capacity, retry later", err)
+		busy.RetryAfter = s.config.Concurrency.RetryAfter
+		// Once slots have been released waits can be estimated from them
+		if wait := s.limiter.EstimatedWait(s.limiter.Queued() + 1); wait > 0 {
+			busy.RetryAfter = int(math.Ceil(wait.Seconds()))
+		}
+		return nil, busy
+	}
+	if err != nil {
+		return nil, contextError(err)
+	}
+	return release, nil
+}
+
+// Drain waits for background processing, including work abandoned by
+// cancelled callers, to finish or for ctx to be done
+func (s *PDFService) Drain(ctx context.Context) error {
+	if err := s.background.Wait(ctx); err != nil {
+		s.log.Warn("Background processing still running at drain deadline", "in_flight", s.background.Count())
+		return err
+	}
+	return nil
+}
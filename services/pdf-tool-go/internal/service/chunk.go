@@ -0,0 +1,146 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ChunkUnit is the unit chunk sizes are measured in
+type ChunkUnit string
+
+const (
+	// ChunkChars measures chunks in characters; it is the default
+	ChunkChars ChunkUnit = "chars"
+	// ChunkTokens measures chunks in whitespace-separated words, which
+	// approximate model tokens closely enough for sizing embeddings
+	ChunkTokens ChunkUnit = "tokens"
+)
+
+// ChunkOptions selects chunked text output. Zero values use the configured
+// defaults.
+type ChunkOptions struct {
+	Size    int
+	Overlap int
+	Unit    ChunkUnit
+}
+
+// TextChunk is a piece of the extracted text sized for embedding
+type TextChunk struct {
+	Index int
+	Text  string
+	// Page and EndPage are the pages the chunk starts and ends on
+	Page    int
+	EndPage int
+	// Start and End are byte offsets of the chunk in the response text
+	Start int
+	End   int
+}
+
+// resolve fills defaults from config and validates the options
+func (o ChunkOptions) resolve(size, overlap int) (ChunkOptions, error) {
+	if o.Unit == "" {
+		o.Unit = ChunkChars
+	}
+	if o.Size == 0 {
+		o.Size = size
+	}
+	if o.Overlap == 0 {
+		o.Overlap = overlap
+	}
+	if o.Unit != ChunkChars && o.Unit != ChunkTokens {
+		return o, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported chunk unit %q; use chars or tokens", o.Unit), nil)
+	}
+	if o.Size <= 0 || o.Overlap < 0 || o.Overlap >= o.Size {
+		return o, NewError(ErrCodeInvalidInput, "chunk size must be positive and larger than the overlap", nil)
+	}
+	return o, nil
+}
+
+// chunkText splits the text of pages, joined as in ExtractTextResponse.Text,
+// into chunks of at most opts.Size units, each repeating up to opts.Overlap
+// units from the end of the previous one. Chunks break between words; a
+// single word longer than a chunk becomes a chunk of its own.
+func chunkText(text string, pages []PageText, opts ChunkOptions) []TextChunk {
+	words := wordBounds(text)
+	starts := pageStarts(pages)
+	chunks := []TextChunk{}
+	for first := 0; first < len(words); {
+		last := first
+		for last+1 < len(words) && chunkLength(text, words, first, last+1, opts.Unit) <= opts.Size {
+			last++
+		}
+
+		start, end := words[first][0], words[last][1]
+		chunks = append(chunks, TextChunk{
+			Index:   len(chunks),
+			Text:    text[start:end],
+			Page:    pageAt(pages, starts, start),
+			EndPage: pageAt(pages, starts, end-1),
+			Start:   start,
+			End:     end,
+		})
+		if last == len(words)-1 {
+			break
+		}
+
+		// The next chunk starts at the earliest word keeping the overlap
+		// within bounds, but always moves forward
+		next := last + 1
+		for next-1 > first && chunkLength(text, words, next-1, last, opts.Unit) <= opts.Overlap {
+			next--
+		}
+		first = next
+	}
+	return chunks
+}
+
+// chunkLength measures the text from word first to word last inclusive
+func chunkLength(text string, words [][2]int, first, last int, unit ChunkUnit) int {
+	if unit == ChunkTokens {
+		return last - first + 1
+	}
+	return utf8.RuneCountInString(text[words[first][0]:words[last][1]])
+}
+
+// wordBounds returns the byte ranges of the whitespace-separated words of
+// text
+func wordBounds(text string) [][2]int {
+	var words [][2]int
+	start := -1
+	for i, r := range text {
+		switch {
+		case unicode.IsSpace(r) && start >= 0:
+			words = append(words, [2]int{start, i})
+			start = -1
+		case !unicode.IsSpace(r) && start < 0:
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, [2]int{start, len(text)})
+	}
+	return words
+}
+
+// pageStarts returns the byte offset of each page in the response text,
+// where page texts are joined by pageSeparator
+func pageStarts(pages []PageText) []int {
+	starts := make([]int, len(pages))
+	position := 0
+	for i, page := range pages {
+		starts[i] = position
+		position += len(page.Text) + len(pageSeparator)
+	}
+	return starts
+}
+
+// pageAt returns the page containing a byte offset of the response text
+func pageAt(pages []PageText, starts []int, offset int) int {
+	i := sort.Search(len(starts), func(i int) bool { return starts[i] > offset }) - 1
+	if i < 0 {
+		return 0
+	}
+	return pages[i].PageNumber
+}
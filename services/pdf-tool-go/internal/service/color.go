@@ -0,0 +1,252 @@
+package service
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// iccHeaderSize is the size of an ICC profile header
+const iccHeaderSize = 128
+
+// ColorSpace is the target colour space of a conversion
+type ColorSpace string
+
+const (
+	// ColorCMYK converts to DeviceCMYK for print
+	ColorCMYK ColorSpace = "cmyk"
+	// ColorSRGB converts to sRGB for screen
+	ColorSRGB ColorSpace = "srgb"
+)
+
+// colorSpaces describes how each target is produced and which ICC profiles
+// can describe it
+var colorSpaces = map[ColorSpace]struct {
+	strategy, model string
+	// iccSpace is the ICC data colour space signature
+	iccSpace   string
+	components int
+}{
+	ColorCMYK: {"CMYK", "DeviceCMYK", "CMYK", 4},
+	ColorSRGB: {"RGB", "DeviceRGB", "RGB ", 3},
+}
+
+// ConvertColorRequest represents a colour space conversion request
+type ConvertColorRequest struct {
+	PDFData    []byte
+	ColorSpace ColorSpace
+	// Profile names an ICC profile from config; empty uses the default
+	Profile string
+	// ICCProfile is an uploaded ICC profile, used instead of Profile
+	ICCProfile []byte
+	// OutputCondition identifies the printing condition the profile
+	// characterises, e.g. "FOGRA39"; it defaults to the profile name
+	OutputCondition string
+}
+
+// iccProfile is the header information of an ICC profile
+type iccProfile struct {
+	// class is the device class signature: "prtr" for output, "mntr" for
+	// display devices
+	class string
+	// space is the data colour space signature, e.g. "CMYK" or "RGB "
+	space string
+}
+
+// parseICCProfile reads and checks an ICC profile header
+func parseICCProfile(data []byte) (*iccProfile, error) {
+	if len(data) < iccHeaderSize || string(data[36:40]) != "acsp" {
+		return nil, NewError(ErrCodeInvalidInput, "not an ICC profile", nil)
+	}
+	if size := binary.BigEndian.Uint32(data[0:4]); int64(size) > int64(len(data)) {
+		return nil, NewError(ErrCodeInvalidInput, "ICC profile is truncated", nil)
+	}
+	return &iccProfile{class: string(data[12:16]), space: string(data[16:20])}, nil
+}
+
+// validateOutputIntent checks a profile can serve as the output intent of
+// a document in space: PDF/X requires an output (or, for RGB, display)
+// device profile whose colour space matches the document's
+func validateOutputIntent(profile *iccProfile, space ColorSpace) error {
+	target := colorSpaces[space]
+	if profile.space != target.iccSpace {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("ICC profile colour space %q does not match %s", strings.TrimSpace(profile.space), space), nil)
+	}
+	if profile.class != "prtr" && !(space == ColorSRGB && profile.class == "mntr") {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("ICC profile device class %q cannot be an output intent", profile.class), nil)
+	}
+	return nil
+}
+
+// iccProfileData returns the uploaded ICC profile, or else the configured
+// profile called name or the default one, along with the profile's name
+func (s *PDFService) iccProfileData(name string, uploaded []byte) ([]byte, string, error) {
+	if uploaded != nil {
+		return uploaded, "Custom", nil
+	}
+	if name == "" {
+		name = s.config.Prepress.DefaultProfile
+	}
+	if name == "" {
+		return nil, "", NewError(ErrCodeInvalidInput, "an ICC profile is required: upload one or name a configured profile", nil)
+	}
+	path, ok := s.config.Prepress.Profiles[name]
+	if !ok {
+		return nil, "", NewError(ErrCodeInvalidInput, fmt.Sprintf("unknown ICC profile %q", name), nil)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("failed to read ICC profile %s: %w", name, err)
+	}
+	return data, name, nil
+}
+
+// ConvertColor converts page content to CMYK or sRGB through an ICC profile
+// and embeds the profile as the document's output intent
+func (s *PDFService) ConvertColor(ctx context.Context, req *ConvertColorRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ConvertColor")
+	defer span.End()
+
+	op := metrics.Start("convert_color")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "convert_color")
+	defer cancel()
+
+	if _, ok := colorSpaces[req.ColorSpace]; !ok {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported colour space %q; use cmyk or srgb", req.ColorSpace), nil)
+	}
+	iccData, profileName, err := s.iccProfileData(req.Profile, req.ICCProfile)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := parseICCProfile(iccData)
+	if err != nil {
+		return nil, err
+	}
+	if err := validateOutputIntent(profile, req.ColorSpace); err != nil {
+		return nil, err
+	}
+	condition := req.OutputCondition
+	if condition == "" {
+		condition = profileName
+	}
+	span.SetAttributes(attribute.String("color_space", string(req.ColorSpace)), attribute.String("profile", profileName))
+
+	s.log.Info("Converting PDF colour space", "color_space", req.ColorSpace, "profile", profileName)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	prologue := outputIntentPS("profile.icc", colorSpaces[req.ColorSpace].components, condition)
+	var converted []byte
+	err = s.runHeavy(ctx, func() error {
+		converted, err = s.convertColor(ctx, req.PDFData, req.ColorSpace, iccData, prologue, nil)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(converted)))
+	s.log.Info("Colour conversion completed", "size", len(converted))
+
+	return converted, nil
+}
+
+// convertColor rewrites a document through Ghostscript's pdfwrite device
+// in space, using the ICC profile, written as profile.icc, for conversion.
+// The PostScript prologue, normally embedding the output intent, is run
+// before the document, and extra arguments are passed before both.
+func (s *PDFService) convertColor(ctx context.Context, pdfData []byte, space ColorSpace, iccData []byte, prologue string, extra []string) ([]byte, error) {
+	target := colorSpaces[space]
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		if _, err := ws.WriteFile("input.pdf", pdfData); err != nil {
+			return err
+		}
+		if _, err := ws.WriteFile("profile.icc", iccData); err != nil {
+			return err
+		}
+		_, err := ws.WriteFile("intent.ps", []byte(prologue))
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	args := []string{
+		"-sDEVICE=pdfwrite",
+		"-sColorConversionStrategy=" + target.strategy,
+		"-sProcessColorModel=" + target.model,
+		"-dOverrideICC=true",
+		"-sOutputICCProfile=profile.icc",
+		"--permit-file-read=profile.icc",
+		"-dSAFER",
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-dBATCH",
+		"-sOutputFile=output.pdf",
+	}
+	args = append(args, extra...)
+	args = append(args, "intent.ps", "input.pdf")
+	if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "gs", Args: args}); err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
+
+// outputIntentPS returns PostScript that embeds an ICC profile as the
+// document's GTS_PDFX output intent through pdfmark
+func outputIntentPS(profileFile string, components int, condition string) string {
+	condition = psString(condition)
+	return fmt.Sprintf(`[/_objdef {icc_profile} /type /stream /OBJ pdfmark
+[{icc_profile} << /N %d >> /PUT pdfmark
+[{icc_profile} (%s) (r) file /PUT pdfmark
+[/_objdef {output_intent} /type /dict /OBJ pdfmark
+[{output_intent} <<
+  /Type /OutputIntent
+  /S /GTS_PDFX
+  /OutputCondition (%s)
+  /OutputConditionIdentifier (%s)
+  /Info (%s)
+  /RegistryName (http://www.color.org)
+  /DestOutputProfile {icc_profile}
+>> /PUT pdfmark
+[{Catalog} << /OutputIntents [ {output_intent} ] >> /PUT pdfmark
+`, components, profileFile, condition, condition, condition)
+}
+
+// psString escapes s for a PostScript string literal
+func psString(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "(", `\(`, ")", `\)`, "\n", " ", "\r", " ").Replace(s)
+}
@@ -0,0 +1,88 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Cover width bounds and default
+const (
+	defaultCoverWidth = 240
+	maxCoverWidth     = 800
+	// coverPageInches is the page width covers are rendered for; narrower
+	// pages such as A4 come out slightly over width and are scaled down
+	coverPageInches = 8
+)
+
+// CoverRequest requests the cover image of a document
+type CoverRequest struct {
+	PDFData []byte
+	// Width is the cover width in pixels; 0 uses the default
+	Width int
+	// Format is png or jpeg
+	Format string
+}
+
+// Cover renders the first page of a document as a small image, for listing
+// views. Unlike GetPage it skips counting pages, which parses the whole
+// document; Ghostscript only interprets the first page.
+func (s *PDFService) Cover(ctx context.Context, req *CoverRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.Cover")
+	defer span.End()
+
+	op := metrics.Start("cover")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "cover")
+	defer cancel()
+
+	width := req.Width
+	if width == 0 {
+		width = defaultCoverWidth
+	}
+	if width < minThumbWidth || width > maxCoverWidth {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("width must be between %d and %d", minThumbWidth, maxCoverWidth), nil)
+	}
+	if req.Format != "png" && req.Format != "jpeg" {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported cover format %q; use png or jpeg", req.Format), nil)
+	}
+	span.SetAttributes(attribute.Int("width", width), attribute.String("format", req.Format))
+
+	encoder, err := s.imageEncoder(&ConvertToImageRequest{Format: req.Format})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(1)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var cover []byte
+	err = s.runHeavy(ctx, func() error {
+		dpi := max(minRenderDPI, (width+coverPageInches-1)/coverPageInches)
+		rendered, err := s.renderWithGhostscript(ctx, req.PDFData, 1, "png", dpi)
+		if err != nil {
+			return err
+		}
+		img, err := png.Decode(bytes.NewReader(rendered))
+		if err != nil {
+			return fmt.Errorf("failed to decode cover: %w", err)
+		}
+		cover, err = encodeImage(imaging.Fit(img, width, 2*width, imaging.Lanczos), req.Format, encoder.Quality)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Output(int64(len(cover)))
+
+	return cover, nil
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/pipeline"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/processor"
+)
+
+// RegisterProcessor makes a custom processor available by name. It must be
+// called before the service handles requests.
+func (s *PDFService) RegisterProcessor(name string, p processor.Processor) {
+	if s.processors == nil {
+		s.processors = make(map[string]processor.Processor)
+	}
+	s.processors[name] = p
+}
+
+// processor returns the custom processor registered as name
+func (s *PDFService) processor(name string) (processor.Processor, error) {
+	if p, ok := s.processors[name]; ok {
+		return p, nil
+	}
+	names := make([]string, 0, len(s.processors))
+	for known := range s.processors {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, NewError(ErrCodeNotFound, fmt.Sprintf("unknown custom operation %q; none are installed", name), nil)
+	}
+	return nil, NewError(ErrCodeNotFound, fmt.Sprintf("unknown custom operation %q; available: %s", name, strings.Join(names, ", ")), nil)
+}
+
+// RunCustom runs the custom processor registered as name over pdfData. Its
+// deadline is configured as the operation custom_<name>.
+func (s *PDFService) RunCustom(ctx context.Context, name string, params map[string]string, pdfData []byte) (*processor.Response, error) {
+	p, err := s.processor(name)
+	if err != nil {
+		return nil, err
+	}
+
+	s.log.Info("Running custom processor", "processor", name)
+
+	var resp *processor.Response
+	_, err = s.pipeline.Run(ctx, "custom_"+name, pdfData,
+		s.checkStep(),
+		pipeline.Step{Name: "process", Mode: pipeline.Heavy, Fn: func(st *pipeline.State) error {
+			out, err := p.Process(st.Ctx, &processor.Request{PDFData: st.Input, Params: params})
+			switch {
+			case errors.Is(err, processor.ErrInvalidInput):
+				return NewError(ErrCodeInvalidInput, err.Error(), err)
+			case err != nil:
+				return fmt.Errorf("custom processor %s failed: %w", name, err)
+			case out == nil || len(out.Data) == 0:
+				return fmt.Errorf("custom processor %s returned no output", name)
+			}
+			resp = out
+			st.Output = out.Data
+			return nil
+		}},
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	if resp.ContentType == "" {
+		resp.ContentType = "application/pdf"
+	}
+	return resp, nil
+}
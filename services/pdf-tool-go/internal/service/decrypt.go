@@ -0,0 +1,297 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"math"
+	"regexp"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+)
+
+// DecryptRequest removes the encryption of a password-protected PDF
+type DecryptRequest struct {
+	PDFData  []byte
+	Password string
+	// Client identifies the caller for attempt throttling, e.g. tenant and
+	// client IP
+	Client string
+}
+
+// DecryptPDF decrypts a PDF with the user or owner password. Wrong passwords
+// are throttled per document and per client with exponential backoff and
+// lockout, so the service cannot be used to guess passwords.
+func (s *PDFService) DecryptPDF(ctx context.Context, req *DecryptRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.DecryptPDF")
+	defer span.End()
+
+	op := metrics.Start("decrypt")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "decrypt")
+	defer cancel()
+
+	if req.Password == "" {
+		return nil, NewError(ErrCodeInvalidInput, "password is required", nil)
+	}
+
+	documents, err := documentKeys(req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	attempt, wait := s.attempts.Reserve(append(documents, "client:"+req.Client)...)
+	if wait > 0 {
+		s.log.Warn("Decrypt attempt blocked", "client", req.Client, "retry_after", wait)
+		blocked := NewError(ErrCodeTooManyAttempts, "too many failed password attempts, retry later", nil)
+		blocked.RetryAfter = int(math.Ceil(wait.Seconds()))
+		return nil, blocked
+	}
+
+	conf := pdfcpu.NewDefaultConfiguration()
+	conf.UserPW = req.Password
+	conf.OwnerPW = req.Password
+
+	var decrypted []byte
+	err = s.runCancellable(ctx, func() error {
+		var buf bytes.Buffer
+		err := inSpan(ctx, "pdfcpu.decrypt", func() error {
+			return api.Decrypt(bytes.NewReader(req.PDFData), &buf, conf)
+		})
+		if err != nil {
+			return classifyDecryptError(err)
+		}
+		decrypted = buf.Bytes()
+		return nil
+	})
+	wrongPassword := CodeOf(err) == ErrCodeEncrypted
+	attempt.Done(wrongPassword)
+	if err != nil {
+		if wrongPassword {
+			s.log.Warn("Decrypt failed with wrong password", "client", req.Client)
+		}
+		return nil, err
+	}
+
+	// The document's password is known now; the client's failures stand.
+	// Documents with several verifiers keep their failures, since the
+	// others may be copied from documents still being guessed.
+	if len(documents) == 1 && documents[0] != unrecognizedDocument {
+		s.attempts.Reset(documents...)
+	}
+	op.Output(int64(len(decrypted)))
+
+	return decrypted, nil
+}
+
+// maxPasswordVerifiers bounds the distinct /U entries a document to
+// decrypt may carry; real documents have one
+const maxPasswordVerifiers = 16
+
+// unrecognizedDocument is the attempt key shared by documents whose
+// encryption dictionary could not be found
+const unrecognizedDocument = "document:unrecognized"
+
+var (
+	// userEntryPattern matches /U names, also spelled /#55
+	userEntryPattern = regexp.MustCompile(`/(?:U|#55)`)
+	objectRefPattern = regexp.MustCompile(`^(\d+)\s+(\d+)\s+R\b`)
+	objectDefPattern = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+)
+
+// documentKeys returns the attempt keys of an encrypted document: one per
+// /U entry, the user password verifier of the encryption dictionary.
+// Passwords are checked against it, and the owner password check depends
+// on it too, so documents cannot dodge their failures by varying bytes the
+// check ignores, such as trailing data, /ID with AES-256 or other objects.
+// Every /U entry found is keyed, so decoys cannot hide the real one.
+func documentKeys(pdfData []byte) ([]string, error) {
+	var keys []string
+	seen := map[string]bool{}
+	add := func(verifier []byte) {
+		key := "document:" + Checksum(verifier)
+		if !seen[key] {
+			seen[key] = true
+			keys = append(keys, key)
+		}
+	}
+
+	// Object offsets by "number generation", indexed on the first
+	// indirect /U entry
+	var objects map[string][]int
+	for _, loc := range userEntryPattern.FindAllIndex(pdfData, -1) {
+		// /U must be the whole name, not a prefix of /URI or /UF
+		if end := loc[1]; end < len(pdfData) && !isPDFDelimiter(pdfData[end]) {
+			continue
+		}
+		i := skipPDFSpace(pdfData, loc[1])
+		if verifier, ok := parsePDFString(pdfData, i); ok {
+			add(verifier)
+		} else if ref := objectRefPattern.FindSubmatch(pdfData[i:]); ref != nil {
+			if objects == nil {
+				objects = indexObjects(pdfData)
+			}
+			for _, offset := range objects[string(ref[1])+" "+string(ref[2])] {
+				if verifier, ok := parsePDFString(pdfData, skipPDFSpace(pdfData, offset)); ok {
+					add(verifier)
+				}
+			}
+		}
+		if len(keys) > maxPasswordVerifiers {
+			return nil, NewError(ErrCodeInvalidInput, "PDF has too many encryption dictionaries", nil)
+		}
+	}
+
+	if len(keys) == 0 {
+		return []string{unrecognizedDocument}, nil
+	}
+	return keys, nil
+}
+
+// indexObjects returns the offsets following each "n g obj" header
+func indexObjects(pdfData []byte) map[string][]int {
+	objects := map[string][]int{}
+	for _, m := range objectDefPattern.FindAllSubmatchIndex(pdfData, -1) {
+		id := string(pdfData[m[2]:m[3]]) + " " + string(pdfData[m[4]:m[5]])
+		objects[id] = append(objects[id], m[1])
+	}
+	return objects
+}
+
+// isPDFDelimiter reports whether c ends a PDF name
+func isPDFDelimiter(c byte) bool {
+	return bytes.IndexByte([]byte(" \t\r\n\f\x00%()<>[]{}/"), c) >= 0
+}
+
+// skipPDFSpace returns the index of the first byte at or after i that is
+// neither whitespace nor part of a comment
+func skipPDFSpace(data []byte, i int) int {
+	for i < len(data) {
+		switch data[i] {
+		case ' ', '\t', '\r', '\n', '\f', 0:
+			i++
+		case '%':
+			for i < len(data) && data[i] != '\r' && data[i] != '\n' {
+				i++
+			}
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// parsePDFString decodes the literal or hexadecimal string starting at i
+func parsePDFString(data []byte, i int) ([]byte, bool) {
+	if i >= len(data) {
+		return nil, false
+	}
+	switch {
+	case data[i] == '(':
+		return parseLiteralString(data, i+1)
+	case data[i] == '<' && (i+1 >= len(data) || data[i+1] != '<'):
+		return parseHexString(data, i+1)
+	}
+	return nil, false
+}
+
+func parseLiteralString(data []byte, i int) ([]byte, bool) {
+	var out []byte
+	depth := 0
+	for i < len(data) {
+		c := data[i]
+		i++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				return out, true
+			}
+			depth--
+		case '\r':
+			// End-of-line markers read as a line feed
+			if i < len(data) && data[i] == '\n' {
+				i++
+			}
+			c = '\n'
+		case '\\':
+			if i >= len(data) {
+				return nil, false
+			}
+			c = data[i]
+			i++
+			switch c {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r', '\n':
+				// Escaped line breaks continue the string
+				if c == '\r' && i < len(data) && data[i] == '\n' {
+					i++
+				}
+				continue
+			default:
+				if c >= '0' && c <= '7' {
+					n := int(c - '0')
+					for digits := 1; digits < 3 && i < len(data) && data[i] >= '0' && data[i] <= '7'; digits++ {
+						n = n*8 + int(data[i]-'0')
+						i++
+					}
+					c = byte(n)
+				}
+			}
+		}
+		out = append(out, c)
+	}
+	return nil, false
+}
+
+func parseHexString(data []byte, i int) ([]byte, bool) {
+	var digits []byte
+	for ; i < len(data); i++ {
+		c := data[i]
+		switch {
+		case c == '>':
+			if len(digits)%2 == 1 {
+				digits = append(digits, '0')
+			}
+			out := make([]byte, len(digits)/2)
+			if _, err := hex.Decode(out, digits); err != nil {
+				return nil, false
+			}
+			return out, true
+		case bytes.IndexByte([]byte(" \t\r\n\f\x00"), c) >= 0:
+		case bytes.IndexByte([]byte("0123456789abcdefABCDEF"), c) >= 0:
+			digits = append(digits, c)
+		default:
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// classifyDecryptError maps a decrypt failure onto a coded error. Unlike
+// other operations, an unencrypted input is the caller's mistake rather than
+// a password failure.
+func classifyDecryptError(err error) error {
+	if errors.Is(err, pdfcpu.ErrWrongPassword) {
+		return NewError(ErrCodeEncrypted, "incorrect password", err)
+	}
+	if strings.Contains(strings.ToLower(err.Error()), "not encrypted") {
+		return NewError(ErrCodeInvalidInput, "PDF is not encrypted", err)
+	}
+	return classifyPDFError(err, "failed to decrypt PDF")
+}
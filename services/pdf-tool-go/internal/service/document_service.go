@@ -0,0 +1,63 @@
+package service
+
+import (
+	"context"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/retention"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// documentPrefix is the storage key prefix for uploaded documents
+const documentPrefix = "documents/"
+
+// DocumentService stores uploaded documents so that successive operations
+// can refer to them by ID instead of re-uploading them. Documents are
+// content-addressed like results.
+type DocumentService struct {
+	objects *ResultService
+}
+
+// NewDocumentService creates a new document service
+func NewDocumentService(store storage.Store, policies *retention.Policies, log logger.Logger) *DocumentService {
+	return &DocumentService{
+		objects: &ResultService{
+			store:    store,
+			policies: policies,
+			log:      log,
+			prefix:   documentPrefix,
+			kind:     "document",
+		},
+	}
+}
+
+// Save stores an uploaded PDF and returns its descriptor
+func (s *DocumentService) Save(ctx context.Context, data []byte) (*Result, error) {
+	return s.objects.Save(ctx, data, "pdf")
+}
+
+// Open opens a stored document for streaming. The caller must close the object.
+func (s *DocumentService) Open(ctx context.Context, id string) (storage.Object, *Result, error) {
+	return s.objects.Open(ctx, id)
+}
+
+// Delete removes a stored document
+func (s *DocumentService) Delete(ctx context.Context, id string) error {
+	return s.objects.Delete(ctx, id)
+}
+
+// List calls fn for every document of the tenant of ctx
+func (s *DocumentService) List(ctx context.Context, fn func(*Result) error) error {
+	return s.objects.List(ctx, fn)
+}
+
+// Downloaded applies delete-after-download retention to a document
+func (s *DocumentService) Downloaded(ctx context.Context, id string) {
+	s.objects.Downloaded(ctx, id)
+}
+
+// Purge removes documents whose retention TTL has expired at now
+func (s *DocumentService) Purge(ctx context.Context, now time.Time) (int, error) {
+	return s.objects.Purge(ctx, now)
+}
@@ -0,0 +1,156 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Entity is a structured value found in extracted text
+type Entity struct {
+	// Type is the kind of entity: the name of the pattern that found it,
+	// e.g. "email", or the label a NER backend gave it
+	Type  string
+	Value string
+	Page  int
+	// Start and End are byte offsets of the value in the page text
+	Start int
+	End   int
+}
+
+// EntityExtractor finds entities in text. Implementations are the regex
+// patterns from config or NER backends; see package ner.
+type EntityExtractor interface {
+	// Extract returns the entities in text with Value, Start and End set.
+	// Page is filled in by the caller, as is Type when left empty.
+	Extract(ctx context.Context, text string) ([]Entity, error)
+}
+
+// builtinEntityPatterns are always available; patterns in config with the
+// same name replace them
+var builtinEntityPatterns = map[string]string{
+	"email":          `[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}`,
+	"ssn":            `\b\d{3}-\d{2}-\d{4}\b`,
+	"invoice_number": `\b(?i:inv(?:oice)?)\.?[ \t]*(?i:no\.?|number|#)?[ \t:#-]*([A-Z0-9-]*\d[A-Z0-9-]*)\b`,
+	"date":           `\b(?:\d{4}-\d{2}-\d{2}|\d{1,2}[/.]\d{1,2}[/.]\d{2,4}|\d{1,2} (?:Jan|Feb|Mar|Apr|May|Jun|Jul|Aug|Sep|Oct|Nov|Dec)[a-z]* \d{4})\b`,
+	"phone":          `(?:\+\d{1,3}[ .-]?)?\(?\b\d{3}\)?[ .-]?\d{3}[ .-]\d{4}\b`,
+	"credit_card":    `\b\d{4}[ -]?\d{4}[ -]?\d{4}[ -]?\d{1,7}\b`,
+}
+
+// patternExtractor finds matches of a regular expression. When the pattern
+// has a capture group, the value is the first group rather than the whole
+// match, so context like "Invoice No." is not part of the entity.
+type patternExtractor struct {
+	name    string
+	pattern *regexp.Regexp
+}
+
+// Extract implements EntityExtractor
+func (e *patternExtractor) Extract(_ context.Context, text string) ([]Entity, error) {
+	var entities []Entity
+	for _, match := range e.pattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := match[0], match[1]
+		if len(match) > 2 && match[2] >= 0 {
+			start, end = match[2], match[3]
+		}
+		entities = append(entities, Entity{Type: e.name, Value: text[start:end], Start: start, End: end})
+	}
+	return entities, nil
+}
+
+// registerEntityPatterns registers the builtin and configured patterns.
+// Configured patterns were compiled by config validation, so they are
+// known to be valid.
+func (s *PDFService) registerEntityPatterns() {
+	for name, pattern := range builtinEntityPatterns {
+		s.RegisterEntityExtractor(name, &patternExtractor{name: name, pattern: regexp.MustCompile(pattern)})
+	}
+	for _, pattern := range s.config.Entities.Patterns {
+		s.RegisterEntityExtractor(pattern.Name, &patternExtractor{name: pattern.Name, pattern: regexp.MustCompile(pattern.Pattern)})
+	}
+}
+
+// RegisterEntityExtractor makes an entity extractor selectable by name per
+// request. It must be called before the service handles requests.
+func (s *PDFService) RegisterEntityExtractor(name string, extractor EntityExtractor) {
+	if s.extractors == nil {
+		s.extractors = make(map[string]EntityExtractor)
+	}
+	s.extractors[name] = extractor
+}
+
+// entityExtractors resolves extractor names, rejecting unknown ones
+func (s *PDFService) entityExtractors(names []string) (map[string]EntityExtractor, error) {
+	extractors := make(map[string]EntityExtractor, len(names))
+	for _, name := range names {
+		extractor, ok := s.extractors[name]
+		if !ok {
+			known := make([]string, 0, len(s.extractors))
+			for name := range s.extractors {
+				known = append(known, name)
+			}
+			sort.Strings(known)
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unknown entity extractor %q; available: %s", name, strings.Join(known, ", ")), nil)
+		}
+		extractors[name] = extractor
+	}
+	return extractors, nil
+}
+
+// extractEntities runs extractors over every page. Entities are ordered by
+// page and position; extractors finding the same span each report it.
+func (s *PDFService) extractEntities(ctx context.Context, pages []PageText, extractors map[string]EntityExtractor) ([]Entity, error) {
+	ctx, span := tracer.Start(ctx, "PDFService.extractEntities")
+	defer span.End()
+
+	entities := []Entity{}
+	for _, page := range pages {
+		if strings.TrimSpace(page.Text) == "" {
+			continue
+		}
+		for name, extractor := range extractors {
+			if err := ctx.Err(); err != nil {
+				return nil, contextError(err)
+			}
+			found, err := extractor.Extract(ctx, page.Text)
+			if err != nil {
+				return nil, extractorError(ctx, err, name)
+			}
+			for _, entity := range found {
+				entity.Page = page.PageNumber
+				if entity.Type == "" {
+					entity.Type = name
+				}
+				entities = append(entities, entity)
+			}
+		}
+	}
+
+	sort.SliceStable(entities, func(i, j int) bool {
+		a, b := entities[i], entities[j]
+		if a.Page != b.Page {
+			return a.Page < b.Page
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.Type < b.Type
+	})
+	return entities, nil
+}
+
+// extractorError maps an entity extractor failure onto a coded error.
+// Coded errors from the extractor are kept.
+func extractorError(ctx context.Context, err error, name string) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+	var pdfErr *PDFError
+	if errors.As(err, &pdfErr) {
+		return err
+	}
+	return fmt.Errorf("entity extractor %s failed: %w", name, err)
+}
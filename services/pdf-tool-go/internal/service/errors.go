@@ -0,0 +1,186 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/breaker"
+)
+
+// ErrorCode is a stable, machine-readable identifier for a processing failure.
+// Clients may switch on these values, so existing codes must never change.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidInput       ErrorCode = "INVALID_INPUT"
+	ErrCodeFileTooLarge       ErrorCode = "FILE_TOO_LARGE"
+	ErrCodeEncrypted          ErrorCode = "PDF_ENCRYPTED"
+	ErrCodeBadPassword        ErrorCode = "BAD_PASSWORD"
+	ErrCodeCorrupted          ErrorCode = "PDF_CORRUPTED"
+	ErrCodeTooManyPages       ErrorCode = "PDF_TOO_MANY_PAGES"
+	ErrCodeUnsupportedVersion ErrorCode = "PDF_UNSUPPORTED_VERSION"
+	ErrCodeTimeout            ErrorCode = "PROCESSING_TIMEOUT"
+	ErrCodeCanceled           ErrorCode = "REQUEST_CANCELED"
+	ErrCodeBusy               ErrorCode = "SERVICE_BUSY"
+	ErrCodeLowResources       ErrorCode = "INSUFFICIENT_RESOURCES"
+	ErrCodeNotFound           ErrorCode = "NOT_FOUND"
+	ErrCodeTooManyAttempts    ErrorCode = "TOO_MANY_ATTEMPTS"
+	ErrCodeRateLimited        ErrorCode = "RATE_LIMITED"
+	ErrCodeUnauthorized       ErrorCode = "UNAUTHORIZED"
+	ErrCodeToolUnavailable    ErrorCode = "TOOL_UNAVAILABLE"
+	ErrCodeInternal           ErrorCode = "INTERNAL_ERROR"
+)
+
+// PDFError is a processing error carrying a stable error code
+type PDFError struct {
+	Code    ErrorCode
+	Message string
+	Err     error
+	// RetryAfter hints how many seconds the client should wait before retrying
+	RetryAfter int
+	// Details carries structured context such as the offending input
+	Details map[string]interface{}
+}
+
+// Error implements the error interface
+func (e *PDFError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%s: %v", e.Message, e.Err)
+	}
+	return e.Message
+}
+
+// Unwrap returns the underlying error
+func (e *PDFError) Unwrap() error {
+	return e.Err
+}
+
+// NewError creates a new coded error
+func NewError(code ErrorCode, message string, err error) *PDFError {
+	return &PDFError{
+		Code:    code,
+		Message: message,
+		Err:     err,
+	}
+}
+
+// CodeOf returns the error code of err, or ErrCodeInternal if err is not a PDFError
+func CodeOf(err error) ErrorCode {
+	var pdfErr *PDFError
+	if errors.As(err, &pdfErr) {
+		return pdfErr.Code
+	}
+	return ErrCodeInternal
+}
+
+// failureReason returns the metrics failure label for err, or "" on success
+func failureReason(err error) string {
+	if err == nil {
+		return ""
+	}
+	return string(CodeOf(err))
+}
+
+// unavailableError maps a call failed fast by an open circuit breaker onto
+// a busy error naming the dependency, or returns nil for any other error
+func unavailableError(err error, dependency string) error {
+	if errors.Is(err, breaker.ErrOpen) {
+		return NewError(ErrCodeBusy, fmt.Sprintf("%s is currently failing, retry later", dependency), err)
+	}
+	return nil
+}
+
+// inputError attributes a failure to one input of a multi-file request
+func inputError(err error, index int, name string) error {
+	label := fmt.Sprintf("input %d", index)
+	if name != "" {
+		label = fmt.Sprintf("input %d (%s)", index, name)
+	}
+
+	var pdfErr *PDFError
+	if !errors.As(err, &pdfErr) {
+		return fmt.Errorf("%s: %w", label, err)
+	}
+
+	attributed := *pdfErr
+	attributed.Message = fmt.Sprintf("%s: %s", label, pdfErr.Message)
+	attributed.Details = map[string]interface{}{
+		"input_index": index,
+	}
+	if name != "" {
+		attributed.Details["input_name"] = name
+	}
+	return &attributed
+}
+
+// inputName returns the client-supplied name of input i, if any
+func inputName(names []string, i int) string {
+	if i < len(names) {
+		return names[i]
+	}
+	return ""
+}
+
+// pdfcpuFailures maps phrases of pdfcpu error messages onto coded errors,
+// checked in order. pdfcpu only exports a sentinel for password failures,
+// so the remaining causes are recognised from its messages. Phrases are
+// specific to pdfcpu's wording; generic words such as "invalid" or "eof"
+// also occur in client mistakes and would misreport them as corrupt PDFs.
+var pdfcpuFailures = []struct {
+	phrase  string
+	code    ErrorCode
+	message string
+}{
+	{"page selection", ErrCodeInvalidInput, "invalid page selection"},
+	{"invalid page range", ErrCodeInvalidInput, "invalid page range"},
+	{"this file is encrypted", ErrCodeEncrypted, "PDF is encrypted and requires a valid password"},
+	{"encrypted pdf", ErrCodeEncrypted, "PDF is encrypted and requires a valid password"},
+	{"invalid password", ErrCodeEncrypted, "PDF is encrypted and requires a valid password"},
+	{"wrong password", ErrCodeEncrypted, "PDF is encrypted and requires a valid password"},
+	{"unsupported pdf version", ErrCodeUnsupportedVersion, "PDF version is not supported"},
+	{"unknown pdf version", ErrCodeUnsupportedVersion, "PDF version is not supported"},
+	{"headerversion", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+	{"no header version", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+	{"xref", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+	{"trailer", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+	{"corrupt", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+	{"malformed", ErrCodeCorrupted, "PDF is corrupted or malformed"},
+}
+
+// classifyPDFError maps a pdfcpu failure onto a coded error. pdfcpu's
+// wrong-password sentinel means encrypted input to operations that take no
+// password; operations taking one classify it themselves. Unrecognised
+// failures are internal.
+func classifyPDFError(err error, message string) error {
+	if err == nil {
+		return nil
+	}
+
+	var pdfErr *PDFError
+	if errors.As(err, &pdfErr) {
+		return err
+	}
+
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &tooLarge):
+		return NewError(ErrCodeFileTooLarge, "upload exceeds the size limit", err)
+	case errors.Is(err, pdfcpu.ErrWrongPassword):
+		return NewError(ErrCodeEncrypted, "PDF is encrypted and requires a valid password", err)
+	case errors.Is(err, io.ErrUnexpectedEOF):
+		return NewError(ErrCodeCorrupted, "PDF is truncated", err)
+	}
+
+	msg := strings.ToLower(err.Error())
+	for _, failure := range pdfcpuFailures {
+		if strings.Contains(msg, failure.phrase) {
+			return NewError(failure.code, failure.message, err)
+		}
+	}
+
+	return fmt.Errorf("%s: %w", message, err)
+}
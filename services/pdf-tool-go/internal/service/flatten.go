@@ -0,0 +1,149 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Transparency flattening resolution bounds; print RIPs need at least
+// screen resolution and gain nothing past image setter resolution
+const (
+	minFlattenDPI = 72
+	maxFlattenDPI = 1200
+)
+
+// FlattenTransparencyRequest represents a transparency flattening request
+type FlattenTransparencyRequest struct {
+	PDFData []byte
+	// DPI is the resolution transparent regions are rasterized at; 0 uses
+	// the configured resolution
+	DPI int
+}
+
+// FlattenTransparencyResponse is a document with its transparency flattened
+type FlattenTransparencyResponse struct {
+	PDF []byte
+	// Transparent counts the transparency groups, soft masks, blend modes
+	// and alpha constants found before flattening
+	Transparent int
+}
+
+// FlattenTransparency flattens the transparency groups, soft masks, blend
+// modes and alpha of a document into opaque content for RIPs that cannot
+// composite transparency. Opaque content keeps its vectors and text;
+// regions where transparent objects overlap are rasterized at the request's
+// resolution. Documents without transparency are returned unchanged.
+func (s *PDFService) FlattenTransparency(ctx context.Context, req *FlattenTransparencyRequest) (_ *FlattenTransparencyResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.FlattenTransparency")
+	defer span.End()
+
+	op := metrics.Start("flatten_transparency")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "flatten_transparency")
+	defer cancel()
+
+	dpi := req.DPI
+	if dpi == 0 {
+		dpi = s.config.Prepress.FlattenDPI
+	}
+	if dpi < minFlattenDPI || dpi > maxFlattenDPI {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("dpi must be between %d and %d", minFlattenDPI, maxFlattenDPI), nil)
+	}
+	span.SetAttributes(attribute.Int("dpi", dpi))
+
+	s.log.Info("Flattening PDF transparency", "dpi", dpi)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response := &FlattenTransparencyResponse{PDF: req.PDFData}
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, req.PDFData)
+		if err != nil {
+			return err
+		}
+		response.Transparent = scanPDFXObjects(ctx2).transparency
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("transparent", response.Transparent))
+	if response.Transparent == 0 {
+		s.log.Info("PDF has no transparency to flatten")
+		return response, nil
+	}
+
+	err = s.runHeavy(ctx, func() error {
+		response.PDF, err = s.flattenTransparency(ctx, req.PDFData, dpi)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(response.PDF)))
+	s.log.Info("Transparency flattening completed", "transparent", response.Transparent, "size", len(response.PDF))
+
+	return response, nil
+}
+
+// flattenTransparency rewrites a document through Ghostscript's pdfwrite
+// device as PDF 1.3, which has no transparency, so Ghostscript composites
+// transparent regions into images at dpi
+func (s *PDFService) flattenTransparency(ctx context.Context, pdfData []byte, dpi int) ([]byte, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=pdfwrite",
+			"-dCompatibilityLevel=1.3",
+			fmt.Sprintf("-r%d", dpi),
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=output.pdf",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,375 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// How a missing font was resolved by EmbedFonts
+const (
+	// FontEmbedded fonts were embedded from the font directory
+	FontEmbedded = "embedded"
+	// FontSubstituted fonts were replaced by a metrics-compatible font
+	FontSubstituted = "substituted"
+	// FontUnresolved fonts had no match; Ghostscript embeds its own best
+	// guess, which may change the layout
+	FontUnresolved = "unresolved"
+)
+
+// fontExtensions are the font file types Ghostscript loads through a
+// Fontmap
+var fontExtensions = map[string]bool{".ttf": true, ".otf": true, ".pfb": true, ".pfa": true}
+
+// standardFonts maps the families of the standard 14 fonts to the URW
+// fonts Ghostscript ships with their metrics
+var standardFonts = map[string]string{
+	"helvetica":    "NimbusSans",
+	"times":        "NimbusRoman",
+	"courier":      "NimbusMonoPS",
+	"symbol":       "StandardSymbolsPS",
+	"zapfdingbats": "D050000L",
+}
+
+// FontResolution is how a font missing from a document was resolved
+type FontResolution struct {
+	// Font is the font's name in the document
+	Font   string
+	Action string
+	// Substitute is the font used in place of a substituted font
+	Substitute string `json:",omitempty"`
+}
+
+// EmbedFontsResponse is a document with its missing fonts embedded
+type EmbedFontsResponse struct {
+	PDF   []byte
+	Fonts []FontResolution
+}
+
+// EmbedFonts embeds the fonts a document uses without embedding, which
+// render with whatever the reader finds on the device otherwise. Fonts are
+// taken from the configured font directory by name or, failing that,
+// replaced by their configured metrics-compatible substitute. The standard
+// 14 fonts get Ghostscript's URW equivalents. Documents with every font
+// embedded are returned unchanged.
+func (s *PDFService) EmbedFonts(ctx context.Context, pdfData []byte) (_ *EmbedFontsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.EmbedFonts")
+	defer span.End()
+
+	op := metrics.Start("embed_fonts")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "embed_fonts")
+	defer cancel()
+
+	s.log.Info("Embedding PDF fonts")
+
+	pageCount, err := s.checkPageCount(ctx, pdfData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(pdfData))); err != nil {
+		return nil, err
+	}
+
+	var missing []string
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		missing = scanPDFXObjects(ctx2).unembeddedFonts
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	response := &EmbedFontsResponse{PDF: pdfData, Fonts: []FontResolution{}}
+	if len(missing) == 0 {
+		s.log.Info("PDF fonts already embedded")
+		return response, nil
+	}
+
+	files, err := fontFiles(s.config.Prepress.FontDirectory)
+	if err != nil {
+		return nil, err
+	}
+	resolver := newFontResolver(files, s.config.Prepress.FontSubstitutes)
+	fontmap := make(map[string]string)
+	for _, name := range missing {
+		resolution, path := resolver.resolve(name)
+		if path != "" && !fontmapName(name) {
+			resolution, path = FontResolution{Font: name, Action: FontUnresolved}, ""
+		}
+		response.Fonts = append(response.Fonts, resolution)
+		if path != "" {
+			fontmap[name] = path
+		}
+	}
+
+	err = s.runHeavy(ctx, func() error {
+		response.PDF, err = s.embedFonts(ctx, pdfData, fontmap)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	unresolved := 0
+	for _, font := range response.Fonts {
+		if font.Action == FontUnresolved {
+			unresolved++
+		}
+	}
+	span.SetAttributes(attribute.Int("fonts", len(response.Fonts)), attribute.Int("unresolved", unresolved))
+	op.Output(int64(len(response.PDF)))
+
+	s.log.Info("PDF fonts embedded", "fonts", len(response.Fonts), "unresolved", unresolved, "size", len(response.PDF))
+
+	return response, nil
+}
+
+// embedFonts rewrites a document through Ghostscript's pdfwrite device with
+// every font embedded. Fontmap maps font names to the files loaded for
+// them; the files are copied into the workspace, so a sandboxed Ghostscript
+// can read them.
+func (s *PDFService) embedFonts(ctx context.Context, pdfData []byte, fontmap map[string]string) ([]byte, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	args := []string{
+		"-sDEVICE=pdfwrite",
+		"-dEmbedAllFonts=true",
+		"-dSubsetFonts=true",
+		"-dSAFER",
+		"-dNOPAUSE",
+		"-dQUIET",
+		"-dBATCH",
+	}
+	err = inSpan(ctx, "tempfile.write", func() error {
+		if _, err := ws.WriteFile("input.pdf", pdfData); err != nil {
+			return err
+		}
+		if len(fontmap) == 0 {
+			return nil
+		}
+		names := make([]string, 0, len(fontmap))
+		for name := range fontmap {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		copied := make(map[string]string)
+		var entries strings.Builder
+		for _, name := range names {
+			source := fontmap[name]
+			file, ok := copied[source]
+			if !ok {
+				file = fmt.Sprintf("font%d%s", len(copied), strings.ToLower(filepath.Ext(source)))
+				if err := copyFontFile(ws, file, source); err != nil {
+					return err
+				}
+				copied[source] = file
+				args = append(args, "--permit-file-read="+ws.Path(file))
+			}
+			fmt.Fprintf(&entries, "/%s (%s) ;\n", name, psString(ws.Path(file)))
+		}
+		_, err := ws.WriteFile("fontmap", []byte(entries.String()))
+		args = append(args, "-sFONTMAP="+ws.Path("fontmap"), "--permit-file-read="+ws.Path("fontmap"))
+		return err
+	}, attribute.Int("bytes", len(pdfData)), attribute.Int("fonts", len(fontmap)))
+	if err != nil {
+		return nil, err
+	}
+
+	args = append(args, "-sOutputFile=output.pdf", "input.pdf")
+	if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "gs", Args: args}); err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
+
+// copyFontFile copies a font file into a workspace
+func copyFontFile(ws *exec.Workspace, name, source string) error {
+	f, err := os.Open(source)
+	if err != nil {
+		return fmt.Errorf("failed to read font %s: %w", filepath.Base(source), err)
+	}
+	defer f.Close()
+	_, err = ws.CopyFile(name, f)
+	return err
+}
+
+// fontFiles indexes the font files below dir by fontKey of their file
+// name. The directory is read per request, so fonts added to it are used
+// without a restart.
+func fontFiles(dir string) (map[string]string, error) {
+	files := make(map[string]string)
+	if dir == "" {
+		return files, nil
+	}
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		ext := strings.ToLower(filepath.Ext(path))
+		if entry.IsDir() || !fontExtensions[ext] {
+			return nil
+		}
+		key := fontKey(strings.TrimSuffix(filepath.Base(path), filepath.Ext(path)))
+		if _, ok := files[key]; !ok {
+			files[key] = path
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read font directory: %w", err)
+	}
+	return files, nil
+}
+
+// fontResolver finds the files loaded for missing fonts
+type fontResolver struct {
+	// files are the font files by fontKey of their name
+	files map[string]string
+	// substitutes are the substitute families by fontKey of the family
+	// they replace
+	substitutes map[string]string
+}
+
+// newFontResolver creates a resolver over indexed font files. Substitute
+// families are matched ignoring case, since configuration keys are not
+// case-sensitive.
+func newFontResolver(files, substitutes map[string]string) *fontResolver {
+	r := &fontResolver{files: files, substitutes: make(map[string]string, len(substitutes))}
+	for family, substitute := range substitutes {
+		r.substitutes[fontKey(family)] = substitute
+	}
+	return r
+}
+
+// resolve finds the font file for a missing font, returning how the font
+// is resolved and the file, or "" where Ghostscript resolves it itself
+func (r *fontResolver) resolve(name string) (FontResolution, string) {
+	family, style := splitFontName(name)
+	if path, ok := r.files[fontKey(stripSubsetPrefix(name))]; ok {
+		return FontResolution{Font: name, Action: FontEmbedded}, path
+	}
+	if path, ok := r.lookup(family, style); ok {
+		return FontResolution{Font: name, Action: FontEmbedded}, path
+	}
+	if substitute, ok := r.substitutes[fontKey(family)]; ok {
+		if path, ok := r.lookup(substitute, style); ok {
+			return FontResolution{Font: name, Action: FontSubstituted, Substitute: fontFileName(path)}, path
+		}
+	}
+	if urw, ok := standardFonts[fontKey(family)]; ok {
+		return FontResolution{Font: name, Action: FontSubstituted, Substitute: urw}, ""
+	}
+	return FontResolution{Font: name, Action: FontUnresolved}, ""
+}
+
+// lookup finds the file of a family in a style, where a regular style may
+// also be left out of the file name
+func (r *fontResolver) lookup(family, style string) (string, bool) {
+	if path, ok := r.files[fontKey(family+style)]; ok {
+		return path, true
+	}
+	if style == "Regular" {
+		path, ok := r.files[fontKey(family)]
+		return path, ok
+	}
+	return "", false
+}
+
+// splitFontName splits a PDF font name such as ArialMT, Arial,Bold or
+// TimesNewRomanPS-BoldItalicMT into its family and a style of Regular,
+// Bold, Italic or BoldItalic. The MT and PS suffixes of Monotype fonts are
+// dropped.
+func splitFontName(name string) (family, style string) {
+	name = stripSubsetPrefix(name)
+	family, style = name, ""
+	if i := strings.IndexAny(name, ",-"); i >= 0 {
+		family, style = name[:i], name[i+1:]
+	}
+	for _, suffix := range []string{"PSMT", "MT", "PS"} {
+		if trimmed := strings.TrimSuffix(family, suffix); trimmed != family && trimmed != "" {
+			family = trimmed
+			break
+		}
+	}
+	style = strings.TrimSuffix(style, "MT")
+	switch key := fontKey(style); {
+	case key == "" || key == "roman" || key == "regular" || key == "normal" || key == "book":
+		style = "Regular"
+	case strings.Contains(key, "bold") && (strings.Contains(key, "italic") || strings.Contains(key, "oblique")):
+		style = "BoldItalic"
+	case strings.Contains(key, "bold"):
+		style = "Bold"
+	case strings.Contains(key, "italic") || strings.Contains(key, "oblique"):
+		style = "Italic"
+	}
+	return family, style
+}
+
+// stripSubsetPrefix drops the six letter tag naming a font subset, as in
+// ABCDEF+Arial
+func stripSubsetPrefix(name string) string {
+	if len(name) > 7 && name[6] == '+' && strings.Trim(name[:6], "ABCDEFGHIJKLMNOPQRSTUVWXYZ") == "" {
+		return name[7:]
+	}
+	return name
+}
+
+// fontmapName reports whether a font name can be written to a Fontmap,
+// which holds PostScript names without delimiters or white space
+func fontmapName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for _, r := range name {
+		if r <= ' ' || r > '~' || strings.ContainsRune("()<>[]{}/%", r) {
+			return false
+		}
+	}
+	return true
+}
+
+// fontKey normalizes a font or file name for matching: lower case letters
+// and digits only
+func fontKey(name string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(name) {
+		if r >= 'a' && r <= 'z' || r >= '0' && r <= '9' {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// fontFileName is the name of a font file without directory and extension
+func fontFileName(path string) string {
+	return strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+}
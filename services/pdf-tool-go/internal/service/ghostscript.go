@@ -0,0 +1,197 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ghostscriptProfiles maps compression profiles to Ghostscript PDFSETTINGS presets
+var ghostscriptProfiles = map[string]string{
+	"screen":  "/screen",  // 72 dpi images, smallest output
+	"ebook":   "/ebook",   // 150 dpi images
+	"printer": "/printer", // 300 dpi images
+}
+
+// compressWithGhostscript rewrites a PDF through Ghostscript's pdfwrite
+// device, which downsamples and recompresses images. This shrinks scanned
+// documents far more than pdfcpu's structural optimization.
+func (s *PDFService) compressWithGhostscript(ctx context.Context, pdfData []byte, preset string) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "PDFService.compressWithGhostscript")
+	defer span.End()
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=pdfwrite",
+			"-dCompatibilityLevel=1.5",
+			"-dPDFSETTINGS=" + preset,
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=output.pdf",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
+
+// ghostscriptDevices maps image formats to Ghostscript output devices
+var ghostscriptDevices = map[string]string{
+	"png":  "png16m",
+	"jpeg": "jpeg",
+}
+
+// renderWithGhostscript rasterizes a single page of a PDF
+func (s *PDFService) renderWithGhostscript(ctx context.Context, pdfData []byte, page int, format string, dpi int) ([]byte, error) {
+	ctx, span := tracer.Start(ctx, "PDFService.renderWithGhostscript")
+	defer span.End()
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	output := "page." + format
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=" + ghostscriptDevices[format],
+			fmt.Sprintf("-r%d", dpi),
+			fmt.Sprintf("-dFirstPage=%d", page),
+			fmt.Sprintf("-dLastPage=%d", page),
+			"-dTextAlphaBits=4",
+			"-dGraphicsAlphaBits=4",
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=" + output,
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile(output)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
+
+// toolError maps an external tool failure onto a coded error
+func toolError(ctx context.Context, err error, tool string) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+	if busy := unavailableError(err, tool); busy != nil {
+		return busy
+	}
+	if errors.Is(err, exec.ErrToolNotAllowed) || errors.Is(err, exec.ErrToolNotFound) {
+		return NewError(ErrCodeToolUnavailable, fmt.Sprintf("%s is not available on this server", tool), err)
+	}
+
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return NewError(ErrCodeCorrupted, fmt.Sprintf("%s could not process the document", tool), err)
+	}
+	return fmt.Errorf("%s failed: %w", tool, err)
+}
+
+// textPositions reads the characters of pages first to last with their
+// boxes through Ghostscript's txtwrite device, at 72 dpi so boxes are in
+// points from the top left of the MediaBox. Characters are keyed by page
+// number.
+func (s *PDFService) textPositions(ctx context.Context, pdfData []byte, first, last int) (map[int][]pageChar, error) {
+	ctx, span := tracer.Start(ctx, "PDFService.textPositions")
+	defer span.End()
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=txtwrite",
+			"-dTextFormat=0",
+			"-r72",
+			fmt.Sprintf("-dFirstPage=%d", first),
+			fmt.Sprintf("-dLastPage=%d", last),
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=text.xml",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("text.xml")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return parseTextPositions(data, first), nil
+}
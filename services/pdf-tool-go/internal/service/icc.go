@@ -0,0 +1,458 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode/utf16"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// ICCProfileInfo describes an ICC profile embedded in a document
+type ICCProfileInfo struct {
+	// ID is the object number of the profile stream, which selects it for
+	// extraction
+	ID         int
+	Components int
+	// ColorSpace and Class are the profile's data colour space and device
+	// class signatures, e.g. "CMYK" and "prtr"
+	ColorSpace  string
+	Class       string
+	Version     string
+	Description string `json:",omitempty"`
+	Size        int
+	// OutputIntent is the subtype of the output intent using the profile,
+	// e.g. GTS_PDFX, if any
+	OutputIntent    string `json:",omitempty"`
+	OutputCondition string `json:",omitempty"`
+}
+
+// ICCProfileReport lists the ICC profiles of a document
+type ICCProfileReport struct {
+	PageCount int
+	Profiles  []ICCProfileInfo
+}
+
+// StripICCRequest represents an ICC profile stripping request
+type StripICCRequest struct {
+	PDFData []byte
+	// KeepOutputIntents strips the profiles of colour spaces only,
+	// keeping the output intents and their profiles
+	KeepOutputIntents bool
+}
+
+// StripICCResponse is a document with its ICC profiles stripped
+type StripICCResponse struct {
+	PDF []byte
+	// Stripped counts the ICC-based colour spaces replaced and output
+	// intents removed
+	Stripped int
+}
+
+// ListICCProfiles lists the ICC profiles of a document's ICC-based colour
+// spaces and output intents
+func (s *PDFService) ListICCProfiles(ctx context.Context, pdfData []byte) (_ *ICCProfileReport, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ListICCProfiles")
+	defer span.End()
+
+	op := metrics.Start("list_icc_profiles")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "list_icc_profiles")
+	defer cancel()
+
+	s.log.Info("Listing PDF ICC profiles")
+
+	var report *ICCProfileReport
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		report = &ICCProfileReport{PageCount: ctx2.PageCount, Profiles: []ICCProfileInfo{}}
+		for _, ref := range iccProfileRefs(ctx2) {
+			data, err := streamContent(ctx2, ref.objNr)
+			if err != nil {
+				s.log.Warn("Unreadable ICC profile", "object", ref.objNr, "error", err)
+				continue
+			}
+			report.Profiles = append(report.Profiles, describeICCProfile(ref, data))
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(report.PageCount)
+	span.SetAttributes(attribute.Int("profiles", len(report.Profiles)))
+
+	s.log.Info("ICC profiles listed", "profiles", len(report.Profiles))
+
+	return report, nil
+}
+
+// ExtractICCProfile returns the ICC profile of a document with the given
+// ID, as listed by ListICCProfiles
+func (s *PDFService) ExtractICCProfile(ctx context.Context, pdfData []byte, id int) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ExtractICCProfile")
+	defer span.End()
+
+	op := metrics.Start("extract_icc_profile")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "extract_icc_profile")
+	defer cancel()
+
+	if id < 1 {
+		return nil, NewError(ErrCodeInvalidInput, "a profile id is required", nil)
+	}
+	span.SetAttributes(attribute.Int("id", id))
+	s.log.Info("Extracting PDF ICC profile", "id", id)
+
+	var profile []byte
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		op.Pages(ctx2.PageCount)
+		for _, ref := range iccProfileRefs(ctx2) {
+			if ref.objNr != id {
+				continue
+			}
+			if profile, err = streamContent(ctx2, id); err != nil {
+				return classifyPDFError(err, "failed to read ICC profile")
+			}
+			return nil
+		}
+		return NewError(ErrCodeNotFound, fmt.Sprintf("document has no ICC profile %d", id), nil)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(profile)))
+	s.log.Info("ICC profile extracted", "id", id, "size", len(profile))
+
+	return profile, nil
+}
+
+// StripICCProfiles replaces the ICC-based colour spaces of a document with
+// their alternate or device colour spaces and removes its output intents,
+// dropping the profiles. Colour is then interpreted by each renderer's own
+// defaults, and PDF/A and PDF/X conformance, which require an output
+// intent, is lost unless output intents are kept.
+func (s *PDFService) StripICCProfiles(ctx context.Context, req *StripICCRequest) (_ *StripICCResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.StripICCProfiles")
+	defer span.End()
+
+	op := metrics.Start("strip_icc_profiles")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "strip_icc_profiles")
+	defer cancel()
+
+	span.SetAttributes(attribute.Bool("keep_output_intents", req.KeepOutputIntents))
+	s.log.Info("Stripping PDF ICC profiles", "keep_output_intents", req.KeepOutputIntents)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response := &StripICCResponse{PDF: req.PDFData}
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, req.PDFData)
+		if err != nil {
+			return err
+		}
+		op.Pages(ctx2.PageCount)
+		response.Stripped = stripICC(ctx2, req.KeepOutputIntents)
+		if response.Stripped == 0 {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		response.PDF = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("stripped", response.Stripped))
+
+	op.Output(int64(len(response.PDF)))
+	s.log.Info("ICC profiles stripped", "stripped", response.Stripped, "size", len(response.PDF))
+
+	return response, nil
+}
+
+// iccProfileRef is an ICC profile stream of a document with the output
+// intent using it, if any
+type iccProfileRef struct {
+	objNr int
+	// components is the N entry of the profile stream
+	components      int
+	outputIntent    string
+	outputCondition string
+}
+
+// iccProfileRefs finds the profile streams of ICC-based colour spaces,
+// anywhere in the document, and of the catalog's output intents, ordered
+// by object number
+func iccProfileRefs(ctx2 *pdfcpu.Context) []iccProfileRef {
+	refs := make(map[int]*iccProfileRef)
+	add := func(obj pdfcpu.Object) *iccProfileRef {
+		ref, ok := obj.(pdfcpu.IndirectRef)
+		if !ok {
+			return nil
+		}
+		objNr := ref.ObjectNumber.Value()
+		if refs[objNr] == nil {
+			profile := &iccProfileRef{objNr: objNr}
+			if stream := streamOrDict(ctx2, ref); stream != nil {
+				if n, ok := numberValue(ctx2, stream["N"]); ok {
+					profile.components = int(n)
+				}
+			}
+			refs[objNr] = profile
+		}
+		return refs[objNr]
+	}
+
+	var visit func(obj pdfcpu.Object, depth int)
+	visit = func(obj pdfcpu.Object, depth int) {
+		if depth > maxObjectDepth {
+			return
+		}
+		switch o := obj.(type) {
+		case pdfcpu.StreamDict:
+			visit(o.Dict, depth+1)
+		case pdfcpu.Dict:
+			for _, value := range o {
+				visit(value, depth+1)
+			}
+		case pdfcpu.Array:
+			if len(o) == 2 && o[0] == pdfcpu.Name("ICCBased") {
+				add(o[1])
+				return
+			}
+			for _, value := range o {
+				visit(value, depth+1)
+			}
+		}
+	}
+	for _, entry := range ctx2.Table {
+		if entry != nil && !entry.Free {
+			visit(entry.Object, 0)
+		}
+	}
+
+	obj, _ := ctx2.RootDict.Find("OutputIntents")
+	obj, _ = ctx2.Dereference(obj)
+	intents, _ := obj.(pdfcpu.Array)
+	for _, entry := range intents {
+		intent, err := ctx2.DereferenceDict(entry)
+		if err != nil || intent == nil {
+			continue
+		}
+		profile := add(intent["DestOutputProfile"])
+		if profile == nil {
+			continue
+		}
+		if subtype, ok := intent["S"].(pdfcpu.Name); ok {
+			profile.outputIntent = decodeName(subtype)
+		}
+		profile.outputCondition, _ = textValue(ctx2, intent["OutputConditionIdentifier"])
+	}
+
+	list := make([]iccProfileRef, 0, len(refs))
+	for _, ref := range refs {
+		list = append(list, *ref)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].objNr < list[j].objNr })
+	return list
+}
+
+// streamContent returns the decoded content of a stream object
+func streamContent(ctx2 *pdfcpu.Context, objNr int) ([]byte, error) {
+	entry, ok := ctx2.Find(objNr)
+	if !ok || entry == nil {
+		return nil, fmt.Errorf("object %d does not exist", objNr)
+	}
+	stream, ok := entry.Object.(pdfcpu.StreamDict)
+	if !ok {
+		return nil, fmt.Errorf("object %d is not a stream", objNr)
+	}
+	if err := stream.Decode(); err != nil {
+		return nil, err
+	}
+	return stream.Content, nil
+}
+
+// describeICCProfile describes a profile from its header and description
+// tag. Profiles with a damaged header are listed with what the document
+// says of them.
+func describeICCProfile(ref iccProfileRef, data []byte) ICCProfileInfo {
+	info := ICCProfileInfo{
+		ID:              ref.objNr,
+		Components:      ref.components,
+		Size:            len(data),
+		OutputIntent:    ref.outputIntent,
+		OutputCondition: ref.outputCondition,
+	}
+	profile, err := parseICCProfile(data)
+	if err != nil {
+		return info
+	}
+	info.ColorSpace = strings.TrimSpace(profile.space)
+	info.Class = profile.class
+	info.Version = fmt.Sprintf("%d.%d", data[8], data[9]>>4)
+	info.Description = iccDescription(data)
+	return info
+}
+
+// iccDescription reads the profile description tag: ASCII text in ICC v2
+// profiles, the first UTF-16 record of a multi-localized text in v4
+func iccDescription(data []byte) string {
+	if len(data) < iccHeaderSize+4 {
+		return ""
+	}
+	count := int(binary.BigEndian.Uint32(data[iccHeaderSize:]))
+	for i := 0; i < count; i++ {
+		entry := iccHeaderSize + 4 + i*12
+		if entry+12 > len(data) {
+			return ""
+		}
+		if string(data[entry:entry+4]) != "desc" {
+			continue
+		}
+		offset := int(binary.BigEndian.Uint32(data[entry+4:]))
+		size := int(binary.BigEndian.Uint32(data[entry+8:]))
+		if offset < 0 || size < 12 || offset+size > len(data) || offset+size < offset {
+			return ""
+		}
+		tag := data[offset : offset+size]
+		switch string(tag[:4]) {
+		case "desc":
+			n := int(binary.BigEndian.Uint32(tag[8:]))
+			if n < 0 || 12+n > len(tag) {
+				return ""
+			}
+			return strings.TrimRight(string(tag[12:12+n]), "\x00 ")
+		case "mluc":
+			if len(tag) < 28 || binary.BigEndian.Uint32(tag[8:]) == 0 {
+				return ""
+			}
+			n := int(binary.BigEndian.Uint32(tag[20:]))
+			start := int(binary.BigEndian.Uint32(tag[24:]))
+			if n < 0 || start < 0 || start+n > len(tag) || start+n < start {
+				return ""
+			}
+			units := make([]uint16, n/2)
+			for j := range units {
+				units[j] = binary.BigEndian.Uint16(tag[start+2*j:])
+			}
+			return strings.TrimRight(string(utf16.Decode(units)), "\x00 ")
+		}
+		return ""
+	}
+	return ""
+}
+
+// deviceSpaces are the device colour spaces standing in for ICC-based
+// ones by number of components
+var deviceSpaces = map[int]pdfcpu.Name{1: "DeviceGray", 3: "DeviceRGB", 4: "DeviceCMYK"}
+
+// stripICC replaces the ICC-based colour spaces of a document with their
+// alternate or device colour spaces and, unless output intents are kept,
+// removes the output intents of the catalog and pages. Default colour
+// spaces that are ICC-based are removed, since device colour spaces are
+// not allowed there. The profile streams are no longer referenced, so
+// they are not written. It returns the number of changes.
+func stripICC(ctx2 *pdfcpu.Context, keepOutputIntents bool) int {
+	stripped := 0
+	replacement := func(obj pdfcpu.Object) (pdfcpu.Object, bool) {
+		array, ok := obj.(pdfcpu.Array)
+		if !ok || len(array) != 2 || array[0] != pdfcpu.Name("ICCBased") {
+			return nil, false
+		}
+		stream := streamOrDict(ctx2, array[1])
+		if stream == nil {
+			return pdfcpu.Name("DeviceRGB"), true
+		}
+		if alternate, ok := stream["Alternate"].(pdfcpu.Name); ok {
+			return alternate, true
+		}
+		n, _ := numberValue(ctx2, stream["N"])
+		if space, ok := deviceSpaces[int(n)]; ok {
+			return space, true
+		}
+		return pdfcpu.Name("DeviceRGB"), true
+	}
+
+	var visit func(obj pdfcpu.Object, depth int)
+	visit = func(obj pdfcpu.Object, depth int) {
+		if depth > maxObjectDepth {
+			return
+		}
+		switch o := obj.(type) {
+		case pdfcpu.StreamDict:
+			visit(o.Dict, depth+1)
+		case pdfcpu.Dict:
+			for key, value := range o {
+				if key == "OutputIntents" && !keepOutputIntents {
+					delete(o, key)
+					stripped++
+					continue
+				}
+				if space, ok := replacement(value); ok {
+					if strings.HasPrefix(key, "Default") {
+						delete(o, key)
+					} else {
+						o[key] = space
+					}
+					stripped++
+					continue
+				}
+				visit(value, depth+1)
+			}
+		case pdfcpu.Array:
+			for i, value := range o {
+				if space, ok := replacement(value); ok {
+					o[i] = space
+					stripped++
+					continue
+				}
+				visit(value, depth+1)
+			}
+		}
+	}
+	for _, entry := range ctx2.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		if space, ok := replacement(entry.Object); ok {
+			entry.Object = space
+			stripped++
+			continue
+		}
+		visit(entry.Object, 0)
+	}
+	return stripped
+}
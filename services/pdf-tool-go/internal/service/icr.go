@@ -0,0 +1,42 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Recognizer recognises handwritten text (ICR) on a rendered page.
+// Implementations are cloud vision APIs or local models; see package icr.
+type Recognizer interface {
+	// Recognize returns the words on a PNG page image rendered at dpi, with
+	// bounding boxes in pixels of the image and confidences from 0 to 100.
+	// No words means the backend could not read the page.
+	Recognize(ctx context.Context, image []byte, dpi int) ([]OCRWord, error)
+}
+
+// RegisterRecognizer makes an ICR backend selectable by name per request.
+// It must be called before the service handles requests.
+func (s *PDFService) RegisterRecognizer(name string, recognizer Recognizer) {
+	if s.recognizers == nil {
+		s.recognizers = make(map[string]Recognizer)
+	}
+	s.recognizers[name] = recognizer
+}
+
+// recognizer returns the ICR backend selected by name
+func (s *PDFService) recognizer(name string) (Recognizer, error) {
+	if recognizer, ok := s.recognizers[name]; ok {
+		return recognizer, nil
+	}
+	names := make([]string, 0, len(s.recognizers))
+	for known := range s.recognizers {
+		names = append(names, known)
+	}
+	sort.Strings(names)
+	if len(names) == 0 {
+		return nil, NewError(ErrCodeToolUnavailable, "handwriting recognition is not configured on this server", nil)
+	}
+	return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unknown ICR backend %q; available: %s", name, strings.Join(names, ", ")), nil)
+}
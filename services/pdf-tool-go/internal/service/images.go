@@ -0,0 +1,242 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+)
+
+// defaultImageQuality is the lossy encoding quality of formats without a
+// configured default
+const defaultImageQuality = 75
+
+// Size-capped images are re-encoded in steps: quality is lowered first,
+// down to a floor below which artifacts outweigh the savings, then the
+// resolution.
+const (
+	minImageBytes     = 1024
+	minFitQuality     = 30
+	fitQualityStep    = 15
+	fitScaleNumerator = 4 // each resolution step renders at 4/5 the dpi
+	fitScaleDivisor   = 5
+)
+
+// imageFormat describes how pages are encoded in an image format
+type imageFormat struct {
+	contentType string
+	// device is the Ghostscript device rendering pages; pages are rendered
+	// to PNG for formats encoded by a separate tool
+	device string
+	// tool encodes rendered PNG pages, when Ghostscript cannot
+	tool string
+	// multiPage formats hold every page in a single image
+	multiPage bool
+}
+
+// ImageFormats maps conversion output formats to their encoders
+var ImageFormats = map[string]imageFormat{
+	"png":  {contentType: "image/png", device: "png16m"},
+	"jpeg": {contentType: "image/jpeg", device: "jpeg"},
+	"webp": {contentType: "image/webp", device: "png16m", tool: "cwebp"},
+	"avif": {contentType: "image/avif", device: "png16m", tool: "avifenc"},
+	"tiff": {contentType: "image/tiff", device: "tiff24nc", multiPage: true},
+}
+
+// imageEncoder resolves the encoder settings of a conversion request
+// against the configured defaults
+func (s *PDFService) imageEncoder(req *ConvertToImageRequest) (config.ImageEncoderConfig, error) {
+	encoder := s.config.Images.Encoders[req.Format]
+	if encoder.Quality == 0 {
+		encoder.Quality = defaultImageQuality
+	}
+	if req.Quality != 0 {
+		if req.Quality < 1 || req.Quality > 100 {
+			return encoder, NewError(ErrCodeInvalidInput, "quality must be between 1 and 100", nil)
+		}
+		encoder.Quality = req.Quality
+	}
+	if req.Lossless != nil {
+		encoder.Lossless = *req.Lossless
+	}
+	if encoder.Lossless && req.Format != "webp" && req.Format != "avif" {
+		return encoder, NewError(ErrCodeInvalidInput, "lossless encoding is only selectable for webp and avif", nil)
+	}
+	if req.Progressive != nil {
+		encoder.Progressive = *req.Progressive
+	}
+	if encoder.Progressive && req.Format != "jpeg" {
+		return encoder, NewError(ErrCodeInvalidInput, "progressive encoding is only selectable for jpeg", nil)
+	}
+	return encoder, nil
+}
+
+// renderImages renders pages of a document and encodes them in format.
+// Multi-page formats return a single image holding every page.
+func (s *PDFService) renderImages(ctx context.Context, pdfData []byte, pages []int, format string, dpi int, encoder config.ImageEncoderConfig) ([][]byte, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	if _, err := ws.WriteFile("input.pdf", pdfData); err != nil {
+		return nil, err
+	}
+	return s.renderStaged(ctx, ws, pages, format, dpi, encoder)
+}
+
+// renderStaged is renderImages for a document already staged in ws as
+// input.pdf. Rendering again in the same workspace replaces the images.
+func (s *PDFService) renderStaged(ctx context.Context, ws *exec.Workspace, pages []int, format string, dpi int, encoder config.ImageEncoderConfig) ([][]byte, error) {
+	target := ImageFormats[format]
+
+	list := make([]string, len(pages))
+	for i, page := range pages {
+		list[i] = strconv.Itoa(page)
+	}
+	output := "page-%04d." + format
+	if target.tool != "" {
+		output = "page-%04d.png"
+	}
+	if target.multiPage {
+		output = "pages." + format
+	}
+	args := []string{
+		"-sDEVICE=" + target.device,
+		fmt.Sprintf("-r%d", dpi),
+		"-sPageList=" + strings.Join(list, ","),
+		"-dTextAlphaBits=4",
+		"-dGraphicsAlphaBits=4",
+	}
+	switch format {
+	case "jpeg":
+		args = append(args, fmt.Sprintf("-dJPEGQ=%d", encoder.Quality))
+	case "tiff":
+		args = append(args, "-sCompression=lzw")
+	}
+	args = append(args, "-dSAFER", "-dNOPAUSE", "-dQUIET", "-dBATCH", "-sOutputFile="+output, "input.pdf")
+	if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "gs", Args: args}); err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	if target.multiPage {
+		data, err := ws.ReadFile(output)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+		}
+		return [][]byte{data}, nil
+	}
+
+	images := make([][]byte, len(pages))
+	for i := range pages {
+		// Ghostscript numbers output files from 1 in render order
+		rendered := fmt.Sprintf("page-%04d.png", i+1)
+		name := fmt.Sprintf("page-%04d.%s", i+1, format)
+		if target.tool != "" {
+			if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: target.tool, Args: encoderArgs(target.tool, encoder, rendered, name)}); err != nil {
+				return nil, toolError(ctx, err, target.tool)
+			}
+		}
+		if format == "jpeg" && encoder.Progressive {
+			// Ghostscript only writes baseline JPEG; jpegtran rewrites the
+			// scans losslessly
+			progressive := fmt.Sprintf("page-%04d-progressive.jpeg", i+1)
+			args := []string{"-progressive", "-optimize", "-copy", "none", "-outfile", progressive, name}
+			if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "jpegtran", Args: args}); err != nil {
+				return nil, toolError(ctx, err, "jpegtran")
+			}
+			name = progressive
+		}
+		var err error
+		if images[i], err = ws.ReadFile(name); err != nil {
+			return nil, fmt.Errorf("failed to read page image: %w", err)
+		}
+	}
+	return images, nil
+}
+
+// encoderArgs returns the arguments encoding input to output with an
+// image encoding tool
+func encoderArgs(tool string, encoder config.ImageEncoderConfig, input, output string) []string {
+	switch tool {
+	case "cwebp":
+		args := []string{"-quiet", "-q", strconv.Itoa(encoder.Quality)}
+		if encoder.Lossless {
+			// Quality sets the compression effort in lossless mode
+			args = append(args, "-lossless")
+		}
+		return append(args, input, "-o", output)
+	case "avifenc":
+		args := []string{"--speed", strconv.Itoa(encoder.Speed)}
+		if encoder.Lossless {
+			args = append(args, "--lossless")
+		} else {
+			args = append(args, "-q", strconv.Itoa(encoder.Quality))
+		}
+		return append(args, input, output)
+	}
+	return nil
+}
+
+// uniquePages returns pages sorted and without repeats, the order
+// Ghostscript renders a page list in
+func uniquePages(pages []int) []int {
+	sorted := append([]int(nil), pages...)
+	sort.Ints(sorted)
+	unique := sorted[:0]
+	for _, page := range sorted {
+		if len(unique) == 0 || page != unique[len(unique)-1] {
+			unique = append(unique, page)
+		}
+	}
+	return unique
+}
+
+// fitImages re-encodes images larger than maxBytes until they fit, as
+// images holds the rendering of pages by renderImages
+func (s *PDFService) fitImages(ctx context.Context, pdfData []byte, pages []int, format string, dpi int, encoder config.ImageEncoderConfig, maxBytes int, images [][]byte) error {
+	for i, data := range images {
+		if len(data) <= maxBytes {
+			continue
+		}
+		// Multi-page formats hold every page in their one image
+		imagePages := pages
+		if !ImageFormats[format].multiPage {
+			imagePages = pages[i : i+1]
+		}
+		fitEncoder, fitDPI := encoder, dpi
+		for len(data) > maxBytes {
+			var ok bool
+			if fitEncoder, fitDPI, ok = shrinkStep(format, fitEncoder, fitDPI); !ok {
+				return NewError(ErrCodeInvalidInput, fmt.Sprintf("page %d cannot be encoded in %d bytes", imagePages[0], maxBytes), nil)
+			}
+			rendered, err := s.renderImages(ctx, pdfData, imagePages, format, fitDPI, fitEncoder)
+			if err != nil {
+				return err
+			}
+			data = rendered[0]
+		}
+		s.log.Debug("Image fitted to size cap", "page", imagePages[0], "size", len(data), "quality", fitEncoder.Quality, "dpi", fitDPI)
+		images[i] = data
+	}
+	return nil
+}
+
+// shrinkStep returns the encoder settings and resolution of the next,
+// smaller encoding of an image, or false once neither can be lowered
+func shrinkStep(format string, encoder config.ImageEncoderConfig, dpi int) (config.ImageEncoderConfig, int, bool) {
+	lossy := (format == "jpeg" || format == "webp" || format == "avif") && !encoder.Lossless
+	if lossy && encoder.Quality > minFitQuality {
+		encoder.Quality = max(minFitQuality, encoder.Quality-fitQualityStep)
+		return encoder, dpi, true
+	}
+	if dpi <= minRenderDPI {
+		return encoder, dpi, false
+	}
+	return encoder, max(minRenderDPI, dpi*fitScaleNumerator/fitScaleDivisor), true
+}
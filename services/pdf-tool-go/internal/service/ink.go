@@ -0,0 +1,364 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxResourceDepth bounds the walk through the form XObjects nested in a
+// page's resources
+const maxResourceDepth = 16
+
+// processColorants are the colorants of process colour, which are not
+// spot colours where they appear in Separation and DeviceN colour spaces
+var processColorants = map[string]bool{"Cyan": true, "Magenta": true, "Yellow": true, "Black": true, "None": true, "All": true}
+
+// PageInk is the ink use of a page
+type PageInk struct {
+	Page int
+	// Cyan, Magenta, Yellow and Black are the coverage of each process
+	// ink in percent of the page area
+	Cyan    float64
+	Magenta float64
+	Yellow  float64
+	Black   float64
+	// Overprint is set where the page's graphics states turn on overprint
+	Overprint  bool
+	SpotColors []string
+}
+
+// InkReport is the ink coverage, overprint and spot colour use of a
+// document
+type InkReport struct {
+	PageCount int
+	Pages     []PageInk
+	// OverprintPages counts the pages using overprint
+	OverprintPages int
+	// SpotColors lists the spot colours of the whole document
+	SpotColors []string
+}
+
+// AnalyzeInk reports the process ink coverage of each page, which pages
+// use overprint and the spot colours of the document. Coverage comes from
+// Ghostscript's inkcov device, which separates every page into CMYK, spot
+// colours included, so it is the ink a process-only press would lay down.
+func (s *PDFService) AnalyzeInk(ctx context.Context, pdfData []byte) (_ *InkReport, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.AnalyzeInk")
+	defer span.End()
+
+	op := metrics.Start("analyze_ink")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "analyze_ink")
+	defer cancel()
+
+	s.log.Info("Analyzing PDF ink coverage")
+
+	pageCount, err := s.checkPageCount(ctx, pdfData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(pdfData))); err != nil {
+		return nil, err
+	}
+
+	var report *InkReport
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		tree, err := pageTree(ctx2)
+		if err != nil {
+			return err
+		}
+		report = inkUsage(ctx2, tree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var coverage [][4]float64
+	err = s.runHeavy(ctx, func() error {
+		coverage, err = s.inkCoverage(ctx, pdfData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// Ghostscript skips pages it cannot render; their coverage stays 0
+	if len(coverage) != report.PageCount {
+		s.log.Warn("Ink coverage misses pages", "covered", len(coverage), "pages", report.PageCount)
+	}
+	for i, inks := range coverage {
+		if i >= len(report.Pages) {
+			break
+		}
+		page := &report.Pages[i]
+		page.Cyan, page.Magenta, page.Yellow, page.Black = inks[0], inks[1], inks[2], inks[3]
+	}
+	span.SetAttributes(attribute.Int("overprint_pages", report.OverprintPages), attribute.Int("spot_colors", len(report.SpotColors)))
+
+	s.log.Info("Ink analysis completed", "pages", report.PageCount, "overprint_pages", report.OverprintPages, "spot_colors", len(report.SpotColors))
+
+	return report, nil
+}
+
+// inkCoverage measures the CMYK coverage of every page in percent through
+// Ghostscript's inkcov device
+func (s *PDFService) inkCoverage(ctx context.Context, pdfData []byte) ([][4]float64, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=inkcov",
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=coverage.txt",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("coverage.txt")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return parseInkCoverage(data), nil
+}
+
+// parseInkCoverage reads the lines of inkcov output, one per page, such as
+// " 0.02285  0.01210  0.00000  0.07421 CMYK OK", converting the fractions
+// to percentages rounded to two decimals
+func parseInkCoverage(data []byte) [][4]float64 {
+	var pages [][4]float64
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) < 5 || fields[4] != "CMYK" {
+			continue
+		}
+		var inks [4]float64
+		for i := range inks {
+			value, err := strconv.ParseFloat(fields[i], 64)
+			if err != nil {
+				continue
+			}
+			inks[i] = math.Round(value*10000) / 100
+		}
+		pages = append(pages, inks)
+	}
+	return pages
+}
+
+// inkUsage finds the overprint and spot colour use of each page in its
+// resources, including those of the forms it draws
+func inkUsage(ctx2 *pdfcpu.Context, tree []pageNode) *InkReport {
+	report := &InkReport{PageCount: len(tree), Pages: make([]PageInk, len(tree)), SpotColors: []string{}}
+	spots := make(map[string]bool)
+	for i, node := range tree {
+		usage := &resourceInk{spots: make(map[string]bool), visited: make(map[int]bool)}
+		if resources, ok := pageResources(ctx2, node.dict); ok {
+			usage.walk(ctx2, resources, 0)
+		}
+		page := PageInk{Page: i + 1, Overprint: usage.overprint, SpotColors: []string{}}
+		for name := range usage.spots {
+			page.SpotColors = append(page.SpotColors, name)
+			spots[name] = true
+		}
+		sort.Strings(page.SpotColors)
+		if page.Overprint {
+			report.OverprintPages++
+		}
+		report.Pages[i] = page
+	}
+	for name := range spots {
+		report.SpotColors = append(report.SpotColors, name)
+	}
+	sort.Strings(report.SpotColors)
+	return report
+}
+
+// pageResources returns the resources of a page, which may be inherited
+// from the page tree
+func pageResources(ctx2 *pdfcpu.Context, page pdfcpu.Dict) (pdfcpu.Dict, bool) {
+	node := page
+	for depth := 0; depth <= maxPageTreeDepth && node != nil; depth++ {
+		if resources, ok := dictEntry(ctx2, node, "Resources"); ok {
+			return resources, true
+		}
+		node, _ = dictEntry(ctx2, node, "Parent")
+	}
+	return nil, false
+}
+
+// resourceInk collects the overprint and spot colour use of resources
+type resourceInk struct {
+	overprint bool
+	spots     map[string]bool
+	// visited are the form XObjects already walked
+	visited map[int]bool
+}
+
+// walk inspects the graphics states, colour spaces, shadings and XObjects
+// of a resource dictionary, descending into forms
+func (u *resourceInk) walk(ctx2 *pdfcpu.Context, resources pdfcpu.Dict, depth int) {
+	if depth > maxResourceDepth {
+		return
+	}
+	if states, ok := dictEntry(ctx2, resources, "ExtGState"); ok {
+		for _, obj := range states {
+			state, err := ctx2.DereferenceDict(obj)
+			if err == nil && state != nil && (state["OP"] == pdfcpu.Boolean(true) || state["op"] == pdfcpu.Boolean(true)) {
+				u.overprint = true
+			}
+		}
+	}
+	if spaces, ok := dictEntry(ctx2, resources, "ColorSpace"); ok {
+		for _, space := range spaces {
+			u.addSpots(ctx2, space)
+		}
+	}
+	if shadings, ok := dictEntry(ctx2, resources, "Shading"); ok {
+		for _, obj := range shadings {
+			if shading := streamOrDict(ctx2, obj); shading != nil {
+				u.addSpots(ctx2, shading["ColorSpace"])
+			}
+		}
+	}
+	xobjects, ok := dictEntry(ctx2, resources, "XObject")
+	if !ok {
+		return
+	}
+	for _, obj := range xobjects {
+		if ref, ok := obj.(pdfcpu.IndirectRef); ok {
+			if u.visited[ref.ObjectNumber.Value()] {
+				continue
+			}
+			u.visited[ref.ObjectNumber.Value()] = true
+		}
+		xobject := streamOrDict(ctx2, obj)
+		if xobject == nil {
+			continue
+		}
+		switch xobject["Subtype"] {
+		case pdfcpu.Name("Image"):
+			u.addSpots(ctx2, xobject["ColorSpace"])
+		case pdfcpu.Name("Form"):
+			if inner, ok := dictEntry(ctx2, xobject, "Resources"); ok {
+				u.walk(ctx2, inner, depth+1)
+			}
+		}
+	}
+}
+
+// addSpots records the spot colorants of a colour space
+func (u *resourceInk) addSpots(ctx2 *pdfcpu.Context, space pdfcpu.Object) {
+	for _, name := range spotColorants(ctx2, space, 0) {
+		u.spots[name] = true
+	}
+}
+
+// spotColorants returns the spot colours of a Separation or DeviceN colour
+// space, or of the base of an Indexed one
+func spotColorants(ctx2 *pdfcpu.Context, space pdfcpu.Object, depth int) []string {
+	obj, err := ctx2.Dereference(space)
+	if err != nil || depth > 2 {
+		return nil
+	}
+	array, ok := obj.(pdfcpu.Array)
+	if !ok || len(array) < 2 {
+		return nil
+	}
+	var names []pdfcpu.Object
+	switch array[0] {
+	case pdfcpu.Name("Separation"):
+		names = pdfcpu.Array{array[1]}
+	case pdfcpu.Name("DeviceN"):
+		inner, _ := ctx2.Dereference(array[1])
+		names, _ = inner.(pdfcpu.Array)
+	case pdfcpu.Name("Indexed"):
+		return spotColorants(ctx2, array[1], depth+1)
+	}
+	var spots []string
+	for _, obj := range names {
+		obj, _ = ctx2.Dereference(obj)
+		if name, ok := obj.(pdfcpu.Name); ok && !processColorants[decodeName(name)] {
+			spots = append(spots, decodeName(name))
+		}
+	}
+	return spots
+}
+
+// decodeName decodes the #xx escapes of a name, as spot colour names such
+// as PANTONE#20185#20C use for spaces
+func decodeName(name pdfcpu.Name) string {
+	s := string(name)
+	if !strings.Contains(s, "#") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '#' && i+2 < len(s) {
+			if c, err := strconv.ParseUint(s[i+1:i+3], 16, 8); err == nil {
+				b.WriteByte(byte(c))
+				i += 2
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+// streamOrDict dereferences an object to its dictionary, or the
+// dictionary of a stream
+func streamOrDict(ctx2 *pdfcpu.Context, obj pdfcpu.Object) pdfcpu.Dict {
+	obj, err := ctx2.Dereference(obj)
+	if err != nil {
+		return nil
+	}
+	switch o := obj.(type) {
+	case pdfcpu.Dict:
+		return o
+	case pdfcpu.StreamDict:
+		return o.Dict
+	}
+	return nil
+}
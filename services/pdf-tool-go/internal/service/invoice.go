@@ -0,0 +1,256 @@
+package service
+
+import (
+	"context"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// amountPattern matches a money amount with an optional currency symbol,
+// using either a decimal point or a decimal comma
+const amountPattern = `[$€£]?\s?-?\d+(?:[,.]\d{3})*[.,]\d{2}\b`
+
+var (
+	amountRe = regexp.MustCompile(amountPattern)
+	dateRe   = regexp.MustCompile(builtinEntityPatterns["date"])
+	// Labels are matched at the start of a line, so "Subtotal" is not
+	// mistaken for "Total"
+	subtotalRe    = regexp.MustCompile(`(?i)^\s*sub[\s-]?total\b`)
+	taxRe         = regexp.MustCompile(`(?i)^\s*(?:sales\s+)?(?:tax|vat|gst|hst)\b`)
+	totalRe       = regexp.MustCompile(`(?i)^\s*(?:grand\s+total|total(?:\s+(?:due|amount))?|amount\s+due|balance\s+due)\b`)
+	dueDateRe     = regexp.MustCompile(`(?i)\b(?:due\s+date|payment\s+due|due)\b`)
+	invoiceDateRe = regexp.MustCompile(`(?i)\b(?:invoice\s+date|date\s+of\s+issue|issue\s+date|date)\b`)
+	// Line items end in quantity, unit price and amount
+	lineItemRe = regexp.MustCompile(`^(.*[A-Za-z].*?)\s+(\d+(?:[.,]\d+)?)\s+(` + amountPattern + `)\s+(` + amountPattern + `)\s*$`)
+	// Vendors are the first line naming neither the document nor a field
+	notVendorRe = regexp.MustCompile(`(?i)invoice|receipt|bill\b|date|page\b|^[\W\d]*$`)
+)
+
+// currencies maps symbols and codes found on invoices to ISO 4217 codes
+var currencies = []struct {
+	re   *regexp.Regexp
+	code string
+}{
+	{regexp.MustCompile(`\$|\bUSD\b`), "USD"},
+	{regexp.MustCompile(`€|\bEUR\b`), "EUR"},
+	{regexp.MustCompile(`£|\bGBP\b`), "GBP"},
+	{regexp.MustCompile(`\bCAD\b`), "CAD"},
+	{regexp.MustCompile(`\bAUD\b`), "AUD"},
+	{regexp.MustCompile(`\bINR\b|₹`), "INR"},
+}
+
+// ExtractInvoiceRequest represents an invoice field extraction request
+type ExtractInvoiceRequest struct {
+	PDFData []byte
+	// Entities optionally names further entity extractors, such as NER
+	// backends, whose results are returned alongside the fields
+	Entities []string
+}
+
+// Invoice holds the structured fields of an invoice or receipt. Fields
+// that could not be found are left empty.
+type Invoice struct {
+	Vendor        string
+	InvoiceNumber string
+	InvoiceDate   string
+	DueDate       string
+	// Currency is an ISO 4217 code
+	Currency  string
+	Subtotal  float64
+	Tax       float64
+	Total     float64
+	LineItems []LineItem
+	PageCount int
+	// Confidence is the mean OCR word confidence from 0 to 100
+	Confidence float64
+	Entities   []Entity `json:",omitempty"`
+}
+
+// LineItem is a row of an invoice's item table
+type LineItem struct {
+	Description string
+	Quantity    float64
+	UnitPrice   float64
+	Amount      float64
+}
+
+// ExtractInvoice OCRs an invoice or receipt and extracts its vendor,
+// number, dates, totals and line items. Fields are found from labels and
+// line layout in the OCR text, so unusual layouts may leave some empty.
+func (s *PDFService) ExtractInvoice(ctx context.Context, req *ExtractInvoiceRequest) (_ *Invoice, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ExtractInvoice")
+	defer span.End()
+
+	op := metrics.Start("extract_invoice")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "extract_invoice")
+	defer cancel()
+
+	extractors, err := s.entityExtractors(append([]string{"invoice_number"}, req.Entities...))
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.StringSlice("entities", req.Entities))
+
+	s.log.Info("Extracting invoice fields from PDF", "entities", req.Entities)
+
+	if !s.config.PDF.OCREnabled {
+		return nil, NewError(ErrCodeToolUnavailable, "OCR is disabled on this server", nil)
+	}
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var result *ocrResult
+	err = s.runHeavy(ctx, func() error {
+		result, err = s.ocr(ctx, req.PDFData, pageCount, ocrOptions{engine: tesseractEngine})
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	entities, err := s.extractEntities(ctx, result.pages, extractors)
+	if err != nil {
+		return nil, err
+	}
+
+	invoice := parseInvoice(result.pages)
+	invoice.PageCount = pageCount
+	var words []OCRWord
+	for _, page := range result.pages {
+		words = append(words, page.Words...)
+	}
+	invoice.Confidence = meanConfidence(words)
+	for _, entity := range entities {
+		if entity.Type == "invoice_number" {
+			if invoice.InvoiceNumber == "" {
+				invoice.InvoiceNumber = entity.Value
+			}
+			continue
+		}
+		invoice.Entities = append(invoice.Entities, entity)
+	}
+
+	s.log.Info("Invoice extraction completed", "page_count", pageCount, "line_items", len(invoice.LineItems))
+
+	return invoice, nil
+}
+
+// parseInvoice finds invoice fields in OCR page texts. Totals take the last
+// labelled line, since item tables may repeat labels on earlier pages.
+func parseInvoice(pages []PageText) *Invoice {
+	invoice := &Invoice{LineItems: []LineItem{}}
+	for _, page := range pages {
+		for _, line := range strings.Split(page.Text, "\n") {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			if invoice.Vendor == "" && !notVendorRe.MatchString(line) {
+				invoice.Vendor = strings.TrimSpace(line)
+			}
+			if invoice.Currency == "" {
+				for _, currency := range currencies {
+					if currency.re.MatchString(line) {
+						invoice.Currency = currency.code
+						break
+					}
+				}
+			}
+
+			switch {
+			case subtotalRe.MatchString(line):
+				invoice.Subtotal = lastAmount(line)
+				continue
+			case taxRe.MatchString(line):
+				invoice.Tax = lastAmount(line)
+				continue
+			case totalRe.MatchString(line):
+				invoice.Total = lastAmount(line)
+				continue
+			}
+
+			if date := dateRe.FindString(line); date != "" {
+				switch {
+				case invoice.DueDate == "" && dueDateRe.MatchString(line):
+					invoice.DueDate = date
+				case invoice.InvoiceDate == "" && invoiceDateRe.MatchString(line):
+					invoice.InvoiceDate = date
+				}
+				continue
+			}
+
+			if match := lineItemRe.FindStringSubmatch(line); match != nil {
+				invoice.LineItems = append(invoice.LineItems, LineItem{
+					Description: strings.TrimSpace(match[1]),
+					Quantity:    parseQuantity(match[2]),
+					UnitPrice:   parseAmount(match[3]),
+					Amount:      parseAmount(match[4]),
+				})
+			}
+		}
+	}
+
+	// Receipts often state only a total, and unlabelled dates are usually
+	// the issue date
+	if invoice.Subtotal == 0 && invoice.Total != 0 {
+		invoice.Subtotal = invoice.Total - invoice.Tax
+	}
+	if invoice.InvoiceDate == "" {
+		for _, page := range pages {
+			if date := dateRe.FindString(page.Text); date != "" && date != invoice.DueDate {
+				invoice.InvoiceDate = date
+				break
+			}
+		}
+	}
+	return invoice
+}
+
+// lastAmount returns the last amount on a line, or 0 for none. Totals
+// lines may carry a rate first, as in "VAT 20% 40.00".
+func lastAmount(line string) float64 {
+	amounts := amountRe.FindAllString(line, -1)
+	if len(amounts) == 0 {
+		return 0
+	}
+	return parseAmount(amounts[len(amounts)-1])
+}
+
+// parseQuantity parses a quantity with a decimal point or comma
+func parseQuantity(s string) float64 {
+	value, _ := strconv.ParseFloat(strings.Replace(s, ",", ".", 1), 64)
+	return value
+}
+
+// parseAmount parses an amount as printed, with thousands separators and
+// either a decimal point or a decimal comma, ignoring currency symbols
+func parseAmount(s string) float64 {
+	s = strings.Map(func(r rune) rune {
+		if (r >= '0' && r <= '9') || r == '.' || r == ',' || r == '-' {
+			return r
+		}
+		return -1
+	}, s)
+	// The last separator followed by exactly two digits is the decimal one
+	if i := strings.LastIndexAny(s, ".,"); i >= 0 && len(s)-i == 3 {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s[:i]) + "." + s[i+1:]
+	} else {
+		s = strings.NewReplacer(".", "", ",", "").Replace(s)
+	}
+	value, _ := strconv.ParseFloat(s, 64)
+	return value
+}
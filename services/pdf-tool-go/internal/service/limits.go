@@ -0,0 +1,85 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/resources"
+)
+
+type contextKey string
+
+const maxPagesKey contextKey = "max_pages"
+
+// WithMaxPages returns a context carrying a per-request page limit that
+// replaces PDFConfig.MaxPages. Only set this for privileged callers.
+func WithMaxPages(ctx context.Context, maxPages int) context.Context {
+	return context.WithValue(ctx, maxPagesKey, maxPages)
+}
+
+// maxPages returns the page limit that applies to the request
+func (s *PDFService) maxPages(ctx context.Context) int {
+	if override, ok := ctx.Value(maxPagesKey).(int); ok && override > 0 {
+		return override
+	}
+	return s.config.PDF.MaxPages
+}
+
+// checkPageCount returns the page count of a document, rejecting it if the
+// count exceeds the page limit of the request. pdfcpu reads and validates
+// the whole document to count its pages, so this costs a full parse; it
+// runs before heavier work to fail oversized documents early.
+func (s *PDFService) checkPageCount(ctx context.Context, pdfData []byte) (int, error) {
+	var pageCount int
+	err := inSpan(ctx, "pdfcpu.page_count", func() error {
+		var err error
+		pageCount, err = api.PageCount(bytes.NewReader(pdfData), nil)
+		return err
+	})
+	if err != nil {
+		return 0, classifyPDFError(err, "failed to read page count")
+	}
+
+	if err := s.enforcePageLimit(ctx, pageCount); err != nil {
+		return 0, err
+	}
+
+	return pageCount, nil
+}
+
+// enforcePageLimit rejects page counts above the page limit
+func (s *PDFService) enforcePageLimit(ctx context.Context, pageCount int) error {
+	limit := s.maxPages(ctx)
+	if limit > 0 && pageCount > limit {
+		return NewError(ErrCodeTooManyPages, fmt.Sprintf("PDF has too many pages: %d (max %d)", pageCount, limit), nil)
+	}
+	return nil
+}
+
+// tempSpaceFactor estimates temp disk needed per input byte: the staged
+// input plus an output of comparable size, with headroom for pdfcpu
+const tempSpaceFactor = 3
+
+// checkResources verifies there is enough disk and memory headroom for an
+// operation on inputBytes of PDF data, waiting briefly for resources to
+// recover before rejecting the request
+func (s *PDFService) checkResources(ctx context.Context, inputBytes int64) error {
+	err := s.guard.Wait(ctx, inputBytes*tempSpaceFactor)
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, resources.ErrLowDisk) || errors.Is(err, resources.ErrHighMemory) {
+		s.log.Warn("Rejecting work due to resource guardrails", "error", err)
+		lowErr := NewError(ErrCodeLowResources, "insufficient server resources, retry later", err)
+		lowErr.RetryAfter = s.config.Guardrails.RetryAfter
+		return lowErr
+	}
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+	return err
+}
@@ -0,0 +1,280 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"html"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// LinkStatus is the outcome of requesting a link
+type LinkStatus struct {
+	StatusCode int    `json:",omitempty"`
+	Error      string `json:",omitempty"`
+	Dead       bool
+}
+
+// LinkChecker validates web links
+type LinkChecker interface {
+	// Check requests each of urls, returning their statuses by URL
+	Check(ctx context.Context, urls []string) map[string]LinkStatus
+}
+
+// SetLinkChecker sets the checker validating extracted links. It must be
+// called before the service handles requests.
+func (s *PDFService) SetLinkChecker(checker LinkChecker) {
+	s.links = checker
+}
+
+// Link is a URI link of a document
+type Link struct {
+	Page int
+	URI  string
+	// Text is the page text under the link. It is left empty on rotated
+	// pages and where Ghostscript is unavailable.
+	Text string `json:",omitempty"`
+	// Rect is the link area in PDF user space: llx, lly, urx, ury
+	Rect [4]float64
+	// Status is set for http and https links when links are validated
+	Status *LinkStatus `json:",omitempty"`
+}
+
+// ExtractLinksRequest lists the links of a document
+type ExtractLinksRequest struct {
+	PDFData []byte
+	// Validate requests every web link, flagging the dead ones
+	Validate bool
+}
+
+// ExtractLinksResponse lists the links of a document in page order
+type ExtractLinksResponse struct {
+	PageCount int
+	Links     []Link
+	// DeadLinks counts the links found dead by validation
+	DeadLinks int
+}
+
+// linkPage is a page of links with the geometry their text is read by
+type linkPage struct {
+	number int
+	node   pageNode
+	links  []int
+}
+
+// ExtractLinks lists the URI links of a document with the page text they
+// cover and, optionally, whether they still resolve. Links are requested
+// once per distinct URL.
+func (s *PDFService) ExtractLinks(ctx context.Context, req *ExtractLinksRequest) (_ *ExtractLinksResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ExtractLinks")
+	defer span.End()
+
+	op := metrics.Start("extract_links")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "extract_links")
+	defer cancel()
+
+	span.SetAttributes(attribute.Bool("validate", req.Validate))
+	if req.Validate && s.links == nil {
+		return nil, NewError(ErrCodeToolUnavailable, "link validation is not available on this server", nil)
+	}
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response := &ExtractLinksResponse{Links: []Link{}}
+	var pages []linkPage
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, req.PDFData)
+		if err != nil {
+			return err
+		}
+		tree, err := pageTree(ctx2)
+		if err != nil {
+			return err
+		}
+		response.PageCount = len(tree)
+		response.Links, pages = uriLinks(ctx2, tree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(response.PageCount)
+
+	if len(pages) > 0 {
+		var chars map[int][]pageChar
+		first, last := pages[0].number, pages[len(pages)-1].number
+		err = s.runHeavy(ctx, func() error {
+			chars, err = s.textPositions(ctx, req.PDFData, first, last)
+			return err
+		})
+		// Links are still listed where the text cannot be read
+		if CodeOf(err) == ErrCodeToolUnavailable {
+			s.log.Warn("Link text unavailable", "error", err)
+			err = nil
+		} else if err != nil {
+			return nil, err
+		}
+		for _, page := range pages {
+			if page.node.rotate != 0 {
+				continue
+			}
+			for _, i := range page.links {
+				response.Links[i].Text = anchorText(chars[page.number], response.Links[i].Rect, page.node.mediaBox)
+			}
+		}
+	}
+
+	if req.Validate {
+		if err := s.validateLinks(ctx, response); err != nil {
+			return nil, err
+		}
+	}
+	span.SetAttributes(attribute.Int("links", len(response.Links)), attribute.Int("dead_links", response.DeadLinks))
+
+	s.log.Info("Links extracted", "links", len(response.Links), "dead_links", response.DeadLinks, "validated", req.Validate)
+
+	return response, nil
+}
+
+// uriLinks lists the URI link annotations of the pages, and the pages
+// holding links
+func uriLinks(ctx2 *pdfcpu.Context, tree []pageNode) ([]Link, []linkPage) {
+	links := []Link{}
+	var pages []linkPage
+	for i, node := range tree {
+		page := linkPage{number: i + 1, node: node}
+		obj, _ := node.dict.Find("Annots")
+		obj, _ = ctx2.Dereference(obj)
+		annots, _ := obj.(pdfcpu.Array)
+		for _, entry := range annots {
+			annot, err := ctx2.DereferenceDict(entry)
+			if err != nil || annot == nil || annot["Subtype"] != pdfcpu.Name("Link") {
+				continue
+			}
+			action, ok := dictEntry(ctx2, annot, "A")
+			if !ok || action["S"] != pdfcpu.Name("URI") {
+				continue
+			}
+			uri, ok := textValue(ctx2, action["URI"])
+			if !ok || strings.TrimSpace(uri) == "" {
+				continue
+			}
+			rect, _ := rectangle(ctx2, annot, "Rect")
+			page.links = append(page.links, len(links))
+			links = append(links, Link{Page: page.number, URI: strings.TrimSpace(uri), Rect: rect})
+		}
+		if len(page.links) > 0 {
+			pages = append(pages, page)
+		}
+	}
+	return links, pages
+}
+
+// validateLinks requests the distinct web links of a response
+func (s *PDFService) validateLinks(ctx context.Context, response *ExtractLinksResponse) error {
+	var urls []string
+	seen := make(map[string]bool)
+	for _, link := range response.Links {
+		lower := strings.ToLower(link.URI)
+		if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") || seen[link.URI] {
+			continue
+		}
+		seen[link.URI] = true
+		urls = append(urls, link.URI)
+	}
+	if max := s.config.LinkCheck.MaxLinks; len(urls) > max {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("document has %d distinct web links; at most %d are validated", len(urls), max), nil)
+	}
+	if len(urls) == 0 {
+		return nil
+	}
+
+	statuses := s.links.Check(ctx, urls)
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	for i, link := range response.Links {
+		status, ok := statuses[link.URI]
+		if !ok {
+			continue
+		}
+		response.Links[i].Status = &status
+		if status.Dead {
+			response.DeadLinks++
+		}
+	}
+	return nil
+}
+
+// pageChar is a character of a page with its box in points from the top
+// left of the MediaBox
+type pageChar struct {
+	x0, y0, x1, y1 float64
+	c              string
+}
+
+// textPositionPattern matches a character of txtwrite's XML output
+var textPositionPattern = regexp.MustCompile(`<char bbox="(-?[\d.]+) (-?[\d.]+) (-?[\d.]+) (-?[\d.]+)" c="([^"]*)"`)
+
+// parseTextPositions reads the characters of txtwrite's XML output, whose
+// pages are numbered from first
+func parseTextPositions(data []byte, first int) map[int][]pageChar {
+	chars := make(map[int][]pageChar)
+	page := first - 1
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if strings.HasPrefix(strings.TrimSpace(line), "<page") {
+			page++
+			continue
+		}
+		m := textPositionPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		var box [4]float64
+		for i := range box {
+			box[i], _ = strconv.ParseFloat(m[i+1], 64)
+		}
+		chars[page] = append(chars[page], pageChar{x0: box[0], y0: box[1], x1: box[2], y1: box[3], c: html.UnescapeString(m[5])})
+	}
+	return chars
+}
+
+// anchorText joins the characters whose centres lie in a link's area,
+// adding spaces at gaps between words and lines
+func anchorText(chars []pageChar, rect, mediaBox [4]float64) string {
+	var b strings.Builder
+	var prev *pageChar
+	for i := range chars {
+		ch := &chars[i]
+		x := mediaBox[0] + (ch.x0+ch.x1)/2
+		y := mediaBox[3] - (ch.y0+ch.y1)/2
+		if x < rect[0] || x > rect[2] || y < rect[1] || y > rect[3] {
+			continue
+		}
+		if prev != nil {
+			height := ch.y1 - ch.y0
+			if ch.x0-prev.x1 > height*0.2 || ch.x0 < prev.x0 || math.Abs(ch.y0-prev.y0) > height/2 {
+				b.WriteByte(' ')
+			}
+		}
+		b.WriteString(ch.c)
+		prev = ch
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
@@ -0,0 +1,123 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MergeItem selects pages of one input for a merge manifest
+type MergeItem struct {
+	// File names the input, as given in MergeRequest.InputNames
+	File string `json:"file"`
+	// Pages selects pages in order, like "1-3,5" or "4-"; empty selects
+	// every page
+	Pages string `json:"pages"`
+	// Rotate turns the selected pages clockwise by a multiple of 90 degrees
+	Rotate int `json:"rotate"`
+}
+
+// manifestPages is the page order and rotations a manifest produces from
+// the appended inputs
+type manifestPages struct {
+	// order selects pages of the appended inputs
+	order []string
+	// rotations maps rotations to the output pages they apply to
+	rotations map[int][]string
+}
+
+// resolveManifest returns the index of the input each manifest item names
+func resolveManifest(items []MergeItem, names []string) ([]int, error) {
+	indexes := make([]int, len(items))
+	for i, item := range items {
+		indexes[i] = -1
+		for j, name := range names {
+			if name != item.File {
+				continue
+			}
+			if indexes[i] >= 0 {
+				return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("manifest item %d: input name %q is ambiguous", i, item.File), nil)
+			}
+			indexes[i] = j
+		}
+		if indexes[i] < 0 {
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("manifest item %d: no input named %q", i, item.File), nil)
+		}
+	}
+	return indexes, nil
+}
+
+// manifestOrder lays out the pages a manifest selects once the inputs are
+// appended in order, where indexes are the resolved inputs of items
+func manifestOrder(items []MergeItem, indexes []int, pageCounts []int, names []string) (*manifestPages, error) {
+	offsets := make([]int, len(pageCounts))
+	for i := 1; i < len(pageCounts); i++ {
+		offsets[i] = offsets[i-1] + pageCounts[i-1]
+	}
+
+	layout := &manifestPages{rotations: make(map[int][]string)}
+	for i, item := range items {
+		input := indexes[i]
+		if item.Rotate%90 != 0 {
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("manifest item %d: rotation must be a multiple of 90", i), nil)
+		}
+		pages, err := parsePageSelection(item.Pages, pageCounts[input])
+		if err != nil {
+			return nil, inputError(err, input, inputName(names, input))
+		}
+		rotation := (item.Rotate%360 + 360) % 360
+		for _, page := range pages {
+			layout.order = append(layout.order, strconv.Itoa(offsets[input]+page))
+			if rotation != 0 {
+				layout.rotations[rotation] = append(layout.rotations[rotation], strconv.Itoa(len(layout.order)))
+			}
+		}
+	}
+	return layout, nil
+}
+
+// parsePageSelection expands a comma-separated selection of pages and
+// ranges, such as "1-3,5,8-", into page numbers in the order given
+func parsePageSelection(selection string, pageCount int) ([]int, error) {
+	selection = strings.TrimSpace(selection)
+	if selection == "" || selection == "all" {
+		selection = "1-"
+	}
+
+	var pages []int
+	for _, part := range strings.Split(selection, ",") {
+		part = strings.TrimSpace(part)
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(strings.TrimSpace(first))
+		if err != nil {
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("invalid page selection %q", part), nil)
+		}
+		end := start
+		if isRange {
+			end = pageCount
+			if last = strings.TrimSpace(last); last != "" {
+				if end, err = strconv.Atoi(last); err != nil {
+					return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("invalid page selection %q", part), nil)
+				}
+			}
+		}
+		if start < 1 || end > pageCount || start > end {
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("page selection %q is outside pages 1-%d", part, pageCount), nil)
+		}
+		for page := start; page <= end; page++ {
+			pages = append(pages, page)
+		}
+	}
+	return pages, nil
+}
+
+// sortedRotations returns the rotations of a layout in increasing order
+func (m *manifestPages) sortedRotations() []int {
+	rotations := make([]int, 0, len(m.rotations))
+	for rotation := range m.rotations {
+		rotations = append(rotations, rotation)
+	}
+	sort.Ints(rotations)
+	return rotations
+}
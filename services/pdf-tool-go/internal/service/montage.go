@@ -0,0 +1,324 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+
+	"github.com/disintegration/imaging"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Contact sheet layout bounds and defaults
+const (
+	defaultSheetColumns = 4
+	maxSheetColumns     = 20
+	defaultThumbWidth   = 200
+	minThumbWidth       = 32
+	maxThumbWidth       = 1024
+	// sheetGap is the spacing around thumbnails, in pixels
+	sheetGap = 16
+	// maxSheetPixels bounds the size of a contact sheet, since it is
+	// composed in memory
+	maxSheetPixels = 64 << 20
+)
+
+// Long image bounds and defaults
+const (
+	defaultLongImageWidth = 1080
+	maxLongImageWidth     = 2048
+	maxLongImageGap       = 256
+	// maxLongImageHeight is the largest height JPEG can encode
+	maxLongImageHeight = 65535
+)
+
+// sheetBackground sets pages apart from the sheet behind them
+var sheetBackground = color.RGBA{R: 0xe0, G: 0xe0, B: 0xe0, A: 0xff}
+
+// ContactSheetRequest represents a contact sheet request
+type ContactSheetRequest struct {
+	PDFData []byte
+	// PageRange selects pages, e.g. "1-12"; empty selects every page
+	PageRange string
+	// Columns is the number of thumbnails per row; 0 uses the default
+	Columns int
+	// ThumbWidth is the width of each thumbnail in pixels; 0 uses the
+	// default
+	ThumbWidth int
+	// Format is png or jpeg
+	Format string
+}
+
+// ContactSheet renders pages as thumbnails laid out in a grid on a single
+// image, for reviewing a document at a glance
+func (s *PDFService) ContactSheet(ctx context.Context, req *ContactSheetRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ContactSheet")
+	defer span.End()
+
+	op := metrics.Start("contact_sheet")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "contact_sheet")
+	defer cancel()
+
+	columns, width := req.Columns, req.ThumbWidth
+	if columns == 0 {
+		columns = defaultSheetColumns
+	}
+	if width == 0 {
+		width = defaultThumbWidth
+	}
+	if columns < 1 || columns > maxSheetColumns {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("columns must be between 1 and %d", maxSheetColumns), nil)
+	}
+	if width < minThumbWidth || width > maxThumbWidth {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("thumbnail width must be between %d and %d", minThumbWidth, maxThumbWidth), nil)
+	}
+	if req.Format != "png" && req.Format != "jpeg" {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported contact sheet format %q; use png or jpeg", req.Format), nil)
+	}
+	span.SetAttributes(attribute.Int("columns", columns), attribute.Int("thumb_width", width))
+
+	s.log.Info("Rendering contact sheet", "columns", columns, "thumb_width", width, "format", req.Format)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePageSelection(req.PageRange, pageCount)
+	if err != nil {
+		return nil, err
+	}
+	pages = uniquePages(pages)
+	op.Pages(len(pages))
+
+	// Thumbnails are at most twice as tall as wide, which bounds the sheet
+	// size before anything is rendered
+	rows := (len(pages) + columns - 1) / columns
+	if estimated := (columns*(width+sheetGap) + sheetGap) * (rows*(2*width+sheetGap) + sheetGap); estimated > maxSheetPixels {
+		return nil, NewError(ErrCodeInvalidInput, "contact sheet would be too large; select fewer pages or smaller thumbnails", nil)
+	}
+
+	encoder, err := s.imageEncoder(&ConvertToImageRequest{Format: req.Format})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var sheet []byte
+	err = s.runHeavy(ctx, func() error {
+		thumbs, err := s.thumbnails(ctx, req.PDFData, pages, width)
+		if err != nil {
+			return err
+		}
+		sheet, err = encodeImage(layoutSheet(thumbs, columns, width), req.Format, encoder.Quality)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(sheet)))
+	s.log.Info("Contact sheet completed", "pages", len(pages), "size", len(sheet))
+
+	return sheet, nil
+}
+
+// LongImageRequest represents a request to stitch pages into one tall
+// image
+type LongImageRequest struct {
+	PDFData []byte
+	// PageRange selects pages, e.g. "1-12"; empty selects every page
+	PageRange string
+	// Width is the width of the image in pixels; 0 uses the default
+	Width int
+	// Gap is the spacing between pages in pixels
+	Gap int
+	// Format is png or jpeg
+	Format string
+}
+
+// LongImage renders pages at a common width and stitches them top to
+// bottom into a single image, for scrolling through a document on mobile
+func (s *PDFService) LongImage(ctx context.Context, req *LongImageRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.LongImage")
+	defer span.End()
+
+	op := metrics.Start("long_image")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "long_image")
+	defer cancel()
+
+	width := req.Width
+	if width == 0 {
+		width = defaultLongImageWidth
+	}
+	if width < minThumbWidth || width > maxLongImageWidth {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("width must be between %d and %d", minThumbWidth, maxLongImageWidth), nil)
+	}
+	if req.Gap < 0 || req.Gap > maxLongImageGap {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("gap must be between 0 and %d", maxLongImageGap), nil)
+	}
+	if req.Format != "png" && req.Format != "jpeg" {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported long image format %q; use png or jpeg", req.Format), nil)
+	}
+	span.SetAttributes(attribute.Int("width", width))
+
+	s.log.Info("Rendering long image", "width", width, "gap", req.Gap, "format", req.Format)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePageSelection(req.PageRange, pageCount)
+	if err != nil {
+		return nil, err
+	}
+	pages = uniquePages(pages)
+	op.Pages(len(pages))
+
+	// Pages are rarely shorter than they are wide, so a square per page
+	// rejects most oversized images before anything is rendered; the
+	// stitched size is checked again once pages are rendered
+	if err := checkLongImageSize(width, len(pages)*(width+req.Gap)-req.Gap); err != nil {
+		return nil, err
+	}
+
+	encoder, err := s.imageEncoder(&ConvertToImageRequest{Format: req.Format})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var long []byte
+	err = s.runHeavy(ctx, func() error {
+		thumbs, err := s.thumbnails(ctx, req.PDFData, pages, width)
+		if err != nil {
+			return err
+		}
+		if err := checkLongImageSize(width, stitchedHeight(thumbs, req.Gap)); err != nil {
+			return err
+		}
+		long, err = encodeImage(stitchPages(thumbs, width, req.Gap), req.Format, encoder.Quality)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(long)))
+	s.log.Info("Long image completed", "pages", len(pages), "size", len(long))
+
+	return long, nil
+}
+
+// thumbnails renders pages and scales them to fit width. Pages are
+// rendered at about twice the thumbnail resolution, for a letter or A4
+// page, so downscaling keeps text edges smooth.
+func (s *PDFService) thumbnails(ctx context.Context, pdfData []byte, pages []int, width int) ([]image.Image, error) {
+	dpi := width / 4
+	dpi = max(minRenderDPI, min(maxRenderDPI, dpi))
+
+	rendered, err := s.renderImages(ctx, pdfData, pages, "png", dpi, config.ImageEncoderConfig{})
+	if err != nil {
+		return nil, err
+	}
+	thumbs := make([]image.Image, len(rendered))
+	for i, data := range rendered {
+		if err := ctx.Err(); err != nil {
+			return nil, contextError(err)
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode page %d: %w", pages[i], err)
+		}
+		// Landscape pages are as wide as portrait ones; tall pages are
+		// capped at twice the width
+		thumbs[i] = imaging.Fit(img, width, 2*width, imaging.Lanczos)
+	}
+	return thumbs, nil
+}
+
+// layoutSheet places thumbnails in a grid of columns, each centred in a
+// cell as wide as width and as tall as the tallest thumbnail
+func layoutSheet(thumbs []image.Image, columns, width int) image.Image {
+	cellHeight := 0
+	for _, thumb := range thumbs {
+		cellHeight = max(cellHeight, thumb.Bounds().Dy())
+	}
+	columns = min(columns, len(thumbs))
+	rows := (len(thumbs) + columns - 1) / columns
+
+	sheet := imaging.New(columns*(width+sheetGap)+sheetGap, rows*(cellHeight+sheetGap)+sheetGap, sheetBackground)
+	for i, thumb := range thumbs {
+		size := thumb.Bounds().Size()
+		x := sheetGap + (i%columns)*(width+sheetGap) + (width-size.X)/2
+		y := sheetGap + (i/columns)*(cellHeight+sheetGap) + (cellHeight-size.Y)/2
+		sheet = imaging.Paste(sheet, thumb, image.Pt(x, y))
+	}
+	return sheet
+}
+
+// checkLongImageSize rejects long images too large to compose or encode
+func checkLongImageSize(width, height int) error {
+	if width*height > maxSheetPixels || height > maxLongImageHeight {
+		return NewError(ErrCodeInvalidInput, "long image would be too large; select fewer pages or a smaller width", nil)
+	}
+	return nil
+}
+
+// stitchedHeight returns the height of thumbnails stacked with gap
+// between them
+func stitchedHeight(thumbs []image.Image, gap int) int {
+	height := 0
+	for i, thumb := range thumbs {
+		if i > 0 {
+			height += gap
+		}
+		height += thumb.Bounds().Dy()
+	}
+	return height
+}
+
+// stitchPages stacks thumbnails top to bottom, each centred in a column
+// as wide as width, with gap between them
+func stitchPages(thumbs []image.Image, width, gap int) image.Image {
+	long := imaging.New(width, stitchedHeight(thumbs, gap), sheetBackground)
+	y := 0
+	for _, thumb := range thumbs {
+		size := thumb.Bounds().Size()
+		long = imaging.Paste(long, thumb, image.Pt((width-size.X)/2, y))
+		y += size.Y + gap
+	}
+	return long
+}
+
+// encodeImage encodes img as png, or as jpeg at quality
+func encodeImage(img image.Image, format string, quality int) ([]byte, error) {
+	var buf bytes.Buffer
+	var err error
+	if format == "jpeg" {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: quality})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s: %w", format, err)
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,314 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxOutlineItems bounds the outline walk, so a cyclic or hostile outline
+// cannot stall a check
+const maxOutlineItems = 100000
+
+// maxDestinationDepth bounds the chain of dictionaries and names a
+// destination is reached through
+const maxDestinationDepth = 8
+
+// Sources of the internal references checked by CheckNavigation
+const (
+	NavigationLink        = "link"
+	NavigationOutline     = "outline"
+	NavigationOpenAction  = "open_action"
+	NavigationDestination = "named_destination"
+)
+
+// BrokenReference is an internal reference that does not lead to a page of
+// the document
+type BrokenReference struct {
+	Source string
+	// Page is the page of a broken link
+	Page int `json:",omitempty"`
+	// Title is the title of a broken outline item
+	Title string `json:",omitempty"`
+	// Destination is the named destination referenced, if any
+	Destination string `json:",omitempty"`
+	Reason      string
+}
+
+// NavigationReport is the result of an internal navigation check
+type NavigationReport struct {
+	Valid     bool
+	PageCount int
+	// References counts the internal references checked
+	References int
+	Broken     []BrokenReference
+}
+
+// CheckNavigation verifies that the internal navigation of a document
+// leads to its pages: GoTo links, outline items, the open action and named
+// destinations. Merges and splits drop and renumber pages without updating
+// such references, so this finds the navigation they broke.
+func (s *PDFService) CheckNavigation(ctx context.Context, pdfData []byte) (_ *NavigationReport, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.CheckNavigation")
+	defer span.End()
+
+	op := metrics.Start("check_navigation")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "check_navigation")
+	defer cancel()
+
+	s.log.Info("Checking PDF navigation")
+
+	var report *NavigationReport
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		tree, err := pageTree(ctx2)
+		if err != nil {
+			return err
+		}
+		report = checkNavigation(ctx2, tree)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(report.PageCount)
+	span.SetAttributes(attribute.Int("references", report.References), attribute.Int("broken", len(report.Broken)))
+
+	s.log.Info("Navigation check completed", "references", report.References, "broken", len(report.Broken))
+
+	return report, nil
+}
+
+// navigationCheck resolves the internal references of a document
+type navigationCheck struct {
+	ctx2 *pdfcpu.Context
+	// pages maps the object numbers of pages to page numbers
+	pages     map[int]int
+	pageCount int
+	// named are the named destinations by name
+	named  map[string]pdfcpu.Object
+	report *NavigationReport
+}
+
+// checkNavigation checks the internal references of a document
+func checkNavigation(ctx2 *pdfcpu.Context, tree []pageNode) *NavigationReport {
+	c := &navigationCheck{
+		ctx2:      ctx2,
+		pages:     make(map[int]int, len(tree)),
+		pageCount: len(tree),
+		named:     namedDestinations(ctx2),
+		report:    &NavigationReport{PageCount: len(tree), Broken: []BrokenReference{}},
+	}
+	for i, page := range tree {
+		if page.objNr != 0 {
+			c.pages[page.objNr] = i + 1
+		}
+	}
+
+	names := make([]string, 0, len(c.named))
+	for name := range c.named {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		c.check(BrokenReference{Source: NavigationDestination, Destination: name}, c.explicit(c.named[name], 0))
+	}
+	for i, page := range tree {
+		obj, _ := page.dict.Find("Annots")
+		obj, _ = ctx2.Dereference(obj)
+		annots, _ := obj.(pdfcpu.Array)
+		for _, entry := range annots {
+			annot, err := ctx2.DereferenceDict(entry)
+			if err != nil || annot == nil || annot["Subtype"] != pdfcpu.Name("Link") {
+				continue
+			}
+			if dest, ok := linkDestination(ctx2, annot); ok {
+				c.reference(BrokenReference{Source: NavigationLink, Page: i + 1}, dest)
+			}
+		}
+	}
+	c.outline()
+	if open, ok := ctx2.RootDict.Find("OpenAction"); ok {
+		obj, _ := ctx2.Dereference(open)
+		if action, ok := obj.(pdfcpu.Dict); ok {
+			if dest, ok := gotoDestination(ctx2, action); ok {
+				c.reference(BrokenReference{Source: NavigationOpenAction}, dest)
+			}
+		} else {
+			c.reference(BrokenReference{Source: NavigationOpenAction}, open)
+		}
+	}
+
+	c.report.Valid = len(c.report.Broken) == 0
+	return c.report
+}
+
+// outline checks the destinations of the outline items
+func (c *navigationCheck) outline() {
+	outlines, ok := dictEntry(c.ctx2, c.ctx2.RootDict, "Outlines")
+	if !ok {
+		return
+	}
+	visited := make(map[int]bool)
+	var walk func(obj pdfcpu.Object)
+	walk = func(obj pdfcpu.Object) {
+		for obj != nil && len(visited) < maxOutlineItems {
+			if ref, ok := obj.(pdfcpu.IndirectRef); ok {
+				if visited[ref.ObjectNumber.Value()] {
+					return
+				}
+				visited[ref.ObjectNumber.Value()] = true
+			}
+			item, err := c.ctx2.DereferenceDict(obj)
+			if err != nil || item == nil {
+				return
+			}
+			title, _ := textValue(c.ctx2, item["Title"])
+			if dest, ok := item.Find("Dest"); ok {
+				c.reference(BrokenReference{Source: NavigationOutline, Title: title}, dest)
+			} else if action, ok := dictEntry(c.ctx2, item, "A"); ok {
+				if dest, ok := gotoDestination(c.ctx2, action); ok {
+					c.reference(BrokenReference{Source: NavigationOutline, Title: title}, dest)
+				}
+			}
+			if first, ok := item.Find("First"); ok {
+				walk(first)
+			}
+			obj, _ = item.Find("Next")
+		}
+	}
+	if first, ok := outlines.Find("First"); ok {
+		walk(first)
+	}
+}
+
+// reference checks a destination referenced from the document, explicit
+// or named
+func (c *navigationCheck) reference(ref BrokenReference, dest pdfcpu.Object) {
+	obj, _ := c.ctx2.Dereference(dest)
+	name, named := destinationName(c.ctx2, obj)
+	if !named {
+		c.check(ref, c.explicit(obj, 0))
+		return
+	}
+	ref.Destination = name
+	target, ok := c.named[name]
+	if !ok {
+		c.check(ref, fmt.Sprintf("named destination %q is not defined", name))
+		return
+	}
+	c.check(ref, c.explicit(target, 0))
+}
+
+// check counts a checked reference, recording it as broken with a reason
+func (c *navigationCheck) check(ref BrokenReference, reason string) {
+	c.report.References++
+	if reason != "" {
+		ref.Reason = reason
+		c.report.Broken = append(c.report.Broken, ref)
+	}
+}
+
+// explicit resolves an explicit destination, an array starting with the
+// target page, or a dictionary holding one in D. It returns why the
+// destination is broken, or "" when it leads to a page.
+func (c *navigationCheck) explicit(dest pdfcpu.Object, depth int) string {
+	if depth > maxDestinationDepth {
+		return "destination is nested too deeply"
+	}
+	obj, _ := c.ctx2.Dereference(dest)
+	switch d := obj.(type) {
+	case pdfcpu.Dict:
+		inner, ok := d.Find("D")
+		if !ok {
+			return "destination is malformed"
+		}
+		return c.explicit(inner, depth+1)
+	case pdfcpu.Array:
+		if len(d) == 0 {
+			return "destination is malformed"
+		}
+		switch page := d[0].(type) {
+		case pdfcpu.IndirectRef:
+			if _, ok := c.pages[page.ObjectNumber.Value()]; !ok {
+				return fmt.Sprintf("target object %d is not a page of the document", page.ObjectNumber.Value())
+			}
+			return ""
+		case pdfcpu.Integer:
+			// Page indexes belong in remote destinations, but some writers
+			// use them locally too
+			if int(page) < 0 || int(page) >= c.pageCount {
+				return fmt.Sprintf("target page %d is outside pages 1-%d", int(page)+1, c.pageCount)
+			}
+			return ""
+		}
+	}
+	return "destination is malformed"
+}
+
+// namedDestinations reads the named destinations of a document, from the
+// catalog's Dests dictionary and the Dests name tree
+func namedDestinations(ctx2 *pdfcpu.Context) map[string]pdfcpu.Object {
+	named := make(map[string]pdfcpu.Object)
+	if dests, ok := dictEntry(ctx2, ctx2.RootDict, "Dests"); ok {
+		for name, dest := range dests {
+			named[name] = dest
+		}
+	}
+	if names, ok := dictEntry(ctx2, ctx2.RootDict, "Names"); ok {
+		if tree, ok := dictEntry(ctx2, names, "Dests"); ok {
+			for _, entry := range nameTreeEntries(ctx2, tree, 0) {
+				named[entry.key] = entry.value
+			}
+		}
+	}
+	return named
+}
+
+// linkDestination returns the destination of an internal link: its Dest,
+// or the destination of its GoTo action
+func linkDestination(ctx2 *pdfcpu.Context, annot pdfcpu.Dict) (pdfcpu.Object, bool) {
+	if dest, ok := annot.Find("Dest"); ok {
+		return dest, true
+	}
+	action, ok := dictEntry(ctx2, annot, "A")
+	if !ok {
+		return nil, false
+	}
+	return gotoDestination(ctx2, action)
+}
+
+// gotoDestination returns the destination of a GoTo action
+func gotoDestination(ctx2 *pdfcpu.Context, action pdfcpu.Dict) (pdfcpu.Object, bool) {
+	if action["S"] != pdfcpu.Name("GoTo") {
+		return nil, false
+	}
+	dest, ok := action.Find("D")
+	if !ok {
+		// A GoTo action without a destination leads nowhere
+		return pdfcpu.Array{}, true
+	}
+	return dest, true
+}
+
+// destinationName returns the name of a named destination: a name or a
+// string
+func destinationName(ctx2 *pdfcpu.Context, obj pdfcpu.Object) (string, bool) {
+	switch name := obj.(type) {
+	case pdfcpu.Name:
+		return string(name), true
+	case pdfcpu.StringLiteral, pdfcpu.HexLiteral:
+		return textValue(ctx2, name)
+	}
+	return "", false
+}
@@ -0,0 +1,411 @@
+package service
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// tsvWordLevel is the level of word rows in tesseract TSV output
+const tsvWordLevel = "5"
+
+// tesseractEngine names tesseract in PageText.Engine
+const tesseractEngine = "tesseract"
+
+// pageSeparator joins page texts in ExtractTextResponse.Text
+const pageSeparator = "\n\n"
+
+// OCRFormat is the output format of OCR results
+type OCRFormat string
+
+const (
+	// OCRFormatJSON returns text and confidences as JSON; it is the default
+	OCRFormatJSON OCRFormat = "json"
+	// OCRFormatHOCR returns an hOCR (XHTML) document with word bounding boxes
+	OCRFormatHOCR OCRFormat = "hocr"
+	// OCRFormatALTO returns an ALTO XML document with word bounding boxes
+	OCRFormatALTO OCRFormat = "alto"
+)
+
+// ocrDocuments maps document formats to the tesseract config producing
+// them and the file it writes
+var ocrDocuments = map[OCRFormat]struct{ config, file string }{
+	OCRFormatHOCR: {"hocr", "ocr.hocr"},
+	OCRFormatALTO: {"alto", "ocr.xml"},
+}
+
+// document reports whether f is returned as a document rather than JSON
+func (f OCRFormat) document() bool {
+	_, ok := ocrDocuments[f]
+	return ok
+}
+
+// ContentType returns the media type of documents in format f
+func (f OCRFormat) ContentType() string {
+	if f == OCRFormatHOCR {
+		return "application/xhtml+xml"
+	}
+	return "application/xml"
+}
+
+// Extension returns the file extension of documents in format f
+func (f OCRFormat) Extension() string {
+	if f == OCRFormatHOCR {
+		return ".hocr"
+	}
+	return ".xml"
+}
+
+// validate rejects unknown formats and documents combined with a
+// searchable PDF, since only JSON responses can reference the stored PDF
+func (f OCRFormat) validate(searchable bool) error {
+	if f != "" && f != OCRFormatJSON && !f.document() {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported format %q; use json, hocr or alto", f), nil)
+	}
+	if searchable && f.document() {
+		return NewError(ErrCodeInvalidInput, "searchable output is only available with the json format", nil)
+	}
+	return nil
+}
+
+// OCRWord is a word recognised by OCR. The bounding box is in pixels of
+// the page rendered at the configured OCR resolution.
+type OCRWord struct {
+	Text string
+	// Confidence is tesseract's confidence from 0 to 100
+	Confidence float64
+	Left       int
+	Top        int
+	Width      int
+	Height     int
+}
+
+// ocrResult is the output of an OCR run
+type ocrResult struct {
+	pages []PageText
+	// searchable is the PDF of the page images with an invisible text
+	// layer, when requested
+	searchable []byte
+	// document is the hOCR or ALTO document, when requested
+	document []byte
+}
+
+// ocrOptions selects the outputs of an OCR run
+type ocrOptions struct {
+	searchable bool
+	format     OCRFormat
+	// recognizer, when set, reads pages as handwriting; pages it cannot
+	// read fall back to tesseract. It is not combined with searchable or
+	// document output, which tesseract produces.
+	recognizer Recognizer
+	engine     string
+}
+
+// ocr renders every page and recognises its text. Pages go through a single
+// tesseract run, which can also write a searchable PDF or an hOCR or ALTO
+// document, unless an ICR backend reads them first.
+func (s *PDFService) ocr(ctx context.Context, pdfData []byte, pageCount int, opts ocrOptions) (*ocrResult, error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ocr")
+	defer span.End()
+	span.SetAttributes(
+		attribute.Bool("searchable", opts.searchable),
+		attribute.Int("pages", pageCount),
+		attribute.String("engine", opts.engine),
+	)
+	document, hasDocument := ocrDocuments[opts.format]
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	// Grayscale is enough to recognise text; a searchable PDF keeps colour
+	// since the rendered pages replace the originals
+	device := "pnggray"
+	if opts.searchable {
+		device = "png16m"
+	}
+	if err := s.renderPages(ctx, ws, device); err != nil {
+		return nil, err
+	}
+	dpi := s.config.PDF.OCRDPI
+
+	result := &ocrResult{pages: make([]PageText, pageCount)}
+	var fallback []int
+	for page := 1; page <= pageCount; page++ {
+		if opts.recognizer == nil {
+			fallback = append(fallback, page)
+			continue
+		}
+		image, err := ws.ReadFile(pageImage(page))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read rendered page: %w", err)
+		}
+		words, err := opts.recognizer.Recognize(ctx, image, dpi)
+		if err != nil {
+			return nil, recognizerError(ctx, err, opts.engine)
+		}
+		if len(words) == 0 {
+			fallback = append(fallback, page)
+			continue
+		}
+		result.pages[page-1] = pageFromWords(page, words, opts.engine)
+	}
+	if len(fallback) == 0 {
+		return result, nil
+	}
+
+	outputs := []string{"tsv"}
+	if opts.searchable {
+		outputs = append(outputs, "pdf")
+	}
+	if hasDocument {
+		outputs = append(outputs, document.config)
+	}
+	if err := s.tesseract(ctx, ws, fallback, outputs...); err != nil {
+		return nil, err
+	}
+
+	err = inSpan(ctx, "tempfile.read", func() error {
+		tsv, err := ws.ReadFile("ocr.tsv")
+		if err != nil {
+			return err
+		}
+		pages, err := parseTSV(tsv, len(fallback))
+		if err != nil {
+			return err
+		}
+		for i, page := range pages {
+			page.PageNumber = fallback[i]
+			result.pages[fallback[i]-1] = page
+		}
+		if opts.searchable {
+			if result.searchable, err = ws.ReadFile("ocr.pdf"); err != nil {
+				return err
+			}
+		}
+		if hasDocument {
+			result.document, err = ws.ReadFile(document.file)
+		}
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	return result, nil
+}
+
+// renderPages renders input.pdf in ws to one PNG per page, named by
+// pageImage, at the configured OCR resolution
+func (s *PDFService) renderPages(ctx context.Context, ws *exec.Workspace, device string) error {
+	_, err := s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=" + device,
+			fmt.Sprintf("-r%d", s.config.PDF.OCRDPI),
+			"-dTextAlphaBits=4",
+			"-dGraphicsAlphaBits=4",
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=page-%04d.png",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return toolError(ctx, err, "gs")
+	}
+	return nil
+}
+
+// tesseract recognises the rendered pages in a single run, writing each
+// output config (tsv, pdf, hocr, alto) to ocr.<ext> in ws. Output pages are
+// numbered from 1 in the order of pages.
+func (s *PDFService) tesseract(ctx context.Context, ws *exec.Workspace, pages []int, outputs ...string) error {
+	var list strings.Builder
+	for _, page := range pages {
+		fmt.Fprintln(&list, pageImage(page))
+	}
+	if _, err := ws.WriteFile("pages.txt", []byte(list.String())); err != nil {
+		return err
+	}
+
+	args := []string{"pages.txt", "ocr", "--dpi", strconv.Itoa(s.config.PDF.OCRDPI)}
+	languages := s.config.PDF.OCRLanguages
+	if override := tenant.SettingsFromContext(ctx).Overrides.OCRLanguages; len(override) > 0 {
+		languages = override
+	}
+	if len(languages) > 0 {
+		args = append(args, "-l", strings.Join(languages, "+"))
+	}
+	args = append(args, outputs...)
+	if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "tesseract", Args: args}); err != nil {
+		return toolError(ctx, err, "tesseract")
+	}
+	return nil
+}
+
+// pageImage returns the file name of a rendered page
+func pageImage(page int) string {
+	return fmt.Sprintf("page-%04d.png", page)
+}
+
+// pageFromWords builds the text of a page read by an ICR backend. Backends
+// report no lines, so a word starting below the previous one starts a new
+// line.
+func pageFromWords(page int, words []OCRWord, engine string) PageText {
+	var text strings.Builder
+	for i, word := range words {
+		if i > 0 {
+			if prev := words[i-1]; word.Top >= prev.Top+prev.Height {
+				text.WriteString("\n")
+			} else {
+				text.WriteString(" ")
+			}
+		}
+		text.WriteString(word.Text)
+	}
+	return PageText{
+		PageNumber: page,
+		Text:       text.String(),
+		Confidence: meanConfidence(words),
+		Words:      words,
+		Engine:     engine,
+	}
+}
+
+// recognizerError maps an ICR backend failure onto a coded error. Coded
+// errors from the backend are kept.
+func recognizerError(ctx context.Context, err error, engine string) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return contextError(ctxErr)
+	}
+	var pdfErr *PDFError
+	if errors.As(err, &pdfErr) {
+		return err
+	}
+	if busy := unavailableError(err, "ICR backend "+engine); busy != nil {
+		return busy
+	}
+	if errors.Is(err, exec.ErrToolNotAllowed) || errors.Is(err, exec.ErrToolNotFound) {
+		return NewError(ErrCodeToolUnavailable, fmt.Sprintf("ICR backend %s is not available on this server", engine), err)
+	}
+	return fmt.Errorf("ICR backend %s failed: %w", engine, err)
+}
+
+// parseTSV builds page texts from tesseract TSV output. Words on a line are
+// joined by spaces and lines by newlines. A page's confidence is the mean
+// confidence of its words.
+func parseTSV(tsv []byte, pageCount int) ([]PageText, error) {
+	pages := make([]PageText, pageCount)
+	lines := make([]string, pageCount)
+	for i := range pages {
+		pages[i].PageNumber = i + 1
+		pages[i].Engine = tesseractEngine
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(tsv))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		// level page block paragraph line word left top width height conf text
+		fields := strings.SplitN(scanner.Text(), "\t", 12)
+		if len(fields) < 12 || fields[0] != tsvWordLevel {
+			continue
+		}
+		text := strings.TrimSpace(fields[11])
+		if text == "" {
+			continue
+		}
+		page, err := strconv.Atoi(fields[1])
+		if err != nil || page < 1 || page > pageCount {
+			return nil, fmt.Errorf("invalid page number %q in tesseract output", fields[1])
+		}
+		word := OCRWord{Text: text}
+		word.Left, _ = strconv.Atoi(fields[6])
+		word.Top, _ = strconv.Atoi(fields[7])
+		word.Width, _ = strconv.Atoi(fields[8])
+		word.Height, _ = strconv.Atoi(fields[9])
+		word.Confidence, _ = strconv.ParseFloat(fields[10], 64)
+
+		p := &pages[page-1]
+		line := strings.Join(fields[2:5], ".")
+		switch {
+		case len(p.Words) == 0:
+		case line != lines[page-1]:
+			p.Text += "\n"
+		default:
+			p.Text += " "
+		}
+		lines[page-1] = line
+		p.Text += text
+		p.Words = append(p.Words, word)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range pages {
+		pages[i].Confidence = meanConfidence(pages[i].Words)
+	}
+	return pages, nil
+}
+
+// meanConfidence returns the mean confidence of words, or 0 for none
+func meanConfidence(words []OCRWord) float64 {
+	if len(words) == 0 {
+		return 0
+	}
+	var sum float64
+	for _, word := range words {
+		sum += word.Confidence
+	}
+	return sum / float64(len(words))
+}
+
+// applyOCR fills response from an OCR result. Words are dropped unless
+// requested, since they are many times the size of the text.
+func (s *PDFService) applyOCR(response *ExtractTextResponse, result *ocrResult, words bool) {
+	var (
+		all   []OCRWord
+		texts = make([]string, len(result.pages))
+	)
+	for i, page := range result.pages {
+		texts[i] = page.Text
+		all = append(all, page.Words...)
+		// Blank pages have no confidence to judge
+		if len(page.Words) > 0 {
+			metrics.OCRPageConfidence(page.Confidence)
+			if page.Confidence < s.config.PDF.OCRLowConfidence {
+				response.LowConfidencePages = append(response.LowConfidencePages, page.PageNumber)
+			}
+		}
+		if !words {
+			result.pages[i].Words = nil
+		}
+	}
+
+	response.Text = strings.Join(texts, pageSeparator)
+	response.Pages = result.pages
+	response.Confidence = meanConfidence(all)
+	response.SearchablePDF = result.searchable
+	response.Document = result.document
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// OutlineText converts all text of a document to vector paths, so it
+// renders identically without any fonts, as packaging and large format
+// print workflows require. The text can no longer be selected, searched or
+// extracted, and invisible text such as OCR layers is dropped.
+func (s *PDFService) OutlineText(ctx context.Context, pdfData []byte) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.OutlineText")
+	defer span.End()
+
+	op := metrics.Start("outline_text")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "outline_text")
+	defer cancel()
+
+	s.log.Info("Converting PDF text to outlines")
+
+	pageCount, err := s.checkPageCount(ctx, pdfData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(pdfData))); err != nil {
+		return nil, err
+	}
+
+	var outlined []byte
+	err = s.runHeavy(ctx, func() error {
+		outlined, err = s.outlineText(ctx, pdfData)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(outlined)))
+	s.log.Info("Text outlining completed", "pages", pageCount, "size", len(outlined))
+
+	return outlined, nil
+}
+
+// outlineText rewrites a document through Ghostscript's pdfwrite device
+// without fonts, which draws each glyph as a path instead
+func (s *PDFService) outlineText(ctx context.Context, pdfData []byte) ([]byte, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=pdfwrite",
+			"-dNoOutputFonts",
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=output.pdf",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
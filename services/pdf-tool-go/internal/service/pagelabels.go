@@ -0,0 +1,286 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PageLabelStyle is the numbering style of a page label range
+type PageLabelStyle string
+
+const (
+	// LabelDecimal numbers pages 1, 2, 3
+	LabelDecimal PageLabelStyle = "decimal"
+	// LabelUpperRoman numbers pages I, II, III
+	LabelUpperRoman PageLabelStyle = "upper-roman"
+	// LabelLowerRoman numbers pages i, ii, iii
+	LabelLowerRoman PageLabelStyle = "lower-roman"
+	// LabelUpperAlpha numbers pages A to Z, then AA to ZZ
+	LabelUpperAlpha PageLabelStyle = "upper-alpha"
+	// LabelLowerAlpha numbers pages a to z, then aa to zz
+	LabelLowerAlpha PageLabelStyle = "lower-alpha"
+	// LabelNone labels pages with the prefix alone
+	LabelNone PageLabelStyle = ""
+)
+
+// labelStyles are the PDF names of the numbering styles
+var labelStyles = map[PageLabelStyle]pdfcpu.Name{
+	LabelDecimal:    "D",
+	LabelUpperRoman: "R",
+	LabelLowerRoman: "r",
+	LabelUpperAlpha: "A",
+	LabelLowerAlpha: "a",
+}
+
+// PageLabelRange labels the pages from its first page up to the next
+// range, e.g. a lower-roman range at page 1 and a decimal range at page 4
+// label pages i, ii, iii, 1, 2
+type PageLabelRange struct {
+	// Page is the first page of the range, counted from 1
+	Page   int            `json:"page"`
+	Style  PageLabelStyle `json:"style,omitempty"`
+	Prefix string         `json:"prefix,omitempty"`
+	// Start is the number of the range's first page; 0 starts at 1
+	Start int `json:"start,omitempty"`
+}
+
+// SetPageLabelsRequest represents a page labelling request
+type SetPageLabelsRequest struct {
+	PDFData []byte
+	// Labels are the label ranges in page order, the first starting at
+	// page 1. Empty removes the document's labels.
+	Labels []PageLabelRange
+}
+
+// SetPageLabels replaces the page labels of a document, which viewers show
+// in place of page numbers
+func (s *PDFService) SetPageLabels(ctx context.Context, req *SetPageLabelsRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.SetPageLabels")
+	defer span.End()
+
+	op := metrics.Start("set_page_labels")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "set_page_labels")
+	defer cancel()
+
+	span.SetAttributes(attribute.Int("ranges", len(req.Labels)))
+	s.log.Info("Setting PDF page labels", "ranges", len(req.Labels))
+
+	if err := validatePageLabels(req.Labels); err != nil {
+		return nil, err
+	}
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	labeled, err := s.writePageLabels(ctx, req.PDFData, req.Labels)
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(labeled)))
+	s.log.Info("Page labels set", "ranges", len(req.Labels), "size", len(labeled))
+
+	return labeled, nil
+}
+
+// writePageLabels rewrites a document with its page labels replaced by
+// ranges, which must be valid
+func (s *PDFService) writePageLabels(ctx context.Context, pdfData []byte, ranges []PageLabelRange) ([]byte, error) {
+	var labeled []byte
+	err := s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		if n := len(ranges); n > 0 && ranges[n-1].Page > ctx2.PageCount {
+			return NewError(ErrCodeInvalidInput, fmt.Sprintf("page label range starts at page %d, past the last page %d", ranges[n-1].Page, ctx2.PageCount), nil)
+		}
+		setPageLabels(ctx2, ranges)
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		labeled = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return labeled, nil
+}
+
+// validatePageLabels checks label ranges are in page order, the first
+// starting at page 1 as PDF requires, with known styles
+func validatePageLabels(ranges []PageLabelRange) error {
+	for i, r := range ranges {
+		switch {
+		case i == 0 && r.Page != 1:
+			return NewError(ErrCodeInvalidInput, "the first page label range must start at page 1", nil)
+		case i > 0 && r.Page <= ranges[i-1].Page:
+			return NewError(ErrCodeInvalidInput, fmt.Sprintf("page label range at page %d is out of order", r.Page), nil)
+		case r.Style != LabelNone && labelStyles[r.Style] == "":
+			return NewError(ErrCodeInvalidInput, fmt.Sprintf("unknown page label style %q; use decimal, upper-roman, lower-roman, upper-alpha or lower-alpha", r.Style), nil)
+		case r.Start < 0:
+			return NewError(ErrCodeInvalidInput, fmt.Sprintf("page label range at page %d: start must be positive", r.Page), nil)
+		}
+	}
+	return nil
+}
+
+// setPageLabels replaces the page labels number tree of the catalog, or
+// removes it for no ranges
+func setPageLabels(ctx2 *pdfcpu.Context, ranges []PageLabelRange) {
+	if len(ranges) == 0 {
+		delete(ctx2.RootDict, "PageLabels")
+		return
+	}
+	nums := make(pdfcpu.Array, 0, 2*len(ranges))
+	for _, r := range ranges {
+		label := pdfcpu.Dict{}
+		if r.Style != LabelNone {
+			label["S"] = labelStyles[r.Style]
+		}
+		if r.Prefix != "" {
+			label["P"] = textString(r.Prefix)
+		}
+		if r.Start > 1 {
+			label["St"] = pdfcpu.Integer(r.Start)
+		}
+		nums = append(nums, pdfcpu.Integer(r.Page-1), label)
+	}
+	ctx2.RootDict["PageLabels"] = pdfcpu.Dict{"Nums": nums}
+}
+
+// readPageLabels returns the label ranges of the catalog's page labels
+// number tree in page order
+func readPageLabels(ctx2 *pdfcpu.Context) []PageLabelRange {
+	root, ok := dictEntry(ctx2, ctx2.RootDict, "PageLabels")
+	if !ok {
+		return nil
+	}
+	var ranges []PageLabelRange
+	var visit func(node pdfcpu.Dict, depth int)
+	visit = func(node pdfcpu.Dict, depth int) {
+		if depth > maxPageTreeDepth {
+			return
+		}
+		obj, _ := ctx2.Dereference(node["Nums"])
+		nums, _ := obj.(pdfcpu.Array)
+		for i := 0; i+1 < len(nums); i += 2 {
+			index, ok := numberValue(ctx2, nums[i])
+			label, err := ctx2.DereferenceDict(nums[i+1])
+			if !ok || index < 0 || err != nil || label == nil {
+				continue
+			}
+			r := PageLabelRange{Page: int(index) + 1}
+			if style, ok := label["S"].(pdfcpu.Name); ok {
+				for name, value := range labelStyles {
+					if value == style {
+						r.Style = name
+					}
+				}
+			}
+			r.Prefix, _ = textValue(ctx2, label["P"])
+			if start, ok := numberValue(ctx2, label["St"]); ok && start > 1 {
+				r.Start = int(start)
+			}
+			ranges = append(ranges, r)
+		}
+		obj, _ = ctx2.Dereference(node["Kids"])
+		kids, _ := obj.(pdfcpu.Array)
+		for _, kid := range kids {
+			if child, err := ctx2.DereferenceDict(kid); err == nil && child != nil {
+				visit(child, depth+1)
+			}
+		}
+	}
+	visit(root, 0)
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].Page < ranges[j].Page })
+	return ranges
+}
+
+// pageLabels returns the label of each page. Pages before the first range,
+// which valid documents do not have, are labelled with their number.
+func pageLabels(ranges []PageLabelRange, pageCount int) []string {
+	if len(ranges) == 0 {
+		return nil
+	}
+	labels := make([]string, pageCount)
+	next := 0
+	var current *PageLabelRange
+	for page := 1; page <= pageCount; page++ {
+		for next < len(ranges) && ranges[next].Page <= page {
+			current = &ranges[next]
+			next++
+		}
+		if current == nil {
+			labels[page-1] = strconv.Itoa(page)
+			continue
+		}
+		start := current.Start
+		if start == 0 {
+			start = 1
+		}
+		labels[page-1] = current.Prefix + formatLabel(current.Style, start+page-current.Page)
+	}
+	return labels
+}
+
+// romanNumerals are the values of roman numerals, subtractive pairs
+// included, in descending order
+var romanNumerals = []struct {
+	value   int
+	numeral string
+}{
+	{1000, "M"}, {900, "CM"}, {500, "D"}, {400, "CD"}, {100, "C"}, {90, "XC"},
+	{50, "L"}, {40, "XL"}, {10, "X"}, {9, "IX"}, {5, "V"}, {4, "IV"}, {1, "I"},
+}
+
+// formatLabel formats the number n of a page in a numbering style
+func formatLabel(style PageLabelStyle, n int) string {
+	switch style {
+	case LabelDecimal:
+		return strconv.Itoa(n)
+	case LabelUpperRoman, LabelLowerRoman:
+		var b strings.Builder
+		for _, r := range romanNumerals {
+			for ; n >= r.value; n -= r.value {
+				b.WriteString(r.numeral)
+			}
+		}
+		if style == LabelLowerRoman {
+			return strings.ToLower(b.String())
+		}
+		return b.String()
+	case LabelUpperAlpha, LabelLowerAlpha:
+		letter := byte('A')
+		if style == LabelLowerAlpha {
+			letter = 'a'
+		}
+		return strings.Repeat(string(letter+byte((n-1)%26)), (n-1)/26+1)
+	}
+	return ""
+}
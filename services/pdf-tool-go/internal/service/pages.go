@@ -0,0 +1,120 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Page render resolution bounds
+const (
+	minRenderDPI = 36
+	maxRenderDPI = 600
+)
+
+// PageFormats maps single-page output formats to their content types
+var PageFormats = map[string]string{
+	"pdf":  "application/pdf",
+	"png":  "image/png",
+	"jpeg": "image/jpeg",
+}
+
+// PageRequest requests a single page of a document
+type PageRequest struct {
+	PDFData []byte
+	Page    int    // 1-based
+	Format  string // pdf, png, jpeg
+	DPI     int    // image formats only
+}
+
+// CheckPage verifies that a page request is valid and its page exists,
+// without extracting or rendering the page, e.g. before answering a
+// conditional request for it
+func (s *PDFService) CheckPage(ctx context.Context, req *PageRequest) error {
+	ctx, span := tracer.Start(ctx, "PDFService.CheckPage")
+	defer span.End()
+
+	ctx, cancel := s.withTimeout(ctx, "page")
+	defer cancel()
+
+	return s.checkPage(ctx, req)
+}
+
+// checkPage validates the format and resolution of a page request and
+// that the document has the page
+func (s *PDFService) checkPage(ctx context.Context, req *PageRequest) error {
+	if _, ok := PageFormats[req.Format]; !ok {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported page format %q", req.Format), nil)
+	}
+	if req.Format != "pdf" && (req.DPI < minRenderDPI || req.DPI > maxRenderDPI) {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("dpi must be between %d and %d", minRenderDPI, maxRenderDPI), nil)
+	}
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return err
+	}
+	if req.Page < 1 || req.Page > pageCount {
+		return NewError(ErrCodeNotFound, fmt.Sprintf("page %d does not exist, document has %d pages", req.Page, pageCount), nil)
+	}
+	return nil
+}
+
+// GetPage returns one page of a document, either as a single-page PDF or
+// rendered to an image
+func (s *PDFService) GetPage(ctx context.Context, req *PageRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.GetPage")
+	defer span.End()
+
+	op := metrics.Start("page")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "page")
+	defer cancel()
+
+	span.SetAttributes(
+		attribute.Int("page", req.Page),
+		attribute.String("format", req.Format),
+	)
+
+	if err := s.checkPage(ctx, req); err != nil {
+		return nil, err
+	}
+	op.Pages(1)
+
+	var page []byte
+	if req.Format == "pdf" {
+		err = s.runCancellable(ctx, func() error {
+			var buf bytes.Buffer
+			err := inSpan(ctx, "pdfcpu.trim", func() error {
+				return api.Trim(bytes.NewReader(req.PDFData), &buf, []string{strconv.Itoa(req.Page)}, nil)
+			})
+			if err != nil {
+				return classifyPDFError(err, "failed to extract page")
+			}
+			page = buf.Bytes()
+			return nil
+		})
+	} else {
+		if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+			return nil, err
+		}
+		err = s.runHeavy(ctx, func() error {
+			rendered, err := s.renderWithGhostscript(ctx, req.PDFData, req.Page, req.Format, req.DPI)
+			page = rendered
+			return err
+		})
+	}
+	if err != nil {
+		return nil, err
+	}
+	op.Output(int64(len(page)))
+
+	return page, nil
+}
@@ -0,0 +1,127 @@
+package service
+
+import (
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+)
+
+// maxPageTreeDepth bounds the page tree walk against malformed trees
+const maxPageTreeDepth = 64
+
+// pageNode is a page of a document with the attributes it inherits
+type pageNode struct {
+	dict pdfcpu.Dict
+	// objNr is the object number of the page, 0 for a direct page object
+	objNr int
+	// mediaBox is the page's llx, lly, urx, ury
+	mediaBox [4]float64
+	// rotate is the clockwise page rotation, one of 0, 90, 180 and 270
+	rotate int
+}
+
+// pageTree lists the pages of a document in order, resolving the MediaBox
+// and Rotate they inherit from the page tree. Pages reached twice are
+// listed once.
+func pageTree(ctx2 *pdfcpu.Context) ([]pageNode, error) {
+	root, ok := dictEntry(ctx2, ctx2.RootDict, "Pages")
+	if !ok {
+		return nil, NewError(ErrCodeCorrupted, "document has no page tree", nil)
+	}
+
+	var pages []pageNode
+	visited := make(map[int]bool)
+	var walk func(node pdfcpu.Dict, inherited pageNode, depth int) error
+	walk = func(node pdfcpu.Dict, inherited pageNode, depth int) error {
+		if depth > maxPageTreeDepth {
+			return NewError(ErrCodeCorrupted, "page tree is too deep", nil)
+		}
+		if box, ok := rectangle(ctx2, node, "MediaBox"); ok {
+			inherited.mediaBox = box
+		}
+		if rotate, ok := number(ctx2, node, "Rotate"); ok {
+			inherited.rotate = (int(rotate)%360 + 360) % 360
+		}
+
+		obj, isNode := node.Find("Kids")
+		if !isNode || node["Type"] == pdfcpu.Name("Page") {
+			inherited.dict = node
+			pages = append(pages, inherited)
+			return nil
+		}
+		obj, _ = ctx2.Dereference(obj)
+		kids, _ := obj.(pdfcpu.Array)
+		for _, kid := range kids {
+			objNr := 0
+			if ref, ok := kid.(pdfcpu.IndirectRef); ok {
+				objNr = ref.ObjectNumber.Value()
+				if visited[objNr] {
+					continue
+				}
+				visited[objNr] = true
+			}
+			child, err := ctx2.DereferenceDict(kid)
+			if err != nil || child == nil {
+				continue
+			}
+			inherited.objNr = objNr
+			if err := walk(child, inherited, depth+1); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(root, pageNode{mediaBox: [4]float64{0, 0, 612, 792}}, 0); err != nil {
+		return nil, err
+	}
+	return pages, nil
+}
+
+// number reads a numeric entry of a dictionary
+func number(ctx2 *pdfcpu.Context, dict pdfcpu.Dict, key string) (float64, bool) {
+	obj, ok := dict.Find(key)
+	if !ok {
+		return 0, false
+	}
+	return numberValue(ctx2, obj)
+}
+
+// numberValue reads an integer or real object
+func numberValue(ctx2 *pdfcpu.Context, obj pdfcpu.Object) (float64, bool) {
+	obj, err := ctx2.Dereference(obj)
+	if err != nil {
+		return 0, false
+	}
+	switch n := obj.(type) {
+	case pdfcpu.Integer:
+		return float64(n), true
+	case pdfcpu.Float:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+// rectangle reads a rectangle entry of a dictionary, normalized so the
+// lower left corner comes first
+func rectangle(ctx2 *pdfcpu.Context, dict pdfcpu.Dict, key string) ([4]float64, bool) {
+	var rect [4]float64
+	obj, ok := dict.Find(key)
+	if !ok {
+		return rect, false
+	}
+	obj, _ = ctx2.Dereference(obj)
+	values, _ := obj.(pdfcpu.Array)
+	if len(values) != 4 {
+		return rect, false
+	}
+	for i, value := range values {
+		if rect[i], ok = numberValue(ctx2, value); !ok {
+			return rect, false
+		}
+	}
+	if rect[0] > rect[2] {
+		rect[0], rect[2] = rect[2], rect[0]
+	}
+	if rect[1] > rect[3] {
+		rect[1], rect[3] = rect[3], rect[1]
+	}
+	return rect, true
+}
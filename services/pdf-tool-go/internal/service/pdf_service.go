@@ -14,54 +14,200 @@ import (
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"sync/atomic"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/pdfcpu/pdfcpu/pkg/api"
 	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/concurrency"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lockout"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/pipeline"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/provenance"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/resources"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/scripting"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/shedding"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/processor"
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var tracer = otel.Tracer("pdf-service")
 
+// supportedVersions lists the PDF header versions pdfcpu can process
+var supportedVersions = map[string]bool{
+	"1.0": true,
+	"1.1": true,
+	"1.2": true,
+	"1.3": true,
+	"1.4": true,
+	"1.5": true,
+	"1.6": true,
+	"1.7": true,
+	"2.0": true,
+}
+
 // PDFService handles all PDF operations
 type PDFService struct {
-	log    logger.Logger
-	config *config.Config
+	log     logger.Logger
+	config  *config.Config
+	limiter *concurrency.Limiter
+	guard   *resources.Guard
+	runner  *exec.Runner
+	// pipeline runs operations built from steps
+	pipeline *pipeline.Engine
+	// shedder tracks slot waits for load shedding; nil when disabled
+	shedder *shedding.Shedder
+	// attempts throttles password guessing on decrypt
+	attempts *lockout.Guard
+	// recognizers are the ICR backends by name
+	recognizers map[string]Recognizer
+	// extractors are the entity extractors by name
+	extractors map[string]EntityExtractor
+	// processors are the custom processors by name
+	processors map[string]processor.Processor
+	// scripts runs tenant scripts at pipeline hooks; nil when disabled
+	scripts *scripting.Engine
+	// provenance signs the manifests embedded in outputs; nil when disabled
+	provenance *provenance.Signer
+	// links validates extracted links; nil when not set
+	links LinkChecker
+	// background tracks processing goroutines for graceful shutdown
+	background lifecycle.Tracker
+	// maxFileSize is reloadable at runtime, so it is read atomically
+	// instead of from config
+	maxFileSize atomic.Int64
 }
 
 // NewPDFService creates a new PDF service instance
 func NewPDFService(log logger.Logger, cfg *config.Config) *PDFService {
-	return &PDFService{
-		log:    log,
-		config: cfg,
+	s := &PDFService{
+		log:      log,
+		config:   cfg,
+		guard:    resources.NewGuard(cfg.PDF.TempDir, cfg.Guardrails),
+		runner:   exec.NewRunner(cfg.Sandbox, cfg.Breakers, log),
+		attempts: lockout.NewGuard(cfg.DecryptLockout),
+	}
+	s.maxFileSize.Store(cfg.PDF.MaxFileSize)
+	s.registerEntityPatterns()
+
+	if cfg.Concurrency.MaxConcurrent > 0 {
+		s.limiter = concurrency.NewLimiter(
+			cfg.Concurrency.MaxConcurrent,
+			cfg.Concurrency.QueueSize,
+			time.Duration(cfg.Concurrency.QueueTimeout)*time.Second,
+		)
+	}
+
+	s.pipeline = s.newPipeline()
+	if cfg.LoadShedding.Enabled {
+		s.shedder = shedding.New(cfg.LoadShedding)
+	}
+
+	return s
+}
+
+// Shedder returns the load shedder, or nil when load shedding is disabled
+func (s *PDFService) Shedder() *shedding.Shedder {
+	return s.shedder
+}
+
+// MaxFileSize returns the upload size limit of the tenant of ctx, which
+// overrides the current global limit when set
+func (s *PDFService) MaxFileSize(ctx context.Context) int64 {
+	if limit := tenant.SettingsFromContext(ctx).Overrides.MaxFileSize; limit > 0 {
+		return limit
 	}
+	return s.maxFileSize.Load()
+}
+
+// SetMaxFileSize changes the upload size limit at runtime
+func (s *PDFService) SetMaxFileSize(maxFileSize int64) {
+	s.maxFileSize.Store(maxFileSize)
+}
+
+// SetScripts sets the engine tenant scripts run on. It must be called
+// before the service handles requests.
+func (s *PDFService) SetScripts(scripts *scripting.Engine) {
+	s.scripts = scripts
+}
+
+// Runner returns the external tool runner used by the service
+func (s *PDFService) Runner() *exec.Runner {
+	return s.runner
 }
 
 // ConvertToImageRequest represents a PDF to image conversion request
 type ConvertToImageRequest struct {
-	PDFData    []byte
-	Format     string // png, jpeg, webp
-	DPI        int
-	PageRange  string // e.g., "1-5" or "1,3,5"
-	Quality    int    // 1-100 for JPEG
+	PDFData   []byte
+	Format    string // png, jpeg, webp, avif, tiff
+	DPI       int
+	PageRange string // e.g., "1-5" or "1,3,5"
+	Quality   int    // 1-100 for JPEG, WebP and AVIF; 0 uses the configured default
+	// Lossless selects lossless WebP or AVIF encoding; nil uses the
+	// configured default
+	Lossless *bool
+	// Progressive selects progressive JPEG encoding; nil uses the
+	// configured default
+	Progressive *bool
+	// MaxBytes caps the size of each image; images over it are re-encoded
+	// at lower quality, then lower resolution, until they fit. 0 leaves
+	// images uncapped.
+	MaxBytes int
 }
 
-// ConvertToImageResponse represents the conversion response
+// ConvertToImageResponse represents the conversion response. TIFF output
+// is a single multi-page image.
 type ConvertToImageResponse struct {
-	Images    [][]byte
-	PageCount int
-	Format    string
+	Images      [][]byte
+	PageCount   int
+	Format      string
+	ContentType string
 }
 
 // MergeRequest represents a PDF merge request
 type MergeRequest struct {
-	PDFs      [][]byte
+	PDFs [][]byte
+	// OutputName is the file name the merged document is offered under
 	OutputName string
+	// InputNames optionally names each input for error reporting
+	InputNames []string
+	// Validate fully validates every input before merging
+	Validate bool
+	// Mode selects how pages are collated; empty appends inputs in order
+	Mode MergeMode
+	// ReverseSecond reverses the pages of the second input when
+	// interleaving, for back sides scanned by turning the whole stack over
+	ReverseSecond bool
+	// Manifest selects, orders and rotates pages of the inputs, which it
+	// names by InputNames. Empty merges every page of each input in turn.
+	Manifest []MergeItem
+	// PageLabels labels the pages of the merged document, such as roman
+	// numerals for front matter followed by decimal numbers
+	PageLabels []PageLabelRange
 }
 
+// MergeMode is a page collation mode for merging
+type MergeMode string
+
+const (
+	// MergeAppend appends the pages of each input in turn
+	MergeAppend MergeMode = "append"
+	// MergeInterleave alternates the pages of two inputs, taking odd pages
+	// from the first and even pages from the second, to rebuild
+	// double-sided documents scanned in two passes
+	MergeInterleave MergeMode = "interleave"
+)
+
 // SplitRequest represents a PDF split request
 type SplitRequest struct {
 	PDFData   []byte
@@ -72,6 +218,22 @@ type SplitRequest struct {
 type ExtractTextRequest struct {
 	PDFData []byte
 	UseOCR  bool
+	// Searchable also produces a searchable PDF: the rendered pages with an
+	// invisible OCR text layer. It implies UseOCR.
+	Searchable bool
+	// Words includes every recognised word with its confidence and position
+	Words bool
+	// Format selects hOCR or ALTO XML output instead of JSON. It implies
+	// UseOCR.
+	Format OCRFormat
+	// ICR names a handwriting recognition backend to read pages with;
+	// pages it cannot read fall back to tesseract. It implies UseOCR.
+	ICR string
+	// Entities names the entity extractors to run over the extracted text
+	Entities []string
+	// Chunking, when set, also returns the text split into chunks for
+	// embedding
+	Chunking *ChunkOptions
 }
 
 // ExtractTextResponse contains extracted text
@@ -79,12 +241,30 @@ type ExtractTextResponse struct {
 	Text      string
 	PageCount int
 	Pages     []PageText
+	// Confidence is the mean OCR word confidence from 0 to 100
+	Confidence float64 `json:",omitempty"`
+	// LowConfidencePages lists pages with text whose confidence is below
+	// the configured threshold, which usually means a poor scan
+	LowConfidencePages []int `json:",omitempty"`
+	// SearchablePDF is set when requested
+	SearchablePDF []byte `json:"-"`
+	// Document is the hOCR or ALTO document when requested
+	Document []byte `json:"-"`
+	// Entities are found by the requested extractors
+	Entities []Entity `json:",omitempty"`
+	// Chunks is the text split for embedding, when requested
+	Chunks []TextChunk `json:",omitempty"`
 }
 
 // PageText represents text from a single page
 type PageText struct {
 	PageNumber int
 	Text       string
+	Confidence float64   `json:",omitempty"`
+	Words      []OCRWord `json:",omitempty"`
+	// Engine is the OCR engine that read the page: tesseract or an ICR
+	// backend
+	Engine string `json:",omitempty"`
 }
 
 // MetadataResponse contains PDF metadata
@@ -99,12 +279,16 @@ type MetadataResponse struct {
 	PageCount    int
 	FileSize     int64
 	Encrypted    bool
+	// PageLabels is the label of each page, for documents with page labels
+	PageLabels      []string         `json:",omitempty"`
+	PageLabelRanges []PageLabelRange `json:",omitempty"`
 }
 
 // CompressRequest represents compression request
 type CompressRequest struct {
 	PDFData          []byte
-	CompressionLevel int // 1-3 (low, medium, high)
+	CompressionLevel int    // 1-3 (low, medium, high)
+	Profile          string // optional Ghostscript profile: screen, ebook, printer
 }
 
 // WatermarkRequest represents watermark addition request
@@ -116,180 +300,528 @@ type WatermarkRequest struct {
 	FontSize     int
 }
 
+// NewWatermarkRequest returns a watermark request for data with the preset
+// of the tenant of ctx, or the built-in defaults
+func NewWatermarkRequest(ctx context.Context, data []byte) *WatermarkRequest {
+	return PresetWatermarkRequest(data, tenant.SettingsFromContext(ctx).Defaults.Watermark)
+}
+
+// PresetWatermarkRequest returns a watermark request for data with preset,
+// whose zero fields keep the built-in defaults
+func PresetWatermarkRequest(data []byte, preset *tenant.Watermark) *WatermarkRequest {
+	req := &WatermarkRequest{
+		PDFData:       data,
+		WatermarkText: "CONFIDENTIAL",
+		Opacity:       0.3,
+		Rotation:      45,
+		FontSize:      48,
+	}
+	if preset == nil {
+		return req
+	}
+	req.WatermarkText = preset.Text
+	if preset.Opacity > 0 {
+		req.Opacity = preset.Opacity
+	}
+	if preset.Rotation != 0 {
+		req.Rotation = preset.Rotation
+	}
+	if preset.FontSize > 0 {
+		req.FontSize = preset.FontSize
+	}
+	return req
+}
+
 // ConvertToImage converts PDF pages to images
-func (s *PDFService) ConvertToImage(ctx context.Context, req *ConvertToImageRequest) (*ConvertToImageResponse, error) {
+func (s *PDFService) ConvertToImage(ctx context.Context, req *ConvertToImageRequest) (_ *ConvertToImageResponse, err error) {
 	ctx, span := tracer.Start(ctx, "PDFService.ConvertToImage")
 	defer span.End()
 
+	op := metrics.Start("convert_image")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "convert_image")
+	defer cancel()
+
 	span.SetAttributes(
 		attribute.String("format", req.Format),
 		attribute.Int("dpi", req.DPI),
 	)
 
-	s.log.Info("Converting PDF to images", "format", req.Format, "dpi", req.DPI)
+	format, ok := ImageFormats[req.Format]
+	if !ok {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported image format %q; use png, jpeg, webp, avif or tiff", req.Format), nil)
+	}
+	if req.DPI < minRenderDPI || req.DPI > maxRenderDPI {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("dpi must be between %d and %d", minRenderDPI, maxRenderDPI), nil)
+	}
+	if req.MaxBytes != 0 && req.MaxBytes < minImageBytes {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("max_bytes must be at least %d", minImageBytes), nil)
+	}
+	encoder, err := s.imageEncoder(req)
+	if err != nil {
+		return nil, err
+	}
 
-	// Create temp file
-	tempFile, err := s.createTempFile(req.PDFData, "input-*.pdf")
+	s.log.Info("Converting PDF to images", "format", req.Format, "dpi", req.DPI, "quality", encoder.Quality, "lossless", encoder.Lossless, "max_bytes", req.MaxBytes)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePageSelection(req.PageRange, pageCount)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
 	}
-	defer os.Remove(tempFile)
+	pages = uniquePages(pages)
+	op.Pages(len(pages))
 
-	// TODO: Implement actual PDF to image conversion
-	// This would use a library like pdfcpu or call external tools like poppler
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
 
 	response := &ConvertToImageResponse{
-		Images:    [][]byte{},
-		PageCount: 0,
-		Format:    req.Format,
+		PageCount:   len(pages),
+		Format:      req.Format,
+		ContentType: format.contentType,
 	}
 
+	err = s.runHeavy(ctx, func() error {
+		response.Images, err = s.renderImages(ctx, req.PDFData, pages, req.Format, req.DPI, encoder)
+		if err != nil || req.MaxBytes == 0 {
+			return err
+		}
+		return s.fitImages(ctx, req.PDFData, pages, req.Format, req.DPI, encoder, req.MaxBytes, response.Images)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	var imageBytes int64
+	for _, image := range response.Images {
+		imageBytes += int64(len(image))
+	}
+	op.Output(imageBytes)
+
 	s.log.Info("PDF to image conversion completed", "pages", response.PageCount)
 
 	return response, nil
 }
 
 // MergePDFs merges multiple PDFs into one
-func (s *PDFService) MergePDFs(ctx context.Context, req *MergeRequest) ([]byte, error) {
+func (s *PDFService) MergePDFs(ctx context.Context, req *MergeRequest) (_ []byte, err error) {
 	ctx, span := tracer.Start(ctx, "PDFService.MergePDFs")
 	defer span.End()
 
-	span.SetAttributes(attribute.Int("pdf_count", len(req.PDFs)))
+	op := metrics.Start("merge")
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "merge")
+	defer cancel()
 
-	s.log.Info("Merging PDFs", "count", len(req.PDFs))
+	span.SetAttributes(attribute.Int("pdf_count", len(req.PDFs)), attribute.String("mode", string(req.Mode)))
+
+	s.log.Info("Merging PDFs", "count", len(req.PDFs), "mode", req.Mode, "output_name", req.OutputName)
 
 	if len(req.PDFs) < 2 {
-		return nil, fmt.Errorf("at least 2 PDFs required for merging")
+		return nil, NewError(ErrCodeInvalidInput, "at least 2 PDFs required for merging", nil)
+	}
+	if limit := s.config.PDF.MaxMergeFiles; limit > 0 && len(req.PDFs) > limit {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("too many PDFs to merge: %d (max %d)", len(req.PDFs), limit), nil)
+	}
+	switch req.Mode {
+	case "", MergeAppend:
+	case MergeInterleave:
+		if len(req.PDFs) != 2 {
+			return nil, NewError(ErrCodeInvalidInput, "interleaving requires exactly 2 PDFs", nil)
+		}
+	default:
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported merge mode %q; use append or interleave", req.Mode), nil)
+	}
+	var manifestInputs []int
+	if len(req.Manifest) > 0 {
+		if req.Mode == MergeInterleave {
+			return nil, NewError(ErrCodeInvalidInput, "a manifest cannot be combined with interleaving", nil)
+		}
+		if manifestInputs, err = resolveManifest(req.Manifest, req.InputNames); err != nil {
+			return nil, err
+		}
+	}
+	if err := validatePageLabels(req.PageLabels); err != nil {
+		return nil, err
 	}
 
-	// Create temp files for input PDFs
-	tempFiles := make([]string, len(req.PDFs))
-	for i, pdfData := range req.PDFs {
-		tempFile, err := s.createTempFile(pdfData, fmt.Sprintf("merge-input-%d-*.pdf", i))
+	// Inspect every input before staging any of them
+	pageCounts := make([]int, len(req.PDFs))
+	err = parallel(ctx, len(req.PDFs), s.config.PDF.StagingWorkers, func(i int) error {
+		pageCount, err := s.checkPageCount(ctx, req.PDFs[i])
+		if err == nil && req.Validate {
+			err = classifyPDFError(inSpan(ctx, "pdfcpu.validate", func() error {
+				return api.Validate(bytes.NewReader(req.PDFs[i]), nil)
+			}), "failed to validate PDF")
+		}
 		if err != nil {
-			return nil, fmt.Errorf("failed to create temp file %d: %w", i, err)
+			return inputError(err, i, inputName(req.InputNames, i))
 		}
-		tempFiles[i] = tempFile
-		defer os.Remove(tempFile)
+		pageCounts[i] = pageCount
+		return nil
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	// Create output temp file
-	outputFile := filepath.Join(s.config.PDF.TempDir, fmt.Sprintf("merge-output-%s.pdf", uuid.New().String()))
-	defer os.Remove(outputFile)
+	layout := &manifestPages{}
+	switch {
+	case req.Mode == MergeInterleave:
+		if layout.order, err = interleaveOrder(pageCounts[0], pageCounts[1], req.ReverseSecond); err != nil {
+			return nil, err
+		}
+	case len(req.Manifest) > 0:
+		if layout, err = manifestOrder(req.Manifest, manifestInputs, pageCounts, req.InputNames); err != nil {
+			return nil, err
+		}
+		// Pages may be selected more than once
+		if err := s.enforcePageLimit(ctx, len(layout.order)); err != nil {
+			return nil, err
+		}
+	}
 
-	// Merge PDFs using pdfcpu
-	if err := api.MergeCreateFile(tempFiles, outputFile, nil); err != nil {
-		return nil, fmt.Errorf("failed to merge PDFs: %w", err)
+	totalPages := 0
+	for _, pageCount := range pageCounts {
+		totalPages += pageCount
+	}
+	if err := s.enforcePageLimit(ctx, totalPages); err != nil {
+		return nil, err
 	}
+	op.Pages(totalPages)
 
-	// Read merged PDF
-	mergedData, err := os.ReadFile(outputFile)
+	var totalSize int64
+	for _, pdfData := range req.PDFs {
+		totalSize += int64(len(pdfData))
+	}
+	op.Input(totalSize)
+	if err := s.checkResources(ctx, totalSize); err != nil {
+		return nil, err
+	}
+
+	var mergedData []byte
+	err = s.runHeavy(ctx, func() error {
+		// Stage input PDFs as temp files in parallel
+		tempFiles := make([]string, len(req.PDFs))
+		defer func() {
+			for _, tempFile := range tempFiles {
+				if tempFile != "" {
+					os.Remove(tempFile)
+				}
+			}
+		}()
+		err := parallel(ctx, len(req.PDFs), s.config.PDF.StagingWorkers, func(i int) error {
+			tempFile, err := s.createTempFile(ctx, req.PDFs[i], fmt.Sprintf("merge-input-%d-*.pdf", i))
+			if err != nil {
+				return fmt.Errorf("failed to create temp file %d: %w", i, err)
+			}
+			tempFiles[i] = tempFile
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+
+		// Create output temp file
+		outputFile := filepath.Join(s.config.PDF.TempDir, fmt.Sprintf("merge-output-%s.pdf", uuid.New().String()))
+		defer os.Remove(outputFile)
+
+		// Merge PDFs using pdfcpu
+		err = inSpan(ctx, "pdfcpu.merge", func() error {
+			return api.MergeCreateFile(tempFiles, outputFile, nil)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to merge PDFs")
+		}
+
+		// Read merged PDF
+		data, err := readTempFile(ctx, outputFile)
+		if err != nil {
+			return fmt.Errorf("failed to read merged PDF: %w", err)
+		}
+		if layout.order == nil {
+			mergedData = data
+			return nil
+		}
+
+		// Reorder the appended pages into their interleaved or manifest
+		// sequence, then rotate them
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.collect", func() error {
+			return api.Collect(bytes.NewReader(data), &buf, layout.order, nil)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to collate PDFs")
+		}
+		for _, rotation := range layout.sortedRotations() {
+			data = buf.Bytes()
+			buf = bytes.Buffer{}
+			err = inSpan(ctx, "pdfcpu.rotate", func() error {
+				return api.Rotate(bytes.NewReader(data), &buf, rotation, layout.rotations[rotation], nil)
+			})
+			if err != nil {
+				return classifyPDFError(err, "failed to rotate pages")
+			}
+		}
+		mergedData = buf.Bytes()
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to read merged PDF: %w", err)
+		return nil, err
+	}
+	if len(req.PageLabels) > 0 {
+		if mergedData, err = s.writePageLabels(ctx, mergedData, req.PageLabels); err != nil {
+			return nil, err
+		}
 	}
 
+	op.Output(int64(len(mergedData)))
+
 	s.log.Info("PDFs merged successfully", "output_size", len(mergedData))
 
 	return mergedData, nil
 }
 
+// interleaveOrder returns the page selection collating the appended pages
+// of two inputs, front and back sides, into their double-sided order. The
+// first input may have one page more, when the last back side is blank
+// and was not scanned.
+func interleaveOrder(front, back int, reverseBack bool) ([]string, error) {
+	if front != back && front != back+1 {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("cannot interleave %d pages with %d: the first PDF must have as many pages as the second or one more", front, back), nil)
+	}
+	order := make([]string, 0, front+back)
+	for i := 0; i < front; i++ {
+		order = append(order, strconv.Itoa(i+1))
+		if i < back {
+			page := i + 1
+			if reverseBack {
+				page = back - i
+			}
+			order = append(order, strconv.Itoa(front+page))
+		}
+	}
+	return order, nil
+}
+
 // SplitPDF splits a PDF into multiple files
 func (s *PDFService) SplitPDF(ctx context.Context, req *SplitRequest) ([][]byte, error) {
-	ctx, span := tracer.Start(ctx, "PDFService.SplitPDF")
-	defer span.End()
-
 	s.log.Info("Splitting PDF", "page_range", req.PageRange)
 
-	tempFile, err := s.createTempFile(req.PDFData, "split-input-*.pdf")
+	st, err := s.pipeline.Run(ctx, "split", req.PDFData,
+		s.checkStep(),
+		pipeline.Step{Name: "split", Mode: pipeline.Cancellable, Fn: func(st *pipeline.State) error {
+			input, err := st.Stage(st.Input, "split-input-*.pdf")
+			if err != nil {
+				return err
+			}
+			outputDir, err := st.OutputDir("split-output-*")
+			if err != nil {
+				return err
+			}
+
+			err = inSpan(st.Ctx, "pdfcpu.split", func() error {
+				return api.SplitFile(input, outputDir, 1, nil)
+			})
+			if err != nil {
+				return classifyPDFError(err, "failed to split PDF")
+			}
+
+			files, err := filepath.Glob(filepath.Join(outputDir, "*.pdf"))
+			if err != nil {
+				return fmt.Errorf("failed to read split files: %w", err)
+			}
+			st.Parts = make([][]byte, 0, len(files))
+			for _, file := range files {
+				if err := st.Ctx.Err(); err != nil {
+					return contextError(err)
+				}
+				data, err := st.ReadFile(file)
+				if err != nil {
+					return fmt.Errorf("failed to read split file %s: %w", file, err)
+				}
+				st.Parts = append(st.Parts, data)
+			}
+			return nil
+		}},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
 	}
-	defer os.Remove(tempFile)
 
-	outputDir := filepath.Join(s.config.PDF.TempDir, fmt.Sprintf("split-output-%s", uuid.New().String()))
-	if err := os.MkdirAll(outputDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create output directory: %w", err)
-	}
-	defer os.RemoveAll(outputDir)
+	s.log.Info("PDF split successfully", "output_count", len(st.Parts))
 
-	// Split PDF using pdfcpu
-	if err := api.SplitFile(tempFile, outputDir, 1, nil); err != nil {
-		return nil, fmt.Errorf("failed to split PDF: %w", err)
-	}
+	return st.Parts, nil
+}
 
-	// Read all split files
-	files, err := filepath.Glob(filepath.Join(outputDir, "*.pdf"))
-	if err != nil {
-		return nil, fmt.Errorf("failed to read split files: %w", err)
-	}
+// ExtractText extracts text from PDF
+func (s *PDFService) ExtractText(ctx context.Context, req *ExtractTextRequest) (_ *ExtractTextResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ExtractText")
+	defer span.End()
 
-	splitPDFs := make([][]byte, 0, len(files))
-	for _, file := range files {
-		data, err := os.ReadFile(file)
-		if err != nil {
-			return nil, fmt.Errorf("failed to read split file %s: %w", file, err)
+	op := metrics.Start("extract_text")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "extract_text")
+	defer cancel()
+
+	if err := req.Format.validate(req.Searchable); err != nil {
+		return nil, err
+	}
+	opts := ocrOptions{searchable: req.Searchable, format: req.Format, engine: tesseractEngine}
+	if req.ICR != "" {
+		if req.Searchable || req.Format.document() {
+			return nil, NewError(ErrCodeInvalidInput, "handwriting recognition only supports the json format without searchable output", nil)
+		}
+		if opts.recognizer, err = s.recognizer(req.ICR); err != nil {
+			return nil, err
+		}
+		opts.engine = req.ICR
+	}
+	var chunking ChunkOptions
+	if req.Chunking != nil {
+		if req.Format.document() {
+			return nil, NewError(ErrCodeInvalidInput, "chunks are only available with the json format", nil)
+		}
+		if chunking, err = req.Chunking.resolve(s.config.PDF.ChunkSize, s.config.PDF.ChunkOverlap); err != nil {
+			return nil, err
+		}
+	}
+	var extractors map[string]EntityExtractor
+	if len(req.Entities) > 0 {
+		if req.Format.document() {
+			return nil, NewError(ErrCodeInvalidInput, "entities are only available with the json format", nil)
+		}
+		if extractors, err = s.entityExtractors(req.Entities); err != nil {
+			return nil, err
 		}
-		splitPDFs = append(splitPDFs, data)
 	}
+	useOCR := req.UseOCR || req.Searchable || req.Format.document() || req.ICR != ""
+	span.SetAttributes(
+		attribute.Bool("use_ocr", useOCR),
+		attribute.Bool("searchable", req.Searchable),
+		attribute.String("format", string(req.Format)),
+		attribute.String("ocr_engine", opts.engine),
+		attribute.StringSlice("entities", req.Entities),
+	)
 
-	s.log.Info("PDF split successfully", "output_count", len(splitPDFs))
+	s.log.Info("Extracting text from PDF", "use_ocr", useOCR, "searchable", req.Searchable, "format", req.Format, "engine", opts.engine)
 
-	return splitPDFs, nil
-}
+	if useOCR && !s.config.PDF.OCREnabled {
+		return nil, NewError(ErrCodeToolUnavailable, "OCR is disabled on this server", nil)
+	}
 
-// ExtractText extracts text from PDF
-func (s *PDFService) ExtractText(ctx context.Context, req *ExtractTextRequest) (*ExtractTextResponse, error) {
-	ctx, span := tracer.Start(ctx, "PDFService.ExtractText")
-	defer span.End()
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var pageCount int
+	err = s.runCancellable(ctx, func() error {
+		tempFile, err := s.createTempFile(ctx, req.PDFData, "extract-text-*.pdf")
+		if err != nil {
+			return fmt.Errorf("failed to create temp file: %w", err)
+		}
+		defer os.Remove(tempFile)
 
-	span.SetAttributes(attribute.Bool("use_ocr", req.UseOCR))
+		// Extract text using pdfcpu
+		reader := bytes.NewReader(req.PDFData)
+		ctx2 := pdfcpu.NewContext(reader, pdfcpu.NewDefaultConfiguration())
 
-	s.log.Info("Extracting text from PDF", "use_ocr", req.UseOCR)
+		if err := inSpan(ctx, "pdfcpu.read", ctx2.Read.ReadContext); err != nil {
+			return classifyPDFError(err, "failed to read PDF context")
+		}
 
-	tempFile, err := s.createTempFile(req.PDFData, "extract-text-*.pdf")
+		// Get page count
+		pageCount = ctx2.PageCount
+		return nil
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
+		return nil, err
 	}
-	defer os.Remove(tempFile)
 
-	// Extract text using pdfcpu
-	reader := bytes.NewReader(req.PDFData)
-	ctx2 := pdfcpu.NewContext(reader, pdfcpu.NewDefaultConfiguration())
-
-	if err := ctx2.Read.ReadContext(); err != nil {
-		return nil, fmt.Errorf("failed to read PDF context: %w", err)
+	if err := s.enforcePageLimit(ctx, pageCount); err != nil {
+		return nil, err
 	}
 
-	// Get page count
-	pageCount := ctx2.PageCount
-
 	response := &ExtractTextResponse{
 		Text:      "",
 		PageCount: pageCount,
 		Pages:     make([]PageText, 0, pageCount),
 	}
 
-	// TODO: Implement actual text extraction
-	// This would use pdfcpu's text extraction or OCR if enabled
+	// TODO: Implement text extraction without OCR using pdfcpu
 
-	s.log.Info("Text extraction completed", "page_count", pageCount)
+	if useOCR {
+		// Only OCR is heavy enough to need a concurrency slot
+		var result *ocrResult
+		err = s.runHeavy(ctx, func() error {
+			result, err = s.ocr(ctx, req.PDFData, pageCount, opts)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+		s.applyOCR(response, result, req.Words)
+	}
+
+	if extractors != nil {
+		if response.Entities, err = s.extractEntities(ctx, response.Pages, extractors); err != nil {
+			return nil, err
+		}
+	}
+	if req.Chunking != nil {
+		response.Chunks = chunkText(response.Text, response.Pages, chunking)
+	}
+
+	op.Pages(pageCount)
+	op.Output(int64(len(response.Text)))
+	if useOCR {
+		metrics.OCRCharacters(utf8.RuneCountInString(response.Text))
+	}
+
+	s.log.Info("Text extraction completed", "page_count", pageCount, "entities", len(response.Entities))
 
 	return response, nil
 }
 
 // ExtractMetadata extracts PDF metadata
-func (s *PDFService) ExtractMetadata(ctx context.Context, pdfData []byte) (*MetadataResponse, error) {
+func (s *PDFService) ExtractMetadata(ctx context.Context, pdfData []byte) (_ *MetadataResponse, err error) {
 	ctx, span := tracer.Start(ctx, "PDFService.ExtractMetadata")
 	defer span.End()
 
+	op := metrics.Start("extract_metadata")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "extract_metadata")
+	defer cancel()
+
 	s.log.Info("Extracting PDF metadata")
 
 	reader := bytes.NewReader(pdfData)
 	ctx2 := pdfcpu.NewContext(reader, pdfcpu.NewDefaultConfiguration())
 
-	if err := ctx2.Read.ReadContext(); err != nil {
-		return nil, fmt.Errorf("failed to read PDF: %w", err)
+	err = s.runCancellable(ctx, func() error {
+		if err := inSpan(ctx, "pdfcpu.read", ctx2.Read.ReadContext); err != nil {
+			return classifyPDFError(err, "failed to read PDF")
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.enforcePageLimit(ctx, ctx2.PageCount); err != nil {
+		return nil, err
 	}
+	op.Pages(ctx2.PageCount)
 
 	// Extract metadata
 	info := ctx2.XRefTable.Info
@@ -299,6 +831,8 @@ func (s *PDFService) ExtractMetadata(ctx context.Context, pdfData []byte) (*Meta
 		FileSize:  int64(len(pdfData)),
 		Encrypted: ctx2.Encrypt != nil,
 	}
+	response.PageLabelRanges = readPageLabels(ctx2)
+	response.PageLabels = pageLabels(response.PageLabelRanges, ctx2.PageCount)
 
 	// Extract info dictionary fields if available
 	if info != nil {
@@ -314,35 +848,45 @@ func (s *PDFService) ExtractMetadata(ctx context.Context, pdfData []byte) (*Meta
 
 // CompressPDF compresses a PDF file
 func (s *PDFService) CompressPDF(ctx context.Context, req *CompressRequest) ([]byte, error) {
-	ctx, span := tracer.Start(ctx, "PDFService.CompressPDF")
-	defer span.End()
-
-	span.SetAttributes(attribute.Int("compression_level", req.CompressionLevel))
-
-	s.log.Info("Compressing PDF", "level", req.CompressionLevel)
-
-	tempFile, err := s.createTempFile(req.PDFData, "compress-input-*.pdf")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tempFile)
-
-	outputFile := filepath.Join(s.config.PDF.TempDir, fmt.Sprintf("compress-output-%s.pdf", uuid.New().String()))
-	defer os.Remove(outputFile)
-
-	// Optimize PDF using pdfcpu
-	if err := api.OptimizeFile(tempFile, outputFile, nil); err != nil {
-		return nil, fmt.Errorf("failed to compress PDF: %w", err)
+	s.log.Info("Compressing PDF", "level", req.CompressionLevel, "profile", req.Profile)
+
+	preset, ok := ghostscriptProfiles[req.Profile]
+	optimize := fileStep("compress", pipeline.Heavy, "compress PDF", func(input, output string) error {
+		return api.OptimizeFile(input, output, nil)
+	})
+	if preset != "" {
+		optimize = pipeline.Step{Name: "compress", Mode: pipeline.Heavy, Fn: func(st *pipeline.State) (err error) {
+			st.Output, err = s.compressWithGhostscript(st.Ctx, st.Input, preset)
+			return err
+		}}
 	}
 
-	compressedData, err := os.ReadFile(outputFile)
+	st, err := s.pipeline.Run(ctx, "compress", req.PDFData,
+		validateStep(func() error {
+			if req.Profile != "" && !ok {
+				return NewError(ErrCodeInvalidInput, fmt.Sprintf("unknown compression profile: %s", req.Profile), nil)
+			}
+			return nil
+		}),
+		s.checkStep(),
+		optimize,
+		// Ghostscript re-encodes everything and can grow already-optimized files
+		pipeline.Step{Name: "keep_smaller", Fn: func(st *pipeline.State) error {
+			if len(st.Output) >= len(st.Input) {
+				s.log.Info("Compression did not reduce size, returning original", "size", len(st.Input))
+				st.Output = st.Input
+			}
+			return nil
+		}},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read compressed PDF: %w", err)
+		return nil, err
 	}
 
 	originalSize := len(req.PDFData)
-	compressedSize := len(compressedData)
+	compressedSize := len(st.Output)
 	compressionRatio := float64(originalSize-compressedSize) / float64(originalSize) * 100
+	metrics.CompressionRatio(req.Profile, int64(originalSize), int64(compressedSize))
 
 	s.log.Info("PDF compression completed",
 		"original_size", originalSize,
@@ -350,48 +894,37 @@ func (s *PDFService) CompressPDF(ctx context.Context, req *CompressRequest) ([]b
 		"ratio", compressionRatio,
 	)
 
-	return compressedData, nil
+	return st.Output, nil
 }
 
 // AddWatermark adds a watermark to PDF
 func (s *PDFService) AddWatermark(ctx context.Context, req *WatermarkRequest) ([]byte, error) {
-	ctx, span := tracer.Start(ctx, "PDFService.AddWatermark")
-	defer span.End()
-
 	s.log.Info("Adding watermark to PDF", "text", req.WatermarkText)
 
-	tempFile, err := s.createTempFile(req.PDFData, "watermark-input-*.pdf")
-	if err != nil {
-		return nil, fmt.Errorf("failed to create temp file: %w", err)
-	}
-	defer os.Remove(tempFile)
-
-	outputFile := filepath.Join(s.config.PDF.TempDir, fmt.Sprintf("watermark-output-%s.pdf", uuid.New().String()))
-	defer os.Remove(outputFile)
-
-	// Configure watermark
-	wm, err := pdfcpu.ParseTextWatermarkDetails(req.WatermarkText, "", false, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse watermark: %w", err)
-	}
-
-	// Add watermark using pdfcpu
-	if err := api.AddWatermarksFile(tempFile, outputFile, nil, wm, nil); err != nil {
-		return nil, fmt.Errorf("failed to add watermark: %w", err)
-	}
-
-	watermarkedData, err := os.ReadFile(outputFile)
+	st, err := s.pipeline.Run(ctx, "watermark", req.PDFData,
+		s.checkStep(),
+		fileStep("watermark", pipeline.Cancellable, "add watermark", func(input, output string) error {
+			wm, err := pdfcpu.ParseTextWatermarkDetails(req.WatermarkText, "", false, nil)
+			if err != nil {
+				return NewError(ErrCodeInvalidInput, "invalid watermark text", err)
+			}
+			return api.AddWatermarksFile(input, output, nil, wm, nil)
+		}),
+	)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read watermarked PDF: %w", err)
+		return nil, err
 	}
 
 	s.log.Info("Watermark added successfully")
 
-	return watermarkedData, nil
+	return st.Output, nil
 }
 
 // createTempFile creates a temporary file with the given data
-func (s *PDFService) createTempFile(data []byte, pattern string) (string, error) {
+func (s *PDFService) createTempFile(ctx context.Context, data []byte, pattern string) (string, error) {
+	_, span := tracer.Start(ctx, "tempfile.write", trace.WithAttributes(attribute.Int("bytes", len(data))))
+	defer span.End()
+
 	// Ensure temp directory exists
 	if err := os.MkdirAll(s.config.PDF.TempDir, 0755); err != nil {
 		return "", fmt.Errorf("failed to create temp directory: %w", err)
@@ -413,19 +946,40 @@ func (s *PDFService) createTempFile(data []byte, pattern string) (string, error)
 	return tmpFile.Name(), nil
 }
 
+// readTempFile reads an operation's output file
+func readTempFile(ctx context.Context, path string) ([]byte, error) {
+	var data []byte
+	err := inSpan(ctx, "tempfile.read", func() error {
+		var err error
+		data, err = os.ReadFile(path)
+		return err
+	})
+	return data, err
+}
+
 // ValidateRequest validates common request parameters
-func (s *PDFService) ValidateRequest(pdfData []byte) error {
+func (s *PDFService) ValidateRequest(ctx context.Context, pdfData []byte) error {
 	if len(pdfData) == 0 {
-		return fmt.Errorf("PDF data is empty")
+		return NewError(ErrCodeInvalidInput, "PDF data is empty", nil)
 	}
 
-	if int64(len(pdfData)) > s.config.PDF.MaxFileSize {
-		return fmt.Errorf("PDF file too large: %d bytes (max %d)", len(pdfData), s.config.PDF.MaxFileSize)
+	if maxFileSize := s.MaxFileSize(ctx); int64(len(pdfData)) > maxFileSize {
+		return NewError(ErrCodeFileTooLarge, fmt.Sprintf("PDF file too large: %d bytes (max %d)", len(pdfData), maxFileSize), nil)
 	}
 
+	return validateHeader(pdfData)
+}
+
+// validateHeader checks the %PDF-x.y header at the start of a document
+func validateHeader(header []byte) error {
 	// Validate PDF magic number
-	if len(pdfData) < 4 || string(pdfData[:4]) != "%PDF" {
-		return fmt.Errorf("invalid PDF format")
+	if len(header) < 4 || string(header[:4]) != "%PDF" {
+		return NewError(ErrCodeInvalidInput, "invalid PDF format", nil)
+	}
+
+	// Validate header version (%PDF-x.y)
+	if len(header) >= 8 && !supportedVersions[string(header[5:8])] {
+		return NewError(ErrCodeUnsupportedVersion, fmt.Sprintf("unsupported PDF version: %s", header[5:8]), nil)
 	}
 
 	return nil
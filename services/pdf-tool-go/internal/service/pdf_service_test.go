@@ -1,10 +1,25 @@
 package service
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/gif"
+	"io"
+	"net/http"
+	"os"
+	osexec "os/exec"
+	"path/filepath"
 	"testing"
+	"time"
 
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
 	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
 	"github.com/stretchr/testify/assert"
 )
@@ -20,19 +35,66 @@ func TestPDFService_ValidateRequest(t *testing.T) {
 
 	t.Run("Valid PDF", func(t *testing.T) {
 		pdfData := []byte("%PDF-1.4\ntest")
-		err := svc.ValidateRequest(pdfData)
+		err := svc.ValidateRequest(context.Background(), pdfData)
 		assert.NoError(t, err)
 	})
 
 	t.Run("Empty Data", func(t *testing.T) {
-		err := svc.ValidateRequest([]byte{})
+		err := svc.ValidateRequest(context.Background(), []byte{})
 		assert.Error(t, err)
 	})
 
 	t.Run("Invalid Format", func(t *testing.T) {
-		err := svc.ValidateRequest([]byte("not a pdf"))
+		err := svc.ValidateRequest(context.Background(), []byte("not a pdf"))
 		assert.Error(t, err)
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
 	})
+
+	t.Run("Unsupported Version", func(t *testing.T) {
+		err := svc.ValidateRequest(context.Background(), []byte("%PDF-3.1\ntest"))
+		assert.Equal(t, ErrCodeUnsupportedVersion, CodeOf(err))
+	})
+
+	t.Run("Too Large", func(t *testing.T) {
+		err := svc.ValidateRequest(context.Background(), make([]byte, 2*1024*1024))
+		assert.Equal(t, ErrCodeFileTooLarge, CodeOf(err))
+	})
+
+	t.Run("Tenant Overrides The Size Limit", func(t *testing.T) {
+		ctx := tenant.WithSettings(context.Background(), tenant.Settings{
+			Overrides: tenant.Overrides{MaxFileSize: 4 * 1024 * 1024},
+		})
+		pdfData := append([]byte("%PDF-1.4\n"), make([]byte, 2*1024*1024)...)
+		assert.NoError(t, svc.ValidateRequest(ctx, pdfData))
+		assert.Equal(t, int64(1024*1024), svc.MaxFileSize(context.Background()))
+	})
+}
+
+func TestClassifyPDFError(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want ErrorCode
+	}{
+		{"corrupt xref", errors.New("pdfcpu: corrupt xref table"), ErrCodeCorrupted},
+		{"missing header", errors.New("pdfcpu: headerVersion: corrupt pdf"), ErrCodeCorrupted},
+		{"truncated pdf", fmt.Errorf("reading object: %w", io.ErrUnexpectedEOF), ErrCodeCorrupted},
+		{"encrypted", pdfcpu.ErrWrongPassword, ErrCodeEncrypted},
+		{"unsupported version", errors.New("pdfcpu: unsupported PDF version 3.1"), ErrCodeUnsupportedVersion},
+		{"invalid page range", errors.New("pdfcpu: invalid page range: 7-3"), ErrCodeInvalidInput},
+		{"invalid page selection", errors.New("pdfcpu: problem with page selection: x"), ErrCodeInvalidInput},
+		{"invalid password", errors.New("pdfcpu: invalid password"), ErrCodeEncrypted},
+		{"truncated upload", fmt.Errorf("reading upload: %w", &http.MaxBytesError{Limit: 1024}), ErrCodeFileTooLarge},
+		{"bare eof is not corruption", errors.New("unexpected eof from tool"), ErrCodeInternal},
+		{"invalid option is not corruption", errors.New("invalid rotation"), ErrCodeInternal},
+		{"unknown is internal", errors.New("disk full"), ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, CodeOf(classifyPDFError(tt.err, "failed")))
+		})
+	}
 }
 
 func TestPDFService_MergePDFs(t *testing.T) {
@@ -52,4 +114,995 @@ func TestPDFService_MergePDFs(t *testing.T) {
 		_, err := svc.MergePDFs(context.Background(), req)
 		assert.Error(t, err)
 	})
+
+	t.Run("Too Many PDFs", func(t *testing.T) {
+		limited := NewPDFService(log, &config.Config{PDF: config.PDFConfig{MaxMergeFiles: 2}})
+		pdf := []byte("%PDF-1.4")
+		_, err := limited.MergePDFs(context.Background(), &MergeRequest{PDFs: [][]byte{pdf, pdf, pdf}})
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+}
+
+func TestRunCancellableStopsAbandonedWork(t *testing.T) {
+	svc := NewPDFService(logger.New("error", "text"), &config.Config{})
+	ctx, cancel := context.WithCancel(context.Background())
+
+	proceed := make(chan struct{})
+	var phases []string
+	err := svc.runCancellable(ctx, func() error {
+		err := inSpan(ctx, "first", func() error {
+			// The caller goes away while the phase is running
+			cancel()
+			<-proceed
+			phases = append(phases, "first")
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return inSpan(ctx, "second", func() error {
+			phases = append(phases, "second")
+			return nil
+		})
+	})
+	assert.Equal(t, ErrCodeCanceled, CodeOf(err))
+	close(proceed)
+
+	// The abandoned work finishes its phase and skips the rest
+	drainCtx, drainCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer drainCancel()
+	assert.NoError(t, svc.Drain(drainCtx))
+	assert.Equal(t, []string{"first"}, phases)
+}
+
+func TestPDFService_GetPage(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{})
+	pdfData := []byte("%PDF-1.4\ntest")
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		_, err := svc.GetPage(context.Background(), &PageRequest{PDFData: pdfData, Page: 1, Format: "bmp"})
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+
+	t.Run("DPI Out Of Range", func(t *testing.T) {
+		_, err := svc.GetPage(context.Background(), &PageRequest{PDFData: pdfData, Page: 1, Format: "png", DPI: 5000})
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+
+	document := benchPDF(3)
+
+	t.Run("Extracts PDF Page", func(t *testing.T) {
+		out, err := svc.GetPage(context.Background(), &PageRequest{PDFData: document, Page: 2, Format: "pdf"})
+		assert.NoError(t, err)
+		pages, err := api.PageCount(bytes.NewReader(out), nil)
+		assert.NoError(t, err)
+		assert.Equal(t, 1, pages)
+		assert.Contains(t, string(out), "Page 2 line 1")
+		assert.NotContains(t, string(out), "Page 1 line 1")
+	})
+
+	t.Run("Missing Page", func(t *testing.T) {
+		_, err := svc.GetPage(context.Background(), &PageRequest{PDFData: document, Page: 4, Format: "pdf"})
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+		_, err = svc.GetPage(context.Background(), &PageRequest{PDFData: document, Page: 0, Format: "png", DPI: 72})
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+
+	t.Run("Check Page", func(t *testing.T) {
+		assert.NoError(t, svc.CheckPage(context.Background(), &PageRequest{PDFData: document, Page: 3, Format: "png", DPI: 72}))
+		assert.Equal(t, ErrCodeNotFound, CodeOf(svc.CheckPage(context.Background(), &PageRequest{PDFData: document, Page: 4, Format: "pdf"})))
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(svc.CheckPage(context.Background(), &PageRequest{PDFData: document, Page: 1, Format: "bmp"})))
+	})
+
+	t.Run("Renders Images", func(t *testing.T) {
+		if _, err := osexec.LookPath("gs"); err != nil {
+			t.Skip("ghostscript is not installed")
+		}
+		renderer := NewPDFService(logger.New("info", "text"), &config.Config{
+			Sandbox: config.SandboxConfig{
+				WorkDir: t.TempDir(),
+				Timeout: 60,
+				Tools:   map[string]string{"gs": ""},
+			},
+		})
+
+		for _, format := range []string{"png", "jpeg"} {
+			out, err := renderer.GetPage(context.Background(), &PageRequest{PDFData: document, Page: 2, Format: format, DPI: 72})
+			if !assert.NoError(t, err, format) {
+				continue
+			}
+			img, decoded, err := image.Decode(bytes.NewReader(out))
+			assert.NoError(t, err, format)
+			assert.Equal(t, format, decoded)
+			// Letter pages at 72 dpi render at their size in points
+			assert.Equal(t, image.Rect(0, 0, 612, 792), img.Bounds(), format)
+		}
+	})
+}
+
+func TestPDFService_Cover(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{})
+	pdfData := []byte("%PDF-1.4\ntest")
+
+	t.Run("Unsupported Format", func(t *testing.T) {
+		_, err := svc.Cover(context.Background(), &CoverRequest{PDFData: pdfData, Format: "pdf"})
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+
+	t.Run("Width Out Of Range", func(t *testing.T) {
+		_, err := svc.Cover(context.Background(), &CoverRequest{PDFData: pdfData, Width: 4000, Format: "jpeg"})
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+}
+
+func TestParseTSV(t *testing.T) {
+	tsv := "level\tpage_num\tblock_num\tpar_num\tline_num\tword_num\tleft\ttop\twidth\theight\tconf\ttext\n" +
+		"1\t1\t0\t0\t0\t0\t0\t0\t2480\t3508\t-1\t\n" +
+		"5\t1\t1\t1\t1\t1\t100\t200\t50\t20\t96.5\tHello\n" +
+		"5\t1\t1\t1\t1\t2\t160\t200\t60\t20\t91.5\tworld\n" +
+		"5\t1\t1\t1\t2\t1\t100\t240\t40\t20\t90\tagain\n" +
+		"5\t1\t1\t1\t2\t2\t150\t240\t40\t20\t95\t \n" +
+		"5\t3\t1\t1\t1\t1\t100\t200\t40\t20\t30\tblurry\n"
+
+	pages, err := parseTSV([]byte(tsv), 3)
+	assert.NoError(t, err)
+	assert.Len(t, pages, 3)
+
+	assert.Equal(t, "Hello world\nagain", pages[0].Text)
+	assert.InDelta(t, 92.666, pages[0].Confidence, 0.01)
+	assert.Equal(t, OCRWord{Text: "world", Confidence: 91.5, Left: 160, Top: 200, Width: 60, Height: 20}, pages[0].Words[1])
+
+	assert.Equal(t, 2, pages[1].PageNumber)
+	assert.Empty(t, pages[1].Text)
+	assert.Zero(t, pages[1].Confidence)
+	assert.Equal(t, "blurry", pages[2].Text)
+
+	_, err = parseTSV([]byte("5\t4\t1\t1\t1\t1\t0\t0\t1\t1\t50\tstray\n"), 3)
+	assert.Error(t, err)
+}
+
+func TestOCRFormat(t *testing.T) {
+	assert.NoError(t, OCRFormat("").validate(true))
+	assert.NoError(t, OCRFormatALTO.validate(false))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(OCRFormat("pdf").validate(false)))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(OCRFormatHOCR.validate(true)))
+	assert.Equal(t, "application/xhtml+xml", OCRFormatHOCR.ContentType())
+}
+
+func TestRecognizer(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{})
+
+	t.Run("Unconfigured", func(t *testing.T) {
+		_, err := svc.recognizer("vision")
+		assert.Equal(t, ErrCodeToolUnavailable, CodeOf(err))
+	})
+
+	t.Run("Unknown Backend", func(t *testing.T) {
+		svc.RegisterRecognizer("vision", nil)
+		_, err := svc.recognizer("local")
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+
+	t.Run("Page From Words", func(t *testing.T) {
+		page := pageFromWords(2, []OCRWord{
+			{Text: "Dear", Confidence: 80, Top: 100, Height: 40},
+			{Text: "Ann,", Confidence: 60, Top: 110, Height: 40},
+			{Text: "thanks", Confidence: 70, Top: 160, Height: 40},
+		}, "vision")
+		assert.Equal(t, "Dear Ann,\nthanks", page.Text)
+		assert.Equal(t, 70.0, page.Confidence)
+		assert.Equal(t, "vision", page.Engine)
+	})
+}
+
+func TestExtractEntities(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{
+		Entities: config.EntityConfig{Patterns: []config.EntityPattern{{Name: "order", Pattern: `Order (\d+)`}}},
+	})
+	pages := []PageText{
+		{PageNumber: 1, Text: "Invoice No. INV-2024-001 dated 2024-03-01\nQuestions: billing@example.com"},
+		{PageNumber: 2, Text: "SSN 123-45-6789, Order 42"},
+	}
+
+	extractors, err := svc.entityExtractors([]string{"email", "ssn", "invoice_number", "date", "order"})
+	assert.NoError(t, err)
+	entities, err := svc.extractEntities(context.Background(), pages, extractors)
+	assert.NoError(t, err)
+	assert.Equal(t, []Entity{
+		{Type: "invoice_number", Value: "INV-2024-001", Page: 1, Start: 12, End: 24},
+		{Type: "date", Value: "2024-03-01", Page: 1, Start: 31, End: 41},
+		{Type: "email", Value: "billing@example.com", Page: 1, Start: 53, End: 72},
+		{Type: "ssn", Value: "123-45-6789", Page: 2, Start: 4, End: 15},
+		{Type: "order", Value: "42", Page: 2, Start: 23, End: 25},
+	}, entities)
+
+	_, err = svc.entityExtractors([]string{"passport"})
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+}
+
+func TestRedactionBoxes(t *testing.T) {
+	page := PageText{
+		Text: "Call 555-123-4567\nor mail jo@example.com",
+		Words: []OCRWord{
+			{Text: "Call", Left: 10, Top: 10, Width: 40, Height: 20},
+			{Text: "555-123-4567", Left: 60, Top: 10, Width: 120, Height: 20},
+			{Text: "or", Left: 10, Top: 40, Width: 20, Height: 20},
+			{Text: "mail", Left: 40, Top: 40, Width: 40, Height: 20},
+			{Text: "jo@example.com", Left: 90, Top: 40, Width: 140, Height: 20},
+		},
+	}
+	entities := []Entity{{Type: "phone", Start: 5, End: 17}, {Type: "email", Start: 26, End: 40}}
+
+	assert.Equal(t, []image.Rectangle{
+		image.Rect(56, 6, 184, 34),
+		image.Rect(86, 36, 234, 64),
+	}, redactionBoxes(page, entities))
+}
+
+func TestParseInvoice(t *testing.T) {
+	pages := []PageText{{PageNumber: 1, Text: "INVOICE\nAcme Supplies Ltd\nInvoice Date: 2024-03-01\nDue Date: 2024-03-31\n" +
+		"Description Qty Unit Price Amount\nPaper A4 box 2 $12.50 $25.00\nToner 1,5 1.000,00 1.500,00\n" +
+		"Subtotal $1,525.00\nVAT 20% $305.00\nTotal Due $1,830.00"}}
+
+	invoice := parseInvoice(pages)
+	assert.Equal(t, "Acme Supplies Ltd", invoice.Vendor)
+	assert.Equal(t, "2024-03-01", invoice.InvoiceDate)
+	assert.Equal(t, "2024-03-31", invoice.DueDate)
+	assert.Equal(t, "USD", invoice.Currency)
+	assert.Equal(t, 1525.0, invoice.Subtotal)
+	assert.Equal(t, 305.0, invoice.Tax)
+	assert.Equal(t, 1830.0, invoice.Total)
+	assert.Equal(t, []LineItem{
+		{Description: "Paper A4 box", Quantity: 2, UnitPrice: 12.5, Amount: 25},
+		{Description: "Toner", Quantity: 1.5, UnitPrice: 1000, Amount: 1500},
+	}, invoice.LineItems)
+
+	t.Run("Receipt With Only A Total", func(t *testing.T) {
+		invoice := parseInvoice([]PageText{{Text: "Corner Cafe\n12/05/2024\nLatte 4.50\nTax 0.50\nTOTAL 5.00"}})
+		assert.Equal(t, "Corner Cafe", invoice.Vendor)
+		assert.Equal(t, "12/05/2024", invoice.InvoiceDate)
+		assert.Equal(t, 4.5, invoice.Subtotal)
+		assert.Empty(t, invoice.LineItems)
+	})
+}
+
+func TestChunkText(t *testing.T) {
+	pages := []PageText{{PageNumber: 1, Text: "one two three"}, {PageNumber: 2, Text: "four five"}}
+	text := "one two three" + pageSeparator + "four five"
+
+	t.Run("Tokens", func(t *testing.T) {
+		chunks := chunkText(text, pages, ChunkOptions{Size: 3, Overlap: 1, Unit: ChunkTokens})
+		assert.Equal(t, []TextChunk{
+			{Index: 0, Text: "one two three", Page: 1, EndPage: 1, Start: 0, End: 13},
+			{Index: 1, Text: "three\n\nfour five", Page: 1, EndPage: 2, Start: 8, End: 24},
+		}, chunks)
+	})
+
+	t.Run("Chars", func(t *testing.T) {
+		chunks := chunkText(text, pages, ChunkOptions{Size: 9, Overlap: 4, Unit: ChunkChars})
+		var texts []string
+		for _, chunk := range chunks {
+			texts = append(texts, chunk.Text)
+		}
+		assert.Equal(t, []string{"one two", "two three", "four five"}, texts)
+		assert.Equal(t, 2, chunks[2].Page)
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		opts, err := ChunkOptions{}.resolve(1000, 200)
+		assert.NoError(t, err)
+		assert.Equal(t, ChunkOptions{Size: 1000, Overlap: 200, Unit: ChunkChars}, opts)
+		_, err = ChunkOptions{Size: 100, Overlap: 100}.resolve(1000, 200)
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+}
+
+func TestSetAltText(t *testing.T) {
+	logo := pdfcpu.Dict{"S": pdfcpu.Name("Figure")}
+	chart := pdfcpu.Dict{"S": pdfcpu.Name("Figure"), "Alt": pdfcpu.StringLiteral("old")}
+	section := pdfcpu.Dict{"S": pdfcpu.Name("Sect"), "K": pdfcpu.Array{chart}}
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	ctx2.RootDict = pdfcpu.Dict{"StructTreeRoot": pdfcpu.Dict{"K": pdfcpu.Array{logo, section}}}
+
+	assert.Equal(t, 1, figuresWithoutAlt(ctx2, ctx2.RootDict["StructTreeRoot"].(pdfcpu.Dict)))
+
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(setAltText(ctx2, map[string]string{"1": "Logo", "3": "Missing"})))
+	_, changed := logo.Find("Alt")
+	assert.False(t, changed)
+
+	assert.NoError(t, setAltText(ctx2, map[string]string{"1": "Logo", "2": "Sales chart"}))
+	assert.Equal(t, pdfcpu.StringLiteral("Logo"), logo["Alt"])
+	assert.Equal(t, pdfcpu.StringLiteral("Sales chart"), chart["Alt"])
+}
+
+func TestFillFields(t *testing.T) {
+	name := pdfcpu.Dict{"T": pdfcpu.StringLiteral("name"), "AP": pdfcpu.Dict{}}
+	date := pdfcpu.Dict{"T": pdfcpu.StringLiteral("date"), "Ff": pdfcpu.Integer(2)}
+	client := pdfcpu.Dict{"T": pdfcpu.StringLiteral("client"), "FT": pdfcpu.Name("Tx"), "Kids": pdfcpu.Array{name, date}}
+	signed := pdfcpu.Dict{"T": pdfcpu.StringLiteral("signed"), "FT": pdfcpu.Name("Btn")}
+	form := pdfcpu.Dict{"Fields": pdfcpu.Array{client, signed}}
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	ctx2.RootDict = pdfcpu.Dict{"AcroForm": form}
+
+	filled := map[string]bool{}
+	assert.NoError(t, fillFields(ctx2, map[string]string{"client.name": "Ann (CEO)", "client.date": "1. März"}, filled))
+	assert.Equal(t, map[string]bool{"client.name": true, "client.date": true}, filled)
+	assert.Equal(t, pdfcpu.StringLiteral(`Ann \(CEO\)`), name["V"])
+	assert.Equal(t, pdfcpu.HexLiteral("feff0031002e0020004d00e40072007a"), date["V"])
+	assert.Equal(t, pdfcpu.Integer(3), date["Ff"])
+	_, stale := name.Find("AP")
+	assert.False(t, stale)
+	assert.Equal(t, pdfcpu.Boolean(true), form["NeedAppearances"])
+
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(fillFields(ctx2, map[string]string{"signed": "yes"}, filled)))
+
+	ctx2.RootDict = pdfcpu.Dict{}
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(fillFields(ctx2, map[string]string{"client.name": "Ann"}, filled)))
+
+	_, err := assemblyManifest([]AssemblyComponent{{Name: "terms"}, {Name: "body", Pages: "1"}, {Name: "terms"}})
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	manifest, err := assemblyManifest([]AssemblyComponent{{Name: "cover"}, {Name: "terms"}, {Name: "cover"}})
+	assert.NoError(t, err)
+	assert.Nil(t, manifest)
+}
+
+func TestAttachFile(t *testing.T) {
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	kid := pdfcpu.Dict{"Names": pdfcpu.Array{pdfcpu.StringLiteral("a.txt"), pdfcpu.Integer(1), pdfcpu.StringLiteral("z.pdf"), pdfcpu.Integer(2)}}
+	names := pdfcpu.Dict{"EmbeddedFiles": pdfcpu.Dict{"Kids": pdfcpu.Array{kid}}}
+	ctx2.RootDict = pdfcpu.Dict{"Names": names}
+
+	assert.NoError(t, attachFile(ctx2, "z.pdf", "Restricted pages 2", []byte("%PDF-1.7")))
+	assert.NoError(t, attachFile(ctx2, "m.pdf", "Restricted pages 3", []byte("%PDF-1.7")))
+
+	tree := names["EmbeddedFiles"].(pdfcpu.Dict)
+	entries := nameTreeEntries(ctx2, tree, 0)
+	keys := make([]string, len(entries))
+	for i, entry := range entries {
+		keys[i] = entry.key
+	}
+	assert.Equal(t, []string{"a.txt", "m.pdf", "z.pdf"}, keys)
+	assert.Equal(t, pdfcpu.Integer(1), entries[0].value)
+	assert.IsType(t, pdfcpu.IndirectRef{}, entries[2].value)
+}
+
+func TestURILinks(t *testing.T) {
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	link := func(uri string, rect ...float64) pdfcpu.Dict {
+		r := pdfcpu.Array{}
+		for _, v := range rect {
+			r = append(r, pdfcpu.Float(v))
+		}
+		return pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "Rect": r, "A": pdfcpu.Dict{"S": pdfcpu.Name("URI"), "URI": pdfcpu.StringLiteral(uri)}}
+	}
+	first := pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "Annots": pdfcpu.Array{
+		link("https://example.com/a", 100, 700, 200, 720),
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "A": pdfcpu.Dict{"S": pdfcpu.Name("GoTo")}},
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Text")},
+	}}
+	second := pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "Rotate": pdfcpu.Integer(-90), "Annots": pdfcpu.Array{link("mailto:a@example.com", 50, 60, 10, 20)}}
+	ctx2.RootDict = pdfcpu.Dict{"Pages": pdfcpu.Dict{
+		"Type":     pdfcpu.Name("Pages"),
+		"MediaBox": pdfcpu.Array{pdfcpu.Integer(0), pdfcpu.Integer(0), pdfcpu.Integer(595), pdfcpu.Integer(842)},
+		"Kids":     pdfcpu.Array{first, pdfcpu.Dict{"Type": pdfcpu.Name("Page")}, second},
+	}}
+
+	tree, err := pageTree(ctx2)
+	assert.NoError(t, err)
+	assert.Len(t, tree, 3)
+	assert.Equal(t, [4]float64{0, 0, 595, 842}, tree[0].mediaBox)
+	assert.Equal(t, 270, tree[2].rotate)
+
+	links, pages := uriLinks(ctx2, tree)
+	assert.Equal(t, []Link{
+		{Page: 1, URI: "https://example.com/a", Rect: [4]float64{100, 700, 200, 720}},
+		{Page: 3, URI: "mailto:a@example.com", Rect: [4]float64{10, 20, 50, 60}},
+	}, links)
+	assert.Len(t, pages, 2)
+	assert.Equal(t, 3, pages[1].number)
+
+	ctx2.RootDict = pdfcpu.Dict{}
+	_, err = pageTree(ctx2)
+	assert.Equal(t, ErrCodeCorrupted, CodeOf(err))
+}
+
+func TestCheckNavigation(t *testing.T) {
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	first, err := ctx2.IndRefForNewObject(pdfcpu.Dict{"Type": pdfcpu.Name("Page")})
+	assert.NoError(t, err)
+	removed := pdfcpu.IndirectRef{ObjectNumber: 99}
+	fit := func(page pdfcpu.Object) pdfcpu.Array { return pdfcpu.Array{page, pdfcpu.Name("Fit")} }
+	goTo := func(dest pdfcpu.Object) pdfcpu.Dict { return pdfcpu.Dict{"S": pdfcpu.Name("GoTo"), "D": dest} }
+	second := pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "Annots": pdfcpu.Array{
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "Dest": fit(*first)},
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "A": goTo(fit(removed))},
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "Dest": pdfcpu.Name("missing")},
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "A": goTo(pdfcpu.StringLiteral("intro"))},
+		pdfcpu.Dict{"Subtype": pdfcpu.Name("Link"), "A": pdfcpu.Dict{"S": pdfcpu.Name("URI")}},
+	}}
+	ctx2.RootDict = pdfcpu.Dict{
+		"Pages": pdfcpu.Dict{"Type": pdfcpu.Name("Pages"), "Kids": pdfcpu.Array{*first, second}},
+		"Names": pdfcpu.Dict{"Dests": pdfcpu.Dict{"Names": pdfcpu.Array{
+			pdfcpu.StringLiteral("gone"), pdfcpu.Dict{"D": fit(removed)},
+			pdfcpu.StringLiteral("intro"), fit(*first),
+		}}},
+		"Outlines": pdfcpu.Dict{"First": pdfcpu.Dict{
+			"Title": pdfcpu.StringLiteral("Chapter 1"),
+			"Dest":  pdfcpu.Name("intro"),
+			"First": pdfcpu.Dict{"Title": pdfcpu.StringLiteral("Old section"), "A": goTo(pdfcpu.StringLiteral("gone"))},
+		}},
+		"OpenAction": fit(pdfcpu.Integer(4)),
+	}
+
+	tree, err := pageTree(ctx2)
+	assert.NoError(t, err)
+	report := checkNavigation(ctx2, tree)
+	assert.False(t, report.Valid)
+	assert.Equal(t, 2, report.PageCount)
+	assert.Equal(t, 9, report.References)
+	assert.Equal(t, []BrokenReference{
+		{Source: NavigationDestination, Destination: "gone", Reason: "target object 99 is not a page of the document"},
+		{Source: NavigationLink, Page: 2, Reason: "target object 99 is not a page of the document"},
+		{Source: NavigationLink, Page: 2, Destination: "missing", Reason: `named destination "missing" is not defined`},
+		{Source: NavigationOutline, Title: "Old section", Destination: "gone", Reason: "target object 99 is not a page of the document"},
+		{Source: NavigationOpenAction, Reason: "target page 5 is outside pages 1-2"},
+	}, report.Broken)
+}
+
+func TestAnchorText(t *testing.T) {
+	xml := []byte(`<page>
+<block>
+<line>
+<span bbox="100 122 190 134" font="Helvetica" size="12.0000">
+<char bbox="100 122 107 134" c="R"/>
+<char bbox="107 122 113 134" c="e"/>
+<char bbox="113 122 119 134" c="a"/>
+<char bbox="119 122 125 134" c="d"/>
+<char bbox="130 122 134 134" c="&amp;"/>
+<char bbox="140 122 146 134" c="g"/>
+<char bbox="146 122 152 134" c="o"/>
+<char bbox="300 122 306 134" c="x"/>
+</span>
+</line>
+</block>
+</page>
+<page>
+<block>
+<line>
+<span bbox="10 10 20 22" font="Helvetica" size="12.0000">
+<char bbox="10 10 16 22" c="&lt;"/>
+</span>
+</line>
+</block>
+</page>
+`)
+	chars := parseTextPositions(xml, 4)
+	assert.Len(t, chars[4], 8)
+	assert.Equal(t, pageChar{x0: 10, y0: 10, x1: 16, y1: 22, c: "<"}, chars[5][0])
+
+	mediaBox := [4]float64{0, 0, 595, 842}
+	assert.Equal(t, "Read & go", anchorText(chars[4], [4]float64{95, 700, 200, 725}, mediaBox))
+	assert.Equal(t, "", anchorText(chars[4], [4]float64{95, 100, 200, 125}, mediaBox))
+}
+
+func TestTextDocument(t *testing.T) {
+	doc := textDocument("# Certificate\nSigned by Zoë (CEO)", textStyle{PageSize: "letter", FontSize: 10, Footer: "Audit"})
+	assert.True(t, bytes.HasPrefix(doc, []byte("%PDF-1.4")))
+	assert.Contains(t, string(doc), "/Count 1")
+	assert.Contains(t, string(doc), "/MediaBox [0 0 612 792]")
+	assert.Contains(t, string(doc), "(Signed by Zo\xeb \\(CEO\\)) Tj")
+	assert.Contains(t, string(doc), "(Audit - Page 1 of 1) Tj")
+
+	long := textDocument(string(bytes.Repeat([]byte("word "), 5000)), textStyle{})
+	assert.Contains(t, string(long), "/Count 6")
+
+	assert.Equal(t, []string{"a", "b"}, wrapText("a b", 10, 6))
+	assert.Equal(t, []string{"ab", "c"}, wrapText("abc", 10, 12))
+
+	text, err := renderAuditTrail("# {{.Title}}\n{{range .Signers}}{{.Name}}\n{{end}}", AuditTrail{Title: "Done", Signers: []AuditSigner{{Name: "Ann"}}})
+	assert.NoError(t, err)
+	assert.Equal(t, "# Done\nAnn", text)
+	_, err = renderAuditTrail("{{.Missing}}", AuditTrail{})
+	assert.Error(t, err)
+}
+
+func TestICCProfile(t *testing.T) {
+	header := func(class, space string) []byte {
+		data := make([]byte, iccHeaderSize)
+		binary.BigEndian.PutUint32(data, iccHeaderSize)
+		copy(data[12:], class)
+		copy(data[16:], space)
+		copy(data[36:], "acsp")
+		return data
+	}
+
+	profile, err := parseICCProfile(header("prtr", "CMYK"))
+	assert.NoError(t, err)
+	assert.NoError(t, validateOutputIntent(profile, ColorCMYK))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(validateOutputIntent(profile, ColorSRGB)))
+
+	profile, err = parseICCProfile(header("mntr", "RGB "))
+	assert.NoError(t, err)
+	assert.NoError(t, validateOutputIntent(profile, ColorSRGB))
+
+	profile, err = parseICCProfile(header("scnr", "CMYK"))
+	assert.NoError(t, err)
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(validateOutputIntent(profile, ColorCMYK)))
+
+	_, err = parseICCProfile([]byte("%PDF-1.7"))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+
+	assert.Equal(t, `FOGRA39 \(coated\)`, psString("FOGRA39 (coated)"))
+}
+
+func TestScanPDFXObjects(t *testing.T) {
+	objects := []pdfcpu.Object{
+		pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "TrimBox": pdfcpu.Array{}},
+		pdfcpu.Dict{"Type": pdfcpu.Name("Page")},
+		pdfcpu.Dict{"Type": pdfcpu.Name("Font"), "Subtype": pdfcpu.Name("Type1"), "BaseFont": pdfcpu.Name("Helvetica")},
+		pdfcpu.Dict{"Type": pdfcpu.Name("Font"), "Subtype": pdfcpu.Name("TrueType"), "BaseFont": pdfcpu.Name("Arial"),
+			"FontDescriptor": pdfcpu.Dict{"FontFile2": pdfcpu.Dict{}}},
+		pdfcpu.Dict{"ExtGState": pdfcpu.Dict{"GS1": pdfcpu.Dict{"ca": pdfcpu.Float(0.5)}, "GS2": pdfcpu.Dict{"CA": pdfcpu.Integer(1)}}},
+		pdfcpu.StreamDict{Dict: pdfcpu.Dict{"Subtype": pdfcpu.Name("Image"), "ColorSpace": pdfcpu.Name("DeviceRGB")}},
+		pdfcpu.StreamDict{Dict: pdfcpu.Dict{"Subtype": pdfcpu.Name("Image"), "ColorSpace": pdfcpu.Name("DeviceCMYK")}},
+	}
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	ctx2.RootDict = pdfcpu.Dict{}
+	ctx2.Table = map[int]*pdfcpu.XRefTableEntry{}
+	for i, obj := range objects {
+		ctx2.Table[i+1] = &pdfcpu.XRefTableEntry{Object: obj}
+	}
+
+	scan := scanPDFXObjects(ctx2)
+	assert.Equal(t, 1, scan.pagesWithoutTrim)
+	assert.Equal(t, []string{"Helvetica"}, scan.unembeddedFonts)
+	assert.Equal(t, 1, scan.transparency)
+	assert.Equal(t, 1, scan.deviceRGB)
+
+	rules := func(report *PDFXReport) []string {
+		var rules []string
+		for _, issue := range report.Issues {
+			rules = append(rules, issue.Rule)
+		}
+		return rules
+	}
+	assert.Contains(t, rules(checkPDFX(ctx2, PDFX1a)), RuleTransparency)
+	assert.NotContains(t, rules(checkPDFX(ctx2, PDFX4)), RuleTransparency)
+	assert.Contains(t, rules(checkPDFX(ctx2, PDFX4)), RuleOutputIntent)
+}
+
+func TestFontResolver(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"Garamond.otf", "liberation/Carlito-Regular.ttf", "liberation/Carlito-Bold.ttf", "type1/Futura-BoldItalic.pfb", "README.txt"} {
+		path := filepath.Join(dir, name)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+		assert.NoError(t, os.WriteFile(path, []byte("font"), 0o644))
+	}
+	files, err := fontFiles(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+
+	resolver := newFontResolver(files, map[string]string{"calibri": "Carlito"})
+	tests := []struct {
+		font       string
+		resolution FontResolution
+		file       string
+	}{
+		{"Garamond", FontResolution{Font: "Garamond", Action: FontEmbedded}, "Garamond.otf"},
+		{"ABCDEF+Futura-BoldOblique", FontResolution{Font: "ABCDEF+Futura-BoldOblique", Action: FontEmbedded}, "type1/Futura-BoldItalic.pfb"},
+		{"Calibri", FontResolution{Font: "Calibri", Action: FontSubstituted, Substitute: "Carlito-Regular"}, "liberation/Carlito-Regular.ttf"},
+		{"Calibri,Bold", FontResolution{Font: "Calibri,Bold", Action: FontSubstituted, Substitute: "Carlito-Bold"}, "liberation/Carlito-Bold.ttf"},
+		{"Calibri-Italic", FontResolution{Font: "Calibri-Italic", Action: FontUnresolved}, ""},
+		{"Times-Roman", FontResolution{Font: "Times-Roman", Action: FontSubstituted, Substitute: "NimbusRoman"}, ""},
+		{"Wingdings", FontResolution{Font: "Wingdings", Action: FontUnresolved}, ""},
+	}
+	for _, tt := range tests {
+		resolution, path := resolver.resolve(tt.font)
+		assert.Equal(t, tt.resolution, resolution, tt.font)
+		if tt.file == "" {
+			assert.Empty(t, path, tt.font)
+		} else {
+			assert.Equal(t, filepath.Join(dir, tt.file), path, tt.font)
+		}
+	}
+
+	family, style := splitFontName("TimesNewRomanPS-BoldItalicMT")
+	assert.Equal(t, []string{"TimesNewRoman", "BoldItalic"}, []string{family, style})
+	family, style = splitFontName("ArialMT")
+	assert.Equal(t, []string{"Arial", "Regular"}, []string{family, style})
+	assert.False(t, fontmapName("Open Sans"))
+	assert.True(t, fontmapName("OpenSans-Bold"))
+}
+
+func TestParseInkCoverage(t *testing.T) {
+	output := " 0.02285  0.01210  0.00000  0.07421 CMYK OK\n" +
+		"Page 2 could not be rendered\n" +
+		" 0.00000  0.00000  0.00000  1.00000 CMYK OK\n"
+	assert.Equal(t, [][4]float64{{2.29, 1.21, 0, 7.42}, {0, 0, 0, 100}}, parseInkCoverage([]byte(output)))
+}
+
+func TestInkUsage(t *testing.T) {
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	pantone := pdfcpu.Array{pdfcpu.Name("Separation"), pdfcpu.Name("PANTONE 185 C"), pdfcpu.Name("DeviceCMYK"), pdfcpu.Dict{}}
+	varnish := pdfcpu.Array{pdfcpu.Name("DeviceN"), pdfcpu.Array{pdfcpu.Name("Cyan"), pdfcpu.Name("Varnish")}, pdfcpu.Name("DeviceCMYK"), pdfcpu.Dict{}}
+	form, err := ctx2.IndRefForNewObject(pdfcpu.StreamDict{Dict: pdfcpu.Dict{
+		"Subtype": pdfcpu.Name("Form"),
+		"Resources": pdfcpu.Dict{
+			"ExtGState": pdfcpu.Dict{"GS1": pdfcpu.Dict{"OP": pdfcpu.Boolean(true)}},
+			"XObject": pdfcpu.Dict{"Im1": pdfcpu.StreamDict{Dict: pdfcpu.Dict{
+				"Subtype":    pdfcpu.Name("Image"),
+				"ColorSpace": pdfcpu.Array{pdfcpu.Name("Indexed"), varnish, pdfcpu.Integer(1), pdfcpu.StringLiteral("")},
+			}}},
+		},
+	}})
+	assert.NoError(t, err)
+	pages := pdfcpu.Dict{"Type": pdfcpu.Name("Pages"), "Resources": pdfcpu.Dict{
+		"ColorSpace": pdfcpu.Dict{"CS1": pantone, "CS2": pdfcpu.Array{pdfcpu.Name("Separation"), pdfcpu.Name("All")}},
+	}}
+	pages["Kids"] = pdfcpu.Array{
+		pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "Parent": pages},
+		pdfcpu.Dict{"Type": pdfcpu.Name("Page"), "Resources": pdfcpu.Dict{
+			"ExtGState": pdfcpu.Dict{"GS1": pdfcpu.Dict{"OP": pdfcpu.Boolean(false)}},
+			"XObject":   pdfcpu.Dict{"Fm1": *form, "Fm2": *form},
+		}},
+	}
+	ctx2.RootDict = pdfcpu.Dict{"Pages": pages}
+
+	tree, err := pageTree(ctx2)
+	assert.NoError(t, err)
+	report := inkUsage(ctx2, tree)
+	assert.Equal(t, []PageInk{
+		{Page: 1, SpotColors: []string{"PANTONE 185 C"}},
+		{Page: 2, Overprint: true, SpotColors: []string{"Varnish"}},
+	}, report.Pages)
+	assert.Equal(t, 1, report.OverprintPages)
+	assert.Equal(t, []string{"PANTONE 185 C", "Varnish"}, report.SpotColors)
+}
+
+func TestMapSpotColors(t *testing.T) {
+	pantone := pdfcpu.Array{pdfcpu.Name("Separation"), pdfcpu.Name("PANTONE#20185#20C"), pdfcpu.Name("DeviceRGB"), pdfcpu.Dict{}}
+	objects := []pdfcpu.Object{
+		pdfcpu.Dict{"ColorSpace": pdfcpu.Dict{
+			"CS1": pantone,
+			"CS2": pdfcpu.Array{pdfcpu.Name("DeviceN"), pdfcpu.Array{pdfcpu.Name("Cyan"), pdfcpu.Name("Varnish")}, pdfcpu.Name("DeviceCMYK"), pdfcpu.Dict{}},
+		}},
+		pdfcpu.Array{pdfcpu.Name("Indexed"), pdfcpu.Array{pdfcpu.Name("Separation"), pdfcpu.Name("Gold"), pdfcpu.Name("DeviceCMYK"), pdfcpu.Dict{}},
+			pdfcpu.Integer(1), pdfcpu.StringLiteral("")},
+	}
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	ctx2.Table = map[int]*pdfcpu.XRefTableEntry{}
+	for i, obj := range objects {
+		ctx2.Table[i+1] = &pdfcpu.XRefTableEntry{Object: obj}
+	}
+
+	spots := mapSpotColors(ctx2, map[string][4]float64{spotKey("Pantone  185 c"): {0, 91, 76, 0}})
+	assert.Equal(t, []SpotConversion{
+		{Name: "Gold"},
+		{Name: "PANTONE 185 C", Mapped: true, CMYK: []float64{0, 91, 76, 0}},
+		{Name: "Varnish"},
+	}, spots)
+	assert.Equal(t, pdfcpu.Name("DeviceCMYK"), pantone[2])
+	assert.Equal(t, cmykTint([4]float64{0, 91, 76, 0}), pantone[3])
+	assert.Equal(t, pdfcpu.Array{pdfcpu.Float(0), pdfcpu.Float(0.91), pdfcpu.Float(0.76), pdfcpu.Float(0)}, cmykTint([4]float64{0, 91, 76, 0})["C1"])
+}
+
+func TestICCProfiles(t *testing.T) {
+	cmyk := pdfcpu.StreamDict{Dict: pdfcpu.Dict{"N": pdfcpu.Integer(4)}}
+	rgb := pdfcpu.StreamDict{Dict: pdfcpu.Dict{"N": pdfcpu.Integer(3), "Alternate": pdfcpu.Name("DeviceRGB")}}
+	catalog := pdfcpu.Dict{"OutputIntents": pdfcpu.Array{pdfcpu.Dict{
+		"S":                         pdfcpu.Name("GTS_PDFX"),
+		"OutputConditionIdentifier": pdfcpu.StringLiteral("FOGRA39"),
+		"DestOutputProfile":         *pdfcpu.NewIndirectRef(1, 0),
+	}}}
+	resources := pdfcpu.Dict{"ColorSpace": pdfcpu.Dict{
+		"CS0":        pdfcpu.Array{pdfcpu.Name("ICCBased"), *pdfcpu.NewIndirectRef(1, 0)},
+		"CS1":        pdfcpu.Array{pdfcpu.Name("Indexed"), pdfcpu.Array{pdfcpu.Name("ICCBased"), *pdfcpu.NewIndirectRef(2, 0)}, pdfcpu.Integer(1), pdfcpu.StringLiteral("")},
+		"DefaultRGB": pdfcpu.Array{pdfcpu.Name("ICCBased"), *pdfcpu.NewIndirectRef(2, 0)},
+	}}
+	newContext := func() *pdfcpu.Context {
+		ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+		ctx2.Table = map[int]*pdfcpu.XRefTableEntry{}
+		for i, obj := range []pdfcpu.Object{cmyk, rgb, catalog.Clone(), resources.Clone()} {
+			ctx2.Table[i+1] = &pdfcpu.XRefTableEntry{Object: obj}
+		}
+		ctx2.RootDict = ctx2.Table[3].Object.(pdfcpu.Dict)
+		return ctx2
+	}
+
+	ctx2 := newContext()
+	assert.Equal(t, []iccProfileRef{
+		{objNr: 1, components: 4, outputIntent: "GTS_PDFX", outputCondition: "FOGRA39"},
+		{objNr: 2, components: 3},
+	}, iccProfileRefs(ctx2))
+
+	assert.Equal(t, 4, stripICC(ctx2, false))
+	assert.Empty(t, iccProfileRefs(ctx2))
+	spaces := ctx2.Table[4].Object.(pdfcpu.Dict)["ColorSpace"].(pdfcpu.Dict)
+	assert.Equal(t, pdfcpu.Name("DeviceCMYK"), spaces["CS0"])
+	assert.Equal(t, pdfcpu.Name("DeviceRGB"), spaces["CS1"].(pdfcpu.Array)[1])
+	assert.NotContains(t, spaces, "DefaultRGB")
+	assert.NotContains(t, ctx2.RootDict, "OutputIntents")
+
+	ctx2 = newContext()
+	assert.Equal(t, 3, stripICC(ctx2, true))
+	assert.Equal(t, []iccProfileRef{
+		{objNr: 1, components: 4, outputIntent: "GTS_PDFX", outputCondition: "FOGRA39"},
+	}, iccProfileRefs(ctx2))
+}
+
+func TestICCDescription(t *testing.T) {
+	profile := func(tag []byte) []byte {
+		data := make([]byte, iccHeaderSize+16, iccHeaderSize+16+len(tag))
+		copy(data[36:], "acsp")
+		binary.BigEndian.PutUint32(data[iccHeaderSize:], 1)
+		copy(data[iccHeaderSize+4:], "desc")
+		binary.BigEndian.PutUint32(data[iccHeaderSize+8:], uint32(len(data)))
+		binary.BigEndian.PutUint32(data[iccHeaderSize+12:], uint32(len(tag)))
+		data = append(data, tag...)
+		binary.BigEndian.PutUint32(data, uint32(len(data)))
+		return data
+	}
+
+	text := []byte("desc\x00\x00\x00\x00\x00\x00\x00\x0fCoated FOGRA39\x00")
+	assert.Equal(t, "Coated FOGRA39", iccDescription(profile(text)))
+
+	mluc := []byte("mluc\x00\x00\x00\x00\x00\x00\x00\x01\x00\x00\x00\x0cenUS\x00\x00\x00\x08\x00\x00\x00\x1c\x00s\x00R\x00G\x00B")
+	assert.Equal(t, "sRGB", iccDescription(profile(mluc)))
+
+	assert.Equal(t, "", iccDescription(profile([]byte("desc\x00\x00\x00\x00\x00\x00\xff\xffshort"))))
+	assert.Equal(t, "", iccDescription(make([]byte, iccHeaderSize)))
+}
+
+func TestPageLabels(t *testing.T) {
+	ranges := []PageLabelRange{
+		{Page: 1, Style: LabelLowerRoman},
+		{Page: 4, Style: LabelDecimal},
+		{Page: 6, Style: LabelUpperAlpha, Prefix: "A-", Start: 26},
+		{Page: 8, Prefix: "Back cover"},
+	}
+	assert.NoError(t, validatePageLabels(ranges))
+
+	ctx2 := pdfcpu.NewContext(nil, pdfcpu.NewDefaultConfiguration())
+	ctx2.RootDict = pdfcpu.Dict{}
+	setPageLabels(ctx2, ranges)
+	assert.Equal(t, ranges, readPageLabels(ctx2))
+	assert.Equal(t, []string{"i", "ii", "iii", "1", "2", "A-Z", "A-AA", "Back cover"}, pageLabels(readPageLabels(ctx2), 8))
+
+	setPageLabels(ctx2, nil)
+	assert.NotContains(t, ctx2.RootDict, "PageLabels")
+	assert.Nil(t, pageLabels(readPageLabels(ctx2), 8))
+
+	assert.Equal(t, "MCMXCIV", formatLabel(LabelUpperRoman, 1994))
+	assert.Equal(t, "xlix", formatLabel(LabelLowerRoman, 49))
+	assert.Equal(t, "ccc", formatLabel(LabelLowerAlpha, 55))
+
+	for _, invalid := range [][]PageLabelRange{
+		{{Page: 2, Style: LabelDecimal}},
+		{{Page: 1}, {Page: 3}, {Page: 3}},
+		{{Page: 1, Style: "roman"}},
+		{{Page: 1, Start: -1}},
+	} {
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(validatePageLabels(invalid)))
+	}
+}
+
+func TestInterleaveOrder(t *testing.T) {
+	order, err := interleaveOrder(3, 3, false)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "4", "2", "5", "3", "6"}, order)
+
+	order, err = interleaveOrder(3, 2, true)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"1", "5", "2", "4", "3"}, order)
+
+	_, err = interleaveOrder(2, 3, false)
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+}
+
+func TestManifestOrder(t *testing.T) {
+	names := []string{"a.pdf", "b.pdf"}
+	items := []MergeItem{
+		{File: "b.pdf", Pages: "5"},
+		{File: "a.pdf", Pages: "1-3", Rotate: 90},
+		{File: "b.pdf", Pages: "4-", Rotate: -90},
+	}
+
+	indexes, err := resolveManifest(items, names)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1, 0, 1}, indexes)
+
+	layout, err := manifestOrder(items, indexes, []int{3, 6}, names)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"8", "1", "2", "3", "7", "8", "9"}, layout.order)
+	assert.Equal(t, map[int][]string{90: {"2", "3", "4"}, 270: {"5", "6", "7"}}, layout.rotations)
+	assert.Equal(t, []int{90, 270}, layout.sortedRotations())
+
+	_, err = resolveManifest([]MergeItem{{File: "c.pdf"}}, names)
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+
+	_, err = manifestOrder([]MergeItem{{File: "a.pdf", Pages: "2-4"}}, []int{0}, []int{3, 6}, names)
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+
+	_, err = manifestOrder([]MergeItem{{File: "a.pdf", Rotate: 45}}, []int{0}, []int{3, 6}, names)
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+}
+
+func TestImageEncoder(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{
+		Images: config.ImageConfig{Encoders: map[string]config.ImageEncoderConfig{"avif": {Quality: 60, Speed: 6}}},
+	})
+	lossless := true
+
+	encoder, err := svc.imageEncoder(&ConvertToImageRequest{Format: "avif"})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"--speed", "6", "-q", "60", "in.png", "out.avif"}, encoderArgs("avifenc", encoder, "in.png", "out.avif"))
+
+	encoder, err = svc.imageEncoder(&ConvertToImageRequest{Format: "webp", Quality: 90, Lossless: &lossless})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"-quiet", "-q", "90", "-lossless", "in.png", "-o", "out.webp"}, encoderArgs("cwebp", encoder, "in.png", "out.webp"))
+
+	_, err = svc.imageEncoder(&ConvertToImageRequest{Format: "jpeg", Lossless: &lossless})
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	_, err = svc.imageEncoder(&ConvertToImageRequest{Format: "jpeg", Quality: 101})
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+
+	assert.Equal(t, []int{1, 3, 5}, uniquePages([]int{5, 1, 3, 1}))
+}
+
+func TestLayoutSheet(t *testing.T) {
+	thumbs := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 100, 150)),
+		image.NewRGBA(image.Rect(0, 0, 100, 80)),
+		image.NewRGBA(image.Rect(0, 0, 100, 150)),
+	}
+
+	sheet := layoutSheet(thumbs, 2, 100)
+	assert.Equal(t, image.Rect(0, 0, 2*(100+sheetGap)+sheetGap, 2*(150+sheetGap)+sheetGap), sheet.Bounds())
+
+	// A single row is only as wide as its thumbnails
+	sheet = layoutSheet(thumbs[:1], 4, 100)
+	assert.Equal(t, image.Rect(0, 0, 100+2*sheetGap, 150+2*sheetGap), sheet.Bounds())
+}
+
+func TestPreviewFrames(t *testing.T) {
+	thumbs := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 100, 140)),
+		image.NewRGBA(image.Rect(0, 0, 100, 70)),
+	}
+
+	frames := previewFrames(thumbs, 100)
+	for _, frame := range frames {
+		assert.Equal(t, image.Rect(0, 0, 100, 140), frame.Bounds())
+	}
+
+	data, err := encodeGIF(frames, 1500)
+	assert.NoError(t, err)
+	anim, err := gif.DecodeAll(bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.Len(t, anim.Image, 2)
+	assert.Equal(t, []int{150, 150}, anim.Delay)
+
+	assert.Equal(t,
+		[]string{"-loop", "0", "-q", "80", "-d", "1500", "-lossy", "a.png", "b.png", "-o", "out.webp"},
+		img2webpArgs(config.ImageEncoderConfig{Quality: 80}, 1500, []string{"a.png", "b.png"}, "out.webp"))
+}
+
+func TestStitchPages(t *testing.T) {
+	thumbs := []image.Image{
+		image.NewRGBA(image.Rect(0, 0, 100, 140)),
+		image.NewRGBA(image.Rect(0, 0, 100, 70)),
+	}
+
+	assert.Equal(t, image.Rect(0, 0, 100, 220), stitchPages(thumbs, 100, 10).Bounds())
+	assert.NoError(t, checkLongImageSize(1080, 60000))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(checkLongImageSize(100, maxLongImageHeight+1)))
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(checkLongImageSize(2048, 40000)))
+}
+
+func TestShrinkStep(t *testing.T) {
+	encoder, dpi, ok := shrinkStep("jpeg", config.ImageEncoderConfig{Quality: 85}, 150)
+	assert.True(t, ok)
+	assert.Equal(t, 70, encoder.Quality)
+	assert.Equal(t, 150, dpi)
+
+	// Quality bottoms out before the resolution is lowered
+	encoder, dpi, ok = shrinkStep("jpeg", config.ImageEncoderConfig{Quality: minFitQuality}, 150)
+	assert.True(t, ok)
+	assert.Equal(t, minFitQuality, encoder.Quality)
+	assert.Equal(t, 120, dpi)
+
+	// Lossless and lossless-only formats only shrink in resolution
+	_, dpi, ok = shrinkStep("webp", config.ImageEncoderConfig{Quality: 80, Lossless: true}, 40)
+	assert.True(t, ok)
+	assert.Equal(t, minRenderDPI, dpi)
+	_, _, ok = shrinkStep("png", config.ImageEncoderConfig{}, minRenderDPI)
+	assert.False(t, ok)
+
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{})
+	progressive := true
+	encoder, err := svc.imageEncoder(&ConvertToImageRequest{Format: "jpeg", Progressive: &progressive})
+	assert.NoError(t, err)
+	assert.True(t, encoder.Progressive)
+	_, err = svc.imageEncoder(&ConvertToImageRequest{Format: "png", Progressive: &progressive})
+	assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+}
+
+func TestParsePageCount(t *testing.T) {
+	count, err := parsePageCount([]byte("   **** Error: xref table is damaged\n1250\n"))
+	assert.NoError(t, err)
+	assert.Equal(t, 1250, count)
+
+	_, err = parsePageCount(nil)
+	assert.Equal(t, ErrCodeCorrupted, CodeOf(err))
+	_, err = parsePageCount([]byte("Error: /undefined in runpdfbegin\n"))
+	assert.Equal(t, ErrCodeCorrupted, CodeOf(err))
+}
+
+func TestDocumentKeys(t *testing.T) {
+	encrypt := "<< /Filter /Standard /V 2 /R 3 /O (owner\\)hash) /U (user\\)hash\\000) /P -4 >>"
+	keys, err := documentKeys([]byte("%PDF-1.7\n1 0 obj\n" + encrypt + "\nendobj\n"))
+	assert.NoError(t, err)
+	assert.Len(t, keys, 1)
+
+	t.Run("Same Verifier Same Key", func(t *testing.T) {
+		for _, variant := range []string{
+			"%PDF-1.7\n9 0 obj\n" + encrypt + "\nendobj\ntrailer << /ID [<01> <02>] >>\n%%EOF\ntrailing bytes",
+			"%PDF-1.7\n1 0 obj\n<< /O (x) /U <7573657229686173680>>>\nendobj\n",
+			"%PDF-1.7\n1 0 obj\n<< /O (x) /#55 % comment\n (user\\051ha\\\nsh\\0) >>\nendobj\n",
+			"%PDF-1.7\n1 0 obj\n<< /O (x) /U 7 0 R >>\nendobj\n7 0 obj (user\\)hash\\000)\nendobj\n",
+		} {
+			got, err := documentKeys([]byte(variant))
+			assert.NoError(t, err)
+			assert.Equal(t, keys, got, variant)
+		}
+	})
+
+	t.Run("Decoys Add Keys", func(t *testing.T) {
+		got, err := documentKeys([]byte("%PDF-1.7\n<< /U (decoy) >>\n" + encrypt + "\n"))
+		assert.NoError(t, err)
+		assert.Len(t, got, 2)
+		assert.Contains(t, got, keys[0])
+	})
+
+	t.Run("Other Names Are Ignored", func(t *testing.T) {
+		got, err := documentKeys([]byte("%PDF-1.7\n<< /URI (https://example.com) /UF (file.pdf) /AA << /U 3 0 R >> >>\n3 0 obj << /S /URI >> endobj\n"))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{unrecognizedDocument}, got)
+	})
+
+	t.Run("Too Many Verifiers", func(t *testing.T) {
+		var data bytes.Buffer
+		for i := 0; i <= maxPasswordVerifiers; i++ {
+			fmt.Fprintf(&data, "<< /U (verifier %d) >>\n", i)
+		}
+		_, err := documentKeys(data.Bytes())
+		assert.Equal(t, ErrCodeInvalidInput, CodeOf(err))
+	})
+}
+
+func TestPDFService_DecryptPDF(t *testing.T) {
+	svc := NewPDFService(logger.New("info", "text"), &config.Config{
+		DecryptLockout: config.LockoutConfig{MaxAttempts: 3, BaseDelay: 1, MaxDelay: 2, Lockout: 60, Window: 60},
+	})
+
+	conf := pdfcpu.NewDefaultConfiguration()
+	conf.UserPW = "secret"
+	conf.OwnerPW = "secret"
+	conf.EncryptUsingAES = true
+	conf.EncryptKeyLength = 256
+	var encrypted bytes.Buffer
+	if !assert.NoError(t, api.Encrypt(bytes.NewReader(benchPDF(1)), &encrypted, conf)) {
+		return
+	}
+
+	t.Run("Wrong Password", func(t *testing.T) {
+		_, err := svc.DecryptPDF(context.Background(), &DecryptRequest{PDFData: encrypted.Bytes(), Password: "guess", Client: "a"})
+		assert.Equal(t, ErrCodeEncrypted, CodeOf(err))
+	})
+
+	t.Run("Altered Copies Share The Lockout", func(t *testing.T) {
+		altered := append(bytes.Clone(encrypted.Bytes()), "\n% altered\n"...)
+		_, err := svc.DecryptPDF(context.Background(), &DecryptRequest{PDFData: altered, Password: "guess", Client: "b"})
+		assert.Equal(t, ErrCodeTooManyAttempts, CodeOf(err))
+	})
 }
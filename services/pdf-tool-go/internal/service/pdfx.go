@@ -0,0 +1,484 @@
+package service
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// PDFXStandard is a PDF/X conformance level
+type PDFXStandard string
+
+const (
+	// PDFX1a requires CMYK or spot colour only, embedded fonts and no
+	// transparency
+	PDFX1a PDFXStandard = "x1a"
+	// PDFX4 allows live transparency and colour managed content
+	PDFX4 PDFXStandard = "x4"
+)
+
+// pdfxStandards describes how each standard is identified and produced
+var pdfxStandards = map[PDFXStandard]struct {
+	// version is the GTS_PDFXVersion written to the document information;
+	// checks accept any value with the same prefix up to the colon
+	version string
+	// compatibility is the PDF version written. Ghostscript flattens
+	// transparency when writing PDF 1.3.
+	compatibility string
+	transparency  bool
+}{
+	PDFX1a: {"PDF/X-1a:2003", "1.3", false},
+	PDFX4:  {"PDF/X-4", "1.6", true},
+}
+
+// PDF/X rules checked by CheckPDFX
+const (
+	RuleOutputIntent  = "output_intent"
+	RulePDFXVersion   = "pdfx_version"
+	RuleTrapped       = "trapped"
+	RuleTrimBox       = "trim_box"
+	RuleFontsEmbedded = "fonts_embedded"
+	RuleTransparency  = "transparency"
+	RuleDeviceRGB     = "device_rgb"
+	RuleEncryption    = "encryption"
+)
+
+// PDFXIssue is a failed PDF/X rule
+type PDFXIssue struct {
+	Rule    string
+	Message string
+	// Fixable issues are resolved by ConvertPDFX
+	Fixable bool
+}
+
+// PDFXReport is the result of a PDF/X check
+type PDFXReport struct {
+	Standard  PDFXStandard
+	Compliant bool
+	PageCount int
+	Issues    []PDFXIssue
+}
+
+// ConvertPDFXRequest represents a PDF/X conversion request
+type ConvertPDFXRequest struct {
+	PDFData  []byte
+	Standard PDFXStandard
+	// Profile names a CMYK output ICC profile from config; empty uses the
+	// default
+	Profile string
+	// ICCProfile is an uploaded ICC profile, used instead of Profile
+	ICCProfile []byte
+	// OutputCondition identifies the printing condition, e.g. "FOGRA39";
+	// it defaults to the profile name
+	OutputCondition string
+}
+
+// PDFXResponse contains a converted document and the issues that remain
+type PDFXResponse struct {
+	PDF    []byte `json:"-"`
+	Report *PDFXReport
+}
+
+// CheckPDFX audits a document against the structural PDF/X rules of a
+// standard: an output intent, version identification, trapping state, trim
+// boxes, embedded fonts and, for PDF/X-1a, no transparency or RGB colour.
+// RGB colour is found in images and colour space resources; colours set
+// directly in content streams are not inspected.
+func (s *PDFService) CheckPDFX(ctx context.Context, pdfData []byte, standard PDFXStandard) (_ *PDFXReport, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.CheckPDFX")
+	defer span.End()
+
+	op := metrics.Start("check_pdfx")
+	op.Input(int64(len(pdfData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "check_pdfx")
+	defer cancel()
+
+	if _, ok := pdfxStandards[standard]; !ok {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported PDF/X standard %q; use x1a or x4", standard), nil)
+	}
+	span.SetAttributes(attribute.String("standard", string(standard)))
+
+	s.log.Info("Checking PDF/X conformance", "standard", standard)
+
+	var report *PDFXReport
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, pdfData)
+		if err != nil {
+			return err
+		}
+		report = checkPDFX(ctx2, standard)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(report.PageCount)
+	span.SetAttributes(attribute.Int("issues", len(report.Issues)))
+
+	s.log.Info("PDF/X check completed", "standard", standard, "compliant", report.Compliant, "issues", len(report.Issues))
+
+	return report, nil
+}
+
+// ConvertPDFX rewrites a document for a PDF/X standard: colour is converted
+// to CMYK through the output profile, which is embedded as output intent,
+// fonts are embedded, trim boxes added and the version and trapping state
+// declared. For PDF/X-1a transparency is flattened. The converted document
+// is checked again and the issues that remain are returned with it.
+func (s *PDFService) ConvertPDFX(ctx context.Context, req *ConvertPDFXRequest) (_ *PDFXResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ConvertPDFX")
+	defer span.End()
+
+	op := metrics.Start("convert_pdfx")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "convert_pdfx")
+	defer cancel()
+
+	standard, ok := pdfxStandards[req.Standard]
+	if !ok {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported PDF/X standard %q; use x1a or x4", req.Standard), nil)
+	}
+	iccData, profileName, err := s.iccProfileData(req.Profile, req.ICCProfile)
+	if err != nil {
+		return nil, err
+	}
+	profile, err := parseICCProfile(iccData)
+	if err != nil {
+		return nil, err
+	}
+	// Both standards are produced in CMYK, so the output intent must be a
+	// CMYK printer profile
+	if err := validateOutputIntent(profile, ColorCMYK); err != nil {
+		return nil, err
+	}
+	condition := req.OutputCondition
+	if condition == "" {
+		condition = profileName
+	}
+	span.SetAttributes(attribute.String("standard", string(req.Standard)), attribute.String("profile", profileName))
+
+	s.log.Info("Converting PDF to PDF/X", "standard", req.Standard, "profile", profileName)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	prologue := outputIntentPS("profile.icc", colorSpaces[ColorCMYK].components, condition) + pdfxInfoPS(standard.version)
+	args := []string{
+		"-dPDFX",
+		"-dCompatibilityLevel=" + standard.compatibility,
+		"-dEmbedAllFonts=true",
+		"-dSubsetFonts=true",
+	}
+	response := &PDFXResponse{}
+	err = s.runHeavy(ctx, func() error {
+		converted, err := s.convertColor(ctx, req.PDFData, ColorCMYK, iccData, prologue, args)
+		if err != nil {
+			return err
+		}
+		ctx2, err := s.readContext(ctx, converted)
+		if err != nil {
+			return err
+		}
+		response.PDF = converted
+		response.Report = checkPDFX(ctx2, req.Standard)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(response.PDF)))
+	s.log.Info("PDF/X conversion completed", "size", len(response.PDF), "remaining_issues", len(response.Report.Issues))
+
+	return response, nil
+}
+
+// pdfxInfoPS returns PostScript declaring the PDF/X version in the
+// document information, since Ghostscript's PDF/X mode identifies its
+// output as PDF/X-3, and that the document is not trapped
+func pdfxInfoPS(version string) string {
+	return fmt.Sprintf("[/GTS_PDFXVersion (%s) /Trapped /False /DOCINFO pdfmark\n", psString(version))
+}
+
+// checkPDFX runs the PDF/X rules of standard over a parsed document
+func checkPDFX(ctx2 *pdfcpu.Context, standard PDFXStandard) *PDFXReport {
+	report := &PDFXReport{Standard: standard, PageCount: ctx2.PageCount, Issues: []PDFXIssue{}}
+	fail := func(rule, message string, fixable bool) {
+		report.Issues = append(report.Issues, PDFXIssue{Rule: rule, Message: message, Fixable: fixable})
+	}
+	target := pdfxStandards[standard]
+
+	if ctx2.Encrypt != nil {
+		fail(RuleEncryption, "document is encrypted", true)
+	}
+
+	if !hasPDFXOutputIntent(ctx2) {
+		fail(RuleOutputIntent, "document has no GTS_PDFX output intent with an embedded profile", true)
+	}
+
+	prefix, _, _ := strings.Cut(target.version, ":")
+	if version := infoString(ctx2, "GTS_PDFXVersion"); !strings.HasPrefix(version, prefix) {
+		fail(RulePDFXVersion, fmt.Sprintf("document information does not identify %s (GTS_PDFXVersion)", prefix), true)
+	}
+	if trapped := infoEntry(ctx2, "Trapped"); trapped != pdfcpu.Name("True") && trapped != pdfcpu.Name("False") {
+		fail(RuleTrapped, "document information must state whether it is trapped (Trapped /True or /False)", true)
+	}
+
+	scan := scanPDFXObjects(ctx2)
+	if scan.pagesWithoutTrim > 0 {
+		fail(RuleTrimBox, fmt.Sprintf("%d pages have neither a TrimBox nor an ArtBox", scan.pagesWithoutTrim), true)
+	}
+	if len(scan.unembeddedFonts) > 0 {
+		fail(RuleFontsEmbedded, "fonts are not embedded: "+strings.Join(scan.unembeddedFonts, ", "), true)
+	}
+	if !target.transparency {
+		if scan.transparency > 0 {
+			fail(RuleTransparency, fmt.Sprintf("%d objects use transparency", scan.transparency), true)
+		}
+		if scan.deviceRGB > 0 {
+			fail(RuleDeviceRGB, fmt.Sprintf("%d images or colour spaces use RGB colour", scan.deviceRGB), true)
+		}
+	}
+
+	report.Compliant = len(report.Issues) == 0
+	return report
+}
+
+// hasPDFXOutputIntent reports whether the catalog has a GTS_PDFX output
+// intent with a destination profile
+func hasPDFXOutputIntent(ctx2 *pdfcpu.Context) bool {
+	intents, ok := ctx2.RootDict.Find("OutputIntents")
+	if !ok {
+		return false
+	}
+	obj, err := ctx2.Dereference(intents)
+	if err != nil {
+		return false
+	}
+	array, _ := obj.(pdfcpu.Array)
+	for _, entry := range array {
+		intent, err := ctx2.DereferenceDict(entry)
+		if err != nil || intent == nil {
+			continue
+		}
+		kind, _ := intent.Find("S")
+		_, profile := intent.Find("DestOutputProfile")
+		if kind == pdfcpu.Name("GTS_PDFX") && profile {
+			return true
+		}
+	}
+	return false
+}
+
+// infoEntry returns an entry of the document information, or nil
+func infoEntry(ctx2 *pdfcpu.Context, key string) pdfcpu.Object {
+	if ctx2.Info == nil {
+		return nil
+	}
+	info, err := ctx2.DereferenceDict(*ctx2.Info)
+	if err != nil || info == nil {
+		return nil
+	}
+	obj, _ := info.Find(key)
+	if obj, err = ctx2.Dereference(obj); err != nil {
+		return nil
+	}
+	return obj
+}
+
+// infoString returns a string entry of the document information
+func infoString(ctx2 *pdfcpu.Context, key string) string {
+	switch value := infoEntry(ctx2, key).(type) {
+	case pdfcpu.StringLiteral:
+		return string(value)
+	case pdfcpu.HexLiteral:
+		decoded, _ := hex.DecodeString(string(value))
+		return string(decoded)
+	}
+	return ""
+}
+
+// pdfxScan counts the objects of a document breaking PDF/X rules
+type pdfxScan struct {
+	pagesWithoutTrim int
+	unembeddedFonts  []string
+	transparency     int
+	deviceRGB        int
+}
+
+// scanPDFXObjects inspects every object of a document, which finds fonts,
+// graphics states and images wherever their resources are inherited from
+func scanPDFXObjects(ctx2 *pdfcpu.Context) pdfxScan {
+	var scan pdfxScan
+	fonts := make(map[string]bool)
+	for _, entry := range ctx2.Table {
+		if entry == nil || entry.Free {
+			continue
+		}
+		var dict pdfcpu.Dict
+		switch obj := entry.Object.(type) {
+		case pdfcpu.Dict:
+			dict = obj
+		case pdfcpu.StreamDict:
+			dict = obj.Dict
+		default:
+			continue
+		}
+
+		kind, _ := dict.Find("Type")
+		subtype, _ := dict.Find("Subtype")
+		switch {
+		case kind == pdfcpu.Name("Page"):
+			_, trim := dict.Find("TrimBox")
+			_, art := dict.Find("ArtBox")
+			if !trim && !art {
+				scan.pagesWithoutTrim++
+			}
+		case kind == pdfcpu.Name("Font"):
+			if name, ok := unembeddedFont(ctx2, dict); ok {
+				fonts[name] = true
+			}
+		case subtype == pdfcpu.Name("Image"):
+			if smask, ok := dict.Find("SMask"); ok && smask != pdfcpu.Name("None") {
+				scan.transparency++
+			}
+			if colorSpace, _ := dict.Find("ColorSpace"); isRGB(ctx2, colorSpace) {
+				scan.deviceRGB++
+			}
+		}
+
+		if group, ok := dictEntry(ctx2, dict, "Group"); ok {
+			if s, _ := group.Find("S"); s == pdfcpu.Name("Transparency") {
+				scan.transparency++
+			}
+		}
+		// Graphics states need no Type, so they are found through the
+		// resource dictionaries naming them
+		if states, ok := dictEntry(ctx2, dict, "ExtGState"); ok {
+			for _, obj := range states {
+				state, err := ctx2.DereferenceDict(obj)
+				if err == nil && state != nil && usesTransparency(ctx2, state) {
+					scan.transparency++
+				}
+			}
+		}
+		if spaces, ok := dictEntry(ctx2, dict, "ColorSpace"); ok {
+			for _, colorSpace := range spaces {
+				if isRGB(ctx2, colorSpace) {
+					scan.deviceRGB++
+				}
+			}
+		}
+	}
+
+	for name := range fonts {
+		scan.unembeddedFonts = append(scan.unembeddedFonts, name)
+	}
+	sort.Strings(scan.unembeddedFonts)
+	return scan
+}
+
+// unembeddedFont returns the name of a font whose program is not embedded.
+// Type 3 fonts are defined in the document, and composite fonts are
+// checked through their descendant fonts, which are objects of their own.
+func unembeddedFont(ctx2 *pdfcpu.Context, font pdfcpu.Dict) (string, bool) {
+	subtype, _ := font.Find("Subtype")
+	if subtype == pdfcpu.Name("Type3") || subtype == pdfcpu.Name("Type0") {
+		return "", false
+	}
+	name := "unnamed"
+	if base, ok := font.Find("BaseFont"); ok {
+		if n, ok := base.(pdfcpu.Name); ok {
+			name = string(n)
+		}
+	}
+	descriptor, ok := dictEntry(ctx2, font, "FontDescriptor")
+	if !ok {
+		return name, true
+	}
+	for _, key := range []string{"FontFile", "FontFile2", "FontFile3"} {
+		if _, ok := descriptor.Find(key); ok {
+			return "", false
+		}
+	}
+	return name, true
+}
+
+// usesTransparency reports whether a graphics state sets a soft mask, a
+// blend mode other than normal or an alpha below 1
+func usesTransparency(ctx2 *pdfcpu.Context, state pdfcpu.Dict) bool {
+	if smask, ok := state.Find("SMask"); ok && smask != pdfcpu.Name("None") {
+		return true
+	}
+	if mode, ok := state.Find("BM"); ok && mode != pdfcpu.Name("Normal") && mode != pdfcpu.Name("Compatible") {
+		return true
+	}
+	for _, key := range []string{"CA", "ca"} {
+		obj, ok := state.Find(key)
+		if !ok {
+			continue
+		}
+		obj, _ = ctx2.Dereference(obj)
+		switch alpha := obj.(type) {
+		case pdfcpu.Float:
+			if alpha < 1 {
+				return true
+			}
+		case pdfcpu.Integer:
+			if alpha < 1 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isRGB reports whether a colour space is device, calibrated or ICC based
+// RGB
+func isRGB(ctx2 *pdfcpu.Context, colorSpace pdfcpu.Object) bool {
+	obj, err := ctx2.Dereference(colorSpace)
+	if err != nil {
+		return false
+	}
+	switch space := obj.(type) {
+	case pdfcpu.Name:
+		return space == "DeviceRGB"
+	case pdfcpu.Array:
+		if len(space) < 2 {
+			return false
+		}
+		switch space[0] {
+		case pdfcpu.Name("CalRGB"):
+			return true
+		case pdfcpu.Name("ICCBased"):
+			stream, err := ctx2.Dereference(space[1])
+			if err != nil {
+				return false
+			}
+			profile, ok := stream.(pdfcpu.StreamDict)
+			if !ok {
+				return false
+			}
+			n, _ := profile.Find("N")
+			return n == pdfcpu.Integer(3)
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/color/palette"
+	"image/draw"
+	"image/gif"
+	"strconv"
+
+	"github.com/disintegration/imaging"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// Animated preview bounds and defaults
+const (
+	defaultPreviewPages = 5
+	maxPreviewPages     = 20
+	defaultPreviewWidth = 400
+	maxPreviewWidth     = 1200
+	defaultFrameDelay   = 1000
+	minFrameDelay       = 100
+	maxFrameDelay       = 10000
+)
+
+// PreviewFormats maps animated preview formats to their content types
+var PreviewFormats = map[string]string{
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// previewBackground fills frames around pages narrower or shorter than
+// the preview
+var previewBackground = color.White
+
+// PreviewRequest represents an animated preview request
+type PreviewRequest struct {
+	PDFData []byte
+	// Pages is the number of leading pages cycled through; 0 uses the
+	// default. Shorter documents use every page.
+	Pages int
+	// Width is the preview width in pixels; 0 uses the default
+	Width int
+	// FrameDelay is how long each page is shown, in milliseconds; 0 uses
+	// the default
+	FrameDelay int
+	// Format is gif or webp
+	Format string
+}
+
+// AnimatedPreview renders the first pages of a document as an animation
+// looping through them, for document previews when sharing
+func (s *PDFService) AnimatedPreview(ctx context.Context, req *PreviewRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.AnimatedPreview")
+	defer span.End()
+
+	op := metrics.Start("preview")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "preview")
+	defer cancel()
+
+	count, width, delay := req.Pages, req.Width, req.FrameDelay
+	if count == 0 {
+		count = defaultPreviewPages
+	}
+	if width == 0 {
+		width = defaultPreviewWidth
+	}
+	if delay == 0 {
+		delay = defaultFrameDelay
+	}
+	if count < 1 || count > maxPreviewPages {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("pages must be between 1 and %d", maxPreviewPages), nil)
+	}
+	if width < minThumbWidth || width > maxPreviewWidth {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("width must be between %d and %d", minThumbWidth, maxPreviewWidth), nil)
+	}
+	if delay < minFrameDelay || delay > maxFrameDelay {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("frame delay must be between %d and %d ms", minFrameDelay, maxFrameDelay), nil)
+	}
+	if _, ok := PreviewFormats[req.Format]; !ok {
+		return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported preview format %q; use gif or webp", req.Format), nil)
+	}
+	span.SetAttributes(attribute.Int("pages", count), attribute.Int("width", width))
+
+	s.log.Info("Rendering animated preview", "pages", count, "width", width, "format", req.Format)
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	pages := make([]int, min(count, pageCount))
+	for i := range pages {
+		pages[i] = i + 1
+	}
+	op.Pages(len(pages))
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	var preview []byte
+	err = s.runHeavy(ctx, func() error {
+		thumbs, err := s.thumbnails(ctx, req.PDFData, pages, width)
+		if err != nil {
+			return err
+		}
+		frames := previewFrames(thumbs, width)
+		if req.Format == "gif" {
+			preview, err = encodeGIF(frames, delay)
+			return err
+		}
+		preview, err = s.encodeAnimatedWebP(ctx, frames, delay)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(preview)))
+	s.log.Info("Animated preview completed", "pages", len(pages), "size", len(preview))
+
+	return preview, nil
+}
+
+// previewFrames centres thumbnails on frames of a common size, as wide as
+// width and as tall as the tallest thumbnail
+func previewFrames(thumbs []image.Image, width int) []*image.NRGBA {
+	height := 0
+	for _, thumb := range thumbs {
+		height = max(height, thumb.Bounds().Dy())
+	}
+	frames := make([]*image.NRGBA, len(thumbs))
+	for i, thumb := range thumbs {
+		size := thumb.Bounds().Size()
+		frame := imaging.New(width, height, previewBackground)
+		frames[i] = imaging.Paste(frame, thumb, image.Pt((width-size.X)/2, (height-size.Y)/2))
+	}
+	return frames
+}
+
+// encodeGIF encodes frames as a looping GIF, dithered to a fixed palette
+func encodeGIF(frames []*image.NRGBA, delay int) ([]byte, error) {
+	anim := &gif.GIF{}
+	for _, frame := range frames {
+		paletted := image.NewPaletted(frame.Bounds(), palette.Plan9)
+		draw.FloydSteinberg.Draw(paletted, frame.Bounds(), frame, image.Point{})
+		anim.Image = append(anim.Image, paletted)
+		// GIF delays are in hundredths of a second
+		anim.Delay = append(anim.Delay, delay/10)
+	}
+
+	var buf bytes.Buffer
+	if err := gif.EncodeAll(&buf, anim); err != nil {
+		return nil, fmt.Errorf("failed to encode gif: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// encodeAnimatedWebP encodes frames as a looping WebP with img2webp, at
+// the configured webp encoder settings
+func (s *PDFService) encodeAnimatedWebP(ctx context.Context, frames []*image.NRGBA, delay int) ([]byte, error) {
+	encoder, err := s.imageEncoder(&ConvertToImageRequest{Format: "webp"})
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	names := make([]string, len(frames))
+	for i, frame := range frames {
+		data, err := encodeImage(frame, "png", 0)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = fmt.Sprintf("frame-%04d.png", i+1)
+		if _, err := ws.WriteFile(names[i], data); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := s.runner.Run(ctx, ws, exec.Command{Tool: "img2webp", Args: img2webpArgs(encoder, delay, names, "preview.webp")}); err != nil {
+		return nil, toolError(ctx, err, "img2webp")
+	}
+	data, err := ws.ReadFile("preview.webp")
+	if err != nil {
+		return nil, fmt.Errorf("failed to read img2webp output: %w", err)
+	}
+	return data, nil
+}
+
+// img2webpArgs returns the arguments encoding frames, shown for delay
+// milliseconds each, to a looping animation at output
+func img2webpArgs(encoder config.ImageEncoderConfig, delay int, frames []string, output string) []string {
+	args := []string{"-loop", "0", "-q", strconv.Itoa(encoder.Quality), "-d", strconv.Itoa(delay)}
+	if encoder.Lossless {
+		args = append(args, "-lossless")
+	} else {
+		args = append(args, "-lossy")
+	}
+	args = append(args, frames...)
+	return append(args, "-o", output)
+}
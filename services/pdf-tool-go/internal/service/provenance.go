@@ -0,0 +1,129 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/pipeline"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/provenance"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+)
+
+// SetProvenance sets the signer of the C2PA manifests embedded in the
+// outputs of tenants opted in. It must be called before the service
+// handles requests.
+func (s *PDFService) SetProvenance(signer *provenance.Signer) {
+	s.provenance = signer
+}
+
+// provenanceStep embeds a signed manifest of the operation in each PDF
+// output of tenants opted in. It runs last, so the manifest binds the
+// final output.
+func (s *PDFService) provenanceStep() pipeline.Step {
+	return pipeline.Step{Name: "provenance", Mode: pipeline.Cancellable, Fn: func(st *pipeline.State) error {
+		if !s.provenance.Has(tenant.FromContext(st.Ctx)) {
+			return nil
+		}
+		now := time.Now()
+		var err error
+		if st.Output, err = s.embedManifest(st, st.Output, now); err != nil {
+			return err
+		}
+		for i, part := range st.Parts {
+			if st.Parts[i], err = s.embedManifest(st, part, now); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// embedManifest embeds a manifest in an output as an associated file of
+// the document, replacing the manifest of an earlier operation, which the
+// rewrite invalidated. Outputs that are not PDF, or are encrypted, are
+// left alone.
+func (s *PDFService) embedManifest(st *pipeline.State, data []byte, when time.Time) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return data, nil
+	}
+	ctx2, err := s.readContext(st.Ctx, data)
+	if CodeOf(err) == ErrCodeEncrypted || (err == nil && ctx2.Encrypt != nil) {
+		return data, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := s.provenance.Manifest(st.Operation, when)
+	placeholder, err := manifest.Placeholder()
+	if err != nil {
+		return nil, err
+	}
+	if err := attachManifest(ctx2, placeholder); err != nil {
+		return nil, fmt.Errorf("failed to attach manifest: %w", err)
+	}
+	var buf bytes.Buffer
+	err = inSpan(st.Ctx, "pdfcpu.write", func() error {
+		return api.WriteContext(ctx2, &buf)
+	})
+	if err != nil {
+		return nil, classifyPDFError(err, "failed to write PDF")
+	}
+
+	output := buf.Bytes()
+	offset := bytes.Index(output, placeholder)
+	store, err := manifest.Seal(output, offset)
+	if err != nil {
+		return nil, err
+	}
+	copy(output[offset:], store)
+	return output, nil
+}
+
+// attachManifest adds a manifest store as an uncompressed embedded file
+// referenced from the catalog's associated files, as C2PA specifies for
+// PDF. Earlier manifests are dropped.
+func attachManifest(ctx2 *pdfcpu.Context, store []byte) error {
+	length := int64(len(store))
+	stream := pdfcpu.StreamDict{
+		Dict: pdfcpu.Dict{
+			"Type":    pdfcpu.Name("EmbeddedFile"),
+			"Subtype": pdfcpu.Name("application#2Fc2pa"),
+			"Length":  pdfcpu.Integer(len(store)),
+		},
+		StreamLength: &length,
+		Content:      store,
+		Raw:          store,
+	}
+	streamRef, err := ctx2.IndRefForNewObject(stream)
+	if err != nil {
+		return err
+	}
+	fileSpec, err := ctx2.IndRefForNewObject(pdfcpu.Dict{
+		"Type":           pdfcpu.Name("Filespec"),
+		"F":              pdfcpu.StringLiteral("manifest.c2pa"),
+		"UF":             pdfcpu.StringLiteral("manifest.c2pa"),
+		"Desc":           pdfcpu.StringLiteral("C2PA Manifest Store"),
+		"AFRelationship": pdfcpu.Name("C2PA_Manifest"),
+		"EF":             pdfcpu.Dict{"F": *streamRef},
+	})
+	if err != nil {
+		return err
+	}
+
+	associated := pdfcpu.Array{*fileSpec}
+	if existing, err := ctx2.DereferenceArray(ctx2.RootDict["AF"]); err == nil {
+		for _, entry := range existing {
+			spec, err := ctx2.DereferenceDict(entry)
+			if err == nil && spec != nil && spec["AFRelationship"] == pdfcpu.Name("C2PA_Manifest") {
+				continue
+			}
+			associated = append(associated, entry)
+		}
+	}
+	ctx2.RootDict.Update("AF", associated)
+	return nil
+}
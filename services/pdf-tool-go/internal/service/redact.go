@@ -0,0 +1,232 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/png"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// redactionPadding widens redaction boxes beyond the OCR bounding box, in
+// pixels of the rendered page, so glyph edges are covered too
+const redactionPadding = 4
+
+// RedactPIIRequest represents a PII detection and redaction request
+type RedactPIIRequest struct {
+	PDFData []byte
+	// Entities names the extractors detecting PII; empty uses the
+	// configured defaults
+	Entities []string
+	// ReportOnly returns the PII found without producing a redacted copy
+	ReportOnly bool
+}
+
+// RedactPIIResponse reports the PII found in a document
+type RedactPIIResponse struct {
+	PageCount int
+	Entities  []Entity
+	// RedactedPages lists the pages containing PII
+	RedactedPages []int
+	// PDF is the redacted copy unless only a report was requested
+	PDF []byte `json:"-"`
+}
+
+// textSpan is a byte range of a page text
+type textSpan struct{ start, end int }
+
+// RedactPII finds PII with the selected entity extractors and, unless only
+// a report is requested, returns a copy with every match blacked out. Pages
+// are rendered and OCRed, then the redacted page images are rebuilt into a
+// PDF with a fresh OCR text layer, so the PII is gone from both the image
+// and the text rather than merely hidden behind a box. Pages without PII go
+// through the same path and come out as images too.
+func (s *PDFService) RedactPII(ctx context.Context, req *RedactPIIRequest) (_ *RedactPIIResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.RedactPII")
+	defer span.End()
+
+	op := metrics.Start("redact_pii")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "redact_pii")
+	defer cancel()
+
+	names := req.Entities
+	if len(names) == 0 {
+		names = s.config.PII.Entities
+	}
+	if len(names) == 0 {
+		return nil, NewError(ErrCodeInvalidInput, "no PII entity extractors selected", nil)
+	}
+	extractors, err := s.entityExtractors(names)
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.StringSlice("entities", names), attribute.Bool("report_only", req.ReportOnly))
+
+	s.log.Info("Detecting PII in PDF", "entities", names, "report_only", req.ReportOnly)
+
+	if !s.config.PDF.OCREnabled {
+		return nil, NewError(ErrCodeToolUnavailable, "OCR is disabled on this server", nil)
+	}
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response := &RedactPIIResponse{PageCount: pageCount}
+	err = s.runHeavy(ctx, func() error {
+		return s.redactPII(ctx, req, pageCount, extractors, response)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(response.PDF)))
+	s.log.Info("PII detection completed", "entities", len(response.Entities), "redacted_pages", len(response.RedactedPages))
+
+	return response, nil
+}
+
+// redactPII fills response from the rendered and OCRed pages
+func (s *PDFService) redactPII(ctx context.Context, req *RedactPIIRequest, pageCount int, extractors map[string]EntityExtractor, response *RedactPIIResponse) error {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	if _, err := ws.WriteFile("input.pdf", req.PDFData); err != nil {
+		return err
+	}
+	// Colour is kept, since the rendered pages become the redacted copy
+	if err := s.renderPages(ctx, ws, "png16m"); err != nil {
+		return err
+	}
+
+	all := make([]int, pageCount)
+	for i := range all {
+		all[i] = i + 1
+	}
+	if err := s.tesseract(ctx, ws, all, "tsv"); err != nil {
+		return err
+	}
+	tsv, err := ws.ReadFile("ocr.tsv")
+	if err != nil {
+		return fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+	pages, err := parseTSV(tsv, pageCount)
+	if err != nil {
+		return fmt.Errorf("failed to read tesseract output: %w", err)
+	}
+
+	if response.Entities, err = s.extractEntities(ctx, pages, extractors); err != nil {
+		return err
+	}
+	byPage := make(map[int][]Entity)
+	for _, entity := range response.Entities {
+		if len(byPage[entity.Page]) == 0 {
+			response.RedactedPages = append(response.RedactedPages, entity.Page)
+		}
+		byPage[entity.Page] = append(byPage[entity.Page], entity)
+	}
+	if req.ReportOnly {
+		return nil
+	}
+
+	for _, page := range response.RedactedPages {
+		boxes := redactionBoxes(pages[page-1], byPage[page])
+		if err := redactImage(ws, pageImage(page), boxes); err != nil {
+			return fmt.Errorf("failed to redact page %d: %w", page, err)
+		}
+	}
+
+	// The rebuilt PDF gets its text layer from the redacted images
+	if err := s.tesseract(ctx, ws, all, "pdf"); err != nil {
+		return err
+	}
+	if response.PDF, err = ws.ReadFile("ocr.pdf"); err != nil {
+		return fmt.Errorf("failed to read redacted PDF: %w", err)
+	}
+	return nil
+}
+
+// wordSpans locates each OCR word of a page in its text. Words appear in
+// the text in order, separated by spaces and newlines.
+func wordSpans(page PageText) []textSpan {
+	spans := make([]textSpan, len(page.Words))
+	offset := 0
+	for i, word := range page.Words {
+		start := strings.Index(page.Text[offset:], word.Text)
+		if start < 0 {
+			spans[i] = textSpan{-1, -1}
+			continue
+		}
+		start += offset
+		offset = start + len(word.Text)
+		spans[i] = textSpan{start, offset}
+	}
+	return spans
+}
+
+// redactionBoxes returns the bounding boxes of the words overlapping any of
+// entities, padded by redactionPadding
+func redactionBoxes(page PageText, entities []Entity) []image.Rectangle {
+	var boxes []image.Rectangle
+	for i, span := range wordSpans(page) {
+		for _, entity := range entities {
+			if span.start < entity.End && entity.Start < span.end {
+				word := page.Words[i]
+				boxes = append(boxes, image.Rect(
+					word.Left-redactionPadding,
+					word.Top-redactionPadding,
+					word.Left+word.Width+redactionPadding,
+					word.Top+word.Height+redactionPadding,
+				))
+				break
+			}
+		}
+	}
+	return boxes
+}
+
+// redactImage paints boxes black on a rendered page in ws
+func redactImage(ws *exec.Workspace, name string, boxes []image.Rectangle) error {
+	data, err := ws.ReadFile(name)
+	if err != nil {
+		return err
+	}
+	src, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+
+	img := image.NewRGBA(src.Bounds())
+	draw.Draw(img, img.Bounds(), src, src.Bounds().Min, draw.Src)
+	black := image.NewUniform(color.Black)
+	for _, box := range boxes {
+		draw.Draw(img, box.Intersect(img.Bounds()), black, image.Point{}, draw.Src)
+	}
+
+	var buf bytes.Buffer
+	encoder := png.Encoder{CompressionLevel: png.BestSpeed}
+	if err := encoder.Encode(&buf, img); err != nil {
+		return err
+	}
+	_, err = ws.WriteFile(name, buf.Bytes())
+	return err
+}
@@ -0,0 +1,226 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/retention"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// resultPrefix is the storage key prefix for operation results
+const resultPrefix = "results/"
+
+// resultIDPattern matches result IDs: the hex SHA-256 of the content (or,
+// for results stored before content addressing, a UUID) plus a file extension
+var resultIDPattern = regexp.MustCompile(`^([0-9a-f]{64}|[0-9a-f-]{36})\.[a-z0-9]+$`)
+
+// Result describes a stored operation output
+type Result struct {
+	ID          string
+	Size        int64
+	ContentType string
+	CreatedAt   time.Time
+	ETag        string
+	// SHA256 is the hex SHA-256 of the content; empty for legacy results
+	SHA256 string
+	// Existed reports that Save found identical content already stored, so
+	// the result is shared with earlier saves
+	Existed bool
+}
+
+// Checksum returns the hex SHA-256 of data
+func Checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ResultService persists operation outputs so they can be downloaded later.
+// Objects are stored per tenant under <prefix><tenant>/<id>; results stored
+// before tenancy live directly under the prefix and belong to the default
+// tenant.
+type ResultService struct {
+	store    storage.Store
+	policies *retention.Policies
+	log      logger.Logger
+	// prefix is the storage key prefix and kind names the stored objects in
+	// errors and logs; DocumentService reuses this type for uploads
+	prefix string
+	kind   string
+}
+
+// NewResultService creates a new result service. A nil policies keeps
+// results until they are deleted.
+func NewResultService(store storage.Store, policies *retention.Policies, log logger.Logger) *ResultService {
+	return &ResultService{
+		store:    store,
+		policies: policies,
+		log:      log,
+		prefix:   resultPrefix,
+		kind:     "result",
+	}
+}
+
+// key returns the storage key of id for the tenant of ctx
+func (s *ResultService) key(ctx context.Context, id string) string {
+	return s.prefix + tenant.FromContext(ctx) + "/" + id
+}
+
+// Save stores an operation output and returns its result descriptor. Results
+// are content-addressed: the ID is derived from the SHA-256 of data, so
+// saving identical output twice returns the same result. When the tenant has
+// a retention TTL the object is rewritten to restart its retention clock.
+func (s *ResultService) Save(ctx context.Context, data []byte, ext string) (*Result, error) {
+	ctx, span := tracer.Start(ctx, "ResultService.Save")
+	defer span.End()
+
+	id := fmt.Sprintf("%s.%s", Checksum(data), ext)
+	key := s.key(ctx, id)
+	info, err := s.store.Stat(ctx, key)
+	existed := err == nil
+	if existed && s.policies.For(tenant.FromContext(ctx)).TTL == 0 {
+		s.log.Info("Stored object deduplicated", "kind", s.kind, "id", id, "size", info.Size)
+		result := resultFromInfo(id, info)
+		result.Existed = true
+		return result, nil
+	}
+
+	info, err = s.store.Put(ctx, key, bytes.NewReader(data), "")
+	if err != nil {
+		return nil, s.storageError(err, "store")
+	}
+
+	s.log.Info("Object stored", "kind", s.kind, "id", id, "size", info.Size)
+
+	result := resultFromInfo(id, info)
+	result.Existed = existed
+	return result, nil
+}
+
+// Open opens a stored result for streaming. The caller must close the object.
+func (s *ResultService) Open(ctx context.Context, id string) (storage.Object, *Result, error) {
+	var (
+		obj  storage.Object
+		info storage.Info
+	)
+	err := s.withKey(ctx, id, func(key string) error {
+		var err error
+		obj, info, err = s.store.Open(ctx, key)
+		return err
+	})
+	if err != nil {
+		return nil, nil, s.storageError(err, "open")
+	}
+
+	return obj, resultFromInfo(id, info), nil
+}
+
+// Delete removes a stored result
+func (s *ResultService) Delete(ctx context.Context, id string) error {
+	err := s.withKey(ctx, id, func(key string) error {
+		return s.store.Delete(ctx, key)
+	})
+	if err != nil {
+		return s.storageError(err, "delete")
+	}
+
+	s.log.Info("Stored object deleted", "kind", s.kind, "id", id)
+	return nil
+}
+
+// Downloaded applies delete-after-download retention once a result has been
+// downloaded in full
+func (s *ResultService) Downloaded(ctx context.Context, id string) {
+	if !s.policies.For(tenant.FromContext(ctx)).DeleteAfterDownload {
+		return
+	}
+	if err := s.Delete(ctx, id); err != nil && CodeOf(err) != ErrCodeNotFound {
+		s.log.Error("Failed to delete downloaded object", "kind", s.kind, "id", id, "error", err)
+	}
+}
+
+// List calls fn for every object of the tenant of ctx. Listing stops at the
+// first error returned by fn.
+func (s *ResultService) List(ctx context.Context, fn func(*Result) error) error {
+	prefix := s.prefix + tenant.FromContext(ctx) + "/"
+	return s.store.List(ctx, prefix, func(info storage.Info) error {
+		id := strings.TrimPrefix(info.Key, prefix)
+		if !resultIDPattern.MatchString(id) {
+			return nil
+		}
+		return fn(resultFromInfo(id, info))
+	})
+}
+
+// Purge removes objects whose tenant's retention TTL has expired at now
+func (s *ResultService) Purge(ctx context.Context, now time.Time) (int, error) {
+	removed := 0
+	err := s.store.List(ctx, s.prefix, func(info storage.Info) error {
+		owner, _, found := strings.Cut(strings.TrimPrefix(info.Key, s.prefix), "/")
+		if !found {
+			owner = config.DefaultTenant
+		}
+		if !retention.Expired(s.policies.For(owner), info.ModTime, now) {
+			return nil
+		}
+
+		if err := s.store.Delete(ctx, info.Key); err != nil && !errors.Is(err, storage.ErrNotFound) {
+			s.log.Warn("Failed to purge expired object", "key", info.Key, "error", err)
+			return nil
+		}
+		removed++
+		return nil
+	})
+	return removed, err
+}
+
+// withKey calls fn with the storage key of id. Objects the default tenant
+// stored before tenancy are found under the old key layout.
+func (s *ResultService) withKey(ctx context.Context, id string, fn func(key string) error) error {
+	if !resultIDPattern.MatchString(id) {
+		return storage.ErrNotFound
+	}
+
+	err := fn(s.key(ctx, id))
+	if errors.Is(err, storage.ErrNotFound) && tenant.FromContext(ctx) == config.DefaultTenant {
+		err = fn(s.prefix + id)
+	}
+	return err
+}
+
+// storageError maps a storage failure onto a coded error
+func (s *ResultService) storageError(err error, op string) error {
+	if errors.Is(err, storage.ErrNotFound) {
+		return NewError(ErrCodeNotFound, s.kind+" not found", err)
+	}
+	if busy := unavailableError(err, "storage"); busy != nil {
+		return busy
+	}
+	return fmt.Errorf("failed to %s %s: %w", op, s.kind, err)
+}
+
+func resultFromInfo(id string, info storage.Info) *Result {
+	result := &Result{
+		ID:          id,
+		Size:        info.Size,
+		ContentType: info.ContentType,
+		CreatedAt:   info.ModTime,
+		ETag:        storage.ETag(info),
+	}
+	// The content hash is the best possible strong validator
+	if sum, _, _ := strings.Cut(id, "."); len(sum) == sha256.Size*2 {
+		result.SHA256 = sum
+		result.ETag = `"` + sum + `"`
+	}
+	return result
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/retention"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResultService_ContentAddressing(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	svc := NewResultService(store, nil, logger.New("info", "text"))
+	ctx := context.Background()
+
+	data := []byte("%PDF-1.4\nresult")
+	sum := Checksum(data)
+
+	t.Run("ID Is Content Hash", func(t *testing.T) {
+		result, err := svc.Save(ctx, data, "pdf")
+		require.NoError(t, err)
+		assert.Equal(t, sum+".pdf", result.ID)
+		assert.Equal(t, sum, result.SHA256)
+		assert.Equal(t, `"`+sum+`"`, result.ETag)
+	})
+
+	t.Run("Identical Output Is Deduplicated", func(t *testing.T) {
+		first, err := svc.Save(ctx, data, "pdf")
+		require.NoError(t, err)
+		second, err := svc.Save(ctx, data, "pdf")
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, second.ID)
+		assert.Equal(t, first.CreatedAt, second.CreatedAt)
+	})
+
+	t.Run("Open By Hash", func(t *testing.T) {
+		obj, result, err := svc.Open(ctx, sum+".pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+		content, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, data, content)
+		assert.Equal(t, sum, result.SHA256)
+	})
+
+	t.Run("Unknown Hash", func(t *testing.T) {
+		_, _, err := svc.Open(ctx, Checksum([]byte("other"))+".pdf")
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+
+	t.Run("Documents Are Separate From Results", func(t *testing.T) {
+		docs := NewDocumentService(store, nil, logger.New("info", "text"))
+		doc, err := docs.Save(ctx, []byte("%PDF-1.4\ndocument"))
+		require.NoError(t, err)
+
+		obj, _, err := docs.Open(ctx, doc.ID)
+		require.NoError(t, err)
+		obj.Close()
+
+		_, _, err = svc.Open(ctx, doc.ID)
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+}
+
+func TestResultService_Retention(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	policies := retention.NewPolicies(config.RetentionConfig{}, []config.TenantConfig{
+		{ID: "acme", Retention: &config.RetentionPolicy{TTL: 60, DeleteAfterDownload: true}},
+	})
+	svc := NewResultService(store, policies, logger.New("info", "text"))
+	acme := tenant.WithID(context.Background(), "acme")
+	other := context.Background()
+	data := []byte("%PDF-1.4\nretained")
+
+	t.Run("Tenants Are Isolated", func(t *testing.T) {
+		result, err := svc.Save(acme, data, "pdf")
+		require.NoError(t, err)
+		_, _, err = svc.Open(other, result.ID)
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+
+	t.Run("Purge Honours Tenant TTL", func(t *testing.T) {
+		kept, err := svc.Save(other, data, "pdf")
+		require.NoError(t, err)
+		_, err = svc.Save(acme, data, "pdf")
+		require.NoError(t, err)
+
+		removed, err := svc.Purge(context.Background(), time.Now().Add(2*time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, 1, removed)
+
+		obj, _, err := svc.Open(other, kept.ID)
+		require.NoError(t, err)
+		obj.Close()
+	})
+
+	t.Run("Delete After Download", func(t *testing.T) {
+		result, err := svc.Save(acme, data, "pdf")
+		require.NoError(t, err)
+		svc.Downloaded(acme, result.ID)
+		_, _, err = svc.Open(acme, result.ID)
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+
+	t.Run("Legacy Layout", func(t *testing.T) {
+		legacy := []byte("%PDF-1.4\nlegacy")
+		id := Checksum(legacy) + ".pdf"
+		_, err := store.Put(context.Background(), resultPrefix+id, bytes.NewReader(legacy), "")
+		require.NoError(t, err)
+		obj, _, err := svc.Open(other, id)
+		require.NoError(t, err)
+		obj.Close()
+		_, _, err = svc.Open(acme, id)
+		assert.Equal(t, ErrCodeNotFound, CodeOf(err))
+	})
+}
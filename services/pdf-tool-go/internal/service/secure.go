@@ -0,0 +1,231 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// SecureSectionRequest moves pages of a document into an encrypted
+// attachment
+type SecureSectionRequest struct {
+	PDFData []byte
+	// Pages selects the restricted pages, like "3-5,9"
+	Pages    string
+	Password string
+	// AttachmentName is the file name of the attachment, restricted.pdf by
+	// default
+	AttachmentName string
+}
+
+// SecureSection extracts the selected pages into a PDF encrypted with
+// AES-256 and embeds it as an attachment of the remaining document, so one
+// file carries both the public pages and the restricted ones. Viewers open
+// the document with its attachments shown.
+func (s *PDFService) SecureSection(ctx context.Context, req *SecureSectionRequest) (_ []byte, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.SecureSection")
+	defer span.End()
+
+	op := metrics.Start("secure_section")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "secure_section")
+	defer cancel()
+
+	if req.Password == "" {
+		return nil, NewError(ErrCodeInvalidInput, "password is required", nil)
+	}
+	if strings.TrimSpace(req.Pages) == "" {
+		return nil, NewError(ErrCodeInvalidInput, "pages to restrict are required", nil)
+	}
+	name := req.AttachmentName
+	if name == "" {
+		name = "restricted.pdf"
+	}
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	pages, err := parsePageSelection(req.Pages, pageCount)
+	if err != nil {
+		return nil, err
+	}
+	pages = uniquePages(pages)
+	if len(pages) == pageCount {
+		return nil, NewError(ErrCodeInvalidInput, "at least one page must stay public", nil)
+	}
+	span.SetAttributes(attribute.Int("restricted_pages", len(pages)))
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	selection := make([]string, len(pages))
+	for i, page := range pages {
+		selection[i] = strconv.Itoa(page)
+	}
+
+	var output []byte
+	err = s.runCancellable(ctx, func() error {
+		var section bytes.Buffer
+		err := inSpan(ctx, "pdfcpu.collect", func() error {
+			return api.Collect(bytes.NewReader(req.PDFData), &section, selection, nil)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to extract restricted pages")
+		}
+
+		conf := pdfcpu.NewDefaultConfiguration()
+		conf.UserPW = req.Password
+		conf.OwnerPW = req.Password
+		conf.EncryptUsingAES = true
+		conf.EncryptKeyLength = 256
+		var encrypted bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.encrypt", func() error {
+			return api.Encrypt(bytes.NewReader(section.Bytes()), &encrypted, conf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to encrypt restricted pages")
+		}
+
+		var public bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.remove_pages", func() error {
+			return api.RemovePages(bytes.NewReader(req.PDFData), &public, selection, nil)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to remove restricted pages")
+		}
+
+		ctx2, err := s.readContext(ctx, public.Bytes())
+		if err != nil {
+			return err
+		}
+		desc := fmt.Sprintf("Restricted pages %s, encrypted", req.Pages)
+		if err := attachFile(ctx2, name, desc, encrypted.Bytes()); err != nil {
+			return fmt.Errorf("failed to attach restricted pages: %w", err)
+		}
+		ctx2.RootDict.Update("PageMode", pdfcpu.Name("UseAttachments"))
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		output = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	op.Output(int64(len(output)))
+
+	s.log.Info("Section secured", "restricted_pages", len(pages), "pages", pageCount)
+
+	return output, nil
+}
+
+// attachFile embeds a PDF as a document attachment listed in the
+// catalog's EmbeddedFiles name tree, replacing an attachment of the same
+// name. The tree is rewritten as a single sorted leaf.
+func attachFile(ctx2 *pdfcpu.Context, name, desc string, data []byte) error {
+	length := int64(len(data))
+	stream := pdfcpu.StreamDict{
+		Dict: pdfcpu.Dict{
+			"Type":    pdfcpu.Name("EmbeddedFile"),
+			"Subtype": pdfcpu.Name("application#2Fpdf"),
+			"Length":  pdfcpu.Integer(len(data)),
+			"Params":  pdfcpu.Dict{"Size": pdfcpu.Integer(len(data))},
+		},
+		StreamLength: &length,
+		Content:      data,
+		Raw:          data,
+	}
+	streamRef, err := ctx2.IndRefForNewObject(stream)
+	if err != nil {
+		return err
+	}
+	fileSpec, err := ctx2.IndRefForNewObject(pdfcpu.Dict{
+		"Type": pdfcpu.Name("Filespec"),
+		"F":    textString(name),
+		"UF":   textString(name),
+		"Desc": textString(desc),
+		"EF":   pdfcpu.Dict{"F": *streamRef},
+	})
+	if err != nil {
+		return err
+	}
+
+	names, ok := dictEntry(ctx2, ctx2.RootDict, "Names")
+	if !ok {
+		names = pdfcpu.Dict{}
+		ctx2.RootDict.Update("Names", names)
+	}
+	var entries []nameTreeEntry
+	if tree, ok := dictEntry(ctx2, names, "EmbeddedFiles"); ok {
+		entries = nameTreeEntries(ctx2, tree, 0)
+	}
+	kept := entries[:0]
+	for _, entry := range entries {
+		if entry.key != name {
+			kept = append(kept, entry)
+		}
+	}
+	entries = append(kept, nameTreeEntry{key: name, value: *fileSpec})
+	sort.SliceStable(entries, func(i, j int) bool { return entries[i].key < entries[j].key })
+
+	leaf := make(pdfcpu.Array, 0, 2*len(entries))
+	for _, entry := range entries {
+		leaf = append(leaf, textString(entry.key), entry.value)
+	}
+	names.Update("EmbeddedFiles", pdfcpu.Dict{"Names": leaf})
+	return nil
+}
+
+// nameTreeEntry is a key and value of a name tree
+type nameTreeEntry struct {
+	key   string
+	value pdfcpu.Object
+}
+
+// maxNameTreeDepth bounds the name tree walk against reference cycles
+const maxNameTreeDepth = 32
+
+// nameTreeEntries collects the entries of a name tree's leaves in order
+func nameTreeEntries(ctx2 *pdfcpu.Context, node pdfcpu.Dict, depth int) []nameTreeEntry {
+	if depth > maxNameTreeDepth {
+		return nil
+	}
+	var entries []nameTreeEntry
+	if obj, ok := node.Find("Names"); ok {
+		pairs, _ := ctx2.Dereference(obj)
+		leaf, _ := pairs.(pdfcpu.Array)
+		for i := 0; i+1 < len(leaf); i += 2 {
+			if key, ok := textValue(ctx2, leaf[i]); ok {
+				entries = append(entries, nameTreeEntry{key: key, value: leaf[i+1]})
+			}
+		}
+	}
+	if obj, ok := node.Find("Kids"); ok {
+		kids, _ := ctx2.Dereference(obj)
+		list, _ := kids.(pdfcpu.Array)
+		for _, kid := range list {
+			if child, err := ctx2.DereferenceDict(kid); err == nil && child != nil {
+				entries = append(entries, nameTreeEntries(ctx2, child, depth+1)...)
+			}
+		}
+	}
+	return entries
+}
@@ -0,0 +1,263 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// maxObjectDepth bounds the walk through the direct objects nested in an
+// object
+const maxObjectDepth = 32
+
+// ConvertSpotColorsRequest represents a spot to process colour conversion
+type ConvertSpotColorsRequest struct {
+	PDFData []byte
+	// Mapping maps spot colour names to CMYK percentages, taking precedence
+	// over the configured table. Names match ignoring case.
+	Mapping map[string][]float64
+}
+
+// SpotConversion is how a spot colour was converted
+type SpotConversion struct {
+	Name string
+	// Mapped is set where the colour was converted through the mapping
+	// table rather than the document's own alternate colour
+	Mapped bool
+	// CMYK are the percentages of a mapped colour at full tint
+	CMYK []float64 `json:",omitempty"`
+}
+
+// ConvertSpotColorsResponse is a document with its spot colours converted
+type ConvertSpotColorsResponse struct {
+	PDF        []byte
+	SpotColors []SpotConversion
+}
+
+// ConvertSpotColors converts the spot colours of a document to CMYK for
+// presses without named colour support. Separation colours in the mapping
+// table are converted to their listed CMYK values, tints scaled linearly;
+// other colours, and DeviceN colours mixing several colorants, go through
+// the alternate colour the document defines for them. Like any conversion
+// to CMYK, RGB content is converted too. Documents without spot colours are
+// returned unchanged.
+func (s *PDFService) ConvertSpotColors(ctx context.Context, req *ConvertSpotColorsRequest) (_ *ConvertSpotColorsResponse, err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.ConvertSpotColors")
+	defer span.End()
+
+	op := metrics.Start("convert_spot_colors")
+	op.Input(int64(len(req.PDFData)))
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "convert_spot_colors")
+	defer cancel()
+
+	mapping := make(map[string][4]float64)
+	for name, cmyk := range s.config.Prepress.SpotColors {
+		if len(cmyk) == 4 {
+			mapping[spotKey(name)] = [4]float64{cmyk[0], cmyk[1], cmyk[2], cmyk[3]}
+		}
+	}
+	for name, cmyk := range req.Mapping {
+		if len(cmyk) != 4 {
+			return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("spot colour %q: four CMYK percentages are required", name), nil)
+		}
+		for _, value := range cmyk {
+			if value < 0 || value > 100 {
+				return nil, NewError(ErrCodeInvalidInput, fmt.Sprintf("spot colour %q: CMYK percentages must be between 0 and 100", name), nil)
+			}
+		}
+		mapping[spotKey(name)] = [4]float64{cmyk[0], cmyk[1], cmyk[2], cmyk[3]}
+	}
+
+	s.log.Info("Converting PDF spot colours", "mapped", len(req.Mapping))
+
+	pageCount, err := s.checkPageCount(ctx, req.PDFData)
+	if err != nil {
+		return nil, err
+	}
+	op.Pages(pageCount)
+
+	if err := s.checkResources(ctx, int64(len(req.PDFData))); err != nil {
+		return nil, err
+	}
+
+	response := &ConvertSpotColorsResponse{PDF: req.PDFData, SpotColors: []SpotConversion{}}
+	var mapped []byte
+	err = s.runCancellable(ctx, func() error {
+		ctx2, err := s.readContext(ctx, req.PDFData)
+		if err != nil {
+			return err
+		}
+		response.SpotColors = mapSpotColors(ctx2, mapping)
+		if len(response.SpotColors) == 0 {
+			return nil
+		}
+
+		var buf bytes.Buffer
+		err = inSpan(ctx, "pdfcpu.write", func() error {
+			return api.WriteContext(ctx2, &buf)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to write PDF")
+		}
+		mapped = buf.Bytes()
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	span.SetAttributes(attribute.Int("spot_colors", len(response.SpotColors)))
+	if len(response.SpotColors) == 0 {
+		s.log.Info("PDF has no spot colours to convert")
+		return response, nil
+	}
+
+	err = s.runHeavy(ctx, func() error {
+		response.PDF, err = s.convertSpotColors(ctx, mapped)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	op.Output(int64(len(response.PDF)))
+	s.log.Info("Spot colour conversion completed", "spot_colors", len(response.SpotColors), "size", len(response.PDF))
+
+	return response, nil
+}
+
+// convertSpotColors rewrites a document through Ghostscript's pdfwrite
+// device in CMYK, converting Separation and DeviceN colours through their
+// alternate colour spaces instead of keeping them
+func (s *PDFService) convertSpotColors(ctx context.Context, pdfData []byte) ([]byte, error) {
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return nil, err
+	}
+	defer ws.Close()
+
+	err = inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.WriteFile("input.pdf", pdfData)
+		return err
+	}, attribute.Int("bytes", len(pdfData)))
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{
+			"-sDEVICE=pdfwrite",
+			"-sColorConversionStrategy=CMYK",
+			"-sProcessColorModel=DeviceCMYK",
+			"-dPreserveSeparation=false",
+			"-dPreserveDeviceN=false",
+			"-dSAFER",
+			"-dNOPAUSE",
+			"-dQUIET",
+			"-dBATCH",
+			"-sOutputFile=output.pdf",
+			"input.pdf",
+		},
+	})
+	if err != nil {
+		return nil, toolError(ctx, err, "gs")
+	}
+
+	var data []byte
+	err = inSpan(ctx, "tempfile.read", func() error {
+		data, err = ws.ReadFile("output.pdf")
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read ghostscript output: %w", err)
+	}
+	return data, nil
+}
+
+// mapSpotColors lists the spot colours of a document, pointing the
+// Separation colour spaces of mapped colours at their CMYK values, so
+// conversion through the alternate space yields them. Mapping is keyed by
+// spotKey.
+func mapSpotColors(ctx2 *pdfcpu.Context, mapping map[string][4]float64) []SpotConversion {
+	spots := make(map[string]SpotConversion)
+	var visit func(obj pdfcpu.Object, depth int)
+	visit = func(obj pdfcpu.Object, depth int) {
+		if depth > maxObjectDepth {
+			return
+		}
+		switch o := obj.(type) {
+		case pdfcpu.StreamDict:
+			visit(o.Dict, depth+1)
+		case pdfcpu.Dict:
+			for _, value := range o {
+				visit(value, depth+1)
+			}
+		case pdfcpu.Array:
+			for _, name := range spotColorants(ctx2, o, 0) {
+				if _, ok := spots[name]; !ok {
+					spots[name] = SpotConversion{Name: name}
+				}
+			}
+			if len(o) == 4 && o[0] == pdfcpu.Name("Separation") {
+				if name, ok := o[1].(pdfcpu.Name); ok {
+					if cmyk, ok := mapping[spotKey(decodeName(name))]; ok {
+						o[2] = pdfcpu.Name("DeviceCMYK")
+						o[3] = cmykTint(cmyk)
+						spots[decodeName(name)] = SpotConversion{Name: decodeName(name), Mapped: true, CMYK: cmyk[:]}
+					}
+				}
+			}
+			for _, value := range o {
+				visit(value, depth+1)
+			}
+		}
+	}
+	for _, entry := range ctx2.Table {
+		if entry != nil && !entry.Free {
+			visit(entry.Object, 0)
+		}
+	}
+
+	names := make([]string, 0, len(spots))
+	for name := range spots {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	conversions := make([]SpotConversion, 0, len(names))
+	for _, name := range names {
+		conversions = append(conversions, spots[name])
+	}
+	return conversions
+}
+
+// cmykTint is a tint transform scaling CMYK percentages linearly from no
+// ink at tint 0 to the full colour at tint 1
+func cmykTint(cmyk [4]float64) pdfcpu.Dict {
+	full := make(pdfcpu.Array, len(cmyk))
+	for i, value := range cmyk {
+		full[i] = pdfcpu.Float(value / 100)
+	}
+	return pdfcpu.Dict{
+		"FunctionType": pdfcpu.Integer(2),
+		"Domain":       pdfcpu.Array{pdfcpu.Integer(0), pdfcpu.Integer(1)},
+		"C0":           pdfcpu.Array{pdfcpu.Integer(0), pdfcpu.Integer(0), pdfcpu.Integer(0), pdfcpu.Integer(0)},
+		"C1":           full,
+		"N":            pdfcpu.Integer(1),
+	}
+}
+
+// spotKey normalizes a spot colour name for matching: lower case with
+// single spaces
+func spotKey(name string) string {
+	return strings.ToLower(strings.Join(strings.Fields(name), " "))
+}
@@ -0,0 +1,36 @@
+package service
+
+// Stats reports the current heavy-operation load
+type Stats struct {
+	InFlight  int `json:"in_flight"`
+	Queued    int `json:"queued"`
+	Capacity  int `json:"capacity"`
+	QueueSize int `json:"queue_size"`
+}
+
+// Stats returns current in-flight and queued heavy operations
+func (s *PDFService) Stats() Stats {
+	if s.limiter == nil {
+		return Stats{}
+	}
+	return Stats{
+		InFlight:  s.limiter.InFlight(),
+		Queued:    s.limiter.Queued(),
+		Capacity:  s.limiter.Capacity(),
+		QueueSize: s.limiter.QueueSize(),
+	}
+}
+
+// SetConcurrency changes the heavy-operation limits at runtime. Limiting
+// that is disabled in the configuration cannot be turned on.
+func (s *PDFService) SetConcurrency(maxConcurrent, queueSize int) error {
+	if s.limiter == nil {
+		return NewError(ErrCodeInvalidInput, "concurrency limiting is disabled", nil)
+	}
+	if maxConcurrent <= 0 || queueSize < 0 {
+		return NewError(ErrCodeInvalidInput, "max_concurrent must be positive and queue_size not negative", nil)
+	}
+	s.limiter.Resize(maxConcurrent, queueSize)
+	s.log.Info("Concurrency limits changed", "max_concurrent", maxConcurrent, "queue_size", queueSize)
+	return nil
+}
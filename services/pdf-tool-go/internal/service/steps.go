@@ -0,0 +1,180 @@
+package service
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"path/filepath"
+
+	"github.com/pdfcpu/pdfcpu/pkg/api"
+	"github.com/pdfcpu/pdfcpu/pkg/pdfcpu"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/pipeline"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/scripting"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+)
+
+// newPipeline creates the engine operations run their steps on
+func (s *PDFService) newPipeline() *pipeline.Engine {
+	return &pipeline.Engine{
+		TempDir:        s.config.PDF.TempDir,
+		Timeout:        s.withTimeout,
+		RunCancellable: s.runCancellable,
+		RunHeavy:       s.runHeavy,
+		Reason:         failureReason,
+		After:          s.afterSteps,
+	}
+}
+
+// afterSteps are the steps run after every pipeline operation
+func (s *PDFService) afterSteps(operation string) []pipeline.Step {
+	var steps []pipeline.Step
+	if s.scripts != nil {
+		steps = append(steps, s.scriptStep())
+	}
+	if s.provenance != nil {
+		steps = append(steps, s.provenanceStep())
+	}
+	return steps
+}
+
+// scriptStep runs the tenant's script over the output and applies the
+// actions it returns
+func (s *PDFService) scriptStep() pipeline.Step {
+	return pipeline.Step{Name: "script", Mode: pipeline.Cancellable, Fn: func(st *pipeline.State) error {
+		owner := tenant.FromContext(st.Ctx)
+		if !s.scripts.Has(owner) {
+			return nil
+		}
+
+		doc := scripting.Document{
+			Operation: st.Operation,
+			Tenant:    owner,
+			Pages:     st.Pages,
+			Size:      len(st.Input),
+		}
+		if ctx2, err := s.readContext(st.Ctx, st.Input); err == nil {
+			doc.Metadata = documentInfo(ctx2)
+		}
+
+		actions, err := s.scripts.Run(st.Ctx, doc)
+		if err != nil {
+			return fmt.Errorf("tenant script: %w", err)
+		}
+		st.OutputName = actions.Name
+		if actions.Watermark == "" {
+			return nil
+		}
+
+		if st.Output, err = watermarkOutput(st, st.Output, actions.Watermark); err != nil {
+			return err
+		}
+		for i, part := range st.Parts {
+			if st.Parts[i], err = watermarkOutput(st, part, actions.Watermark); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// watermarkOutput stamps a script's watermark on an output. Outputs that
+// are not PDF, such as from custom processors, are left alone.
+func watermarkOutput(st *pipeline.State, data []byte, text string) ([]byte, error) {
+	if !bytes.HasPrefix(data, []byte("%PDF")) {
+		return data, nil
+	}
+
+	input, err := st.Stage(data, "script-input-*.pdf")
+	if err != nil {
+		return nil, err
+	}
+	dir, err := st.OutputDir("script-output-*")
+	if err != nil {
+		return nil, err
+	}
+	output := filepath.Join(dir, "output.pdf")
+
+	err = inSpan(st.Ctx, "pdfcpu.watermark", func() error {
+		wm, err := pdfcpu.ParseTextWatermarkDetails(text, "", false, nil)
+		if err != nil {
+			return fmt.Errorf("tenant script returned an invalid watermark: %w", err)
+		}
+		return api.AddWatermarksFile(input, output, nil, wm, nil)
+	})
+	if err != nil {
+		return nil, classifyPDFError(err, "failed to add script watermark")
+	}
+	return st.ReadFile(output)
+}
+
+// documentInfo returns the text entries of the document information
+// dictionary
+func documentInfo(ctx2 *pdfcpu.Context) map[string]string {
+	info := make(map[string]string)
+	if ctx2.Info == nil {
+		return info
+	}
+	dict, err := ctx2.DereferenceDict(*ctx2.Info)
+	if err != nil || dict == nil {
+		return info
+	}
+	for _, key := range []string{"Title", "Author", "Subject", "Keywords", "Creator", "Producer"} {
+		switch value, _ := dict.Find(key); value := value.(type) {
+		case pdfcpu.StringLiteral:
+			info[key] = string(value)
+		case pdfcpu.HexLiteral:
+			if decoded, err := hex.DecodeString(string(value)); err == nil {
+				info[key] = string(decoded)
+			}
+		}
+	}
+	return info
+}
+
+// validateStep checks request parameters before the input is touched
+func validateStep(validate func() error) pipeline.Step {
+	return pipeline.Step{Name: "validate", Fn: func(*pipeline.State) error {
+		return validate()
+	}}
+}
+
+// checkStep reads the page count of the input, enforcing the page limit,
+// and waits for resources to process it
+func (s *PDFService) checkStep() pipeline.Step {
+	return pipeline.Step{Name: "check", Fn: func(st *pipeline.State) error {
+		pageCount, err := s.checkPageCount(st.Ctx, st.Input)
+		if err != nil {
+			return err
+		}
+		st.Pages = pageCount
+		return s.checkResources(st.Ctx, int64(len(st.Input)))
+	}}
+}
+
+// fileStep stages the input, runs a file-based pdfcpu operation writing
+// output and reads the output back. What names the operation in errors.
+func fileStep(name string, mode pipeline.Mode, what string, run func(input, output string) error) pipeline.Step {
+	return pipeline.Step{Name: name, Mode: mode, Fn: func(st *pipeline.State) error {
+		input, err := st.Stage(st.Input, name+"-input-*.pdf")
+		if err != nil {
+			return err
+		}
+		dir, err := st.OutputDir(name + "-output-*")
+		if err != nil {
+			return err
+		}
+		output := filepath.Join(dir, "output.pdf")
+
+		err = inSpan(st.Ctx, "pdfcpu."+name, func() error {
+			return run(input, output)
+		})
+		if err != nil {
+			return classifyPDFError(err, "failed to "+what)
+		}
+
+		if st.Output, err = st.ReadFile(output); err != nil {
+			return fmt.Errorf("failed to read %s output: %w", name, err)
+		}
+		return nil
+	}}
+}
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/exec"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+	"go.opentelemetry.io/otel/attribute"
+)
+
+// defaultStreamBatchPages is the number of pages rendered at a time when
+// pdf.stream_batch_pages is unset
+const defaultStreamBatchPages = 10
+
+// pageCountPS prints the page count of input.pdf. Ghostscript reads pages
+// on demand, so unlike pdfcpu it does not load the whole document.
+const pageCountPS = "(input.pdf) (r) file runpdfbegin pdfpagecount = quit"
+
+// StreamImagesRequest requests a page-at-a-time image conversion
+type StreamImagesRequest struct {
+	// Input is staged on disk as it is read; it is never held in memory
+	Input     io.Reader
+	Format    string // png, jpeg, webp, avif
+	DPI       int
+	PageRange string
+	Quality   int
+	Lossless  *bool
+}
+
+// PageImage is one page rendered by StreamImages
+type PageImage struct {
+	Page int
+	Data []byte
+}
+
+// StreamImages converts pages to images like ConvertToImage, but for
+// documents of any size: the input is staged on disk rather than held in
+// memory, and pages are rendered a batch at a time and passed to emit as
+// they are ready. Memory use is bounded by the batch size, not the page
+// count. Rendering stops at the first error emit returns.
+func (s *PDFService) StreamImages(ctx context.Context, req *StreamImagesRequest, emit func(PageImage) error) (err error) {
+	ctx, span := tracer.Start(ctx, "PDFService.StreamImages")
+	defer span.End()
+
+	op := metrics.Start("convert_image_stream")
+	defer func() { op.Done(failureReason(err)) }()
+
+	ctx, cancel := s.withTimeout(ctx, "convert_image_stream")
+	defer cancel()
+
+	span.SetAttributes(attribute.String("format", req.Format), attribute.Int("dpi", req.DPI))
+
+	format, ok := ImageFormats[req.Format]
+	if !ok || format.multiPage {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("unsupported streamed image format %q; use png, jpeg, webp or avif", req.Format), nil)
+	}
+	if req.DPI < minRenderDPI || req.DPI > maxRenderDPI {
+		return NewError(ErrCodeInvalidInput, fmt.Sprintf("dpi must be between %d and %d", minRenderDPI, maxRenderDPI), nil)
+	}
+	encoder, err := s.imageEncoder(&ConvertToImageRequest{Format: req.Format, Quality: req.Quality, Lossless: req.Lossless})
+	if err != nil {
+		return err
+	}
+	batch := s.config.PDF.StreamBatchPages
+	if batch <= 0 {
+		batch = defaultStreamBatchPages
+	}
+
+	ws, err := s.runner.NewWorkspace()
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	size, err := s.stageInput(ctx, ws, req.Input)
+	if err != nil {
+		return err
+	}
+	op.Input(size)
+
+	s.log.Info("Streaming PDF to images", "format", req.Format, "dpi", req.DPI, "size", size, "batch_pages", batch)
+
+	if err := s.checkResources(ctx, size); err != nil {
+		return err
+	}
+
+	var pageCount int
+	err = s.runCancellable(ctx, func() error {
+		var err error
+		pageCount, err = s.stagedPageCount(ctx, ws)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	if err := s.enforcePageLimit(ctx, pageCount); err != nil {
+		return err
+	}
+	pages, err := parsePageSelection(req.PageRange, pageCount)
+	if err != nil {
+		return err
+	}
+	pages = uniquePages(pages)
+	op.Pages(len(pages))
+
+	var output int64
+	for start := 0; start < len(pages); start += batch {
+		chunk := pages[start:min(start+batch, len(pages))]
+
+		// Each batch takes a concurrency slot of its own, so long documents
+		// do not hold one for their whole run
+		var images [][]byte
+		err := s.runHeavy(ctx, func() error {
+			var err error
+			images, err = s.renderStaged(ctx, ws, chunk, req.Format, req.DPI, encoder)
+			return err
+		})
+		if err != nil {
+			return err
+		}
+		for i, data := range images {
+			if err := emit(PageImage{Page: chunk[i], Data: data}); err != nil {
+				return err
+			}
+			output += int64(len(data))
+		}
+	}
+	op.Output(output)
+
+	s.log.Info("Streamed PDF to image conversion completed", "pages", len(pages), "size", output)
+
+	return nil
+}
+
+// stageInput copies a document into ws as input.pdf, checking its header
+// and enforcing the file size limit as it is read, and returns its size
+func (s *PDFService) stageInput(ctx context.Context, ws *exec.Workspace, r io.Reader) (int64, error) {
+	buffered := bufio.NewReader(r)
+	header, _ := buffered.Peek(8)
+	if len(header) == 0 {
+		return 0, NewError(ErrCodeInvalidInput, "PDF data is empty", nil)
+	}
+	if err := validateHeader(header); err != nil {
+		return 0, err
+	}
+
+	maxFileSize := s.MaxFileSize(ctx)
+	counted := &countingReader{r: io.LimitReader(buffered, maxFileSize+1)}
+	err := inSpan(ctx, "tempfile.write", func() error {
+		_, err := ws.CopyFile("input.pdf", counted)
+		return err
+	})
+	if err != nil {
+		return 0, err
+	}
+	if counted.n > maxFileSize {
+		return 0, NewError(ErrCodeFileTooLarge, fmt.Sprintf("PDF file too large: more than %d bytes", maxFileSize), nil)
+	}
+	return counted.n, nil
+}
+
+// stagedPageCount counts the pages of the document staged in ws with
+// Ghostscript
+func (s *PDFService) stagedPageCount(ctx context.Context, ws *exec.Workspace) (int, error) {
+	result, err := s.runner.Run(ctx, ws, exec.Command{
+		Tool: "gs",
+		Args: []string{"-dNODISPLAY", "-dSAFER", "--permit-file-read=input.pdf", "-dQUIET", "-dBATCH", "-dNOPAUSE", "-c", pageCountPS},
+	})
+	if err != nil {
+		return 0, toolError(ctx, err, "gs")
+	}
+	return parsePageCount(result.Stdout)
+}
+
+// parsePageCount reads the page count printed by pageCountPS, which ends
+// the output; Ghostscript may warn about damaged files first
+func parsePageCount(stdout []byte) (int, error) {
+	fields := strings.Fields(string(stdout))
+	if len(fields) == 0 {
+		return 0, NewError(ErrCodeCorrupted, "failed to read page count", nil)
+	}
+	count, err := strconv.Atoi(fields[len(fields)-1])
+	if err != nil || count < 1 {
+		return 0, NewError(ErrCodeCorrupted, "failed to read page count", err)
+	}
+	return count, nil
+}
+
+// countingReader counts the bytes read through it
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
@@ -0,0 +1,227 @@
+package service
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Page sizes of generated documents in points
+var textPageSizes = map[string][2]float64{
+	"a4":     {595, 842},
+	"letter": {612, 792},
+}
+
+// textMargin is the page margin of generated documents in points
+const textMargin = 56
+
+// textStyle lays out a generated text document
+type textStyle struct {
+	// PageSize is a4 or letter; other values fall back to a4
+	PageSize string
+	// FontSize is the body text size in points
+	FontSize float64
+	// Footer is set at the bottom of every page with the page number
+	Footer string
+}
+
+// textLine is a laid out line of a generated document
+type textLine struct {
+	text    string
+	heading bool
+	// y is the baseline from the bottom of the page
+	y float64
+}
+
+// textDocument generates a PDF setting text in Helvetica, wrapped to the
+// page width and continued on further pages as needed. Lines starting with
+// "# " are set as bold headings and blank lines leave a gap. Characters
+// outside Windows-1252 are replaced, as the standard fonts are not
+// embedded.
+func textDocument(text string, style textStyle) []byte {
+	size, ok := textPageSizes[style.PageSize]
+	if !ok {
+		size = textPageSizes["a4"]
+	}
+	fontSize := style.FontSize
+	if fontSize <= 0 {
+		fontSize = 10
+	}
+	headingSize := fontSize * 1.4
+	width, height := size[0], size[1]
+	top, bottom := height-textMargin, textMargin+2*fontSize
+
+	var pages [][]textLine
+	var page []textLine
+	y := top
+	place := func(line string, heading bool, lineSize float64) {
+		if y-lineSize*1.4 < bottom {
+			pages = append(pages, page)
+			page, y = nil, top
+		}
+		y -= lineSize * 1.4
+		page = append(page, textLine{text: line, heading: heading, y: y})
+	}
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		switch {
+		case line == "":
+			y -= fontSize * 0.7
+		case strings.HasPrefix(line, "# "):
+			y -= fontSize * 0.7
+			for _, part := range wrapText(strings.TrimPrefix(line, "# "), headingSize, width-2*textMargin) {
+				place(part, true, headingSize)
+			}
+		default:
+			for _, part := range wrapText(line, fontSize, width-2*textMargin) {
+				place(part, false, fontSize)
+			}
+		}
+	}
+	pages = append(pages, page)
+
+	var w pdfWriter
+	w.header()
+	kids := make([]string, len(pages))
+	const firstPage = 5
+	for i := range pages {
+		kids[i] = fmt.Sprintf("%d 0 R", firstPage+2*i)
+	}
+	w.object("<< /Type /Catalog /Pages 2 0 R >>")
+	w.object(fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(pages)))
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>")
+	w.object("<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>")
+	for i, lines := range pages {
+		var content bytes.Buffer
+		for _, line := range lines {
+			font, lineSize := "F1", fontSize
+			if line.heading {
+				font, lineSize = "F2", headingSize
+			}
+			fmt.Fprintf(&content, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, num(lineSize), num(textMargin), num(line.y), pdfText(line.text))
+		}
+		footer := fmt.Sprintf("Page %d of %d", i+1, len(pages))
+		if style.Footer != "" {
+			footer = style.Footer + " - " + footer
+		}
+		fmt.Fprintf(&content, "BT /F1 %s Tf %s %s Td (%s) Tj ET\n", num(fontSize*0.8), num(textMargin), num(textMargin), pdfText(footer))
+
+		w.object(fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /F1 3 0 R /F2 4 0 R >> >> /Contents %d 0 R >>",
+			num(width), num(height), firstPage+2*i+1))
+		w.object(fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", content.Len(), content.String()))
+	}
+	return w.finish()
+}
+
+// pdfWriter writes the objects of a PDF in order, numbered from 1
+type pdfWriter struct {
+	buf     bytes.Buffer
+	offsets []int
+}
+
+func (w *pdfWriter) header() {
+	w.buf.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+}
+
+func (w *pdfWriter) object(body string) {
+	w.offsets = append(w.offsets, w.buf.Len())
+	fmt.Fprintf(&w.buf, "%d 0 obj\n%s\nendobj\n", len(w.offsets), body)
+}
+
+// finish writes the cross-reference table and trailer; the first object
+// is the catalog
+func (w *pdfWriter) finish() []byte {
+	xref := w.buf.Len()
+	fmt.Fprintf(&w.buf, "xref\n0 %d\n0000000000 65535 f \n", len(w.offsets)+1)
+	for _, offset := range w.offsets {
+		fmt.Fprintf(&w.buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&w.buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(w.offsets)+1, xref)
+	return w.buf.Bytes()
+}
+
+// num formats a length in points
+func num(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+// wrapText breaks a line into parts no wider than width at the given font
+// size, at spaces where possible
+func wrapText(line string, fontSize, width float64) []string {
+	var parts []string
+	for textWidth(line, fontSize) > width {
+		cut := 0
+		for i := range line {
+			if textWidth(line[:i], fontSize) > width {
+				break
+			}
+			cut = i
+		}
+		if space := strings.LastIndexByte(line[:cut], ' '); space > 0 {
+			cut = space
+		}
+		if cut == 0 {
+			_, cut = utf8.DecodeRuneInString(line)
+		}
+		parts = append(parts, line[:cut])
+		line = strings.TrimLeft(line[cut:], " ")
+	}
+	return append(parts, line)
+}
+
+// textWidth measures s set in Helvetica at fontSize. Bold headings are
+// slightly wider, which the wrapping leaves to the margin.
+func textWidth(s string, fontSize float64) float64 {
+	units := 0
+	for _, r := range s {
+		if r >= 32 && r <= 126 {
+			units += helveticaWidths[r-32]
+		} else {
+			units += 556
+		}
+	}
+	return float64(units) * fontSize / 1000
+}
+
+// helveticaWidths are the advance widths of the printable ASCII characters
+// in Helvetica, in thousandths of the font size
+var helveticaWidths = [95]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// winAnsiExtras maps the characters Windows-1252 places in 0x80-0x9F
+var winAnsiExtras = map[rune]byte{
+	'€': 0x80, '‚': 0x82, 'ƒ': 0x83, '„': 0x84, '…': 0x85, '†': 0x86, '‡': 0x87, 'ˆ': 0x88,
+	'‰': 0x89, 'Š': 0x8A, '‹': 0x8B, 'Œ': 0x8C, 'Ž': 0x8E, '‘': 0x91, '’': 0x92, '“': 0x93,
+	'”': 0x94, '•': 0x95, '–': 0x96, '—': 0x97, '˜': 0x98, '™': 0x99, 'š': 0x9A, '›': 0x9B,
+	'œ': 0x9C, 'ž': 0x9E, 'Ÿ': 0x9F,
+}
+
+// pdfText encodes s as the contents of a literal string in WinAnsiEncoding,
+// replacing characters it lacks with "?"
+func pdfText(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch {
+		case r == '(' || r == ')' || r == '\\':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r >= 32 && r <= 126:
+			b.WriteRune(r)
+		case r >= 0xA0 && r <= 0xFF:
+			b.WriteByte(byte(r))
+		case winAnsiExtras[r] != 0:
+			b.WriteByte(winAnsiExtras[r])
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,23 @@
+package service
+
+import (
+	"context"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// inSpan runs fn inside a child span of ctx, recording any error on the span.
+// Used for pdfcpu phases and temp file I/O, which take no context themselves.
+// Each phase is a cancellation checkpoint: once ctx is done, fn is not run,
+// so work abandoned by a cancelled caller stops at its next phase.
+func inSpan(ctx context.Context, name string, fn func() error, attrs ...attribute.KeyValue) error {
+	if err := ctx.Err(); err != nil {
+		return contextError(err)
+	}
+	_, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	err := fn()
+	telemetry.EndSpan(span, err)
+	return err
+}
@@ -0,0 +1,86 @@
+package share
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// MemoryCounter counts downloads in memory, so counts are per instance and
+// lost on restart
+type MemoryCounter struct {
+	mu        sync.Mutex
+	downloads map[string]*count
+	now       func() time.Time
+}
+
+type count struct {
+	n       int
+	expires time.Time
+}
+
+// NewMemoryCounter creates an empty counter
+func NewMemoryCounter() *MemoryCounter {
+	return &MemoryCounter{downloads: make(map[string]*count), now: time.Now}
+}
+
+// Increment records a download of link id, dropping counts of expired links
+func (c *MemoryCounter) Increment(_ context.Context, id string, expires time.Time) (int, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := c.now()
+	for key, entry := range c.downloads {
+		if now.After(entry.expires) {
+			delete(c.downloads, key)
+		}
+	}
+	entry, ok := c.downloads[id]
+	if !ok {
+		entry = &count{expires: expires}
+		c.downloads[id] = entry
+	}
+	entry.n++
+	return entry.n, nil
+}
+
+// SQLCounter counts downloads in a SQL database, shared by all replicas
+type SQLCounter struct {
+	db *database.DB
+}
+
+// NewSQLCounter migrates the share tables of db to the current schema and
+// returns a counter backed by them
+func NewSQLCounter(ctx context.Context, db *database.DB) (*SQLCounter, error) {
+	steps, err := database.Load(migrations, "migrations/"+db.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(ctx, db, "share", steps); err != nil {
+		return nil, err
+	}
+	return &SQLCounter{db: db}, nil
+}
+
+// Increment records a download of link id, dropping counts of expired links
+func (c *SQLCounter) Increment(ctx context.Context, id string, expires time.Time) (int, error) {
+	if _, err := c.db.ExecContext(ctx, c.db.Rebind(`DELETE FROM share_downloads WHERE expires_at < $1`), time.Now().UTC()); err != nil {
+		return 0, fmt.Errorf("failed to prune share downloads: %w", err)
+	}
+
+	var n int
+	err := c.db.QueryRowContext(ctx, c.db.Rebind(`INSERT INTO share_downloads (link_id, downloads, expires_at) VALUES ($1, 1, $2)
+		ON CONFLICT (link_id) DO UPDATE SET downloads = share_downloads.downloads + 1
+		RETURNING downloads`), id, expires.UTC()).Scan(&n)
+	if err != nil {
+		return 0, fmt.Errorf("failed to count share download: %w", err)
+	}
+	return n, nil
+}
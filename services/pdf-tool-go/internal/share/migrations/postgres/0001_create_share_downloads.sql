@@ -0,0 +1,9 @@
+-- Downloads of share links limited to a number of downloads; rows are
+-- kept until the link expires
+CREATE TABLE share_downloads (
+    link_id    TEXT        PRIMARY KEY,
+    downloads  INTEGER     NOT NULL,
+    expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX share_downloads_expires ON share_downloads (expires_at);
@@ -0,0 +1,200 @@
+/**
+ * Share Links
+ *
+ * Signed, expiring links to stored results that end users can download
+ * without an API key. A link is an HMAC-signed token naming the tenant,
+ * result and expiry, optionally limited to a number of downloads and
+ * protected by a password.
+ */
+
+package share
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lockout"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// Link is a share link as minted or resolved
+type Link struct {
+	ID     string `json:"id"`
+	Tenant string `json:"t"`
+	Result string `json:"r"`
+	// Expires is a Unix time
+	Expires int64 `json:"exp"`
+	// MaxDownloads limits the downloads of the link; 0 is unlimited
+	MaxDownloads int `json:"max,omitempty"`
+	// Password is the keyed hash of the link's password, if it has one
+	Password string `json:"pw,omitempty"`
+	// Recipient names who the link was given to, for download watermarks
+	Recipient string `json:"to,omitempty"`
+}
+
+// ExpiresAt returns the expiry of the link
+func (l *Link) ExpiresAt() time.Time {
+	return time.Unix(l.Expires, 0)
+}
+
+// Options are the optional limits of a new link
+type Options struct {
+	// TTL is how long the link is valid; 0 takes the configured default
+	TTL          time.Duration
+	MaxDownloads int
+	Password     string
+	Recipient    string
+}
+
+// maxRecipient is the length of the longest recipient name, which every
+// token of a link carries
+const maxRecipient = 200
+
+// Counter counts the downloads of links
+type Counter interface {
+	// Increment records a download of link id, kept until expires, and
+	// returns the downloads so far including this one
+	Increment(ctx context.Context, id string, expires time.Time) (int, error)
+}
+
+// Links mints and resolves share links
+type Links struct {
+	secret     []byte
+	defaultTTL time.Duration
+	maxTTL     time.Duration
+	publicURL  string
+	downloads  Counter
+	attempts   *lockout.Guard
+	now        func() time.Time
+}
+
+// New creates share links signed with the configured secret. Failed
+// password attempts are throttled per link with the lockout settings.
+func New(cfg config.ShareConfig, lockoutCfg config.LockoutConfig, downloads Counter) *Links {
+	return &Links{
+		secret:     []byte(cfg.Secret),
+		defaultTTL: time.Duration(cfg.DefaultTTL) * time.Second,
+		maxTTL:     time.Duration(cfg.MaxTTL) * time.Second,
+		publicURL:  strings.TrimSuffix(cfg.PublicURL, "/"),
+		downloads:  downloads,
+		attempts:   lockout.NewGuard(lockoutCfg),
+		now:        time.Now,
+	}
+}
+
+// Mint returns a token for result of tenant with opts
+func (l *Links) Mint(tenant, result string, opts Options) (string, *Link, error) {
+	ttl := opts.TTL
+	if ttl == 0 {
+		ttl = l.defaultTTL
+	}
+	if ttl < 0 || ttl > l.maxTTL {
+		return "", nil, service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("expires_in must be between 1 and %d seconds", int(l.maxTTL/time.Second)), nil)
+	}
+	if opts.MaxDownloads < 0 {
+		return "", nil, service.NewError(service.ErrCodeInvalidInput, "max_downloads must not be negative", nil)
+	}
+	if len(opts.Recipient) > maxRecipient {
+		return "", nil, service.NewError(service.ErrCodeInvalidInput,
+			fmt.Sprintf("recipient must not be longer than %d characters", maxRecipient), nil)
+	}
+
+	link := &Link{
+		ID:           uuid.NewString(),
+		Tenant:       tenant,
+		Result:       result,
+		Expires:      l.now().Add(ttl).Unix(),
+		MaxDownloads: opts.MaxDownloads,
+		Recipient:    opts.Recipient,
+	}
+	if opts.Password != "" {
+		link.Password = l.hashPassword(link.ID, opts.Password)
+	}
+
+	payload, err := json.Marshal(link)
+	if err != nil {
+		return "", nil, service.NewError(service.ErrCodeInternal, "failed to encode share link", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + l.sign(encoded), link, nil
+}
+
+// URL returns the public download URL of token, for links sent outside a
+// request such as by email
+func (l *Links) URL(token string) string {
+	return l.publicURL + "/share/" + token
+}
+
+// Resolve checks token and the password given for it, and counts a
+// download when download is set. Tokens that are malformed, forged,
+// expired or out of downloads are all reported as not found.
+func (l *Links) Resolve(ctx context.Context, token, password string, download bool) (*Link, error) {
+	invalid := service.NewError(service.ErrCodeNotFound, "share link is invalid or has expired", nil)
+
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(l.sign(encoded))) {
+		return nil, invalid
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, invalid
+	}
+	var link Link
+	if err := json.Unmarshal(payload, &link); err != nil {
+		return nil, invalid
+	}
+	if !l.now().Before(link.ExpiresAt()) {
+		return nil, invalid
+	}
+
+	if link.Password != "" {
+		key := "share:" + link.ID
+		if wait := l.attempts.Check(key); wait > 0 {
+			blocked := service.NewError(service.ErrCodeTooManyAttempts, "too many failed password attempts, retry later", nil)
+			blocked.RetryAfter = int(math.Ceil(wait.Seconds()))
+			return nil, blocked
+		}
+		if !hmac.Equal([]byte(link.Password), []byte(l.hashPassword(link.ID, password))) {
+			l.attempts.Failure(key)
+			return nil, service.NewError(service.ErrCodeBadPassword, "share link password is missing or wrong", nil)
+		}
+		l.attempts.Reset(key)
+	}
+
+	if download && link.MaxDownloads > 0 {
+		count, err := l.downloads.Increment(ctx, link.ID, link.ExpiresAt())
+		if err != nil {
+			return nil, service.NewError(service.ErrCodeInternal, "failed to count share link download", err)
+		}
+		if count > link.MaxDownloads {
+			return nil, invalid
+		}
+	}
+	return &link, nil
+}
+
+// sign returns the signature of an encoded payload
+func (l *Links) sign(encoded string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte("link|" + encoded))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// hashPassword returns the hash of the password of link id. It is keyed
+// with the secret, so the hash in a token cannot be attacked offline.
+func (l *Links) hashPassword(id, password string) string {
+	mac := hmac.New(sha256.New, l.secret)
+	mac.Write([]byte("password|" + id + "|" + password))
+	return hex.EncodeToString(mac.Sum(nil))
+}
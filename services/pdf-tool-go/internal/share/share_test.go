@@ -0,0 +1,105 @@
+package share
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLinks(t *testing.T) {
+	ctx := context.Background()
+	cfg := config.ShareConfig{Enabled: true, Secret: strings.Repeat("s", 32), DefaultTTL: 3600, MaxTTL: 86400}
+	lockoutCfg := config.LockoutConfig{MaxAttempts: 4, BaseDelay: 1, MaxDelay: 3, Lockout: 60, Window: 120}
+	now := time.Unix(1700000000, 0)
+	newLinks := func() *Links {
+		downloads := NewMemoryCounter()
+		downloads.now = func() time.Time { return now }
+		l := New(cfg, lockoutCfg, downloads)
+		l.now = func() time.Time { return now }
+		return l
+	}
+
+	t.Run("Minted Links Resolve Until Expiry", func(t *testing.T) {
+		l := newLinks()
+		token, link, err := l.Mint("acme", "abc.pdf", Options{Recipient: "jane@example.com"})
+		require.NoError(t, err)
+		assert.Equal(t, now.Add(time.Hour), link.ExpiresAt())
+
+		resolved, err := l.Resolve(ctx, token, "", true)
+		require.NoError(t, err)
+		assert.Equal(t, "acme", resolved.Tenant)
+		assert.Equal(t, "abc.pdf", resolved.Result)
+		assert.Equal(t, "jane@example.com", resolved.Recipient)
+
+		l.now = func() time.Time { return now.Add(time.Hour) }
+		_, err = l.Resolve(ctx, token, "", true)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+	})
+
+	t.Run("Lifetimes Are Capped", func(t *testing.T) {
+		l := newLinks()
+		_, _, err := l.Mint("acme", "abc.pdf", Options{TTL: 48 * time.Hour})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, _, err = l.Mint("acme", "abc.pdf", Options{MaxDownloads: -1})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+		_, _, err = l.Mint("acme", "abc.pdf", Options{Recipient: strings.Repeat("x", maxRecipient+1)})
+		assert.Equal(t, service.ErrCodeInvalidInput, service.CodeOf(err))
+	})
+
+	t.Run("Forged Tokens Are Rejected", func(t *testing.T) {
+		l := newLinks()
+		token, link, err := l.Mint("acme", "abc.pdf", Options{})
+		require.NoError(t, err)
+
+		_, signature, _ := strings.Cut(token, ".")
+		link.Tenant = "globex"
+		payload, err := json.Marshal(link)
+		require.NoError(t, err)
+		encoded := base64.RawURLEncoding.EncodeToString(payload)
+		for _, bad := range []string{encoded + "." + signature, encoded, "", token + "x"} {
+			_, err := l.Resolve(ctx, bad, "", false)
+			assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err), bad)
+		}
+
+		l.secret = []byte(strings.Repeat("t", 32))
+		_, err = l.Resolve(ctx, token, "", false)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+	})
+
+	t.Run("Passwords Are Required And Throttled", func(t *testing.T) {
+		l := newLinks()
+		token, link, err := l.Mint("acme", "abc.pdf", Options{Password: "hunter2"})
+		require.NoError(t, err)
+		assert.NotContains(t, link.Password, "hunter2")
+
+		_, err = l.Resolve(ctx, token, "hunter2", false)
+		require.NoError(t, err)
+		_, err = l.Resolve(ctx, token, "", false)
+		assert.Equal(t, service.ErrCodeBadPassword, service.CodeOf(err))
+		_, err = l.Resolve(ctx, token, "hunter2", false)
+		assert.Equal(t, service.ErrCodeTooManyAttempts, service.CodeOf(err))
+	})
+
+	t.Run("Download Limits Count Downloads Only", func(t *testing.T) {
+		l := newLinks()
+		token, _, err := l.Mint("acme", "abc.pdf", Options{MaxDownloads: 2})
+		require.NoError(t, err)
+
+		for i := 0; i < 2; i++ {
+			_, err := l.Resolve(ctx, token, "", true)
+			require.NoError(t, err)
+			_, err = l.Resolve(ctx, token, "", false)
+			require.NoError(t, err)
+		}
+		_, err = l.Resolve(ctx, token, "", true)
+		assert.Equal(t, service.ErrCodeNotFound, service.CodeOf(err))
+	})
+}
@@ -0,0 +1,146 @@
+/**
+ * Load Shedding
+ *
+ * Rejects requests while heavy operations wait too long for a processing
+ * slot to meet the latency target, lowest priority first, so the requests
+ * still admitted are served in time instead of all of them timing out.
+ */
+
+package shedding
+
+import (
+	"math/rand"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/metrics"
+)
+
+const (
+	// maxSamples bounds the waits kept per window
+	maxSamples = 2048
+	// refreshInterval bounds how often the percentile is recomputed
+	refreshInterval = time.Second
+)
+
+// Priority ranks requests; lower priorities are shed first
+type Priority int
+
+const (
+	// PriorityBulk is for background and bulk traffic that can retry later
+	PriorityBulk Priority = iota
+	// PriorityInteractive is for requests a user is waiting on
+	PriorityInteractive
+)
+
+// ParsePriority parses a priority name
+func ParsePriority(name string) (Priority, bool) {
+	switch strings.ToLower(name) {
+	case "bulk":
+		return PriorityBulk, true
+	case "interactive":
+		return PriorityInteractive, true
+	}
+	return 0, false
+}
+
+func (p Priority) String() string {
+	if p == PriorityBulk {
+		return "bulk"
+	}
+	return "interactive"
+}
+
+type sample struct {
+	at   time.Time
+	wait time.Duration
+}
+
+// Shedder tracks the 99th percentile of recent slot waits against a
+// target. Once it reaches the target all bulk requests are shed; past it
+// interactive requests are shed with a probability growing with the
+// overshoot, reaching all of them at twice the target. Samples age out
+// of the window, so shedding stops on its own once the backlog clears.
+type Shedder struct {
+	target time.Duration
+	window time.Duration
+
+	mu       sync.Mutex
+	samples  []sample
+	next     int
+	p99      time.Duration
+	computed time.Time
+
+	now    func() time.Time
+	random func() float64
+}
+
+// New creates a shedder
+func New(cfg config.LoadSheddingConfig) *Shedder {
+	return &Shedder{
+		target:  time.Duration(cfg.TargetWait) * time.Millisecond,
+		window:  time.Duration(cfg.Window) * time.Second,
+		samples: make([]sample, 0, maxSamples),
+		now:     time.Now,
+		random:  rand.Float64,
+	}
+}
+
+// Observe records how long an operation waited for a processing slot
+func (s *Shedder) Observe(wait time.Duration) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry := sample{at: s.now(), wait: wait}
+	if len(s.samples) < maxSamples {
+		s.samples = append(s.samples, entry)
+		return
+	}
+	s.samples[s.next] = entry
+	s.next = (s.next + 1) % maxSamples
+}
+
+// Shed reports whether a request of priority should be rejected
+func (s *Shedder) Shed(priority Priority) bool {
+	overshoot := float64(s.Latency())/float64(s.target) - 1
+	switch {
+	case overshoot < 0:
+		return false
+	case priority == PriorityBulk:
+		return true
+	default:
+		return s.random() < overshoot
+	}
+}
+
+// Latency returns the 99th percentile slot wait over the window
+func (s *Shedder) Latency() time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	if now.Sub(s.computed) < refreshInterval {
+		return s.p99
+	}
+	s.computed = now
+
+	waits := make([]time.Duration, 0, len(s.samples))
+	for _, entry := range s.samples {
+		if now.Sub(entry.at) <= s.window {
+			waits = append(waits, entry.wait)
+		}
+	}
+	s.p99 = 0
+	if len(waits) > 0 {
+		sort.Slice(waits, func(i, j int) bool { return waits[i] < waits[j] })
+		s.p99 = waits[(len(waits)*99-1)/100]
+	}
+	metrics.QueueWaitP99(s.p99)
+	return s.p99
+}
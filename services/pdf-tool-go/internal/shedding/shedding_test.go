@@ -0,0 +1,62 @@
+package shedding
+
+import (
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestShedder(t *testing.T) {
+	now := time.Unix(1700000000, 0)
+	newShedder := func(waits ...time.Duration) *Shedder {
+		s := New(config.LoadSheddingConfig{Enabled: true, TargetWait: 1000, Window: 30})
+		s.now = func() time.Time { return now }
+		s.random = func() float64 { return 0.5 }
+		for _, wait := range waits {
+			s.Observe(wait)
+		}
+		return s
+	}
+
+	t.Run("Under Target", func(t *testing.T) {
+		s := newShedder(0, 100*time.Millisecond, 900*time.Millisecond)
+		assert.False(t, s.Shed(PriorityBulk))
+		assert.False(t, s.Shed(PriorityInteractive))
+	})
+
+	t.Run("Bulk Shed First", func(t *testing.T) {
+		s := newShedder(1200 * time.Millisecond)
+		assert.True(t, s.Shed(PriorityBulk))
+		// 20% over target sheds interactive requests with probability 0.2
+		assert.False(t, s.Shed(PriorityInteractive))
+	})
+
+	t.Run("Interactive Shed Past Target", func(t *testing.T) {
+		s := newShedder(1600 * time.Millisecond)
+		assert.True(t, s.Shed(PriorityInteractive))
+	})
+
+	t.Run("Percentile Ignores Outliers", func(t *testing.T) {
+		waits := make([]time.Duration, 200)
+		waits[0] = 10 * time.Second
+		s := newShedder(waits...)
+		assert.Zero(t, s.Latency())
+	})
+
+	t.Run("Samples Age Out", func(t *testing.T) {
+		s := newShedder(5 * time.Second)
+		assert.True(t, s.Shed(PriorityBulk))
+		now = now.Add(time.Minute)
+		assert.False(t, s.Shed(PriorityBulk))
+	})
+
+	t.Run("Priorities", func(t *testing.T) {
+		p, ok := ParsePriority("Bulk")
+		assert.True(t, ok)
+		assert.Equal(t, PriorityBulk, p)
+		_, ok = ParsePriority("urgent")
+		assert.False(t, ok)
+	})
+}
@@ -0,0 +1,79 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/breaker"
+)
+
+// breakerStore wraps a Store with a circuit breaker, so an unreachable or
+// hanging backend fails requests fast instead of holding them until their
+// timeouts
+type breakerStore struct {
+	next       Store
+	dependency string
+	breakers   *breaker.Set
+}
+
+// isStoreFailure reports whether err means the store itself is failing.
+// Missing objects and callers giving up do not count.
+func isStoreFailure(err error) bool {
+	return !errors.Is(err, ErrNotFound) && !errors.Is(err, context.Canceled)
+}
+
+// Put implements Store
+func (b *breakerStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (info Info, err error) {
+	err = b.breakers.Do(b.dependency, func() error {
+		info, err = b.next.Put(ctx, key, r, contentType)
+		return err
+	})
+	return info, err
+}
+
+// Open implements Store. Only opening goes through the breaker; reads
+// happen later as the object is served.
+func (b *breakerStore) Open(ctx context.Context, key string) (obj Object, info Info, err error) {
+	err = b.breakers.Do(b.dependency, func() error {
+		obj, info, err = b.next.Open(ctx, key)
+		return err
+	})
+	return obj, info, err
+}
+
+// Stat implements Store
+func (b *breakerStore) Stat(ctx context.Context, key string) (info Info, err error) {
+	err = b.breakers.Do(b.dependency, func() error {
+		info, err = b.next.Stat(ctx, key)
+		return err
+	})
+	return info, err
+}
+
+// Delete implements Store
+func (b *breakerStore) Delete(ctx context.Context, key string) error {
+	return b.breakers.Do(b.dependency, func() error {
+		return b.next.Delete(ctx, key)
+	})
+}
+
+// List implements Store
+func (b *breakerStore) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	var listErr, fnErr error
+	err := b.breakers.Do(b.dependency, func() error {
+		listErr = b.next.List(ctx, prefix, func(info Info) error {
+			fnErr = fn(info)
+			return fnErr
+		})
+		// Errors returned by fn are the caller's, not the store's
+		if fnErr != nil {
+			return nil
+		}
+		return listErr
+	})
+	if fnErr != nil {
+		return listErr
+	}
+	return err
+}
@@ -0,0 +1,56 @@
+package storage
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLocalStore_Path(t *testing.T) {
+	root := t.TempDir()
+	s, err := NewLocalStore(root)
+	require.NoError(t, err)
+
+	t.Run("Valid Keys", func(t *testing.T) {
+		path, err := s.path("results/default/abc.pdf")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "results", "default", "abc.pdf"), path)
+
+		path, err = s.path("results//default/./abc.pdf")
+		require.NoError(t, err)
+		assert.Equal(t, filepath.Join(root, "results", "default", "abc.pdf"), path)
+	})
+
+	for _, key := range []string{
+		"",
+		"/",
+		"../outside.pdf",
+		"results/../../outside.pdf",
+		"results/..",
+		"/etc/passwd",
+		"//etc/passwd",
+		".",
+	} {
+		t.Run("Rejects "+key, func(t *testing.T) {
+			_, err := s.path(key)
+			assert.Error(t, err)
+		})
+	}
+
+	t.Run("Operations Reject Escaping Keys", func(t *testing.T) {
+		ctx := context.Background()
+		_, err := s.Put(ctx, "../outside.pdf", strings.NewReader("data"), "")
+		assert.Error(t, err)
+		assert.NoFileExists(t, filepath.Join(filepath.Dir(root), "outside.pdf"))
+
+		_, _, err = s.Open(ctx, "/etc/passwd")
+		assert.Error(t, err)
+		_, err = s.Stat(ctx, "../"+filepath.Base(root))
+		assert.Error(t, err)
+		assert.Error(t, s.Delete(ctx, "results/../../x"))
+	})
+}
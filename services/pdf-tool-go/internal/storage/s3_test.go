@@ -0,0 +1,173 @@
+package storage
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeS3 serves one object over the S3 path-style API and records the
+// ranges of GET requests
+type fakeS3 struct {
+	content  string
+	mu       sync.Mutex
+	ranges   []string
+	modified time.Time
+}
+
+func (f *fakeS3) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/bucket/results/doc.pdf" {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Last-Modified", f.modified.UTC().Format(http.TimeFormat))
+	w.Header().Set("Content-Type", "application/pdf")
+	if r.Method == http.MethodHead {
+		w.Header().Set("Content-Length", strconv.Itoa(len(f.content)))
+		return
+	}
+
+	f.mu.Lock()
+	f.ranges = append(f.ranges, r.Header.Get("Range"))
+	f.mu.Unlock()
+	start, err := strconv.Atoi(strings.TrimSuffix(strings.TrimPrefix(r.Header.Get("Range"), "bytes="), "-"))
+	if err != nil || start >= len(f.content) {
+		w.WriteHeader(http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+	w.Header().Set("Content-Range", "bytes "+strconv.Itoa(start)+"-"+strconv.Itoa(len(f.content)-1)+"/"+strconv.Itoa(len(f.content)))
+	w.Header().Set("Content-Length", strconv.Itoa(len(f.content)-start))
+	w.WriteHeader(http.StatusPartialContent)
+	io.WriteString(w, f.content[start:])
+}
+
+func (f *fakeS3) requests() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.ranges...)
+}
+
+func newFakeS3Store(t *testing.T) (*S3Store, *fakeS3) {
+	fake := &fakeS3{
+		content:  "%PDF-1.7 0123456789 abcdefghij %%EOF",
+		modified: time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC),
+	}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	client := s3.New(s3.Options{
+		Region:       "us-east-1",
+		BaseEndpoint: aws.String(server.URL),
+		UsePathStyle: true,
+		Credentials:  credentials.NewStaticCredentialsProvider("key", "secret", ""),
+	})
+	return &S3Store{client: client, bucket: "bucket"}, fake
+}
+
+func TestS3Object(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("Reads From Offset", func(t *testing.T) {
+		store, fake := newFakeS3Store(t)
+		obj, info, err := store.Open(ctx, "results/doc.pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+		assert.Equal(t, int64(len(fake.content)), info.Size)
+		assert.Empty(t, fake.requests(), "opening fetches no content")
+
+		pos, err := obj.Seek(9, io.SeekStart)
+		require.NoError(t, err)
+		assert.Equal(t, int64(9), pos)
+		data, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, fake.content[9:], string(data))
+		assert.Equal(t, []string{"bytes=9-"}, fake.requests())
+	})
+
+	t.Run("Seeking Restarts The Read", func(t *testing.T) {
+		store, fake := newFakeS3Store(t)
+		obj, _, err := store.Open(ctx, "results/doc.pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+
+		buf := make([]byte, 4)
+		_, err = io.ReadFull(obj, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "%PDF", string(buf))
+
+		// Seeking to the current offset keeps the open response
+		_, err = obj.Seek(0, io.SeekCurrent)
+		require.NoError(t, err)
+		_, err = io.ReadFull(obj, buf)
+		require.NoError(t, err)
+		assert.Equal(t, "-1.7", string(buf))
+		assert.Equal(t, []string{"bytes=0-"}, fake.requests())
+
+		_, err = obj.Seek(-5, io.SeekEnd)
+		require.NoError(t, err)
+		data, err := io.ReadAll(obj)
+		require.NoError(t, err)
+		assert.Equal(t, "%%EOF", string(data))
+		assert.Equal(t, []string{"bytes=0-", "bytes=" + strconv.Itoa(len(fake.content)-5) + "-"}, fake.requests())
+	})
+
+	t.Run("Read At End", func(t *testing.T) {
+		store, fake := newFakeS3Store(t)
+		obj, _, err := store.Open(ctx, "results/doc.pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+
+		_, err = obj.Seek(0, io.SeekEnd)
+		require.NoError(t, err)
+		n, err := obj.Read(make([]byte, 8))
+		assert.Zero(t, n)
+		assert.Equal(t, io.EOF, err)
+		assert.Empty(t, fake.requests())
+	})
+
+	t.Run("Invalid Seeks", func(t *testing.T) {
+		store, _ := newFakeS3Store(t)
+		obj, _, err := store.Open(ctx, "results/doc.pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+
+		_, err = obj.Seek(-1, io.SeekStart)
+		assert.Error(t, err)
+		_, err = obj.Seek(0, 7)
+		assert.Error(t, err)
+	})
+
+	t.Run("Missing Object", func(t *testing.T) {
+		store, _ := newFakeS3Store(t)
+		_, _, err := store.Open(ctx, "results/missing.pdf")
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Served Ranges Fetch Only The Range", func(t *testing.T) {
+		store, fake := newFakeS3Store(t)
+		obj, info, err := store.Open(ctx, "results/doc.pdf")
+		require.NoError(t, err)
+		defer obj.Close()
+
+		req := httptest.NewRequest(http.MethodGet, "/results/doc.pdf", nil)
+		req.Header.Set("Range", "bytes=9-18")
+		rec := httptest.NewRecorder()
+		http.ServeContent(rec, req, "doc.pdf", info.ModTime, obj)
+
+		assert.Equal(t, http.StatusPartialContent, rec.Code)
+		assert.Equal(t, fake.content[9:19], rec.Body.String())
+		assert.Equal(t, []string{"bytes=9-"}, fake.requests())
+	})
+}
@@ -0,0 +1,87 @@
+/**
+ * Storage Module
+ *
+ * Blob storage abstraction for processed results and uploaded documents.
+ */
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/breaker"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+// ErrNotFound is returned when a key does not exist
+var ErrNotFound = errors.New("object not found")
+
+// Info describes a stored object
+type Info struct {
+	Key         string
+	Size        int64
+	ModTime     time.Time
+	ContentType string
+}
+
+// Object is a stored object opened for reading. It is seekable so it can be
+// served with http.ServeContent.
+type Object interface {
+	io.ReadSeeker
+	io.Closer
+}
+
+// Store is a key/value blob store
+type Store interface {
+	// Put stores the content of r under key
+	Put(ctx context.Context, key string, r io.Reader, contentType string) (Info, error)
+	// Open opens the object stored under key for reading
+	Open(ctx context.Context, key string) (Object, Info, error)
+	// Stat returns information about the object stored under key
+	Stat(ctx context.Context, key string) (Info, error)
+	// Delete removes the object stored under key
+	Delete(ctx context.Context, key string) error
+	// List calls fn for every object whose key starts with prefix. Listing
+	// stops at the first error returned by fn.
+	List(ctx context.Context, prefix string, fn func(Info) error) error
+}
+
+// New creates the store selected by configuration, behind a circuit breaker
+func New(cfg config.StorageConfig, breakers config.BreakerConfig, log logger.Logger) (Store, error) {
+	var (
+		store Store
+		err   error
+	)
+	switch cfg.Type {
+	case "local", "":
+		store, err = NewLocalStore(cfg.LocalPath)
+	case "s3":
+		store, err = NewS3Store(context.Background(), cfg)
+	default:
+		return nil, fmt.Errorf("unsupported storage type: %s", cfg.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+	store = &breakerStore{
+		next:       store,
+		dependency: "storage",
+		breakers:   breaker.New(breakers, log, isStoreFailure),
+	}
+	return newTracedStore(store, cfg.Type), nil
+}
+
+// ETag returns a strong validator for an object, derived from its key, size
+// and modification time. Stored objects are never rewritten in place, so
+// this changes whenever the content can have changed.
+func ETag(info Info) string {
+	h := fnv.New64a()
+	h.Write([]byte(info.Key))
+	return fmt.Sprintf("\"%x-%x-%x\"", h.Sum64(), info.Size, info.ModTime.UnixNano())
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"context"
+	"io"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/telemetry"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
+)
+
+var tracer = otel.Tracer("storage")
+
+// tracedStore wraps a Store with a span per operation
+type tracedStore struct {
+	next    Store
+	backend string
+}
+
+func newTracedStore(next Store, backend string) *tracedStore {
+	if backend == "" {
+		backend = "local"
+	}
+	return &tracedStore{next: next, backend: backend}
+}
+
+func (t *tracedStore) start(ctx context.Context, op, key string) (context.Context, trace.Span) {
+	return tracer.Start(ctx, "storage."+op, trace.WithAttributes(
+		attribute.String("storage.backend", t.backend),
+		attribute.String("storage.key", key),
+	))
+}
+
+// Put implements Store
+func (t *tracedStore) Put(ctx context.Context, key string, r io.Reader, contentType string) (Info, error) {
+	ctx, span := t.start(ctx, "put", key)
+	info, err := t.next.Put(ctx, key, r, contentType)
+	span.SetAttributes(attribute.Int64("storage.size", info.Size))
+	telemetry.EndSpan(span, err)
+	return info, err
+}
+
+// Open implements Store. The span covers opening only; reads happen later
+// as the object is served.
+func (t *tracedStore) Open(ctx context.Context, key string) (Object, Info, error) {
+	ctx, span := t.start(ctx, "open", key)
+	obj, info, err := t.next.Open(ctx, key)
+	telemetry.EndSpan(span, err)
+	return obj, info, err
+}
+
+// Stat implements Store
+func (t *tracedStore) Stat(ctx context.Context, key string) (Info, error) {
+	ctx, span := t.start(ctx, "stat", key)
+	info, err := t.next.Stat(ctx, key)
+	telemetry.EndSpan(span, err)
+	return info, err
+}
+
+// Delete implements Store
+func (t *tracedStore) Delete(ctx context.Context, key string) error {
+	ctx, span := t.start(ctx, "delete", key)
+	err := t.next.Delete(ctx, key)
+	telemetry.EndSpan(span, err)
+	return err
+}
+
+// List implements Store
+func (t *tracedStore) List(ctx context.Context, prefix string, fn func(Info) error) error {
+	ctx, span := tracer.Start(ctx, "storage.list", trace.WithAttributes(
+		attribute.String("storage.backend", t.backend),
+		attribute.String("storage.prefix", prefix),
+	))
+	err := t.next.List(ctx, prefix, fn)
+	telemetry.EndSpan(span, err)
+	return err
+}
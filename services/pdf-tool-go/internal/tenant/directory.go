@@ -0,0 +1,534 @@
+package tenant
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+//go:embed migrations
+var migrations embed.FS
+
+// keyPrefix marks API keys issued by the directory
+const keyPrefix = "pdt_"
+
+// Errors of directory operations
+var (
+	ErrNotFound    = errors.New("tenant not found")
+	ErrKeyNotFound = errors.New("api key not found")
+	ErrExists      = errors.New("tenant already exists")
+	ErrInvalid     = errors.New("invalid tenant")
+)
+
+// Tenant is a tenant onboarded through the directory
+type Tenant struct {
+	ID string `json:"id"`
+	Settings
+	Keys      []Key     `json:"keys"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// Key describes an API key without revealing it
+type Key struct {
+	ID string `json:"id"`
+	// Prefix is the start of the key, for operators to tell keys apart
+	Prefix    string    `json:"prefix"`
+	CreatedAt time.Time `json:"created_at"`
+	// ExpiresAt is set on keys being rotated out
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+// IssuedKey is a newly issued API key. The key itself is returned only
+// once; the directory keeps its hash.
+type IssuedKey struct {
+	Key
+	Secret string `json:"key"`
+}
+
+// snapshot is the in-memory copy of the directory requests are resolved
+// against
+type snapshot struct {
+	tenants map[string]Settings
+	// keys maps key hashes to their tenant and expiry
+	keys map[string]keyEntry
+}
+
+type keyEntry struct {
+	tenant    string
+	expiresAt *time.Time
+}
+
+// Directory keeps tenants onboarded at runtime, with their API keys,
+// quotas, defaults and configuration overrides, in the database. Requests are resolved against a
+// snapshot, refreshed after every change and periodically to pick up
+// changes made through other replicas.
+type Directory struct {
+	db  *database.DB
+	log logger.Logger
+	// reserved are the IDs of configured tenants, which cannot be created
+	reserved map[string]bool
+
+	mu   sync.RWMutex
+	snap snapshot
+}
+
+// NewDirectory migrates the tenant tables of db to the current schema and
+// loads the directory. configured are the tenants from the configuration,
+// whose IDs stay reserved.
+func NewDirectory(ctx context.Context, db *database.DB, configured []config.TenantConfig, log logger.Logger) (*Directory, error) {
+	steps, err := database.Load(migrations, "migrations/"+db.Driver)
+	if err != nil {
+		return nil, err
+	}
+	if err := database.Migrate(ctx, db, "tenant", steps); err != nil {
+		return nil, err
+	}
+
+	d := &Directory{db: db, log: log, reserved: make(map[string]bool, len(configured))}
+	for _, tenant := range configured {
+		d.reserved[tenant.ID] = true
+	}
+	if err := d.Refresh(ctx); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// Start refreshes the snapshot every interval until ctx is done
+func (d *Directory) Start(ctx context.Context, interval time.Duration) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := d.Refresh(ctx); err != nil {
+					d.log.Warn("Failed to refresh tenant directory", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Refresh reloads the snapshot from the database, dropping expired keys
+func (d *Directory) Refresh(ctx context.Context) error {
+	now := time.Now()
+	if _, err := d.db.ExecContext(ctx, d.db.Rebind(`DELETE FROM tenant_api_keys WHERE expires_at < $1`), now.UTC()); err != nil {
+		return fmt.Errorf("failed to prune api keys: %w", err)
+	}
+	tenants, err := d.query(ctx, "TRUE", nil)
+	if err != nil {
+		return err
+	}
+
+	snap := snapshot{tenants: make(map[string]Settings, len(tenants)), keys: make(map[string]keyEntry)}
+	for _, tenant := range tenants {
+		snap.tenants[tenant.ID] = tenant.Settings
+	}
+	rows, err := d.db.QueryContext(ctx, `SELECT tenant_id, key_hash, expires_at FROM tenant_api_keys`)
+	if err != nil {
+		return fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			entry   keyEntry
+			hash    string
+			expires sql.NullTime
+		)
+		if err := rows.Scan(&entry.tenant, &hash, &expires); err != nil {
+			return fmt.Errorf("failed to read api key: %w", err)
+		}
+		entry.expiresAt = timePtr(expires)
+		snap.keys[hash] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to query api keys: %w", err)
+	}
+
+	d.mu.Lock()
+	d.snap = snap
+	d.mu.Unlock()
+	return nil
+}
+
+// lookup returns the tenant owning apiKey in the snapshot. Keys are
+// matched by hash, so lookups reveal nothing about the keys themselves.
+func (d *Directory) lookup(apiKey string) (string, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	entry, ok := d.snap.keys[hashKey(apiKey)]
+	if !ok || (entry.expiresAt != nil && time.Now().After(*entry.expiresAt)) {
+		return "", false
+	}
+	return entry.tenant, true
+}
+
+// settings returns the settings of tenant id in the snapshot
+func (d *Directory) settings(id string) (Settings, bool) {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	settings, ok := d.snap.tenants[id]
+	return settings, ok
+}
+
+// List returns the onboarded tenants by ID
+func (d *Directory) List(ctx context.Context) ([]Tenant, error) {
+	return d.query(ctx, "TRUE", nil)
+}
+
+// Get returns tenant id
+func (d *Directory) Get(ctx context.Context, id string) (Tenant, error) {
+	tenants, err := d.query(ctx, "id = $1", []interface{}{id})
+	if err != nil {
+		return Tenant{}, err
+	}
+	if len(tenants) == 0 {
+		return Tenant{}, ErrNotFound
+	}
+	return tenants[0], nil
+}
+
+// Create onboards tenant id with settings and issues its first API key
+func (d *Directory) Create(ctx context.Context, id string, settings Settings) (Tenant, IssuedKey, error) {
+	if !config.ValidTenantID(id) {
+		return Tenant{}, IssuedKey{}, fmt.Errorf("%w: id %q must be lowercase letters, digits, - and _", ErrInvalid, id)
+	}
+	if d.reserved[id] {
+		return Tenant{}, IssuedKey{}, fmt.Errorf("%w: %s is configured", ErrExists, id)
+	}
+	if err := settings.validate(); err != nil {
+		return Tenant{}, IssuedKey{}, err
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return Tenant{}, IssuedKey{}, fmt.Errorf("failed to encode tenant settings: %w", err)
+	}
+
+	var issued IssuedKey
+	err = d.inTx(ctx, func(tx *sql.Tx) error {
+		var exists int
+		err := tx.QueryRowContext(ctx, d.db.Rebind(`SELECT COUNT(*) FROM tenants WHERE id = $1`), id).Scan(&exists)
+		if err != nil {
+			return fmt.Errorf("failed to query tenant: %w", err)
+		}
+		if exists > 0 {
+			return ErrExists
+		}
+		now := time.Now().UTC()
+		if _, err := tx.ExecContext(ctx, d.db.Rebind(`INSERT INTO tenants (id, settings, created_at, updated_at)
+			VALUES ($1, $2, $3, $3)`), id, string(data), now); err != nil {
+			return fmt.Errorf("failed to insert tenant: %w", err)
+		}
+		issued, err = d.insertKey(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return Tenant{}, IssuedKey{}, err
+	}
+	d.changed(ctx)
+
+	tenant, err := d.Get(ctx, id)
+	return tenant, issued, err
+}
+
+// SetQuotas replaces the quotas of tenant id
+func (d *Directory) SetQuotas(ctx context.Context, id string, quotas Quotas) (Tenant, error) {
+	return d.update(ctx, id, func(settings *Settings) {
+		settings.Quotas = quotas
+	})
+}
+
+// SetDefaults replaces the defaults of tenant id
+func (d *Directory) SetDefaults(ctx context.Context, id string, defaults Defaults) (Tenant, error) {
+	return d.update(ctx, id, func(settings *Settings) {
+		settings.Defaults = defaults
+	})
+}
+
+// SetOverrides replaces the configuration overrides of tenant id
+func (d *Directory) SetOverrides(ctx context.Context, id string, overrides Overrides) (Tenant, error) {
+	return d.update(ctx, id, func(settings *Settings) {
+		settings.Overrides = overrides
+	})
+}
+
+// SetPolicies replaces the policies of tenant id
+func (d *Directory) SetPolicies(ctx context.Context, id string, policies Policies) (Tenant, error) {
+	return d.update(ctx, id, func(settings *Settings) {
+		settings.Policies = policies
+	})
+}
+
+// Delete removes tenant id and its API keys. Stored documents and results
+// of the tenant are left to retention.
+func (d *Directory) Delete(ctx context.Context, id string) error {
+	res, err := d.db.ExecContext(ctx, d.db.Rebind(`DELETE FROM tenants WHERE id = $1`), id)
+	if err != nil {
+		return fmt.Errorf("failed to delete tenant: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrNotFound
+	}
+	d.changed(ctx)
+	return nil
+}
+
+// IssueKey issues another API key for tenant id
+func (d *Directory) IssueKey(ctx context.Context, id string) (IssuedKey, error) {
+	var issued IssuedKey
+	err := d.inTx(ctx, func(tx *sql.Tx) error {
+		if err := d.lockTenant(ctx, tx, id); err != nil {
+			return err
+		}
+		var err error
+		issued, err = d.insertKey(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return IssuedKey{}, err
+	}
+	d.changed(ctx)
+	return issued, nil
+}
+
+// RotateKey replaces API key keyID of tenant id with a new key. The old key
+// keeps working for grace, so clients can switch over; without grace it is
+// revoked at once.
+func (d *Directory) RotateKey(ctx context.Context, id, keyID string, grace time.Duration) (IssuedKey, error) {
+	var issued IssuedKey
+	err := d.inTx(ctx, func(tx *sql.Tx) error {
+		if err := d.lockTenant(ctx, tx, id); err != nil {
+			return err
+		}
+		var res sql.Result
+		var err error
+		if grace > 0 {
+			res, err = tx.ExecContext(ctx, d.db.Rebind(`UPDATE tenant_api_keys SET expires_at = $3
+				WHERE tenant_id = $1 AND id = $2`),
+				id, keyID, time.Now().Add(grace).UTC())
+		} else {
+			res, err = tx.ExecContext(ctx, d.db.Rebind(`DELETE FROM tenant_api_keys WHERE tenant_id = $1 AND id = $2`), id, keyID)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to rotate api key: %w", err)
+		}
+		if n, err := res.RowsAffected(); err == nil && n == 0 {
+			return ErrKeyNotFound
+		}
+		issued, err = d.insertKey(ctx, tx, id)
+		return err
+	})
+	if err != nil {
+		return IssuedKey{}, err
+	}
+	d.changed(ctx)
+	return issued, nil
+}
+
+// RevokeKey deletes API key keyID of tenant id
+func (d *Directory) RevokeKey(ctx context.Context, id, keyID string) error {
+	res, err := d.db.ExecContext(ctx, d.db.Rebind(`DELETE FROM tenant_api_keys WHERE tenant_id = $1 AND id = $2`), id, keyID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke api key: %w", err)
+	}
+	if n, err := res.RowsAffected(); err == nil && n == 0 {
+		return ErrKeyNotFound
+	}
+	d.changed(ctx)
+	return nil
+}
+
+// update applies change to the settings of tenant id
+func (d *Directory) update(ctx context.Context, id string, change func(settings *Settings)) (Tenant, error) {
+	err := d.inTx(ctx, func(tx *sql.Tx) error {
+		var data []byte
+		err := tx.QueryRowContext(ctx, d.db.Rebind(`SELECT settings FROM tenants WHERE id = $1`), id).Scan(&data)
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrNotFound
+		}
+		if err != nil {
+			return fmt.Errorf("failed to query tenant: %w", err)
+		}
+		var settings Settings
+		if err := json.Unmarshal(data, &settings); err != nil {
+			return fmt.Errorf("failed to decode tenant settings: %w", err)
+		}
+		change(&settings)
+		if err := settings.validate(); err != nil {
+			return err
+		}
+		if data, err = json.Marshal(settings); err != nil {
+			return fmt.Errorf("failed to encode tenant settings: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, d.db.Rebind(`UPDATE tenants SET settings = $2, updated_at = $3 WHERE id = $1`),
+			id, string(data), time.Now().UTC()); err != nil {
+			return fmt.Errorf("failed to update tenant: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Tenant{}, err
+	}
+	d.changed(ctx)
+	return d.Get(ctx, id)
+}
+
+// query loads the tenants matching condition with their keys, by ID
+func (d *Directory) query(ctx context.Context, condition string, args []interface{}) ([]Tenant, error) {
+	rows, err := d.db.QueryContext(ctx, d.db.Rebind(`SELECT id, settings, created_at, updated_at FROM tenants
+		WHERE `+condition+` ORDER BY id`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	defer rows.Close()
+
+	var tenants []Tenant
+	byID := make(map[string]*Tenant)
+	for rows.Next() {
+		var (
+			tenant Tenant
+			data   []byte
+		)
+		if err := rows.Scan(&tenant.ID, &data, &tenant.CreatedAt, &tenant.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to read tenant: %w", err)
+		}
+		if err := json.Unmarshal(data, &tenant.Settings); err != nil {
+			return nil, fmt.Errorf("failed to decode tenant settings: %w", err)
+		}
+		tenant.Keys = []Key{}
+		tenants = append(tenants, tenant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query tenants: %w", err)
+	}
+	for i := range tenants {
+		byID[tenants[i].ID] = &tenants[i]
+	}
+	if len(tenants) == 0 {
+		return nil, nil
+	}
+
+	keys, err := d.db.QueryContext(ctx, d.db.Rebind(`SELECT tenant_id, id, prefix, created_at, expires_at FROM tenant_api_keys
+		WHERE tenant_id IN (SELECT id FROM tenants WHERE `+condition+`) ORDER BY tenant_id, created_at, id`), args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	defer keys.Close()
+	for keys.Next() {
+		var (
+			id      string
+			key     Key
+			expires sql.NullTime
+		)
+		if err := keys.Scan(&id, &key.ID, &key.Prefix, &key.CreatedAt, &expires); err != nil {
+			return nil, fmt.Errorf("failed to read api key: %w", err)
+		}
+		key.ExpiresAt = timePtr(expires)
+		if tenant, ok := byID[id]; ok {
+			tenant.Keys = append(tenant.Keys, key)
+		}
+	}
+	if err := keys.Err(); err != nil {
+		return nil, fmt.Errorf("failed to query api keys: %w", err)
+	}
+	return tenants, nil
+}
+
+// lockTenant checks tenant id exists, locking its row on PostgreSQL so key
+// changes of one tenant are serialised
+func (d *Directory) lockTenant(ctx context.Context, tx *sql.Tx, id string) error {
+	query := `SELECT id FROM tenants WHERE id = $1`
+	if d.db.Driver == "postgres" {
+		query += ` FOR UPDATE`
+	}
+	err := tx.QueryRowContext(ctx, d.db.Rebind(query), id).Scan(&id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return ErrNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("failed to query tenant: %w", err)
+	}
+	return nil
+}
+
+// insertKey generates an API key for tenant id and stores its hash
+func (d *Directory) insertKey(ctx context.Context, tx *sql.Tx, id string) (IssuedKey, error) {
+	secret, err := generateKey()
+	if err != nil {
+		return IssuedKey{}, err
+	}
+	issued := IssuedKey{
+		Key: Key{
+			ID:        uuid.NewString(),
+			Prefix:    secret[:len(keyPrefix)+6],
+			CreatedAt: time.Now().UTC(),
+		},
+		Secret: secret,
+	}
+	if _, err := tx.ExecContext(ctx, d.db.Rebind(`INSERT INTO tenant_api_keys (id, tenant_id, key_hash, prefix, created_at)
+		VALUES ($1, $2, $3, $4, $5)`), issued.ID, id, hashKey(secret), issued.Prefix, issued.CreatedAt); err != nil {
+		return IssuedKey{}, fmt.Errorf("failed to insert api key: %w", err)
+	}
+	return issued, nil
+}
+
+// changed refreshes the snapshot after a change, which is already stored,
+// so a failed refresh is left to the next periodic one
+func (d *Directory) changed(ctx context.Context) {
+	if err := d.Refresh(ctx); err != nil {
+		d.log.Warn("Failed to refresh tenant directory", "error", err)
+	}
+}
+
+// inTx runs fn in a transaction, committing if it succeeds
+func (d *Directory) inTx(ctx context.Context, fn func(tx *sql.Tx) error) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// generateKey returns a new random API key
+func generateKey() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate api key: %w", err)
+	}
+	return keyPrefix + base64.RawURLEncoding.EncodeToString(buf), nil
+}
+
+func hashKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func timePtr(t sql.NullTime) *time.Time {
+	if !t.Valid {
+		return nil
+	}
+	return &t.Time
+}
@@ -0,0 +1,147 @@
+package tenant
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/database"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDirectory(t *testing.T) {
+	ctx := context.Background()
+	db, err := database.Open(ctx, config.DatabaseConfig{
+		Driver:       "sqlite",
+		DSN:          filepath.Join(t.TempDir(), "tenants.db"),
+		MaxOpenConns: 4,
+	})
+	require.NoError(t, err)
+	defer db.Close()
+
+	configured := []config.TenantConfig{{ID: "acme", APIKeys: []string{"acme-key"}}}
+	d, err := NewDirectory(ctx, db, configured, logger.New("error", "text"))
+	require.NoError(t, err)
+	resolver := NewResolver(configured, d)
+	resolve := func(key string) string {
+		id, _ := resolver.Resolve(key)
+		return id
+	}
+	unknown := func(t *testing.T, key string) {
+		_, err := resolver.Resolve(key)
+		assert.ErrorIs(t, err, ErrUnknownKey)
+	}
+
+	settings := Settings{
+		Quotas: Quotas{RequestsPerMin: 30, Burst: 5},
+		Defaults: Defaults{
+			Watermark: &Watermark{Text: "GLOBEX INTERNAL", Opacity: 0.5},
+			Retention: &Retention{TTL: 3600},
+		},
+	}
+	tenant, key, err := d.Create(ctx, "globex", settings)
+	require.NoError(t, err)
+
+	t.Run("Created Tenants Resolve By Key", func(t *testing.T) {
+		assert.Equal(t, "globex", tenant.ID)
+		assert.Equal(t, settings, tenant.Settings)
+		require.Len(t, tenant.Keys, 1)
+		assert.Equal(t, key.ID, tenant.Keys[0].ID)
+		assert.Contains(t, key.Secret, key.Prefix)
+
+		assert.Equal(t, "globex", resolve(key.Secret))
+		assert.Equal(t, "acme", resolve("acme-key"))
+		unknown(t, "pdt_unknown")
+		assert.Equal(t, config.DefaultTenant, resolve(""))
+		assert.Equal(t, settings, resolver.Settings("globex"))
+		policy, ok := resolver.Retention("globex")
+		assert.True(t, ok)
+		assert.Equal(t, config.RetentionPolicy{TTL: 3600}, policy)
+	})
+
+	t.Run("IDs Must Be Valid And Unused", func(t *testing.T) {
+		_, _, err := d.Create(ctx, "globex", Settings{})
+		assert.ErrorIs(t, err, ErrExists)
+		_, _, err = d.Create(ctx, "acme", Settings{})
+		assert.ErrorIs(t, err, ErrExists)
+		_, _, err = d.Create(ctx, "Not Valid", Settings{})
+		assert.ErrorIs(t, err, ErrInvalid)
+		_, _, err = d.Create(ctx, "initech", Settings{Quotas: Quotas{Burst: -1}})
+		assert.ErrorIs(t, err, ErrInvalid)
+	})
+
+	t.Run("Quotas And Defaults Are Replaced", func(t *testing.T) {
+		updated, err := d.SetQuotas(ctx, "globex", Quotas{RequestsPerMin: 600})
+		require.NoError(t, err)
+		assert.Equal(t, Quotas{RequestsPerMin: 600}, updated.Quotas)
+		assert.Equal(t, settings.Defaults, updated.Defaults)
+
+		updated, err = d.SetDefaults(ctx, "globex", Defaults{})
+		require.NoError(t, err)
+		assert.Nil(t, updated.Defaults.Watermark)
+		_, ok := resolver.Retention("globex")
+		assert.False(t, ok)
+
+		_, err = d.SetQuotas(ctx, "missing", Quotas{})
+		assert.ErrorIs(t, err, ErrNotFound)
+	})
+
+	t.Run("Overrides Resolve Per Tenant", func(t *testing.T) {
+		overrides := Overrides{MaxFileSize: 200 << 20, OCRLanguages: []string{"deu", "chi_sim"}, ImageFormat: "webp"}
+		_, err := d.SetOverrides(ctx, "globex", overrides)
+		require.NoError(t, err)
+		assert.Equal(t, overrides, resolver.Settings("globex").Overrides)
+		assert.Equal(t, Overrides{}, resolver.Settings("acme").Overrides)
+
+		_, err = d.SetOverrides(ctx, "globex", Overrides{OCRLanguages: []string{"eng", "--psm"}})
+		assert.ErrorIs(t, err, ErrInvalid)
+		assert.Equal(t, overrides, resolver.Settings("globex").Overrides)
+	})
+
+	t.Run("Policies Resolve Per Tenant", func(t *testing.T) {
+		policies := Policies{DownloadWatermark: &Watermark{Text: "Downloaded by {user} on {date}", Opacity: 0.2}}
+		_, err := d.SetPolicies(ctx, "globex", policies)
+		require.NoError(t, err)
+		assert.Equal(t, policies, resolver.Settings("globex").Policies)
+
+		_, err = d.SetPolicies(ctx, "globex", Policies{DownloadWatermark: &Watermark{Opacity: 0.2}})
+		assert.ErrorIs(t, err, ErrInvalid)
+		assert.Equal(t, policies, resolver.Settings("globex").Policies)
+	})
+
+	t.Run("Rotated Keys Work Until Grace Ends", func(t *testing.T) {
+		rotated, err := d.RotateKey(ctx, "globex", key.ID, time.Hour)
+		require.NoError(t, err)
+		assert.Equal(t, "globex", resolve(key.Secret))
+		assert.Equal(t, "globex", resolve(rotated.Secret))
+
+		next, err := d.RotateKey(ctx, "globex", rotated.ID, 0)
+		require.NoError(t, err)
+		unknown(t, rotated.Secret)
+		assert.Equal(t, "globex", resolve(next.Secret))
+
+		_, err = d.RotateKey(ctx, "globex", rotated.ID, 0)
+		assert.ErrorIs(t, err, ErrKeyNotFound)
+	})
+
+	t.Run("Revoked Keys And Deleted Tenants Stop Resolving", func(t *testing.T) {
+		issued, err := d.IssueKey(ctx, "globex")
+		require.NoError(t, err)
+		assert.Equal(t, "globex", resolve(issued.Secret))
+		require.NoError(t, d.RevokeKey(ctx, "globex", issued.ID))
+		unknown(t, issued.Secret)
+
+		require.NoError(t, d.Delete(ctx, "globex"))
+		unknown(t, key.Secret)
+		assert.Equal(t, Settings{}, resolver.Settings("globex"))
+		assert.ErrorIs(t, d.Delete(ctx, "globex"), ErrNotFound)
+
+		tenants, err := d.List(ctx)
+		require.NoError(t, err)
+		assert.Empty(t, tenants)
+	})
+}
@@ -0,0 +1,209 @@
+package tenant
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// adminPath is the admin path of the tenant directory
+const adminPath = "/admin/tenants"
+
+// createRequest is the body of a tenant creation
+type createRequest struct {
+	ID string `json:"id"`
+	Settings
+}
+
+// created is the response to a tenant creation, with its first API key
+type created struct {
+	Tenant Tenant    `json:"tenant"`
+	APIKey IssuedKey `json:"api_key"`
+}
+
+// Handler serves the directory on the admin listener at /admin/tenants/:
+//
+//	GET    /admin/tenants/                      list tenants
+//	POST   /admin/tenants/                      create a tenant and its first key
+//	GET    /admin/tenants/{id}                  describe a tenant
+//	DELETE /admin/tenants/{id}                  remove a tenant and its keys
+//	PUT    /admin/tenants/{id}/quotas           replace its quotas
+//	PUT    /admin/tenants/{id}/defaults         replace its defaults
+//	PUT    /admin/tenants/{id}/overrides        replace its configuration overrides
+//	PUT    /admin/tenants/{id}/policies         replace its policies
+//	POST   /admin/tenants/{id}/keys             issue another key
+//	POST   /admin/tenants/{id}/keys/{key}/rotate?grace=<seconds>
+//	DELETE /admin/tenants/{id}/keys/{key}       revoke a key
+//
+// API keys are returned only when issued.
+func (d *Directory) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rest := strings.Trim(strings.TrimPrefix(r.URL.Path, adminPath), "/")
+		parts := strings.Split(rest, "/")
+		ctx := r.Context()
+
+		switch {
+		case rest == "" && r.Method == http.MethodGet:
+			tenants, err := d.List(ctx)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			if tenants == nil {
+				tenants = []Tenant{}
+			}
+			writeJSON(w, http.StatusOK, tenants)
+
+		case rest == "" && r.Method == http.MethodPost:
+			var req createRequest
+			if !decode(w, r, &req) {
+				return
+			}
+			tenant, key, err := d.Create(ctx, req.ID, req.Settings)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant onboarded", "tenant", tenant.ID)
+			writeJSON(w, http.StatusCreated, created{Tenant: tenant, APIKey: key})
+
+		case len(parts) == 1 && r.Method == http.MethodGet:
+			tenant, err := d.Get(ctx, parts[0])
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			writeJSON(w, http.StatusOK, tenant)
+
+		case len(parts) == 1 && r.Method == http.MethodDelete:
+			if err := d.Delete(ctx, parts[0]); err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant removed", "tenant", parts[0])
+			w.WriteHeader(http.StatusNoContent)
+
+		case len(parts) == 2 && parts[1] == "quotas" && r.Method == http.MethodPut:
+			var quotas Quotas
+			if !decode(w, r, &quotas) {
+				return
+			}
+			tenant, err := d.SetQuotas(ctx, parts[0], quotas)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant quotas changed", "tenant", tenant.ID)
+			writeJSON(w, http.StatusOK, tenant)
+
+		case len(parts) == 2 && parts[1] == "defaults" && r.Method == http.MethodPut:
+			var defaults Defaults
+			if !decode(w, r, &defaults) {
+				return
+			}
+			tenant, err := d.SetDefaults(ctx, parts[0], defaults)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant defaults changed", "tenant", tenant.ID)
+			writeJSON(w, http.StatusOK, tenant)
+
+		case len(parts) == 2 && parts[1] == "overrides" && r.Method == http.MethodPut:
+			var overrides Overrides
+			if !decode(w, r, &overrides) {
+				return
+			}
+			tenant, err := d.SetOverrides(ctx, parts[0], overrides)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant configuration overrides changed", "tenant", tenant.ID)
+			writeJSON(w, http.StatusOK, tenant)
+
+		case len(parts) == 2 && parts[1] == "policies" && r.Method == http.MethodPut:
+			var policies Policies
+			if !decode(w, r, &policies) {
+				return
+			}
+			tenant, err := d.SetPolicies(ctx, parts[0], policies)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant policies changed", "tenant", tenant.ID)
+			writeJSON(w, http.StatusOK, tenant)
+
+		case len(parts) == 2 && parts[1] == "keys" && r.Method == http.MethodPost:
+			key, err := d.IssueKey(ctx, parts[0])
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant API key issued", "tenant", parts[0], "key_id", key.ID)
+			writeJSON(w, http.StatusCreated, key)
+
+		case len(parts) == 4 && parts[1] == "keys" && parts[3] == "rotate" && r.Method == http.MethodPost:
+			grace := 0
+			if value := r.URL.Query().Get("grace"); value != "" {
+				var err error
+				if grace, err = strconv.Atoi(value); err != nil || grace < 0 {
+					http.Error(w, "grace must be a non-negative number of seconds", http.StatusBadRequest)
+					return
+				}
+			}
+			key, err := d.RotateKey(ctx, parts[0], parts[2], time.Duration(grace)*time.Second)
+			if err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant API key rotated", "tenant", parts[0], "key_id", parts[2], "new_key_id", key.ID, "grace", grace)
+			writeJSON(w, http.StatusCreated, key)
+
+		case len(parts) == 3 && parts[1] == "keys" && r.Method == http.MethodDelete:
+			if err := d.RevokeKey(ctx, parts[0], parts[2]); err != nil {
+				d.fail(w, err)
+				return
+			}
+			d.log.Info("Tenant API key revoked", "tenant", parts[0], "key_id", parts[2])
+			w.WriteHeader(http.StatusNoContent)
+
+		default:
+			http.Error(w, "not found", http.StatusNotFound)
+		}
+	})
+}
+
+// fail answers with the status of a directory error
+func (d *Directory) fail(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, ErrKeyNotFound):
+		http.Error(w, err.Error(), http.StatusNotFound)
+	case errors.Is(err, ErrExists):
+		http.Error(w, err.Error(), http.StatusConflict)
+	case errors.Is(err, ErrInvalid):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	default:
+		d.log.Error("Tenant directory operation failed", "error", err)
+		http.Error(w, "tenant directory unavailable", http.StatusInternalServerError)
+	}
+}
+
+// decode reads a JSON request body, answering 400 if it is invalid
+func decode(w http.ResponseWriter, r *http.Request, v interface{}) bool {
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<16)).Decode(v); err != nil {
+		http.Error(w, "invalid body: "+err.Error(), http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(body)
+}
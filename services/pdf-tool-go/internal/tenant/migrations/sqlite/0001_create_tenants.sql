@@ -0,0 +1,19 @@
+-- Settings hold the quotas and defaults of a tenant as one document
+CREATE TABLE tenants (
+    id         TEXT        PRIMARY KEY,
+    settings   TEXT        NOT NULL,
+    created_at TIMESTAMP   NOT NULL,
+    updated_at TIMESTAMP   NOT NULL
+);
+
+-- Only hashes of API keys are stored; prefix identifies a key to operators
+CREATE TABLE tenant_api_keys (
+    id         TEXT        PRIMARY KEY,
+    tenant_id  TEXT        NOT NULL REFERENCES tenants (id) ON DELETE CASCADE,
+    key_hash   TEXT        NOT NULL UNIQUE,
+    prefix     TEXT        NOT NULL,
+    created_at TIMESTAMP   NOT NULL,
+    expires_at TIMESTAMP
+);
+
+CREATE INDEX tenant_api_keys_tenant ON tenant_api_keys (tenant_id);
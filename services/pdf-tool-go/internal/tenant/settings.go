@@ -0,0 +1,136 @@
+package tenant
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+// Settings are the quotas, defaults, configuration overrides and policies
+// of a tenant onboarded at runtime
+type Settings struct {
+	Quotas    Quotas    `json:"quotas"`
+	Defaults  Defaults  `json:"defaults"`
+	Overrides Overrides `json:"overrides"`
+	Policies  Policies  `json:"policies"`
+}
+
+// Quotas limit a tenant; zero values keep the global limits
+type Quotas struct {
+	// RequestsPerMin and Burst replace the rate limit of the tenant's
+	// shared bucket; endpoints limited on their own keep their limits
+	RequestsPerMin int `json:"requests_per_minute,omitempty"`
+	Burst          int `json:"burst,omitempty"`
+}
+
+// Defaults apply to a tenant's requests that do not set them
+type Defaults struct {
+	// Watermark is the preset of watermark requests without text
+	Watermark *Watermark `json:"watermark,omitempty"`
+	// Retention replaces the default retention policy
+	Retention *Retention `json:"retention,omitempty"`
+}
+
+// Watermark is a watermark preset; zero fields keep the built-in defaults
+type Watermark struct {
+	Text     string  `json:"text"`
+	Opacity  float64 `json:"opacity,omitempty"`
+	Rotation int     `json:"rotation,omitempty"`
+	FontSize int     `json:"font_size,omitempty"`
+}
+
+// Overrides replace global configuration for a tenant's requests; unset
+// fields keep the global value
+type Overrides struct {
+	// MaxFileSize replaces pdf.max_file_size, in bytes
+	MaxFileSize int64 `json:"max_file_size,omitempty"`
+	// OCRLanguages replaces pdf.ocr_languages
+	OCRLanguages []string `json:"ocr_languages,omitempty"`
+	// ImageFormat is the format of image conversions without ?format=
+	ImageFormat string `json:"image_format,omitempty"`
+	// OCRFormat is the format of text extractions without ?format=
+	OCRFormat string `json:"ocr_format,omitempty"`
+}
+
+// Policies are rules applied to all of a tenant's requests, which callers
+// cannot opt out of
+type Policies struct {
+	// DownloadWatermark is stamped on every PDF downloaded from storage or
+	// through a share link. Its text may name the downloader with {user},
+	// the tenant with {tenant}, and the UTC time of the download with
+	// {date} and {time}.
+	DownloadWatermark *Watermark `json:"download_watermark,omitempty"`
+}
+
+// ocrLanguagePattern matches tesseract language names, such as "eng" or
+// "chi_sim"
+var ocrLanguagePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+// Retention is a retention policy as set through the admin API
+type Retention struct {
+	// TTL is in seconds; 0 keeps objects until they are explicitly deleted
+	TTL                 int  `json:"ttl"`
+	DeleteAfterDownload bool `json:"delete_after_download"`
+}
+
+// Policy converts r to a configured retention policy
+func (r Retention) Policy() config.RetentionPolicy {
+	return config.RetentionPolicy(r)
+}
+
+// validate rejects negative quotas and malformed defaults and overrides.
+// Formats are checked by the operations using them.
+func (s Settings) validate() error {
+	if s.Quotas.RequestsPerMin < 0 || s.Quotas.Burst < 0 {
+		return fmt.Errorf("%w: quotas must not be negative", ErrInvalid)
+	}
+	if err := s.Defaults.Watermark.validate("watermark preset"); err != nil {
+		return err
+	}
+	if err := s.Policies.DownloadWatermark.validate("download watermark"); err != nil {
+		return err
+	}
+	if r := s.Defaults.Retention; r != nil && r.TTL < 0 {
+		return fmt.Errorf("%w: retention ttl must not be negative", ErrInvalid)
+	}
+	if s.Overrides.MaxFileSize < 0 {
+		return fmt.Errorf("%w: max_file_size must not be negative", ErrInvalid)
+	}
+	for _, language := range s.Overrides.OCRLanguages {
+		if !ocrLanguagePattern.MatchString(language) {
+			return fmt.Errorf("%w: invalid ocr language %q", ErrInvalid, language)
+		}
+	}
+	return nil
+}
+
+// validate rejects a watermark without text or with an opacity or font
+// size out of range; a nil watermark is valid
+func (w *Watermark) validate(name string) error {
+	if w == nil {
+		return nil
+	}
+	if w.Text == "" {
+		return fmt.Errorf("%w: %s needs text", ErrInvalid, name)
+	}
+	if w.Opacity < 0 || w.Opacity > 1 || w.FontSize < 0 {
+		return fmt.Errorf("%w: %s opacity must be between 0 and 1 and font_size not negative", ErrInvalid, name)
+	}
+	return nil
+}
+
+type settingsKey struct{}
+
+// WithSettings returns a context carrying the settings of the request's
+// tenant
+func WithSettings(ctx context.Context, settings Settings) context.Context {
+	return context.WithValue(ctx, settingsKey{}, settings)
+}
+
+// SettingsFromContext returns the tenant settings carried by ctx, or none
+func SettingsFromContext(ctx context.Context) Settings {
+	settings, _ := ctx.Value(settingsKey{}).(Settings)
+	return settings
+}
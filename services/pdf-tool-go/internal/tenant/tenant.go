@@ -0,0 +1,104 @@
+/**
+ * Tenants
+ *
+ * Resolves the tenant of a request from its API key and carries it in the
+ * request context. Tenants are configured, or onboarded at runtime into a
+ * directory kept in the database.
+ */
+
+package tenant
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+type contextKey struct{}
+
+// ErrUnknownKey is returned for API keys no tenant owns
+var ErrUnknownKey = errors.New("unknown API key")
+
+// WithID returns a context carrying the tenant ID
+func WithID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the tenant ID carried by ctx, or the default tenant
+func FromContext(ctx context.Context) string {
+	if id, ok := ctx.Value(contextKey{}).(string); ok && id != "" {
+		return id
+	}
+	return config.DefaultTenant
+}
+
+// Resolver maps API keys to tenants
+type Resolver struct {
+	tenants []config.TenantConfig
+	// directory holds the onboarded tenants; nil without onboarding
+	directory *Directory
+}
+
+// NewResolver creates a resolver for the configured tenants and those in
+// directory, which may be nil
+func NewResolver(tenants []config.TenantConfig, directory *Directory) *Resolver {
+	return &Resolver{tenants: tenants, directory: directory}
+}
+
+// Resolve returns the tenant owning apiKey, or the default tenant when no
+// key is given. Keys no tenant owns fail with ErrUnknownKey rather than
+// falling back to the default tenant, so a mistyped or revoked key never
+// reaches the default tenant's documents.
+func (r *Resolver) Resolve(apiKey string) (string, error) {
+	if apiKey == "" {
+		return config.DefaultTenant, nil
+	}
+	if id, ok := r.Lookup(apiKey); ok {
+		return id, nil
+	}
+	return "", ErrUnknownKey
+}
+
+// Lookup returns the tenant owning apiKey. It returns false for missing
+// and unknown keys. Every configured key is compared in constant time so
+// timing reveals nothing about which keys exist; keys of onboarded tenants
+// are looked up by hash.
+func (r *Resolver) Lookup(apiKey string) (string, bool) {
+	if apiKey == "" {
+		return "", false
+	}
+	id, found := "", false
+	for _, tenant := range r.tenants {
+		for _, key := range tenant.APIKeys {
+			if subtle.ConstantTimeCompare([]byte(apiKey), []byte(key)) == 1 {
+				id, found = tenant.ID, true
+			}
+		}
+	}
+	if !found && r.directory != nil {
+		return r.directory.lookup(apiKey)
+	}
+	return id, found
+}
+
+// Settings returns the quotas and defaults of tenant id, which are unset
+// for configured tenants and the default tenant. A nil Resolver has no
+// settings.
+func (r *Resolver) Settings(id string) Settings {
+	if r == nil || r.directory == nil {
+		return Settings{}
+	}
+	settings, _ := r.directory.settings(id)
+	return settings
+}
+
+// Retention returns the retention policy onboarded tenant id was given
+func (r *Resolver) Retention(id string) (config.RetentionPolicy, bool) {
+	retention := r.Settings(id).Defaults.Retention
+	if retention == nil {
+		return config.RetentionPolicy{}, false
+	}
+	return retention.Policy(), true
+}
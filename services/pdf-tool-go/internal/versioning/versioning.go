@@ -0,0 +1,208 @@
+/**
+ * API Versioning
+ *
+ * Routes are served under /api/v<N>/ for every supported version, and under
+ * /api/ with the version negotiated from the Accept header, e.g.
+ * "Accept: application/vnd.pdf-tool.v2+json". Handlers that
+ * changed incompatibly are registered per version; deprecated versions
+ * announce themselves with Deprecation, Sunset and Link headers.
+ */
+
+package versioning
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+)
+
+// Version is a major API version
+type Version int
+
+const (
+	V1 Version = 1
+	// V2 returns split output as a ZIP archive and nests errors in an
+	// envelope
+	V2 Version = 2
+)
+
+// Versions are the supported versions, oldest first
+var Versions = []Version{V1, V2}
+
+// Key is the gin context key holding the request's version
+const Key = "api_version"
+
+// Header reports the version that served a request
+const Header = "API-Version"
+
+// mediaTypePattern matches vendor media types selecting a version, such as
+// application/vnd.pdf-tool.v2+json
+var mediaTypePattern = regexp.MustCompile(`application/vnd\.pdf-tool\.v(\d+)(\+[a-z]+)?`)
+
+// versionPrefix matches the version segment of versioned route paths
+var versionPrefix = regexp.MustCompile(`^/api/v\d+/`)
+
+func (v Version) String() string {
+	return "v" + strconv.Itoa(int(v))
+}
+
+// Supported reports whether v is a supported version
+func Supported(v Version) bool {
+	return v >= Versions[0] && v <= Versions[len(Versions)-1]
+}
+
+// Of returns the version of a request, V1 outside the versioned API
+func Of(c *gin.Context) Version {
+	if v, ok := c.Get(Key); ok {
+		return v.(Version)
+	}
+	return V1
+}
+
+// Negotiate returns the version an Accept header selects, or fallback when
+// it selects none. It reports false for a header selecting only
+// unsupported versions.
+func Negotiate(accept string, fallback Version) (Version, bool) {
+	matches := mediaTypePattern.FindAllStringSubmatch(accept, -1)
+	if len(matches) == 0 {
+		return fallback, true
+	}
+	for _, match := range matches {
+		if n, err := strconv.Atoi(match[1]); err == nil && Supported(Version(n)) {
+			return Version(n), true
+		}
+	}
+	return 0, false
+}
+
+// Resolve sets the version of API requests: the one in the path under
+// /api/v<N>/, or for unversioned /api/ paths the one the Accept header
+// selects, or the configured default. It must run before middleware that
+// writes versioned responses.
+func Resolve(cfg config.VersioningConfig) gin.HandlerFunc {
+	deprecations := make(map[Version]http.Header, len(Versions))
+	for _, v := range Versions {
+		deprecations[v] = deprecationHeaders(cfg.Deprecations[v.String()])
+	}
+	return func(c *gin.Context) {
+		path := c.Request.URL.Path
+		if !strings.HasPrefix(path, "/api/") {
+			c.Next()
+			return
+		}
+
+		v, ok := fromPath(path)
+		if !ok {
+			// Add, not set: CORS has already varied the response by Origin
+			c.Writer.Header().Add("Vary", "Accept")
+			if v, ok = Negotiate(c.GetHeader("Accept"), Version(cfg.Default)); !ok {
+				c.AbortWithStatusJSON(http.StatusNotAcceptable, gin.H{
+					"error": fmt.Sprintf("unsupported API version; supported: %s", supportedList()),
+					"code":  service.ErrCodeInvalidInput,
+				})
+				return
+			}
+		}
+
+		c.Set(Key, v)
+		c.Header(Header, v.String())
+		for name, values := range deprecations[v] {
+			c.Header(name, values[0])
+		}
+		c.Next()
+	}
+}
+
+// fromPath returns the supported version in a versioned path
+func fromPath(path string) (Version, bool) {
+	segment, _, _ := strings.Cut(strings.TrimPrefix(path, "/api/"), "/")
+	n, err := strconv.Atoi(strings.TrimPrefix(segment, "v"))
+	if err != nil || !strings.HasPrefix(segment, "v") || !Supported(Version(n)) {
+		return 0, false
+	}
+	return Version(n), true
+}
+
+// deprecationHeaders returns the headers announcing a deprecation: the
+// Deprecation date (RFC 9745), the Sunset date (RFC 8594) and a link to
+// migration notes. Dates are validated with the configuration.
+func deprecationHeaders(d config.VersionDeprecation) http.Header {
+	header := make(http.Header)
+	if date, err := time.Parse(time.DateOnly, d.Date); err == nil {
+		header.Set("Deprecation", "@"+strconv.FormatInt(date.Unix(), 10))
+	}
+	if sunset, err := time.Parse(time.DateOnly, d.Sunset); err == nil {
+		header.Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Link != "" {
+		header.Set("Link", fmt.Sprintf(`<%s>; rel="deprecation"`, d.Link))
+	}
+	return header
+}
+
+func supportedList() string {
+	names := make([]string, len(Versions))
+	for i, v := range Versions {
+		names[i] = v.String()
+	}
+	return strings.Join(names, ", ")
+}
+
+// Handlers are the handlers of a route by the version they were introduced
+// in. A request is handled by the newest handler not newer than its version.
+type Handlers map[Version]gin.HandlerFunc
+
+// Handle dispatches c to its version's handler
+func (h Handlers) Handle(c *gin.Context) {
+	for v := Of(c); v >= Versions[0]; v-- {
+		if handler, ok := h[v]; ok {
+			handler(c)
+			return
+		}
+	}
+	c.AbortWithStatusJSON(http.StatusNotFound, ErrorBody(c, string(service.ErrCodeNotFound), "route is not available in API "+Of(c).String(), nil))
+}
+
+// ErrorBody returns an error response body in the format of the request's
+// version: v1 has the message in "error" beside "code" and "details"; v2
+// nests code, message and details in an "error" object.
+func ErrorBody(c *gin.Context, code, message string, details map[string]interface{}) gin.H {
+	if Of(c) >= V2 {
+		envelope := gin.H{"code": code, "message": message}
+		if len(details) > 0 {
+			envelope["details"] = details
+		}
+		return gin.H{"error": envelope}
+	}
+
+	body := gin.H{"error": message, "code": code}
+	if len(details) > 0 {
+		body["details"] = details
+	}
+	return body
+}
+
+// URL returns the versioned URL of path, relative to /api/, for links in
+// responses to c
+func URL(c *gin.Context, path string) string {
+	return "/api/" + Of(c).String() + path
+}
+
+// RoutePath returns fullPath, a route path of any version, as the v1 route
+// path that configuration such as per-endpoint rate limits is keyed by
+func RoutePath(fullPath string) string {
+	if versionPrefix.MatchString(fullPath) {
+		return versionPrefix.ReplaceAllString(fullPath, "/api/v1/")
+	}
+	if strings.HasPrefix(fullPath, "/api/") {
+		return "/api/v1/" + strings.TrimPrefix(fullPath, "/api/")
+	}
+	return fullPath
+}
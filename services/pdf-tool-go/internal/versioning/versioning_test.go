@@ -0,0 +1,82 @@
+package versioning
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		name   string
+		accept string
+		want   Version
+		ok     bool
+	}{
+		{"no preference", "application/json", V1, true},
+		{"vendor type", "application/vnd.pdf-tool.v2+json", V2, true},
+		{"first supported wins", "application/vnd.pdf-tool.v9+json, application/vnd.pdf-tool.v1+json", V1, true},
+		{"only unsupported", "application/vnd.pdf-tool.v9+json", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := Negotiate(tt.accept, V1)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestRoutePath(t *testing.T) {
+	assert.Equal(t, "/api/v1/pdf/merge", RoutePath("/api/v2/pdf/merge"))
+	assert.Equal(t, "/api/v1/pdf/merge", RoutePath("/api/pdf/merge"))
+	assert.Equal(t, "/api/v1/pdf/merge", RoutePath("/api/v1/pdf/merge"))
+	assert.Equal(t, "/health", RoutePath("/health"))
+}
+
+func TestFromPath(t *testing.T) {
+	v, ok := fromPath("/api/v2/pdf/split")
+	assert.True(t, ok)
+	assert.Equal(t, V2, v)
+
+	_, ok = fromPath("/api/pdf/split")
+	assert.False(t, ok)
+	_, ok = fromPath("/api/v9/pdf/split")
+	assert.False(t, ok)
+}
+
+func TestDeprecationHeaders(t *testing.T) {
+	header := deprecationHeaders(config.VersionDeprecation{
+		Date:   "2026-01-01",
+		Sunset: "2026-07-01",
+		Link:   "https://docs.example.com/migrate-v2",
+	})
+	assert.Equal(t, "@1767225600", header.Get("Deprecation"))
+	assert.Equal(t, "Wed, 01 Jul 2026 00:00:00 GMT", header.Get("Sunset"))
+	assert.Equal(t, `<https://docs.example.com/migrate-v2>; rel="deprecation"`, header.Get("Link"))
+
+	assert.Empty(t, deprecationHeaders(config.VersionDeprecation{}))
+}
+
+func TestResolveVary(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	router := gin.New()
+	// Stands in for the CORS middleware, which runs first
+	router.Use(func(c *gin.Context) {
+		c.Writer.Header().Add("Vary", "Origin")
+		c.Next()
+	})
+	router.Use(Resolve(config.VersioningConfig{Default: int(V1)}))
+	router.GET("/api/pdf/ping", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/api/pdf/ping", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, []string{"Origin", "Accept"}, w.Header().Values("Vary"))
+}
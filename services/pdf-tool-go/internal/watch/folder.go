@@ -0,0 +1,87 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+// Entry is a file in a watched directory
+type Entry struct {
+	Name    string
+	Size    int64
+	ModTime time.Time
+}
+
+// Folder is a session with a watched file tree. Paths are slash-separated.
+type Folder interface {
+	// List returns the regular files in dir
+	List(dir string) ([]Entry, error)
+	Read(name string) ([]byte, error)
+	// Write creates or replaces name
+	Write(name string, data []byte) error
+	// Rename moves a file. It fails if another session moved it first.
+	Rename(from, to string) error
+	MkdirAll(dir string) error
+	Close() error
+}
+
+// open starts a session with the folder described by cfg
+func open(ctx context.Context, cfg config.WatchFolder) (Folder, error) {
+	switch cfg.Type {
+	case "local":
+		return localFolder{}, nil
+	case "sftp":
+		return dialSFTP(ctx, cfg)
+	case "ftp":
+		return dialFTP(ctx, cfg)
+	default:
+		return nil, fmt.Errorf("unsupported watch folder type: %s", cfg.Type)
+	}
+}
+
+// localFolder is a directory on a local or mounted file system
+type localFolder struct{}
+
+func (localFolder) List(dir string) ([]Entry, error) {
+	entries, err := os.ReadDir(filepath.FromSlash(dir))
+	if err != nil {
+		return nil, err
+	}
+	var files []Entry
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		files = append(files, Entry{Name: entry.Name(), Size: info.Size(), ModTime: info.ModTime()})
+	}
+	return files, nil
+}
+
+func (localFolder) Read(name string) ([]byte, error) {
+	return os.ReadFile(filepath.FromSlash(name))
+}
+
+func (localFolder) Write(name string, data []byte) error {
+	return os.WriteFile(filepath.FromSlash(name), data, 0o644)
+}
+
+func (localFolder) Rename(from, to string) error {
+	return os.Rename(filepath.FromSlash(from), filepath.FromSlash(to))
+}
+
+func (localFolder) MkdirAll(dir string) error {
+	return os.MkdirAll(filepath.FromSlash(dir), 0o755)
+}
+
+func (localFolder) Close() error {
+	return nil
+}
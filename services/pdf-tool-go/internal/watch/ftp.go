@@ -0,0 +1,89 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/jlaffaye/ftp"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+)
+
+// ftpFolder is a directory on an FTP server
+type ftpFolder struct {
+	conn *ftp.ServerConn
+}
+
+// dialFTP connects and logs in to the server of cfg
+func dialFTP(ctx context.Context, cfg config.WatchFolder) (*ftpFolder, error) {
+	conn, err := ftp.Dial(cfg.Address,
+		ftp.DialWithContext(ctx),
+		ftp.DialWithTimeout(time.Duration(cfg.Timeout)*time.Second))
+	if err != nil {
+		return nil, err
+	}
+	if err := conn.Login(cfg.Username, cfg.Password); err != nil {
+		conn.Quit()
+		return nil, err
+	}
+	return &ftpFolder{conn: conn}, nil
+}
+
+func (f *ftpFolder) List(dir string) ([]Entry, error) {
+	entries, err := f.conn.List(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []Entry
+	for _, entry := range entries {
+		if entry.Type == ftp.EntryTypeFile {
+			files = append(files, Entry{Name: entry.Name, Size: int64(entry.Size), ModTime: entry.Time})
+		}
+	}
+	return files, nil
+}
+
+func (f *ftpFolder) Read(name string) ([]byte, error) {
+	resp, err := f.conn.Retr(name)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Close()
+	return io.ReadAll(resp)
+}
+
+// Write uploads to a temporary name first, so a partial upload is never
+// seen under the final name
+func (f *ftpFolder) Write(name string, data []byte) error {
+	tmp := path.Join(path.Dir(name), "."+path.Base(name)+".part")
+	if err := f.conn.Stor(tmp, bytes.NewReader(data)); err != nil {
+		return err
+	}
+	// FTP has no atomic replace; a leftover target is removed first
+	f.conn.Delete(name)
+	return f.conn.Rename(tmp, name)
+}
+
+func (f *ftpFolder) Rename(from, to string) error {
+	return f.conn.Rename(from, to)
+}
+
+// MkdirAll creates dir and its parents, ignoring those that exist
+func (f *ftpFolder) MkdirAll(dir string) error {
+	current := ""
+	if strings.HasPrefix(dir, "/") {
+		current = "/"
+	}
+	for _, part := range strings.Split(strings.Trim(dir, "/"), "/") {
+		current = path.Join(current, part)
+		f.conn.MakeDir(current)
+	}
+	return nil
+}
+
+func (f *ftpFolder) Close() error {
+	return f.conn.Quit()
+}
@@ -0,0 +1,120 @@
+package watch
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"path"
+	"time"
+
+	"github.com/pkg/sftp"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"golang.org/x/crypto/ssh"
+)
+
+// sftpFolder is a directory on an SFTP server
+type sftpFolder struct {
+	ssh    *ssh.Client
+	client *sftp.Client
+}
+
+// dialSFTP connects to the server of cfg, which must present the
+// configured host key
+func dialSFTP(ctx context.Context, cfg config.WatchFolder) (*sftpFolder, error) {
+	hostKey, _, _, _, err := ssh.ParseAuthorizedKey([]byte(cfg.HostKey))
+	if err != nil {
+		return nil, fmt.Errorf("invalid host key: %w", err)
+	}
+	var auth []ssh.AuthMethod
+	if cfg.PrivateKey != "" {
+		signer, err := ssh.ParsePrivateKey([]byte(cfg.PrivateKey))
+		if err != nil {
+			return nil, fmt.Errorf("invalid private key: %w", err)
+		}
+		auth = append(auth, ssh.PublicKeys(signer))
+	}
+	if cfg.Password != "" {
+		auth = append(auth, ssh.Password(cfg.Password))
+	}
+
+	timeout := time.Duration(cfg.Timeout) * time.Second
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := dialer.DialContext(ctx, "tcp", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, cfg.Address, &ssh.ClientConfig{
+		User:            cfg.Username,
+		Auth:            auth,
+		HostKeyCallback: ssh.FixedHostKey(hostKey),
+		Timeout:         timeout,
+	})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	sshClient := ssh.NewClient(sshConn, chans, reqs)
+
+	client, err := sftp.NewClient(sshClient)
+	if err != nil {
+		sshClient.Close()
+		return nil, err
+	}
+	return &sftpFolder{ssh: sshClient, client: client}, nil
+}
+
+func (f *sftpFolder) List(dir string) ([]Entry, error) {
+	infos, err := f.client.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []Entry
+	for _, info := range infos {
+		if info.Mode().IsRegular() {
+			files = append(files, Entry{Name: info.Name(), Size: info.Size(), ModTime: info.ModTime()})
+		}
+	}
+	return files, nil
+}
+
+func (f *sftpFolder) Read(name string) ([]byte, error) {
+	file, err := f.client.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	return io.ReadAll(file)
+}
+
+// Write uploads to a temporary name first, so a partial upload is never
+// seen under the final name
+func (f *sftpFolder) Write(name string, data []byte) error {
+	tmp := path.Join(path.Dir(name), "."+path.Base(name)+".part")
+	file, err := f.client.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC)
+	if err != nil {
+		return err
+	}
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return f.client.PosixRename(tmp, name)
+}
+
+func (f *sftpFolder) Rename(from, to string) error {
+	return f.client.Rename(from, to)
+}
+
+func (f *sftpFolder) MkdirAll(dir string) error {
+	return f.client.MkdirAll(dir)
+}
+
+func (f *sftpFolder) Close() error {
+	f.client.Close()
+	return f.ssh.Close()
+}
@@ -0,0 +1,339 @@
+/**
+ * Watch Folders
+ *
+ * Polls local, SFTP and FTP directories for new files and runs each through
+ * a configured batch pipeline, for partners that exchange documents by
+ * dropping files rather than calling the API. Results are written back to
+ * an output directory and the input is moved aside, to a done directory on
+ * success or a failed directory with an error report.
+ */
+
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/lifecycle"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+)
+
+var filesTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+	Name: "pdf_tool_watch_files_total",
+	Help: "Watched files handled by folder and outcome",
+}, []string{"folder", "outcome"})
+
+// defaultPattern matches the files picked up when a folder sets no pattern
+const defaultPattern = "*.pdf"
+
+// claim is a file taken from the inbox and submitted as a job
+type claim struct {
+	name     string
+	document *service.Result
+}
+
+// Watcher polls one watch folder. Files are claimed by moving them into a
+// processing directory of this instance, so several instances can watch
+// the same folder.
+type Watcher struct {
+	cfg         config.WatchFolder
+	maxFileSize func(ctx context.Context) int64
+	steps       []batch.Step
+	documents   *service.DocumentService
+	results     *service.ResultService
+	jobs        *batch.Manager
+	log         logger.Logger
+	// processing holds the files claimed by this instance
+	processing string
+
+	// claims are the submitted files by job ID. They are only touched by
+	// the polling goroutine.
+	claims   map[string]*claim
+	stopping chan struct{}
+	stopOnce sync.Once
+	running  lifecycle.Tracker
+}
+
+// New creates a watcher of folder. Its steps are validated against the
+// batch limits; files larger than maxFileSize returns for the folder's
+// tenant are rejected. The limit is read on every poll, so configuration
+// reloads apply.
+func New(folder config.WatchFolder, batchCfg config.BatchConfig, maxFileSize func(ctx context.Context) int64, documents *service.DocumentService,
+	results *service.ResultService, jobs *batch.Manager, log logger.Logger) (*Watcher, error) {
+	if folder.Pattern == "" {
+		folder.Pattern = defaultPattern
+	}
+	if folder.Tenant == "" {
+		folder.Tenant = config.DefaultTenant
+	}
+
+	steps := make([]batch.Step, len(folder.Steps))
+	for i, step := range folder.Steps {
+		steps[i] = batch.Step{Operation: step.Operation, Params: step.Params}
+	}
+	sub := batch.Submission{Documents: []string{folder.Name}, Steps: steps, Priority: batch.PriorityBulk}
+	if err := batch.Validate(batchCfg, sub); err != nil {
+		return nil, fmt.Errorf("watch folder %s: %w", folder.Name, err)
+	}
+
+	return &Watcher{
+		cfg:         folder,
+		maxFileSize: maxFileSize,
+		steps:       steps,
+		documents:   documents,
+		results:     results,
+		jobs:        jobs,
+		log:         log,
+		processing:  path.Join(folder.Inbox, ".processing", jobs.Owner()),
+		claims:      make(map[string]*claim),
+		stopping:    make(chan struct{}),
+	}, nil
+}
+
+// Start polls the folder until ctx is done or Drain is called. Files this
+// instance claimed before it last stopped are returned to the inbox first.
+func (w *Watcher) Start(ctx context.Context) {
+	ctx = tenant.WithID(ctx, w.cfg.Tenant)
+	done := w.running.Start()
+	go func() {
+		defer done()
+		w.poll(ctx, true)
+		ticker := time.NewTicker(time.Duration(w.cfg.Interval) * time.Second)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-w.stopping:
+				return
+			case <-ticker.C:
+				w.poll(ctx, false)
+			}
+		}
+	}()
+}
+
+// Drain stops polling and waits for the current poll. Files whose jobs
+// are still running stay claimed and are picked up again on restart.
+func (w *Watcher) Drain(ctx context.Context) error {
+	w.stopOnce.Do(func() { close(w.stopping) })
+	return w.running.Wait(ctx)
+}
+
+// poll runs one session with the folder: it settles finished jobs, then
+// claims and submits new files
+func (w *Watcher) poll(ctx context.Context, restore bool) {
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(w.cfg.Timeout)*time.Second)
+	defer cancel()
+
+	folder, err := open(ctx, w.cfg)
+	if err != nil {
+		w.log.Warn("Failed to connect to watch folder", "folder", w.cfg.Name, "error", err)
+		return
+	}
+	defer folder.Close()
+
+	for _, dir := range []string{w.processing, w.cfg.Output, w.cfg.Done, w.cfg.Failed} {
+		if err := folder.MkdirAll(dir); err != nil {
+			w.log.Warn("Failed to create watch folder directory", "folder", w.cfg.Name, "dir", dir, "error", err)
+			return
+		}
+	}
+	if restore {
+		w.restore(folder)
+	}
+	w.settle(ctx, folder)
+	w.claim(ctx, folder)
+}
+
+// restore returns files left in the processing directory to the inbox
+func (w *Watcher) restore(folder Folder) {
+	entries, err := folder.List(w.processing)
+	if err != nil {
+		w.log.Warn("Failed to list claimed files", "folder", w.cfg.Name, "error", err)
+		return
+	}
+	for _, entry := range entries {
+		if err := folder.Rename(path.Join(w.processing, entry.Name), path.Join(w.cfg.Inbox, entry.Name)); err != nil {
+			w.log.Warn("Failed to return claimed file to inbox", "folder", w.cfg.Name, "file", entry.Name, "error", err)
+		}
+	}
+	if len(entries) > 0 {
+		w.log.Info("Returned claimed files to inbox", "folder", w.cfg.Name, "files", len(entries))
+	}
+}
+
+// settle writes back the results of finished jobs and moves their inputs
+// aside
+func (w *Watcher) settle(ctx context.Context, folder Folder) {
+	for id, c := range w.claims {
+		job, err := w.jobs.Get(ctx, id)
+		if err != nil {
+			if service.CodeOf(err) != service.ErrCodeNotFound {
+				w.log.Warn("Failed to check watch job", "folder", w.cfg.Name, "job_id", id, "error", err)
+				continue
+			}
+			w.fail(ctx, folder, c, id, &batch.Failure{Code: service.ErrCodeNotFound, Error: "job expired before it finished"})
+			delete(w.claims, id)
+			continue
+		}
+
+		switch job.Status {
+		case batch.StatusSucceeded:
+			if err := w.writeResults(ctx, folder, c.name, job.Results); err != nil {
+				// Leave the claim for the next poll
+				w.log.Warn("Failed to write watch job results", "folder", w.cfg.Name, "job_id", id, "file", c.name, "error", err)
+				continue
+			}
+			w.moveAside(ctx, folder, c, w.cfg.Done, "done")
+		case batch.StatusFailed, batch.StatusCanceled:
+			failure := job.Failure
+			if failure == nil {
+				failure = &batch.Failure{Code: service.ErrCodeInternal, Error: "job " + string(job.Status)}
+			}
+			w.fail(ctx, folder, c, id, failure)
+		default:
+			continue
+		}
+		delete(w.claims, id)
+	}
+}
+
+// writeResults writes the results of the job run on name to the output
+// directory: name.pdf for one result, name-1.pdf, name-2.pdf... for more
+func (w *Watcher) writeResults(ctx context.Context, folder Folder, name string, results []string) error {
+	base := strings.TrimSuffix(name, path.Ext(name))
+	for i, id := range results {
+		data, err := w.readResult(ctx, id)
+		if err != nil {
+			return err
+		}
+		target := base + ".pdf"
+		if len(results) > 1 {
+			target = fmt.Sprintf("%s-%d.pdf", base, i+1)
+		}
+		if err := folder.Write(path.Join(w.cfg.Output, target), data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (w *Watcher) readResult(ctx context.Context, id string) ([]byte, error) {
+	obj, _, err := w.results.Open(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+// fail moves a claimed file to the failed directory next to a report of
+// why its job failed
+func (w *Watcher) fail(ctx context.Context, folder Folder, c *claim, jobID string, failure *batch.Failure) {
+	report, _ := json.MarshalIndent(map[string]interface{}{
+		"file":    c.name,
+		"job_id":  jobID,
+		"failure": failure,
+	}, "", "  ")
+	if err := folder.Write(path.Join(w.cfg.Failed, c.name+".error.json"), report); err != nil {
+		w.log.Warn("Failed to write watch error report", "folder", w.cfg.Name, "file", c.name, "error", err)
+	}
+	w.moveAside(ctx, folder, c, w.cfg.Failed, "failed")
+}
+
+// moveAside moves a claimed file to dir and removes its uploaded document
+func (w *Watcher) moveAside(ctx context.Context, folder Folder, c *claim, dir, outcome string) {
+	if err := folder.Rename(path.Join(w.processing, c.name), path.Join(dir, c.name)); err != nil {
+		w.log.Warn("Failed to move watched file", "folder", w.cfg.Name, "file", c.name, "dir", dir, "error", err)
+	}
+	w.release(ctx, c)
+	filesTotal.WithLabelValues(w.cfg.Name, outcome).Inc()
+	w.log.Info("Watched file processed", "folder", w.cfg.Name, "file", c.name, "outcome", outcome)
+}
+
+// release removes the document uploaded for a claimed file. Documents
+// stored before the file arrived are not the watcher's to remove.
+func (w *Watcher) release(ctx context.Context, c *claim) {
+	if c.document == nil || c.document.Existed {
+		return
+	}
+	if err := w.documents.Delete(ctx, c.document.ID); err != nil {
+		w.log.Warn("Failed to delete watched document", "folder", w.cfg.Name, "document", c.document.ID, "error", err)
+	}
+}
+
+// claim submits the inbox files matching the pattern that have not changed
+// for the minimum age. It stops at the first busy rejection; the file is
+// returned to the inbox and retried on the next poll.
+func (w *Watcher) claim(ctx context.Context, folder Folder) {
+	entries, err := folder.List(w.cfg.Inbox)
+	if err != nil {
+		w.log.Warn("Failed to list watch folder inbox", "folder", w.cfg.Name, "error", err)
+		return
+	}
+
+	settled := time.Now().Add(-time.Duration(w.cfg.MinAge) * time.Second)
+	for _, entry := range entries {
+		if matched, _ := path.Match(w.cfg.Pattern, entry.Name); !matched || entry.ModTime.After(settled) {
+			continue
+		}
+		claimed := path.Join(w.processing, entry.Name)
+		if err := folder.Rename(path.Join(w.cfg.Inbox, entry.Name), claimed); err != nil {
+			// Another instance claimed it first
+			continue
+		}
+		c := &claim{name: entry.Name}
+
+		if limit := w.maxFileSize(ctx); entry.Size > limit {
+			w.fail(ctx, folder, c, "", &batch.Failure{Input: -1, Code: service.ErrCodeFileTooLarge,
+				Error: fmt.Sprintf("file exceeds the maximum size of %d bytes", limit)})
+			continue
+		}
+
+		job, err := w.submit(ctx, folder, c)
+		if service.CodeOf(err) == service.ErrCodeBusy {
+			if err := folder.Rename(claimed, path.Join(w.cfg.Inbox, entry.Name)); err != nil {
+				w.log.Warn("Failed to return watched file to inbox", "folder", w.cfg.Name, "file", entry.Name, "error", err)
+			}
+			w.release(ctx, c)
+			w.log.Info("Batch queue busy, deferring watched files", "folder", w.cfg.Name, "file", entry.Name)
+			return
+		}
+		if err != nil {
+			w.fail(ctx, folder, c, "", &batch.Failure{Input: -1, Code: service.CodeOf(err), Error: err.Error()})
+			continue
+		}
+		w.claims[job.ID] = c
+		w.log.Info("Watched file submitted", "folder", w.cfg.Name, "file", entry.Name, "job_id", job.ID)
+	}
+}
+
+// submit uploads a claimed file and queues its job
+func (w *Watcher) submit(ctx context.Context, folder Folder, c *claim) (*batch.Job, error) {
+	data, err := folder.Read(path.Join(w.processing, c.name))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read file: %w", err)
+	}
+	c.document, err = w.documents.Save(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	return w.jobs.Submit(ctx, batch.Submission{
+		Documents: []string{c.document.ID},
+		Steps:     w.steps,
+		Priority:  batch.PriorityBulk,
+	})
+}
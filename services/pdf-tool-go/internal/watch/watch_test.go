@@ -0,0 +1,158 @@
+package watch
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/batch"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/config"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/service"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/storage"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/internal/tenant"
+	"github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeProcessor compresses by appending "~" and fails on inputs containing
+// "bad"
+type fakeProcessor struct{}
+
+func (fakeProcessor) SplitPDF(ctx context.Context, req *service.SplitRequest) ([][]byte, error) {
+	return [][]byte{req.PDFData}, nil
+}
+
+func (fakeProcessor) MergePDFs(ctx context.Context, req *service.MergeRequest) ([]byte, error) {
+	return bytes.Join(req.PDFs, nil), nil
+}
+
+func (fakeProcessor) CompressPDF(ctx context.Context, req *service.CompressRequest) ([]byte, error) {
+	if bytes.Contains(req.PDFData, []byte("bad")) {
+		return nil, service.NewError(service.ErrCodeCorrupted, "PDF is corrupted or malformed", nil)
+	}
+	return append(req.PDFData, '~'), nil
+}
+
+func (fakeProcessor) AddWatermark(ctx context.Context, req *service.WatermarkRequest) ([]byte, error) {
+	return req.PDFData, nil
+}
+
+func (fakeProcessor) FillForm(ctx context.Context, req *service.FillFormRequest) ([]byte, error) {
+	return req.PDFData, nil
+}
+
+func TestWatcher(t *testing.T) {
+	store, err := storage.NewLocalStore(t.TempDir())
+	require.NoError(t, err)
+	log := logger.New("error", "text")
+	documents := service.NewDocumentService(store, nil, log)
+	results := service.NewResultService(store, nil, log)
+	batchCfg := config.BatchConfig{InstanceID: "node-1", Workers: 1, QueueSize: 10, TenantQueueSize: 10, InteractiveRatio: 1,
+		MaxSteps: 5, MaxDocuments: 5, JobTimeout: 60, JobTTL: 60, MaxAttempts: 1, RetryDelay: 1, MaxRetryDelay: 1}
+	jobs := batch.NewManager(batchCfg, nil, batch.NewMemoryStore(time.Hour), fakeProcessor{}, store, documents, results, log)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	jobs.Start(ctx)
+
+	root := t.TempDir()
+	dir := func(name string) string { return filepath.Join(root, name) }
+	folder := config.WatchFolder{Name: "partner", Type: "local", Tenant: "acme",
+		Inbox: dir("in"), Output: dir("out"), Done: dir("done"), Failed: dir("failed"),
+		Steps: []config.WatchStep{{Operation: "compress"}}, Interval: 1, Timeout: 10}
+
+	var limit atomic.Int64
+	limit.Store(1024)
+	maxFileSize := func(context.Context) int64 { return limit.Load() }
+
+	t.Run("Invalid Steps Are Rejected", func(t *testing.T) {
+		invalid := folder
+		invalid.Steps = []config.WatchStep{{Operation: "shred"}}
+		_, err := New(invalid, batchCfg, maxFileSize, documents, results, jobs, log)
+		assert.Error(t, err)
+	})
+
+	w, err := New(folder, batchCfg, maxFileSize, documents, results, jobs, log)
+	require.NoError(t, err)
+
+	require.NoError(t, os.MkdirAll(filepath.Join(dir("in"), ".processing", "node-1"), 0o755))
+	write := func(name, content string) {
+		require.NoError(t, os.WriteFile(name, []byte(content), 0o644))
+	}
+	write(filepath.Join(dir("in"), "good.pdf"), "good")
+	write(filepath.Join(dir("in"), "bad.pdf"), "bad")
+	write(filepath.Join(dir("in"), "big.pdf"), string(make([]byte, 2048)))
+	write(filepath.Join(dir("in"), "notes.txt"), "ignored")
+	write(filepath.Join(dir("in"), ".processing", "node-1", "left.pdf"), "left")
+
+	pollCtx := tenant.WithID(context.Background(), "acme")
+	w.poll(pollCtx, true)
+	require.Eventually(t, func() bool {
+		w.poll(pollCtx, false)
+		return len(w.claims) == 0
+	}, 5*time.Second, 50*time.Millisecond)
+
+	read := func(name string) string {
+		data, err := os.ReadFile(name)
+		require.NoError(t, err)
+		return string(data)
+	}
+
+	t.Run("Results Are Written And Inputs Moved To Done", func(t *testing.T) {
+		assert.Equal(t, "good~", read(filepath.Join(dir("out"), "good.pdf")))
+		assert.Equal(t, "good", read(filepath.Join(dir("done"), "good.pdf")))
+		assert.Equal(t, "left~", read(filepath.Join(dir("out"), "left.pdf")))
+	})
+
+	t.Run("Failures Are Reported Next To The Input", func(t *testing.T) {
+		assert.Equal(t, "bad", read(filepath.Join(dir("failed"), "bad.pdf")))
+		var report struct {
+			JobID   string         `json:"job_id"`
+			Failure *batch.Failure `json:"failure"`
+		}
+		require.NoError(t, json.Unmarshal([]byte(read(filepath.Join(dir("failed"), "bad.pdf.error.json"))), &report))
+		assert.NotEmpty(t, report.JobID)
+		assert.Equal(t, service.ErrCodeCorrupted, report.Failure.Code)
+		assert.Equal(t, "compress", report.Failure.Operation)
+	})
+
+	t.Run("Oversized Files Are Rejected", func(t *testing.T) {
+		assert.FileExists(t, filepath.Join(dir("failed"), "big.pdf"))
+		assert.Contains(t, read(filepath.Join(dir("failed"), "big.pdf.error.json")), string(service.ErrCodeFileTooLarge))
+	})
+
+	t.Run("Size Limit Changes Apply", func(t *testing.T) {
+		limit.Store(4096)
+		write(filepath.Join(dir("in"), "large.pdf"), string(make([]byte, 2048)))
+		require.Eventually(t, func() bool {
+			w.poll(pollCtx, false)
+			return len(w.claims) == 0
+		}, 5*time.Second, 50*time.Millisecond)
+		assert.FileExists(t, filepath.Join(dir("out"), "large.pdf"))
+		assert.NoFileExists(t, filepath.Join(dir("failed"), "large.pdf"))
+	})
+
+	t.Run("Unmatched Files Stay In The Inbox", func(t *testing.T) {
+		entries, err := os.ReadDir(dir("in"))
+		require.NoError(t, err)
+		var names []string
+		for _, entry := range entries {
+			names = append(names, entry.Name())
+		}
+		assert.ElementsMatch(t, []string{".processing", "notes.txt"}, names)
+	})
+
+	t.Run("Uploaded Documents Are Removed", func(t *testing.T) {
+		var stored []string
+		require.NoError(t, documents.List(pollCtx, func(doc *service.Result) error {
+			stored = append(stored, doc.ID)
+			return nil
+		}))
+		assert.Empty(t, stored)
+	})
+}
@@ -0,0 +1,31 @@
+package bufferpool
+
+import (
+	"bytes"
+	"sync"
+)
+
+// maxPooledSize caps the capacity of buffers kept in the pool so a single
+// huge upload does not pin its memory for the lifetime of the process
+const maxPooledSize = 64 << 20 // 64MB
+
+var pool = sync.Pool{
+	New: func() interface{} {
+		return new(bytes.Buffer)
+	},
+}
+
+// Get returns an empty buffer from the pool
+func Get() *bytes.Buffer {
+	return pool.Get().(*bytes.Buffer)
+}
+
+// Put resets buf and returns it to the pool. The caller must not use buf,
+// or any slice obtained from it, afterwards.
+func Put(buf *bytes.Buffer) {
+	if buf == nil || buf.Cap() > maxPooledSize {
+		return
+	}
+	buf.Reset()
+	pool.Put(buf)
+}
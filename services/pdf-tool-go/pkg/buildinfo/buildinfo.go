@@ -0,0 +1,54 @@
+package buildinfo
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// Build metadata, overridden at build time with:
+//
+//	-ldflags "-X github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo.Version=1.2.3
+//	          -X github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo.Commit=abc123
+//	          -X github.com/rajmahavir/taskmanager/services/pdf-tool-go/pkg/buildinfo.BuildTime=2024-01-01T00:00:00Z"
+var (
+	Version   = "1.0.0"
+	Commit    = ""
+	BuildTime = ""
+)
+
+// Info describes the running build
+type Info struct {
+	Version   string `json:"version"`
+	Commit    string `json:"commit"`
+	BuildTime string `json:"build_time,omitempty"`
+	GoVersion string `json:"go_version"`
+}
+
+// Get returns the build info, falling back to VCS data embedded by the Go
+// toolchain when the commit was not injected via ldflags
+func Get() Info {
+	info := Info{
+		Version:   Version,
+		Commit:    Commit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+
+	if info.Commit == "" {
+		info.Commit = "unknown"
+		if bi, ok := debug.ReadBuildInfo(); ok {
+			for _, setting := range bi.Settings {
+				switch setting.Key {
+				case "vcs.revision":
+					info.Commit = setting.Value
+				case "vcs.time":
+					if info.BuildTime == "" {
+						info.BuildTime = setting.Value
+					}
+				}
+			}
+		}
+	}
+
+	return info
+}
@@ -11,6 +11,11 @@ type Logger interface {
 	Error(msg string, keysAndValues ...interface{})
 }
 
+// LevelSetter is implemented by loggers whose level can change at runtime
+type LevelSetter interface {
+	SetLevel(level string) error
+}
+
 type logrusLogger struct {
 	logger *logrus.Logger
 }
@@ -31,6 +36,15 @@ func New(level, format string) Logger {
 	return &logrusLogger{logger: log}
 }
 
+func (l *logrusLogger) SetLevel(level string) error {
+	lvl, err := logrus.ParseLevel(level)
+	if err != nil {
+		return err
+	}
+	l.logger.SetLevel(lvl)
+	return nil
+}
+
 func (l *logrusLogger) Debug(msg string, keysAndValues ...interface{}) {
 	l.logger.WithFields(parseFields(keysAndValues...)).Debug(msg)
 }
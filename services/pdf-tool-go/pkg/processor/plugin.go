@@ -0,0 +1,114 @@
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/rpc"
+	"os/exec"
+	"strings"
+
+	"github.com/hashicorp/go-plugin"
+)
+
+// Handshake is the go-plugin handshake processor plugins and the service
+// agree on. Bump ProtocolVersion when Request or Response change
+// incompatibly.
+var Handshake = plugin.HandshakeConfig{
+	ProtocolVersion:  1,
+	MagicCookieKey:   "PDF_TOOL_PROCESSOR_PLUGIN",
+	MagicCookieValue: "d2b1c6f0-processor",
+}
+
+// pluginName is the name the processor is dispensed under
+const pluginName = "processor"
+
+// Serve runs p as a plugin; call it from the plugin binary's main
+func Serve(p Processor) {
+	plugin.Serve(&plugin.ServeConfig{
+		HandshakeConfig: Handshake,
+		Plugins:         plugin.PluginSet{pluginName: &rpcPlugin{impl: p}},
+	})
+}
+
+// Open starts the plugin binary at path and returns its processor. Close
+// stops the plugin process.
+func Open(path string) (p Processor, close func(), err error) {
+	client := plugin.NewClient(&plugin.ClientConfig{
+		HandshakeConfig:  Handshake,
+		Plugins:          plugin.PluginSet{pluginName: &rpcPlugin{}},
+		Cmd:              exec.Command(path),
+		AllowedProtocols: []plugin.Protocol{plugin.ProtocolNetRPC},
+	})
+
+	protocol, err := client.Client()
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to start plugin %s: %w", path, err)
+	}
+	raw, err := protocol.Dispense(pluginName)
+	if err != nil {
+		client.Kill()
+		return nil, nil, fmt.Errorf("failed to dispense plugin %s: %w", path, err)
+	}
+	return raw.(Processor), client.Kill, nil
+}
+
+// rpcPlugin carries processors over net/rpc
+type rpcPlugin struct {
+	impl Processor
+}
+
+func (p *rpcPlugin) Server(*plugin.MuxBroker) (interface{}, error) {
+	return &rpcServer{impl: p.impl}, nil
+}
+
+func (p *rpcPlugin) Client(_ *plugin.MuxBroker, client *rpc.Client) (interface{}, error) {
+	return &rpcClient{client: client}, nil
+}
+
+// rpcServer runs in the plugin process. net/rpc has no context, so the
+// request is processed to completion even if the caller gave up.
+type rpcServer struct {
+	impl Processor
+}
+
+func (s *rpcServer) Process(req *Request, resp *Response) error {
+	out, err := s.impl.Process(context.Background(), req)
+	if err != nil {
+		if errors.Is(err, ErrInvalidInput) && !strings.HasPrefix(err.Error(), ErrInvalidInput.Error()) {
+			return fmt.Errorf("%w: %s", ErrInvalidInput, err)
+		}
+		return err
+	}
+	*resp = *out
+	return nil
+}
+
+// rpcClient runs in the service and implements Processor over the plugin
+type rpcClient struct {
+	client *rpc.Client
+}
+
+func (c *rpcClient) Process(ctx context.Context, req *Request) (*Response, error) {
+	resp := new(Response)
+	call := c.client.Go("Plugin.Process", req, resp, nil)
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-call.Done:
+	}
+
+	// Errors cross the process boundary as text
+	var serverErr rpc.ServerError
+	if errors.As(call.Error, &serverErr) {
+		if msg := string(serverErr); strings.HasPrefix(msg, ErrInvalidInput.Error()) {
+			return nil, fmt.Errorf("%w%s", ErrInvalidInput, strings.TrimPrefix(msg, ErrInvalidInput.Error()))
+		}
+		return nil, errors.New(string(serverErr))
+	}
+	if call.Error != nil {
+		return nil, call.Error
+	}
+	return resp, nil
+}
@@ -0,0 +1,81 @@
+/**
+ * Custom Processors
+ *
+ * Custom PDF operations platform teams add to the service, such as
+ * company-specific stamping. Processors are compiled in by calling Register
+ * from an init function, or run out of process as hashicorp/go-plugin
+ * binaries built with Serve. Each is exposed under /api/v1/pdf/custom/:name.
+ */
+
+package processor
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrInvalidInput is returned, possibly wrapped, by processors rejecting
+// their parameters or input document; the request fails as a client error
+var ErrInvalidInput = errors.New("invalid input")
+
+// Request is the input of a custom operation
+type Request struct {
+	// PDFData is the uploaded document
+	PDFData []byte
+	// Params are the query parameters of the request
+	Params map[string]string
+}
+
+// Response is the output of a custom operation
+type Response struct {
+	Data []byte
+	// ContentType of Data; empty means application/pdf
+	ContentType string
+}
+
+// Processor is a custom PDF operation
+type Processor interface {
+	Process(ctx context.Context, req *Request) (*Response, error)
+}
+
+// Func adapts a function to a Processor
+type Func func(ctx context.Context, req *Request) (*Response, error)
+
+// Process calls f
+func (f Func) Process(ctx context.Context, req *Request) (*Response, error) {
+	return f(ctx, req)
+}
+
+var (
+	mu         sync.Mutex
+	processors = make(map[string]Processor)
+)
+
+// Register makes a compiled-in processor available under name. It panics if
+// name is registered twice.
+func Register(name string, p Processor) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if p == nil {
+		panic("processor: Register processor is nil")
+	}
+	if _, dup := processors[name]; dup {
+		panic(fmt.Sprintf("processor: Register called twice for %q", name))
+	}
+	processors[name] = p
+}
+
+// Registered returns the compiled-in processors by name
+func Registered() map[string]Processor {
+	mu.Lock()
+	defer mu.Unlock()
+
+	registered := make(map[string]Processor, len(processors))
+	for name, p := range processors {
+		registered[name] = p
+	}
+	return registered
+}
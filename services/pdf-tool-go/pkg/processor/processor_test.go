@@ -0,0 +1,28 @@
+package processor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	stamp := Func(func(ctx context.Context, req *Request) (*Response, error) {
+		return &Response{Data: req.PDFData}, nil
+	})
+
+	t.Run("Registered Processors Are Listed", func(t *testing.T) {
+		Register("test-stamp", stamp)
+		p, ok := Registered()["test-stamp"]
+		assert.True(t, ok)
+
+		resp, err := p.Process(context.Background(), &Request{PDFData: []byte("%PDF")})
+		assert.NoError(t, err)
+		assert.Equal(t, []byte("%PDF"), resp.Data)
+	})
+
+	t.Run("Duplicate Names Panic", func(t *testing.T) {
+		assert.Panics(t, func() { Register("test-stamp", stamp) })
+	})
+}
@@ -4,10 +4,13 @@ import (
 	"context"
 
 	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
 	"go.opentelemetry.io/otel/exporters/prometheus"
+	"go.opentelemetry.io/otel/propagation"
 	"go.opentelemetry.io/otel/sdk/metric"
 	"go.opentelemetry.io/otel/sdk/trace"
 	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	oteltrace "go.opentelemetry.io/otel/trace"
 )
 
 func InitTracer(serviceName, version string) (func(context.Context) error, error) {
@@ -16,6 +19,10 @@ func InitTracer(serviceName, version string) (func(context.Context) error, error
 		trace.WithBatcher(exporter),
 	)
 	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{},
+		propagation.Baggage{},
+	))
 	return tp.Shutdown, nil
 }
 
@@ -25,3 +32,28 @@ func InitMetrics() *metric.MeterProvider {
 	otel.SetMeterProvider(provider)
 	return provider
 }
+
+// EndSpan records err on span, if any, and ends it
+func EndSpan(span oteltrace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// InjectTraceContext serialises the trace context of ctx so it can be stored
+// on an async record (such as a job) and picked up by whoever processes it
+func InjectTraceContext(ctx context.Context) map[string]string {
+	carrier := propagation.MapCarrier{}
+	otel.GetTextMapPropagator().Inject(ctx, carrier)
+	return carrier
+}
+
+// LinkTraceContext returns a span option linking a new span to the trace
+// serialised by InjectTraceContext. Async work runs in its own trace, linked
+// back to the request that created it.
+func LinkTraceContext(carrier map[string]string) oteltrace.SpanStartOption {
+	ctx := otel.GetTextMapPropagator().Extract(context.Background(), propagation.MapCarrier(carrier))
+	return oteltrace.WithLinks(oteltrace.LinkFromContext(ctx))
+}